@@ -0,0 +1,470 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"water-ai/llm"
+	"water-ai/tools"
+)
+
+// =============================================================================
+// LLM Client Adapter
+// =============================================================================
+
+// LLMClientAdapter lets a FunctionCallAgent drive an llm.Client, converting
+// the agent's messages and results to and from llm content blocks. It
+// reports the usage of each call.
+type LLMClientAdapter struct {
+	Client      llm.Client
+	Temperature float64
+	// Sampling, when set, fixes top_p and the seed of every call, e.g.
+	// for reproducible runs
+	Sampling *llm.Sampling
+
+	mu        sync.Mutex
+	lastUsage TokenUsage
+}
+
+// NewLLMClientAdapter wraps client for use by agents.
+func NewLLMClientAdapter(client llm.Client, temperature float64) *LLMClientAdapter {
+	return &LLMClientAdapter{Client: client, Temperature: temperature}
+}
+
+func (a *LLMClientAdapter) Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var params []*llm.ToolParam
+	for _, t := range tools {
+		params = append(params, &llm.ToolParam{Name: t.Name, Description: t.Description, InputSchema: t.Schema})
+	}
+
+	resp, err := a.Client.Generate(toLLMMessages(messages), maxTokens, systemPrompt, a.Temperature, params, nil, nil, a.Sampling)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.lastUsage = TokenUsage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens}
+	a.mu.Unlock()
+
+	var results []interface{}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case llm.ContentTypeText:
+			results = append(results, TextResult{Text: block.Text})
+		case llm.ContentTypeThinking:
+			results = append(results, ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature})
+		case llm.ContentTypeToolCall:
+			results = append(results, ToolCallParameters{ID: block.ToolCallID, Name: block.ToolName, Arguments: block.ToolInput, ArgumentsError: block.ToolInputError})
+		}
+	}
+	return results, nil
+}
+
+// LastUsage returns the usage of the most recent Generate call.
+func (a *LLMClientAdapter) LastUsage() TokenUsage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lastUsage
+}
+
+// toLLMMessages converts agent messages, in any of the content shapes
+// History and the context manager produce, into llm messages.
+func toLLMMessages(messages []Message) []*llm.Message {
+	out := make([]*llm.Message, 0, len(messages))
+	for _, msg := range messages {
+		var blocks []*llm.ContentBlock
+		switch content := msg.Content.(type) {
+		case string:
+			blocks = append(blocks, &llm.ContentBlock{Type: llm.ContentTypeText, Text: content})
+		case []map[string]interface{}:
+			for _, item := range content {
+				blocks = append(blocks, mapToLLMBlock(item))
+			}
+		case []interface{}:
+			for _, item := range content {
+				switch v := item.(type) {
+				case TextResult:
+					blocks = append(blocks, &llm.ContentBlock{Type: llm.ContentTypeText, Text: v.Text})
+				case ThinkingBlock:
+					blocks = append(blocks, &llm.ContentBlock{Type: llm.ContentTypeThinking, Thinking: v.Thinking, Signature: v.Signature})
+				case ToolCallParameters:
+					blocks = append(blocks, &llm.ContentBlock{Type: llm.ContentTypeToolCall, ToolCallID: v.ID, ToolName: v.Name, ToolInput: v.Arguments, ToolInputError: v.ArgumentsError})
+				case map[string]interface{}:
+					blocks = append(blocks, mapToLLMBlock(v))
+				default:
+					blocks = append(blocks, &llm.ContentBlock{Type: llm.ContentTypeText, Text: fmt.Sprint(v)})
+				}
+			}
+		default:
+			blocks = append(blocks, &llm.ContentBlock{Type: llm.ContentTypeText, Text: fmt.Sprint(content)})
+		}
+		out = append(out, &llm.Message{Role: msg.Role, Content: blocks})
+	}
+	return out
+}
+
+func mapToLLMBlock(item map[string]interface{}) *llm.ContentBlock {
+	str := func(key string) string {
+		s, _ := item[key].(string)
+		return s
+	}
+	switch item["type"] {
+	case "text":
+		return &llm.ContentBlock{Type: llm.ContentTypeText, Text: str("text")}
+	case "tool_call":
+		input, _ := item["tool_input"].(map[string]interface{})
+		return &llm.ContentBlock{Type: llm.ContentTypeToolCall, ToolCallID: str("tool_call_id"), ToolName: str("tool_name"), ToolInput: input}
+	case "tool_result":
+		return &llm.ContentBlock{Type: llm.ContentTypeToolResult, ToolCallID: str("tool_call_id"), ToolName: str("tool_name"), ToolOutput: item["result"]}
+	}
+	if source, ok := item["source"].(map[string]interface{}); ok {
+		s := func(key string) string {
+			v, _ := source[key].(string)
+			return v
+		}
+		return &llm.ContentBlock{Type: llm.ContentTypeImage, Source: &llm.ImageSource{Type: s("type"), MediaType: s("media_type"), Data: s("data")}}
+	}
+	js, _ := json.Marshal(item)
+	return &llm.ContentBlock{Type: llm.ContentTypeText, Text: string(js)}
+}
+
+// =============================================================================
+// Message History
+// =============================================================================
+
+// History is an in-memory MessageHistory. User prompts are stored as
+// strings (with image maps when attached), assistant turns as the LLM
+// results, and tool results as "tool_result" maps.
+type History struct {
+	messages []Message
+}
+
+// NewHistory returns an empty History.
+func NewHistory() *History {
+	return &History{}
+}
+
+func (h *History) AddUserPrompt(prompt string, images []interface{}) {
+	if len(images) == 0 {
+		h.messages = append(h.messages, Message{Role: "user", Content: prompt})
+		return
+	}
+	content := append([]interface{}{}, images...)
+	content = append(content, map[string]interface{}{"type": "text", "text": prompt})
+	h.messages = append(h.messages, Message{Role: "user", Content: content})
+}
+
+func (h *History) AddAssistantTurn(responses []interface{}) {
+	h.messages = append(h.messages, Message{Role: "assistant", Content: responses})
+}
+
+func (h *History) AddToolCallResult(toolCall ToolCallParameters, result string) {
+	h.messages = append(h.messages, Message{Role: "user", Content: []map[string]interface{}{
+		{"type": "tool_result", "tool_call_id": toolCall.ID, "tool_name": toolCall.Name, "result": result},
+	}})
+}
+
+func (h *History) GetMessagesForLLM() []Message {
+	out := make([]Message, len(h.messages))
+	copy(out, h.messages)
+	return out
+}
+
+func (h *History) SetMessages(messages []Message) {
+	h.messages = messages
+}
+
+func (h *History) GetPendingToolCalls() []ToolCallParameters {
+	if len(h.messages) == 0 {
+		return nil
+	}
+	last := h.messages[len(h.messages)-1]
+	items, ok := last.Content.([]interface{})
+	if last.Role != "assistant" || !ok {
+		return nil
+	}
+	var calls []ToolCallParameters
+	for _, item := range items {
+		if tc, ok := item.(ToolCallParameters); ok {
+			calls = append(calls, tc)
+		}
+	}
+	return calls
+}
+
+func (h *History) GetLastAssistantTextResponse() string {
+	for i := len(h.messages) - 1; i >= 0; i-- {
+		msg := h.messages[i]
+		if msg.Role != "assistant" {
+			continue
+		}
+		switch content := msg.Content.(type) {
+		case string:
+			return content
+		case []interface{}:
+			for _, item := range content {
+				if tr, ok := item.(TextResult); ok {
+					return tr.Text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (h *History) Clear() { h.messages = nil }
+
+// Truncate is a no-op; agents with a ContextManager summarize instead.
+func (h *History) Truncate() {}
+
+// CountTokens estimates the history's size at four characters per token.
+func (h *History) CountTokens() int {
+	total := 0
+	for _, block := range toBlockLists(h.messages) {
+		js, _ := json.Marshal(block)
+		total += len(js) / 4
+	}
+	return total
+}
+
+func (h *History) IsNextTurnUser() bool {
+	return len(h.messages) == 0 || h.messages[len(h.messages)-1].Role == "assistant"
+}
+
+// LLMHistory is a MessageHistory stored in an llm.MessageHistory, so an
+// agent can continue a conversation kept in llm content blocks, such as a
+// server session's.
+type LLMHistory struct {
+	History *llm.MessageHistory
+}
+
+// NewLLMHistory returns a MessageHistory backed by h.
+func NewLLMHistory(h *llm.MessageHistory) *LLMHistory {
+	return &LLMHistory{History: h}
+}
+
+func (h *LLMHistory) add(role string, content []interface{}) {
+	h.History.Messages = append(h.History.Messages, toLLMMessages([]Message{{Role: role, Content: content}})...)
+}
+
+func (h *LLMHistory) AddUserPrompt(prompt string, images []interface{}) {
+	content := append([]interface{}{}, images...)
+	h.add("user", append(content, TextResult{Text: prompt}))
+}
+
+func (h *LLMHistory) AddAssistantTurn(responses []interface{}) {
+	h.add("assistant", responses)
+}
+
+func (h *LLMHistory) AddToolCallResult(toolCall ToolCallParameters, result string) {
+	h.History.AddToolResult(toolCall.ID, toolCall.Name, result)
+}
+
+func (h *LLMHistory) GetMessagesForLLM() []Message {
+	return fromLLMMessages(h.History.GetMessages())
+}
+
+func (h *LLMHistory) SetMessages(messages []Message) {
+	h.History.Messages = toLLMMessages(messages)
+}
+
+func (h *LLMHistory) GetPendingToolCalls() []ToolCallParameters {
+	last := h.last()
+	if last == nil || last.Role != "assistant" {
+		return nil
+	}
+	var calls []ToolCallParameters
+	for _, block := range last.Content {
+		if block.Type == llm.ContentTypeToolCall {
+			calls = append(calls, ToolCallParameters{ID: block.ToolCallID, Name: block.ToolName, Arguments: block.ToolInput, ArgumentsError: block.ToolInputError})
+		}
+	}
+	return calls
+}
+
+func (h *LLMHistory) GetLastAssistantTextResponse() string {
+	messages := h.History.GetMessages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "assistant" {
+			continue
+		}
+		for _, block := range messages[i].Content {
+			if block.Type == llm.ContentTypeText {
+				return block.Text
+			}
+		}
+	}
+	return ""
+}
+
+func (h *LLMHistory) Clear() { h.History.Clear() }
+
+// Truncate is a no-op; agents with a ContextManager summarize instead.
+func (h *LLMHistory) Truncate() {}
+
+// CountTokens estimates the history's size at four characters per token.
+func (h *LLMHistory) CountTokens() int {
+	js, _ := json.Marshal(h.History.GetMessages())
+	return len(js) / 4
+}
+
+func (h *LLMHistory) IsNextTurnUser() bool {
+	last := h.last()
+	return last == nil || last.Role == "assistant"
+}
+
+func (h *LLMHistory) last() *llm.Message {
+	messages := h.History.GetMessages()
+	if len(messages) == 0 {
+		return nil
+	}
+	return messages[len(messages)-1]
+}
+
+// fromLLMMessages converts llm messages into agent messages that
+// toLLMMessages turns back into the same blocks.
+func fromLLMMessages(messages []*llm.Message) []Message {
+	out := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		content := make([]interface{}, 0, len(msg.Content))
+		for _, block := range msg.Content {
+			switch block.Type {
+			case llm.ContentTypeThinking:
+				content = append(content, ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature})
+			case llm.ContentTypeToolCall:
+				content = append(content, ToolCallParameters{ID: block.ToolCallID, Name: block.ToolName, Arguments: block.ToolInput, ArgumentsError: block.ToolInputError})
+			case llm.ContentTypeToolResult:
+				content = append(content, map[string]interface{}{"type": "tool_result", "tool_call_id": block.ToolCallID, "tool_name": block.ToolName, "result": block.ToolOutput})
+			case llm.ContentTypeImage:
+				if block.Source != nil {
+					content = append(content, map[string]interface{}{"source": map[string]interface{}{"type": block.Source.Type, "media_type": block.Source.MediaType, "data": block.Source.Data}})
+				}
+			case llm.ContentTypeRedactedThinking:
+				// Redacted thinking cannot be replayed without its data.
+			default:
+				content = append(content, TextResult{Text: block.Text})
+			}
+		}
+		out = append(out, Message{Role: msg.Role, Content: content})
+	}
+	return out
+}
+
+// =============================================================================
+// Tools & Workspace
+// =============================================================================
+
+// toolAdapter exposes a tools.Tool as an LLMTool.
+type toolAdapter struct {
+	tool tools.Tool
+}
+
+// WrapTool adapts t for use by agents.
+func WrapTool(t tools.Tool) LLMTool {
+	return &toolAdapter{tool: t}
+}
+
+func (a *toolAdapter) GetToolParam() ToolParam {
+	return ToolParam{Name: a.tool.Name(), Description: a.tool.Description(), Schema: a.tool.InputSchema()}
+}
+
+func (a *toolAdapter) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	out, err := a.tool.Run(ctx, tools.ToolInput(input))
+	if err != nil {
+		return ToolImplOutput{}, err
+	}
+	if out == nil {
+		return ToolImplOutput{}, nil
+	}
+	message := fmt.Sprintf("%s completed", a.tool.Name())
+	if out.Error != "" {
+		message = fmt.Sprintf("%s failed: %s", a.tool.Name(), out.Error)
+	}
+	return ToolImplOutput{ToolOutput: out.Text, ToolResultMessage: message}, nil
+}
+
+// systemToolAdapter exposes a tools.SystemTool as an LLMTool.
+type systemToolAdapter struct {
+	tool tools.SystemTool
+}
+
+// WrapSystemTool adapts t for use by agents.
+func WrapSystemTool(t tools.SystemTool) LLMTool {
+	return &systemToolAdapter{tool: t}
+}
+
+func (a *systemToolAdapter) GetToolParam() ToolParam {
+	return ToolParam{Name: a.tool.Name(), Description: a.tool.Description(), Schema: a.tool.Schema()}
+}
+
+func (a *systemToolAdapter) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	out, err := a.tool.Run(ctx, tools.ToolInput(input))
+	if err != nil {
+		return ToolImplOutput{}, err
+	}
+	return ToolImplOutput{ToolOutput: out.Output, ToolResultMessage: out.ResultMessage}, nil
+}
+
+// MarkFinal marks tool as ending the run: its output is the final answer.
+// A plan-only run ends at a planned call to it.
+func MarkFinal(tool LLMTool) LLMTool {
+	return &finalTool{LLMTool: tool}
+}
+
+type finalTool struct {
+	LLMTool
+}
+
+func (t *finalTool) GetToolParam() ToolParam {
+	p := t.LLMTool.GetToolParam()
+	p.Final = true
+	return p
+}
+
+func (t *finalTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	out, err := t.LLMTool.Run(ctx, input, history)
+	if err == nil {
+		out.IsFinal = true
+	}
+	return out, err
+}
+
+// isFinal reports whether the tool call names a tool marked with Final.
+func isFinal(params []ToolParam, call ToolCallParameters) bool {
+	for _, p := range params {
+		if p.Name == call.Name {
+			return p.Final
+		}
+	}
+	return false
+}
+
+// DirWorkspace is a WorkspaceManager rooted at a local directory.
+type DirWorkspace struct {
+	Root string
+	ID   string
+}
+
+func (w *DirWorkspace) RelativePath(path string) string {
+	if rel, err := filepath.Rel(w.Root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func (w *DirWorkspace) WorkspacePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(w.Root, path)
+}
+
+func (w *DirWorkspace) SessionID() string { return w.ID }
@@ -0,0 +1,133 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"water-ai/llm"
+	"water-ai/tools"
+)
+
+func TestLLMClientAdapterConvertsMessages(t *testing.T) {
+	mock := llm.NewMockClient(&llm.GenerateResponse{
+		Content: []*llm.ContentBlock{
+			{Type: llm.ContentTypeThinking, Thinking: "hmm"},
+			{Type: llm.ContentTypeToolCall, ToolCallID: "c2", ToolName: "ls", ToolInput: map[string]interface{}{"dir": "."}},
+		},
+		Usage: llm.UsageMetadata{InputTokens: 12, OutputTokens: 3},
+	})
+	adapter := NewLLMClientAdapter(mock, 0.5)
+	seed := 7
+	adapter.Sampling = &llm.Sampling{Seed: &seed}
+
+	history := NewHistory()
+	history.AddUserPrompt("list files", nil)
+	history.AddAssistantTurn([]interface{}{TextResult{Text: "ok"}, ToolCallParameters{ID: "c1", Name: "ls", Arguments: map[string]interface{}{}}})
+	history.AddToolCallResult(ToolCallParameters{ID: "c1", Name: "ls"}, "a.txt")
+
+	got, err := adapter.Generate(context.Background(), history.GetMessagesForLLM(), 100, []ToolParam{{Name: "ls"}}, "sys")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("results = %v; want thinking and tool call", got)
+	}
+	if call, ok := got[1].(ToolCallParameters); !ok || call.ID != "c2" || call.Arguments["dir"] != "." {
+		t.Errorf("results[1] = %#v; want the c2 tool call", got[1])
+	}
+	if usage := adapter.LastUsage(); usage.InputTokens != 12 || usage.OutputTokens != 3 {
+		t.Errorf("LastUsage() = %+v; want 12 in, 3 out", usage)
+	}
+
+	call := mock.Calls()[0]
+	if call.Temperature != 0.5 || call.SystemPrompt != "sys" || len(call.Tools) != 1 {
+		t.Errorf("call = %+v; want temperature, system prompt and tools passed through", call)
+	}
+	if call.Sampling == nil || *call.Sampling.Seed != 7 {
+		t.Errorf("call sampling = %+v; want the adapter's seed", call.Sampling)
+	}
+	wantTypes := []llm.ContentType{llm.ContentTypeText, llm.ContentTypeToolCall, llm.ContentTypeToolResult}
+	var gotTypes []llm.ContentType
+	for _, msg := range call.Messages {
+		gotTypes = append(gotTypes, msg.Content[len(msg.Content)-1].Type)
+	}
+	for i := range wantTypes {
+		if i >= len(gotTypes) || gotTypes[i] != wantTypes[i] {
+			t.Fatalf("message block types = %v; want %v", gotTypes, wantTypes)
+		}
+	}
+	if result := call.Messages[2].Content[0]; result.ToolCallID != "c1" || result.ToolOutput != "a.txt" {
+		t.Errorf("tool result block = %+v; want c1 -> a.txt", result)
+	}
+}
+
+func TestHistoryPendingCallsAndLastText(t *testing.T) {
+	history := NewHistory()
+	if !history.IsNextTurnUser() {
+		t.Error("empty history: IsNextTurnUser() = false; want true")
+	}
+	history.AddUserPrompt("hi", nil)
+	history.AddAssistantTurn([]interface{}{TextResult{Text: "calling"}, ToolCallParameters{ID: "x", Name: "ls"}})
+
+	if calls := history.GetPendingToolCalls(); len(calls) != 1 || calls[0].ID != "x" {
+		t.Errorf("GetPendingToolCalls() = %v; want [x]", calls)
+	}
+	if got := history.GetLastAssistantTextResponse(); got != "calling" {
+		t.Errorf("GetLastAssistantTextResponse() = %q; want calling", got)
+	}
+	history.AddToolCallResult(ToolCallParameters{ID: "x", Name: "ls"}, "done")
+	if calls := history.GetPendingToolCalls(); len(calls) != 0 {
+		t.Errorf("GetPendingToolCalls() after result = %v; want none", calls)
+	}
+}
+
+func TestWrapTool(t *testing.T) {
+	dir := t.TempDir()
+	tool := WrapTool(&tools.FileEditorTool{BaseDir: dir})
+	if name := tool.GetToolParam().Name; name != "file_editor" {
+		t.Errorf("tool name = %q; want file_editor", name)
+	}
+
+	out, err := tool.Run(context.Background(), map[string]interface{}{"action": "read", "path": "missing.txt"}, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.ToolResultMessage == "" || out.ToolOutput == "" {
+		t.Errorf("Run() = %+v; want the error reported as output", out)
+	}
+}
+
+func TestLLMHistoryRoundTrip(t *testing.T) {
+	backing := llm.NewMessageHistory()
+	history := NewLLMHistory(backing)
+	history.AddUserPrompt("list files", nil)
+	history.AddAssistantTurn([]interface{}{ThinkingBlock{Thinking: "List first.", Signature: "sig-1"}, TextResult{Text: "Listing."}, ToolCallParameters{ID: "c1", Name: "ls", Arguments: map[string]interface{}{"dir": "."}}})
+
+	pending := history.GetPendingToolCalls()
+	if len(pending) != 1 || pending[0].ID != "c1" || pending[0].Arguments["dir"] != "." {
+		t.Fatalf("GetPendingToolCalls() = %+v; want the c1 call", pending)
+	}
+	history.AddToolCallResult(pending[0], "a.txt")
+	if history.IsNextTurnUser() {
+		t.Error("IsNextTurnUser() = true after a tool result; want false")
+	}
+	if got := history.GetLastAssistantTextResponse(); got != "Listing." {
+		t.Errorf("GetLastAssistantTextResponse() = %q; want %q", got, "Listing.")
+	}
+
+	msgs := backing.GetMessages()
+	if len(msgs) != 3 || msgs[1].Content[2].Type != llm.ContentTypeToolCall || msgs[2].Content[0].ToolOutput != "a.txt" {
+		t.Fatalf("backing history = %+v; want prompt, tool call and result", msgs)
+	}
+	if msgs[1].Content[0].Signature != "sig-1" {
+		t.Errorf("thinking signature = %q; want it kept", msgs[1].Content[0].Signature)
+	}
+
+	before, _ := json.Marshal(backing.GetMessages())
+	history.SetMessages(history.GetMessagesForLLM())
+	after, _ := json.Marshal(backing.GetMessages())
+	if string(before) != string(after) {
+		t.Errorf("history changed by a round trip:\n%s\n%s", before, after)
+	}
+}
@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoPendingApproval is returned when resolving a tool call that is not
+// waiting for approval.
+var ErrNoPendingApproval = errors.New("no tool call is awaiting approval with that ID")
+
+// Approver decides whether a tool call may run, usually by asking the
+// user. AwaitApproval blocks until it has an answer or ctx ends.
+type Approver interface {
+	AwaitApproval(ctx context.Context, call ToolCallParameters) (bool, error)
+}
+
+// RequireApproval marks tool as needing the user's approval before each
+// run, e.g. for the terminal and file writes.
+func RequireApproval(tool LLMTool) LLMTool {
+	return &approvalTool{LLMTool: tool}
+}
+
+type approvalTool struct {
+	LLMTool
+}
+
+func (t *approvalTool) GetToolParam() ToolParam {
+	p := t.LLMTool.GetToolParam()
+	p.RequiresApproval = true
+	return p
+}
+
+// requiresApproval reports whether the tool call names a tool marked with
+// RequiresApproval.
+func requiresApproval(params []ToolParam, call ToolCallParameters) bool {
+	for _, p := range params {
+		if p.Name == call.Name {
+			return p.RequiresApproval
+		}
+	}
+	return false
+}
+
+// ApprovalQueue is an Approver answered from elsewhere, such as the
+// approve and deny WebSocket messages, by tool call ID.
+type ApprovalQueue struct {
+	mu      sync.Mutex
+	pending map[string]chan bool
+}
+
+func NewApprovalQueue() *ApprovalQueue {
+	return &ApprovalQueue{pending: make(map[string]chan bool)}
+}
+
+func (q *ApprovalQueue) AwaitApproval(ctx context.Context, call ToolCallParameters) (bool, error) {
+	answer := make(chan bool, 1)
+	q.mu.Lock()
+	q.pending[call.ID] = answer
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.pending, call.ID)
+		q.mu.Unlock()
+	}()
+
+	select {
+	case approved := <-answer:
+		return approved, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Resolve answers the approval request for the tool call with the given
+// ID.
+func (q *ApprovalQueue) Resolve(toolCallID string, approved bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	answer, ok := q.pending[toolCallID]
+	if !ok {
+		return ErrNoPendingApproval
+	}
+	delete(q.pending, toolCallID)
+	answer <- approved
+	return nil
+}
@@ -0,0 +1,112 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// answerApprovals resolves the first tool call the agent sends with
+// awaiting_approval, returning the event.
+func answerApprovals(agent *FunctionCallAgent, queue *ApprovalQueue, approved bool) <-chan RealtimeEvent {
+	asked := make(chan RealtimeEvent, 1)
+	go func() {
+		for e := range agent.MessageQueue {
+			if e.Type == EventTypeToolCall && e.Content["awaiting_approval"] == true {
+				asked <- e
+				id, _ := e.Content["tool_call_id"].(string)
+				// The agent registers the call just after emitting the event.
+				for queue.Resolve(id, approved) != nil {
+					time.Sleep(time.Millisecond)
+				}
+				return
+			}
+		}
+	}()
+	return asked
+}
+
+func TestToolApproval(t *testing.T) {
+	tests := []struct {
+		name       string
+		approved   bool
+		wantRan    bool
+		wantResult string
+	}{
+		{"approve", true, true, "bash"},
+		{"deny", false, false, ToolRejectedMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &echoTool{name: "bash"}
+			ran := &countingToolManager{AgentToolManager: NewAgentToolManager([]LLMTool{RequireApproval(tool)})}
+			client := &scriptedLLMClient{responses: [][]interface{}{
+				{ToolCallParameters{ID: "call-1", Name: "bash"}},
+			}}
+			history := &sliceHistory{}
+			agent := newTestAgent(client, history, nil, nil)
+			agent.ToolManager = ran
+			queue := NewApprovalQueue()
+			agent.Approver = queue
+			asked := answerApprovals(agent, queue, tt.approved)
+
+			if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "go"}, history); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			select {
+			case e := <-asked:
+				if e.Content["tool_call_id"] != "call-1" {
+					t.Errorf("approval asked for %v; want call-1", e.Content["tool_call_id"])
+				}
+			default:
+				t.Fatal("no tool_call event with awaiting_approval")
+			}
+			if got := len(ran.calls) == 1; got != tt.wantRan {
+				t.Errorf("tool ran = %v; want %v", got, tt.wantRan)
+			}
+			result, _ := history.messages[2].Content.([]map[string]interface{})
+			if len(result) != 1 || result[0]["result"] != tt.wantResult {
+				t.Errorf("tool result = %v; want %q", history.messages[2].Content, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestToolWithoutApprovalRunsUnasked(t *testing.T) {
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "bash"}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{&echoTool{name: "bash"}})
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "go"}, history); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, e := range drainEvents(agent) {
+		if e.Type == EventTypeToolCall && e.Content["awaiting_approval"] != false {
+			t.Errorf("tool_call awaiting_approval = %v; want false", e.Content["awaiting_approval"])
+		}
+	}
+	result, _ := history.messages[2].Content.([]map[string]interface{})
+	if len(result) != 1 || result[0]["result"] != "bash" {
+		t.Errorf("tool result = %v; want the tool output", history.messages[2].Content)
+	}
+}
+
+func TestApprovalQueue(t *testing.T) {
+	q := NewApprovalQueue()
+	if err := q.Resolve("call-1", true); !errors.Is(err, ErrNoPendingApproval) {
+		t.Errorf("Resolve() with nothing pending error = %v; want ErrNoPendingApproval", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.AwaitApproval(ctx, ToolCallParameters{ID: "call-2"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("AwaitApproval() on a cancelled context error = %v; want context.Canceled", err)
+	}
+	if err := q.Resolve("call-2", true); !errors.Is(err, ErrNoPendingApproval) {
+		t.Errorf("Resolve() after the wait ended error = %v; want ErrNoPendingApproval", err)
+	}
+}
@@ -240,6 +240,7 @@ func (m *mockMessageHistory) AddUserPrompt(prompt string, images []interface{})
 func (m *mockMessageHistory) AddAssistantTurn(responses []interface{})          {}
 func (m *mockMessageHistory) AddToolCallResult(toolCall ToolCallParameters, result string) {}
 func (m *mockMessageHistory) GetMessagesForLLM() []Message                        { return nil }
+func (m *mockMessageHistory) SetMessages(messages []Message)                      {}
 func (m *mockMessageHistory) GetPendingToolCalls() []ToolCallParameters            { return nil }
 func (m *mockMessageHistory) GetLastAssistantTextResponse() string               { return "" }
 func (m *mockMessageHistory) Clear()                                              {}
@@ -0,0 +1,34 @@
+package agents
+
+import (
+	"fmt"
+
+	"water-ai/browser"
+)
+
+// SuperviseBrowser wraps b so that browser actions run through the
+// returned Supervisor survive a Playwright crash. Each restart is reported
+// to the client as a system event.
+func (a *FunctionCallAgent) SuperviseBrowser(b *browser.Browser) *browser.Supervisor {
+	s := browser.NewSupervisor(b)
+	s.OnRestart = func(cause error) {
+		message := fmt.Sprintf("The browser crashed (%v) and was restarted; retrying the last action.", cause)
+		a.Logger.Println(message)
+		a.emitEvent(EventTypeSystem, map[string]interface{}{"message": message})
+	}
+	return s
+}
+
+// MirrorBrowserTabs sends a browser_tabs event to the client whenever a
+// tab of b opens, closes or becomes current, so the browser panel can
+// show a tab strip.
+func (a *FunctionCallAgent) MirrorBrowserTabs(b *browser.Browser) {
+	b.OnTabChange = func(e browser.TabEvent) {
+		a.emitEvent(EventTypeBrowserTabs, map[string]interface{}{
+			"kind":   string(e.Kind),
+			"tab":    e.Tab,
+			"active": e.Active,
+			"tabs":   e.Tabs,
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"strings"
+
+	contextmanager "water-ai/llm/context_manager"
+)
+
+const summaryPrefix = "Conversation Summary:"
+
+// LLMContextManager adapts the summarizing contextmanager.Manager to the
+// agents' ContextManager interface. Summaries are generated through the
+// same LLMClient the agent uses for its turns.
+type LLMContextManager struct {
+	manager    *contextmanager.Manager
+	maxContext int
+	logger     *slog.Logger
+}
+
+// NewLLMContextManager creates a ContextManager that summarizes history via
+// client once cfg.TokenBudget or cfg.MaxSize is exceeded. A nil cfg uses the
+// contextmanager defaults.
+func NewLLMContextManager(client LLMClient, logger *slog.Logger, cfg *contextmanager.Config) *LLMContextManager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	m := contextmanager.New(&summaryClient{client: client}, charTokenCounter{}, logger, cfg)
+	maxContext := contextmanager.DefaultTokenBudget
+	if cfg != nil {
+		maxContext = cfg.TokenBudget
+	}
+	return &LLMContextManager{manager: m, maxContext: maxContext, logger: logger}
+}
+
+// CountTokens estimates the token count of messages.
+func (c *LLMContextManager) CountTokens(messages []Message) int {
+	return c.manager.CountTokens(toBlockLists(messages))
+}
+
+// GetMaxContextLength returns the configured token budget.
+func (c *LLMContextManager) GetMaxContextLength() int {
+	return c.maxContext
+}
+
+// Summarize asks the model for a summary of the whole conversation in
+// messages.
+func (c *LLMContextManager) Summarize(ctx context.Context, messages []Message) (string, error) {
+	return c.manager.GenerateCompleteConversationSummary(ctx, toBlockLists(messages))
+}
+
+// ReserveSystemTokens reports the size of the system prompt and tool
+// definitions sent with every turn, so a budget allocation can set aside
+// their share.
+func (c *LLMContextManager) ReserveSystemTokens(systemPrompt string, tools ToolManager) {
+	tokens := charTokenCounter{}.CountTokens(systemPrompt)
+	if params, err := toolParams(tools); err == nil {
+		js, _ := json.Marshal(params)
+		tokens += charTokenCounter{}.CountTokens(string(js))
+	}
+	c.manager.SetSystemTokens(tokens)
+}
+
+// ApplyTruncationIfNeeded summarizes older messages when over budget. The
+// kept head and tail are returned as the original Message values so no
+// information is lost in conversion; only the summary message is new, and
+// tool results the manager trimmed are carried over.
+func (c *LLMContextManager) ApplyTruncationIfNeeded(messages []Message) []Message {
+	lists := toBlockLists(messages)
+	truncated, err := c.manager.ApplyTruncationIfNeeded(context.Background(), lists)
+	if err != nil {
+		c.logger.Error("Context truncation failed", "error", err)
+		return messages
+	}
+	summaryIdx := -1
+	for i, list := range truncated {
+		if len(list) == 1 {
+			if tr, ok := list[0].(contextmanager.TextResult); ok && strings.HasPrefix(tr.Text, summaryPrefix) {
+				summaryIdx = i
+				break
+			}
+		}
+	}
+	if summaryIdx < 0 {
+		if len(truncated) != len(messages) {
+			return messages
+		}
+		result := make([]Message, len(messages))
+		for i, msg := range messages {
+			result[i] = withTrimmedResults(msg, truncated[i])
+		}
+		return result
+	}
+
+	tail := len(truncated) - summaryIdx - 1
+	summaryText := truncated[summaryIdx][0].(contextmanager.TextResult).Text
+
+	result := make([]Message, 0, len(truncated))
+	for i, msg := range messages[:summaryIdx] {
+		result = append(result, withTrimmedResults(msg, truncated[i]))
+	}
+	result = append(result, Message{Role: "assistant", Content: summaryText})
+	for j, msg := range messages[len(messages)-tail:] {
+		result = append(result, withTrimmedResults(msg, truncated[summaryIdx+1+j]))
+	}
+	return result
+}
+
+// withTrimmedResults returns msg with the result of each tool_result item
+// replaced by the output of the matching block in blocks, which toBlocks
+// built from msg item by item. msg is returned unchanged when nothing was
+// trimmed.
+func withTrimmedResults(msg Message, blocks []contextmanager.ContentBlock) Message {
+	trimmed := func(i int, item map[string]interface{}) (map[string]interface{}, bool) {
+		if i >= len(blocks) || item["type"] != "tool_result" {
+			return item, false
+		}
+		tr, ok := blocks[i].(contextmanager.ToolFormattedResult)
+		if !ok || !strings.HasSuffix(tr.ToolOutput, contextmanager.TrimmedToolResultMarker) {
+			return item, false
+		}
+		copied := make(map[string]interface{}, len(item))
+		for k, v := range item {
+			copied[k] = v
+		}
+		copied["result"] = tr.ToolOutput
+		return copied, true
+	}
+
+	switch content := msg.Content.(type) {
+	case []map[string]interface{}:
+		items := make([]map[string]interface{}, len(content))
+		changed := false
+		for i, item := range content {
+			var ok bool
+			items[i], ok = trimmed(i, item)
+			changed = changed || ok
+		}
+		if changed {
+			msg.Content = items
+		}
+	case []interface{}:
+		items := make([]interface{}, len(content))
+		changed := false
+		for i, item := range content {
+			items[i] = item
+			if m, isMap := item.(map[string]interface{}); isMap {
+				var ok bool
+				items[i], ok = trimmed(i, m)
+				changed = changed || ok
+			}
+		}
+		if changed {
+			msg.Content = items
+		}
+	}
+	return msg
+}
+
+// summaryClient routes contextmanager summary requests through an agent LLMClient.
+type summaryClient struct {
+	client LLMClient
+}
+
+func (s *summaryClient) Generate(ctx context.Context, messages [][]contextmanager.ContentBlock, maxTokens int, temperature float64) ([]contextmanager.ContentBlock, error) {
+	var prompt strings.Builder
+	for _, list := range messages {
+		for _, block := range list {
+			if tp, ok := block.(contextmanager.TextPrompt); ok {
+				prompt.WriteString(tp.Text)
+			}
+		}
+	}
+
+	response, err := s.client.Generate(ctx, []Message{{Role: "user", Content: prompt.String()}}, maxTokens, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []contextmanager.ContentBlock
+	for _, item := range response {
+		if tr, ok := item.(TextResult); ok {
+			blocks = append(blocks, contextmanager.TextResult{Text: tr.Text})
+		}
+	}
+	return blocks, nil
+}
+
+// charTokenCounter approximates 1 token per 4 characters.
+type charTokenCounter struct{}
+
+func (charTokenCounter) CountTokens(text string) int {
+	return len(text) / 4
+}
+
+// toBlockLists converts agent messages into the contextmanager representation,
+// one block list per message.
+func toBlockLists(messages []Message) [][]contextmanager.ContentBlock {
+	lists := make([][]contextmanager.ContentBlock, 0, len(messages))
+	for _, msg := range messages {
+		lists = append(lists, toBlocks(msg))
+	}
+	return lists
+}
+
+func toBlocks(msg Message) []contextmanager.ContentBlock {
+	text := func(s string) contextmanager.ContentBlock {
+		if msg.Role == "user" {
+			return contextmanager.TextPrompt{Text: s}
+		}
+		return contextmanager.TextResult{Text: s}
+	}
+
+	switch content := msg.Content.(type) {
+	case string:
+		return []contextmanager.ContentBlock{text(content)}
+	case []map[string]interface{}:
+		blocks := make([]contextmanager.ContentBlock, 0, len(content))
+		for _, item := range content {
+			blocks = append(blocks, mapToBlock(item, text))
+		}
+		return blocks
+	case []interface{}:
+		blocks := make([]contextmanager.ContentBlock, 0, len(content))
+		for _, item := range content {
+			switch v := item.(type) {
+			case TextResult:
+				blocks = append(blocks, text(v.Text))
+			case ThinkingBlock:
+				blocks = append(blocks, contextmanager.AnthropicThinkingBlock{Thinking: v.Thinking})
+			case ToolCallParameters:
+				blocks = append(blocks, contextmanager.ToolCall{ToolInput: v.Arguments})
+			case map[string]interface{}:
+				blocks = append(blocks, mapToBlock(v, text))
+			default:
+				blocks = append(blocks, text(fmt.Sprint(v)))
+			}
+		}
+		return blocks
+	default:
+		return []contextmanager.ContentBlock{text(fmt.Sprint(content))}
+	}
+}
+
+func mapToBlock(item map[string]interface{}, text func(string) contextmanager.ContentBlock) contextmanager.ContentBlock {
+	switch item["type"] {
+	case "text":
+		s, _ := item["text"].(string)
+		return text(s)
+	case "image":
+		return imageBlock(item)
+	case "tool_call":
+		return contextmanager.ToolCall{ToolInput: item["tool_input"]}
+	case "tool_result":
+		if s, ok := item["result"].(string); ok {
+			return contextmanager.ToolFormattedResult{ToolOutput: s}
+		}
+		js, _ := json.Marshal(item["result"])
+		return contextmanager.ToolFormattedResult{ToolOutput: string(js)}
+	default:
+		if _, ok := item["source"]; ok {
+			return imageBlock(item)
+		}
+		js, _ := json.Marshal(item)
+		return text(string(js))
+	}
+}
+
+// imageBlock builds an ImageBlock, reading the pixel size from the
+// base64 image data in item's source when it can be decoded.
+func imageBlock(item map[string]interface{}) contextmanager.ImageBlock {
+	source, _ := item["source"].(map[string]interface{})
+	data, _ := source["data"].(string)
+	if data == "" {
+		return contextmanager.ImageBlock{}
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return contextmanager.ImageBlock{}
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return contextmanager.ImageBlock{}
+	}
+	return contextmanager.ImageBlock{Width: cfg.Width, Height: cfg.Height}
+}
@@ -0,0 +1,106 @@
+package agents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	contextmanager "water-ai/llm/context_manager"
+)
+
+func TestMapToBlockReadsImageSize(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 320, 240)))
+	item := map[string]interface{}{
+		"type": "image",
+		"source": map[string]interface{}{
+			"type":       "base64",
+			"media_type": "image/png",
+			"data":       base64.StdEncoding.EncodeToString(buf.Bytes()),
+		},
+	}
+
+	text := func(s string) contextmanager.ContentBlock { return contextmanager.TextPrompt{Text: s} }
+	got := mapToBlock(item, text)
+	if got != (contextmanager.ImageBlock{Width: 320, Height: 240}) {
+		t.Errorf("mapToBlock() = %#v; want a 320x240 ImageBlock", got)
+	}
+
+	item["source"] = map[string]interface{}{"data": "not base64!"}
+	if got := mapToBlock(item, text); got != (contextmanager.ImageBlock{}) {
+		t.Errorf("mapToBlock() with bad data = %#v; want an ImageBlock of unknown size", got)
+	}
+}
+
+func budgetedTestConfig() *contextmanager.Config {
+	return &contextmanager.Config{
+		TokenBudget:    1000,
+		MaxSize:        100,
+		MaxEventLength: contextmanager.DefaultMaxEventLength,
+		Budget:         &contextmanager.BudgetAllocation{SystemPercent: 20, RecentTurnsPercent: 50, ToolResultsPercent: 30},
+	}
+}
+
+func TestApplyTruncationKeepsTrimmedToolResults(t *testing.T) {
+	output := strings.Repeat("é", 1500)
+	messages := []Message{
+		{Role: "user", Content: "list the files"},
+		{Role: "assistant", Content: []interface{}{ToolCallParameters{ID: "call-1", Name: "terminal_execute"}}},
+		{Role: "user", Content: []map[string]interface{}{
+			{"type": "tool_result", "tool_call_id": "call-1", "tool_name": "terminal_execute", "result": output},
+		}},
+	}
+
+	cm := NewLLMContextManager(&scriptedLLMClient{}, nil, budgetedTestConfig())
+	got := cm.ApplyTruncationIfNeeded(messages)
+
+	if len(got) != len(messages) {
+		t.Fatalf("ApplyTruncationIfNeeded() returned %d messages; want %d", len(got), len(messages))
+	}
+	result, _ := got[2].Content.([]map[string]interface{})[0]["result"].(string)
+	if !strings.HasSuffix(result, contextmanager.TrimmedToolResultMarker) || len(result) >= len(output) {
+		t.Errorf("tool result of %d bytes was not trimmed", len(result))
+	}
+	if !utf8.ValidString(result) {
+		t.Error("trimmed tool result is not valid UTF-8")
+	}
+	if id := got[2].Content.([]map[string]interface{})[0]["tool_call_id"]; id != "call-1" {
+		t.Errorf("tool_call_id = %v; want call-1", id)
+	}
+	if messages[2].Content.([]map[string]interface{})[0]["result"] != output {
+		t.Error("the caller's messages were modified")
+	}
+}
+
+func TestReserveSystemTokensShrinksHistoryBudget(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: strings.Repeat("first task ", 50)},
+		{Role: "assistant", Content: strings.Repeat("done ", 50)},
+		{Role: "user", Content: strings.Repeat("second task ", 50)},
+		{Role: "assistant", Content: strings.Repeat("done again ", 50)},
+		{Role: "user", Content: "and now?"},
+	}
+
+	summarized := func(got []Message) bool {
+		for _, msg := range got {
+			if s, ok := msg.Content.(string); ok && strings.HasPrefix(s, summaryPrefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	cm := NewLLMContextManager(&scriptedLLMClient{summary: "two tasks done"}, nil, budgetedTestConfig())
+	if summarized(cm.ApplyTruncationIfNeeded(messages)) {
+		t.Fatal("history within budget was summarized")
+	}
+
+	cm.ReserveSystemTokens(strings.Repeat("system ", 400), NewAgentToolManager(nil))
+	if !summarized(cm.ApplyTruncationIfNeeded(messages)) {
+		t.Error("history was not summarized after reserving a large system prompt")
+	}
+}
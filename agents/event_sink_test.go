@@ -0,0 +1,98 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type savedEvent struct {
+	sessionID, eventType string
+}
+
+// fakeSink records saved events, failing every save when err is set.
+type fakeSink struct {
+	mu     sync.Mutex
+	events []savedEvent
+	err    error
+}
+
+func (s *fakeSink) Save(sessionID, eventType string, payload interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, savedEvent{sessionID, eventType})
+	return s.err
+}
+
+func (s *fakeSink) saved() []savedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]savedEvent(nil), s.events...)
+}
+
+// countingWebSocket counts the messages forwarded to the client.
+type countingWebSocket struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (w *countingWebSocket) SendJSON(v interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sent++
+	return nil
+}
+
+func (w *countingWebSocket) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sent
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the message processor")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMessageProcessingSavesEventsInOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		sinkErr error
+	}{
+		{"saved", nil},
+		{"sink failing", errors.New("database is locked")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeSink{err: tt.sinkErr}
+			ws := &countingWebSocket{}
+			agent := newTestAgent(&scriptedLLMClient{}, &sliceHistory{}, nil, nil)
+			agent.EventSink = sink
+			agent.Websocket = ws
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			agent.StartMessageProcessing(ctx)
+
+			types := []string{EventTypeUserMessage, EventTypeToolCall, EventTypeToolResult, EventTypeAgentResponse}
+			for _, typ := range types {
+				agent.emitEvent(typ, map[string]interface{}{})
+			}
+			waitUntil(t, func() bool { return len(sink.saved()) == len(types) && ws.count() == len(types)-1 })
+
+			for i, e := range sink.saved() {
+				if e.eventType != types[i] || e.sessionID != "test-session" {
+					t.Errorf("saved event %d = %+v; want %s for test-session", i, e, types[i])
+				}
+			}
+		})
+	}
+}
@@ -3,12 +3,18 @@ package agents
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
-	"sort"
+	"reflect"
 	"strings"
+	"time"
+
+	"water-ai/core/config"
+	"water-ai/metrics"
+	"water-ai/tools"
 )
 
 const (
@@ -17,6 +23,15 @@ const (
 	ToolCallInterruptFakeRsp     = "Tool execution interrupted by user. You can resume by providing a new instruction."
 	AgentInterruptFakeRsp        = "Agent interrupted by user. You can resume by providing a new instruction."
 	CompleteMessage              = "Task Completed"
+	TokenBudgetExceededMsg       = "Agent stopped: token budget exhausted."
+	PlanOnlyToolResult           = "[PLAN ONLY] The tool was not run. Assume it succeeded and plan the next step."
+	ToolRejectedMsg              = "The user rejected this tool call. Do not retry it; ask the user or try another approach."
+)
+
+// Components label the metrics recorded by agents.
+const (
+	metricsComponentAgent    = "agent"
+	metricsComponentReviewer = "reviewer"
 )
 
 // SystemPromptBuilder interface
@@ -28,27 +43,55 @@ type FunctionCallAgent struct {
 	BaseAgent
 	SystemPromptBuilder SystemPromptBuilder
 	Client              LLMClient
-	Tools               []LLMTool
+	ToolManager         ToolManager
 	History             MessageHistory
 	WorkspaceManager    WorkspaceManager
 	MessageQueue        chan RealtimeEvent
 	Logger              *log.Logger
+	ContextManager      ContextManager
 	MaxOutputTokens     int
 	MaxTurns            int
+	// TokenBudget caps the input+output tokens the agent may spend over the
+	// session; 0 means unlimited.
+	TokenBudget         int
+	// RequestID is the correlation ID of the request driving the agent;
+	// error events carry it as request_id.
+	RequestID           string
+	// TurnTimeout bounds each turn's model call and tool execution; 0
+	// means no bound. After MaxConsecutiveTimeouts timed-out turns in a
+	// row the run fails with ErrTurnTimeout.
+	TurnTimeout            time.Duration
+	MaxConsecutiveTimeouts int
+	// EventSink persists the agent's events; nil keeps them in memory
+	// only.
+	EventSink           EventSink
+	// PlanOnly records the tool calls the model makes instead of running
+	// them, so a run produces a plan without side effects.
+	PlanOnly            bool
+	// Approver is asked before running tools whose ToolParam has
+	// RequiresApproval set. Without one, such tools are never run.
+	Approver            Approver
 	Websocket           WebSocket
+	// MinimizeStdoutLogs drops the decorative turn delimiters, token counts
+	// and planning text from Logger, keeping warnings and errors.
+	MinimizeStdoutLogs  bool
 	
 	interrupted         bool
 	sessionID           string
+	tokensUsed          int
+	timeouts            int
+	plan                []ToolCallParameters
 }
 
 func NewFunctionCallAgent(
 	systemPromptBuilder SystemPromptBuilder,
 	client LLMClient,
-	tools []LLMTool,
+	toolManager ToolManager,
 	initHistory MessageHistory,
 	workspaceManager WorkspaceManager,
 	messageQueue chan RealtimeEvent,
 	logger *log.Logger,
+	contextManager ContextManager,
 	maxOutputTokens int,
 	maxTurns int,
 	websocket WebSocket,
@@ -73,18 +116,39 @@ try breaking down the task into smaller steps. After call this tool to update or
 		},
 		SystemPromptBuilder: systemPromptBuilder,
 		Client:              client,
-		Tools:               tools,
+		ToolManager:         toolManager,
 		History:             initHistory,
 		WorkspaceManager:    workspaceManager,
 		MessageQueue:        messageQueue,
 		Logger:              logger,
+		ContextManager:      contextManager,
 		MaxOutputTokens:     maxOutputTokens,
 		MaxTurns:            maxTurns,
+		MaxConsecutiveTimeouts: DefaultMaxConsecutiveTimeouts,
 		Websocket:           websocket,
 		sessionID:           workspaceManager.SessionID(),
 	}
 }
 
+// ApplyConfig sets the turn, output, token and time limits from cfg.
+func (a *FunctionCallAgent) ApplyConfig(cfg *config.WaterAgentConfig) {
+	a.MaxTurns = cfg.MaxTurns
+	a.MaxOutputTokens = cfg.MaxOutputTokensPerTurn
+	a.TokenBudget = cfg.TokenBudget
+	a.TurnTimeout = cfg.TurnTimeout()
+}
+
+// TokensUsed returns the input+output tokens spent so far in the session.
+func (a *FunctionCallAgent) TokensUsed() int {
+	return a.tokensUsed
+}
+
+// SetTokensUsed counts tokens spent before this agent was built, e.g. by
+// earlier queries of the session, against TokenBudget.
+func (a *FunctionCallAgent) SetTokensUsed(tokens int) {
+	a.tokensUsed = tokens
+}
+
 func (a *FunctionCallAgent) StartMessageProcessing(ctx context.Context) {
 	go func() {
 		defer a.Logger.Println("Message processor stopped")
@@ -93,12 +157,7 @@ func (a *FunctionCallAgent) StartMessageProcessing(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case msg := <-a.MessageQueue:
-				// Note: Database saving would happen here using Events.SaveEvent
-				if a.sessionID != "" {
-					// Events.SaveEvent(a.sessionID, msg)
-				} else {
-					a.Logger.Printf("No session ID, skipping event save: %v", msg)
-				}
+				a.saveEvent(msg)
 
 				if msg.Type != EventTypeUserMessage && a.Websocket != nil {
 					if err := a.Websocket.SendJSON(msg); err != nil {
@@ -111,23 +170,19 @@ func (a *FunctionCallAgent) StartMessageProcessing(ctx context.Context) {
 	}()
 }
 
-func (a *FunctionCallAgent) validateToolParameters() ([]ToolParam, error) {
-	var params []ToolParam
-	names := make([]string, 0)
-
-	for _, tool := range a.Tools {
-		p := tool.GetToolParam()
-		params = append(params, p)
-		names = append(names, p.Name)
+// saveEvent persists msg through the EventSink. Failures are logged so a
+// database problem does not stop events reaching the client.
+func (a *FunctionCallAgent) saveEvent(msg RealtimeEvent) {
+	if a.EventSink == nil {
+		return
 	}
-
-	sort.Strings(names)
-	for i := 0; i < len(names)-1; i++ {
-		if names[i] == names[i+1] {
-			return nil, fmt.Errorf("tool %s is duplicated", names[i])
-		}
+	if a.sessionID == "" {
+		a.Logger.Printf("No session ID, skipping event save: %v", msg)
+		return
+	}
+	if err := a.EventSink.Save(a.sessionID, msg.Type, msg); err != nil {
+		a.Logger.Printf("Failed to save %s event: %v", msg.Type, err)
 	}
-	return params, nil
 }
 
 // encodeImage Helper (simulates ii_agent.tools.utils.encode_image)
@@ -141,6 +196,9 @@ func encodeImage(path string) (string, error) {
 
 func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
 	instruction, _ := toolInput["instruction"].(string)
+	if orientation, _ := toolInput["orientation_instruction"].(string); orientation != "" {
+		instruction += "\n\n" + orientation
+	}
 	
 	// Handle Files Input
 	var files []string
@@ -155,7 +213,7 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 	}
 
 	delimiter := "--------------------------------------------- USER INPUT ---------------------------------------------\n" + instruction
-	a.Logger.Printf("\n%s\n", delimiter)
+	a.logVerbose("\n%s\n", delimiter)
 
 	var imageBlocks []interface{}
 
@@ -164,7 +222,7 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 		for _, file := range files {
 			relPath := a.WorkspaceManager.RelativePath(file)
 			instruction += fmt.Sprintf(" - %s\n", relPath)
-			a.Logger.Printf("Attached file: %s", relPath)
+			a.logVerbose("Attached file: %s", relPath)
 
 			// Process images
 			ext := ""
@@ -193,15 +251,17 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 
 	a.History.AddUserPrompt(instruction, imageBlocks)
 	a.interrupted = false
+	a.timeouts = 0
+	a.plan = nil
 
 	remainingTurns := a.MaxTurns
 	for remainingTurns > 0 {
-		a.History.Truncate()
+		a.truncateHistory()
 		remainingTurns--
 
-		a.Logger.Println("\n--------------------------------------------- NEW TURN ---------------------------------------------")
+		a.logVerbose("\n--------------------------------------------- NEW TURN ---------------------------------------------")
 
-		toolParams, err := a.validateToolParameters()
+		toolParams, err := toolParams(a.ToolManager)
 		if err != nil {
 			return ToolImplOutput{}, err
 		}
@@ -211,20 +271,38 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 			return ToolImplOutput{ToolOutput: AgentInterruptMsg, ToolResultMessage: AgentInterruptMsg}, nil
 		}
 
-		a.Logger.Printf("(Current token count: %d)\n", a.History.CountTokens())
+		if a.TokenBudget > 0 && a.tokensUsed >= a.TokenBudget {
+			return a.stopForBudget(), nil
+		}
+
+		a.logVerbose("(Current token count: %d)\n", a.History.CountTokens())
+		metrics.AgentTurns.Inc(metricsComponentAgent)
 
 		// Generate
-		modelResponse, err := a.Client.Generate(
-			ctx,
-			a.History.GetMessagesForLLM(),
-			a.MaxOutputTokens,
-			toolParams,
-			a.SystemPromptBuilder.GetSystemPrompt(),
-		)
+		turnCtx, cancelTurn := turnContext(ctx, a.TurnTimeout)
+		callStarted := time.Now()
+		messages := a.History.GetMessagesForLLM()
+		modelResponse, err := runInTurn(turnCtx, func(ctx context.Context, guard *turnGuard) ([]interface{}, error) {
+			if streaming, ok := a.Client.(StreamingLLMClient); ok {
+				return a.streamResponse(ctx, guard, streaming, messages, toolParams)
+			}
+			return a.Client.Generate(ctx, messages, a.MaxOutputTokens, toolParams, a.SystemPromptBuilder.GetSystemPrompt())
+		})
+		metrics.LLMCallDuration.Observe(time.Since(callStarted).Seconds(), metricsComponentAgent)
 
+		if turnTimedOut(ctx, turnCtx) {
+			cancelTurn()
+			if err := a.recordTimeout("Model call"); err != nil {
+				return ToolImplOutput{ToolOutput: err.Error()}, err
+			}
+			continue
+		}
 		if err != nil {
+			cancelTurn()
+			a.emitEvent(EventTypeError, map[string]interface{}{"message": fmt.Sprintf("Error calling LLM: %v", err)})
 			return ToolImplOutput{ToolOutput: "Error calling LLM"}, err
 		}
+		a.recordUsage(modelResponse)
 
 		if len(modelResponse) == 0 {
 			modelResponse = []interface{}{TextResult{Text: CompleteMessage}}
@@ -235,11 +313,15 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 		// Check if we are done (no tools called)
 		pendingTools := a.History.GetPendingToolCalls()
 		if len(pendingTools) == 0 {
-			a.Logger.Println("[no tools were called]")
-			a.emitEvent(EventTypeAgentResponse, map[string]interface{}{"text": "Task completed"})
+			cancelTurn()
+			a.logVerbose("[no tools were called]")
+			if a.PlanOnly {
+				return a.finishPlan(), nil
+			}
+			a.emitEvent(EventTypeAgentResponse, map[string]interface{}{"text": CompleteMessage})
 			return ToolImplOutput{
 				ToolOutput: a.History.GetLastAssistantTextResponse(),
-				ToolResultMessage: "Task completed",
+				ToolResultMessage: CompleteMessage,
 			}, nil
 		}
 
@@ -258,57 +340,102 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 				}
 				formatted := fmt.Sprintf("```Thinking:\n%s\n```", strings.TrimSpace(wrappedThinking))
 				
-				a.Logger.Printf("Top-level agent planning next step: %s\n", formatted)
+				a.logVerbose("Top-level agent planning next step: %s\n", formatted)
 				a.emitEvent(EventTypeAgentThinking, map[string]interface{}{"text": formatted})
 			} else if tr, ok := item.(TextResult); ok {
-				a.Logger.Printf("Top-level agent planning next step: %s\n", tr.Text)
+				a.logVerbose("Top-level agent planning next step: %s\n", tr.Text)
 				a.emitEvent(EventTypeAgentThinking, map[string]interface{}{"text": tr.Text})
 			}
 		}
 
 		if len(pendingTools) > 1 {
+			cancelTurn()
 			return ToolImplOutput{}, errors.New("only one tool call per turn is supported")
 		}
 
 		toolCall := pendingTools[0]
+		inputErr := validateToolCall(toolParams, toolCall)
+		gated := inputErr == nil && !a.PlanOnly && requiresApproval(toolParams, toolCall)
 		a.emitEvent(EventTypeToolCall, map[string]interface{}{
-			"tool_call_id": toolCall.ID,
-			"tool_name":    toolCall.Name,
-			"tool_input":   toolCall.Arguments,
+			"tool_call_id":      toolCall.ID,
+			"tool_name":         toolCall.Name,
+			"tool_input":        toolCall.Arguments,
+			"awaiting_approval": gated,
 		})
 
 		// Handle interruption before tool run
 		if a.interrupted {
+			cancelTurn()
 			a.addToolCallResult(toolCall, ToolResultInterruptMsg)
 			a.addFakeAssistantTurn(ToolCallInterruptFakeRsp)
 			return ToolImplOutput{ToolOutput: ToolResultInterruptMsg, ToolResultMessage: ToolResultInterruptMsg}, nil
 		}
 
-		// Execute Tool
-		var selectedTool LLMTool
-		for _, t := range a.Tools {
-			if t.GetToolParam().Name == toolCall.Name {
-				selectedTool = t
-				break
+		// Reject malformed arguments before the tool sees them so the model
+		// can correct the call on its next turn.
+		if inputErr != nil {
+			cancelTurn()
+			a.Logger.Printf("Tool input validation failed: %v", inputErr)
+			a.addToolCallResult(toolCall, inputErr.Error())
+			continue
+		}
+
+		if a.PlanOnly {
+			cancelTurn()
+			a.plan = append(a.plan, toolCall)
+			a.addToolCallResult(toolCall, PlanOnlyToolResult)
+			if isFinal(toolParams, toolCall) {
+				return a.finishPlan(), nil
 			}
+			continue
 		}
 
-		var toolOutput ToolImplOutput
-		if selectedTool != nil {
-			toolOutput, err = selectedTool.Run(ctx, toolCall.Arguments, a.History)
+		if gated {
+			// The user may take longer than a turn to answer, so the turn
+			// timeout restarts once the call is approved.
+			cancelTurn()
+			approved, err := a.awaitApproval(ctx, toolCall)
 			if err != nil {
-				// Log error, but return generic failure string to history
-				a.Logger.Printf("Tool execution error: %v", err)
-				toolOutput = ToolImplOutput{
-					ToolOutput: fmt.Sprintf("Error executing tool: %v", err),
-					IsFinal: false,
-				}
+				a.addToolCallResult(toolCall, ToolResultInterruptMsg)
+				a.addFakeAssistantTurn(ToolCallInterruptFakeRsp)
+				return ToolImplOutput{ToolOutput: ToolResultInterruptMsg, ToolResultMessage: ToolResultInterruptMsg}, nil
+			}
+			if !approved {
+				a.addToolCallResult(toolCall, ToolRejectedMsg)
+				continue
+			}
+			turnCtx, cancelTurn = turnContext(ctx, a.TurnTimeout)
+		}
+
+		// Execute Tool
+		sink := a.toolOutputSink(toolCall)
+		toolOutput, err := runInTurn(turnCtx, func(ctx context.Context, guard *turnGuard) (ToolImplOutput, error) {
+			ctx = tools.WithOutputSink(ctx, func(stream, line string) {
+				guard.do(func() { sink(stream, line) })
+			})
+			return a.ToolManager.RunTool(ctx, toolCall, guard.history(a.History))
+		})
+		timedOut := turnTimedOut(ctx, turnCtx)
+		if timedOut {
+			toolOutput = ToolImplOutput{ToolOutput: fmt.Sprintf("Error executing tool: %s did not finish within %s", toolCall.Name, a.TurnTimeout)}
+		} else if err != nil {
+			// Log error, but return generic failure string to history
+			a.Logger.Printf("Tool execution error: %v", err)
+			toolOutput = ToolImplOutput{
+				ToolOutput: fmt.Sprintf("Error executing tool: %v", err),
+				IsFinal: false,
 			}
-		} else {
-			toolOutput = ToolImplOutput{ToolOutput: "Tool not found", IsFinal: false}
 		}
+		cancelTurn()
 
 		a.addToolCallResult(toolCall, toolOutput.ToolOutput)
+		if timedOut {
+			if err := a.recordTimeout("Tool " + toolCall.Name); err != nil {
+				return ToolImplOutput{ToolOutput: err.Error()}, err
+			}
+			continue
+		}
+		a.timeouts = 0
 		
 		// Check for Final Answer (should_stop logic)
 		if toolOutput.IsFinal {
@@ -317,25 +444,145 @@ func (a *FunctionCallAgent) Run(ctx context.Context, toolInput map[string]interf
 			a.addFakeAssistantTurn(finalAnswer)
 			return ToolImplOutput{
 				ToolOutput: finalAnswer,
-				ToolResultMessage: "Task completed",
+				ToolResultMessage: CompleteMessage,
 			}, nil
 		}
 	}
 
+	if a.PlanOnly {
+		return a.finishPlan(), nil
+	}
 	agentAnswer := "Agent did not complete after max turns"
 	a.emitEvent(EventTypeAgentResponse, map[string]interface{}{"text": agentAnswer})
 	return ToolImplOutput{ToolOutput: agentAnswer, ToolResultMessage: agentAnswer}, nil
 }
 
+// awaitApproval blocks until the user approves or denies call. Without an
+// Approver the call is denied.
+func (a *FunctionCallAgent) awaitApproval(ctx context.Context, call ToolCallParameters) (bool, error) {
+	if a.Approver == nil {
+		a.Logger.Printf("No approver for %s; denying it", call.Name)
+		return false, nil
+	}
+	a.Logger.Printf("Waiting for approval of %s (%s)", call.Name, call.ID)
+	return a.Approver.AwaitApproval(ctx, call)
+}
+
+// Plan returns the tool calls recorded by the last plan-only run.
+func (a *FunctionCallAgent) Plan() []ToolCallParameters {
+	return a.plan
+}
+
+// finishPlan ends a plan-only run, returning the recorded tool calls as a
+// numbered list followed by the model's closing remarks.
+func (a *FunctionCallAgent) finishPlan() ToolImplOutput {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Plan (%d steps):\n", len(a.plan))
+	for i, call := range a.plan {
+		args, _ := json.Marshal(call.Arguments)
+		fmt.Fprintf(&sb, "%d. %s %s\n", i+1, call.Name, args)
+	}
+	if text := a.History.GetLastAssistantTextResponse(); text != "" {
+		sb.WriteString("\n" + text)
+	}
+	plan := strings.TrimSpace(sb.String())
+	a.emitEvent(EventTypeAgentResponse, map[string]interface{}{"text": plan, "plan_only": true})
+	return ToolImplOutput{ToolOutput: plan, ToolResultMessage: CompleteMessage}
+}
+
+// recordUsage adds the tokens of the last Generate call to the session
+// total, estimating them when the client does not report usage.
+func (a *FunctionCallAgent) recordUsage(response []interface{}) {
+	if reporter, ok := a.Client.(UsageReporter); ok {
+		usage := reporter.LastUsage()
+		a.tokensUsed += usage.InputTokens + usage.OutputTokens
+		metrics.RecordTokens(metricsComponentAgent, usage.InputTokens, usage.OutputTokens)
+		return
+	}
+
+	input := a.History.CountTokens()
+	if a.ContextManager != nil {
+		input = a.ContextManager.CountTokens(a.History.GetMessagesForLLM())
+	}
+	output := 0
+	for _, item := range response {
+		switch v := item.(type) {
+		case TextResult:
+			output += len(v.Text) / 4
+		case ThinkingBlock:
+			output += len(v.Thinking) / 4
+		case ToolCallParameters:
+			js, _ := json.Marshal(v.Arguments)
+			output += len(js) / 4
+		}
+	}
+	a.tokensUsed += input + output
+	metrics.RecordTokens(metricsComponentAgent, input, output)
+}
+
+// recordTimeout notes that what timed out, telling the client the turn was
+// abandoned. It returns ErrTurnTimeout once MaxConsecutiveTimeouts turns in
+// a row have timed out.
+func (a *FunctionCallAgent) recordTimeout(what string) error {
+	a.timeouts++
+	message := fmt.Sprintf("%s timed out after %s; turn abandoned (%d in a row).", what, a.TurnTimeout, a.timeouts)
+	a.Logger.Println(message)
+	if a.MaxConsecutiveTimeouts > 0 && a.timeouts >= a.MaxConsecutiveTimeouts {
+		err := fmt.Errorf("%w: %d turns in a row", ErrTurnTimeout, a.timeouts)
+		a.emitEvent(EventTypeError, map[string]interface{}{"message": fmt.Sprintf("Agent stopped: %v", err)})
+		return err
+	}
+	a.emitEvent(EventTypeSystem, map[string]interface{}{"message": message})
+	return nil
+}
+
+// stopForBudget ends the run once the token budget is spent, telling the
+// client why.
+func (a *FunctionCallAgent) stopForBudget() ToolImplOutput {
+	message := fmt.Sprintf("%s Used %d of %d tokens.", TokenBudgetExceededMsg, a.tokensUsed, a.TokenBudget)
+	a.Logger.Println(message)
+	a.emitEvent(EventTypeSystem, map[string]interface{}{
+		"message":      message,
+		"tokens_used":  a.tokensUsed,
+		"token_budget": a.TokenBudget,
+	})
+	a.addFakeAssistantTurn(message)
+	return ToolImplOutput{ToolOutput: message, ToolResultMessage: TokenBudgetExceededMsg}
+}
+
+// truncateHistory summarizes the history through the ContextManager when it
+// grows past the token budget, replacing it in place. Without a
+// ContextManager it falls back to the history's own Truncate.
+func (a *FunctionCallAgent) truncateHistory() {
+	if a.ContextManager == nil {
+		a.History.Truncate()
+		return
+	}
+
+	messages := a.History.GetMessagesForLLM()
+	truncated := a.ContextManager.ApplyTruncationIfNeeded(messages)
+	if len(truncated) != len(messages) {
+		a.Logger.Printf("History truncated from %d to %d messages", len(messages), len(truncated))
+		a.History.SetMessages(truncated)
+	} else if !reflect.DeepEqual(truncated, messages) {
+		a.Logger.Printf("Tool results in history trimmed to fit the context budget")
+		a.History.SetMessages(truncated)
+	}
+}
+
 // RunAgent is the convenience wrapper (mimics run_agent logic)
+// With resume, the instruction continues the conversation in the history
+// instead of starting over.
 func (a *FunctionCallAgent) RunAgent(instruction string, files []string, resume bool, orientationInstruction string) (string, error) {
-	// Reset tool logic if implemented via manager
-	// a.ToolManager.Reset()
-
-	if !resume {
+	if resume {
+		if err := prepareResume(a.History); err != nil {
+			return "", err
+		}
+	} else {
+		a.ToolManager.Reset()
 		a.History.Clear()
-		a.interrupted = false
 	}
+	a.interrupted = false
 
 	toolInput := map[string]interface{}{
 		"instruction": instruction,
@@ -349,7 +596,18 @@ func (a *FunctionCallAgent) RunAgent(instruction string, files []string, resume
 	return output.ToolOutput, err
 }
 
+// logVerbose logs progress detail that MinimizeStdoutLogs suppresses.
+func (a *FunctionCallAgent) logVerbose(format string, args ...interface{}) {
+	if a.MinimizeStdoutLogs {
+		return
+	}
+	a.Logger.Printf(format, args...)
+}
+
 func (a *FunctionCallAgent) emitEvent(eventType string, content map[string]interface{}) {
+	if eventType == EventTypeError && a.RequestID != "" {
+		content["request_id"] = a.RequestID
+	}
 	a.MessageQueue <- RealtimeEvent{
 		Type:    eventType,
 		Content: content,
@@ -365,6 +623,20 @@ func (a *FunctionCallAgent) addToolCallResult(toolCall ToolCallParameters, resul
 	})
 }
 
+// toolOutputSink streams a running tool's output lines to the client as
+// partial tool results so the terminal panel updates live.
+func (a *FunctionCallAgent) toolOutputSink(toolCall ToolCallParameters) tools.OutputSink {
+	return func(stream, line string) {
+		a.emitEvent(EventTypeToolResult, map[string]interface{}{
+			"tool_call_id": toolCall.ID,
+			"tool_name":    toolCall.Name,
+			"stream":       stream,
+			"result":       line,
+			"partial":      true,
+		})
+	}
+}
+
 func (a *FunctionCallAgent) addFakeAssistantTurn(text string) {
 	a.History.AddAssistantTurn([]interface{}{TextResult{Text: text}})
 	evtType := EventTypeAgentResponse
@@ -0,0 +1,316 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	contextmanager "water-ai/llm/context_manager"
+)
+
+// sliceHistory is a minimal in-memory MessageHistory for agent loop tests.
+type sliceHistory struct {
+	messages []Message
+}
+
+func (h *sliceHistory) AddUserPrompt(prompt string, images []interface{}) {
+	h.messages = append(h.messages, Message{Role: "user", Content: prompt})
+}
+
+func (h *sliceHistory) AddAssistantTurn(responses []interface{}) {
+	h.messages = append(h.messages, Message{Role: "assistant", Content: responses})
+}
+
+func (h *sliceHistory) AddToolCallResult(toolCall ToolCallParameters, result string) {
+	h.messages = append(h.messages, Message{Role: "user", Content: []map[string]interface{}{
+		{"type": "tool_result", "tool_call_id": toolCall.ID, "result": result},
+	}})
+}
+
+func (h *sliceHistory) GetMessagesForLLM() []Message {
+	out := make([]Message, len(h.messages))
+	copy(out, h.messages)
+	return out
+}
+
+func (h *sliceHistory) SetMessages(messages []Message) {
+	h.messages = messages
+}
+
+func (h *sliceHistory) GetPendingToolCalls() []ToolCallParameters {
+	if len(h.messages) == 0 {
+		return nil
+	}
+	last := h.messages[len(h.messages)-1]
+	items, ok := last.Content.([]interface{})
+	if last.Role != "assistant" || !ok {
+		return nil
+	}
+	var calls []ToolCallParameters
+	for _, item := range items {
+		if tc, ok := item.(ToolCallParameters); ok {
+			calls = append(calls, tc)
+		}
+	}
+	return calls
+}
+
+func (h *sliceHistory) GetLastAssistantTextResponse() string {
+	for i := len(h.messages) - 1; i >= 0; i-- {
+		if items, ok := h.messages[i].Content.([]interface{}); ok && h.messages[i].Role == "assistant" {
+			for _, item := range items {
+				if tr, ok := item.(TextResult); ok {
+					return tr.Text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (h *sliceHistory) Clear()               { h.messages = nil }
+func (h *sliceHistory) Truncate()            {}
+func (h *sliceHistory) CountTokens() int     { return 0 }
+func (h *sliceHistory) IsNextTurnUser() bool { return true }
+
+// scriptedLLMClient returns queued responses in order and records every call.
+type scriptedLLMClient struct {
+	mu        sync.Mutex
+	responses [][]interface{}
+	calls     [][]Message
+	// summary is returned for tool-less calls (context summarization).
+	summary string
+}
+
+func (c *scriptedLLMClient) Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, messages)
+	if tools == nil && c.summary != "" {
+		return []interface{}{TextResult{Text: c.summary}}, nil
+	}
+	if len(c.responses) == 0 {
+		return []interface{}{TextResult{Text: "done"}}, nil
+	}
+	next := c.responses[0]
+	c.responses = c.responses[1:]
+	return next, nil
+}
+
+type staticPrompt string
+
+func (p staticPrompt) GetSystemPrompt() string { return string(p) }
+
+func newTestAgent(client LLMClient, history MessageHistory, cm ContextManager, tools []LLMTool) *FunctionCallAgent {
+	return NewFunctionCallAgent(
+		staticPrompt("system"),
+		client,
+		NewAgentToolManager(tools),
+		history,
+		&mockWorkspaceManager{},
+		make(chan RealtimeEvent, 100),
+		log.New(io.Discard, "", 0),
+		cm,
+		1024,
+		5,
+		nil,
+	)
+}
+
+func TestFunctionCallAgentSummarizesHistoryWithTinyBudget(t *testing.T) {
+	history := &sliceHistory{}
+	history.AddUserPrompt("Original task: build a website", nil)
+	for i := 0; i < 10; i++ {
+		history.AddAssistantTurn([]interface{}{TextResult{Text: strings.Repeat("working on it ", 20)}})
+		history.AddUserPrompt(strings.Repeat("keep going ", 20), nil)
+	}
+	before := len(history.messages)
+
+	client := &scriptedLLMClient{summary: "the agent built half a website"}
+	cm := NewLLMContextManager(client, nil, &contextmanager.Config{
+		TokenBudget:    50,
+		MaxSize:        100,
+		MaxEventLength: contextmanager.DefaultMaxEventLength,
+	})
+	agent := newTestAgent(client, history, cm, nil)
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "finish it"}, history); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(history.messages) >= before {
+		t.Errorf("history length = %d; want fewer than %d", len(history.messages), before)
+	}
+
+	found := false
+	for _, msg := range history.messages {
+		if s, ok := msg.Content.(string); ok && strings.HasPrefix(s, summaryPrefix) {
+			found = true
+			if !strings.Contains(s, "the agent built half a website") {
+				t.Errorf("summary = %q; want LLM summary text", s)
+			}
+		}
+	}
+	if !found {
+		t.Error("history should contain a conversation summary message")
+	}
+
+	if first, _ := history.messages[0].Content.(string); first != "Original task: build a website" {
+		t.Errorf("first message = %q; want original task preserved", first)
+	}
+
+	// The summarization request and the agent turn both go through the agent's client.
+	if len(client.calls) < 2 {
+		t.Errorf("client calls = %d; want summary + turn", len(client.calls))
+	}
+}
+
+func TestFunctionCallAgentWithoutContextManagerKeepsHistory(t *testing.T) {
+	history := &sliceHistory{}
+	client := &scriptedLLMClient{}
+	agent := newTestAgent(client, history, nil, nil)
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "hello"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.ToolOutput != "done" {
+		t.Errorf("ToolOutput = %q; want done", out.ToolOutput)
+	}
+	if len(history.messages) != 2 {
+		t.Errorf("history length = %d; want 2", len(history.messages))
+	}
+}
+
+// meteredLLMClient always calls the "mock" tool and reports a fixed usage
+// per call.
+type meteredLLMClient struct {
+	calls int
+	usage TokenUsage
+}
+
+func (c *meteredLLMClient) Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error) {
+	c.calls++
+	return []interface{}{ToolCallParameters{ID: "call", Name: "mock", Arguments: map[string]interface{}{}}}, nil
+}
+
+func (c *meteredLLMClient) LastUsage() TokenUsage { return c.usage }
+
+func TestFunctionCallAgentStopsAtTokenBudget(t *testing.T) {
+	history := &sliceHistory{}
+	client := &meteredLLMClient{usage: TokenUsage{InputTokens: 80, OutputTokens: 20}}
+	agent := newTestAgent(client, history, nil, []LLMTool{&mockLLMTool{}})
+	agent.MaxTurns = 50
+	agent.TokenBudget = 150
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "loop forever"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("LLM calls = %d; want 2 (stop once 150 tokens are spent)", client.calls)
+	}
+	if out.ToolResultMessage != TokenBudgetExceededMsg {
+		t.Errorf("ToolResultMessage = %q; want %q", out.ToolResultMessage, TokenBudgetExceededMsg)
+	}
+	if agent.TokensUsed() != 200 {
+		t.Errorf("TokensUsed() = %d; want 200", agent.TokensUsed())
+	}
+
+	var system map[string]interface{}
+	for len(agent.MessageQueue) > 0 {
+		evt := <-agent.MessageQueue
+		if evt.Type == EventTypeSystem {
+			system = evt.Content
+		}
+	}
+	if system == nil || !strings.Contains(system["message"].(string), "token budget") {
+		t.Errorf("system event = %v; want a token budget message", system)
+	}
+
+	// The budget is per session: a further run stops before calling the LLM.
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "more"}, history); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("LLM calls after exhausted budget = %d; want 2", client.calls)
+	}
+}
+
+func TestFunctionCallAgentZeroBudgetIsUnlimited(t *testing.T) {
+	history := &sliceHistory{}
+	client := &meteredLLMClient{usage: TokenUsage{InputTokens: 1000000}}
+	agent := newTestAgent(client, history, nil, []LLMTool{&mockLLMTool{}})
+	agent.TokenBudget = 0
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "loop"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if client.calls != agent.MaxTurns {
+		t.Errorf("LLM calls = %d; want MaxTurns (%d)", client.calls, agent.MaxTurns)
+	}
+	if out.ToolResultMessage == TokenBudgetExceededMsg {
+		t.Error("a zero budget should never stop the agent")
+	}
+}
+
+// failingLLMClient fails every call.
+type failingLLMClient struct{}
+
+func (failingLLMClient) Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error) {
+	return nil, errors.New("upstream unavailable")
+}
+
+func TestFunctionCallAgentErrorEventCarriesRequestID(t *testing.T) {
+	history := &sliceHistory{}
+	agent := newTestAgent(failingLLMClient{}, history, nil, nil)
+	agent.RequestID = "req-123"
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "hello"}, history); err == nil {
+		t.Fatal("Run() error = nil; want the LLM failure")
+	}
+
+	var errEvent *RealtimeEvent
+	for len(agent.MessageQueue) > 0 {
+		evt := <-agent.MessageQueue
+		if evt.Type == EventTypeError {
+			errEvent = &evt
+		}
+	}
+	if errEvent == nil {
+		t.Fatal("no error event emitted")
+	}
+	content := errEvent.Content
+	if content["request_id"] != "req-123" {
+		t.Errorf("request_id = %v; want req-123", content["request_id"])
+	}
+	if msg, _ := content["message"].(string); !strings.Contains(msg, "upstream unavailable") {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestFunctionCallAgentMinimizeStdoutLogs(t *testing.T) {
+	for _, minimize := range []bool{false, true} {
+		history := &sliceHistory{}
+		client := &scriptedLLMClient{responses: [][]interface{}{{TextResult{Text: "All done."}}}}
+		agent := newTestAgent(client, history, nil, nil)
+		var out bytes.Buffer
+		agent.Logger = log.New(&out, "", 0)
+		agent.MinimizeStdoutLogs = minimize
+
+		if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "say hi"}, history); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		for _, verbose := range []string{"USER INPUT", "NEW TURN", "Current token count", "no tools were called"} {
+			if got := strings.Contains(out.String(), verbose); got == minimize {
+				t.Errorf("MinimizeStdoutLogs = %v: logged %q = %v; want %v", minimize, verbose, got, !minimize)
+			}
+		}
+	}
+}
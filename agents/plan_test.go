@@ -0,0 +1,97 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlanOnlyRecordsToolCallsWithoutRunning(t *testing.T) {
+	tool := &schemaTool{}
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{TextResult{Text: "First list the files."}, ToolCallParameters{ID: "call-1", Name: "bash", Arguments: map[string]interface{}{"command": "ls"}}},
+		{ToolCallParameters{ID: "call-2", Name: "bash", Arguments: map[string]interface{}{"command": "rm -rf build"}}},
+		{TextResult{Text: "That cleans the build."}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{tool})
+	agent.PlanOnly = true
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "clean up"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if tool.ran {
+		t.Error("tool ran in plan-only mode")
+	}
+
+	plan := agent.Plan()
+	if len(plan) != 2 || plan[0].Arguments["command"] != "ls" || plan[1].Arguments["command"] != "rm -rf build" {
+		t.Fatalf("Plan() = %+v; want both bash calls", plan)
+	}
+	for _, want := range []string{"Plan (2 steps)", `1. bash {"command":"ls"}`, `2. bash {"command":"rm -rf build"}`, "That cleans the build."} {
+		if !strings.Contains(out.ToolOutput, want) {
+			t.Errorf("output = %q; want it to contain %q", out.ToolOutput, want)
+		}
+	}
+
+	// The model saw a simulated result for each call.
+	result, _ := client.calls[1][2].Content.([]map[string]interface{})
+	if len(result) != 1 || result[0]["result"] != PlanOnlyToolResult {
+		t.Errorf("tool result sent to the model = %v; want the plan-only notice", client.calls[1][2].Content)
+	}
+}
+
+// completeTool returns its answer argument.
+type completeTool struct{}
+
+func (t *completeTool) GetToolParam() ToolParam { return ToolParam{Name: "complete"} }
+
+func (t *completeTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	answer, _ := input["answer"].(string)
+	return ToolImplOutput{ToolOutput: answer}, nil
+}
+
+func TestPlanOnlyEndsAtFinalTool(t *testing.T) {
+	tool := &schemaTool{}
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "bash", Arguments: map[string]interface{}{"command": "make"}}},
+		{ToolCallParameters{ID: "call-2", Name: "complete", Arguments: map[string]interface{}{"answer": "Built."}}},
+		{ToolCallParameters{ID: "call-3", Name: "bash", Arguments: map[string]interface{}{"command": "make again"}}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{tool, MarkFinal(&completeTool{})})
+	agent.PlanOnly = true
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "build"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(client.calls) != 2 {
+		t.Errorf("LLM calls = %d; want the run to end at the planned complete call", len(client.calls))
+	}
+	plan := agent.Plan()
+	if len(plan) != 2 || plan[1].Name != "complete" {
+		t.Fatalf("Plan() = %+v; want the bash call and the complete call", plan)
+	}
+	if !strings.Contains(out.ToolOutput, `2. complete {"answer":"Built."}`) {
+		t.Errorf("output = %q; want the complete call as the last step", out.ToolOutput)
+	}
+}
+
+func TestFinalToolEndsRun(t *testing.T) {
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "complete", Arguments: map[string]interface{}{"answer": "Built."}}},
+		{ToolCallParameters{ID: "call-2", Name: "complete", Arguments: map[string]interface{}{"answer": "Again."}}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{MarkFinal(&completeTool{})})
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "build"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.ToolOutput != "Built." || len(client.calls) != 1 {
+		t.Errorf("output = %q after %d calls; want the first answer", out.ToolOutput, len(client.calls))
+	}
+}
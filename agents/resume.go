@@ -0,0 +1,25 @@
+package agents
+
+import "errors"
+
+// ErrNotResumable is returned when resuming a run whose history does not
+// end on an assistant turn, e.g. because it stopped on an unanswered user
+// prompt.
+var ErrNotResumable = errors.New("history cannot be resumed: the next turn is not the user's")
+
+// prepareResume readies history for a new instruction. Tool calls left
+// pending by an interrupted run are answered as interrupted, as Run does
+// when it is cancelled before a tool runs, so the model sees why they have
+// no output.
+func prepareResume(history MessageHistory) error {
+	if pending := history.GetPendingToolCalls(); len(pending) > 0 {
+		for _, call := range pending {
+			history.AddToolCallResult(call, ToolResultInterruptMsg)
+		}
+		history.AddAssistantTurn([]interface{}{TextResult{Text: ToolCallInterruptFakeRsp}})
+	}
+	if !history.IsNextTurnUser() {
+		return ErrNotResumable
+	}
+	return nil
+}
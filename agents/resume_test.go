@@ -0,0 +1,77 @@
+package agents
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunAgentResumesPendingToolCall(t *testing.T) {
+	history := NewHistory()
+	history.AddUserPrompt("build a site", nil)
+	history.AddAssistantTurn([]interface{}{ToolCallParameters{ID: "call-1", Name: "bash"}})
+
+	client := &scriptedLLMClient{}
+	agent := newTestAgent(client, history, nil, []LLMTool{&echoTool{name: "bash"}})
+	manager := &countingToolManager{AgentToolManager: NewAgentToolManager(agent.ToolManager.GetTools())}
+	agent.ToolManager = manager
+
+	out, err := agent.RunAgent("use python instead", nil, true, "")
+	if err != nil {
+		t.Fatalf("RunAgent(resume) error = %v", err)
+	}
+	if out != "done" {
+		t.Errorf("output = %q; want done", out)
+	}
+	if manager.resets != 0 {
+		t.Errorf("tool manager reset %d times on resume; want 0", manager.resets)
+	}
+
+	sent := client.calls[0]
+	if len(sent) != 5 {
+		t.Fatalf("messages sent = %d; want the old turns, the interrupted result, a reply and the new prompt", len(sent))
+	}
+	result, _ := sent[2].Content.([]map[string]interface{})
+	if len(result) != 1 || result[0]["tool_call_id"] != "call-1" || result[0]["result"] != ToolResultInterruptMsg {
+		t.Errorf("pending call answered with %v; want an interrupted result for call-1", sent[2].Content)
+	}
+	if sent[3].Role != "assistant" {
+		t.Errorf("message after the tool result has role %q; want assistant", sent[3].Role)
+	}
+	if prompt, _ := sent[4].Content.(string); prompt != "use python instead" {
+		t.Errorf("last message = %v; want the new instruction", sent[4].Content)
+	}
+}
+
+func TestRunAgentCleanStartClearsHistory(t *testing.T) {
+	history := NewHistory()
+	history.AddUserPrompt("old task", nil)
+	history.AddAssistantTurn([]interface{}{ToolCallParameters{ID: "call-1", Name: "bash"}})
+
+	client := &scriptedLLMClient{}
+	agent := newTestAgent(client, history, nil, nil)
+	if _, err := agent.RunAgent("new task", nil, false, ""); err != nil {
+		t.Fatalf("RunAgent() error = %v", err)
+	}
+
+	sent := client.calls[0]
+	if len(sent) != 1 {
+		t.Fatalf("messages sent = %d; want only the new instruction", len(sent))
+	}
+	if prompt, _ := sent[0].Content.(string); prompt != "new task" {
+		t.Errorf("first message = %v; want the new instruction", sent[0].Content)
+	}
+}
+
+func TestRunAgentRejectsUnresumableHistory(t *testing.T) {
+	history := NewHistory()
+	history.AddUserPrompt("unanswered", nil)
+
+	client := &scriptedLLMClient{}
+	agent := newTestAgent(client, history, nil, nil)
+	if _, err := agent.RunAgent("continue", nil, true, ""); !errors.Is(err, ErrNotResumable) {
+		t.Fatalf("RunAgent(resume) error = %v; want ErrNotResumable", err)
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("model called %d times; want 0", len(client.calls))
+	}
+}
@@ -0,0 +1,112 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// DefaultReviewRounds is how many times a ReviewLoop reviews the general
+// agent's result before accepting it.
+const DefaultReviewRounds = 2
+
+// Review verdicts end the reviewer's feedback summary.
+const (
+	ReviewVerdictPass = "VERDICT: PASS"
+	ReviewVerdictFail = "VERDICT: FAIL"
+)
+
+// ErrReviewFailed is returned by ReviewLoop.Run when the last review
+// round still found problems.
+var ErrReviewFailed = errors.New("review still failing")
+
+// GeneralAgent is the agent whose work a ReviewLoop checks, usually a
+// FunctionCallAgent.
+type GeneralAgent interface {
+	RunAgent(instruction string, files []string, resume bool, orientationInstruction string) (string, error)
+}
+
+// Reviewer reviews the general agent's result, usually a ReviewerAgent.
+type Reviewer interface {
+	RunAgent(task, result, workspaceDir string, resume bool) (string, error)
+}
+
+// ReviewLoop runs the general agent, has the reviewer check its result and
+// feeds failing reviews back to the agent until a review passes or
+// MaxRounds reviews have been made.
+type ReviewLoop struct {
+	Agent        GeneralAgent
+	Reviewer     Reviewer
+	WorkspaceDir string
+	MaxRounds    int
+	MessageQueue chan RealtimeEvent
+	Logger       *log.Logger
+}
+
+func NewReviewLoop(agent GeneralAgent, reviewer Reviewer, workspaceDir string, messageQueue chan RealtimeEvent, logger *log.Logger) *ReviewLoop {
+	return &ReviewLoop{
+		Agent:        agent,
+		Reviewer:     reviewer,
+		WorkspaceDir: workspaceDir,
+		MaxRounds:    DefaultReviewRounds,
+		MessageQueue: messageQueue,
+		Logger:       logger,
+	}
+}
+
+// ReviewFailed reports whether the reviewer's feedback gives a failing
+// verdict.
+func ReviewFailed(feedback string) bool {
+	return strings.Contains(strings.ToUpper(feedback), ReviewVerdictFail)
+}
+
+// Run solves task with the general agent and returns its last result. The
+// error wraps ErrReviewFailed when no review passed within MaxRounds.
+func (l *ReviewLoop) Run(task string, files []string) (string, error) {
+	result, err := l.Agent.RunAgent(task, files, false, "")
+	if err != nil {
+		return result, err
+	}
+
+	for round := 1; round <= l.MaxRounds; round++ {
+		l.emitRound(round, fmt.Sprintf("Review round %d of %d: reviewing the result.", round, l.MaxRounds))
+		feedback, err := l.Reviewer.RunAgent(task, result, l.WorkspaceDir, false)
+		if err != nil {
+			return result, fmt.Errorf("review round %d: %w", round, err)
+		}
+		if !ReviewFailed(feedback) {
+			l.emitRound(round, fmt.Sprintf("Review round %d passed.", round))
+			return result, nil
+		}
+
+		if round == l.MaxRounds {
+			l.emitRound(round, fmt.Sprintf("Review round %d found problems; no review rounds left.", round))
+			return result, fmt.Errorf("%w after %d rounds:\n%s", ErrReviewFailed, round, feedback)
+		}
+		l.emitRound(round, fmt.Sprintf("Review round %d found problems; sending the feedback to the agent.", round))
+		l.Logger.Printf("Reviewer feedback (round %d):\n%s", round, feedback)
+
+		result, err = l.Agent.RunAgent(task, nil, true, reviewOrientation(feedback))
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// reviewOrientation tells the general agent what the reviewer found.
+func reviewOrientation(feedback string) string {
+	return "A reviewer checked your result and found problems. Fix them, verify the fixes, then finish.\n\nReviewer feedback:\n" + feedback
+}
+
+func (l *ReviewLoop) emitRound(round int, message string) {
+	l.MessageQueue <- RealtimeEvent{
+		Type: EventTypeSystem,
+		Content: map[string]interface{}{
+			"message":           message,
+			"review_round":      round,
+			"max_review_rounds": l.MaxRounds,
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package agents
+
+import (
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+type generalRun struct {
+	instruction string
+	resume      bool
+	orientation string
+}
+
+type mockGeneralAgent struct {
+	runs []generalRun
+}
+
+func (a *mockGeneralAgent) RunAgent(instruction string, files []string, resume bool, orientationInstruction string) (string, error) {
+	a.runs = append(a.runs, generalRun{instruction, resume, orientationInstruction})
+	return "result", nil
+}
+
+// mockReviewer returns queued feedback, passing once the queue is empty.
+type mockReviewer struct {
+	feedback []string
+	calls    int
+}
+
+func (r *mockReviewer) RunAgent(task, result, workspaceDir string, resume bool) (string, error) {
+	r.calls++
+	if len(r.feedback) == 0 {
+		return "All good.\n" + ReviewVerdictPass, nil
+	}
+	next := r.feedback[0]
+	r.feedback = r.feedback[1:]
+	return next, nil
+}
+
+func newTestReviewLoop(agent GeneralAgent, reviewer Reviewer) (*ReviewLoop, chan RealtimeEvent) {
+	events := make(chan RealtimeEvent, 100)
+	return NewReviewLoop(agent, reviewer, "/workspace", events, log.New(io.Discard, "", 0)), events
+}
+
+func TestReviewLoopRerunsAgentOnFailingReview(t *testing.T) {
+	agent := &mockGeneralAgent{}
+	reviewer := &mockReviewer{feedback: []string{"The submit button does nothing.\n" + ReviewVerdictFail}}
+	loop, events := newTestReviewLoop(agent, reviewer)
+
+	if _, err := loop.Run("build a form", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(agent.runs) != 2 {
+		t.Fatalf("general agent ran %d times; want 2", len(agent.runs))
+	}
+	second := agent.runs[1]
+	if !second.resume || second.instruction != "build a form" {
+		t.Errorf("second run = %+v; want a resumed run of the task", second)
+	}
+	if !strings.Contains(second.orientation, "The submit button does nothing.") {
+		t.Errorf("orientation = %q; want the reviewer feedback", second.orientation)
+	}
+	if reviewer.calls != 2 {
+		t.Errorf("reviewer ran %d times; want 2", reviewer.calls)
+	}
+
+	close(events)
+	var rounds []int
+	for e := range events {
+		if e.Type != EventTypeSystem {
+			t.Errorf("event type = %q; want %q", e.Type, EventTypeSystem)
+		}
+		rounds = append(rounds, e.Content["review_round"].(int))
+	}
+	if len(rounds) != 4 || rounds[0] != 1 || rounds[3] != 2 {
+		t.Errorf("review rounds = %v; want a start and outcome event for rounds 1 and 2", rounds)
+	}
+}
+
+func TestReviewLoopStopsAfterMaxRounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		feedback  []string
+		maxRounds int
+		wantRuns  int
+		wantErr   bool
+	}{
+		{"passing review", nil, 2, 1, false},
+		{"no rounds", []string{ReviewVerdictFail}, 0, 1, false},
+		{"always failing", []string{ReviewVerdictFail, ReviewVerdictFail, ReviewVerdictFail}, 2, 2, true},
+		{"lowercase verdict", []string{"verdict: fail"}, 3, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &mockGeneralAgent{}
+			loop, _ := newTestReviewLoop(agent, &mockReviewer{feedback: tt.feedback})
+			loop.MaxRounds = tt.maxRounds
+			_, err := loop.Run("task", nil)
+			if errors.Is(err, ErrReviewFailed) != tt.wantErr || (err != nil && !tt.wantErr) {
+				t.Fatalf("Run() error = %v; want ErrReviewFailed: %v", err, tt.wantErr)
+			}
+			if len(agent.runs) != tt.wantRuns {
+				t.Errorf("general agent ran %d times; want %d", len(agent.runs), tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestOrientationInstructionIsAppended(t *testing.T) {
+	client := &scriptedLLMClient{}
+	agent := newTestAgent(client, &sliceHistory{}, nil, nil)
+	if _, err := agent.RunAgent("build it", nil, false, "fix the button"); err != nil {
+		t.Fatalf("RunAgent() error = %v", err)
+	}
+	prompt, _ := client.calls[0][0].Content.(string)
+	if prompt != "build it\n\nfix the button" {
+		t.Errorf("user prompt = %q; want the orientation appended", prompt)
+	}
+}
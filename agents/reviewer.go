@@ -5,15 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"sort"
 	"time"
+
+	"water-ai/metrics"
 )
 
 type ReviewerAgent struct {
 	BaseAgent
 	SystemPrompt    string
 	Client          LLMClient
-	Tools           []LLMTool // In Python this is wrapped in AgentToolManager
+	ToolManager     ToolManager
 	MessageQueue    chan RealtimeEvent
 	Logger          *log.Logger
 	ContextManager  ContextManager
@@ -21,15 +22,41 @@ type ReviewerAgent struct {
 	MaxTurns        int
 	Websocket       WebSocket
 	History         MessageHistory
+	// TurnTimeout bounds each review turn; see FunctionCallAgent.
+	TurnTimeout            time.Duration
+	MaxConsecutiveTimeouts int
 	
 	interrupted      bool
-	cachedToolParams []ToolParam
+	timeouts         int
+}
+
+// ReturnControlToolName is the tool the reviewer calls once it has checked
+// the general agent's work; its feedback summary follows.
+const ReturnControlToolName = "return_control_to_general_agent"
+
+// returnControlTool ends a review; see ReturnControlToolName.
+type returnControlTool struct{}
+
+// NewReturnControlTool returns the tool that ends a review. Give it to the
+// reviewer along with the tools it tests the work with.
+func NewReturnControlTool() LLMTool { return returnControlTool{} }
+
+func (returnControlTool) GetToolParam() ToolParam {
+	return ToolParam{
+		Name:        ReturnControlToolName,
+		Description: "Call this once you have finished reviewing the general agent's work.",
+		Schema:      map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+	}
+}
+
+func (returnControlTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	return ToolImplOutput{ToolOutput: "Review finished."}, nil
 }
 
 func NewReviewerAgent(
 	systemPrompt string,
 	client LLMClient,
-	tools []LLMTool,
+	toolManager ToolManager,
 	messageQueue chan RealtimeEvent,
 	logger *log.Logger,
 	contextManager ContextManager,
@@ -61,13 +88,14 @@ This agent conducts thorough reviews with emphasis on:
 		},
 		SystemPrompt:    systemPrompt,
 		Client:          client,
-		Tools:           tools,
+		ToolManager:     toolManager,
 		MessageQueue:    messageQueue,
 		Logger:          logger,
 		ContextManager:  contextManager,
 		History:         history,
 		MaxOutputTokens: maxOutputTokens,
 		MaxTurns:        maxTurns,
+		MaxConsecutiveTimeouts: DefaultMaxConsecutiveTimeouts,
 		Websocket:       websocket,
 	}
 }
@@ -77,38 +105,16 @@ func (r *ReviewerAgent) StartMessageProcessing() {
 	// No-op to match Python
 }
 
-func (r *ReviewerAgent) validateToolParameters() ([]ToolParam, error) {
-	if r.cachedToolParams != nil {
-		return r.cachedToolParams, nil
-	}
-
-	var params []ToolParam
-	names := make([]string, 0)
-	
-	for _, tool := range r.Tools {
-		p := tool.GetToolParam()
-		params = append(params, p)
-		names = append(names, p.Name)
-	}
-
-	sort.Strings(names)
-	for i := 0; i < len(names)-1; i++ {
-		if names[i] == names[i+1] {
-			return nil, fmt.Errorf("tool %s is duplicated", names[i])
-		}
-	}
-
-	r.cachedToolParams = params
-	return params, nil
-}
-
 func (r *ReviewerAgent) generateLLMResponse(ctx context.Context, messages []Message, tools []ToolParam) ([]interface{}, error) {
 	start := time.Now()
 	
 	// Centralized LLM response generation with timing metrics
-	response, err := r.Client.Generate(ctx, messages, r.MaxOutputTokens, tools, r.SystemPrompt)
+	response, err := runInTurn(ctx, func(ctx context.Context, _ *turnGuard) ([]interface{}, error) {
+		return r.Client.Generate(ctx, messages, r.MaxOutputTokens, tools, r.SystemPrompt)
+	})
 	
 	elapsed := time.Since(start)
+	metrics.LLMCallDuration.Observe(elapsed.Seconds(), metricsComponentReviewer)
 	r.Logger.Printf("LLM generation took %.2fs", elapsed.Seconds())
 	return response, err
 }
@@ -139,6 +145,7 @@ Now your turn to review the general agent's work.
 
 	r.History.AddUserPrompt(reviewInstruction, nil)
 	r.interrupted = false
+	r.timeouts = 0
 
 	remainingTurns := r.MaxTurns
 
@@ -153,7 +160,7 @@ Now your turn to review the general agent's work.
 		delimiter := "--------------------------------------------- REVIEWER TURN ---------------------------------------------"
 		r.Logger.Printf("\n%s\n", delimiter)
 
-		toolParams, err := r.validateToolParameters()
+		toolParams, err := toolParams(r.ToolManager)
 		if err != nil {
 			return ToolImplOutput{}, err
 		}
@@ -176,8 +183,17 @@ Now your turn to review the general agent's work.
 		// Note: Python sets history message list here, but in Go interfaces usually handle state internally.
 		// We proceed with truncatedMessages for generation.
 
-		modelResponse, err := r.generateLLMResponse(ctx, truncatedMessages, toolParams)
+		turnCtx, cancelTurn := turnContext(ctx, r.TurnTimeout)
+		modelResponse, err := r.generateLLMResponse(turnCtx, truncatedMessages, toolParams)
+		if turnTimedOut(ctx, turnCtx) {
+			cancelTurn()
+			if err := r.recordTimeout("Model call"); err != nil {
+				return ToolImplOutput{ToolOutput: err.Error()}, err
+			}
+			continue
+		}
 		if err != nil {
+			cancelTurn()
 			return ToolImplOutput{ToolOutput: "Error calling LLM"}, err
 		}
 
@@ -189,8 +205,13 @@ Now your turn to review the general agent's work.
 
 		pendingTools := r.History.GetPendingToolCalls()
 		if len(pendingTools) > 1 {
+			cancelTurn()
 			return ToolImplOutput{}, errors.New("only one tool call per turn is supported")
 		}
+		if len(pendingTools) == 0 {
+			cancelTurn()
+			r.timeouts = 0
+		}
 
 		if len(pendingTools) == 1 {
 			toolCall := pendingTools[0]
@@ -203,39 +224,50 @@ Now your turn to review the general agent's work.
 			}
 
 			if r.interrupted {
+				cancelTurn()
 				r.History.AddToolCallResult(toolCall, "Tool execution interrupted")
 				return ToolImplOutput{ToolOutput: "Reviewer interrupted", ToolResultMessage: "Reviewer interrupted during tool execution"}, nil
 			}
 
-			// Run Tool
-			var toolOutputStr string
-			var foundTool bool
-			for _, t := range r.Tools {
-				if t.GetToolParam().Name == toolCall.Name {
-					res, err := t.Run(ctx, toolCall.Arguments, r.History)
-					if err != nil {
-						toolOutputStr = fmt.Sprintf("Error: %v", err)
-					} else {
-						toolOutputStr = res.ToolOutput
-					}
-					foundTool = true
-					break
-				}
+			if err := validateToolCall(toolParams, toolCall); err != nil {
+				cancelTurn()
+				r.History.AddToolCallResult(toolCall, err.Error())
+				continue
 			}
-			if !foundTool {
-				toolOutputStr = "Tool not found"
+
+			// Run Tool
+			res, err := runInTurn(turnCtx, func(ctx context.Context, guard *turnGuard) (ToolImplOutput, error) {
+				return r.ToolManager.RunTool(ctx, toolCall, guard.history(r.History))
+			})
+			timedOut := turnTimedOut(ctx, turnCtx)
+			cancelTurn()
+			toolOutputStr := res.ToolOutput
+			if timedOut {
+				toolOutputStr = fmt.Sprintf("Error: %s did not finish within %s", toolCall.Name, r.TurnTimeout)
+			} else if err != nil {
+				toolOutputStr = fmt.Sprintf("Error: %v", err)
 			}
 
 			r.History.AddToolCallResult(toolCall, toolOutputStr)
+			if timedOut {
+				if err := r.recordTimeout("Tool " + toolCall.Name); err != nil {
+					return ToolImplOutput{ToolOutput: err.Error()}, err
+				}
+				continue
+			}
+			r.timeouts = 0
 
-			if toolCall.Name == "return_control_to_general_agent" {
-				summarizeReview := "Now based on your review, please rewrite detailed feedback to the general agent."
+			if toolCall.Name == ReturnControlToolName {
+				summarizeReview := fmt.Sprintf("Now based on your review, please rewrite detailed feedback to the general agent. "+
+					"End with a line reading %q if everything works, or %q if you found any failure.", ReviewVerdictPass, ReviewVerdictFail)
 				r.History.AddUserPrompt(summarizeReview, nil)
 				
 				currentMessages = r.History.GetMessagesForLLM()
 				truncatedMessages = r.ContextManager.ApplyTruncationIfNeeded(currentMessages)
 				
-				summaryResponse, err := r.generateLLMResponse(ctx, truncatedMessages, toolParams)
+				summaryCtx, cancelSummary := turnContext(ctx, r.TurnTimeout)
+				summaryResponse, err := r.generateLLMResponse(summaryCtx, truncatedMessages, toolParams)
+				cancelSummary()
 				if err != nil {
 					return ToolImplOutput{}, err
 				}
@@ -274,15 +306,15 @@ Now your turn to review the general agent's work.
 func (r *ReviewerAgent) RunAgent(task, result, workspaceDir string, resume bool) (string, error) {
 	// In Go, usually run synchronously, or use StartMessageProcessing for background
 	
-	// Reset tool logic if implemented in a manager
-	// r.ToolManager.Reset() 
-	
 	if resume {
-		// assert r.History.IsNextTurnUser()
+		if err := prepareResume(r.History); err != nil {
+			return "", err
+		}
 	} else {
+		r.ToolManager.Reset()
 		r.History.Clear()
-		r.interrupted = false
 	}
+	r.interrupted = false
 
 	toolInput := map[string]interface{}{
 		"task":          task,
@@ -295,6 +327,17 @@ func (r *ReviewerAgent) RunAgent(task, result, workspaceDir string, resume bool)
 	return output.ToolOutput, err
 }
 
+// recordTimeout logs an abandoned turn and returns ErrTurnTimeout once
+// MaxConsecutiveTimeouts turns in a row have timed out.
+func (r *ReviewerAgent) recordTimeout(what string) error {
+	r.timeouts++
+	r.Logger.Printf("%s timed out after %s; review turn abandoned (%d in a row)", what, r.TurnTimeout, r.timeouts)
+	if r.MaxConsecutiveTimeouts > 0 && r.timeouts >= r.MaxConsecutiveTimeouts {
+		return fmt.Errorf("%w: %d review turns in a row", ErrTurnTimeout, r.timeouts)
+	}
+	return nil
+}
+
 func (r *ReviewerAgent) Cancel() {
 	r.interrupted = true
 	r.Logger.Println("Reviewer cancellation requested")
@@ -303,5 +346,5 @@ func (r *ReviewerAgent) Cancel() {
 func (r *ReviewerAgent) Clear() {
 	r.History.Clear()
 	r.interrupted = false
-	r.cachedToolParams = nil
+	r.ToolManager.Reset()
 }
\ No newline at end of file
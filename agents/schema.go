@@ -0,0 +1,190 @@
+package agents
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ToolInputError reports the arguments of a tool call that do not match
+// the tool's input schema. Its message is fed back to the model as the
+// tool result so it can correct the call.
+type ToolInputError struct {
+	Tool     string
+	Problems []string
+}
+
+func (e *ToolInputError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Invalid arguments for %s:\n", e.Tool)
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "- %s\n", p)
+	}
+	sb.WriteString("Fix the arguments and call the tool again.")
+	return sb.String()
+}
+
+// validateToolCall checks call's arguments against the schema of the tool
+// it names. Calls to unknown tools are left for the ToolManager to reject.
+func validateToolCall(params []ToolParam, call ToolCallParameters) error {
+	if call.ArgumentsError != "" {
+		return &ToolInputError{Tool: call.Name, Problems: []string{call.ArgumentsError}}
+	}
+	for _, p := range params {
+		if p.Name == call.Name {
+			return ValidateToolInput(p.Name, p.Schema, call.Arguments)
+		}
+	}
+	return nil
+}
+
+// ValidateToolInput checks input against a JSON object schema: required
+// fields must be present and every known property must have the declared
+// type and, if given, one of the enum values. Whole float64 values pass as
+// integers, since JSON decoding yields float64 for every number.
+func ValidateToolInput(tool string, schema map[string]interface{}, input map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+	var problems []string
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := input[name]; !ok {
+			problems = append(problems, fmt.Sprintf("missing required field %q", name))
+		}
+	}
+
+	properties := schemaProperties(schema["properties"])
+	names := make([]string, 0, len(input))
+	for name := range input {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := properties[name]
+		if !ok {
+			continue
+		}
+		value := input[name]
+		if want, _ := prop["type"].(string); want != "" && !hasSchemaType(value, want) {
+			problems = append(problems, fmt.Sprintf("field %q must be %s, got %s", name, want, jsonTypeName(value)))
+			continue
+		}
+		if enum := stringList(prop["enum"]); len(enum) > 0 {
+			if s, _ := value.(string); !contains(enum, s) {
+				problems = append(problems, fmt.Sprintf("field %q must be one of %s, got %v", name, strings.Join(enum, ", "), value))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ToolInputError{Tool: tool, Problems: problems}
+	}
+	return nil
+}
+
+// schemaProperties normalizes the properties of a schema, which tools
+// declare either as map[string]interface{} or map[string]string values.
+func schemaProperties(v interface{}) map[string]map[string]interface{} {
+	props := map[string]map[string]interface{}{}
+	m, _ := v.(map[string]interface{})
+	for name, p := range m {
+		switch p := p.(type) {
+		case map[string]interface{}:
+			props[name] = p
+		case map[string]string:
+			converted := make(map[string]interface{}, len(p))
+			for k, v := range p {
+				converted[k] = v
+			}
+			props[name] = converted
+		}
+	}
+	return props
+}
+
+// stringList returns v as a []string if it is a []string or a
+// []interface{} of strings.
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func hasSchemaType(value interface{}, want string) bool {
+	switch want {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case int, int64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int64, float64:
+			return true
+		}
+		return false
+	case "array":
+		switch value.(type) {
+		case []interface{}, []string:
+			return true
+		}
+		return false
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	// Unknown types are not checked.
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64:
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}, []string:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
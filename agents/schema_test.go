@@ -0,0 +1,119 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testToolSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"command": map[string]interface{}{"type": "string"},
+		"timeout": map[string]interface{}{"type": "integer"},
+		"action":  map[string]interface{}{"type": "string", "enum": []string{"run", "kill"}},
+		"task":    map[string]string{"type": "string"},
+	},
+	"required": []string{"command"},
+}
+
+func TestValidateToolInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+		want  []string
+	}{
+		{"valid", map[string]interface{}{"command": "ls", "timeout": 30}, nil},
+		{"whole float as integer", map[string]interface{}{"command": "ls", "timeout": float64(30)}, nil},
+		{"unknown field ignored", map[string]interface{}{"command": "ls", "extra": true}, nil},
+		{"missing required", map[string]interface{}{"timeout": 30}, []string{`missing required field "command"`}},
+		{"wrong type", map[string]interface{}{"command": 42}, []string{`field "command" must be string, got integer`}},
+		{"fractional integer", map[string]interface{}{"command": "ls", "timeout": 1.5}, []string{`field "timeout" must be integer, got number`}},
+		{"string property schema", map[string]interface{}{"command": "ls", "task": false}, []string{`field "task" must be string, got boolean`}},
+		{"enum", map[string]interface{}{"command": "ls", "action": "stop"}, []string{`field "action" must be one of run, kill, got stop`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateToolInput("bash", testToolSchema, tt.input)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidateToolInput() error = %v; want nil", err)
+				}
+				return
+			}
+			var inputErr *ToolInputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("ValidateToolInput() error = %v; want a ToolInputError", err)
+			}
+			if strings.Join(inputErr.Problems, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("problems = %q; want %q", inputErr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+// schemaTool is a tool with testToolSchema that records whether it ran.
+type schemaTool struct {
+	ran bool
+}
+
+func (t *schemaTool) GetToolParam() ToolParam { return ToolParam{Name: "bash", Schema: testToolSchema} }
+
+func (t *schemaTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	t.ran = true
+	return ToolImplOutput{ToolOutput: "ran"}, nil
+}
+
+func TestFunctionCallAgentFeedsBackInvalidToolInput(t *testing.T) {
+	tool := &schemaTool{}
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "bash", Arguments: map[string]interface{}{"timeout": "soon"}}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{tool})
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "list files"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v; want the turn to continue", err)
+	}
+	if out.ToolOutput != "done" {
+		t.Errorf("output = %q; want the model's next answer", out.ToolOutput)
+	}
+	if tool.ran {
+		t.Error("tool ran with invalid arguments")
+	}
+
+	result, _ := history.messages[2].Content.([]map[string]interface{})[0]["result"].(string)
+	for _, want := range []string{"Invalid arguments for bash", `missing required field "command"`, `field "timeout" must be integer, got string`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("tool result = %q; want it to contain %q", result, want)
+		}
+	}
+}
+
+func TestFunctionCallAgentFeedsBackMalformedArguments(t *testing.T) {
+	tool := &schemaTool{}
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "bash", Arguments: map[string]interface{}{}, ArgumentsError: `arguments are not valid JSON (unexpected end of JSON input): "{\"command\":"`}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{tool})
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "list files"}, history); err != nil {
+		t.Fatalf("Run() error = %v; want the turn to continue", err)
+	}
+	if tool.ran {
+		t.Error("tool ran with malformed arguments")
+	}
+
+	result, _ := history.messages[2].Content.([]map[string]interface{})[0]["result"].(string)
+	for _, want := range []string{"Invalid arguments for bash", "not valid JSON", "call the tool again"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("tool result = %q; want it to contain %q", result, want)
+		}
+	}
+	if strings.Contains(result, "missing required field") {
+		t.Errorf("tool result = %q; want only the JSON error, not schema problems", result)
+	}
+}
@@ -0,0 +1,128 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StreamDelta is one increment of a streamed model response: text,
+// thinking, or a fragment of a tool call.
+type StreamDelta struct {
+	Text     string
+	Thinking string
+	ToolCall *ToolCallDelta
+}
+
+// ToolCallDelta is a fragment of a streamed tool call. Index identifies the
+// call; its ID and Name arrive with the first fragment and Arguments is
+// the next piece of the JSON-encoded arguments.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// StreamingLLMClient is implemented by clients that can stream responses.
+// GenerateStream calls onDelta for each increment and returns once the
+// response is complete.
+type StreamingLLMClient interface {
+	LLMClient
+	GenerateStream(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string, onDelta func(StreamDelta)) error
+}
+
+// streamResponse generates a response through a streaming client. Text
+// deltas reach the client immediately as agent_response_delta events; tool
+// calls are only returned once their arguments are complete, so the usual
+// tool_call event carries well-formed arguments.
+func (a *FunctionCallAgent) streamResponse(ctx context.Context, guard *turnGuard, client StreamingLLMClient, messages []Message, toolParams []ToolParam) ([]interface{}, error) {
+	var text, thinking strings.Builder
+	calls := newToolCallAssembler()
+	err := client.GenerateStream(ctx, messages, a.MaxOutputTokens, toolParams, a.SystemPromptBuilder.GetSystemPrompt(), func(d StreamDelta) {
+		if d.Text != "" {
+			text.WriteString(d.Text)
+			guard.do(func() {
+				a.emitEvent(EventTypeAgentResponseDelta, map[string]interface{}{"text": d.Text})
+			})
+		}
+		thinking.WriteString(d.Thinking)
+		if d.ToolCall != nil {
+			calls.add(*d.ToolCall)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	toolCalls, err := calls.toolCalls()
+	if err != nil {
+		return nil, err
+	}
+	var response []interface{}
+	if thinking.Len() > 0 {
+		response = append(response, ThinkingBlock{Thinking: thinking.String()})
+	}
+	if text.Len() > 0 {
+		response = append(response, TextResult{Text: text.String()})
+	}
+	for _, call := range toolCalls {
+		response = append(response, call)
+	}
+	return response, nil
+}
+
+// toolCallAssembler buffers streamed tool-call fragments by index until
+// the stream ends.
+type toolCallAssembler struct {
+	calls map[int]*partialToolCall
+}
+
+type partialToolCall struct {
+	id, name string
+	args     strings.Builder
+}
+
+func newToolCallAssembler() *toolCallAssembler {
+	return &toolCallAssembler{calls: make(map[int]*partialToolCall)}
+}
+
+func (as *toolCallAssembler) add(d ToolCallDelta) {
+	call, ok := as.calls[d.Index]
+	if !ok {
+		call = &partialToolCall{}
+		as.calls[d.Index] = call
+	}
+	if d.ID != "" {
+		call.id = d.ID
+	}
+	if d.Name != "" {
+		call.name = d.Name
+	}
+	call.args.WriteString(d.Arguments)
+}
+
+// toolCalls returns the assembled calls in index order. Arguments that do
+// not parse as a JSON object mean the stream was cut short.
+func (as *toolCallAssembler) toolCalls() ([]ToolCallParameters, error) {
+	indexes := make([]int, 0, len(as.calls))
+	for i := range as.calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	var out []ToolCallParameters
+	for _, i := range indexes {
+		call := as.calls[i]
+		args := map[string]interface{}{}
+		if raw := strings.TrimSpace(call.args.String()); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &args); err != nil {
+				return nil, fmt.Errorf("tool call %s (%s) has incomplete arguments %q: %w", call.name, call.id, raw, err)
+			}
+		}
+		out = append(out, ToolCallParameters{ID: call.id, Name: call.name, Arguments: args})
+	}
+	return out, nil
+}
@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// streamingLLMClient streams each scripted response as a list of deltas.
+type streamingLLMClient struct {
+	scriptedLLMClient
+	streams [][]StreamDelta
+}
+
+func (c *streamingLLMClient) GenerateStream(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string, onDelta func(StreamDelta)) error {
+	c.mu.Lock()
+	c.calls = append(c.calls, messages)
+	var next []StreamDelta
+	if len(c.streams) > 0 {
+		next, c.streams = c.streams[0], c.streams[1:]
+	} else {
+		next = []StreamDelta{{Text: "done"}}
+	}
+	c.mu.Unlock()
+	for _, d := range next {
+		onDelta(d)
+	}
+	return nil
+}
+
+func TestStreamedToolCallArgumentsAreAssembled(t *testing.T) {
+	client := &streamingLLMClient{streams: [][]StreamDelta{{
+		{Text: "Listing "},
+		{ToolCall: &ToolCallDelta{Index: 0, ID: "call-1", Name: "bash", Arguments: `{"comm`}},
+		{Text: "files."},
+		{ToolCall: &ToolCallDelta{Index: 0, Arguments: `and":"l`}},
+		{ToolCall: &ToolCallDelta{Index: 0, Arguments: `s"}`}},
+	}}}
+	tool := &schemaTool{}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{tool})
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "list files"}, history); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var deltas []string
+	var toolCalls []RealtimeEvent
+	for _, e := range drainEvents(agent) {
+		switch e.Type {
+		case EventTypeAgentResponseDelta:
+			deltas = append(deltas, e.Content["text"].(string))
+		case EventTypeToolCall:
+			toolCalls = append(toolCalls, e)
+		}
+	}
+	if got := strings.Join(deltas, "|"); got != "Listing |files.|done" {
+		t.Errorf("text deltas = %q; want %q", got, "Listing |files.|done")
+	}
+	if len(toolCalls) != 1 {
+		t.Fatalf("got %d tool_call events; want 1", len(toolCalls))
+	}
+	input, _ := toolCalls[0].Content["tool_input"].(map[string]interface{})
+	if toolCalls[0].Content["tool_call_id"] != "call-1" || input["command"] != "ls" {
+		t.Errorf("tool_call event = %v; want call-1 with command ls", toolCalls[0].Content)
+	}
+	if !tool.ran {
+		t.Error("tool did not run")
+	}
+}
+
+func TestToolCallAssemblerRejectsTruncatedArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		deltas  []ToolCallDelta
+		want    int
+		wantErr bool
+	}{
+		{"no arguments", []ToolCallDelta{{ID: "a", Name: "noop"}}, 1, false},
+		{"two calls", []ToolCallDelta{{Index: 1, ID: "b", Name: "x", Arguments: "{}"}, {Index: 0, ID: "a", Name: "y", Arguments: `{"n":1}`}}, 2, false},
+		{"truncated", []ToolCallDelta{{ID: "a", Name: "bash", Arguments: `{"command":"l`}}, 0, true},
+	}
+	for _, tt := range tests {
+		as := newToolCallAssembler()
+		for _, d := range tt.deltas {
+			as.add(d)
+		}
+		calls, err := as.toolCalls()
+		if (err != nil) != tt.wantErr || len(calls) != tt.want {
+			t.Errorf("%s: toolCalls() = %v, %v; want %d calls, error %v", tt.name, calls, err, tt.want, tt.wantErr)
+		}
+		if tt.name == "two calls" && (calls[0].ID != "a" || calls[1].ID != "b") {
+			t.Errorf("%s: calls = %+v; want index order", tt.name, calls)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrToolNotFound is returned by RunTool for a call to an unknown tool.
+var ErrToolNotFound = errors.New("tool not found")
+
+// ToolParamsProvider is implemented by tool managers that validate and
+// cache their tool params. Other managers are validated on every turn.
+type ToolParamsProvider interface {
+	ToolParams() ([]ToolParam, error)
+}
+
+// resetter is implemented by tools holding per-run state, such as a shell
+// session.
+type resetter interface {
+	Reset()
+}
+
+// AgentToolManager is the ToolManager over a fixed set of tools.
+type AgentToolManager struct {
+	tools  []LLMTool
+	params []ToolParam
+}
+
+func NewAgentToolManager(tools []LLMTool) *AgentToolManager {
+	return &AgentToolManager{tools: tools}
+}
+
+func (m *AgentToolManager) GetTools() []LLMTool {
+	return m.tools
+}
+
+// ToolParams returns the params of every tool, checking once that no two
+// tools share a name.
+func (m *AgentToolManager) ToolParams() ([]ToolParam, error) {
+	if m.params != nil {
+		return m.params, nil
+	}
+	params, err := validateToolParams(m.tools)
+	if err != nil {
+		return nil, err
+	}
+	m.params = params
+	return params, nil
+}
+
+// RunTool runs the tool named by call.
+func (m *AgentToolManager) RunTool(ctx context.Context, call ToolCallParameters, history MessageHistory) (ToolImplOutput, error) {
+	for _, t := range m.tools {
+		if t.GetToolParam().Name == call.Name {
+			return t.Run(ctx, call.Arguments, history)
+		}
+	}
+	return ToolImplOutput{}, fmt.Errorf("%w: %s", ErrToolNotFound, call.Name)
+}
+
+// Reset clears the per-run state of the tools before a fresh run.
+func (m *AgentToolManager) Reset() {
+	m.params = nil
+	for _, t := range m.tools {
+		if r, ok := t.(resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// toolParams returns the validated params of the manager's tools.
+func toolParams(m ToolManager) ([]ToolParam, error) {
+	if p, ok := m.(ToolParamsProvider); ok {
+		return p.ToolParams()
+	}
+	return validateToolParams(m.GetTools())
+}
+
+func validateToolParams(tools []LLMTool) ([]ToolParam, error) {
+	var params []ToolParam
+	names := make([]string, 0)
+
+	for _, tool := range tools {
+		p := tool.GetToolParam()
+		params = append(params, p)
+		names = append(names, p.Name)
+	}
+
+	sort.Strings(names)
+	for i := 0; i < len(names)-1; i++ {
+		if names[i] == names[i+1] {
+			return nil, fmt.Errorf("tool %s is duplicated", names[i])
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,101 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// echoTool returns its name and counts resets.
+type echoTool struct {
+	name   string
+	resets int
+}
+
+func (t *echoTool) GetToolParam() ToolParam { return ToolParam{Name: t.name} }
+
+func (t *echoTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	return ToolImplOutput{ToolOutput: t.name}, nil
+}
+
+func (t *echoTool) Reset() { t.resets++ }
+
+// countingToolManager records the calls an agent makes on its ToolManager.
+type countingToolManager struct {
+	*AgentToolManager
+	resets int
+	calls  []string
+}
+
+func (m *countingToolManager) RunTool(ctx context.Context, call ToolCallParameters, history MessageHistory) (ToolImplOutput, error) {
+	m.calls = append(m.calls, call.Name)
+	return m.AgentToolManager.RunTool(ctx, call, history)
+}
+
+func (m *countingToolManager) Reset() {
+	m.resets++
+	m.AgentToolManager.Reset()
+}
+
+func TestAgentToolManagerRunToolDispatch(t *testing.T) {
+	m := NewAgentToolManager([]LLMTool{&echoTool{name: "read_file"}, &echoTool{name: "bash"}})
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{"bash", "bash", nil},
+		{"read_file", "read_file", nil},
+		{"missing", "", ErrToolNotFound},
+	}
+	for _, tt := range tests {
+		out, err := m.RunTool(context.Background(), ToolCallParameters{Name: tt.name}, nil)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("RunTool(%s) error = %v; want %v", tt.name, err, tt.wantErr)
+		}
+		if out.ToolOutput != tt.want {
+			t.Errorf("RunTool(%s) = %q; want %q", tt.name, out.ToolOutput, tt.want)
+		}
+	}
+}
+
+func TestAgentToolManagerToolParams(t *testing.T) {
+	m := NewAgentToolManager([]LLMTool{&echoTool{name: "a"}, &echoTool{name: "b"}})
+	params, err := m.ToolParams()
+	if err != nil || len(params) != 2 {
+		t.Fatalf("ToolParams() = %v, %v; want two params", params, err)
+	}
+
+	dup := NewAgentToolManager([]LLMTool{&echoTool{name: "a"}, &echoTool{name: "a"}})
+	if _, err := dup.ToolParams(); err == nil {
+		t.Error("ToolParams() with duplicate names should fail")
+	}
+}
+
+func TestRunAgentResetsToolsUnlessResuming(t *testing.T) {
+	tool := &echoTool{name: "bash"}
+	manager := &countingToolManager{AgentToolManager: NewAgentToolManager([]LLMTool{tool})}
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "bash"}},
+	}}
+	agent := newTestAgent(client, &sliceHistory{}, nil, nil)
+	agent.ToolManager = manager
+
+	if _, err := agent.RunAgent("first", nil, false, ""); err != nil {
+		t.Fatalf("RunAgent() error = %v", err)
+	}
+	if manager.resets != 1 || tool.resets != 1 {
+		t.Errorf("resets after a fresh run = %d (tool %d); want 1", manager.resets, tool.resets)
+	}
+	if len(manager.calls) != 1 || manager.calls[0] != "bash" {
+		t.Errorf("RunTool calls = %v; want [bash]", manager.calls)
+	}
+
+	if _, err := agent.RunAgent("again", nil, true, ""); err != nil {
+		t.Fatalf("RunAgent(resume) error = %v", err)
+	}
+	if manager.resets != 1 {
+		t.Errorf("resets after a resumed run = %d; want still 1", manager.resets)
+	}
+}
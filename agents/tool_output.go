@@ -0,0 +1,49 @@
+package agents
+
+import (
+	"context"
+
+	"water-ai/tools"
+)
+
+// OffloadLargeOutputs saves outputs of llmTools larger than threshold bytes
+// under the workspace at root, giving the model a preview and a reference
+// instead, and adds the read_tool_output tool that reads them back. Zero
+// uses tools.DefaultLargeOutputThreshold; a negative threshold disables
+// offloading.
+func OffloadLargeOutputs(llmTools []LLMTool, root string, threshold int) []LLMTool {
+	if threshold == 0 {
+		threshold = tools.DefaultLargeOutputThreshold
+	}
+	if threshold < 0 {
+		return llmTools
+	}
+	out := make([]LLMTool, 0, len(llmTools)+1)
+	for _, tool := range llmTools {
+		out = append(out, &offloadTool{LLMTool: tool, root: root, threshold: threshold})
+	}
+	return append(out, WrapSystemTool(&tools.ReadToolOutputTool{WorkspaceRoot: root}))
+}
+
+type offloadTool struct {
+	LLMTool
+	root      string
+	threshold int
+}
+
+func (t *offloadTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	out, err := t.LLMTool.Run(ctx, input, history)
+	if err != nil {
+		return out, err
+	}
+	name := t.GetToolParam().Name
+	ref, offloaded, err := tools.OffloadLargeOutput(t.root, name, out.ToolOutput, t.threshold)
+	if err != nil {
+		// The full output still reaches the model
+		return out, nil
+	}
+	if offloaded {
+		out.ToolOutput = ref
+	}
+	return out, nil
+}
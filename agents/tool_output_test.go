@@ -0,0 +1,75 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// dumpTool prints a numbered line per count.
+type dumpTool struct{ lines int }
+
+func (t *dumpTool) GetToolParam() ToolParam {
+	return ToolParam{Name: "dump", Schema: map[string]interface{}{"type": "object"}}
+}
+
+func (t *dumpTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	var sb strings.Builder
+	for i := 1; i <= t.lines; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	return ToolImplOutput{ToolOutput: sb.String()}, nil
+}
+
+// replyingLLMClient answers each call with reply applied to the messages.
+type replyingLLMClient struct {
+	reply func(messages []Message) []interface{}
+}
+
+func (c *replyingLLMClient) Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error) {
+	return c.reply(messages), nil
+}
+
+func lastToolResult(messages []Message) string {
+	items, _ := messages[len(messages)-1].Content.([]map[string]interface{})
+	if len(items) == 0 {
+		return ""
+	}
+	result, _ := items[0]["result"].(string)
+	return result
+}
+
+func TestAgentOffloadsLargeToolOutput(t *testing.T) {
+	root := t.TempDir()
+	savedPath := regexp.MustCompile(`Full output saved to: (\S+)`)
+	var reference, readBack string
+	client := &replyingLLMClient{reply: func(messages []Message) []interface{} {
+		switch len(messages) {
+		case 1:
+			return []interface{}{ToolCallParameters{ID: "c1", Name: "dump", Arguments: map[string]interface{}{}}}
+		case 3:
+			reference = lastToolResult(messages)
+			m := savedPath.FindStringSubmatch(reference)
+			if m == nil {
+				return []interface{}{TextResult{Text: "no reference"}}
+			}
+			return []interface{}{ToolCallParameters{ID: "c2", Name: "read_tool_output", Arguments: map[string]interface{}{"path": m[1], "start_line": 5000, "end_line": 5001}}}
+		default:
+			readBack = lastToolResult(messages)
+			return []interface{}{TextResult{Text: "done"}}
+		}
+	}}
+	agent := newTestAgent(client, &sliceHistory{}, nil, OffloadLargeOutputs([]LLMTool{&dumpTool{lines: 10000}}, root, 1000))
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "dump"}, agent.History); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(reference, "Preview:") || strings.Contains(reference, "line 5000\n") {
+		t.Errorf("dump result = %.200q; want a reference to the saved output", reference)
+	}
+	if readBack != "line 5000\nline 5001" {
+		t.Errorf("read_tool_output result = %q; want lines 5000-5001", readBack)
+	}
+}
@@ -0,0 +1,134 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultMaxConsecutiveTimeouts is how many turns in a row may time out
+// before an agent gives up on the task.
+const DefaultMaxConsecutiveTimeouts = 3
+
+// ErrTurnTimeout is returned when too many consecutive turns time out.
+var ErrTurnTimeout = errors.New("agent turn timed out")
+
+// turnContext bounds one turn by timeout; 0 means no bound.
+func turnContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// turnTimedOut reports whether turnCtx hit its deadline rather than being
+// cancelled through parent.
+func turnTimedOut(parent, turnCtx context.Context) bool {
+	return parent.Err() == nil && errors.Is(turnCtx.Err(), context.DeadlineExceeded)
+}
+
+// runInTurn runs fn and waits for it until ctx ends, so a model call or
+// tool that ignores its context cannot hang the turn. An abandoned fn keeps
+// running in the background until it notices ctx; its result is discarded
+// and guard drops any side effect it attempts after runInTurn returns.
+func runInTurn[T any](ctx context.Context, fn func(ctx context.Context, guard *turnGuard) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	guard := &turnGuard{}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn(ctx, guard)
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		guard.close()
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// turnGuard fences off the side effects of a model call or tool run by
+// runInTurn: once the turn has abandoned it, its events and history
+// writes are dropped instead of racing the next turn.
+type turnGuard struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+// do runs effect unless the turn has been abandoned.
+func (g *turnGuard) do(effect func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		effect()
+	}
+}
+
+// close waits for an effect in progress and drops all later ones.
+func (g *turnGuard) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+}
+
+// history returns h with every call routed through the guard.
+func (g *turnGuard) history(h MessageHistory) MessageHistory {
+	return &guardedHistory{history: h, guard: g}
+}
+
+// guardedHistory is the history a tool sees while runInTurn runs it.
+type guardedHistory struct {
+	history MessageHistory
+	guard   *turnGuard
+}
+
+func (h *guardedHistory) AddUserPrompt(prompt string, images []interface{}) {
+	h.guard.do(func() { h.history.AddUserPrompt(prompt, images) })
+}
+
+func (h *guardedHistory) AddAssistantTurn(responses []interface{}) {
+	h.guard.do(func() { h.history.AddAssistantTurn(responses) })
+}
+
+func (h *guardedHistory) AddToolCallResult(toolCall ToolCallParameters, result string) {
+	h.guard.do(func() { h.history.AddToolCallResult(toolCall, result) })
+}
+
+func (h *guardedHistory) GetMessagesForLLM() (messages []Message) {
+	h.guard.do(func() { messages = h.history.GetMessagesForLLM() })
+	return messages
+}
+
+func (h *guardedHistory) SetMessages(messages []Message) {
+	h.guard.do(func() { h.history.SetMessages(messages) })
+}
+
+func (h *guardedHistory) GetPendingToolCalls() (calls []ToolCallParameters) {
+	h.guard.do(func() { calls = h.history.GetPendingToolCalls() })
+	return calls
+}
+
+func (h *guardedHistory) GetLastAssistantTextResponse() (text string) {
+	h.guard.do(func() { text = h.history.GetLastAssistantTextResponse() })
+	return text
+}
+
+func (h *guardedHistory) Clear() { h.guard.do(h.history.Clear) }
+
+func (h *guardedHistory) Truncate() { h.guard.do(h.history.Truncate) }
+
+func (h *guardedHistory) CountTokens() (n int) {
+	h.guard.do(func() { n = h.history.CountTokens() })
+	return n
+}
+
+func (h *guardedHistory) IsNextTurnUser() (next bool) {
+	h.guard.do(func() { next = h.history.IsNextTurnUser() })
+	return next
+}
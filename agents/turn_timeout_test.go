@@ -0,0 +1,162 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"water-ai/tools"
+)
+
+// stallingLLMClient blocks on its first stalls calls without honoring the
+// context, then answers like scriptedLLMClient.
+type stallingLLMClient struct {
+	scriptedLLMClient
+	stalls  int
+	release chan struct{}
+}
+
+func (c *stallingLLMClient) Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error) {
+	c.mu.Lock()
+	stall := c.stalls > 0
+	c.stalls--
+	c.mu.Unlock()
+	if stall {
+		<-c.release
+		return []interface{}{TextResult{Text: "too late"}}, nil
+	}
+	return c.scriptedLLMClient.Generate(ctx, messages, maxTokens, tools, systemPrompt)
+}
+
+// blockingTool runs until its context ends.
+type blockingTool struct{}
+
+func (blockingTool) GetToolParam() ToolParam { return ToolParam{Name: "slow_tool"} }
+
+func (blockingTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	<-ctx.Done()
+	return ToolImplOutput{ToolOutput: "cancelled"}, ctx.Err()
+}
+
+func drainEvents(agent *FunctionCallAgent) []RealtimeEvent {
+	var events []RealtimeEvent
+	for {
+		select {
+		case e := <-agent.MessageQueue:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestFunctionCallAgentAbandonsStalledModelCall(t *testing.T) {
+	client := &stallingLLMClient{stalls: 1, release: make(chan struct{})}
+	defer close(client.release)
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, nil)
+	agent.TurnTimeout = 20 * time.Millisecond
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "hello"}, history)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.ToolOutput != "done" {
+		t.Errorf("output = %q; want the answer from the turn after the timeout", out.ToolOutput)
+	}
+
+	var notice string
+	for _, e := range drainEvents(agent) {
+		if e.Type == EventTypeSystem {
+			notice, _ = e.Content["message"].(string)
+		}
+	}
+	if !strings.Contains(notice, "Model call timed out after 20ms") {
+		t.Errorf("system event = %q; want a timeout notice", notice)
+	}
+}
+
+func TestFunctionCallAgentRecordsToolTimeout(t *testing.T) {
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "slow_tool"}},
+	}}
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, []LLMTool{blockingTool{}})
+	agent.TurnTimeout = 20 * time.Millisecond
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "hello"}, history); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var result string
+	for _, msg := range history.messages {
+		if items, ok := msg.Content.([]map[string]interface{}); ok {
+			result, _ = items[0]["result"].(string)
+		}
+	}
+	if !strings.Contains(result, "slow_tool did not finish within 20ms") {
+		t.Errorf("tool result = %q; want a timeout error", result)
+	}
+}
+
+func TestFunctionCallAgentStopsAfterConsecutiveTimeouts(t *testing.T) {
+	client := &stallingLLMClient{stalls: 10, release: make(chan struct{})}
+	defer close(client.release)
+	history := &sliceHistory{}
+	agent := newTestAgent(client, history, nil, nil)
+	agent.TurnTimeout = 10 * time.Millisecond
+	agent.MaxConsecutiveTimeouts = 2
+
+	_, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "hello"}, history)
+	if !errors.Is(err, ErrTurnTimeout) {
+		t.Fatalf("Run() error = %v; want ErrTurnTimeout", err)
+	}
+	if n := len(client.calls); n != 0 {
+		t.Errorf("answered calls = %d; want 0", n)
+	}
+}
+
+// lateTool ignores its context and, once released, streams output and
+// writes to the history.
+type lateTool struct {
+	release chan struct{}
+	done    chan struct{}
+}
+
+func (lateTool) GetToolParam() ToolParam { return ToolParam{Name: "late_tool"} }
+
+func (t lateTool) Run(ctx context.Context, input map[string]interface{}, history MessageHistory) (ToolImplOutput, error) {
+	defer close(t.done)
+	<-t.release
+	tools.OutputSinkFrom(ctx)("stdout", "late line")
+	history.AddUserPrompt("late write", nil)
+	return ToolImplOutput{ToolOutput: "too late"}, nil
+}
+
+func TestAbandonedToolCannotWriteAfterTimeout(t *testing.T) {
+	client := &scriptedLLMClient{responses: [][]interface{}{
+		{ToolCallParameters{ID: "call-1", Name: "late_tool"}},
+	}}
+	history := &sliceHistory{}
+	tool := lateTool{release: make(chan struct{}), done: make(chan struct{})}
+	agent := newTestAgent(client, history, nil, []LLMTool{tool})
+	agent.TurnTimeout = 20 * time.Millisecond
+
+	if _, err := agent.Run(context.Background(), map[string]interface{}{"instruction": "hello"}, history); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	drainEvents(agent)
+	messages := len(history.messages)
+
+	close(tool.release)
+	<-tool.done
+
+	if n := len(history.messages); n != messages {
+		t.Errorf("history grew from %d to %d messages after the tool was abandoned", messages, n)
+	}
+	if events := drainEvents(agent); len(events) != 0 {
+		t.Errorf("abandoned tool emitted %v", events)
+	}
+}
@@ -6,12 +6,16 @@ import (
 
 // EventType constants matching ii_agent
 const (
-	EventTypeUserMessage       = "user_message"
-	EventTypeAgentResponse     = "agent_response"
-	EventTypeAgentThinking     = "agent_thinking"
-	EventTypeToolCall          = "tool_call"
-	EventTypeToolResult        = "tool_result"
-	EventTypeResponseInterrupt = "agent_response_interrupted"
+	EventTypeUserMessage        = "user_message"
+	EventTypeAgentResponse      = "agent_response"
+	EventTypeAgentThinking      = "agent_thinking"
+	EventTypeToolCall           = "tool_call"
+	EventTypeToolResult         = "tool_result"
+	EventTypeResponseInterrupt  = "agent_response_interrupted"
+	EventTypeAgentResponseDelta = "agent_response_delta"
+	EventTypeBrowserTabs        = "browser_tabs"
+	EventTypeSystem             = "system"
+	EventTypeError              = "error"
 )
 
 // --- Tooling & LLM Interfaces ---
@@ -29,6 +33,9 @@ type ToolCallParameters struct {
 	ID        string
 	Name      string
 	Arguments map[string]interface{}
+	// ArgumentsError is set when the model sent arguments that could not
+	// be parsed; the call is rejected without running the tool.
+	ArgumentsError string
 }
 
 // ToolParam describes the tool definition sent to the LLM
@@ -36,6 +43,10 @@ type ToolParam struct {
 	Name        string
 	Description string
 	Schema      map[string]interface{}
+	// RequiresApproval makes the agent ask the user before each run
+	RequiresApproval bool
+	// Final marks a tool whose call ends the run, such as complete
+	Final bool
 }
 
 // LLMTool represents the base interface for any tool (including Agents)
@@ -57,6 +68,19 @@ type LLMClient interface {
 	Generate(ctx context.Context, messages []Message, maxTokens int, tools []ToolParam, systemPrompt string) ([]interface{}, error)
 }
 
+// TokenUsage is the token count of one LLM call.
+type TokenUsage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// UsageReporter is implemented by LLM clients that know the actual usage
+// of their most recent Generate call. Other clients are charged an
+// estimate.
+type UsageReporter interface {
+	LastUsage() TokenUsage
+}
+
 // --- Message History & Context ---
 
 type Message struct {
@@ -69,6 +93,7 @@ type MessageHistory interface {
 	AddAssistantTurn(responses []interface{})
 	AddToolCallResult(toolCall ToolCallParameters, result string)
 	GetMessagesForLLM() []Message
+	SetMessages(messages []Message) // replaces the history, e.g. after summarization
 	GetPendingToolCalls() []ToolCallParameters
 	GetLastAssistantTextResponse() string
 	Clear()
@@ -102,6 +127,11 @@ type WebSocket interface {
 	SendJSON(v interface{}) error
 }
 
+// EventSink persists a session's events, e.g. db.Events.
+type EventSink interface {
+	Save(sessionID, eventType string, payload interface{}) error
+}
+
 // --- LLM Result Types ---
 
 type TextResult struct {
@@ -109,5 +139,6 @@ type TextResult struct {
 }
 
 type ThinkingBlock struct {
-	Thinking string
+	Thinking  string
+	Signature string
 }
\ No newline at end of file
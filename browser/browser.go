@@ -1,9 +1,10 @@
 package browser
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -22,12 +23,20 @@ type Browser struct {
 	state             *BrowserState
 	cdpSession        playwright.CDPSession
 	detector          Detector
+	networkLog        *networkLog
+	downloads         downloads
 	
 	ScreenshotScaleFactor float64
+	// OnTabChange, if set, is called when a tab opens, closes or becomes
+	// the current one.
+	OnTabChange func(TabEvent)
 }
 
 // NewBrowser initializes the browser structure.
 func NewBrowser(config BrowserConfig, closeContext bool) *Browser {
+	if d := config.Device; d != nil && d.Width > 0 && d.Height > 0 {
+		config.ViewportSize = ViewportSize{Width: d.Width, Height: d.Height}
+	}
 	return &Browser{
 		Config:       config,
 		CloseContext: closeContext,
@@ -45,9 +54,29 @@ func initState(url string) *BrowserState {
 	}
 }
 
+// connectOverCDP connects to the remote browser at Config.CDPURL, retrying
+// with Config.ConnectRetry.
+func (b *Browser) connectOverCDP(browserType playwright.BrowserType) (playwright.Browser, error) {
+	var browser playwright.Browser
+	err := retry.Do(
+		func() error {
+			var err error
+			browser, err = browserType.ConnectOverCDP(b.Config.CDPURL, playwright.BrowserTypeConnectOverCDPOptions{
+				Timeout: playwright.Float(2500),
+			})
+			return err
+		},
+		b.Config.connectRetry().options()...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect over CDP: %w", err)
+	}
+	return browser, nil
+}
+
 // Init initializes the Playwright instance, browser, and context.
 func (b *Browser) Init() error {
-	log.Println("Initializing browser")
+	b.logger().Info("initializing browser", "engine", b.engine())
 	var err error
 
 	if b.playwright == nil {
@@ -58,34 +87,23 @@ func (b *Browser) Init() error {
 	}
 
 	if b.playwrightBrowser == nil {
+		browserType, err := b.browserType()
+		if err != nil {
+			return err
+		}
 		if b.Config.CDPURL != "" {
-			log.Printf("Connecting to remote browser via CDP %s", b.Config.CDPURL)
-			err = retry.Do(
-				func() error {
-					b.playwrightBrowser, err = b.playwright.Chromium.ConnectOverCDP(b.Config.CDPURL, playwright.BrowserTypeConnectOverCDPOptions{
-						Timeout: playwright.Float(2500),
-					})
-					return err
-				},
-				retry.Attempts(3),
-				retry.Delay(1*time.Second),
-			)
+			if !b.isChromium() {
+				return fmt.Errorf("connecting over CDP requires the chromium engine, not %s", b.Config.Engine)
+			}
+			b.logger().Info("connecting to remote browser over CDP", "host", urlHost(b.Config.CDPURL))
+			b.logger().Debug("CDP endpoint", "url", b.Config.CDPURL)
+			b.playwrightBrowser, err = b.connectOverCDP(browserType)
 			if err != nil {
-				return fmt.Errorf("failed to connect over CDP: %w", err)
+				return err
 			}
 		} else {
-			log.Println("Launching new browser instance")
-			b.playwrightBrowser, err = b.playwright.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-				Headless: playwright.Bool(false),
-				Args: []string{
-					"--no-sandbox",
-					"--disable-blink-features=AutomationControlled",
-					"--disable-web-security",
-					"--disable-site-isolation-trials",
-					"--disable-features=IsolateOrigins,site-per-process",
-					fmt.Sprintf("--window-size=%d,%d", b.Config.ViewportSize.Width, b.Config.ViewportSize.Height),
-				},
-			})
+			b.logger().Info("launching browser", "engine", b.engine())
+			b.playwrightBrowser, err = browserType.Launch(b.launchOptions())
 			if err != nil {
 				return fmt.Errorf("failed to launch browser: %w", err)
 			}
@@ -96,16 +114,7 @@ func (b *Browser) Init() error {
 		if len(b.playwrightBrowser.Contexts()) > 0 {
 			b.context = b.playwrightBrowser.Contexts()[0]
 		} else {
-			b.context, err = b.playwrightBrowser.NewContext(playwright.BrowserNewContextOptions{
-				Viewport: &playwright.Size{
-					Width:  b.Config.ViewportSize.Width,
-					Height: b.Config.ViewportSize.Height,
-				},
-				UserAgent:         playwright.String("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/85.0.4183.102 Safari/537.36"),
-				JavaScriptEnabled: playwright.Bool(true),
-				BypassCSP:         playwright.Bool(true),
-				IgnoreHttpsErrors: playwright.Bool(true),
-			})
+			b.context, err = b.playwrightBrowser.NewContext(b.contextOptions())
 			if err != nil {
 				return fmt.Errorf("failed to create context: %w", err)
 			}
@@ -113,6 +122,16 @@ func (b *Browser) Init() error {
 		if err := b.applyAntiDetectionScripts(); err != nil {
 			return err
 		}
+		if b.Config.RecordNetwork {
+			b.recordNetwork()
+		}
+		if b.Config.AcceptDownloads {
+			// Download events fire on pages, not the context.
+			for _, page := range b.context.Pages() {
+				b.watchDownloads(page)
+			}
+			b.context.OnPage(b.watchDownloads)
+		}
 	}
 
 	b.context.On("page", func(page playwright.Page) {
@@ -121,14 +140,13 @@ func (b *Browser) Init() error {
 
 	// Add Cookies from storage state if present
 	if cookies, ok := b.Config.StorageState["cookies"]; ok {
-		// Needs robust type assertion in real world, assuming generic map slice here
-		if cookieList, ok := cookies.([]interface{}); ok {
-			var pCookies []playwright.OptionalCookie
-			// conversion logic omitted for brevity, essentially marshalling to OptionalCookie struct
-			// In Go, usually easiest to marshal to JSON then back to proper struct
-			bytes, _ := json.Marshal(cookieList)
-			json.Unmarshal(bytes, &pCookies)
-			b.context.AddCookies(pCookies)
+		// Cookies are decoded JSON from a saved state or the
+		// []playwright.Cookie of GetStorageState; JSON converts either.
+		var pCookies []playwright.OptionalCookie
+		if data, err := json.Marshal(cookies); err == nil && json.Unmarshal(data, &pCookies) == nil && len(pCookies) > 0 {
+			if err := b.context.AddCookies(pCookies); err != nil {
+				b.logger().Warn("failed to restore cookies", "count", len(pCookies), "error", err)
+			}
 		}
 	}
 
@@ -146,24 +164,79 @@ func (b *Browser) Init() error {
 	return nil
 }
 
+// engine returns the configured engine, defaulting to Chromium.
+func (b *Browser) engine() Engine {
+	if b.Config.Engine == "" {
+		return EngineChromium
+	}
+	return b.Config.Engine
+}
+
+func (b *Browser) isChromium() bool {
+	return b.engine() == EngineChromium
+}
+
+// browserType returns the Playwright browser type for the configured engine.
+func (b *Browser) browserType() (playwright.BrowserType, error) {
+	switch b.engine() {
+	case EngineChromium:
+		return b.playwright.Chromium, nil
+	case EngineFirefox:
+		return b.playwright.Firefox, nil
+	case EngineWebKit:
+		return b.playwright.WebKit, nil
+	default:
+		return nil, fmt.Errorf("unknown browser engine %q: use %s, %s or %s",
+			b.Config.Engine, EngineChromium, EngineFirefox, EngineWebKit)
+	}
+}
+
+// launchOptions returns the launch options for the configured engine. The
+// command-line switches are Chromium's; Firefox and WebKit reject them.
+func (b *Browser) launchOptions() playwright.BrowserTypeLaunchOptions {
+	options := playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(false),
+	}
+	if b.isChromium() {
+		options.Args = []string{
+			"--no-sandbox",
+			"--disable-blink-features=AutomationControlled",
+			"--disable-web-security",
+			"--disable-site-isolation-trials",
+			"--disable-features=IsolateOrigins,site-per-process",
+			fmt.Sprintf("--window-size=%d,%d", b.Config.ViewportSize.Width, b.Config.ViewportSize.Height),
+		}
+	}
+	return options
+}
+
 func (b *Browser) onPageChange(page playwright.Page) {
-	log.Printf("Current page changed to %s", page.URL())
+	b.logger().Debug("current page changed", "url", page.URL())
+	defer b.emitTabEvent(TabOpened, page)
+	if !b.isChromium() {
+		// CDP is Chromium-only; the context viewport already applies.
+		b.currentPage = page
+		return
+	}
 	var err error
 	b.cdpSession, err = b.context.NewCDPSession(page)
 	if err != nil {
-		log.Printf("Failed to create CDP session on page change: %v", err)
+		b.logger().Warn("failed to create CDP session on page change", "error", err)
 		return
 	}
 
 	// Set metrics
-	params := map[string]interface{}{
-		"width":             b.Config.ViewportSize.Width,
-		"height":            b.Config.ViewportSize.Height,
-		"deviceScaleFactor": 1,
-		"mobile":            false,
+	b.cdpSession.Send("Emulation.setDeviceMetricsOverride", b.deviceMetricsParams())
+	// A context we did not create (over CDP) has not had the device's
+	// user agent and touch support applied.
+	if b.Config.Device != nil {
+		d := b.device()
+		if d.UserAgent != "" {
+			b.cdpSession.Send("Emulation.setUserAgentOverride", map[string]interface{}{"userAgent": d.UserAgent})
+		}
+		b.cdpSession.Send("Emulation.setTouchEmulationEnabled", map[string]interface{}{"enabled": d.Mobile})
 	}
-	b.cdpSession.Send("Emulation.setDeviceMetricsOverride", params)
-	
+
 	b.cdpSession.Send("Emulation.setVisibleSize", map[string]interface{}{
 		"width":  b.Config.ViewportSize.Width,
 		"height": b.Config.ViewportSize.Height,
@@ -172,36 +245,124 @@ func (b *Browser) onPageChange(page playwright.Page) {
 	b.currentPage = page
 }
 
+// device returns the emulated device: Config.Device, or a desktop at
+// Config.ViewportSize, with defaults filled in.
+func (b *Browser) device() DeviceProfile {
+	d := DeviceProfile{}
+	if b.Config.Device != nil {
+		d = *b.Config.Device
+	}
+	d.Width, d.Height = b.Config.ViewportSize.Width, b.Config.ViewportSize.Height
+	if d.DeviceScaleFactor <= 0 {
+		d.DeviceScaleFactor = 1
+	}
+	// A Chrome user agent on another engine would contradict every other
+	// fingerprint, so Firefox and WebKit keep their own unless a device
+	// asks for one.
+	if d.UserAgent == "" && b.isChromium() {
+		d.UserAgent = chromeUserAgent
+	}
+	return d
+}
+
+// contextOptions are the options for a new browser context emulating
+// device().
+func (b *Browser) contextOptions() playwright.BrowserNewContextOptions {
+	d := b.device()
+	options := playwright.BrowserNewContextOptions{
+		Viewport:          &playwright.Size{Width: d.Width, Height: d.Height},
+		DeviceScaleFactor: playwright.Float(d.DeviceScaleFactor),
+		JavaScriptEnabled: playwright.Bool(true),
+		BypassCSP:         playwright.Bool(true),
+		IgnoreHttpsErrors: playwright.Bool(true),
+		AcceptDownloads:   playwright.Bool(b.Config.AcceptDownloads),
+	}
+	if d.UserAgent != "" {
+		options.UserAgent = playwright.String(d.UserAgent)
+	}
+	if d.Mobile {
+		// Firefox cannot emulate mobile devices; it gets the size only.
+		if b.engine() == EngineFirefox {
+			b.logger().Warn("firefox does not support mobile emulation; using the device size only")
+		} else {
+			options.IsMobile = playwright.Bool(true)
+			options.HasTouch = playwright.Bool(true)
+		}
+	}
+	return options
+}
+
+// deviceMetricsParams are the Emulation.setDeviceMetricsOverride
+// parameters for device().
+func (b *Browser) deviceMetricsParams() map[string]interface{} {
+	d := b.device()
+	return map[string]interface{}{
+		"width":             d.Width,
+		"height":            d.Height,
+		"deviceScaleFactor": d.DeviceScaleFactor,
+		"mobile":            d.Mobile,
+	}
+}
+
+const chromeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/85.0.4183.102 Safari/537.36"
+
+// openShadowRootsScript forces shadow roots open so that element detection
+// can see inside them. It works on every engine.
+const openShadowRootsScript = `
+	(function () {
+		const originalAttachShadow = Element.prototype.attachShadow;
+		Element.prototype.attachShadow = function attachShadow(options) {
+			return originalAttachShadow.call(this, { ...options, mode: "open" });
+		};
+	})();
+`
+
+// chromiumAntiDetectionScript hides the automation fingerprints that
+// Chromium exposes under Playwright.
+const chromiumAntiDetectionScript = `
+	// Webdriver property
+	Object.defineProperty(navigator, 'webdriver', { get: () => undefined });
+	// Languages
+	Object.defineProperty(navigator, 'languages', { get: () => ['en-US'] });
+	// Plugins
+	Object.defineProperty(navigator, 'plugins', { get: () => [1, 2, 3, 4, 5] });
+	// Chrome runtime
+	window.chrome = { runtime: {} };
+	// Permissions
+	const originalQuery = window.navigator.permissions.query;
+	window.navigator.permissions.query = (parameters) => (
+		parameters.name === 'notifications' ?
+			Promise.resolve({ state: Notification.permission }) :
+			originalQuery(parameters)
+	);
+`
+
+// webdriverScript hides navigator.webdriver, the one automation flag that
+// Firefox and WebKit share with Chromium.
+const webdriverScript = `
+	Object.defineProperty(navigator, 'webdriver', { get: () => undefined });
+`
+
+// antiDetectionScript returns the init script for the configured engine.
+// The Chromium fingerprint patches (window.chrome, plugins, permissions)
+// would stand out on Firefox and WebKit, so those only hide webdriver.
+func (b *Browser) antiDetectionScript() string {
+	if b.isChromium() {
+		return chromiumAntiDetectionScript + openShadowRootsScript
+	}
+	return webdriverScript + openShadowRootsScript
+}
+
 func (b *Browser) applyAntiDetectionScripts() error {
-	script := `
-		// Webdriver property
-		Object.defineProperty(navigator, 'webdriver', { get: () => undefined });
-		// Languages
-		Object.defineProperty(navigator, 'languages', { get: () => ['en-US'] });
-		// Plugins
-		Object.defineProperty(navigator, 'plugins', { get: () => [1, 2, 3, 4, 5] });
-		// Chrome runtime
-		window.chrome = { runtime: {} };
-		// Permissions
-		const originalQuery = window.navigator.permissions.query;
-		window.navigator.permissions.query = (parameters) => (
-			parameters.name === 'notifications' ?
-				Promise.resolve({ state: Notification.permission }) :
-				originalQuery(parameters)
-		);
-		(function () {
-			const originalAttachShadow = Element.prototype.attachShadow;
-			Element.prototype.attachShadow = function attachShadow(options) {
-				return originalAttachShadow.call(this, { ...options, mode: "open" });
-			};
-		})();
-	`
-	err := b.context.AddInitScript(playwright.Script{Content: playwright.String(script)})
+	if !b.isChromium() {
+		b.logger().Debug("skipping Chromium anti-detection patches", "engine", b.engine())
+	}
+	err := b.context.AddInitScript(playwright.Script{Content: playwright.String(b.antiDetectionScript())})
 	return err
 }
 
 func (b *Browser) Close() error {
-	log.Println("Closing browser")
+	b.logger().Info("closing browser")
 	if b.cdpSession != nil {
 		b.cdpSession.Detach()
 		b.cdpSession = nil
@@ -228,7 +389,56 @@ func (b *Browser) Restart() error {
 	return b.Init()
 }
 
-func (b *Browser) Goto(url string) error {
+// RestartWithStorageState restarts the browser and restores the cookies of
+// state, as returned by GetStorageState.
+func (b *Browser) RestartWithStorageState(state map[string]interface{}) error {
+	if state != nil {
+		b.Config.StorageState = state
+	}
+	return b.Restart()
+}
+
+// OnDisconnected calls fn when the browser disconnects or its context
+// closes, e.g. because Playwright crashed.
+func (b *Browser) OnDisconnected(fn func()) {
+	if b.playwrightBrowser != nil {
+		b.playwrightBrowser.OnDisconnected(func(playwright.Browser) { fn() })
+	}
+	if b.context != nil {
+		b.context.OnClose(func(playwright.BrowserContext) { fn() })
+	}
+}
+
+const (
+	// DefaultGotoWait is how long Goto sleeps after navigating when no
+	// WaitCondition is given.
+	DefaultGotoWait = 2 * time.Second
+	// DefaultWaitTimeout bounds a WaitCondition or WaitForSelector with
+	// no timeout of its own.
+	DefaultWaitTimeout = 30 * time.Second
+)
+
+// WaitCondition says what Goto waits for once the DOM has loaded. When
+// both LoadState and Selector are set, Goto waits for the load state and
+// then the selector. The zero value sleeps for DefaultGotoWait.
+type WaitCondition struct {
+	LoadState *playwright.LoadState
+	Selector  string
+	// Timeout applies to each wait; zero means DefaultWaitTimeout.
+	Timeout time.Duration
+}
+
+// timeoutMillis converts a wait timeout to Playwright's milliseconds.
+func timeoutMillis(timeout time.Duration) *float64 {
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	return playwright.Float(float64(timeout.Milliseconds()))
+}
+
+// Goto navigates the current tab to url and waits for the first condition
+// in wait, or for DefaultGotoWait if there is none.
+func (b *Browser) Goto(url string, wait ...WaitCondition) error {
 	page, err := b.GetCurrentPage()
 	if err != nil {
 		return err
@@ -237,10 +447,134 @@ func (b *Browser) Goto(url string) error {
 	if err != nil {
 		return err
 	}
-	time.Sleep(2 * time.Second)
+	if len(wait) == 0 || (wait[0].LoadState == nil && wait[0].Selector == "") {
+		time.Sleep(DefaultGotoWait)
+		return nil
+	}
+
+	cond := wait[0]
+	if cond.LoadState != nil {
+		err := page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+			State:   cond.LoadState,
+			Timeout: timeoutMillis(cond.Timeout),
+		})
+		if err != nil {
+			return fmt.Errorf("waiting for %s: %w", *cond.LoadState, err)
+		}
+	}
+	if cond.Selector != "" {
+		return b.WaitForSelector(cond.Selector, cond.Timeout)
+	}
+	return nil
+}
+
+// WaitForSelector waits until an element matching selector is visible on
+// the current tab. A zero timeout means DefaultWaitTimeout.
+func (b *Browser) WaitForSelector(selector string, timeout time.Duration) error {
+	page, err := b.GetCurrentPage()
+	if err != nil {
+		return err
+	}
+	err = page.Locator(selector).First().WaitFor(playwright.LocatorWaitForOptions{
+		State:   playwright.WaitForSelectorStateVisible,
+		Timeout: timeoutMillis(timeout),
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %q: %w", selector, err)
+	}
 	return nil
 }
 
+// pageMarkdownScript renders the rendered DOM of the current page as
+// lightweight markdown: headings, list items and links are marked up and
+// everything else is kept as visible text.
+const pageMarkdownScript = `() => {
+	const skip = new Set(["SCRIPT", "STYLE", "NOSCRIPT", "SVG", "IFRAME", "NAV", "FOOTER"]);
+	const out = [];
+	const walk = (node) => {
+		if (node.nodeType === Node.TEXT_NODE) {
+			const text = node.textContent.replace(/\s+/g, " ");
+			if (text.trim()) out.push(text);
+			return;
+		}
+		if (node.nodeType !== Node.ELEMENT_NODE || skip.has(node.tagName)) return;
+		const style = window.getComputedStyle(node);
+		if (style.display === "none" || style.visibility === "hidden") return;
+		const tag = node.tagName;
+		if (/^H[1-6]$/.test(tag)) out.push("\n\n" + "#".repeat(Number(tag[1])) + " ");
+		else if (tag === "LI") out.push("\n- ");
+		else if (tag === "BR") out.push("\n");
+		else if (style.display === "block" || tag === "P" || tag === "TR") out.push("\n\n");
+		if (tag === "A" && node.href) {
+			const text = node.innerText.replace(/\s+/g, " ").trim();
+			if (text) out.push("[" + text + "](" + node.href + ")");
+			return;
+		}
+		node.childNodes.forEach(walk);
+	};
+	walk(document.body);
+	return out.join("").replace(/[ \t]+\n/g, "\n").replace(/\n{3,}/g, "\n\n").trim();
+}`
+
+// GetPageMarkdown navigates the current tab to url and returns the rendered
+// page as markdown, headed by its title. Unlike a plain HTTP fetch this sees
+// content produced by JavaScript.
+func (b *Browser) GetPageMarkdown(url string) (string, error) {
+	if err := b.Goto(url); err != nil {
+		return "", err
+	}
+	page, err := b.GetCurrentPage()
+	if err != nil {
+		return "", err
+	}
+	result, err := page.Evaluate(pageMarkdownScript)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract page content: %w", err)
+	}
+	text, _ := result.(string)
+	if title, _ := page.Title(); title != "" {
+		text = "# " + title + "\n\n" + text
+	}
+	return text, nil
+}
+
+// MaxEvaluateResultSize caps the JSON size of a value returned by Evaluate
+// so that a runaway script cannot flood the agent's context.
+const MaxEvaluateResultSize = 64 * 1024
+
+// Evaluate runs script (a JavaScript expression or function) in the current
+// page and returns its result as plain JSON values: numbers are float64,
+// objects map[string]interface{}. Results that cannot be serialized, or that
+// exceed MaxEvaluateResultSize once serialized, are errors.
+func (b *Browser) Evaluate(script string) (interface{}, error) {
+	page, err := b.GetCurrentPage()
+	if err != nil {
+		return nil, err
+	}
+	result, err := page.Evaluate(script)
+	if err != nil {
+		return nil, fmt.Errorf("script failed or returned a value that cannot be serialized: %w", err)
+	}
+	return evaluateResult(result)
+}
+
+// evaluateResult round-trips an evaluation result through JSON, enforcing
+// MaxEvaluateResultSize.
+func evaluateResult(result interface{}) (interface{}, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("script result cannot be serialized to JSON: %w", err)
+	}
+	if len(data) > MaxEvaluateResultSize {
+		return nil, fmt.Errorf("script result is %d bytes; the limit is %d", len(data), MaxEvaluateResultSize)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("script result cannot be serialized to JSON: %w", err)
+	}
+	return value, nil
+}
+
 func (b *Browser) GetTabsInfo() ([]TabInfo, error) {
 	var tabs []TabInfo
 	for i, page := range b.context.Pages() {
@@ -269,6 +603,7 @@ func (b *Browser) SwitchToTab(pageID int) error {
 	b.currentPage = page
 	page.BringToFront()
 	page.WaitForLoadState()
+	b.emitTabEvent(TabActivated, page)
 	return nil
 }
 
@@ -285,9 +620,11 @@ func (b *Browser) CreateNewTab(url string) error {
 	b.currentPage = newPage
 	newPage.WaitForLoadState()
 	if url != "" {
-		_, err := newPage.Goto(url, playwright.PageGotoOptions{WaitUntil: playwright.WaitUntilStateDomcontentloaded})
-		return err
+		if _, err := newPage.Goto(url, playwright.PageGotoOptions{WaitUntil: playwright.WaitUntilStateDomcontentloaded}); err != nil {
+			return err
+		}
 	}
+	b.emitTabEvent(TabActivated, newPage)
 	return nil
 }
 
@@ -295,7 +632,13 @@ func (b *Browser) CloseCurrentTab() error {
 	if b.currentPage == nil {
 		return nil
 	}
+	var closed TabInfo
+	if b.OnTabChange != nil {
+		closed = b.tabInfo(b.currentPage)
+	}
 	b.currentPage.Close()
+	b.currentPage = nil
+	b.notifyTabChange(TabClosed, closed)
 	
 	if b.context != nil && len(b.context.Pages()) > 0 {
 		return b.SwitchToTab(0)
@@ -350,7 +693,7 @@ func (b *Browser) updateStateInternal() (*BrowserState, error) {
 				interactiveElements[el.Index] = el
 			}
 
-			highlightScreenshot := PutHighlightElementsOnScreenshot(interactiveElements, screenshotB64)
+			highlightScreenshot := putHighlightElements(b.logger(), interactiveElements, screenshotB64)
 			tabs, _ := b.GetTabsInfo()
 
 			state = &BrowserState{
@@ -363,12 +706,11 @@ func (b *Browser) updateStateInternal() (*BrowserState, error) {
 			}
 			return nil
 		},
-		retry.Attempts(3),
-		retry.DelayType(retry.BackOffDelay),
+		b.Config.stateRetry().options()...,
 	)
 
 	if err != nil {
-		log.Printf("Failed to update state after multiple attempts: %v", err)
+		b.logger().Warn("failed to update state after retries", "error", err)
 		if b.state != nil {
 			return b.state, nil
 		}
@@ -397,29 +739,30 @@ func (b *Browser) DetectBrowserElements() (InteractiveElementsData, error) {
 	return data, nil
 }
 
+// GetInteractiveElements returns the DOM's interactive elements, merged
+// with those the Detector finds in the screenshot when one is configured,
+// with overlapping elements suppressed and the rest sorted into reading
+// order.
 func (b *Browser) GetInteractiveElements(screenshotB64 string, detectSheets bool) (InteractiveElementsData, error) {
-	var elements []InteractiveElement
 	browserData, err := b.DetectBrowserElements()
 	if err != nil {
 		return InteractiveElementsData{}, err
 	}
 
+	elements := browserData.Elements
 	if b.detector != nil {
-		scaleFactor := float64(browserData.Viewport.Width) / 1024.0
+		scaleFactor := float64(browserData.Viewport.Width) / b.Config.detectorBaselineWidth()
 		cvElements, err := b.detector.DetectFromImage(screenshotB64, scaleFactor, detectSheets)
 		if err == nil {
-			elements = append(browserData.Elements, cvElements...)
-			elements = FilterElements(elements, 0.7)
+			elements = append(elements, cvElements...)
 		} else {
-			elements = browserData.Elements
+			b.logger().Warn("element detection failed, using DOM elements only", "error", err)
 		}
-	} else {
-		elements = browserData.Elements
 	}
 
 	return InteractiveElementsData{
 		Viewport: browserData.Viewport,
-		Elements: elements,
+		Elements: FilterElements(elements, b.Config.iouThreshold()),
 	}, nil
 }
 
@@ -436,16 +779,146 @@ func (b *Browser) GetCDPSession() (playwright.CDPSession, error) {
 	return b.cdpSession, nil
 }
 
+// DefaultMaxScreenshotHeight caps full-page screenshots when
+// BrowserConfig.MaxScreenshotHeight is unset. Very tall pages are cut off
+// here rather than decoded into one huge image.
+const DefaultMaxScreenshotHeight = 16384
+
+// FastScreenshot captures the viewport in CSS pixels, the coordinates of
+// the interactive elements, whatever the device scale factor.
 func (b *Browser) FastScreenshot() (string, error) {
-	session, err := b.GetCDPSession()
+	if !b.isChromium() {
+		// Without CDP, fall back to Playwright's own viewport screenshot.
+		data, err := b.currentPage.Screenshot(playwright.PageScreenshotOptions{Scale: playwright.ScreenshotScaleCss})
+		if err != nil {
+			return "", err
+		}
+		return ScaleB64Image(base64.StdEncoding.EncodeToString(data), b.ScreenshotScaleFactor), nil
+	}
+
+	metrics, err := b.layoutMetrics()
 	if err != nil {
 		return "", err
 	}
-
-	params := map[string]interface{}{
+	vv := metrics.CSSVisualViewport
+	if vv.ClientWidth <= 0 || vv.ClientHeight <= 0 {
+		vv.ClientWidth = float64(b.Config.ViewportSize.Width)
+		vv.ClientHeight = float64(b.Config.ViewportSize.Height)
+	}
+	return b.captureScreenshot(map[string]interface{}{
 		"format":                "png",
 		"fromSurface":           false,
 		"captureBeyondViewport": false,
+		"clip": map[string]interface{}{
+			"x":      vv.PageX,
+			"y":      vv.PageY,
+			"width":  vv.ClientWidth,
+			"height": vv.ClientHeight,
+			"scale":  b.cssScale(),
+		},
+	})
+}
+
+// cssScale is the clip scale that captures one image pixel per CSS pixel
+// on a device with a device scale factor.
+func (b *Browser) cssScale() float64 {
+	return 1 / b.device().DeviceScaleFactor
+}
+
+// layoutMetrics are the parts of Page.getLayoutMetrics the screenshots
+// use, in CSS pixels.
+type layoutMetrics struct {
+	CSSContentSize struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	} `json:"cssContentSize"`
+	CSSVisualViewport struct {
+		PageX        float64 `json:"pageX"`
+		PageY        float64 `json:"pageY"`
+		ClientWidth  float64 `json:"clientWidth"`
+		ClientHeight float64 `json:"clientHeight"`
+	} `json:"cssVisualViewport"`
+}
+
+func (b *Browser) layoutMetrics() (layoutMetrics, error) {
+	var metrics layoutMetrics
+	session, err := b.GetCDPSession()
+	if err != nil {
+		return metrics, err
+	}
+	result, err := session.Send("Page.getLayoutMetrics", nil)
+	if err != nil {
+		return metrics, err
+	}
+	jsonBytes, _ := json.Marshal(result)
+	json.Unmarshal(jsonBytes, &metrics)
+	return metrics, nil
+}
+
+// FullPageScreenshot captures the whole scrollable page rather than just
+// the viewport, up to MaxScreenshotHeight, and returns it as a base64 PNG.
+func (b *Browser) FullPageScreenshot() (string, error) {
+	maxHeight := b.Config.MaxScreenshotHeight
+	if maxHeight <= 0 {
+		maxHeight = DefaultMaxScreenshotHeight
+	}
+
+	if !b.isChromium() {
+		page, err := b.GetCurrentPage()
+		if err != nil {
+			return "", err
+		}
+		width := float64(b.Config.ViewportSize.Width)
+		if size := page.ViewportSize(); size != nil {
+			width = float64(size.Width)
+		}
+		data, err := page.Screenshot(playwright.PageScreenshotOptions{
+			FullPage: playwright.Bool(true),
+			Clip:     &playwright.Rect{Width: width, Height: float64(maxHeight)},
+			Scale:    playwright.ScreenshotScaleCss,
+		})
+		if err != nil {
+			return "", err
+		}
+		return ScaleB64Image(base64.StdEncoding.EncodeToString(data), b.ScreenshotScaleFactor), nil
+	}
+
+	metrics, err := b.layoutMetrics()
+	if err != nil {
+		return "", err
+	}
+
+	width := metrics.CSSContentSize.Width
+	height := metrics.CSSContentSize.Height
+	if width <= 0 || height <= 0 {
+		width = float64(b.Config.ViewportSize.Width)
+		height = float64(b.Config.ViewportSize.Height)
+	}
+	if height > float64(maxHeight) {
+		b.logger().Info("capping full-page screenshot", "page_height", height, "max_height", maxHeight)
+		height = float64(maxHeight)
+	}
+
+	return b.captureScreenshot(map[string]interface{}{
+		"format":                "png",
+		"fromSurface":           true,
+		"captureBeyondViewport": true,
+		"clip": map[string]interface{}{
+			"x":      0,
+			"y":      0,
+			"width":  width,
+			"height": height,
+			"scale":  b.cssScale(),
+		},
+	})
+}
+
+// captureScreenshot sends Page.captureScreenshot with params over CDP and
+// returns the scaled base64 image.
+func (b *Browser) captureScreenshot(params map[string]interface{}) (string, error) {
+	session, err := b.GetCDPSession()
+	if err != nil {
+		return "", err
 	}
 
 	result, err := session.Send("Page.captureScreenshot", params)
@@ -473,7 +946,7 @@ func (b *Browser) HandlePDFURLNavigation() (*BrowserState, error) {
 		return nil, err
 	}
 	
-	if IsPDFURL(page.URL()) {
+	if IsPDFURL(context.Background(), page.URL()) {
 		time.Sleep(5 * time.Second)
 		page.Keyboard().Press("Escape")
 		time.Sleep(100 * time.Millisecond)
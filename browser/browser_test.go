@@ -0,0 +1,343 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fakeBrowserType records which engine was asked to start and fails, so
+// Init stops before it needs a real browser.
+type fakeBrowserType struct {
+	playwright.BrowserType
+	name     string
+	launched *[]string
+}
+
+func (f *fakeBrowserType) Launch(options ...playwright.BrowserTypeLaunchOptions) (playwright.Browser, error) {
+	*f.launched = append(*f.launched, f.name)
+	return nil, errors.New("no browser in tests")
+}
+
+func (f *fakeBrowserType) ConnectOverCDP(url string, options ...playwright.BrowserTypeConnectOverCDPOptions) (playwright.Browser, error) {
+	*f.launched = append(*f.launched, f.name+" over CDP")
+	return nil, errors.New("no browser in tests")
+}
+
+func newFakeBrowser(config BrowserConfig) (*Browser, *[]string) {
+	var launched []string
+	b := NewBrowser(config, true)
+	b.playwright = &playwright.Playwright{
+		Chromium: &fakeBrowserType{name: "chromium", launched: &launched},
+		Firefox:  &fakeBrowserType{name: "firefox", launched: &launched},
+		WebKit:   &fakeBrowserType{name: "webkit", launched: &launched},
+	}
+	return b, &launched
+}
+
+// flakyBrowserType fails ConnectOverCDP until its failures are used up.
+type flakyBrowserType struct {
+	playwright.BrowserType
+	failures int
+	calls    int
+}
+
+func (f *flakyBrowserType) ConnectOverCDP(url string, options ...playwright.BrowserTypeConnectOverCDPOptions) (playwright.Browser, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	return &connectedBrowser{}, nil
+}
+
+// connectedBrowser stands in for a connected browser.
+type connectedBrowser struct{ playwright.Browser }
+
+func TestConnectOverCDPHonorsRetryAttempts(t *testing.T) {
+	tests := []struct {
+		attempts  uint
+		failures  int
+		wantErr   bool
+		wantCalls int
+	}{
+		{attempts: 5, failures: 4, wantCalls: 5},
+		{attempts: 5, failures: 5, wantErr: true, wantCalls: 5},
+		{attempts: 1, failures: 0, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		config := DefaultBrowserConfig()
+		config.CDPURL = "http://localhost:9222"
+		config.ConnectRetry = RetryPolicy{Attempts: tt.attempts, Delay: time.Millisecond, MaxJitter: time.Millisecond}
+		browserType := &flakyBrowserType{failures: tt.failures}
+
+		browser, err := NewBrowser(config, true).connectOverCDP(browserType)
+		if (err != nil) != tt.wantErr || (err == nil && browser == nil) {
+			t.Errorf("attempts %d, failures %d: connectOverCDP() = %v, %v; want error %v", tt.attempts, tt.failures, browser, err, tt.wantErr)
+		}
+		if browserType.calls != tt.wantCalls {
+			t.Errorf("attempts %d, failures %d: %d connect calls; want %d", tt.attempts, tt.failures, browserType.calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	got := RetryPolicy{Attempts: 7, MaxJitter: -1}.orDefault(DefaultConnectRetry)
+	want := RetryPolicy{Attempts: 7, Delay: DefaultConnectRetry.Delay, MaxDelay: DefaultConnectRetry.MaxDelay, MaxJitter: -1}
+	if got != want {
+		t.Errorf("orDefault() = %+v; want %+v", got, want)
+	}
+}
+
+func TestInitSelectsEngine(t *testing.T) {
+	tests := []struct {
+		engine Engine
+		want   string
+	}{
+		{"", "chromium"},
+		{EngineChromium, "chromium"},
+		{EngineFirefox, "firefox"},
+		{EngineWebKit, "webkit"},
+	}
+	for _, tt := range tests {
+		config := DefaultBrowserConfig()
+		config.Engine = tt.engine
+		b, launched := newFakeBrowser(config)
+		if err := b.Init(); err == nil {
+			t.Fatalf("Init(%q) error = nil; want the fake launch failure", tt.engine)
+		}
+		if len(*launched) != 1 || (*launched)[0] != tt.want {
+			t.Errorf("Init(%q) launched %v; want [%s]", tt.engine, *launched, tt.want)
+		}
+	}
+}
+
+func TestInitRejectsCDPForOtherEngines(t *testing.T) {
+	config := DefaultBrowserConfig()
+	config.Engine = EngineFirefox
+	config.CDPURL = "http://localhost:9222"
+	b, launched := newFakeBrowser(config)
+
+	err := b.Init()
+	if err == nil || !strings.Contains(err.Error(), "requires the chromium engine") {
+		t.Errorf("Init() error = %v; want a chromium-only CDP error", err)
+	}
+	if len(*launched) != 0 {
+		t.Errorf("launched %v; want nothing started", *launched)
+	}
+}
+
+func TestInitRejectsUnknownEngine(t *testing.T) {
+	config := DefaultBrowserConfig()
+	config.Engine = "netscape"
+	b, _ := newFakeBrowser(config)
+
+	if err := b.Init(); err == nil || !strings.Contains(err.Error(), `unknown browser engine "netscape"`) {
+		t.Errorf("Init() error = %v; want an unknown engine error", err)
+	}
+}
+
+func TestLaunchOptionsPerEngine(t *testing.T) {
+	for _, engine := range []Engine{EngineChromium, EngineFirefox, EngineWebKit} {
+		b := NewBrowser(BrowserConfig{Engine: engine, ViewportSize: ViewportSize{Width: 800, Height: 600}}, true)
+		args := b.launchOptions().Args
+		if engine == EngineChromium && len(args) == 0 {
+			t.Errorf("%s launch args empty; want the Chromium switches", engine)
+		}
+		if engine != EngineChromium && len(args) != 0 {
+			t.Errorf("%s launch args = %v; want none", engine, args)
+		}
+
+		script := b.antiDetectionScript()
+		if hasChrome := strings.Contains(script, "window.chrome"); hasChrome != (engine == EngineChromium) {
+			t.Errorf("%s anti-detection script patches window.chrome = %v", engine, hasChrome)
+		}
+		if !strings.Contains(script, "attachShadow") {
+			t.Errorf("%s anti-detection script should open shadow roots", engine)
+		}
+	}
+}
+
+// fakeCDPSession answers layout metrics with a fixed page size and records
+// the screenshot parameters it is sent.
+type fakeCDPSession struct {
+	playwright.CDPSession
+	pageHeight float64
+	screenshot map[string]interface{}
+	// dpr, when set, makes screenshots white PNGs the size of the clip
+	// in device pixels instead of placeholder data.
+	dpr float64
+}
+
+func (f *fakeCDPSession) Send(method string, params map[string]interface{}) (interface{}, error) {
+	switch method {
+	case "Page.getLayoutMetrics":
+		return map[string]interface{}{
+			"cssContentSize": map[string]interface{}{"width": 1268.0, "height": f.pageHeight},
+		}, nil
+	case "Page.captureScreenshot":
+		f.screenshot = params
+		if f.dpr > 0 {
+			clip := params["clip"].(map[string]interface{})
+			scale := clip["scale"].(float64) * f.dpr
+			img := image.NewRGBA(image.Rect(0, 0, int(clip["width"].(float64)*scale), int(clip["height"].(float64)*scale)))
+			draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+			var buf bytes.Buffer
+			png.Encode(&buf, img)
+			return map[string]interface{}{"data": base64.StdEncoding.EncodeToString(buf.Bytes())}, nil
+		}
+		return map[string]interface{}{"data": "aW1hZ2U="}, nil
+	}
+	return nil, fmt.Errorf("unexpected CDP method %s", method)
+}
+
+func TestFullPageScreenshotCapturesBeyondViewport(t *testing.T) {
+	tests := []struct {
+		name       string
+		pageHeight float64
+		maxHeight  int
+		wantHeight float64
+	}{
+		{"short page", 3000, 0, 3000},
+		{"default cap", 50000, 0, DefaultMaxScreenshotHeight},
+		{"configured cap", 5000, 2000, 2000},
+	}
+	for _, tt := range tests {
+		session := &fakeCDPSession{pageHeight: tt.pageHeight}
+		config := DefaultBrowserConfig()
+		config.MaxScreenshotHeight = tt.maxHeight
+		b := NewBrowser(config, true)
+		b.cdpSession = session
+		b.ScreenshotScaleFactor = 1
+
+		got, err := b.FullPageScreenshot()
+		if err != nil {
+			t.Fatalf("%s: FullPageScreenshot() error = %v", tt.name, err)
+		}
+		if got != "aW1hZ2U=" {
+			t.Errorf("%s: screenshot = %q; want the CDP data", tt.name, got)
+		}
+		if session.screenshot["captureBeyondViewport"] != true {
+			t.Errorf("%s: captureBeyondViewport = %v; want true", tt.name, session.screenshot["captureBeyondViewport"])
+		}
+		clip, _ := session.screenshot["clip"].(map[string]interface{})
+		if clip["height"] != tt.wantHeight {
+			t.Errorf("%s: clip height = %v; want %v", tt.name, clip["height"], tt.wantHeight)
+		}
+	}
+}
+
+func TestFastScreenshotStaysInViewport(t *testing.T) {
+	session := &fakeCDPSession{}
+	b := NewBrowser(DefaultBrowserConfig(), true)
+	b.cdpSession = session
+	b.ScreenshotScaleFactor = 1
+
+	if _, err := b.FastScreenshot(); err != nil {
+		t.Fatalf("FastScreenshot() error = %v", err)
+	}
+	if session.screenshot["captureBeyondViewport"] != false {
+		t.Errorf("captureBeyondViewport = %v; want false", session.screenshot["captureBeyondViewport"])
+	}
+}
+
+func TestTimeoutMillis(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    float64
+	}{
+		{0, 30000},
+		{-time.Second, 30000},
+		{1500 * time.Millisecond, 1500},
+	}
+	for _, tt := range tests {
+		if got := *timeoutMillis(tt.timeout); got != tt.want {
+			t.Errorf("timeoutMillis(%v) = %v; want %v", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+// delayedContentPage adds #ready to the page a while after it loads.
+const delayedContentPage = `<html><body><script>
+	setTimeout(() => {
+		const el = document.createElement("div");
+		el.id = "ready";
+		el.textContent = "loaded late";
+		document.body.appendChild(el);
+	}, 500);
+</script></body></html>`
+
+func TestGotoWaitsForSelector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, delayedContentPage)
+	}))
+	defer srv.Close()
+
+	b := NewBrowser(DefaultBrowserConfig(), true)
+	if err := b.Init(); err != nil {
+		t.Skipf("browser unavailable: %v", err)
+	}
+	defer b.Close()
+
+	start := time.Now()
+	if err := b.Goto(srv.URL, WaitCondition{Selector: "#ready", Timeout: 5 * time.Second}); err != nil {
+		t.Fatalf("Goto() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= DefaultGotoWait+time.Second {
+		t.Errorf("Goto took %v; want it to return once #ready appears", elapsed)
+	}
+	page, _ := b.GetCurrentPage()
+	if text, err := page.Locator("#ready").TextContent(); err != nil || text != "loaded late" {
+		t.Errorf("#ready text = %q, %v; want the delayed content", text, err)
+	}
+
+	if err := b.WaitForSelector("#never", 200*time.Millisecond); err == nil {
+		t.Error("WaitForSelector(#never) error = nil; want a timeout")
+	}
+}
+
+func TestEvaluateResult(t *testing.T) {
+	got, err := evaluateResult(map[string]interface{}{"count": 2, "items": []string{"a"}})
+	if err != nil {
+		t.Fatalf("evaluateResult() error = %v", err)
+	}
+	m, _ := got.(map[string]interface{})
+	if m["count"] != float64(2) {
+		t.Errorf("count = %#v; want float64(2)", m["count"])
+	}
+
+	if _, err := evaluateResult(math.NaN()); err == nil || !strings.Contains(err.Error(), "cannot be serialized") {
+		t.Errorf("evaluateResult(NaN) error = %v; want a serialization error", err)
+	}
+	if _, err := evaluateResult(strings.Repeat("x", MaxEvaluateResultSize)); err == nil || !strings.Contains(err.Error(), "the limit is") {
+		t.Errorf("evaluateResult(huge) error = %v; want a size error", err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	b := NewBrowser(DefaultBrowserConfig(), true)
+	if err := b.Init(); err != nil {
+		t.Skipf("browser unavailable: %v", err)
+	}
+	defer b.Close()
+
+	got, err := b.Evaluate("() => 1+1")
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got != float64(2) {
+		t.Errorf("Evaluate(1+1) = %#v; want 2", got)
+	}
+}
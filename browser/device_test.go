@@ -0,0 +1,102 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	_ "image/png"
+	"reflect"
+	"testing"
+)
+
+func TestDeviceEmulationParams(t *testing.T) {
+	iphone := DeviceProfiles["iphone-14"]
+	tests := []struct {
+		name        string
+		engine      Engine
+		device      *DeviceProfile
+		wantMetrics map[string]interface{}
+		wantUA      string
+		wantMobile  bool
+	}{
+		{
+			name:        "desktop default",
+			wantMetrics: map[string]interface{}{"width": 1268, "height": 951, "deviceScaleFactor": 1.0, "mobile": false},
+			wantUA:      chromeUserAgent,
+		},
+		{
+			name:        "mobile profile",
+			device:      &iphone,
+			wantMetrics: map[string]interface{}{"width": 390, "height": 844, "deviceScaleFactor": 3.0, "mobile": true},
+			wantUA:      iphone.UserAgent,
+			wantMobile:  true,
+		},
+		{
+			name:        "mobile on firefox keeps the size only",
+			engine:      EngineFirefox,
+			device:      &iphone,
+			wantMetrics: map[string]interface{}{"width": 390, "height": 844, "deviceScaleFactor": 3.0, "mobile": true},
+			wantUA:      iphone.UserAgent,
+		},
+	}
+	for _, tt := range tests {
+		config := DefaultBrowserConfig()
+		config.Engine = tt.engine
+		config.Device = tt.device
+		b := NewBrowser(config, true)
+
+		if got := b.deviceMetricsParams(); !reflect.DeepEqual(got, tt.wantMetrics) {
+			t.Errorf("%s: deviceMetricsParams() = %v; want %v", tt.name, got, tt.wantMetrics)
+		}
+		options := b.contextOptions()
+		if options.Viewport.Width != tt.wantMetrics["width"] || options.Viewport.Height != tt.wantMetrics["height"] {
+			t.Errorf("%s: context viewport = %+v; want %vx%v", tt.name, *options.Viewport, tt.wantMetrics["width"], tt.wantMetrics["height"])
+		}
+		if *options.DeviceScaleFactor != tt.wantMetrics["deviceScaleFactor"] {
+			t.Errorf("%s: context DeviceScaleFactor = %v; want %v", tt.name, *options.DeviceScaleFactor, tt.wantMetrics["deviceScaleFactor"])
+		}
+		if options.UserAgent == nil || *options.UserAgent != tt.wantUA {
+			t.Errorf("%s: context UserAgent = %v; want %q", tt.name, options.UserAgent, tt.wantUA)
+		}
+		if gotMobile := options.IsMobile != nil && *options.IsMobile; gotMobile != tt.wantMobile {
+			t.Errorf("%s: context IsMobile = %v; want %v", tt.name, gotMobile, tt.wantMobile)
+		}
+	}
+}
+
+func TestMobileHighlightsLineUpWithScreenshot(t *testing.T) {
+	iphone := DeviceProfiles["iphone-14"]
+	config := DefaultBrowserConfig()
+	config.Device = &iphone
+	b := NewBrowser(config, true)
+	session := &fakeCDPSession{dpr: iphone.DeviceScaleFactor}
+	b.cdpSession = session
+	b.ScreenshotScaleFactor = 1
+
+	screenshot, err := b.FastScreenshot()
+	if err != nil {
+		t.Fatalf("FastScreenshot() error = %v", err)
+	}
+	clip, _ := session.screenshot["clip"].(map[string]interface{})
+	if clip["scale"] != 1/iphone.DeviceScaleFactor {
+		t.Errorf("clip scale = %v; want 1/%v", clip["scale"], iphone.DeviceScaleFactor)
+	}
+
+	// A button in the bottom right corner, in CSS pixels.
+	button := InteractiveElement{Index: 1, Rect: Rect{Left: 300, Top: 760, Width: 60, Height: 40}}
+	highlighted := putHighlightElements(defaultLogger(), map[int]InteractiveElement{1: button}, screenshot)
+	data, _ := base64.StdEncoding.DecodeString(highlighted)
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode highlighted screenshot: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != iphone.Width || size.Y != iphone.Height {
+		t.Fatalf("screenshot = %dx%d; want the %dx%d CSS viewport", size.X, size.Y, iphone.Width, iphone.Height)
+	}
+	r, g, bl := elementColor(button, 1)
+	want := color.RGBA{uint8(r), uint8(g), uint8(bl), 255}
+	if got := color.RGBAModel.Convert(img.At(300, 780)); got != want {
+		t.Errorf("pixel on the button's left edge = %v; want the highlight %v", got, want)
+	}
+}
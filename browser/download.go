@@ -0,0 +1,114 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DownloadsDirName is the folder under BrowserConfig.WorkspaceDir that
+// downloads are saved to.
+const DownloadsDirName = "downloads"
+
+// DownloadInfo describes a file the browser downloaded.
+type DownloadInfo struct {
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	URL      string `json:"url"`
+	Size     int64  `json:"size"`
+}
+
+// downloads records the files saved so far.
+type downloads struct {
+	mu    sync.Mutex
+	files []DownloadInfo
+}
+
+func (d *downloads) add(info DownloadInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files = append(d.files, info)
+}
+
+func (d *downloads) list() []DownloadInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DownloadInfo(nil), d.files...)
+}
+
+// downloadsDir returns where downloads are saved.
+func (b *Browser) downloadsDir() string {
+	return filepath.Join(b.Config.WorkspaceDir, DownloadsDirName)
+}
+
+// watchDownloads saves every download started from page. Saving waits for
+// the download to finish, so it runs off the event goroutine.
+func (b *Browser) watchDownloads(page playwright.Page) {
+	page.OnDownload(func(download playwright.Download) {
+		go func() {
+			if _, err := b.saveDownload(download); err != nil {
+				b.logger().Warn("failed to save download", "host", urlHost(download.URL()), "error", err)
+			}
+		}()
+	})
+}
+
+// saveDownload saves download into the downloads folder under its
+// suggested name, made unique if a file of that name already exists.
+func (b *Browser) saveDownload(download playwright.Download) (DownloadInfo, error) {
+	dir := b.downloadsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return DownloadInfo{}, err
+	}
+
+	// The suggested name comes from the server; keep only its base name so
+	// it cannot escape the downloads folder.
+	name := filepath.Base(filepath.Clean("/" + download.SuggestedFilename()))
+	if name == "/" || name == "." {
+		name = "download"
+	}
+	path := uniquePath(filepath.Join(dir, name))
+	if err := download.SaveAs(path); err != nil {
+		return DownloadInfo{}, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return DownloadInfo{}, err
+	}
+
+	info := DownloadInfo{
+		Filename: filepath.Base(path),
+		Path:     path,
+		URL:      download.URL(),
+		Size:     stat.Size(),
+	}
+	b.downloads.add(info)
+	b.logger().Info("saved download", "file", info.Filename, "size", info.Size)
+	b.logger().Debug("download source", "url", info.URL, "path", info.Path)
+	return info, nil
+}
+
+// uniquePath returns path, or path with " (n)" before its extension if that
+// file already exists.
+func uniquePath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// GetDownloads returns the files downloaded so far, oldest first.
+func (b *Browser) GetDownloads() []DownloadInfo {
+	return b.downloads.list()
+}
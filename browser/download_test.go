@@ -0,0 +1,61 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fakeDownload stands in for a finished Playwright download.
+type fakeDownload struct {
+	playwright.Download
+	name    string
+	url     string
+	content string
+}
+
+func (d *fakeDownload) SuggestedFilename() string { return d.name }
+func (d *fakeDownload) URL() string               { return d.url }
+
+func (d *fakeDownload) SaveAs(path string) error {
+	return os.WriteFile(path, []byte(d.content), 0644)
+}
+
+func TestSaveDownload(t *testing.T) {
+	workspace := t.TempDir()
+	b := NewBrowser(BrowserConfig{AcceptDownloads: true, WorkspaceDir: workspace}, true)
+	wantDir := filepath.Join(workspace, "downloads")
+
+	downloads := []*fakeDownload{
+		{name: "report.csv", url: "https://example.com/report.csv", content: "a,b\n1,2\n"},
+		{name: "report.csv", url: "https://example.com/report.csv?v=2", content: "a,b\n"},
+		{name: "../../escape.pdf", url: "https://example.com/escape", content: "%PDF"},
+	}
+	wantNames := []string{"report.csv", "report (1).csv", "escape.pdf"}
+
+	for i, d := range downloads {
+		info, err := b.saveDownload(d)
+		if err != nil {
+			t.Fatalf("saveDownload(%q) error = %v", d.name, err)
+		}
+		if info.Filename != wantNames[i] || info.Path != filepath.Join(wantDir, wantNames[i]) {
+			t.Errorf("download %d saved as %s (%s); want %s in %s", i, info.Filename, info.Path, wantNames[i], wantDir)
+		}
+		if info.Size != int64(len(d.content)) || info.URL != d.url {
+			t.Errorf("download %d info = %+v; want size %d and URL %s", i, info, len(d.content), d.url)
+		}
+		if data, err := os.ReadFile(info.Path); err != nil || string(data) != d.content {
+			t.Errorf("download %d file = %q, %v; want %q", i, data, err, d.content)
+		}
+	}
+
+	got := b.GetDownloads()
+	if len(got) != len(downloads) {
+		t.Fatalf("GetDownloads() returned %d entries; want %d", len(got), len(downloads))
+	}
+	if got[0].Filename != "report.csv" {
+		t.Errorf("GetDownloads()[0] = %+v; want report.csv first", got[0])
+	}
+}
@@ -0,0 +1,31 @@
+package browser
+
+import (
+	"log/slog"
+	"net/url"
+
+	"water-ai/core"
+)
+
+// defaultLogger is used when BrowserConfig.Logger is nil.
+func defaultLogger() *slog.Logger {
+	return core.Logger.With("component", "browser")
+}
+
+// logger returns the browser's logger. URLs may carry tokens, so they are
+// only logged in full at debug level; info and above use urlHost.
+func (b *Browser) logger() *slog.Logger {
+	if b.Config.Logger != nil {
+		return b.Config.Logger
+	}
+	return defaultLogger()
+}
+
+// urlHost returns the host of rawURL, safe to log at any level.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(unparsed)"
+	}
+	return u.Host
+}
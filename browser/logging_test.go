@@ -0,0 +1,55 @@
+package browser
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBrowserLoggingRespectsLevel(t *testing.T) {
+	const secretURL = "https://example.com/reset?token=s3cret"
+	tests := []struct {
+		level      slog.Level
+		wantURL    bool
+		wantClosed bool
+	}{
+		{slog.LevelDebug, true, true},
+		{slog.LevelInfo, false, true},
+		{slog.LevelWarn, false, false},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		config := DefaultBrowserConfig()
+		config.Engine = EngineFirefox // skips the CDP session on page change
+		config.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: tt.level}))
+		b := NewBrowser(config, true)
+		ctx := &fakeTabContext{}
+		page, _ := ctx.NewPage()
+		page.(*fakeTabPage).url = secretURL
+
+		b.onPageChange(page)
+		b.Close()
+
+		out := buf.String()
+		if got := strings.Contains(out, "s3cret"); got != tt.wantURL {
+			t.Errorf("level %v: URL logged = %v; want %v\n%s", tt.level, got, tt.wantURL, out)
+		}
+		if got := strings.Contains(out, "closing browser"); got != tt.wantClosed {
+			t.Errorf("level %v: close logged = %v; want %v\n%s", tt.level, got, tt.wantClosed, out)
+		}
+	}
+}
+
+func TestURLHost(t *testing.T) {
+	tests := map[string]string{
+		"ws://user:pw@cdp.example.com:9222/devtools?token=x": "cdp.example.com:9222",
+		"not a url":                 "(unparsed)",
+		"https://example.com/a?b=c": "example.com",
+	}
+	for in, want := range tests {
+		if got := urlHost(in); got != want {
+			t.Errorf("urlHost(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,13 @@ package browser
 
 // models.go
 
+import (
+	"log/slog"
+	"time"
+
+	"github.com/avast/retry-go"
+)
+
 type TabInfo struct {
 	PageID int    `json:"pageId"`
 	URL    string `json:"url"`
@@ -69,16 +76,160 @@ type ViewportSize struct {
 	Height int
 }
 
+// Engine names the Playwright browser engine to drive.
+type Engine string
+
+const (
+	EngineChromium Engine = "chromium"
+	EngineFirefox  Engine = "firefox"
+	EngineWebKit   Engine = "webkit"
+)
+
 type BrowserConfig struct {
+	// Engine selects the browser engine; empty means Chromium. Connecting
+	// over CDP (CDPURL) requires Chromium.
+	Engine       Engine
 	CDPURL       string
 	ViewportSize ViewportSize
+	// Device, if set, is the device to emulate; its size replaces
+	// ViewportSize. Nil emulates a desktop at ViewportSize.
+	Device       *DeviceProfile
 	StorageState map[string]interface{}
 	Detector     Detector
+	// RecordNetwork keeps the most recent requests in a ring buffer of
+	// NetworkLogSize entries (DefaultNetworkLogSize if zero), read with
+	// Browser.GetNetworkLog.
+	RecordNetwork  bool
+	NetworkLogSize int
+	// MaxScreenshotHeight caps FullPageScreenshot, in CSS pixels
+	// (DefaultMaxScreenshotHeight if zero).
+	MaxScreenshotHeight int
+	// AcceptDownloads saves files the pages download into the downloads
+	// folder under WorkspaceDir; otherwise downloads are cancelled.
+	AcceptDownloads bool
+	WorkspaceDir    string
+	// DetectorBaselineWidth is the screenshot width the Detector was tuned
+	// for; detections are scaled by the viewport width over it.
+	DetectorBaselineWidth float64
+	// IoUThreshold is the intersection-over-union above which overlapping
+	// elements are merged into one.
+	IoUThreshold float64
+	// Logger receives the browser's diagnostics; nil uses core.Logger.
+	Logger *slog.Logger
+	// ConnectRetry tunes retries of the CDP connect in Init and StateRetry
+	// those of UpdateState; zero fields take DefaultConnectRetry and
+	// DefaultStateRetry.
+	ConnectRetry RetryPolicy
+	StateRetry   RetryPolicy
+}
+
+// DeviceProfile describes an emulated device. A zero DeviceScaleFactor
+// means 1 and an empty UserAgent keeps the browser's default.
+type DeviceProfile struct {
+	Width             int
+	Height            int
+	DeviceScaleFactor float64
+	Mobile            bool
+	UserAgent         string
+}
+
+// DeviceProfiles are common devices for responsive testing, by name.
+var DeviceProfiles = map[string]DeviceProfile{
+	"iphone-14": {
+		Width: 390, Height: 844, DeviceScaleFactor: 3, Mobile: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"pixel-7": {
+		Width: 412, Height: 915, DeviceScaleFactor: 2.625, Mobile: true,
+		UserAgent: "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
+	},
+	"ipad": {
+		Width: 820, Height: 1180, DeviceScaleFactor: 2, Mobile: true,
+		UserAgent: "Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"desktop-hidpi": {Width: 1440, Height: 900, DeviceScaleFactor: 2},
+}
+
+// RetryPolicy makes up to Attempts tries, waiting Delay after the first
+// failure and doubling it after each one, capped at MaxDelay, plus a random
+// jitter of up to MaxJitter. A negative MaxJitter disables the jitter.
+type RetryPolicy struct {
+	Attempts  uint
+	Delay     time.Duration
+	MaxDelay  time.Duration
+	MaxJitter time.Duration
+}
+
+var (
+	DefaultConnectRetry = RetryPolicy{Attempts: 3, Delay: time.Second, MaxDelay: 10 * time.Second, MaxJitter: 500 * time.Millisecond}
+	DefaultStateRetry   = RetryPolicy{Attempts: 3, Delay: 100 * time.Millisecond, MaxDelay: 2 * time.Second, MaxJitter: 100 * time.Millisecond}
+)
+
+// orDefault fills the zero fields of p from def.
+func (p RetryPolicy) orDefault(def RetryPolicy) RetryPolicy {
+	if p.Attempts == 0 {
+		p.Attempts = def.Attempts
+	}
+	if p.Delay <= 0 {
+		p.Delay = def.Delay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = def.MaxDelay
+	}
+	if p.MaxJitter == 0 {
+		p.MaxJitter = def.MaxJitter
+	}
+	return p
+}
+
+// options returns the retry-go options for exponential backoff with
+// jitter.
+func (p RetryPolicy) options() []retry.Option {
+	delay := retry.DelayType(retry.BackOffDelay)
+	if p.MaxJitter > 0 {
+		delay = retry.DelayType(retry.CombineDelay(retry.BackOffDelay, retry.RandomDelay))
+	}
+	return []retry.Option{
+		retry.Attempts(p.Attempts),
+		retry.Delay(p.Delay),
+		retry.MaxDelay(p.MaxDelay),
+		retry.MaxJitter(p.MaxJitter),
+		delay,
+	}
+}
+
+const (
+	DefaultDetectorBaselineWidth = 1024.0
+	DefaultIoUThreshold          = 0.7
+)
+
+func (c BrowserConfig) detectorBaselineWidth() float64 {
+	if c.DetectorBaselineWidth <= 0 {
+		return DefaultDetectorBaselineWidth
+	}
+	return c.DetectorBaselineWidth
+}
+
+func (c BrowserConfig) connectRetry() RetryPolicy {
+	return c.ConnectRetry.orDefault(DefaultConnectRetry)
+}
+
+func (c BrowserConfig) stateRetry() RetryPolicy {
+	return c.StateRetry.orDefault(DefaultStateRetry)
+}
+
+func (c BrowserConfig) iouThreshold() float64 {
+	if c.IoUThreshold <= 0 {
+		return DefaultIoUThreshold
+	}
+	return c.IoUThreshold
 }
 
 func DefaultBrowserConfig() BrowserConfig {
 	return BrowserConfig{
-		ViewportSize: ViewportSize{Width: 1268, Height: 951},
+		ViewportSize:          ViewportSize{Width: 1268, Height: 951},
+		DetectorBaselineWidth: DefaultDetectorBaselineWidth,
+		IoUThreshold:          DefaultIoUThreshold,
 	}
 }
 
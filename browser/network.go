@@ -0,0 +1,105 @@
+package browser
+
+import (
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DefaultNetworkLogSize is how many requests the network log keeps when
+// BrowserConfig.NetworkLogSize is unset.
+const DefaultNetworkLogSize = 500
+
+// NetworkEntry is one request seen by the browser. Status and ContentType
+// are empty for requests that failed before a response arrived; Failure
+// then says why.
+type NetworkEntry struct {
+	Time         time.Time `json:"time"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	ResourceType string    `json:"resourceType"`
+	Status       int       `json:"status,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	Failure      string    `json:"failure,omitempty"`
+}
+
+// networkLog is a fixed-size ring buffer of network entries; once full,
+// each new entry overwrites the oldest.
+type networkLog struct {
+	mu      sync.Mutex
+	entries []NetworkEntry
+	next    int
+	full    bool
+}
+
+func newNetworkLog(size int) *networkLog {
+	if size <= 0 {
+		size = DefaultNetworkLogSize
+	}
+	return &networkLog{entries: make([]NetworkEntry, size)}
+}
+
+func (l *networkLog) add(entry NetworkEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// snapshot returns the entries oldest first.
+func (l *networkLog) snapshot() []NetworkEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]NetworkEntry(nil), l.entries[:l.next]...)
+	}
+	out := make([]NetworkEntry, 0, len(l.entries))
+	out = append(out, l.entries[l.next:]...)
+	return append(out, l.entries[:l.next]...)
+}
+
+// recordNetwork logs every response and failed request in the browser
+// context, across all of its tabs.
+func (b *Browser) recordNetwork() {
+	if b.networkLog == nil {
+		b.networkLog = newNetworkLog(b.Config.NetworkLogSize)
+	}
+	netLog := b.networkLog
+	b.context.OnResponse(func(response playwright.Response) {
+		request := response.Request()
+		netLog.add(NetworkEntry{
+			Time:         time.Now(),
+			Method:       request.Method(),
+			URL:          response.URL(),
+			ResourceType: request.ResourceType(),
+			Status:       response.Status(),
+			ContentType:  response.Headers()["content-type"],
+		})
+	})
+	b.context.OnRequestFailed(func(request playwright.Request) {
+		entry := NetworkEntry{
+			Time:         time.Now(),
+			Method:       request.Method(),
+			URL:          request.URL(),
+			ResourceType: request.ResourceType(),
+			Failure:      "request failed",
+		}
+		if err := request.Failure(); err != nil {
+			entry.Failure = err.Error()
+		}
+		netLog.add(entry)
+	})
+}
+
+// GetNetworkLog returns the recorded requests, oldest first. It returns nil
+// unless BrowserConfig.RecordNetwork is set.
+func (b *Browser) GetNetworkLog() []NetworkEntry {
+	if b.networkLog == nil {
+		return nil
+	}
+	return b.networkLog.snapshot()
+}
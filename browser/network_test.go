@@ -0,0 +1,76 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNetworkLogKeepsNewestEntries(t *testing.T) {
+	l := newNetworkLog(3)
+	if got := l.snapshot(); len(got) != 0 {
+		t.Errorf("empty snapshot = %v; want none", got)
+	}
+	for i := 1; i <= 5; i++ {
+		l.add(NetworkEntry{URL: fmt.Sprintf("/%d", i)})
+	}
+
+	got := l.snapshot()
+	want := []string{"/3", "/4", "/5"}
+	if len(got) != len(want) {
+		t.Fatalf("snapshot has %d entries; want %d", len(got), len(want))
+	}
+	for i, entry := range got {
+		if entry.URL != want[i] {
+			t.Errorf("entry %d URL = %q; want %q", i, entry.URL, want[i])
+		}
+	}
+}
+
+func TestNetworkLogDefaultSize(t *testing.T) {
+	if l := newNetworkLog(0); len(l.entries) != DefaultNetworkLogSize {
+		t.Errorf("size = %d; want %d", len(l.entries), DefaultNetworkLogSize)
+	}
+}
+
+func TestGetNetworkLogDisabled(t *testing.T) {
+	b := NewBrowser(DefaultBrowserConfig(), true)
+	if got := b.GetNetworkLog(); got != nil {
+		t.Errorf("GetNetworkLog() = %v; want nil when recording is off", got)
+	}
+}
+
+func TestRecordNetworkPageLoad(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body>hello</body></html>")
+	}))
+	defer srv.Close()
+
+	config := DefaultBrowserConfig()
+	config.RecordNetwork = true
+	b := NewBrowser(config, true)
+	if err := b.Init(); err != nil {
+		t.Skipf("browser unavailable: %v", err)
+	}
+	defer b.Close()
+
+	page, err := b.GetCurrentPage()
+	if err != nil {
+		t.Fatalf("GetCurrentPage() error = %v", err)
+	}
+	if _, err := page.Goto(srv.URL + "/page"); err != nil {
+		t.Fatalf("Goto() error = %v", err)
+	}
+
+	for _, entry := range b.GetNetworkLog() {
+		if entry.URL == srv.URL+"/page" {
+			if entry.Method != http.MethodGet || entry.Status != http.StatusOK || entry.ContentType != "text/html; charset=utf-8" {
+				t.Errorf("entry = %+v; want GET 200 text/html", entry)
+			}
+			return
+		}
+	}
+	t.Errorf("network log %v has no entry for %s/page", b.GetNetworkLog(), srv.URL)
+}
@@ -0,0 +1,85 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+
+	"water-ai/utils"
+)
+
+const (
+	// maxPDFBytes bounds how much of a PDF ExtractPDFText downloads.
+	maxPDFBytes = 50 << 20
+	pdfTimeout  = 60 * time.Second
+)
+
+// ExtractPDFText downloads the PDF at url and returns its text, each page
+// headed "--- Page N ---" so that it can be cited, truncated to
+// utils.VisitWebPageMaxOutputLength.
+func ExtractPDFText(url string) (string, error) {
+	client := &http.Client{Timeout: pdfTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPDFBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if len(data) > maxPDFBytes {
+		return "", fmt.Errorf("PDF at %s is larger than %d MB", url, maxPDFBytes>>20)
+	}
+	return PDFText(data)
+}
+
+// PDFText extracts the page-delimited text of the PDF in data, as
+// ExtractPDFText does.
+func PDFText(data []byte) (text string, err error) {
+	// The parser panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	var b strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		pageText, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to read page %d: %w", i, err)
+		}
+		if i > 1 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "--- Page %d ---\n%s", i, strings.TrimSpace(pageText))
+		if b.Len() > utils.VisitWebPageMaxOutputLength {
+			break
+		}
+	}
+
+	text = b.String()
+	if len(text) > utils.VisitWebPageMaxOutputLength {
+		text = text[:utils.VisitWebPageMaxOutputLength] + "\n" + utils.TruncatedMessage
+	}
+	return text, nil
+}
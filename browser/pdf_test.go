@@ -0,0 +1,36 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtractPDFText(t *testing.T) {
+	sample, err := os.ReadFile("testdata/sample.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(sample)
+	}))
+	defer srv.Close()
+
+	text, err := ExtractPDFText(srv.URL + "/sample.pdf")
+	if err != nil {
+		t.Fatalf("ExtractPDFText() error = %v", err)
+	}
+	want := "--- Page 1 ---\nWater covers the Earth\n\n--- Page 2 ---\nRivers flow to the sea"
+	if text != want {
+		t.Errorf("ExtractPDFText() = %q; want %q", text, want)
+	}
+}
+
+func TestPDFTextRejectsInvalidPDF(t *testing.T) {
+	if _, err := PDFText([]byte("%PDF-1.4")); err == nil || !strings.Contains(err.Error(), "failed to parse PDF") {
+		t.Errorf("PDFText(truncated) error = %v; want a parse error", err)
+	}
+}
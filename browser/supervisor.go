@@ -0,0 +1,113 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// restartableBrowser is the part of *Browser a Supervisor drives.
+type restartableBrowser interface {
+	GetStorageState() (map[string]interface{}, error)
+	RestartWithStorageState(state map[string]interface{}) error
+	OnDisconnected(fn func())
+}
+
+// Supervisor restarts a browser that crashed mid-task. Actions run through
+// Do; when one fails because the browser or its context went away, the
+// browser is restarted with the cookies saved before the action and the
+// action is retried once.
+type Supervisor struct {
+	browser restartableBrowser
+	logger  *slog.Logger
+	// OnRestart, if set, is told about each restart and the failure that
+	// caused it, e.g. to notify the user.
+	OnRestart func(cause error)
+
+	mu           sync.Mutex
+	generation   int // bumped on restart so stale disconnect events are ignored
+	disconnected bool
+	storageState map[string]interface{}
+}
+
+// NewSupervisor supervises b, which should already be initialized.
+func NewSupervisor(b restartableBrowser) *Supervisor {
+	s := &Supervisor{browser: b, logger: defaultLogger()}
+	if lb, ok := b.(interface{ logger() *slog.Logger }); ok {
+		s.logger = lb.logger()
+	}
+	s.watch()
+	return s
+}
+
+func (s *Supervisor) watch() {
+	s.mu.Lock()
+	gen := s.generation
+	s.mu.Unlock()
+	s.browser.OnDisconnected(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if gen == s.generation {
+			s.disconnected = true
+		}
+	})
+}
+
+// Disconnected reports whether the browser has gone away since it was last
+// (re)started.
+func (s *Supervisor) Disconnected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.disconnected
+}
+
+// Do runs action, restarting the browser and retrying once if it failed
+// because the browser crashed.
+func (s *Supervisor) Do(action func() error) error {
+	if !s.Disconnected() {
+		if state, err := s.browser.GetStorageState(); err == nil && len(state) > 0 {
+			s.mu.Lock()
+			s.storageState = state
+			s.mu.Unlock()
+		}
+	}
+
+	err := action()
+	if err == nil || !(s.Disconnected() || IsDisconnectError(err)) {
+		return err
+	}
+
+	s.logger.Warn("browser disconnected; restarting", "error", err)
+	s.mu.Lock()
+	s.generation++
+	state := s.storageState
+	s.mu.Unlock()
+	if rerr := s.browser.RestartWithStorageState(state); rerr != nil {
+		return fmt.Errorf("browser crashed and could not be restarted: %w (after %v)", rerr, err)
+	}
+	s.mu.Lock()
+	s.disconnected = false
+	s.mu.Unlock()
+	s.watch()
+	if s.OnRestart != nil {
+		s.OnRestart(err)
+	}
+	return action()
+}
+
+// IsDisconnectError reports whether err comes from a browser, context or
+// page that has been closed or lost its connection.
+func IsDisconnectError(err error) bool {
+	if errors.Is(err, playwright.ErrTargetClosed) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "target closed") ||
+		strings.Contains(msg, "has been closed") ||
+		strings.Contains(msg, "browser has disconnected") ||
+		strings.Contains(msg, "connection closed")
+}
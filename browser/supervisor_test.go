@@ -0,0 +1,95 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// crashingBrowser is a restartableBrowser whose disconnect can be
+// triggered by the test.
+type crashingBrowser struct {
+	cookies    string
+	onDisc     []func()
+	restarts   int
+	restoredTo map[string]interface{}
+}
+
+func (c *crashingBrowser) GetStorageState() (map[string]interface{}, error) {
+	return map[string]interface{}{"cookies": c.cookies}, nil
+}
+
+func (c *crashingBrowser) RestartWithStorageState(state map[string]interface{}) error {
+	c.restarts++
+	c.restoredTo = state
+	c.onDisc = nil
+	return nil
+}
+
+func (c *crashingBrowser) OnDisconnected(fn func()) { c.onDisc = append(c.onDisc, fn) }
+
+func (c *crashingBrowser) crash() {
+	for _, fn := range c.onDisc {
+		fn()
+	}
+}
+
+func TestSupervisorRestartsOnceAfterDisconnect(t *testing.T) {
+	b := &crashingBrowser{cookies: "session=1"}
+	s := NewSupervisor(b)
+	var restartCauses []error
+	s.OnRestart = func(cause error) { restartCauses = append(restartCauses, cause) }
+
+	calls := 0
+	err := s.Do(func() error {
+		calls++
+		if calls == 1 {
+			b.cookies = "lost"
+			b.crash()
+			return fmt.Errorf("click failed: %w", playwright.ErrTargetClosed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v; want the retry to succeed", err)
+	}
+	if calls != 2 || b.restarts != 1 || len(restartCauses) != 1 {
+		t.Errorf("calls = %d, restarts = %d, OnRestart calls = %d; want 2, 1, 1", calls, b.restarts, len(restartCauses))
+	}
+	if want := map[string]interface{}{"cookies": "session=1"}; !reflect.DeepEqual(b.restoredTo, want) {
+		t.Errorf("restored state = %v; want the state saved before the crash %v", b.restoredTo, want)
+	}
+	if s.Disconnected() {
+		t.Error("Disconnected() = true after a successful restart")
+	}
+}
+
+func TestSupervisorLeavesOtherErrorsAlone(t *testing.T) {
+	b := &crashingBrowser{}
+	s := NewSupervisor(b)
+	wantErr := errors.New("element not found")
+	calls := 0
+	if err := s.Do(func() error { calls++; return wantErr }); err != wantErr {
+		t.Errorf("Do() error = %v; want %v", err, wantErr)
+	}
+	if calls != 1 || b.restarts != 0 {
+		t.Errorf("calls = %d, restarts = %d; want 1, 0", calls, b.restarts)
+	}
+}
+
+func TestSupervisorRetriesOnlyOnce(t *testing.T) {
+	b := &crashingBrowser{}
+	s := NewSupervisor(b)
+	calls := 0
+	err := s.Do(func() error {
+		calls++
+		b.crash()
+		return errors.New("Target page, context or browser has been closed")
+	})
+	if err == nil || calls != 2 || b.restarts != 1 {
+		t.Errorf("Do() error = %v, calls = %d, restarts = %d; want an error after 2 calls and 1 restart", err, calls, b.restarts)
+	}
+}
@@ -0,0 +1,59 @@
+package browser
+
+import "github.com/playwright-community/playwright-go"
+
+// TabEventKind says what happened to a tab.
+type TabEventKind string
+
+const (
+	TabOpened    TabEventKind = "open"
+	TabClosed    TabEventKind = "close"
+	TabActivated TabEventKind = "activate"
+)
+
+// TabEvent reports a tab change along with every open tab, so listeners
+// can redraw a tab strip from the event alone. Active is the PageID of the
+// current tab, or -1 when there is none.
+type TabEvent struct {
+	Kind   TabEventKind `json:"kind"`
+	Tab    TabInfo      `json:"tab"`
+	Active int          `json:"active"`
+	Tabs   []TabInfo    `json:"tabs"`
+}
+
+// tabInfo describes page, whose PageID is its position among the
+// context's pages (-1 if it is no longer open).
+func (b *Browser) tabInfo(page playwright.Page) TabInfo {
+	title, _ := page.Title()
+	return TabInfo{PageID: b.pageIndex(page), URL: page.URL(), Title: title}
+}
+
+func (b *Browser) pageIndex(page playwright.Page) int {
+	if b.context == nil || page == nil {
+		return -1
+	}
+	for i, p := range b.context.Pages() {
+		if p == page {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Browser) emitTabEvent(kind TabEventKind, page playwright.Page) {
+	if b.OnTabChange == nil {
+		return
+	}
+	b.notifyTabChange(kind, b.tabInfo(page))
+}
+
+func (b *Browser) notifyTabChange(kind TabEventKind, tab TabInfo) {
+	if b.OnTabChange == nil || b.context == nil {
+		return
+	}
+	tabs, _ := b.GetTabsInfo()
+	if tabs == nil {
+		tabs = []TabInfo{}
+	}
+	b.OnTabChange(TabEvent{Kind: kind, Tab: tab, Active: b.pageIndex(b.currentPage), Tabs: tabs})
+}
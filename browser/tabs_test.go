@@ -0,0 +1,99 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fakeTabContext is a browser context whose pages are fakeTabPages.
+type fakeTabContext struct {
+	playwright.BrowserContext
+	pages []playwright.Page
+}
+
+func (c *fakeTabContext) Pages() []playwright.Page { return c.pages }
+
+func (c *fakeTabContext) NewPage() (playwright.Page, error) {
+	page := &fakeTabPage{ctx: c, url: "about:blank"}
+	c.pages = append(c.pages, page)
+	return page, nil
+}
+
+type fakeTabPage struct {
+	playwright.Page
+	ctx *fakeTabContext
+	url string
+}
+
+func (p *fakeTabPage) URL() string                                                      { return p.url }
+func (p *fakeTabPage) Title() (string, error)                                           { return "Title of " + p.url, nil }
+func (p *fakeTabPage) WaitForLoadState(...playwright.PageWaitForLoadStateOptions) error { return nil }
+func (p *fakeTabPage) BringToFront() error                                              { return nil }
+
+func (p *fakeTabPage) Goto(url string, _ ...playwright.PageGotoOptions) (playwright.Response, error) {
+	p.url = url
+	return nil, nil
+}
+
+func (p *fakeTabPage) Close(...playwright.PageCloseOptions) error {
+	for i, page := range p.ctx.pages {
+		if page == p {
+			p.ctx.pages = append(p.ctx.pages[:i], p.ctx.pages[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func newTabBrowser() (*Browser, *[]TabEvent) {
+	var events []TabEvent
+	b := NewBrowser(DefaultBrowserConfig(), true)
+	b.context = &fakeTabContext{}
+	b.context.NewPage()
+	b.currentPage = b.context.Pages()[0]
+	b.OnTabChange = func(e TabEvent) { events = append(events, e) }
+	return b, &events
+}
+
+func TestCreateNewTabEmitsTabEvent(t *testing.T) {
+	b, events := newTabBrowser()
+	if err := b.CreateNewTab("https://example.com/docs"); err != nil {
+		t.Fatalf("CreateNewTab() error = %v", err)
+	}
+	if len(*events) != 1 {
+		t.Fatalf("got %d tab events; want 1", len(*events))
+	}
+	e := (*events)[0]
+	if e.Kind != TabActivated || e.Tab.URL != "https://example.com/docs" || e.Tab.PageID != 1 || e.Active != 1 {
+		t.Errorf("event = %+v; want the new tab 1 at https://example.com/docs activated", e)
+	}
+	if len(e.Tabs) != 2 || e.Tabs[1].Title != "Title of https://example.com/docs" {
+		t.Errorf("tabs = %+v; want both tabs listed", e.Tabs)
+	}
+}
+
+func TestSwitchAndCloseTabEmitTabEvents(t *testing.T) {
+	b, events := newTabBrowser()
+	b.CreateNewTab("https://example.com/")
+	b.SwitchToTab(0)
+	b.SwitchToTab(1)
+	b.CloseCurrentTab()
+
+	var kinds []TabEventKind
+	for _, e := range *events {
+		kinds = append(kinds, e.Kind)
+	}
+	want := []TabEventKind{TabActivated, TabActivated, TabActivated, TabClosed, TabActivated}
+	if len(kinds) != len(want) {
+		t.Fatalf("event kinds = %v; want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("event kinds = %v; want %v", kinds, want)
+		}
+	}
+	closed := (*events)[3]
+	if closed.Tab.URL != "https://example.com/" || len(closed.Tabs) != 1 || closed.Active != -1 {
+		t.Errorf("close event = %+v; want example.com closed leaving one tab", closed)
+	}
+}
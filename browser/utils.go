@@ -2,11 +2,13 @@ package browser
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
+	"hash/fnv"
 	"image"
 	"image/png"
-	"log"
+	"log/slog"
 	"math"
 	"net/http"
 	"net/url"
@@ -16,20 +18,25 @@ import (
 
 	"github.com/fogleman/gg" // Graphics library equivalent to PIL ImageDraw
 	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/font/opentype"
 )
 
 // PutHighlightElementsOnScreenshot draws bounding boxes and labels on the screenshot.
 func PutHighlightElementsOnScreenshot(elements map[int]InteractiveElement, screenshotB64 string) string {
+	return putHighlightElements(defaultLogger(), elements, screenshotB64)
+}
+
+func putHighlightElements(logger *slog.Logger, elements map[int]InteractiveElement, screenshotB64 string) string {
 	decodedData, err := base64.StdEncoding.DecodeString(screenshotB64)
 	if err != nil {
-		log.Printf("Failed to decode screenshot base64: %v", err)
+		logger.Warn("failed to decode screenshot base64", "error", err)
 		return screenshotB64
 	}
 
 	img, _, err := image.Decode(bytes.NewReader(decodedData))
 	if err != nil {
-		log.Printf("Failed to decode screenshot image: %v", err)
+		logger.Warn("failed to decode screenshot image", "error", err)
 		return screenshotB64
 	}
 
@@ -46,22 +53,19 @@ func PutHighlightElementsOnScreenshot(elements map[int]InteractiveElement, scree
 				DPI:     72,
 				Hinting: font.HintingFull,
 			})
-			dc.SetFontFace(face)
 		} else {
-			log.Printf("Failed to parse font: %v", err)
+			logger.Warn("failed to parse font", "error", err)
 		}
 	}
-
-	baseColors := [][]int{
-		{204, 0, 0}, {0, 136, 0}, {0, 0, 204}, {204, 112, 0},
-		{102, 0, 102}, {0, 102, 102}, {204, 51, 153}, {44, 0, 102},
-		{204, 35, 0}, {28, 102, 66}, {170, 0, 0}, {36, 82, 123},
+	if face == nil {
+		face = basicfont.Face7x13
 	}
+	dc.SetFontFace(face)
+	metrics := face.Metrics()
+	ascent := float64(metrics.Ascent) / 64
+	textHeight := ascent + float64(metrics.Descent)/64
 
-	type LabelRect struct {
-		Left, Top, Right, Bottom float64
-	}
-	var placedLabels []LabelRect
+	var placedLabels []labelRect
 
 	// Map iteration is random in Go, but we want stability if we were looping strictly.
 	// However, the input is a map, so we iterate as is. The logic relies on ID.
@@ -81,9 +85,7 @@ func PutHighlightElementsOnScreenshot(elements map[int]InteractiveElement, scree
 			continue
 		}
 
-		// Color generation
-		baseColor := baseColors[idx%len(baseColors)]
-		r, g, b := generateUniqueColor(baseColor, idx)
+		r, g, b := elementColor(element, idx)
 
 		rect := element.Rect
 
@@ -95,79 +97,81 @@ func PutHighlightElementsOnScreenshot(elements map[int]InteractiveElement, scree
 
 		// Prepare label
 		labelText := fmt.Sprintf("%d", idx)
-		textWidth, textHeight := dc.MeasureString(labelText)
-		
-		// Adjust dimensions for aesthetics
+		textWidth, _ := dc.MeasureString(labelText)
 		labelWidth := textWidth + 4
 		labelHeight := textHeight + 4
-
-		labelX := rect.Left + rect.Width - labelWidth
-		labelY := rect.Top
-
-		if labelWidth > rect.Width || labelHeight > rect.Height {
-			labelX = rect.Left + rect.Width
-			labelY = rect.Top
-		}
-
-		// Check overlap
-		currLabel := LabelRect{labelX, labelY, labelX + labelWidth, labelY + labelHeight}
-		
-		for _, existing := range placedLabels {
-			if !(currLabel.Right < existing.Left || currLabel.Left > existing.Right || currLabel.Bottom < existing.Top || currLabel.Top > existing.Bottom) {
-				// Overlap detected, push down
-				labelY = existing.Bottom + 2
-				currLabel.Top = labelY
-				currLabel.Bottom = labelY + labelHeight
-				// Simple break, might need restart in complex cases but matches Python
-				break 
-			}
-		}
-		
-		// Boundaries check
-		imgWidth := float64(dc.Width())
-		imgHeight := float64(dc.Height())
-
-		if currLabel.Left < 0 {
-			currLabel.Left = 0
-			currLabel.Right = labelWidth
-		} else if currLabel.Right >= imgWidth {
-			currLabel.Left = imgWidth - labelWidth - 1
-			currLabel.Right = imgWidth - 1
-		}
-
-		if currLabel.Top < 0 {
-			currLabel.Top = 0
-			currLabel.Bottom = labelHeight
-		} else if currLabel.Bottom >= imgHeight {
-			currLabel.Top = imgHeight - labelHeight - 1
-			currLabel.Bottom = imgHeight - 1
-		}
+		currLabel := placeLabel(rect, labelWidth, labelHeight, placedLabels, float64(dc.Width()), float64(dc.Height()))
 
 		// Draw Label Background
 		dc.SetRGB255(r, g, b)
 		dc.DrawRectangle(currLabel.Left, currLabel.Top, labelWidth, labelHeight)
 		dc.Fill()
 
-		// Draw Text
+		// Draw Text: DrawString anchors at the baseline, which sits one
+		// ascent below the top of the vertically centered line of text.
 		dc.SetRGB255(255, 255, 255)
-		// gg draws text anchored at bottom-left by default roughly, but MeasureString helps.
-		// However, gg's DrawString anchors at baseline.
-		// We use magic offsets from Python: x+3, y-1 (but Python DrawText is top-left anchor).
-		// In gg, we need to center it or approximate.
-		dc.DrawString(labelText, currLabel.Left+2, currLabel.Top+textHeight) // Approximation
-		
+		baseline := currLabel.Top + (labelHeight-textHeight)/2 + ascent
+		dc.DrawString(labelText, currLabel.Left+(labelWidth-textWidth)/2, baseline)
+
 		placedLabels = append(placedLabels, currLabel)
 	}
 
 	var buf bytes.Buffer
 	if err := png.Encode(&buf, dc.Image()); err != nil {
-		log.Printf("Failed to encode highlighted image: %v", err)
+		logger.Warn("failed to encode highlighted image", "error", err)
 		return screenshotB64
 	}
 
 	return base64.StdEncoding.EncodeToString(buf.Bytes())
 }
 
+type labelRect struct {
+	Left, Top, Right, Bottom float64
+}
+
+// placeLabel positions a width x height label at the top-right corner
+// inside rect, or just outside its right edge if it does not fit, then
+// moves it below the first already placed label it overlaps and finally
+// clamps it to lie fully inside an imgWidth x imgHeight image.
+func placeLabel(rect Rect, width, height float64, placed []labelRect, imgWidth, imgHeight float64) labelRect {
+	left := rect.Left + rect.Width - width
+	top := rect.Top
+	if width > rect.Width || height > rect.Height {
+		left = rect.Left + rect.Width
+	}
+
+	for _, existing := range placed {
+		if !(left+width < existing.Left || left > existing.Right || top+height < existing.Top || top > existing.Bottom) {
+			// Overlap detected, push down
+			top = existing.Bottom + 2
+			break
+		}
+	}
+
+	left = math.Max(0, math.Min(left, imgWidth-width))
+	top = math.Max(0, math.Min(top, imgHeight-height))
+	return labelRect{left, top, left + width, top + height}
+}
+
+var baseColors = [][]int{
+	{204, 0, 0}, {0, 136, 0}, {0, 0, 204}, {204, 112, 0},
+	{102, 0, 102}, {0, 102, 102}, {204, 51, 153}, {44, 0, 102},
+	{204, 35, 0}, {28, 102, 66}, {170, 0, 0}, {36, 82, 123},
+}
+
+// elementColor picks the highlight color for element. It is keyed by a hash
+// of BrowserAgentID so that a control keeps its color between screenshots
+// as indices shift; elements without one fall back to their index.
+func elementColor(element InteractiveElement, idx int) (int, int, int) {
+	key := idx
+	if element.BrowserAgentID != "" {
+		h := fnv.New32a()
+		h.Write([]byte(element.BrowserAgentID))
+		key = int(h.Sum32() & math.MaxInt32)
+	}
+	return generateUniqueColor(baseColors[key%len(baseColors)], key)
+}
+
 func generateUniqueColor(baseColor []int, idx int) (int, int, int) {
 	r, g, b := baseColor[0], baseColor[1], baseColor[2]
 
@@ -362,7 +366,14 @@ func sortElementsByPosition(elements []InteractiveElement) []InteractiveElement
 	return sortedList
 }
 
-func IsPDFURL(targetURL string) bool {
+// pdfProbeTimeout bounds each request IsPDFURL makes when ctx has no
+// earlier deadline.
+const pdfProbeTimeout = 5 * time.Second
+
+// IsPDFURL reports whether targetURL points to a PDF. A ".pdf" path is
+// enough; otherwise it asks the server with a HEAD request and, only if
+// that is inconclusive, a GET for the first byte, so no body is downloaded.
+func IsPDFURL(ctx context.Context, targetURL string) bool {
 	u, err := url.Parse(targetURL)
 	if err != nil {
 		return false
@@ -370,31 +381,40 @@ func IsPDFURL(targetURL string) bool {
 	if strings.HasSuffix(strings.ToLower(u.Path), ".pdf") {
 		return true
 	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	
-	// HEAD request
-	resp, err := client.Head(targetURL)
-	if err == nil {
-		defer resp.Body.Close()
-		ct := strings.ToLower(resp.Header.Get("Content-Type"))
-		if strings.Contains(ct, "application/pdf") {
-			return true
-		}
+	if isPDF, ok := probeContentType(ctx, http.MethodHead, targetURL); ok {
+		return isPDF
 	}
+	isPDF, _ := probeContentType(ctx, http.MethodGet, targetURL)
+	return isPDF
+}
+
+// probeContentType requests targetURL with method and reports whether the
+// response is a PDF. ok is false if the answer was inconclusive: the
+// request failed, the status was not 2xx, or there was no Content-Type.
+func probeContentType(ctx context.Context, method, targetURL string) (isPDF, ok bool) {
+	ctx, cancel := context.WithTimeout(ctx, pdfProbeTimeout)
+	defer cancel()
 
-	// Fallback GET
-	req, err := http.NewRequest("GET", targetURL, nil)
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, nil)
 	if err != nil {
-		return false
+		return false, false
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
 	}
-	// Use Range header to just get bytes (simulating minimal get) or simply abort
-	resp, err = client.Do(req)
-	if err == nil {
-		defer resp.Body.Close()
-		ct := strings.ToLower(resp.Header.Get("Content-Type"))
-		return strings.Contains(ct, "application/pdf")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, false
 	}
+	defer resp.Body.Close()
 
-	return false
-}
\ No newline at end of file
+	ct := strings.ToLower(resp.Header.Get("Content-Type"))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || ct == "" {
+		return false, false
+	}
+	return strings.Contains(ct, "application/pdf"), true
+}
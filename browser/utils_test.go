@@ -0,0 +1,223 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestIsPDFURL(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Range"))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/head-pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("%"))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		want     bool
+		requests []string
+	}{
+		{"/paper.PDF", true, nil},
+		{"/head-pdf", true, []string{"HEAD /head-pdf "}},
+		{"/get-only", true, []string{"HEAD /get-only ", "GET /get-only bytes=0-0"}},
+		{"/page", false, []string{"HEAD /page "}},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		requests = nil
+		mu.Unlock()
+
+		if got := IsPDFURL(context.Background(), srv.URL+tt.path); got != tt.want {
+			t.Errorf("IsPDFURL(%s) = %v; want %v", tt.path, got, tt.want)
+		}
+		mu.Lock()
+		if len(requests) != len(tt.requests) {
+			t.Errorf("IsPDFURL(%s) made requests %q; want %q", tt.path, requests, tt.requests)
+		} else {
+			for i := range requests {
+				if requests[i] != tt.requests[i] {
+					t.Errorf("IsPDFURL(%s) request %d = %q; want %q", tt.path, i, requests[i], tt.requests[i])
+				}
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+func TestIsPDFURLCancelledContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if IsPDFURL(ctx, srv.URL+"/paper") {
+		t.Error("IsPDFURL with a cancelled context = true; want false")
+	}
+}
+
+func rectElement(left, top, right, bottom float64) InteractiveElement {
+	return InteractiveElement{Rect: Rect{
+		Left: left, Top: top, Right: right, Bottom: bottom,
+		Width: right - left, Height: bottom - top,
+	}}
+}
+
+func TestFilterElementsIoUThreshold(t *testing.T) {
+	// The first two boxes overlap with an IoU of 1/3; the third is apart.
+	elements := func() []InteractiveElement {
+		return []InteractiveElement{
+			rectElement(0, 0, 100, 100),
+			rectElement(0, 50, 100, 150),
+			rectElement(300, 0, 400, 100),
+		}
+	}
+
+	tests := []struct {
+		threshold float64
+		want      int
+	}{
+		{DefaultIoUThreshold, 3},
+		{0.5, 3},
+		{0.3, 2},
+		{0.1, 2},
+	}
+	for _, tt := range tests {
+		got := FilterElements(elements(), tt.threshold)
+		if len(got) != tt.want {
+			t.Errorf("FilterElements(threshold %v) kept %d elements; want %d", tt.threshold, len(got), tt.want)
+		}
+		for i, el := range got {
+			if el.Index != i {
+				t.Errorf("FilterElements(threshold %v)[%d].Index = %d; want %d", tt.threshold, i, el.Index, i)
+			}
+		}
+	}
+}
+
+func TestBrowserConfigDetectionDefaults(t *testing.T) {
+	var zero BrowserConfig
+	if got := zero.detectorBaselineWidth(); got != DefaultDetectorBaselineWidth {
+		t.Errorf("detectorBaselineWidth() = %v; want %v", got, DefaultDetectorBaselineWidth)
+	}
+	if got := zero.iouThreshold(); got != DefaultIoUThreshold {
+		t.Errorf("iouThreshold() = %v; want %v", got, DefaultIoUThreshold)
+	}
+
+	tuned := BrowserConfig{DetectorBaselineWidth: 1280, IoUThreshold: 0.4}
+	if tuned.detectorBaselineWidth() != 1280 || tuned.iouThreshold() != 0.4 {
+		t.Errorf("tuned config = %v, %v; want 1280, 0.4", tuned.detectorBaselineWidth(), tuned.iouThreshold())
+	}
+}
+
+func TestElementColorStableAcrossIndices(t *testing.T) {
+	button := InteractiveElement{BrowserAgentID: "agent-42"}
+	r1, g1, b1 := elementColor(button, 3)
+	r2, g2, b2 := elementColor(button, 17)
+	if r1 != r2 || g1 != g2 || b1 != b2 {
+		t.Errorf("agent-42 color at index 3 = (%d,%d,%d), at 17 = (%d,%d,%d); want identical", r1, g1, b1, r2, g2, b2)
+	}
+
+	// Without an ID the index decides, as before.
+	anonymous := InteractiveElement{}
+	r, g, b := elementColor(anonymous, 5)
+	wr, wg, wb := generateUniqueColor(baseColors[5], 5)
+	if r != wr || g != wg || b != wb {
+		t.Errorf("anonymous color = (%d,%d,%d); want (%d,%d,%d)", r, g, b, wr, wg, wb)
+	}
+	for _, c := range []int{r1, g1, b1} {
+		if c < 0 || c > 255 {
+			t.Errorf("color component %d out of range", c)
+		}
+	}
+}
+
+func TestPlaceLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		rect   Rect
+		placed []labelRect
+		want   labelRect
+	}{
+		{"inside top-right corner", Rect{Left: 20, Top: 20, Width: 100, Height: 40}, nil, labelRect{110, 20, 120, 35}},
+		{"outside a small element", Rect{Left: 20, Top: 20, Width: 8, Height: 8}, nil, labelRect{28, 20, 38, 35}},
+		{"below an overlapping label", Rect{Left: 20, Top: 20, Width: 100, Height: 40}, []labelRect{{100, 15, 130, 30}}, labelRect{110, 32, 120, 47}},
+		{"clamped at the right edge", Rect{Left: 190, Top: 10, Width: 5, Height: 5}, nil, labelRect{190, 10, 200, 25}},
+		{"clamped at the bottom edge", Rect{Left: 20, Top: 95, Width: 100, Height: 20}, nil, labelRect{110, 85, 120, 100}},
+	}
+	for _, tt := range tests {
+		if got := placeLabel(tt.rect, 10, 15, tt.placed, 200, 100); got != tt.want {
+			t.Errorf("%s: placeLabel() = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPutHighlightElementsOnScreenshot(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	element := rectElement(20, 20, 120, 60)
+	element.BrowserAgentID = "agent-1"
+	out := PutHighlightElementsOnScreenshot(map[int]InteractiveElement{0: element}, base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	data, err := base64.StdEncoding.DecodeString(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode highlighted image: %v", err)
+	}
+
+	r, g, b := elementColor(element, 0)
+	want := color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+	at := func(x, y int) color.RGBA {
+		return color.RGBAModel.Convert(rendered.At(x, y)).(color.RGBA)
+	}
+
+	// The element outline runs along its edges.
+	for _, p := range []image.Point{{20, 40}, {70, 60}, {50, 20}} {
+		if got := at(p.X, p.Y); got != want {
+			t.Errorf("outline pixel %v = %v; want %v", p, got, want)
+		}
+	}
+	// The label background fills the top-right corner inside the element.
+	if got := at(118, 22); got != want {
+		t.Errorf("label background pixel = %v; want %v", got, want)
+	}
+	// Outside both, the screenshot is untouched.
+	if got := at(60, 40); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("interior pixel = %v; want white", got)
+	}
+}
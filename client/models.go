@@ -2,6 +2,8 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -14,6 +16,9 @@ type Message struct {
 	IsHidden  bool   `json:"is_hidden"`
 }
 
+// RoleThinking marks a message holding the agent's reasoning rather than an answer
+const RoleThinking = "thinking"
+
 // WebSocketMessage represents a WebSocket message
 type WebSocketMessage struct {
 	Type    string          `json:"type"`
@@ -25,6 +30,38 @@ type InitAgentContent struct {
 	ModelName     string                 `json:"model_name"`
 	ToolArgs      map[string]interface{} `json:"tool_args"`
 	ThinkingTokens int                   `json:"thinking_tokens"`
+	Sandbox       *SandboxSettings       `json:"sandbox,omitempty"`
+}
+
+// Workspace modes accepted by the server
+const (
+	WorkspaceModeLocal  = "local"
+	WorkspaceModeDocker = "docker"
+	WorkspaceModeE2B    = "e2b"
+)
+
+// WorkspaceModes lists the workspace modes in the order they are offered
+var WorkspaceModes = []string{WorkspaceModeLocal, WorkspaceModeDocker, WorkspaceModeE2B}
+
+// SandboxSettings selects where the agent works
+type SandboxSettings struct {
+	Mode       string `json:"mode"`
+	TemplateID string `json:"template_id,omitempty"`
+	APIKey     string `json:"sandbox_api_key,omitempty"`
+}
+
+// Validate rejects combinations the server would refuse
+func (s SandboxSettings) Validate() error {
+	switch s.Mode {
+	case WorkspaceModeLocal, WorkspaceModeDocker:
+		return nil
+	case WorkspaceModeE2B:
+		if s.APIKey == "" {
+			return errors.New("an E2B API key is required for the e2b workspace mode")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown workspace mode %q", s.Mode)
 }
 
 // QueryContent represents the content for query message
@@ -53,12 +90,18 @@ const (
 	EventTypeWorkspaceInfo         = "workspace_info"
 	EventTypeToolCall              = "tool_call"
 	EventTypeToolResult            = "tool_result"
+	EventTypeAgentThinking         = "agent_thinking"
+	EventTypeResponseInterrupt     = "agent_response_interrupted"
+	EventTypeSessionSummary        = "session_summary"
+	EventTypeUsage                 = "usage"
+	EventTypeBrowserTabs           = "browser_tabs"
 )
 
 // ConnectionEstablishedEvent represents the connection_established event
 type ConnectionEstablishedEvent struct {
 	Message       string `json:"message"`
 	WorkspacePath string `json:"workspace_path"`
+	SessionID     string `json:"session_id"`
 }
 
 // AgentInitializedEvent represents the agent_initialized event
@@ -89,14 +132,88 @@ type SystemEvent struct {
 
 // ToolCallEvent represents a tool call event
 type ToolCallEvent struct {
-	ToolName  string                 `json:"tool_name"`
-	ToolInput map[string]interface{} `json:"tool_input"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"`
+	ToolName   string                 `json:"tool_name"`
+	ToolInput  map[string]interface{} `json:"tool_input"`
+	// AwaitingApproval means the agent waits for AnswerToolCall before
+	// running the tool
+	AwaitingApproval bool `json:"awaiting_approval,omitempty"`
 }
 
 // ToolResultEvent represents a tool result event
 type ToolResultEvent struct {
-	ToolName string      `json:"tool_name"`
-	Result   interface{} `json:"result"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+	ToolName   string      `json:"tool_name"`
+	Result     interface{} `json:"result"`
+	Stream     string      `json:"stream,omitempty"`  // "stdout" or "stderr" for partial output
+	Partial    bool        `json:"partial,omitempty"` // a streamed output line, not the final result
+
+	// Typed views of Result, set by the tool kind when Result decodes as one
+	Screenshot *ScreenshotResult `json:"-"`
+	Command    *CommandResult    `json:"-"`
+	File       *FileResult       `json:"-"`
+}
+
+// AgentThinkingEvent carries the agent's reasoning for the current turn
+type AgentThinkingEvent struct {
+	Text string `json:"text"`
+}
+
+// PongEvent is the reply to a ping
+type PongEvent struct{}
+
+// WorkspaceInfoEvent represents the workspace_info event
+type WorkspaceInfoEvent struct {
+	Path string `json:"path"`
+}
+
+// ResponseInterruptEvent carries the partial response of a cancelled turn
+type ResponseInterruptEvent struct {
+	Text string `json:"text"`
+}
+
+// UsageTotals is the token usage reported in a session summary
+type UsageTotals struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	Calls        int `json:"calls"`
+}
+
+// UsageEvent carries the session's cumulative usage and estimated cost
+type UsageEvent struct {
+	SessionID string      `json:"session_id"`
+	Totals    UsageTotals `json:"totals"`
+	CostUSD   float64     `json:"cost_usd"`
+}
+
+// BrowserTab is one open tab of the agent's browser
+type BrowserTab struct {
+	PageID int    `json:"pageId"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+}
+
+// BrowserTabsEvent reports a tab that opened, closed or became current
+// ("open", "close" or "activate"), with every open tab. Active is the
+// PageID of the current tab, or -1.
+type BrowserTabsEvent struct {
+	Kind   string       `json:"kind"`
+	Tab    BrowserTab   `json:"tab"`
+	Active int          `json:"active"`
+	Tabs   []BrowserTab `json:"tabs"`
+}
+
+// SessionSummaryEvent represents the session_summary card sent when a task finishes
+type SessionSummaryEvent struct {
+	SessionID       string      `json:"session_id"`
+	Model           string      `json:"model"`
+	Asked           string      `json:"asked"`
+	Done            string      `json:"done"`
+	Deliverables    []string    `json:"deliverables"`
+	Usage           UsageTotals `json:"usage"`
+	CostUSD         float64     `json:"cost_usd"`
+	DurationSeconds float64     `json:"duration_seconds"`
+	Summary         string      `json:"summary"`
 }
 
 // AppState holds the application state
@@ -111,9 +228,24 @@ type AppState struct {
 	VSCodeURL         string
 	BrowserURL        string
 	BrowserScreenshot []byte
+	// BrowserTabs are the browser's open tabs; ActiveBrowserTab is the
+	// PageID of the current one
+	BrowserTabs      []BrowserTab
+	ActiveBrowserTab int
 	CodeContent       string
 	CodeFile          string
 	TerminalOutput    string
+	LastPong          time.Time
+	LastSummary       *SessionSummaryEvent
+	ShowThinking      bool
+	Sandbox           SandboxSettings
+	SessionID         string
+	// Attachments are uploaded files waiting to be sent with the next query
+	Attachments []Attachment
+	// Usage is the session's running token usage and cost
+	Usage UsageEvent
+	// CostBudgetUSD is the session cost past which the GUI warns; 0 disables it
+	CostBudgetUSD float64
 }
 
 // NewAppState creates a new AppState with default values
@@ -121,7 +253,45 @@ func NewAppState() *AppState {
 	return &AppState{
 		Messages:      []Message{},
 		SelectedModel: "gpt-4",
+		ShowThinking:  true,
+		Sandbox:       SandboxSettings{Mode: WorkspaceModeLocal},
+		CostBudgetUSD: DefaultCostBudgetUSD,
+	}
+}
+
+// Attachment is a file uploaded to the session workspace
+type Attachment struct {
+	Name string // original file name
+	Path string // workspace-relative path returned by the upload
+	Size int64
+}
+
+// AddAttachment queues an uploaded file for the next query, replacing any
+// attachment with the same path
+func (s *AppState) AddAttachment(a Attachment) {
+	s.RemoveAttachment(a.Path)
+	s.Attachments = append(s.Attachments, a)
+}
+
+// RemoveAttachment drops the pending attachment with the given path
+func (s *AppState) RemoveAttachment(path string) {
+	kept := s.Attachments[:0]
+	for _, a := range s.Attachments {
+		if a.Path != path {
+			kept = append(kept, a)
+		}
+	}
+	s.Attachments = kept
+}
+
+// TakeAttachments returns the pending attachment paths and clears them
+func (s *AppState) TakeAttachments() []string {
+	paths := make([]string, 0, len(s.Attachments))
+	for _, a := range s.Attachments {
+		paths = append(paths, a.Path)
 	}
+	s.Attachments = nil
+	return paths
 }
 
 // AddMessage adds a new message to the state
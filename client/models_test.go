@@ -0,0 +1,199 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// processEvent feeds a raw server message through processMessage and
+// returns what was forwarded to onEvent.
+func processEvent(t *testing.T, c *WebSocketClient, raw string) (string, interface{}) {
+	t.Helper()
+	var gotType string
+	var gotContent interface{}
+	c.SetOnEvent(func(eventType string, content interface{}) {
+		gotType, gotContent = eventType, content
+	})
+	c.processMessage([]byte(raw))
+	return gotType, gotContent
+}
+
+func TestProcessMessageAgentThinking(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	var thinking []string
+	c.SetOnThinking(func(e AgentThinkingEvent) { thinking = append(thinking, e.Text) })
+
+	typ, content := processEvent(t, c, `{"type":"agent_thinking","content":{"text":"Let me check the tests first."}}`)
+	if typ != EventTypeAgentThinking {
+		t.Fatalf("event type = %q; want %q", typ, EventTypeAgentThinking)
+	}
+	if e, ok := content.(AgentThinkingEvent); !ok || e.Text != "Let me check the tests first." {
+		t.Errorf("content = %#v; want AgentThinkingEvent", content)
+	}
+	if len(thinking) != 1 || thinking[0] != "Let me check the tests first." {
+		t.Errorf("onThinking received %v", thinking)
+	}
+	if len(c.state.Messages) != 0 {
+		t.Error("thinking should not be added as a chat message")
+	}
+}
+
+func TestProcessMessagePong(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	typ, content := processEvent(t, c, `{"type":"pong","content":{}}`)
+	if typ != EventTypePong {
+		t.Fatalf("event type = %q; want %q", typ, EventTypePong)
+	}
+	if _, ok := content.(PongEvent); !ok {
+		t.Errorf("content = %#v; want PongEvent", content)
+	}
+	if c.state.LastPong.IsZero() {
+		t.Error("LastPong should be recorded")
+	}
+}
+
+func TestProcessMessageWorkspaceInfo(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	typ, content := processEvent(t, c, `{"type":"workspace_info","content":{"path":"/data/ws/123"}}`)
+	if typ != EventTypeWorkspaceInfo {
+		t.Fatalf("event type = %q; want %q", typ, EventTypeWorkspaceInfo)
+	}
+	if e, ok := content.(WorkspaceInfoEvent); !ok || e.Path != "/data/ws/123" {
+		t.Errorf("content = %#v; want WorkspaceInfoEvent", content)
+	}
+	if c.state.WorkspacePath != "/data/ws/123" {
+		t.Errorf("WorkspacePath = %q; want /data/ws/123", c.state.WorkspacePath)
+	}
+}
+
+func TestProcessMessageResponseInterrupt(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	c.state.IsLoading = true
+	typ, content := processEvent(t, c, `{"type":"agent_response_interrupted","content":{"text":"Stopped early."}}`)
+	if typ != EventTypeResponseInterrupt {
+		t.Fatalf("event type = %q; want %q", typ, EventTypeResponseInterrupt)
+	}
+	if e, ok := content.(ResponseInterruptEvent); !ok || e.Text != "Stopped early." {
+		t.Errorf("content = %#v; want ResponseInterruptEvent", content)
+	}
+	if c.state.IsLoading {
+		t.Error("an interrupt should stop the loading state")
+	}
+	if len(c.state.Messages) != 1 || c.state.Messages[0].Content != "Stopped early." {
+		t.Errorf("messages = %+v; want the partial response", c.state.Messages)
+	}
+}
+
+func TestProcessMessageSessionSummary(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	raw := `{"type":"session_summary","content":{"asked":"Build it","done":"Built it","deliverables":["main.go"],"usage":{"input_tokens":10,"output_tokens":5,"calls":1},"cost_usd":0.01}}`
+	typ, _ := processEvent(t, c, raw)
+	if typ != EventTypeSessionSummary {
+		t.Fatalf("event type = %q; want %q", typ, EventTypeSessionSummary)
+	}
+	got := c.state.LastSummary
+	if got == nil || got.Asked != "Build it" || len(got.Deliverables) != 1 || got.Usage.InputTokens != 10 {
+		t.Errorf("LastSummary = %+v", got)
+	}
+}
+
+func TestInitAgentContentSandboxJSON(t *testing.T) {
+	content := InitAgentContent{
+		ModelName: "gpt-4o",
+		Sandbox:   &SandboxSettings{Mode: WorkspaceModeE2B, TemplateID: "water-base", APIKey: "e2b-key"},
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got struct {
+		ModelName string `json:"model_name"`
+		Sandbox   struct {
+			Mode          string `json:"mode"`
+			TemplateID    string `json:"template_id"`
+			SandboxAPIKey string `json:"sandbox_api_key"`
+		} `json:"sandbox"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.ModelName != "gpt-4o" || got.Sandbox.Mode != "e2b" || got.Sandbox.TemplateID != "water-base" || got.Sandbox.SandboxAPIKey != "e2b-key" {
+		t.Errorf("init_agent JSON = %s", data)
+	}
+
+	data, _ = json.Marshal(InitAgentContent{ModelName: "gpt-4o"})
+	if strings.Contains(string(data), "sandbox") {
+		t.Errorf("init_agent without sandbox = %s; want no sandbox field", data)
+	}
+}
+
+func TestSandboxSettingsValidate(t *testing.T) {
+	tests := []struct {
+		settings SandboxSettings
+		wantErr  bool
+	}{
+		{SandboxSettings{Mode: WorkspaceModeLocal}, false},
+		{SandboxSettings{Mode: WorkspaceModeDocker}, false},
+		{SandboxSettings{Mode: WorkspaceModeE2B, APIKey: "e2b-key"}, false},
+		{SandboxSettings{Mode: WorkspaceModeE2B}, true},
+		{SandboxSettings{Mode: "vm"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.settings.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v; wantErr %v", tt.settings, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAppStateAttachments(t *testing.T) {
+	s := NewAppState()
+	s.AddAttachment(Attachment{Name: "a.png", Path: "uploads/a.png"})
+	s.AddAttachment(Attachment{Name: "b.txt", Path: "uploads/b.txt"})
+	s.AddAttachment(Attachment{Name: "a.png", Path: "uploads/a.png", Size: 10})
+	if len(s.Attachments) != 2 {
+		t.Fatalf("Attachments = %+v; want a re-added file to replace the old entry", s.Attachments)
+	}
+
+	s.RemoveAttachment("uploads/b.txt")
+	if len(s.Attachments) != 1 || s.Attachments[0].Size != 10 {
+		t.Errorf("Attachments after remove = %+v", s.Attachments)
+	}
+
+	paths := s.TakeAttachments()
+	if len(paths) != 1 || paths[0] != "uploads/a.png" {
+		t.Errorf("TakeAttachments() = %v; want [uploads/a.png]", paths)
+	}
+	if len(s.Attachments) != 0 {
+		t.Errorf("Attachments after take = %+v; want none", s.Attachments)
+	}
+}
+
+func TestProcessMessageUsage(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	typ, content := processEvent(t, c, `{"type":"usage","content":{"session_id":"s1","totals":{"input_tokens":4000,"output_tokens":1000,"calls":2},"cost_usd":0.027}}`)
+	if typ != EventTypeUsage {
+		t.Fatalf("event type = %q; want %q", typ, EventTypeUsage)
+	}
+	u, ok := content.(UsageEvent)
+	if !ok || u.Totals.InputTokens != 4000 || u.CostUSD != 0.027 {
+		t.Errorf("content = %#v; want the usage totals", content)
+	}
+	if c.state.Usage != u {
+		t.Errorf("state.Usage = %+v; want %+v", c.state.Usage, u)
+	}
+}
+
+func TestProcessMessageBrowserTabs(t *testing.T) {
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	typ, content := processEvent(t, c, `{"type":"browser_tabs","content":{"kind":"activate","tab":{"pageId":1,"url":"https://example.com/docs","title":"Docs"},"active":1,"tabs":[{"pageId":0,"url":"https://example.com/","title":"Example"},{"pageId":1,"url":"https://example.com/docs","title":"Docs"}]}}`)
+	if typ != EventTypeBrowserTabs {
+		t.Fatalf("event type = %q; want %q", typ, EventTypeBrowserTabs)
+	}
+	if e, ok := content.(BrowserTabsEvent); !ok || e.Kind != "activate" || e.Tab.URL != "https://example.com/docs" {
+		t.Errorf("content = %#v; want the activated docs tab", content)
+	}
+	if len(c.state.BrowserTabs) != 2 || c.state.ActiveBrowserTab != 1 || c.state.BrowserURL != "https://example.com/docs" {
+		t.Errorf("state tabs = %+v, active %d, URL %q; want two tabs with docs current", c.state.BrowserTabs, c.state.ActiveBrowserTab, c.state.BrowserURL)
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ScreenshotResult is the result of a browser screenshot tool
+type ScreenshotResult struct {
+	ImageB64 string `json:"image_b64"`
+	URL      string `json:"url,omitempty"`
+}
+
+// CommandResult is the result of a terminal command. ExitCode is nil when
+// the tool reported plain output without a status.
+type CommandResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+}
+
+// Output joins stdout and stderr for display
+func (r CommandResult) Output() string {
+	switch {
+	case r.Stderr == "":
+		return r.Stdout
+	case r.Stdout == "":
+		return r.Stderr
+	}
+	return r.Stdout + "\n" + r.Stderr
+}
+
+// FileResult is the result of a file read or write
+type FileResult struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// decodeResult fills the typed result matching the tool, accepting either
+// the structured payload or a bare string. A result of unknown shape
+// leaves the typed field nil; Text still renders it.
+func (e *ToolResultEvent) decodeResult() {
+	switch e.ToolName {
+	case "browser_view", "browser_screenshot":
+		var r ScreenshotResult
+		if s, ok := e.Result.(string); ok {
+			r.ImageB64 = s
+		} else if !decodeStructured(e.Result, &r) || r.ImageB64 == "" {
+			return
+		}
+		// Accept data URIs as well as raw base64
+		if strings.HasPrefix(r.ImageB64, "data:") {
+			if _, data, ok := strings.Cut(r.ImageB64, ","); ok {
+				r.ImageB64 = data
+			}
+		}
+		e.Screenshot = &r
+	case "execute_command", "terminal_execute":
+		var r CommandResult
+		if s, ok := e.Result.(string); ok {
+			if e.Stream == "stderr" {
+				r.Stderr = s
+			} else {
+				r.Stdout = s
+			}
+		} else if !decodeStructured(e.Result, &r) {
+			return
+		}
+		e.Command = &r
+	case "write_file", "read_file":
+		var r FileResult
+		if s, ok := e.Result.(string); ok {
+			r.Content = s
+		} else if !decodeStructured(e.Result, &r) {
+			return
+		}
+		e.File = &r
+	}
+}
+
+// decodeStructured converts a decoded JSON object into out, reporting
+// whether it was an object
+func decodeStructured(result interface{}, out interface{}) bool {
+	if _, ok := result.(map[string]interface{}); !ok {
+		return false
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+// Text renders the raw result: strings as-is, anything else as JSON
+func (e ToolResultEvent) Text() string {
+	switch r := e.Result.(type) {
+	case nil:
+		return ""
+	case string:
+		return r
+	}
+	data, err := json.MarshalIndent(e.Result, "", "  ")
+	if err != nil {
+		return fmt.Sprint(e.Result)
+	}
+	return string(data)
+}
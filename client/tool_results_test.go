@@ -0,0 +1,79 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func decodeToolResult(t *testing.T, raw string) ToolResultEvent {
+	t.Helper()
+	c := NewWebSocketClient("ws://unused", NewAppState())
+	_, content := processEvent(t, c, `{"type":"tool_result","content":`+raw+`}`)
+	tr, ok := content.(ToolResultEvent)
+	if !ok {
+		t.Fatalf("content = %#v; want ToolResultEvent", content)
+	}
+	return tr
+}
+
+func TestDecodeScreenshotResult(t *testing.T) {
+	tests := []struct{ name, raw string }{
+		{"structured", `{"tool_name":"browser_screenshot","result":{"image_b64":"iVBORw0KGgo=","url":"https://example.com"}}`},
+		{"data uri", `{"tool_name":"browser_view","result":{"image_b64":"data:image/png;base64,iVBORw0KGgo="}}`},
+		{"bare string", `{"tool_name":"browser_view","result":"iVBORw0KGgo="}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := decodeToolResult(t, tt.raw)
+			if tr.Screenshot == nil || tr.Screenshot.ImageB64 != "iVBORw0KGgo=" {
+				t.Errorf("Screenshot = %+v; want the base64 image", tr.Screenshot)
+			}
+		})
+	}
+}
+
+func TestDecodeCommandResult(t *testing.T) {
+	tr := decodeToolResult(t, `{"tool_name":"execute_command","result":{"stdout":"ok","stderr":"warning","exit_code":2}}`)
+	cr := tr.Command
+	if cr == nil || cr.Stdout != "ok" || cr.Stderr != "warning" || cr.ExitCode == nil || *cr.ExitCode != 2 {
+		t.Fatalf("Command = %+v", cr)
+	}
+	if got := cr.Output(); got != "ok\nwarning" {
+		t.Errorf("Output() = %q", got)
+	}
+
+	tr = decodeToolResult(t, `{"tool_name":"terminal_execute","result":"boom","stream":"stderr","partial":true}`)
+	if tr.Command == nil || tr.Command.Stderr != "boom" || tr.Command.ExitCode != nil {
+		t.Errorf("partial stderr Command = %+v", tr.Command)
+	}
+}
+
+func TestDecodeFileResult(t *testing.T) {
+	tr := decodeToolResult(t, `{"tool_name":"read_file","result":{"path":"main.go","content":"package main"}}`)
+	if tr.File == nil || tr.File.Path != "main.go" || tr.File.Content != "package main" {
+		t.Errorf("File = %+v", tr.File)
+	}
+
+	tr = decodeToolResult(t, `{"tool_name":"write_file","result":"package main"}`)
+	if tr.File == nil || tr.File.Content != "package main" || tr.File.Path != "" {
+		t.Errorf("string File = %+v", tr.File)
+	}
+}
+
+func TestDecodeUnknownResultFallsBackToText(t *testing.T) {
+	tr := decodeToolResult(t, `{"tool_name":"browser_view","result":{"status":"loaded"}}`)
+	if tr.Screenshot != nil {
+		t.Errorf("Screenshot = %+v; want nil for an unknown shape", tr.Screenshot)
+	}
+	if text := tr.Text(); !strings.Contains(text, `"status": "loaded"`) {
+		t.Errorf("Text() = %q; want the JSON rendering", text)
+	}
+
+	tr = decodeToolResult(t, `{"tool_name":"execute_command","result":["a","b"]}`)
+	if tr.Command != nil {
+		t.Errorf("Command = %+v; want nil for an array result", tr.Command)
+	}
+	if tr := decodeToolResult(t, `{"tool_name":"web_search","result":"plain"}`); tr.Text() != "plain" {
+		t.Errorf("Text() = %q; want plain", tr.Text())
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TranscriptFileName is the autosaved conversation under the user config directory
+const TranscriptFileName = "transcript.json"
+
+// TranscriptAutosaveInterval is how often the GUI saves the conversation
+const TranscriptAutosaveInterval = 30 * time.Second
+
+// Transcript is the conversation saved between runs of the app
+type Transcript struct {
+	SessionID string    `json:"session_id"`
+	Messages  []Message `json:"messages"`
+	SavedAt   time.Time `json:"saved_at"`
+}
+
+// DefaultTranscriptPath returns where the GUI keeps its transcript
+func DefaultTranscriptPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "water-ai", TranscriptFileName), nil
+}
+
+// Transcript returns the state's conversation for saving
+func (s *AppState) Transcript() Transcript {
+	messages := make([]Message, len(s.Messages))
+	copy(messages, s.Messages)
+	return Transcript{SessionID: s.SessionID, Messages: messages, SavedAt: time.Now()}
+}
+
+// Restore replaces the conversation with a saved transcript, so the next
+// connection rejoins its session
+func (s *AppState) Restore(t *Transcript) {
+	s.Messages = append([]Message{}, t.Messages...)
+	s.SessionID = t.SessionID
+	s.IsAgentInitialized = false
+}
+
+// SaveTranscript writes t to path, replacing the file atomically
+func SaveTranscript(path string, t Transcript) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// LoadTranscript reads the transcript at path. A missing file yields nil
+// without an error.
+func LoadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %w", err)
+	}
+	return &t, nil
+}
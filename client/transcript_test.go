@@ -0,0 +1,56 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranscriptSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", TranscriptFileName)
+
+	state := NewAppState()
+	state.SessionID = "2b7e1f2c-0000-4000-8000-000000000001"
+	state.AddMessage(NewMessage("user", "Build a todo app"))
+	state.AddMessage(NewMessage("assistant", "Done."))
+	state.AddMessage(NewMessage(RoleThinking, "Check the tests first"))
+
+	if err := SaveTranscript(path, state.Transcript()); err != nil {
+		t.Fatalf("SaveTranscript() error = %v", err)
+	}
+	loaded, err := LoadTranscript(path)
+	if err != nil {
+		t.Fatalf("LoadTranscript() error = %v", err)
+	}
+
+	restored := NewAppState()
+	restored.IsAgentInitialized = true
+	restored.Restore(loaded)
+	if restored.SessionID != state.SessionID {
+		t.Errorf("SessionID = %q; want %q", restored.SessionID, state.SessionID)
+	}
+	if len(restored.Messages) != 3 {
+		t.Fatalf("restored %d messages; want 3", len(restored.Messages))
+	}
+	for i, msg := range restored.Messages {
+		if msg != state.Messages[i] {
+			t.Errorf("message %d = %+v; want %+v", i, msg, state.Messages[i])
+		}
+	}
+	if restored.IsAgentInitialized {
+		t.Error("restored state should re-initialize the agent")
+	}
+}
+
+func TestLoadTranscriptMissingAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if tr, err := LoadTranscript(filepath.Join(dir, "missing.json")); tr != nil || err != nil {
+		t.Errorf("LoadTranscript(missing) = %v, %v; want nil, nil", tr, err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("{not json"), 0600)
+	if _, err := LoadTranscript(corrupt); err == nil {
+		t.Error("LoadTranscript(corrupt) error = nil; want a parse error")
+	}
+}
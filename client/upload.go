@@ -0,0 +1,101 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxUploadSize matches the server's limit on uploaded files
+const MaxUploadSize = 20 << 20
+
+// uploadTimeout bounds one /api/upload request
+const uploadTimeout = 60 * time.Second
+
+// ErrFileTooLarge is returned for files over MaxUploadSize
+var ErrFileTooLarge = fmt.Errorf("file exceeds the %d MB upload limit", MaxUploadSize>>20)
+
+// uploadURL derives the HTTP upload endpoint from the WebSocket URL
+func (c *WebSocketClient) uploadURL() (string, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "wss":
+		u.Scheme = "https"
+	default:
+		u.Scheme = "http"
+	}
+	u.Path = "/api/upload"
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+// UploadFile uploads the local file at path to the session workspace and
+// returns the attachment to send with the next query
+func (c *WebSocketClient) UploadFile(path string) (Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if info.Size() > MaxUploadSize {
+		return Attachment{}, ErrFileTooLarge
+	}
+	if c.state.SessionID == "" {
+		return Attachment{}, ErrNotConnected
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	endpoint, err := c.uploadURL()
+	if err != nil {
+		return Attachment{}, err
+	}
+	name := filepath.Base(path)
+	body, err := json.Marshal(map[string]interface{}{
+		"session_id": c.state.SessionID,
+		"file": map[string]string{
+			"path":    name,
+			"content": "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data),
+		},
+	})
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	httpClient := &http.Client{Timeout: uploadTimeout}
+	resp, err := httpClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Error string `json:"error"`
+		File  struct {
+			Path string `json:"path"`
+		} `json:"file"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return Attachment{}, fmt.Errorf("upload failed: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Attachment{}, fmt.Errorf("upload failed: %s", result.Error)
+	}
+	return Attachment{
+		Name: name,
+		Path: strings.TrimPrefix(result.File.Path, "/"),
+		Size: info.Size(),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadFile(t *testing.T) {
+	var got struct {
+		SessionID string `json:"session_id"`
+		File      struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"file"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/upload" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"file": {"path": "/uploads/notes.txt"}}`))
+	}))
+	defer srv.Close()
+
+	state := NewAppState()
+	state.SessionID = "session-1"
+	c := NewWebSocketClient("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", state)
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("hello"), 0644)
+
+	a, err := c.UploadFile(path)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if a.Path != "uploads/notes.txt" || a.Name != "notes.txt" || a.Size != 5 {
+		t.Errorf("attachment = %+v", a)
+	}
+	if got.SessionID != "session-1" || got.File.Path != "notes.txt" || !strings.HasPrefix(got.File.Content, "data:text/plain") {
+		t.Errorf("upload request = %+v", got)
+	}
+}
+
+func TestUploadFileTooLarge(t *testing.T) {
+	state := NewAppState()
+	state.SessionID = "session-1"
+	c := NewWebSocketClient("ws://unused/ws", state)
+
+	path := filepath.Join(t.TempDir(), "big.bin")
+	f, _ := os.Create(path)
+	f.Truncate(MaxUploadSize + 1)
+	f.Close()
+
+	if _, err := c.UploadFile(path); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("UploadFile() error = %v; want ErrFileTooLarge", err)
+	}
+}
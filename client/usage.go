@@ -0,0 +1,57 @@
+package client
+
+import "fmt"
+
+// DefaultCostBudgetUSD is the session cost at which the GUI turns red
+const DefaultCostBudgetUSD = 5.0
+
+// budgetWarningFraction is the share of the budget at which the GUI warns
+const budgetWarningFraction = 0.8
+
+// BudgetLevel says how a session's cost compares with its budget
+type BudgetLevel int
+
+const (
+	BudgetOK BudgetLevel = iota
+	BudgetWarning
+	BudgetExceeded
+)
+
+// FormatTokens renders a token count compactly, e.g. 950, 12.3k, 1.2M
+func FormatTokens(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprintf("%d", n)
+	case n < 1_000_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	}
+}
+
+// FormatCost renders an estimated USD cost, showing sub-cent amounts as
+// "<$0.01" rather than rounding them to zero
+func FormatCost(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// FormatUsage renders the status bar text for a session's usage
+func FormatUsage(u UsageEvent) string {
+	return fmt.Sprintf("%s tokens · %s", FormatTokens(u.Totals.InputTokens+u.Totals.OutputTokens), FormatCost(u.CostUSD))
+}
+
+// Budget compares a cost with a budget; a budget of 0 never warns
+func Budget(costUSD, budgetUSD float64) BudgetLevel {
+	switch {
+	case budgetUSD <= 0:
+		return BudgetOK
+	case costUSD >= budgetUSD:
+		return BudgetExceeded
+	case costUSD >= budgetUSD*budgetWarningFraction:
+		return BudgetWarning
+	}
+	return BudgetOK
+}
@@ -0,0 +1,37 @@
+package client
+
+import "testing"
+
+func TestFormatUsage(t *testing.T) {
+	tests := []struct {
+		usage UsageEvent
+		want  string
+	}{
+		{UsageEvent{}, "0 tokens · $0.00"},
+		{UsageEvent{Totals: UsageTotals{InputTokens: 600, OutputTokens: 350}, CostUSD: 0.004}, "950 tokens · <$0.01"},
+		{UsageEvent{Totals: UsageTotals{InputTokens: 10000, OutputTokens: 2300}, CostUSD: 0.0279}, "12.3k tokens · $0.03"},
+		{UsageEvent{Totals: UsageTotals{InputTokens: 1200000, OutputTokens: 50000}, CostUSD: 4.5}, "1.2M tokens · $4.50"},
+	}
+	for _, tt := range tests {
+		if got := FormatUsage(tt.usage); got != tt.want {
+			t.Errorf("FormatUsage(%+v) = %q; want %q", tt.usage, got, tt.want)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		cost, budget float64
+		want         BudgetLevel
+	}{
+		{1, 5, BudgetOK},
+		{4, 5, BudgetWarning},
+		{5, 5, BudgetExceeded},
+		{100, 0, BudgetOK},
+	}
+	for _, tt := range tests {
+		if got := Budget(tt.cost, tt.budget); got != tt.want {
+			t.Errorf("Budget(%v, %v) = %v; want %v", tt.cost, tt.budget, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"sync"
@@ -21,54 +23,122 @@ type WebSocketClient struct {
 	onStateChange   func()
 	onConnected     func()
 	onDisconnected  func()
-	stopChan        chan struct{}
-	reconnect       bool
+	onThinking      func(event AgentThinkingEvent)
+	onReconnectAttempt func(attempt, of int, retryIn time.Duration)
+	onReconnectFailed  func()
+
+	// ctx spans one Connect..Disconnect lifetime; cancelling it stops the
+	// read, ping and reconnect loops. It is replaced under mu by Connect.
+	ctx          context.Context
+	cancel       context.CancelFunc
+	reconnecting bool
+
+	reconnectBase        time.Duration
+	reconnectMax         time.Duration
+	maxReconnectAttempts int
+
+	// outbox holds messages sent while disconnected, flushed in order on reconnect
+	outbox    []WebSocketMessage
+	outboxCap int
 }
 
+// DefaultOutboxCapacity is the number of messages buffered while disconnected
+const DefaultOutboxCapacity = 100
+
+// Reconnect backoff defaults: exponential from the base delay, capped at
+// the max, with jitter so many clients don't retry in lockstep.
+const (
+	DefaultReconnectBase        = time.Second
+	DefaultReconnectMax         = 30 * time.Second
+	DefaultMaxReconnectAttempts = 5
+)
+
 // NewWebSocketClient creates a new WebSocket client
 func NewWebSocketClient(serverURL string, state *AppState) *WebSocketClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &WebSocketClient{
-		url:       serverURL,
-		state:     state,
-		reconnect: true,
-		stopChan:  make(chan struct{}),
+		url:                  serverURL,
+		state:                state,
+		ctx:                  ctx,
+		cancel:               cancel,
+		outboxCap:            DefaultOutboxCapacity,
+		reconnectBase:        DefaultReconnectBase,
+		reconnectMax:         DefaultReconnectMax,
+		maxReconnectAttempts: DefaultMaxReconnectAttempts,
 	}
 }
 
-// Connect establishes a WebSocket connection to the server
+// SetReconnectBackoff configures the reconnect delays and attempt limit
+func (c *WebSocketClient) SetReconnectBackoff(base, max time.Duration, attempts int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnectBase = base
+	c.reconnectMax = max
+	c.maxReconnectAttempts = attempts
+}
+
+// SetOutboxCapacity sets how many messages are buffered while disconnected
+func (c *WebSocketClient) SetOutboxCapacity(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outboxCap = n
+}
+
+// Connect establishes a WebSocket connection to the server. It is a no-op
+// when already connected and may be called again after Disconnect.
 func (c *WebSocketClient) Connect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+	if c.ctx.Err() != nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
 	return c.connectInternal()
 }
 
-// connectInternal establishes a WebSocket connection without locking
-// This is used internally for reconnection attempts
+// dialer accepts per-message deflate so large events such as screenshots
+// arrive compressed
+var dialer = &websocket.Dialer{
+	Proxy:             http.ProxyFromEnvironment,
+	HandshakeTimeout:  45 * time.Second,
+	EnableCompression: true,
+}
+
+// connectInternal dials the server and starts the loops for the new
+// connection. Callers must hold c.mu.
 func (c *WebSocketClient) connectInternal() error {
 	u, err := url.Parse(c.url)
 	if err != nil {
 		return err
 	}
 
-	// Add session_uuid query parameter if needed
+	// Rejoin the current session, if any, so the server keeps its history
 	q := u.Query()
-	q.Set("session_uuid", "")
+	q.Set("session_uuid", c.state.SessionID)
 	u.RawQuery = q.Encode()
 
 	header := http.Header{}
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+	conn, _, err := dialer.Dial(u.String(), header)
 	if err != nil {
 		return err
 	}
+	// Our messages are small; only the server's large events are compressed
+	conn.EnableWriteCompression(false)
 
 	c.conn = conn
 	c.state.IsConnected = true
 
+	// Deliver anything queued while we were offline
+	c.flushOutbox()
+
 	// Start the message handler
-	go c.handleMessages()
+	go c.handleMessages(c.ctx, conn)
 
 	// Start the ping loop
-	go c.pingLoop()
+	go c.pingLoop(c.ctx, conn)
 
 	if c.onConnected != nil {
 		c.onConnected()
@@ -81,13 +151,13 @@ func (c *WebSocketClient) connectInternal() error {
 	return nil
 }
 
-// Disconnect closes the WebSocket connection
+// Disconnect closes the WebSocket connection and stops any reconnect
+// attempt. It is safe to call more than once.
 func (c *WebSocketClient) Disconnect() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.reconnect = false
-	close(c.stopChan)
+	c.cancel()
 
 	if c.conn != nil {
 		c.conn.Close()
@@ -105,27 +175,73 @@ func (c *WebSocketClient) Disconnect() {
 	}
 }
 
-// SendMessage sends a message to the server
+// SendMessage sends a message to the server. While the connection is down
+// (and reconnection is enabled) the message is queued and delivered once
+// the client reconnects; ErrOutboxFull is returned when the queue is full.
+// Pings are never queued.
 func (c *WebSocketClient) SendMessage(msgType string, content interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.conn == nil {
-		return ErrNotConnected
-	}
-
 	msg := WebSocketMessage{
 		Type:    msgType,
 		Content: mustMarshal(content),
 	}
 
+	if c.conn == nil {
+		if msgType == "ping" || c.ctx.Err() != nil {
+			return ErrNotConnected
+		}
+		if len(c.outbox) >= c.outboxCap {
+			return ErrOutboxFull
+		}
+		c.outbox = append(c.outbox, msg)
+		return nil
+	}
+
 	return c.conn.WriteJSON(msg)
 }
 
-// handleMessages reads and processes incoming messages
-func (c *WebSocketClient) handleMessages() {
+// flushOutbox writes queued messages in order. Each message leaves the
+// queue only once written, so a failed flush resumes where it stopped
+// without resending. Callers must hold c.mu.
+func (c *WebSocketClient) flushOutbox() {
+	for len(c.outbox) > 0 {
+		if err := c.conn.WriteJSON(c.outbox[0]); err != nil {
+			log.Printf("Error flushing queued message: %v", err)
+			return
+		}
+		c.outbox = c.outbox[1:]
+	}
+	c.outbox = nil
+}
+
+// PendingMessages returns the number of messages waiting for a connection
+func (c *WebSocketClient) PendingMessages() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.outbox)
+}
+
+// handleMessages reads and processes incoming messages from conn. When
+// the connection drops without a Disconnect, it starts reconnecting.
+func (c *WebSocketClient) handleMessages(ctx context.Context, conn *websocket.Conn) {
 	defer func() {
+		c.mu.Lock()
+		if c.conn != conn {
+			// Disconnect already tore this connection down
+			c.mu.Unlock()
+			return
+		}
+		// Drop the dead connection so sends are queued until we reconnect
+		c.conn = nil
 		c.state.IsConnected = false
+		startReconnect := ctx.Err() == nil && !c.reconnecting
+		if startReconnect {
+			c.reconnecting = true
+		}
+		c.mu.Unlock()
+
 		if c.onDisconnected != nil {
 			c.onDisconnected()
 		}
@@ -133,31 +249,21 @@ func (c *WebSocketClient) handleMessages() {
 			c.onStateChange()
 		}
 
-		// Attempt reconnection if enabled
-		if c.reconnect {
-			go c.reconnectLoop()
+		if startReconnect {
+			go c.reconnectLoop(ctx)
 		}
 	}()
 
 	for {
-		select {
-		case <-c.stopChan:
-			return
-		default:
-			if c.conn == nil {
-				return
-			}
-
-			_, message, err := c.conn.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("WebSocket error: %v", err)
-				}
-				return
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil && websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("WebSocket error: %v", err)
 			}
-
-			c.processMessage(message)
+			return
 		}
+
+		c.processMessage(message)
 	}
 }
 
@@ -174,6 +280,7 @@ func (c *WebSocketClient) processMessage(data []byte) {
 		var event ConnectionEstablishedEvent
 		if err := json.Unmarshal(msg.Content, &event); err == nil {
 			c.state.WorkspacePath = event.WorkspacePath
+			c.state.SessionID = event.SessionID
 			if c.onEvent != nil {
 				c.onEvent(msg.Type, event)
 			}
@@ -243,6 +350,78 @@ func (c *WebSocketClient) processMessage(data []byte) {
 	case EventTypeToolResult:
 		var event ToolResultEvent
 		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			event.decodeResult()
+			if c.onEvent != nil {
+				c.onEvent(msg.Type, event)
+			}
+		}
+
+	case EventTypeAgentThinking:
+		var event AgentThinkingEvent
+		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			if c.onThinking != nil {
+				c.onThinking(event)
+			}
+			if c.onEvent != nil {
+				c.onEvent(msg.Type, event)
+			}
+		}
+
+	case EventTypePong:
+		c.state.LastPong = time.Now()
+		if c.onEvent != nil {
+			c.onEvent(msg.Type, PongEvent{})
+		}
+
+	case EventTypeWorkspaceInfo:
+		var event WorkspaceInfoEvent
+		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			c.state.WorkspacePath = event.Path
+			if c.onEvent != nil {
+				c.onEvent(msg.Type, event)
+			}
+		}
+
+	case EventTypeResponseInterrupt:
+		var event ResponseInterruptEvent
+		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			c.state.IsLoading = false
+			if event.Text != "" {
+				c.state.AddMessage(NewMessage("assistant", event.Text))
+			}
+			if c.onEvent != nil {
+				c.onEvent(msg.Type, event)
+			}
+		}
+
+	case EventTypeSessionSummary:
+		var event SessionSummaryEvent
+		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			c.state.LastSummary = &event
+			if c.onEvent != nil {
+				c.onEvent(msg.Type, event)
+			}
+		}
+
+	case EventTypeUsage:
+		var event UsageEvent
+		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			c.state.Usage = event
+			if c.onEvent != nil {
+				c.onEvent(msg.Type, event)
+			}
+		}
+
+	case EventTypeBrowserTabs:
+		var event BrowserTabsEvent
+		if err := json.Unmarshal(msg.Content, &event); err == nil {
+			c.state.BrowserTabs = event.Tabs
+			c.state.ActiveBrowserTab = event.Active
+			for _, tab := range event.Tabs {
+				if tab.PageID == event.Active {
+					c.state.BrowserURL = tab.URL
+				}
+			}
 			if c.onEvent != nil {
 				c.onEvent(msg.Type, event)
 			}
@@ -257,42 +436,82 @@ func (c *WebSocketClient) processMessage(data []byte) {
 	}
 }
 
-// pingLoop sends periodic pings to keep the connection alive
-func (c *WebSocketClient) pingLoop() {
+// pingLoop sends periodic pings on conn until ctx ends or conn is replaced
+func (c *WebSocketClient) pingLoop(ctx context.Context, conn *websocket.Conn) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-c.stopChan:
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			c.mu.Lock()
-			if c.conn != nil {
-				c.conn.WriteJSON(WebSocketMessage{Type: "ping"})
+			if c.conn != conn {
+				c.mu.Unlock()
+				return
 			}
+			conn.WriteJSON(WebSocketMessage{Type: "ping"})
 			c.mu.Unlock()
 		}
 	}
 }
 
-// reconnectLoop attempts to reconnect to the server
-func (c *WebSocketClient) reconnectLoop() {
-	// Create a new stopChan for reconnection attempts
+// reconnectDelay returns the jittered backoff before the given attempt
+// (0-based): a random duration in [d/2, d) where d doubles per attempt.
+func reconnectDelay(attempt int, base, max time.Duration) time.Duration {
+	d := base << attempt
+	if d > max || d <= 0 {
+		d = max
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// reconnectLoop attempts to reconnect until it succeeds, runs out of
+// attempts, or ctx is cancelled by Disconnect.
+func (c *WebSocketClient) reconnectLoop(ctx context.Context) {
+	defer func() {
+		c.mu.Lock()
+		c.reconnecting = false
+		c.mu.Unlock()
+	}()
+
 	c.mu.Lock()
-	c.stopChan = make(chan struct{})
+	base, max, attempts := c.reconnectBase, c.reconnectMax, c.maxReconnectAttempts
 	c.mu.Unlock()
 
-	for i := 0; i < 5 && c.reconnect; i++ {
-		time.Sleep(time.Duration(i+1) * time.Second)
-		log.Printf("Attempting to reconnect (%d/5)...", i+1)
-		
-		if err := c.connectInternal(); err == nil {
+	for i := 0; i < attempts; i++ {
+		delay := reconnectDelay(i, base, max)
+		if c.onReconnectAttempt != nil {
+			c.onReconnectAttempt(i+1, attempts, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		log.Printf("Attempting to reconnect (%d/%d)...", i+1, attempts)
+
+		c.mu.Lock()
+		if ctx.Err() != nil || c.conn != nil {
+			// Disconnected meanwhile, or someone else already reconnected
+			c.mu.Unlock()
+			return
+		}
+		err := c.connectInternal()
+		c.mu.Unlock()
+		if err == nil {
 			log.Println("Reconnected successfully")
 			return
 		}
 	}
-	log.Println("Failed to reconnect after 5 attempts")
+	log.Printf("Failed to reconnect after %d attempts", attempts)
+	if c.onReconnectFailed != nil {
+		c.onReconnectFailed()
+	}
 }
 
 // SetOnEvent sets the event callback
@@ -305,6 +524,11 @@ func (c *WebSocketClient) SetOnStateChange(callback func()) {
 	c.onStateChange = callback
 }
 
+// SetOnThinking sets the callback receiving the agent's reasoning
+func (c *WebSocketClient) SetOnThinking(callback func(event AgentThinkingEvent)) {
+	c.onThinking = callback
+}
+
 // SetOnConnected sets the connected callback
 func (c *WebSocketClient) SetOnConnected(callback func()) {
 	c.onConnected = callback
@@ -315,12 +539,24 @@ func (c *WebSocketClient) SetOnDisconnected(callback func()) {
 	c.onDisconnected = callback
 }
 
-// InitAgent sends the init_agent message
-func (c *WebSocketClient) InitAgent(modelName string, toolArgs map[string]interface{}, thinkingTokens int) error {
+// SetOnReconnectAttempt sets the callback announcing each reconnect
+// attempt (1-based, out of the attempt limit) and the delay before it runs
+func (c *WebSocketClient) SetOnReconnectAttempt(callback func(attempt, of int, retryIn time.Duration)) {
+	c.onReconnectAttempt = callback
+}
+
+// SetOnReconnectFailed sets the callback run when every reconnect attempt failed
+func (c *WebSocketClient) SetOnReconnectFailed(callback func()) {
+	c.onReconnectFailed = callback
+}
+
+// InitAgent sends the init_agent message; a nil sandbox keeps the server's default
+func (c *WebSocketClient) InitAgent(modelName string, toolArgs map[string]interface{}, thinkingTokens int, sandbox *SandboxSettings) error {
 	return c.SendMessage("init_agent", InitAgentContent{
 		ModelName:      modelName,
 		ToolArgs:       toolArgs,
 		ThinkingTokens: thinkingTokens,
+		Sandbox:        sandbox,
 	})
 }
 
@@ -346,10 +582,43 @@ func (c *WebSocketClient) CancelQuery() error {
 	return c.SendMessage("cancel", map[string]interface{}{})
 }
 
+// AnswerToolCall approves or denies a tool call sent with
+// AwaitingApproval
+func (c *WebSocketClient) AnswerToolCall(toolCallID string, approved bool) error {
+	msgType := "deny"
+	if approved {
+		msgType = "approve"
+	}
+	return c.SendMessage(msgType, map[string]interface{}{"tool_call_id": toolCallID})
+}
+
+// SwitchBrowserTab asks the server to make the browser tab with pageID
+// the current one
+func (c *WebSocketClient) SwitchBrowserTab(pageID int) error {
+	return c.SendMessage("switch_tab", map[string]interface{}{"page_id": pageID})
+}
+
+// TranscribeAudio asks the server to transcribe an audio file uploaded to
+// path; with asQuery the transcript is also run as a query
+func (c *WebSocketClient) TranscribeAudio(path string, asQuery bool) error {
+	return c.SendMessage("audio", map[string]interface{}{"path": path, "as_query": asQuery})
+}
+
+// Subscribe limits the events this connection receives to eventTypes;
+// no types restores all events
+func (c *WebSocketClient) Subscribe(eventTypes ...string) error {
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return c.SendMessage("subscribe", map[string]interface{}{"event_types": eventTypes})
+}
+
 // Helper functions
 
 var ErrNotConnected = &ConnectionError{Message: "not connected to server"}
 
+var ErrOutboxFull = &ConnectionError{Message: "not connected and the outgoing message queue is full"}
+
 type ConnectionError struct {
 	Message string
 }
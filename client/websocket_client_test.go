@@ -0,0 +1,319 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testServer is a WebSocket endpoint that records received message types
+// and can drop its current connection to simulate a network blip.
+type testServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	received []WebSocketMessage
+	conns    []*websocket.Conn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ts := &testServer{}
+	upgrader := websocket.Upgrader{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		ts.mu.Lock()
+		ts.conns = append(ts.conns, conn)
+		ts.mu.Unlock()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg WebSocketMessage
+			json.Unmarshal(data, &msg)
+			ts.mu.Lock()
+			ts.received = append(ts.received, msg)
+			ts.mu.Unlock()
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *testServer) wsURL() string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func (ts *testServer) dropConnections() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, conn := range ts.conns {
+		conn.Close()
+	}
+	ts.conns = nil
+}
+
+func (ts *testServer) queries() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	var out []string
+	for _, msg := range ts.received {
+		if msg.Type != "query" {
+			continue
+		}
+		var q QueryContent
+		json.Unmarshal(msg.Content, &q)
+		out = append(out, q.Text)
+	}
+	return out
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestSendMessageBufferedUntilReconnect(t *testing.T) {
+	ts := newTestServer(t)
+	c := NewWebSocketClient(ts.wsURL(), NewAppState())
+	c.SetReconnectBackoff(20*time.Millisecond, 100*time.Millisecond, 5)
+	defer c.Disconnect()
+
+	// Queued before the first connection.
+	if err := c.SendQuery("first", false, nil); err != nil {
+		t.Fatalf("SendQuery() while disconnected error = %v", err)
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if !waitFor(t, 2*time.Second, func() bool { return len(ts.queries()) == 1 }) {
+		t.Fatalf("queued message not delivered on connect: %v", ts.queries())
+	}
+
+	// Queued during a reconnect window.
+	ts.dropConnections()
+	if !waitFor(t, 2*time.Second, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.conn == nil
+	}) {
+		t.Fatal("client did not notice the dropped connection")
+	}
+	if err := c.SendQuery("second", false, nil); err != nil {
+		t.Fatalf("SendQuery() during reconnect error = %v", err)
+	}
+	if err := c.SendQuery("third", false, nil); err != nil {
+		t.Fatalf("SendQuery() during reconnect error = %v", err)
+	}
+
+	if !waitFor(t, 5*time.Second, func() bool { return len(ts.queries()) == 3 }) {
+		t.Fatalf("queued messages not delivered after reconnect: %v", ts.queries())
+	}
+	got := ts.queries()
+	if got[0] != "first" || got[1] != "second" || got[2] != "third" {
+		t.Errorf("delivery order = %v; want [first second third]", got)
+	}
+	if n := c.PendingMessages(); n != 0 {
+		t.Errorf("PendingMessages() = %d; want 0", n)
+	}
+}
+
+func TestSendMessageOutboxFull(t *testing.T) {
+	c := NewWebSocketClient("ws://127.0.0.1:0", NewAppState())
+	c.SetOutboxCapacity(2)
+
+	for i := 0; i < 2; i++ {
+		if err := c.SendMessage("query", QueryContent{Text: "x"}); err != nil {
+			t.Fatalf("SendMessage() #%d error = %v", i, err)
+		}
+	}
+	if err := c.SendMessage("query", QueryContent{Text: "x"}); err != ErrOutboxFull {
+		t.Errorf("SendMessage() on full outbox = %v; want ErrOutboxFull", err)
+	}
+	if err := c.SendMessage("ping", nil); err != ErrNotConnected {
+		t.Errorf("ping while disconnected = %v; want ErrNotConnected", err)
+	}
+	if n := c.PendingMessages(); n != 2 {
+		t.Errorf("PendingMessages() = %d; want 2", n)
+	}
+}
+
+func (ts *testServer) connCount() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return len(ts.conns)
+}
+
+func TestConnectDisconnectConcurrently(t *testing.T) {
+	ts := newTestServer(t)
+	c := NewWebSocketClient(ts.wsURL(), NewAppState())
+	c.SetReconnectBackoff(time.Millisecond, 5*time.Millisecond, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if (i+j)%2 == 0 {
+					c.Connect()
+				} else {
+					c.Disconnect()
+				}
+				if j%3 == 0 {
+					ts.dropConnections()
+				}
+				c.SendQuery("hello", false, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	c.Disconnect()
+	c.Disconnect() // idempotent
+
+	// No reconnect loop may survive the final Disconnect.
+	time.Sleep(50 * time.Millisecond)
+	ts.dropConnections()
+	time.Sleep(100 * time.Millisecond)
+	if n := ts.connCount(); n != 0 {
+		t.Errorf("client reconnected %d time(s) after Disconnect", n)
+	}
+
+	// The client can still be reused.
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() after Disconnect error = %v", err)
+	}
+	c.Disconnect()
+}
+
+func TestDisconnectStopsReconnectLoop(t *testing.T) {
+	ts := newTestServer(t)
+	c := NewWebSocketClient(ts.wsURL(), NewAppState())
+	c.SetReconnectBackoff(50*time.Millisecond, 50*time.Millisecond, 5)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	ts.dropConnections()
+	waitFor(t, time.Second, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.reconnecting
+	})
+	c.Disconnect()
+
+	time.Sleep(150 * time.Millisecond)
+	if n := ts.connCount(); n != 0 {
+		t.Errorf("reconnect loop kept running after Disconnect (%d new connections)", n)
+	}
+}
+
+func TestReconnectDelayJitter(t *testing.T) {
+	base, max := 100*time.Millisecond, time.Second
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		want *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			d := reconnectDelay(attempt, base, max)
+			if d < want/2 || d > want {
+				t.Fatalf("reconnectDelay(%d) = %v; want in [%v, %v]", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestReconnectAttemptCallbacks(t *testing.T) {
+	// Accept the first connection, then refuse every reconnect.
+	var accepted bool
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accepted {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		accepted = true
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	c := NewWebSocketClient("ws"+strings.TrimPrefix(srv.URL, "http"), NewAppState())
+	c.SetReconnectBackoff(time.Millisecond, 5*time.Millisecond, 3)
+
+	var mu sync.Mutex
+	var attempts []int
+	failed := make(chan struct{})
+	c.SetOnReconnectAttempt(func(attempt, of int, retryIn time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if of != 3 {
+			t.Errorf("attempt limit = %d; want 3", of)
+		}
+		if retryIn <= 0 || retryIn > 5*time.Millisecond {
+			t.Errorf("retryIn = %v; want within the backoff bounds", retryIn)
+		}
+		attempts = append(attempts, attempt)
+	})
+	c.SetOnReconnectFailed(func() { close(failed) })
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	select {
+	case <-failed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("reconnect failure callback not called")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[1] != 2 || attempts[2] != 3 {
+		t.Errorf("attempts = %v; want [1 2 3]", attempts)
+	}
+}
+
+func TestConnectRejoinsSession(t *testing.T) {
+	got := make(chan string, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.URL.Query().Get("session_uuid")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	state := NewAppState()
+	state.SessionID = "2b7e1f2c-0000-4000-8000-000000000001"
+	c := NewWebSocketClient("ws"+strings.TrimPrefix(srv.URL, "http"), state)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if id := <-got; id != state.SessionID {
+		t.Errorf("session_uuid = %q; want %q", id, state.SessionID)
+	}
+}
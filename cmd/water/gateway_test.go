@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"water-ai/core/config"
+	"water-ai/db"
 	"water-ai/server"
 )
 
@@ -108,3 +115,124 @@ func TestWaitForServer(t *testing.T) {
 		t.Errorf("expected 200, got %d", resp.StatusCode)
 	}
 }
+
+// TestServeGatewayShutsDownOnSignal verifies that cancelling the signal
+// context stops the gateway cleanly.
+func TestServeGatewayShutsDownOnSignal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testPort := "17779"
+	srv := server.CreateServer(server.Config{Port: testPort})
+	srv.Router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	httpServer := &http.Server{
+		Addr:    ":" + testPort,
+		Handler: srv.Router,
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveGateway(ctx, srv, httpServer)
+	}()
+
+	healthURL := "http://localhost:" + testPort + "/health"
+	waitForServer(healthURL, 5*time.Second)
+	cancel(errors.New("interrupt signal received"))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serveGateway() error = %v; want nil after shutdown", err)
+		}
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("serveGateway did not return after the signal")
+	}
+
+	client := http.Client{Timeout: 500 * time.Millisecond}
+	if resp, err := client.Get(healthURL); err == nil {
+		resp.Body.Close()
+		t.Error("gateway still serving after shutdown")
+	}
+}
+
+// TestServeGatewayListenError verifies that a failure to listen is
+// returned rather than waiting for a signal.
+func TestServeGatewayListenError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	srv := server.CreateServer(server.Config{})
+	httpServer := &http.Server{Addr: ":-1", Handler: srv.Router}
+	if err := serveGateway(context.Background(), srv, httpServer); err == nil {
+		t.Error("serveGateway() error = nil; want the listen error")
+	}
+}
+
+// TestUnifiedGatewayListensOnLoopbackOnly verifies that the GUI's gateway,
+// which runs without an API key, cannot be reached from other hosts.
+func TestUnifiedGatewayListensOnLoopbackOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testPort := "17780"
+	srv := server.CreateServer(server.Config{Port: testPort})
+	srv.Router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	httpServer := unifiedHTTPServer(testPort, srv.Router)
+	go httpServer.ListenAndServe()
+	defer httpServer.Close()
+
+	waitForServer("http://127.0.0.1:"+testPort+"/health", 5*time.Second)
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:"+testPort, time.Second)
+	if err != nil {
+		t.Fatalf("gateway not reachable on loopback: %v", err)
+	}
+	conn.Close()
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked := 0
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		checked++
+		addr := net.JoinHostPort(ipNet.IP.String(), testPort)
+		if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+			conn.Close()
+			t.Errorf("gateway reachable on %s; want loopback only", addr)
+		}
+	}
+	if checked == 0 {
+		t.Skip("no non-loopback address to check")
+	}
+}
+
+// TestOpenDatabase verifies that the gateway opens the database at the
+// configured URL, creating its directory.
+func TestOpenDatabase(t *testing.T) {
+	cfg := config.NewFullConfig()
+	path := filepath.Join(t.TempDir(), "store", "water_agent.db")
+	url := "sqlite:///" + path
+	cfg.Agent.DatabaseURL = &url
+
+	if err := openDatabase(cfg); err != nil {
+		t.Fatalf("openDatabase() error = %v", err)
+	}
+	defer db.Close()
+	if db.DB == nil {
+		t.Fatal("db.DB is nil after openDatabase")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database file not created: %v", err)
+	}
+
+	cfg.Agent.DatabaseURL = nil
+	if err := openDatabase(cfg); err == nil {
+		t.Error("openDatabase() error = nil; want an error without a URL")
+	}
+}
@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"water-ai/core"
+	"water-ai/core/config"
+	"water-ai/db"
 	"water-ai/resources"
 	"water-ai/server"
 	"water-ai/ui"
@@ -27,60 +33,113 @@ var (
 
 const (
 	serverPort = "7777"
-	serverURL  = "http://localhost:" + serverPort
+	serverURL  = "http://" + loopbackHost + ":" + serverPort
 	healthURL  = serverURL + "/health"
+	// loopbackHost is the only address the GUI's gateway listens on.
+	loopbackHost = "127.0.0.1"
 )
 
 func main() {
-	// ---------------------------------------------------------
-	// MODE 1: Background Service (headless daemon)
-	// ---------------------------------------------------------
-	// When invoked with "server" argument, run only the gateway.
-	if len(os.Args) > 1 && os.Args[1] == "server" {
-		runBackgroundService()
-		return
+	logger := core.Logger
+	flags, args, err := parseStartupFlags(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
 	}
 
 	// ---------------------------------------------------------
-	// MODE 2: Version flag
+	// MODE 1: Version flag
 	// ---------------------------------------------------------
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
+	if flags.version {
 		fmt.Printf("Water AI %s (commit: %s, built: %s, go: %s)\n",
 			Version, GitCommit, BuildDate, GoVersion)
 		return
 	}
 
+	cfg, err := loadStartupConfig(flags)
+	if err != nil {
+		logger.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Agent.LogFormat != "" {
+		core.SetLogFormat(string(cfg.Agent.LogFormat))
+	}
+
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	// ---------------------------------------------------------
+	// MODE 2: Background Service (headless daemon)
+	// ---------------------------------------------------------
+	// When invoked with "server" argument, run only the gateway.
+	case "server":
+		mustOpenDatabase(cfg)
+		runBackgroundService(serverConfig(cfg, flags, serverPort))
+
+	// ---------------------------------------------------------
+	// MODE 3: One-shot agent task (headless, no gateway)
+	// ---------------------------------------------------------
+	case "run":
+		os.Exit(runCommand(args[1:], cfg, os.Stdin, os.Stdout, os.Stderr, newAgentClient))
+
 	// ---------------------------------------------------------
-	// MODE 3: Unified GUI + Gateway (default)
+	// MODE 4: Unified GUI + Gateway (default)
 	// ---------------------------------------------------------
 	// Start the gateway in a background goroutine, then launch
 	// the Fyne GUI on the main thread. When the GUI window is
 	// closed the gateway is gracefully shut down.
-	runUnified()
+	case "":
+		srvCfg := serverConfig(cfg, flags, serverPort)
+		// The GUI connects to the fixed local port without a key.
+		srvCfg.Port = serverPort
+		srvCfg.APIKey = ""
+		mustOpenDatabase(cfg)
+		runUnified(srvCfg)
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		os.Exit(2)
+	}
+}
+
+// openDatabase opens the session database at the configured
+// Agent.DatabaseURL, which the loaded config derives from FileStorePath
+// when unset.
+func openDatabase(cfg *config.FullConfig) error {
+	if cfg.Agent.DatabaseURL == nil || *cfg.Agent.DatabaseURL == "" {
+		return errors.New("no database URL configured")
+	}
+	return db.InitDB(*cfg.Agent.DatabaseURL)
+}
+
+// mustOpenDatabase opens the session database for the gateway, exiting
+// when it cannot be opened.
+func mustOpenDatabase(cfg *config.FullConfig) {
+	if err := openDatabase(cfg); err != nil {
+		core.Logger.Error("Failed to open database", "error", err)
+		os.Exit(1)
+	}
 }
 
 // runUnified starts the gateway service in a goroutine and the Fyne GUI
 // on the main thread. The gateway is shut down when the GUI exits.
-func runUnified() {
+func runUnified(cfg server.Config) {
 	logger := core.Logger
 
 	// --- Start the gateway server in the background ---
-	srv := server.CreateServer(server.Config{
-		Port: serverPort,
-	})
+	srv := server.CreateServer(cfg)
+	srv.Build = buildInfo()
 
 	// Add health endpoint for connectivity checks
 	srv.Router.GET("/health", func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
 
-	httpServer := &http.Server{
-		Addr:    ":" + serverPort,
-		Handler: srv.Router,
-	}
+	httpServer := unifiedHTTPServer(serverPort, srv.Router)
 
 	go func() {
-		logger.Info("Water AI gateway starting", "port", serverPort)
+		logger.Info("Water AI gateway starting", "addr", httpServer.Addr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("Gateway failed to start", "error", err)
 		}
@@ -101,31 +160,103 @@ func runUnified() {
 
 	// --- GUI has exited — shut down the gateway gracefully ---
 	logger.Info("GUI closed, shutting down gateway...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := httpServer.Shutdown(ctx); err != nil {
-		logger.Error("Gateway shutdown error", "error", err)
-	}
+	shutdownGateway(srv, httpServer)
 	logger.Info("Water AI shut down cleanly")
 }
 
+// unifiedHTTPServer returns the HTTP server for the GUI's gateway. The
+// gateway runs without an API key and its agents run commands, so it only
+// listens on the loopback address.
+func unifiedHTTPServer(port string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:    net.JoinHostPort(loopbackHost, port),
+		Handler: handler,
+	}
+}
+
 // runBackgroundService runs the gateway as a standalone headless service.
-func runBackgroundService() {
+func runBackgroundService(cfg server.Config) {
 	logger := core.Logger
-	logger.Info("Water AI Background Service Started", "port", serverPort)
+	logger.Info("Water AI Background Service Started", "port", cfg.Port)
 
-	srv := server.CreateServer(server.Config{
-		Port: serverPort,
-	})
+	srv := server.CreateServer(cfg)
+	srv.Build = buildInfo()
 
 	srv.Router.GET("/health", func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
 
-	if err := srv.Router.Run(":" + serverPort); err != nil {
+	httpServer := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: srv.Router,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := serveGateway(ctx, srv, httpServer); err != nil {
 		logger.Error("Service failed to start", "error", err)
 		os.Exit(1)
 	}
+	logger.Info("Water AI shut down cleanly")
+}
+
+// shutdownTimeout bounds how long the gateway waits for sessions and
+// requests to finish when stopping.
+const shutdownTimeout = 5 * time.Second
+
+// serveGateway serves httpServer until it fails or ctx is done, then shuts
+// the gateway down gracefully. It returns nil after a clean shutdown.
+func serveGateway(ctx context.Context, srv *server.Server, httpServer *http.Server) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	core.Logger.Info("Shutting down gateway", "reason", context.Cause(ctx))
+	shutdownGateway(srv, httpServer)
+	if err := <-errCh; err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// shutdownGateway drains the WebSocket sessions, stops the HTTP server and
+// closes the database, giving up after shutdownTimeout. The database stays
+// open when agent turns outlive the drain, since they may still write to it.
+func shutdownGateway(srv *server.Server, httpServer *http.Server) {
+	logger := core.Logger
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	drainErr := srv.WSManager.Shutdown(ctx)
+	if drainErr != nil {
+		logger.Warn("WebSocket sessions did not drain in time", "error", drainErr)
+	}
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Error("Gateway shutdown error", "error", err)
+	}
+	if drainErr != nil {
+		logger.Warn("Leaving the database open for agent turns still running")
+		return
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close database", "error", err)
+	}
+}
+
+// buildInfo returns the ldflags-injected version details
+func buildInfo() server.BuildInfo {
+	return server.BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: GoVersion,
+	}
 }
 
 // waitForServer polls the health endpoint until it responds or the timeout elapses.
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"water-ai/agents"
+	"water-ai/core/config"
+	"water-ai/llm"
+	contextmanager "water-ai/llm/context_manager"
+	"water-ai/prompts"
+	"water-ai/tools"
+	"water-ai/utils"
+)
+
+// clientFactory creates the LLM client for a model from the LLM
+// configuration; tests substitute a mock.
+type clientFactory func(cfg config.LLMConfig, model string) (agents.LLMClient, error)
+
+// newAgentClient returns a client for model using the key, provider, base
+// URL and temperature in cfg, falling back to the environment for the key.
+func newAgentClient(cfg config.LLMConfig, model string) (agents.LLMClient, error) {
+	client, err := llm.NewClientFromConfig(cfg, model, 0)
+	if err != nil {
+		return nil, err
+	}
+	return agents.NewLLMClientAdapter(client, cfg.Temperature), nil
+}
+
+type systemPrompt string
+
+func (p systemPrompt) GetSystemPrompt() string { return string(p) }
+
+// runCommand implements `water run [flags] [instruction]`: it runs one
+// agent task in a workspace directory without the GUI or gateway, prints
+// the final answer to stdout and returns the process exit code. The
+// instruction is read from stdin when it is not given as an argument.
+// Flag defaults come from cfg.
+func runCommand(args []string, cfg *config.FullConfig, stdin io.Reader, stdout, stderr io.Writer, newClient clientFactory) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: water run [flags] [instruction]")
+		fmt.Fprintln(stderr, "\nRuns one agent task and prints the final answer. The instruction is read from stdin if omitted.")
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	defaultModel := cfg.LLM.Model
+	if defaultModel == "" {
+		defaultModel = config.DefaultModel
+	}
+	model := fs.String("model", defaultModel, "LLM model to use")
+	workspace := fs.String("workspace", ".", "directory the agent works in")
+	maxTurns := fs.Int("max-turns", cfg.Agent.MaxTurns, "maximum number of agent turns")
+	reviewRounds := fs.Int("review-rounds", 0, "have a reviewer check the result up to this many times, sending failures back to the agent; 0 disables review")
+	verbose := fs.Bool("verbose", false, "log agent progress to stderr")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	instruction := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if instruction == "" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read instruction from stdin: %v\n", err)
+			return 1
+		}
+		instruction = strings.TrimSpace(string(data))
+	}
+	if instruction == "" {
+		fs.Usage()
+		return 2
+	}
+
+	root, err := filepath.Abs(*workspace)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid workspace: %v\n", err)
+		return 1
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		fmt.Fprintf(stderr, "failed to create workspace: %v\n", err)
+		return 1
+	}
+
+	client, err := newClient(cfg.LLM, *model)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create LLM client: %v\n", err)
+		return 1
+	}
+
+	logger := log.New(io.Discard, "", 0)
+	if *verbose {
+		logger = log.New(stderr, "", log.LstdFlags)
+	}
+
+	events := make(chan agents.RealtimeEvent, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for evt := range events {
+			switch evt.Type {
+			case agents.EventTypeToolCall:
+				fmt.Fprintf(stderr, "> %v\n", evt.Content["tool_name"])
+			case agents.EventTypeSystem:
+				fmt.Fprintf(stderr, "%v\n", evt.Content["message"])
+			}
+		}
+	}()
+
+	// There is no sandbox here: commands run on the host, pinned to the
+	// workspace
+	settings := utils.NewSandboxSettings()
+	executor := utils.NewLocalExecutor(utils.NewWorkspaceManager(filepath.Dir(root), filepath.Base(root), settings), settings)
+
+	history := agents.NewHistory()
+	agent := agents.NewFunctionCallAgent(
+		systemPrompt(prompts.GetSystemPrompt(prompts.WorkspaceModeLocal, false)),
+		client,
+		agents.NewAgentToolManager(agents.OffloadLargeOutputs([]agents.LLMTool{
+			agents.WrapTool(&tools.FileEditorTool{BaseDir: root}),
+			agents.WrapTool(&tools.TerminalTool{WorkDir: root, Executor: executor}),
+		}, root, 0)),
+		history,
+		&agents.DirWorkspace{Root: root},
+		events,
+		logger,
+		nil,
+		cfg.Agent.MaxOutputTokensPerTurn,
+		*maxTurns,
+		nil,
+	)
+	agent.ApplyConfig(&cfg.Agent)
+	// -max-turns defaults to the configured limit but may override it
+	agent.MaxTurns = *maxTurns
+	agent.MinimizeStdoutLogs = cfg.Agent.MinimizeStdoutLogs
+
+	if *reviewRounds > 0 {
+		reviewTools := agents.NewAgentToolManager(agents.OffloadLargeOutputs([]agents.LLMTool{
+			agents.WrapTool(&tools.FileEditorTool{BaseDir: root}),
+			agents.WrapTool(&tools.TerminalTool{WorkDir: root, Executor: executor}),
+			agents.NewReturnControlTool(),
+		}, root, 0))
+		cm := agents.NewLLMContextManager(client, nil, contextmanager.BudgetedConfig())
+		cm.ReserveSystemTokens(prompts.ReviewerSystemPrompt, reviewTools)
+		reviewer := agents.NewReviewerAgent(
+			prompts.ReviewerSystemPrompt,
+			client,
+			reviewTools,
+			events,
+			logger,
+			cm,
+			agents.NewHistory(),
+			cfg.Agent.MaxOutputTokensPerTurn,
+			*maxTurns,
+			nil,
+		)
+		reviewer.TurnTimeout = cfg.Agent.TurnTimeout()
+		loop := agents.NewReviewLoop(agent, reviewer, root, events, logger)
+		loop.MaxRounds = *reviewRounds
+
+		result, err := loop.Run(instruction, nil)
+		close(events)
+		<-done
+		if err != nil && !errors.Is(err, agents.ErrReviewFailed) {
+			fmt.Fprintf(stderr, "agent failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, result)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	out, err := agent.Run(context.Background(), map[string]interface{}{"instruction": instruction}, history)
+	close(events)
+	<-done
+	if err != nil {
+		fmt.Fprintf(stderr, "agent failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, out.ToolOutput)
+	if out.ToolResultMessage != agents.CompleteMessage {
+		fmt.Fprintf(stderr, "agent stopped before completing the task: %s\n", out.ToolResultMessage)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"water-ai/agents"
+	"water-ai/core/config"
+	"water-ai/llm"
+)
+
+// mockClientFactory serves every model from mock.
+func mockClientFactory(mock *llm.MockClient) clientFactory {
+	return func(cfg config.LLMConfig, model string) (agents.LLMClient, error) {
+		return agents.NewLLMClientAdapter(mock, 0), nil
+	}
+}
+
+func TestRunCommandPrintsFinalAnswer(t *testing.T) {
+	workspace := t.TempDir()
+	mock := llm.NewMockClient()
+	mock.EnqueueBlocks(&llm.ContentBlock{
+		Type:       llm.ContentTypeToolCall,
+		ToolCallID: "call-1",
+		ToolName:   "file_editor",
+		ToolInput:  map[string]interface{}{"action": "write", "path": "answer.txt", "content": "42"},
+	})
+	mock.EnqueueBlocks(&llm.ContentBlock{Type: llm.ContentTypeText, Text: "The answer is 42."})
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"--model", "mock", "--workspace", workspace, "write the answer"}, config.NewFullConfig(), strings.NewReader(""), &stdout, &stderr, mockClientFactory(mock))
+	if code != 0 {
+		t.Fatalf("exit code = %d; want 0 (stderr: %s)", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "The answer is 42." {
+		t.Errorf("stdout = %q; want the final answer", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "answer.txt")); err != nil || string(data) != "42" {
+		t.Errorf("answer.txt = %q, %v; want the tool to have written 42", data, err)
+	}
+	if !strings.Contains(stderr.String(), "file_editor") {
+		t.Errorf("stderr = %q; want the tool call reported", stderr.String())
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("LLM calls = %d; want 2", len(calls))
+	}
+	first := calls[0].Messages[0].Content[0].Text
+	if first != "write the answer" {
+		t.Errorf("first prompt = %q; want the instruction", first)
+	}
+}
+
+func TestRunCommandRunsCommandsInWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "pwd > where.txt"})).
+		EnqueueBlocks(llm.TextBlock("Done."))
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"--model", "mock", "--workspace", workspace, "where are you?"}, config.NewFullConfig(), strings.NewReader(""), &stdout, &stderr, mockClientFactory(mock))
+	if code != 0 {
+		t.Fatalf("exit code = %d; want 0 (stderr: %s)", code, stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "where.txt"))
+	if err != nil {
+		t.Fatalf("where.txt not written in the workspace: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(workspace)
+	if got, _ := filepath.EvalSymlinks(strings.TrimSpace(string(data))); got != want {
+		t.Errorf("command ran in %q; want %q", got, want)
+	}
+}
+
+func TestRunCommandReadsInstructionFromStdin(t *testing.T) {
+	mock := llm.NewMockClient()
+	mock.EnqueueBlocks(&llm.ContentBlock{Type: llm.ContentTypeText, Text: "done"})
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"--workspace", t.TempDir()}, config.NewFullConfig(), strings.NewReader("summarize the repo\n"), &stdout, &stderr, mockClientFactory(mock))
+	if code != 0 {
+		t.Fatalf("exit code = %d; want 0 (stderr: %s)", code, stderr.String())
+	}
+	if got := mock.Calls()[0].Messages[0].Content[0].Text; got != "summarize the repo" {
+		t.Errorf("prompt = %q; want the stdin instruction", got)
+	}
+}
+
+func TestRunCommandFailures(t *testing.T) {
+	failing := llm.NewMockClient()
+	failing.EnqueueError(errors.New("rate limited"))
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		client  *llm.MockClient
+		want    int
+		wantErr string
+	}{
+		{"no instruction", nil, "", llm.NewMockClient(), 2, "Usage: water run"},
+		{"llm error", []string{"do it"}, "", failing, 1, "rate limited"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"--workspace", t.TempDir()}, tt.args...)
+		if code := runCommand(args, config.NewFullConfig(), strings.NewReader(tt.stdin), &stdout, &stderr, mockClientFactory(tt.client)); code != tt.want {
+			t.Errorf("%s: exit code = %d; want %d", tt.name, code, tt.want)
+		}
+		if !strings.Contains(stderr.String(), tt.wantErr) {
+			t.Errorf("%s: stderr = %q; want it to mention %q", tt.name, stderr.String(), tt.wantErr)
+		}
+	}
+}
+
+func TestRunCommandUsesConfigFileKey(t *testing.T) {
+	t.Setenv("LLM_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.URL.Path != "/chat/completions" || auth != "Bearer file-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "Hello from the config."}}]}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "water.json")
+	file := fmt.Sprintf(`{"llm": {"model": "my-proxy-model", "api_type": "openai", "api_key": "file-key", "base_url": %q}}`, srv.URL)
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfigWith(path, config.FileOverEnv)
+	if err != nil {
+		t.Fatalf("LoadConfigWith() error = %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"--workspace", t.TempDir(), "say hello"}, cfg, strings.NewReader(""), &stdout, &stderr, newAgentClient)
+	if code != 0 {
+		t.Fatalf("exit code = %d; want 0 (stderr: %s)", code, stderr.String())
+	}
+	if auth != "Bearer file-key" {
+		t.Errorf("Authorization = %q; want the key from the config file", auth)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "Hello from the config." {
+		t.Errorf("stdout = %q; want the answer from the configured endpoint", got)
+	}
+}
+
+func TestRunCommandReviewsResult(t *testing.T) {
+	returnControl := &llm.ContentBlock{Type: llm.ContentTypeToolCall, ToolCallID: "review-1", ToolName: agents.ReturnControlToolName, ToolInput: map[string]interface{}{}}
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.TextBlock("first try")).
+		EnqueueBlocks(returnControl).
+		EnqueueBlocks(llm.TextBlock("The page is blank.\n" + agents.ReviewVerdictFail)).
+		EnqueueBlocks(llm.TextBlock("fixed")).
+		EnqueueBlocks(returnControl).
+		EnqueueBlocks(llm.TextBlock("Works.\n" + agents.ReviewVerdictPass))
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"--workspace", t.TempDir(), "--review-rounds", "2", "build a page"}, config.NewFullConfig(), strings.NewReader(""), &stdout, &stderr, mockClientFactory(mock))
+	if code != 0 {
+		t.Fatalf("exit code = %d; want 0 (stderr: %s)", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "fixed" {
+		t.Errorf("stdout = %q; want the result after the review feedback", got)
+	}
+	if !strings.Contains(stderr.String(), "Review round 2 passed.") {
+		t.Errorf("stderr = %q; want the review rounds reported", stderr.String())
+	}
+}
+
+func TestRunCommandFailsWhenReviewKeepsFailing(t *testing.T) {
+	returnControl := &llm.ContentBlock{Type: llm.ContentTypeToolCall, ToolCallID: "review-1", ToolName: agents.ReturnControlToolName, ToolInput: map[string]interface{}{}}
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.TextBlock("first try")).
+		EnqueueBlocks(returnControl).
+		EnqueueBlocks(llm.TextBlock("The page is blank.\n" + agents.ReviewVerdictFail))
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"--workspace", t.TempDir(), "--review-rounds", "1", "build a page"}, config.NewFullConfig(), strings.NewReader(""), &stdout, &stderr, mockClientFactory(mock))
+	if code != 1 {
+		t.Fatalf("exit code = %d; want 1 (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "The page is blank.") {
+		t.Errorf("stderr = %q; want the failing review", stderr.String())
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"water-ai/core/config"
+	"water-ai/server"
+)
+
+// startupFlags are the flags accepted before the subcommand, e.g.
+// `water --config water.yaml server`.
+type startupFlags struct {
+	configPath    string
+	envPath       string
+	port          string
+	workspaceRoot string
+	version       bool
+}
+
+// parseStartupFlags parses the global flags in args and returns them with
+// the remaining arguments, starting at the subcommand.
+func parseStartupFlags(args []string, stderr io.Writer) (startupFlags, []string, error) {
+	var f startupFlags
+	fs := flag.NewFlagSet("water", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: water [flags] [server | run [flags] [instruction]]")
+		fmt.Fprintln(stderr, "\nWithout a subcommand, starts the GUI and the gateway.")
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&f.configPath, "config", "", "JSON or YAML config file; its values override the environment")
+	fs.StringVar(&f.envPath, "env", "", "dotenv file loaded into the environment before the config file")
+	fs.StringVar(&f.port, "port", "", "gateway port for `water server`, overriding the config file")
+	fs.StringVar(&f.workspaceRoot, "workspace-root", "", "session workspace directory, overriding the config file")
+	fs.BoolVar(&f.version, "version", false, "print the version and exit")
+	fs.BoolVar(&f.version, "v", false, "shorthand for --version")
+	if err := fs.Parse(args); err != nil {
+		return f, nil, err
+	}
+	return f, fs.Args(), nil
+}
+
+// loadStartupConfig builds the configuration from, lowest precedence first,
+// the process environment, the --env file and the --config file. Flags are
+// applied on top by serverConfig.
+func loadStartupConfig(f startupFlags) (*config.FullConfig, error) {
+	if f.envPath != "" {
+		if err := config.LoadEnvFile(f.envPath); err != nil {
+			return nil, err
+		}
+	}
+	if f.configPath != "" {
+		return config.LoadConfigWith(f.configPath, config.FileOverEnv)
+	}
+	return config.LoadEnvConfig()
+}
+
+// serverConfig returns the gateway configuration from cfg with the flags
+// applied, listening on defaultPort unless configured otherwise.
+func serverConfig(cfg *config.FullConfig, f startupFlags, defaultPort string) server.Config {
+	sc := server.Config{
+		Port:          cfg.Server.Port,
+		WorkspaceRoot: cfg.Server.WorkspaceRoot,
+		EnableMetrics: cfg.Server.EnableMetrics,
+		Audio:         cfg.Audio,
+		MinimizeLogs:  cfg.Agent.MinimizeStdoutLogs,
+		LLM:           cfg.LLM,
+		Sandbox:       cfg.Sandbox,
+		ThirdParty:    cfg.ThirdParty,
+		Agent:         &cfg.Agent,
+	}
+	if cfg.Server.APIKey != nil {
+		sc.APIKey = cfg.Server.APIKey.Reveal()
+	}
+	if f.port != "" {
+		sc.Port = f.port
+	}
+	if f.workspaceRoot != "" {
+		sc.WorkspaceRoot = f.workspaceRoot
+	}
+	if sc.Port == "" {
+		sc.Port = defaultPort
+	}
+	return sc
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStartupConfigFromFile(t *testing.T) {
+	t.Setenv("GATEWAY_PORT", "9000")
+	t.Setenv("WORKSPACE_ROOT", "")
+	t.Setenv("WATER_API_KEY", "")
+	path := writeFile(t, "water.json", `{"server": {"port": "8181", "workspace_root": "/srv/sessions", "api_key": "file-key"}}`)
+
+	tests := []struct {
+		name          string
+		args          []string
+		wantPort      string
+		wantWorkspace string
+	}{
+		{"file overrides env", []string{"--config", path, "server"}, "8181", "/srv/sessions"},
+		{"flags override file", []string{"--config", path, "--port", "8282", "--workspace-root", "/tmp/ws", "server"}, "8282", "/tmp/ws"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, rest, err := parseStartupFlags(tt.args, io.Discard)
+			if err != nil {
+				t.Fatalf("parseStartupFlags() error = %v", err)
+			}
+			if len(rest) != 1 || rest[0] != "server" {
+				t.Errorf("remaining args = %q; want [server]", rest)
+			}
+			cfg, err := loadStartupConfig(flags)
+			if err != nil {
+				t.Fatalf("loadStartupConfig() error = %v", err)
+			}
+			sc := serverConfig(cfg, flags, serverPort)
+			if sc.Port != tt.wantPort || sc.WorkspaceRoot != tt.wantWorkspace {
+				t.Errorf("server.Config = %+v; want port %s, workspace %s", sc, tt.wantPort, tt.wantWorkspace)
+			}
+			if sc.APIKey != "file-key" {
+				t.Errorf("APIKey = %q; want file-key", sc.APIKey)
+			}
+		})
+	}
+}
+
+func TestStartupConfigFromEnvFile(t *testing.T) {
+	t.Setenv("GATEWAY_PORT", "")
+	t.Setenv("WATER_METRICS", "")
+	envPath := writeFile(t, ".env", "GATEWAY_PORT=8383\nWATER_METRICS=true\n")
+
+	flags, _, err := parseStartupFlags([]string{"--env", envPath}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseStartupFlags() error = %v", err)
+	}
+	cfg, err := loadStartupConfig(flags)
+	if err != nil {
+		t.Fatalf("loadStartupConfig() error = %v", err)
+	}
+	if sc := serverConfig(cfg, flags, serverPort); sc.Port != "8383" || !sc.EnableMetrics {
+		t.Errorf("server.Config = %+v; want port 8383 with metrics", sc)
+	}
+}
+
+func TestServerConfigDefaultPort(t *testing.T) {
+	t.Setenv("GATEWAY_PORT", "")
+	cfg, err := loadStartupConfig(startupFlags{})
+	if err != nil {
+		t.Fatalf("loadStartupConfig() error = %v", err)
+	}
+	sc := serverConfig(cfg, startupFlags{}, serverPort)
+	if sc.Port != serverPort {
+		t.Errorf("Port = %q; want %q", sc.Port, serverPort)
+	}
+	if sc.Agent == nil || sc.Agent.TokenBudget != cfg.Agent.TokenBudget || sc.Agent.TurnTimeoutSeconds != cfg.Agent.TurnTimeoutSeconds {
+		t.Errorf("Agent = %+v; want the agent limits of the config", sc.Agent)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // =============================================================================
@@ -16,6 +17,7 @@ const (
 	MaxOutputTokensPerTurn = 32000
 	MaxTurns               = 200
 	TokenBudget            = 0 // Assuming 0 as default from original import placeholder
+	TurnTimeoutSeconds     = 600
 	DefaultModel           = "gpt-4-turbo"
 )
 
@@ -27,6 +29,14 @@ const (
 	WorkSpaceModeE2B    WorkSpaceMode = "e2b"
 )
 
+// LogFormat selects how core.Logger writes records.
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
+)
+
 type APIType string
 
 const (
@@ -51,8 +61,10 @@ func (s SecretString) String() string {
 	return "********"
 }
 
-// Reveal returns the actual value.
+// Reveal returns the actual value. Calls are counted and can be audited
+// with AuditReveals.
 func (s SecretString) Reveal() string {
+	recordReveal()
 	return string(s)
 }
 
@@ -69,6 +81,11 @@ type AudioConfig struct {
 	OpenAIAPIKey    *SecretString `json:"openai_api_key,omitempty"`
 	AzureEndpoint   *string       `json:"azure_endpoint,omitempty"`
 	AzureAPIVersion *string       `json:"azure_api_version,omitempty"`
+	// TTSEnabled speaks the agent's final responses; TTSVoice and TTSSpeed
+	// default to the provider's when empty or zero.
+	TTSEnabled bool    `json:"tts_enabled"`
+	TTSVoice   string  `json:"tts_voice,omitempty"`
+	TTSSpeed   float64 `json:"tts_speed,omitempty"`
 }
 
 func (c *AudioConfig) Update(settings AudioConfig) {
@@ -81,6 +98,15 @@ func (c *AudioConfig) Update(settings AudioConfig) {
 	if settings.AzureAPIVersion != nil && c.AzureAPIVersion == nil {
 		c.AzureAPIVersion = settings.AzureAPIVersion
 	}
+	if settings.TTSEnabled {
+		c.TTSEnabled = true
+	}
+	if settings.TTSVoice != "" && c.TTSVoice == "" {
+		c.TTSVoice = settings.TTSVoice
+	}
+	if settings.TTSSpeed != 0 && c.TTSSpeed == 0 {
+		c.TTSSpeed = settings.TTSSpeed
+	}
 }
 
 // =============================================================================
@@ -91,6 +117,7 @@ type ThirdPartyIntegrationConfig struct {
 	NeonDBAPIKey *SecretString `json:"neon_db_api_key,omitempty"`
 	OpenAIAPIKey *SecretString `json:"openai_api_key,omitempty"`
 	VercelAPIKey *SecretString `json:"vercel_api_key,omitempty"`
+	GitHubToken  *SecretString `json:"github_token,omitempty"`
 }
 
 func (c *ThirdPartyIntegrationConfig) Update(settings ThirdPartyIntegrationConfig) {
@@ -103,6 +130,9 @@ func (c *ThirdPartyIntegrationConfig) Update(settings ThirdPartyIntegrationConfi
 	if settings.VercelAPIKey != nil && c.VercelAPIKey == nil {
 		c.VercelAPIKey = settings.VercelAPIKey
 	}
+	if settings.GitHubToken != nil && c.GitHubToken == nil {
+		c.GitHubToken = settings.GitHubToken
+	}
 }
 
 // =============================================================================
@@ -141,9 +171,15 @@ type WaterAgentConfig struct {
 	HostWorkspacePath      string        `json:"host_workspace_path"`
 	UseContainerWorkspace  WorkSpaceMode `json:"use_container_workspace"`
 	MinimizeStdoutLogs     bool          `json:"minimize_stdout_logs"`
+	// LogFormat is "text" for human-readable logs or "json" for one JSON
+	// object per line.
+	LogFormat              LogFormat     `json:"log_format"`
 	MaxOutputTokensPerTurn int           `json:"max_output_tokens_per_turn"`
 	MaxTurns               int           `json:"max_turns"`
 	TokenBudget            int           `json:"token_budget"`
+	// TurnTimeoutSeconds bounds one model call plus tool execution; 0
+	// means no bound.
+	TurnTimeoutSeconds     int           `json:"turn_timeout_seconds"`
 	DatabaseURL            *string       `json:"database_url,omitempty"`
 }
 
@@ -156,9 +192,11 @@ func NewWaterAgentConfig() (*WaterAgentConfig, error) {
 		HostWorkspacePath:      getEnv("HOST_WORKSPACE_PATH", "~/.water_agent/workspace"),
 		UseContainerWorkspace:  WorkSpaceMode(getEnv("USE_CONTAINER_WORKSPACE", string(WorkSpaceModeDocker))),
 		MinimizeStdoutLogs:     getEnvBool("MINIMIZE_STDOUT_LOGS", false),
+		LogFormat:              LogFormat(getEnv("LOG_FORMAT", string(LogFormatText))),
 		MaxOutputTokensPerTurn: getEnvInt("MAX_OUTPUT_TOKENS_PER_TURN", MaxOutputTokensPerTurn),
 		MaxTurns:               getEnvInt("MAX_TURNS", MaxTurns),
 		TokenBudget:            getEnvInt("TOKEN_BUDGET", TokenBudget),
+		TurnTimeoutSeconds:     getEnvInt("TURN_TIMEOUT_SECONDS", TurnTimeoutSeconds),
 	}
 
 	// Expand paths
@@ -191,6 +229,11 @@ func (c *WaterAgentConfig) HostWorkspace() (string, error) {
 	return expandPath(c.HostWorkspacePath)
 }
 
+// TurnTimeout returns TurnTimeoutSeconds as a duration.
+func (c *WaterAgentConfig) TurnTimeout() time.Duration {
+	return time.Duration(c.TurnTimeoutSeconds) * time.Second
+}
+
 func (c *WaterAgentConfig) LogsPath() string {
 	return filepath.Join(c.FileStorePath, "logs")
 }
@@ -266,6 +309,11 @@ type SandboxConfig struct {
 	TemplateID    *string       `json:"template_id,omitempty"`
 	SandboxAPIKey *SecretString `json:"sandbox_api_key,omitempty"`
 	ServicePort   int           `json:"service_port"`
+	// Image, MemoryLimitMB and CPULimit configure docker sandboxes; zero
+	// values use the default image and no limits.
+	Image         string  `json:"image,omitempty"`
+	MemoryLimitMB int     `json:"memory_limit_mb,omitempty"`
+	CPULimit      float64 `json:"cpu_limit,omitempty"`
 }
 
 func NewSandboxConfig() SandboxConfig {
@@ -290,6 +338,15 @@ func (c *SandboxConfig) Update(settings SandboxConfig) {
 	if settings.TemplateID != nil && c.TemplateID == nil {
 		c.TemplateID = settings.TemplateID
 	}
+	if settings.Image != "" {
+		c.Image = settings.Image
+	}
+	if settings.MemoryLimitMB != 0 {
+		c.MemoryLimitMB = settings.MemoryLimitMB
+	}
+	if settings.CPULimit != 0 {
+		c.CPULimit = settings.CPULimit
+	}
 }
 
 // =============================================================================
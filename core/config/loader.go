@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// =============================================================================
+// Config File
+// =============================================================================
+
+// FullConfig is the complete configuration read from a config file by
+// LoadConfig. Each section uses the same keys as its JSON form.
+type FullConfig struct {
+	Server     ServerConfig                `json:"server"`
+	Agent      WaterAgentConfig            `json:"agent"`
+	LLM        LLMConfig                   `json:"llm"`
+	Sandbox    SandboxConfig               `json:"sandbox"`
+	Search     SearchConfig                `json:"search"`
+	Media      MediaConfig                 `json:"media"`
+	Audio      AudioConfig                 `json:"audio"`
+	ThirdParty ThirdPartyIntegrationConfig `json:"third_party"`
+	Client     ClientConfig                `json:"client"`
+}
+
+// ServerConfig configures the gateway. The environment variables are
+// GATEWAY_PORT, WORKSPACE_ROOT, WATER_API_KEY and WATER_METRICS.
+type ServerConfig struct {
+	Port          string        `json:"port,omitempty"`
+	WorkspaceRoot string        `json:"workspace_root,omitempty"`
+	APIKey        *SecretString `json:"api_key,omitempty"`
+	EnableMetrics bool          `json:"enable_metrics,omitempty"`
+}
+
+// NewFullConfig returns the configuration used when a file sets nothing.
+func NewFullConfig() *FullConfig {
+	return &FullConfig{
+		Agent: WaterAgentConfig{
+			FileStore:              "local",
+			FileStorePath:          "~/.water_agent",
+			HostWorkspacePath:      "~/.water_agent/workspace",
+			UseContainerWorkspace:  WorkSpaceModeDocker,
+			MaxOutputTokensPerTurn: MaxOutputTokensPerTurn,
+			MaxTurns:               MaxTurns,
+			TokenBudget:            TokenBudget,
+			TurnTimeoutSeconds:     TurnTimeoutSeconds,
+			LogFormat:              LogFormatText,
+		},
+		LLM:     NewLLMConfig(),
+		Sandbox: NewSandboxConfig(),
+		Client:  NewClientConfig(),
+	}
+}
+
+// Precedence says which source wins when both the config file and the
+// environment set a value.
+type Precedence int
+
+const (
+	// EnvOverFile lets environment variables override the file.
+	EnvOverFile Precedence = iota
+	// FileOverEnv lets the file override environment variables.
+	FileOverEnv
+)
+
+// LoadConfig reads a JSON (.json) or YAML (.yaml, .yml) config file over
+// the defaults of NewFullConfig, then applies environment variable
+// overrides, so the environment wins over the file. Unknown keys in the
+// file and settings that fail Validate are errors. Secrets are written as
+// plain strings.
+func LoadConfig(path string) (*FullConfig, error) {
+	return LoadConfigWith(path, EnvOverFile)
+}
+
+// LoadConfigWith is LoadConfig with a choice of which of the file and the
+// environment wins.
+func LoadConfigWith(path string, precedence Precedence) (*FullConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file %s: use .json, .yaml or .yml", path)
+	}
+
+	cfg := NewFullConfig()
+	// Decoding only sets the keys present in the file, so whichever source
+	// is applied last wins.
+	if precedence == FileOverEnv {
+		if err := cfg.applyEnv(); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if precedence == EnvOverFile {
+		if err := cfg.applyEnv(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Agent.resolvePaths(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s:\n%w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadEnvConfig returns the defaults of NewFullConfig overridden by the
+// environment, as LoadConfig would with an empty file.
+func LoadEnvConfig() (*FullConfig, error) {
+	cfg := NewFullConfig()
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Agent.resolvePaths(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// yamlToJSON converts a YAML document to JSON so that one set of struct
+// tags serves both formats. An empty document becomes an empty object.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(doc)
+}
+
+// resolvePaths expands FileStorePath and derives DatabaseURL from it when
+// unset, as NewWaterAgentConfig does.
+func (c *WaterAgentConfig) resolvePaths() error {
+	var err error
+	if c.FileStorePath, err = expandPath(c.FileStorePath); err != nil {
+		return err
+	}
+	if c.DatabaseURL == nil || *c.DatabaseURL == "" {
+		generatedURL := "sqlite:///" + filepath.Join(c.FileStorePath, "water_agent.db")
+		c.DatabaseURL = &generatedURL
+	}
+	return nil
+}
+
+// =============================================================================
+// Env Files
+// =============================================================================
+
+// LoadEnvFile sets the variables of a dotenv file in the process
+// environment, overriding any already set. Each line is KEY=VALUE,
+// optionally prefixed with "export"; blank lines and lines starting with #
+// are skipped, and single or double quotes around a value are removed.
+func LoadEnvFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("invalid env file %s: line %d: want KEY=VALUE", path, i+1)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("invalid env file %s: line %d: %w", path, i+1, err)
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Environment Overrides
+// =============================================================================
+
+// applyEnv overrides config values with any environment variables that are
+// set. Malformed numbers and booleans are errors rather than being ignored.
+func (c *FullConfig) applyEnv() error {
+	envString("GATEWAY_PORT", &c.Server.Port)
+	envString("WORKSPACE_ROOT", &c.Server.WorkspaceRoot)
+	envSecretPtr("WATER_API_KEY", &c.Server.APIKey)
+
+	envString("FILE_STORE", &c.Agent.FileStore)
+	envString("FILE_STORE_PATH", &c.Agent.FileStorePath)
+	envString("HOST_WORKSPACE_PATH", &c.Agent.HostWorkspacePath)
+	if v, ok := os.LookupEnv("USE_CONTAINER_WORKSPACE"); ok {
+		c.Agent.UseContainerWorkspace = WorkSpaceMode(v)
+	}
+	envStringPtr("DATABASE_URL", &c.Agent.DatabaseURL)
+	if v, ok := os.LookupEnv("LOG_FORMAT"); ok {
+		c.Agent.LogFormat = LogFormat(v)
+	}
+
+	envString("LLM_MODEL", &c.LLM.Model)
+	envSecretPtr("LLM_API_KEY", &c.LLM.APIKey)
+	envStringPtr("LLM_BASE_URL", &c.LLM.BaseURL)
+	if v, ok := os.LookupEnv("LLM_API_TYPE"); ok {
+		c.LLM.APIType = APIType(v)
+	}
+
+	if v, ok := os.LookupEnv("SANDBOX_MODE"); ok {
+		c.Sandbox.Mode = WorkSpaceMode(v)
+	}
+	envStringPtr("SANDBOX_TEMPLATE_ID", &c.Sandbox.TemplateID)
+	envSecretPtr("SANDBOX_API_KEY", &c.Sandbox.SandboxAPIKey)
+	envString("SANDBOX_IMAGE", &c.Sandbox.Image)
+
+	envSecretPtr("FIRECRAWL_API_KEY", &c.Search.FirecrawlAPIKey)
+	envSecretPtr("SERPAPI_API_KEY", &c.Search.SerpapiAPIKey)
+	envSecretPtr("TAVILY_API_KEY", &c.Search.TavilyAPIKey)
+	envSecretPtr("JINA_API_KEY", &c.Search.JinaAPIKey)
+
+	envStringPtr("GCP_PROJECT_ID", &c.Media.GCPProjectID)
+	envStringPtr("GCP_LOCATION", &c.Media.GCPLocation)
+	envStringPtr("GCS_OUTPUT_BUCKET", &c.Media.GCSOutputBucket)
+	envSecretPtr("GOOGLE_AI_STUDIO_API_KEY", &c.Media.GoogleAIStudioAPIKey)
+
+	envSecretPtr("OPENAI_API_KEY", &c.Audio.OpenAIAPIKey)
+	envStringPtr("AZURE_ENDPOINT", &c.Audio.AzureEndpoint)
+	envStringPtr("AZURE_API_VERSION", &c.Audio.AzureAPIVersion)
+	envString("TTS_VOICE", &c.Audio.TTSVoice)
+
+	envSecretPtr("NEON_DB_API_KEY", &c.ThirdParty.NeonDBAPIKey)
+	envSecretPtr("OPENAI_API_KEY", &c.ThirdParty.OpenAIAPIKey)
+	envSecretPtr("VERCEL_API_KEY", &c.ThirdParty.VercelAPIKey)
+	envSecretPtr("GITHUB_TOKEN", &c.ThirdParty.GitHubToken)
+
+	for _, o := range []struct {
+		key   string
+		value *int
+	}{
+		{"MAX_OUTPUT_TOKENS_PER_TURN", &c.Agent.MaxOutputTokensPerTurn},
+		{"MAX_TURNS", &c.Agent.MaxTurns},
+		{"TOKEN_BUDGET", &c.Agent.TokenBudget},
+		{"TURN_TIMEOUT_SECONDS", &c.Agent.TurnTimeoutSeconds},
+		{"LLM_MAX_RETRIES", &c.LLM.MaxRetries},
+		{"SANDBOX_SERVICE_PORT", &c.Sandbox.ServicePort},
+		{"SANDBOX_MEMORY_LIMIT_MB", &c.Sandbox.MemoryLimitMB},
+	} {
+		if err := envInt(o.key, o.value); err != nil {
+			return err
+		}
+	}
+	if err := envFloat("LLM_TEMPERATURE", &c.LLM.Temperature); err != nil {
+		return err
+	}
+	if err := envFloat("SANDBOX_CPU_LIMIT", &c.Sandbox.CPULimit); err != nil {
+		return err
+	}
+	if err := envFloat("TTS_SPEED", &c.Audio.TTSSpeed); err != nil {
+		return err
+	}
+	if err := envBool("TTS_ENABLED", &c.Audio.TTSEnabled); err != nil {
+		return err
+	}
+	if err := envBool("WATER_METRICS", &c.Server.EnableMetrics); err != nil {
+		return err
+	}
+	return envBool("MINIMIZE_STDOUT_LOGS", &c.Agent.MinimizeStdoutLogs)
+}
+
+func envString(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+func envStringPtr(key string, dst **string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = StringPtr(v)
+	}
+}
+
+func envSecretPtr(key string, dst **SecretString) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = SecretPtr(v)
+	}
+}
+
+func envInt(key string, dst *int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s=%q: want an integer", key, v)
+	}
+	*dst = i
+	return nil
+}
+
+func envFloat(key string, dst *float64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s=%q: want a number", key, v)
+	}
+	*dst = f
+	return nil
+}
+
+func envBool(key string, dst *bool) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s=%q: want true or false", key, v)
+	}
+	*dst = b
+	return nil
+}
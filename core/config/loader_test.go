@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const sampleYAML = `
+agent:
+  file_store_path: /srv/water
+  max_turns: 50
+llm:
+  model: claude-3-5-sonnet
+  api_key: sk-file
+  temperature: 0.5
+sandbox:
+  mode: e2b
+  sandbox_api_key: e2b-secret
+  image: water/sandbox:1
+  memory_limit_mb: 2048
+  cpu_limit: 1.5
+search:
+  tavily_api_key: tvly-file
+media:
+  gcp_project_id: my-project
+`
+
+const sampleJSON = `{
+  "agent": {"file_store_path": "/srv/water", "max_turns": 50},
+  "llm": {"model": "claude-3-5-sonnet", "api_key": "sk-file", "temperature": 0.5},
+  "sandbox": {"mode": "e2b", "sandbox_api_key": "e2b-secret", "image": "water/sandbox:1", "memory_limit_mb": 2048, "cpu_limit": 1.5},
+  "search": {"tavily_api_key": "tvly-file"},
+  "media": {"gcp_project_id": "my-project"}
+}`
+
+func TestLoadConfig(t *testing.T) {
+	for _, tt := range []struct{ name, content string }{
+		{"water.yaml", sampleYAML},
+		{"water.json", sampleJSON},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfigFile(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Agent.FileStorePath != "/srv/water" || cfg.Agent.MaxTurns != 50 {
+				t.Errorf("Agent = %+v", cfg.Agent)
+			}
+			if cfg.LLM.Model != "claude-3-5-sonnet" || cfg.LLM.Temperature != 0.5 {
+				t.Errorf("LLM = %+v", cfg.LLM)
+			}
+			if cfg.LLM.APIKey == nil || cfg.LLM.APIKey.Reveal() != "sk-file" {
+				t.Errorf("LLM.APIKey = %v; want sk-file", cfg.LLM.APIKey)
+			}
+			if cfg.Sandbox.Mode != WorkSpaceModeE2B || cfg.Sandbox.SandboxAPIKey.Reveal() != "e2b-secret" ||
+				cfg.Sandbox.Image != "water/sandbox:1" || cfg.Sandbox.MemoryLimitMB != 2048 || cfg.Sandbox.CPULimit != 1.5 {
+				t.Errorf("Sandbox = %+v", cfg.Sandbox)
+			}
+			if cfg.Search.TavilyAPIKey.Reveal() != "tvly-file" || *cfg.Media.GCPProjectID != "my-project" {
+				t.Errorf("Search = %+v, Media = %+v", cfg.Search, cfg.Media)
+			}
+
+			// Unset values keep their defaults.
+			if cfg.LLM.MaxRetries != 3 || cfg.Sandbox.ServicePort != 17300 || cfg.Client.DefaultShell != "/bin/bash" {
+				t.Errorf("defaults lost: LLM = %+v, Sandbox = %+v, Client = %+v", cfg.LLM, cfg.Sandbox, cfg.Client)
+			}
+			if want := "sqlite:///" + filepath.Join("/srv/water", "water_agent.db"); *cfg.Agent.DatabaseURL != want {
+				t.Errorf("DatabaseURL = %s; want %s", *cfg.Agent.DatabaseURL, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	t.Setenv("MAX_TURNS", "7")
+	t.Setenv("LLM_API_KEY", "sk-env")
+	t.Setenv("TAVILY_API_KEY", "tvly-env")
+	t.Setenv("SANDBOX_MODE", "local")
+
+	cfg, err := LoadConfig(writeConfigFile(t, "water.yaml", sampleYAML))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Agent.MaxTurns != 7 {
+		t.Errorf("MaxTurns = %d; want 7 from the environment", cfg.Agent.MaxTurns)
+	}
+	if cfg.LLM.APIKey.Reveal() != "sk-env" || cfg.Search.TavilyAPIKey.Reveal() != "tvly-env" {
+		t.Errorf("secrets = %q, %q; want the environment values", cfg.LLM.APIKey.Reveal(), cfg.Search.TavilyAPIKey.Reveal())
+	}
+	if cfg.Sandbox.Mode != WorkSpaceModeLocal {
+		t.Errorf("Sandbox.Mode = %s; want local", cfg.Sandbox.Mode)
+	}
+	if cfg.LLM.Model != "claude-3-5-sonnet" {
+		t.Errorf("Model = %s; want the file value", cfg.LLM.Model)
+	}
+}
+
+func TestLoadConfigFileOverEnv(t *testing.T) {
+	t.Setenv("MAX_TURNS", "7")
+	t.Setenv("LLM_TEMPERATURE", "0.9")
+
+	cfg, err := LoadConfigWith(writeConfigFile(t, "water.yaml", sampleYAML), FileOverEnv)
+	if err != nil {
+		t.Fatalf("LoadConfigWith() error = %v", err)
+	}
+	if cfg.Agent.MaxTurns != 50 {
+		t.Errorf("MaxTurns = %d; want 50 from the file", cfg.Agent.MaxTurns)
+	}
+	if cfg.LLM.Temperature != 0.5 {
+		t.Errorf("Temperature = %v; want 0.5 from the file", cfg.LLM.Temperature)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	// Register the keys with t.Setenv so they are restored afterwards.
+	t.Setenv("WATER_TEST_PLAIN", "")
+	t.Setenv("WATER_TEST_QUOTED", "")
+	t.Setenv("WATER_TEST_EXPORTED", "from-process")
+
+	path := writeConfigFile(t, ".env", `# comment
+WATER_TEST_PLAIN=plain
+
+WATER_TEST_QUOTED="quoted value"
+export WATER_TEST_EXPORTED='single'
+`)
+	if err := LoadEnvFile(path); err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+	for key, want := range map[string]string{
+		"WATER_TEST_PLAIN":    "plain",
+		"WATER_TEST_QUOTED":   "quoted value",
+		"WATER_TEST_EXPORTED": "single",
+	} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q; want %q", key, got, want)
+		}
+	}
+
+	if err := LoadEnvFile(writeConfigFile(t, ".env", "NOT A PAIR\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("LoadEnvFile(malformed) error = %v; want a line 1 error", err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		want    string
+	}{
+		{"unknown section", "water.yaml", "agnet:\n  max_turns: 1\n", nil, `unknown field "agnet"`},
+		{"unknown key", "water.json", `{"llm": {"modle": "x"}}`, nil, `unknown field "modle"`},
+		{"wrong type", "water.yaml", "agent:\n  max_turns: many\n", nil, "max_turns"},
+		{"bad extension", "water.toml", "", nil, "unsupported config file"},
+		{"bad env", "water.json", `{}`, map[string]string{"MAX_TURNS": "lots"}, `invalid MAX_TURNS="lots"`},
+		{"negative memory limit", "water.json", `{"sandbox": {"memory_limit_mb": -1}}`, nil, "memory_limit_mb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := LoadConfig(writeConfigFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig() error = %v; want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadConfig() of a missing file should fail")
+	}
+}
+
+func TestLoadConfigEmptyYAML(t *testing.T) {
+	cfg, err := LoadConfig(writeConfigFile(t, "water.yml", ""))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.LLM.Model != DefaultModel {
+		t.Errorf("Model = %s; want the default", cfg.LLM.Model)
+	}
+}
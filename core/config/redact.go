@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// =============================================================================
+// Redaction
+// =============================================================================
+
+var secretStringType = reflect.TypeOf(SecretString(""))
+
+// RedactMap returns a copy of the struct or map v that is safe to log:
+// nested structs become maps keyed by their JSON names and every
+// SecretString or *SecretString is replaced by its redacted form. It
+// returns nil if v is not a struct or map (or a pointer to one).
+func RedactMap(v any) map[string]any {
+	m, _ := redactValue(reflect.ValueOf(v)).(map[string]any)
+	return m
+}
+
+func redactValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == secretStringType {
+		return SecretString(v.String()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem())
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			out[name] = redactValue(v.Field(i))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = redactValue(v.Index(i))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// jsonFieldName returns the key a struct field marshals under, and false
+// for fields excluded with `json:"-"`.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// =============================================================================
+// Reveal Auditing
+// =============================================================================
+
+var (
+	revealCount atomic.Int64
+	revealAudit atomic.Pointer[func(caller string)]
+)
+
+// RevealCount returns how many times SecretString.Reveal has been called.
+func RevealCount() int64 {
+	return revealCount.Load()
+}
+
+// AuditReveals makes every later SecretString.Reveal call report its
+// caller as "file:line" to fn. Pass nil to stop auditing.
+func AuditReveals(fn func(caller string)) {
+	if fn == nil {
+		revealAudit.Store(nil)
+		return
+	}
+	revealAudit.Store(&fn)
+}
+
+// recordReveal counts a Reveal call and reports it to the audit function.
+func recordReveal() {
+	revealCount.Add(1)
+	audit := revealAudit.Load()
+	if audit == nil {
+		return
+	}
+	// Skip recordReveal and Reveal to get Reveal's caller.
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	(*audit)(caller)
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactMap(t *testing.T) {
+	type nested struct {
+		Token  SecretString            `json:"token"`
+		Keys   []*SecretString         `json:"keys"`
+		ByName map[string]SearchConfig `json:"by_name"`
+	}
+	type request struct {
+		Name    string        `json:"name"`
+		Count   int           `json:"count,omitempty"`
+		Key     *SecretString `json:"api_key"`
+		NoKey   *SecretString `json:"no_key"`
+		Empty   SecretString  `json:"empty"`
+		Plain   string
+		Skipped string    `json:"-"`
+		LLM     LLMConfig `json:"llm"`
+		Nested  *nested   `json:"nested"`
+		private SecretString
+	}
+
+	in := request{
+		Name:    "demo",
+		Count:   3,
+		Key:     SecretPtr("sk-top"),
+		Plain:   "visible",
+		Skipped: "hidden",
+		LLM:     LLMConfig{Model: "gpt-4o", APIKey: SecretPtr("sk-llm")},
+		Nested: &nested{
+			Token:  "secret-token",
+			Keys:   []*SecretString{SecretPtr("secret-k1"), nil},
+			ByName: map[string]SearchConfig{"web": {TavilyAPIKey: SecretPtr("secret-tvly")}},
+		},
+		private: "sk-private",
+	}
+	got := RedactMap(&in)
+
+	if got["name"] != "demo" || got["count"] != 3 || got["Plain"] != "visible" {
+		t.Errorf("plain fields = %v, %v, %v; want them unchanged", got["name"], got["count"], got["Plain"])
+	}
+	if got["api_key"] != "********" || got["no_key"] != nil || got["empty"] != "" {
+		t.Errorf("top-level secrets = %v, %v, %q", got["api_key"], got["no_key"], got["empty"])
+	}
+	if _, ok := got["Skipped"]; ok {
+		t.Error(`fields tagged json:"-" should be omitted`)
+	}
+	if _, ok := got["private"]; ok {
+		t.Error("unexported fields should be omitted")
+	}
+
+	llm := got["llm"].(map[string]any)
+	if llm["model"] != "gpt-4o" || llm["api_key"] != "********" {
+		t.Errorf("llm = %v", llm)
+	}
+	n := got["nested"].(map[string]any)
+	if n["token"] != "********" {
+		t.Errorf("nested token = %v", n["token"])
+	}
+	if keys := n["keys"].([]any); keys[0] != "********" || keys[1] != nil {
+		t.Errorf("nested keys = %v", keys)
+	}
+	web := n["by_name"].(map[string]any)["web"].(map[string]any)
+	if web["tavily_api_key"] != "********" || web["jina_api_key"] != nil {
+		t.Errorf("map value = %v", web)
+	}
+
+	for _, secret := range []string{"sk-top", "sk-llm", "secret-token", "secret-k1", "secret-tvly", "sk-private"} {
+		if strings.Contains(fmt.Sprint(got), secret) {
+			t.Errorf("redacted map leaks %q: %v", secret, got)
+		}
+	}
+	if in.Key.Reveal() != "sk-top" {
+		t.Error("RedactMap() should not modify its argument")
+	}
+}
+
+func TestRedactMapNonStruct(t *testing.T) {
+	if got := RedactMap("sk-secret"); got != nil {
+		t.Errorf("RedactMap(string) = %v; want nil", got)
+	}
+	if got := RedactMap(nil); got != nil {
+		t.Errorf("RedactMap(nil) = %v; want nil", got)
+	}
+	got := RedactMap(map[string]any{"key": SecretString("s"), "n": 1})
+	if got["key"] != "********" || got["n"] != 1 {
+		t.Errorf("RedactMap(map) = %v", got)
+	}
+}
+
+func TestRevealAudit(t *testing.T) {
+	var callers []string
+	AuditReveals(func(caller string) { callers = append(callers, caller) })
+	defer AuditReveals(nil)
+
+	before := RevealCount()
+	secret := SecretString("s")
+	secret.Reveal()
+	secret.Reveal()
+
+	if got := RevealCount() - before; got != 2 {
+		t.Errorf("RevealCount() grew by %d; want 2", got)
+	}
+	if len(callers) != 2 || !strings.Contains(callers[0], "redact_test.go:") {
+		t.Errorf("audited callers = %v; want this test file", callers)
+	}
+
+	AuditReveals(nil)
+	secret.Reveal()
+	if len(callers) != 2 {
+		t.Error("Reveal() should not be audited after AuditReveals(nil)")
+	}
+}
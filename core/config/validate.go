@@ -0,0 +1,188 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// Validation
+// =============================================================================
+
+// validWorkSpaceModes, validAPITypes and validLogFormats list the accepted enum values in the
+// order they are suggested in error messages.
+var (
+	validWorkSpaceModes = []WorkSpaceMode{WorkSpaceModeDocker, WorkSpaceModeLocal, WorkSpaceModeE2B}
+	validAPITypes       = []APIType{APITypeOpenAI, APITypeAnthropic, APITypeGemini}
+	validLogFormats     = []LogFormat{LogFormatText, LogFormatJSON}
+)
+
+// problems collects validation failures, each prefixed with the key of the
+// offending field.
+type problems struct {
+	prefix string
+	errs   []error
+}
+
+func (p *problems) addf(key, format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf("%s%s: %s", p.prefix, key, fmt.Sprintf(format, args...)))
+}
+
+// err returns all problems joined into one error, or nil if there are none.
+func (p *problems) err() error {
+	return errors.Join(p.errs...)
+}
+
+func isWorkSpaceMode(mode WorkSpaceMode) bool {
+	for _, m := range validWorkSpaceModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+func isAPIType(apiType APIType) bool {
+	for _, a := range validAPITypes {
+		if apiType == a {
+			return true
+		}
+	}
+	return false
+}
+
+func isLogFormat(format LogFormat) bool {
+	for _, f := range validLogFormats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+func joinValues[T ~string](values []T) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+// Validate reports every invalid setting in c as one error.
+func (c *WaterAgentConfig) Validate() error {
+	p := &problems{}
+	c.validate(p)
+	return p.err()
+}
+
+func (c *WaterAgentConfig) validate(p *problems) {
+	if !isWorkSpaceMode(c.UseContainerWorkspace) {
+		p.addf("use_container_workspace", "%q is not a workspace mode; use one of %s (USE_CONTAINER_WORKSPACE)",
+			c.UseContainerWorkspace, joinValues(validWorkSpaceModes))
+	}
+	if c.MaxTurns <= 0 {
+		p.addf("max_turns", "must be positive, got %d (MAX_TURNS)", c.MaxTurns)
+	}
+	if c.MaxOutputTokensPerTurn <= 0 {
+		p.addf("max_output_tokens_per_turn", "must be positive, got %d (MAX_OUTPUT_TOKENS_PER_TURN)", c.MaxOutputTokensPerTurn)
+	}
+	if c.TokenBudget < 0 {
+		p.addf("token_budget", "must be 0 (unlimited) or positive, got %d (TOKEN_BUDGET)", c.TokenBudget)
+	}
+	if c.TurnTimeoutSeconds < 0 {
+		p.addf("turn_timeout_seconds", "must be 0 (unbounded) or positive, got %d (TURN_TIMEOUT_SECONDS)", c.TurnTimeoutSeconds)
+	}
+	if c.LogFormat != "" && !isLogFormat(c.LogFormat) {
+		p.addf("log_format", "%q is not a log format; use one of %s (LOG_FORMAT)", c.LogFormat, joinValues(validLogFormats))
+	}
+}
+
+// Validate reports every invalid setting in c as one error.
+func (c *LLMConfig) Validate() error {
+	p := &problems{}
+	c.validate(p)
+	return p.err()
+}
+
+func (c *LLMConfig) validate(p *problems) {
+	if strings.TrimSpace(c.Model) == "" {
+		p.addf("model", "is required")
+	}
+	if !isAPIType(c.APIType) {
+		p.addf("api_type", "%q is not supported; use one of %s", c.APIType, joinValues(validAPITypes))
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		p.addf("temperature", "must be between 0 and 2, got %g", c.Temperature)
+	}
+	if c.MaxRetries < 0 {
+		p.addf("max_retries", "must not be negative, got %d", c.MaxRetries)
+	}
+}
+
+// Validate reports every invalid setting in c as one error.
+func (c *SandboxConfig) Validate() error {
+	p := &problems{}
+	c.validate(p)
+	return p.err()
+}
+
+func (c *SandboxConfig) validate(p *problems) {
+	if !isWorkSpaceMode(c.Mode) {
+		p.addf("mode", "%q is not a workspace mode; use one of %s", c.Mode, joinValues(validWorkSpaceModes))
+	}
+	if c.Mode == WorkSpaceModeE2B && (c.SandboxAPIKey == nil || c.SandboxAPIKey.Reveal() == "") {
+		p.addf("sandbox_api_key", "is required when mode is e2b (SANDBOX_API_KEY)")
+	}
+	if c.ServicePort < 0 || c.ServicePort > 65535 {
+		p.addf("service_port", "must be a port number, got %d", c.ServicePort)
+	}
+	if c.MemoryLimitMB < 0 {
+		p.addf("memory_limit_mb", "must not be negative, got %d", c.MemoryLimitMB)
+	}
+	if c.CPULimit < 0 {
+		p.addf("cpu_limit", "must not be negative, got %g", c.CPULimit)
+	}
+}
+
+// Validate reports every invalid setting in c as one error.
+func (c *AudioConfig) Validate() error {
+	p := &problems{}
+	c.validate(p)
+	return p.err()
+}
+
+func (c *AudioConfig) validate(p *problems) {
+	if c.TTSSpeed != 0 && (c.TTSSpeed < 0.25 || c.TTSSpeed > 4) {
+		p.addf("tts_speed", "must be between 0.25 and 4, got %g (TTS_SPEED)", c.TTSSpeed)
+	}
+	if c.TTSEnabled && (c.OpenAIAPIKey == nil || c.OpenAIAPIKey.Reveal() == "") {
+		p.addf("tts_enabled", "requires openai_api_key (OPENAI_API_KEY)")
+	}
+}
+
+func (c *ServerConfig) validate(p *problems) {
+	if c.Port == "" {
+		return
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		p.addf("port", "must be a port number, got %q (GATEWAY_PORT)", c.Port)
+	}
+}
+
+// Validate reports every invalid setting across all sections of c as one
+// error, each problem prefixed with its section.
+func (c *FullConfig) Validate() error {
+	p := &problems{prefix: "server."}
+	c.Server.validate(p)
+	p.prefix = "agent."
+	c.Agent.validate(p)
+	p.prefix = "llm."
+	c.LLM.validate(p)
+	p.prefix = "sandbox."
+	c.Sandbox.validate(p)
+	p.prefix = "audio."
+	c.Audio.validate(p)
+	return p.err()
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validAgentConfig() WaterAgentConfig {
+	return NewFullConfig().Agent
+}
+
+func TestLLMConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*LLMConfig)
+		want   []string
+	}{
+		{"valid", func(c *LLMConfig) {}, nil},
+		{"empty model", func(c *LLMConfig) { c.Model = " " }, []string{"model: is required"}},
+		{"unknown api type", func(c *LLMConfig) { c.APIType = "cohere" }, []string{`api_type: "cohere" is not supported; use one of openai, anthropic, gemini`}},
+		{"temperature too high", func(c *LLMConfig) { c.Temperature = 2.5 }, []string{"temperature: must be between 0 and 2, got 2.5"}},
+		{"negative temperature", func(c *LLMConfig) { c.Temperature = -0.1 }, []string{"temperature: must be between 0 and 2, got -0.1"}},
+		{"negative retries", func(c *LLMConfig) { c.MaxRetries = -1 }, []string{"max_retries: must not be negative, got -1"}},
+		{
+			"several problems",
+			func(c *LLMConfig) { c.Model = ""; c.Temperature = 3; c.MaxRetries = -2 },
+			[]string{"model: is required", "temperature: must be between 0 and 2, got 3", "max_retries: must not be negative, got -2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewLLMConfig()
+			tt.modify(&cfg)
+			assertProblems(t, cfg.Validate(), tt.want)
+		})
+	}
+}
+
+func TestWaterAgentConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*WaterAgentConfig)
+		want   []string
+	}{
+		{"valid", func(c *WaterAgentConfig) {}, nil},
+		{
+			"bad workspace mode",
+			func(c *WaterAgentConfig) { c.UseContainerWorkspace = "kubernetes" },
+			[]string{`use_container_workspace: "kubernetes" is not a workspace mode; use one of docker, local, e2b (USE_CONTAINER_WORKSPACE)`},
+		},
+		{"zero max turns", func(c *WaterAgentConfig) { c.MaxTurns = 0 }, []string{"max_turns: must be positive, got 0 (MAX_TURNS)"}},
+		{"negative budget", func(c *WaterAgentConfig) { c.TokenBudget = -5 }, []string{"token_budget: must be 0 (unlimited) or positive, got -5 (TOKEN_BUDGET)"}},
+		{"negative turn timeout", func(c *WaterAgentConfig) { c.TurnTimeoutSeconds = -1 }, []string{"turn_timeout_seconds: must be 0 (unbounded) or positive, got -1 (TURN_TIMEOUT_SECONDS)"}},
+		{"json logs", func(c *WaterAgentConfig) { c.LogFormat = LogFormatJSON }, nil},
+		{"bad log format", func(c *WaterAgentConfig) { c.LogFormat = "xml" }, []string{`log_format: "xml" is not a log format; use one of text, json (LOG_FORMAT)`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validAgentConfig()
+			tt.modify(&cfg)
+			assertProblems(t, cfg.Validate(), tt.want)
+		})
+	}
+}
+
+func TestSandboxConfigValidate(t *testing.T) {
+	cfg := NewSandboxConfig()
+	cfg.Mode = WorkSpaceModeE2B
+	assertProblems(t, cfg.Validate(), []string{"sandbox_api_key: is required when mode is e2b (SANDBOX_API_KEY)"})
+
+	cfg.SandboxAPIKey = SecretPtr("e2b-key")
+	assertProblems(t, cfg.Validate(), nil)
+
+	cfg.Mode = "vm"
+	assertProblems(t, cfg.Validate(), []string{`mode: "vm" is not a workspace mode`})
+}
+
+func TestAudioConfigValidate(t *testing.T) {
+	cfg := AudioConfig{TTSEnabled: true, TTSSpeed: 5}
+	assertProblems(t, cfg.Validate(), []string{
+		"tts_speed: must be between 0.25 and 4, got 5 (TTS_SPEED)",
+		"tts_enabled: requires openai_api_key (OPENAI_API_KEY)",
+	})
+
+	cfg.OpenAIAPIKey = SecretPtr("sk-test")
+	cfg.TTSSpeed = 1.5
+	assertProblems(t, cfg.Validate(), nil)
+}
+
+func TestFullConfigValidatePrefixesSections(t *testing.T) {
+	cfg := NewFullConfig()
+	cfg.Agent.UseContainerWorkspace = "none"
+	cfg.LLM.Temperature = 9
+	cfg.Sandbox.Mode = WorkSpaceModeE2B
+
+	assertProblems(t, cfg.Validate(), []string{
+		`agent.use_container_workspace: "none"`,
+		"llm.temperature: must be between 0 and 2, got 9",
+		"sandbox.sandbox_api_key: is required when mode is e2b",
+	})
+}
+
+func TestLoadConfigValidates(t *testing.T) {
+	_, err := LoadConfig(writeConfigFile(t, "water.yaml", "llm:\n  api_type: cohere\nsandbox:\n  mode: e2b\n"))
+	assertProblems(t, err, []string{`llm.api_type: "cohere" is not supported`, "sandbox.sandbox_api_key: is required"})
+}
+
+// assertProblems checks that err holds exactly one line per wanted message,
+// each containing it, in order.
+func assertProblems(t *testing.T, err error, want []string) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Errorf("Validate() error = %v; want nil", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("Validate() error = nil; want %q", want)
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if strings.HasPrefix(lines[0], "invalid config file ") {
+		lines = lines[1:]
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Validate() error = %q; want %d problems", err, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("problem %d = %q; want it to contain %q", i, lines[i], w)
+		}
+	}
+}
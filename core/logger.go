@@ -1,14 +1,25 @@
 package core
 
 import (
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 )
 
+// Log formats accepted by LOG_FORMAT and SetLogFormat.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 // Logger is the global logger instance for water-ai.
 var Logger *slog.Logger
 
+// logLevel is Logger's level. It is shared by every logger derived from
+// Logger, so changing it takes effect everywhere.
+var logLevel = new(slog.LevelVar)
+
 func init() {
 	Initialize()
 }
@@ -37,17 +48,43 @@ func Initialize() {
 		level = slog.LevelInfo
 	}
 
-	// Configure the handler options
+	logLevel.Set(level)
+	setLogger(os.Stderr, os.Getenv("LOG_FORMAT"))
+}
+
+// SetLogFormat replaces Logger with one writing format, LogFormatText or
+// LogFormatJSON, to stderr. Loggers derived from the previous Logger keep
+// its format, so call it before creating components.
+func SetLogFormat(format string) {
+	setLogger(os.Stderr, format)
+}
+
+// setLogger points Logger and the slog default at w. JSON output is one
+// object per line with time, level, msg, service and any attributes such as
+// component; anything else falls back to human-readable text.
+func setLogger(w io.Writer, format string) {
 	opts := &slog.HandlerOptions{
-		Level: level,
+		Level: logLevel,
 	}
 
-	// Create a TextHandler
-	handler := slog.NewTextHandler(os.Stderr, opts)
+	var handler slog.Handler
+	if strings.EqualFold(format, LogFormatJSON) {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
 
 	// Initialize the logger with the service name "water_ai"
 	Logger = slog.New(handler).With("service", "water_ai")
 
 	// Set as the default global logger for the application
 	slog.SetDefault(Logger)
-}
\ No newline at end of file
+}
+
+// MinimizeLogs raises the log level to at least WARN, for deployments with
+// MinimizeStdoutLogs set.
+func MinimizeLogs() {
+	if logLevel.Level() < slog.LevelWarn {
+		logLevel.Set(slog.LevelWarn)
+	}
+}
@@ -1,7 +1,12 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -120,4 +125,73 @@ func TestLoggerLevelFromEnv(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestMinimizeLogs(t *testing.T) {
+	origEnv := os.Getenv("LOG_LEVEL")
+	defer func() {
+		os.Setenv("LOG_LEVEL", origEnv)
+		Initialize()
+	}()
+
+	tests := []struct {
+		env  string
+		want slog.Level
+	}{
+		{"DEBUG", slog.LevelWarn},
+		{"INFO", slog.LevelWarn},
+		{"ERROR", slog.LevelError},
+	}
+	for _, tt := range tests {
+		os.Setenv("LOG_LEVEL", tt.env)
+		Initialize()
+		derived := Logger.With("component", "test")
+		MinimizeLogs()
+		if got := logLevel.Level(); got != tt.want {
+			t.Errorf("LOG_LEVEL=%s: level after MinimizeLogs = %v; want %v", tt.env, got, tt.want)
+		}
+		if derived.Enabled(context.Background(), slog.LevelInfo) {
+			t.Errorf("LOG_LEVEL=%s: derived logger still logs INFO after MinimizeLogs", tt.env)
+		}
+	}
+}
+
+func TestLoggerJSONFormat(t *testing.T) {
+	defer Initialize()
+
+	var buf bytes.Buffer
+	setLogger(&buf, LogFormatJSON)
+	Logger.With("component", "process_manager").Warn("process started", "pid", 42, "port", 7777)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log line %q is not valid JSON: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"level":     "WARN",
+		"msg":       "process started",
+		"service":   "water_ai",
+		"component": "process_manager",
+		"pid":       float64(42),
+		"port":      float64(7777),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record[%q] = %v; want %v", key, record[key], value)
+		}
+	}
+	if _, ok := record["time"]; !ok {
+		t.Errorf("record has no time: %v", record)
+	}
+}
+
+func TestLoggerTextFormatDefault(t *testing.T) {
+	defer Initialize()
+
+	var buf bytes.Buffer
+	setLogger(&buf, "")
+	Logger.Warn("process started", "pid", 42)
+
+	if got := buf.String(); !strings.Contains(got, "level=WARN") || !strings.Contains(got, "pid=42") {
+		t.Errorf("text log line = %q; want level=WARN and pid=42", got)
+	}
+}
@@ -3,7 +3,11 @@ package db
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,24 +32,29 @@ const (
 // MODELS
 // ==========================================
 
-// Session represents an agent session.
+// Session represents an agent session. Archived sessions are soft-deleted:
+// DeletedAt is set and default queries skip them. Only active sessions must
+// have unique workspaces, so a workspace can be reused after an archive.
 type Session struct {
 	ID           string    `gorm:"primaryKey;type:text;length:36"`
-	WorkspaceDir string    `gorm:"uniqueIndex;not null"`
+	WorkspaceDir string    `gorm:"uniqueIndex:idx_sessions_active_workspace,where:deleted_at IS NULL;not null"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 	DeviceID     *string   `gorm:"index"`
 	Name         *string
 	SandboxID    *string
-	Events       []Event `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	Events       []Event        `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
 }
 
-// Event represents a realtime event.
+// Event represents a realtime event. Deleted events are soft-deleted and
+// kept as an audit trail.
 type Event struct {
 	ID           string          `gorm:"primaryKey;type:text;length:36"`
 	SessionID    string          `gorm:"index;not null;type:text;length:36"`
 	Timestamp    time.Time       `gorm:"index;autoCreateTime"`
 	EventType    string          `gorm:"not null"`
 	EventPayload json.RawMessage `gorm:"type:json;not null"`
+	DeletedAt    gorm.DeletedAt  `gorm:"index"`
 
 	// Associations
 	Session Session `gorm:"foreignKey:SessionID"`
@@ -71,10 +80,18 @@ func (e *Event) BeforeCreate(tx *gorm.DB) (err error) {
 // ==========================================
 
 // InitDB initializes the SQLite connection and runs auto-migrations.
-// Pass the database path (e.g., "water-ai/water_ai.db").
+// Pass the database path (e.g., "water-ai/water_ai.db") or a
+// "sqlite:///" URL as in the agent config. The database's directory is
+// created if missing.
 func InitDB(databaseUrl string) error {
 	var err error
-	
+	dsn := sqliteDSN(databaseUrl)
+	if dir := filepath.Dir(strings.SplitN(dsn, "?", 2)[0]); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
 	// Configure GORM
 	config := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Error),
@@ -82,7 +99,7 @@ func InitDB(databaseUrl string) error {
 
 	// Connect to SQLite
 	// check_same_thread=False is handled automatically by GORM's connection pooling
-	DB, err = gorm.Open(sqlite.Open(databaseUrl), config)
+	DB, err = gorm.Open(sqlite.Open(dsn), config)
 	if err != nil {
 		return err
 	}
@@ -94,21 +111,98 @@ func InitDB(databaseUrl string) error {
 		return err
 	}
 
+	// Databases created before soft-delete enforce workspace uniqueness
+	// across archived sessions too; the partial index above replaces it.
+	if DB.Migrator().HasIndex(&Session{}, legacyWorkspaceIndex) {
+		if err := DB.Migrator().DropIndex(&Session{}, legacyWorkspaceIndex); err != nil {
+			log.Printf("Error dropping %s: %v", legacyWorkspaceIndex, err)
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Close closes the database opened by InitDB, if any, and clears DB.
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	DB = nil
+	return sqlDB.Close()
+}
+
+// legacyWorkspaceIndex is the unique index on sessions.workspace_dir that
+// predates soft-delete.
+const legacyWorkspaceIndex = "idx_sessions_workspace_dir"
+
+// sqliteURLPrefix starts the SQLAlchemy-style database URLs of the agent
+// config; an absolute path follows it, e.g. "sqlite:////srv/water.db".
+const sqliteURLPrefix = "sqlite:///"
+
+// sqliteBusyTimeoutMS is how long a connection waits on a locked database
+// before failing with "database is locked".
+const sqliteBusyTimeoutMS = 5000
+
+// sqliteDSN adds the connection options that let concurrent sessions share
+// the database: WAL so readers do not block the writer, a busy timeout so
+// writers queue instead of failing, and immediate transactions so a
+// transaction takes the write lock up front rather than failing when it
+// upgrades from a read. Options already present in databaseUrl are kept,
+// and a "sqlite:///" URL is turned into its path.
+func sqliteDSN(databaseUrl string) string {
+	options := []struct{ key, value string }{
+		{"_journal_mode", "WAL"},
+		{"_busy_timeout", fmt.Sprint(sqliteBusyTimeoutMS)},
+		{"_txlock", "immediate"},
+	}
+	dsn := strings.TrimPrefix(databaseUrl, sqliteURLPrefix)
+	for _, opt := range options {
+		if strings.Contains(dsn, opt.key+"=") {
+			continue
+		}
+		sep := "&"
+		if !strings.Contains(dsn, "?") {
+			sep = "?"
+		}
+		dsn += sep + opt.key + "=" + opt.value
+	}
+	return dsn
+}
+
+// WithTransaction runs fn in a database transaction, committing if it
+// returns nil and rolling back otherwise. Pass the tx it receives to store
+// methods so that they take part in the transaction.
+func WithTransaction(fn func(tx *gorm.DB) error) error {
+	return DB.Transaction(fn)
+}
+
+// conn returns the caller's transaction if one was passed, or the global DB.
+func conn(tx []*gorm.DB) *gorm.DB {
+	if len(tx) > 0 && tx[0] != nil {
+		return tx[0]
+	}
+	return DB
+}
+
 // ==========================================
 // SESSIONS OPERATIONS
 // ==========================================
 
 type SessionStore struct{}
 
-// CreateSession creates a new session.
+// CreateSession creates a new session. An optional tx makes it part of a
+// caller's transaction.
 func (s *SessionStore) CreateSession(
 	sessionID uuid.UUID,
 	workspacePath string,
 	deviceID *string,
 	sandboxID *string,
+	tx ...*gorm.DB,
 ) (uuid.UUID, string, error) {
 	
 	sess := Session{
@@ -118,7 +212,7 @@ func (s *SessionStore) CreateSession(
 		SandboxID:    sandboxID,
 	}
 
-	result := DB.Create(&sess)
+	result := conn(tx).Create(&sess)
 	if result.Error != nil {
 		return uuid.Nil, "", result.Error
 	}
@@ -156,9 +250,78 @@ func (s *SessionStore) GetSessionByDeviceID(deviceID string) (*Session, error) {
 	return &sess, result.Error
 }
 
-// UpdateSessionName updates the name of a session.
-func (s *SessionStore) UpdateSessionName(sessionID uuid.UUID, name string) error {
-	return DB.Model(&Session{}).Where("id = ?", sessionID.String()).Update("name", name).Error
+// UpdateSessionName updates the name of a session. An optional tx makes it
+// part of a caller's transaction.
+func (s *SessionStore) UpdateSessionName(sessionID uuid.UUID, name string, tx ...*gorm.DB) error {
+	return conn(tx).Model(&Session{}).Where("id = ?", sessionID.String()).Update("name", name).Error
+}
+
+// DeleteSession permanently deletes a session, archived or not, and all of
+// its events. Events are removed explicitly because SQLite only cascades
+// when foreign keys are enabled. Deleting a session that does not exist is
+// not an error.
+func (s *SessionStore) DeleteSession(sessionID uuid.UUID) error {
+	return WithTransaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("session_id = ?", sessionID.String()).Delete(&Event{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", sessionID.String()).Delete(&Session{}).Error
+	})
+}
+
+// ArchiveSession soft-deletes a session and its events, hiding them from
+// default queries until RestoreSession is called. It returns false if there
+// is no active session with sessionID.
+func (s *SessionStore) ArchiveSession(sessionID uuid.UUID) (bool, error) {
+	archived := false
+	err := WithTransaction(func(tx *gorm.DB) error {
+		// Session and events share one timestamp so that a restore brings
+		// back exactly the events archived with the session.
+		now := time.Now()
+		result := tx.Model(&Session{}).Where("id = ?", sessionID.String()).Update("deleted_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		archived = true
+		return tx.Model(&Event{}).Where("session_id = ?", sessionID.String()).Update("deleted_at", now).Error
+	})
+	return archived && err == nil, err
+}
+
+// RestoreSession undoes ArchiveSession. It returns false if there is no
+// archived session with sessionID, and fails if another active session now
+// uses the same workspace.
+func (s *SessionStore) RestoreSession(sessionID uuid.UUID) (bool, error) {
+	restored := false
+	err := WithTransaction(func(tx *gorm.DB) error {
+		var sess Session
+		result := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", sessionID.String()).First(&sess)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Unscoped().Model(&Event{}).
+			Where("session_id = ? AND deleted_at = (SELECT deleted_at FROM sessions WHERE id = ?)", sess.ID, sess.ID).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&sess).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		restored = true
+		return nil
+	})
+	return restored && err == nil, err
+}
+
+// GetArchivedSessions gets all archived sessions, most recently archived
+// first.
+func (s *SessionStore) GetArchivedSessions() ([]Session, error) {
+	var sessions []Session
+	err := DB.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&sessions).Error
+	return sessions, err
 }
 
 // GetSandboxIDBySessionID gets the sandbox_id of a session.
@@ -191,7 +354,8 @@ type EventStore struct{}
 
 // SaveEvent saves an event to the database.
 // eventPayload should be a struct or map that can be marshaled to JSON.
-func (e *EventStore) SaveEvent(sessionID uuid.UUID, eventType string, eventPayload interface{}) (uuid.UUID, error) {
+// An optional tx makes it part of a caller's transaction.
+func (e *EventStore) SaveEvent(sessionID uuid.UUID, eventType string, eventPayload interface{}, tx ...*gorm.DB) (uuid.UUID, error) {
 	payloadBytes, err := json.Marshal(eventPayload)
 	if err != nil {
 		return uuid.Nil, err
@@ -203,13 +367,24 @@ func (e *EventStore) SaveEvent(sessionID uuid.UUID, eventType string, eventPaylo
 		EventPayload: payloadBytes,
 	}
 
-	if err := DB.Create(&evt).Error; err != nil {
+	if err := conn(tx).Create(&evt).Error; err != nil {
 		return uuid.Nil, err
 	}
 
 	return uuid.MustParse(evt.ID), nil
 }
 
+// Save saves an event for the session with the given ID, so the store can
+// serve as an agent's EventSink.
+func (e *EventStore) Save(sessionID, eventType string, payload interface{}) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID %q: %w", sessionID, err)
+	}
+	_, err = e.SaveEvent(id, eventType, payload)
+	return err
+}
+
 // GetSessionEvents gets all events for a session.
 func (e *EventStore) GetSessionEvents(sessionID uuid.UUID) ([]Event, error) {
 	var events []Event
@@ -217,6 +392,67 @@ func (e *EventStore) GetSessionEvents(sessionID uuid.UUID) ([]Event, error) {
 	return events, err
 }
 
+// GetLatestEvent gets the most recent event of eventType for a session, or
+// nil if there is none.
+func (e *EventStore) GetLatestEvent(sessionID uuid.UUID, eventType string) (*Event, error) {
+	var evt Event
+	result := DB.Where("session_id = ? AND event_type = ?", sessionID.String(), eventType).
+		Order("timestamp DESC").
+		First(&evt)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &evt, result.Error
+}
+
+// GetLastEventTimes gets the timestamp of the most recent event of each of
+// the given sessions. Sessions without events are absent from the map.
+func (e *EventStore) GetLastEventTimes(sessionIDs []string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time, len(sessionIDs))
+	if len(sessionIDs) == 0 {
+		return times, nil
+	}
+	var events []Event
+	err := DB.Select("session_id", "timestamp").
+		Where("session_id IN ?", sessionIDs).
+		Where("(session_id, timestamp) IN (?)", DB.Model(&Event{}).
+			Select("session_id, MAX(timestamp)").
+			Where("session_id IN ?", sessionIDs).
+			Group("session_id")).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, evt := range events {
+		times[evt.SessionID] = evt.Timestamp
+	}
+	return times, nil
+}
+
+// SearchEvents returns the events of a session whose JSON payload contains
+// query, oldest first. Matching is a case-insensitive (for ASCII) LIKE over
+// the stored payload text, so keys as well as values can match.
+func (e *EventStore) SearchEvents(sessionID uuid.UUID, query string) ([]Event, error) {
+	var events []Event
+	if query == "" {
+		return events, nil
+	}
+	err := DB.Where("session_id = ? AND event_payload LIKE ? ESCAPE '\\'", sessionID.String(), likePattern(query)).
+		Order("timestamp ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// likePattern builds a LIKE pattern matching query anywhere in a JSON
+// document. The query is JSON-escaped the way payloads are stored and LIKE
+// wildcards in it are escaped.
+func likePattern(query string) string {
+	encoded, _ := json.Marshal(query)
+	escaped := string(encoded[1 : len(encoded)-1])
+	escaped = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(escaped)
+	return "%" + escaped + "%"
+}
+
 // DeleteSessionEvents deletes all events for a session.
 func (e *EventStore) DeleteSessionEvents(sessionID uuid.UUID) error {
 	return DB.Where("session_id = ?", sessionID.String()).Delete(&Event{}).Error
@@ -2,8 +2,13 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
@@ -372,6 +377,28 @@ func TestSaveEvent(t *testing.T) {
 	}
 }
 
+func TestEventStoreSave(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	sessionID := uuid.New()
+	if _, _, err := Sessions.CreateSession(sessionID, "/test/workspace", nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	if err := Events.Save(sessionID.String(), "tool_call", map[string]interface{}{"tool_name": "bash"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	events, err := Events.GetSessionEvents(sessionID)
+	if err != nil || len(events) != 1 || events[0].EventType != "tool_call" {
+		t.Errorf("GetSessionEvents() = %v, %v; want the saved tool_call", events, err)
+	}
+
+	if err := Events.Save("not-a-uuid", "tool_call", nil); err == nil {
+		t.Error("Save() with an invalid session ID should fail")
+	}
+}
+
 func TestGetSessionEvents(t *testing.T) {
 	db := setupTestDB(t)
 	defer teardownTestDB(db)
@@ -439,6 +466,102 @@ func TestDeleteSessionEvents(t *testing.T) {
 	}
 }
 
+func TestDeleteSession(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	sessionID := uuid.New()
+	if _, _, err := Sessions.CreateSession(sessionID, "/test/workspace", nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	Events.SaveEvent(sessionID, "event_type_A", map[string]interface{}{"index": 0})
+
+	if err := Sessions.DeleteSession(sessionID); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+
+	sess, err := Sessions.GetSessionByID(sessionID)
+	if err != nil || sess != nil {
+		t.Errorf("GetSessionByID() = %v, %v; want nil, nil", sess, err)
+	}
+	events, _ := Events.GetSessionEvents(sessionID)
+	if len(events) != 0 {
+		t.Errorf("GetSessionEvents() returned %d events after delete; want 0", len(events))
+	}
+
+	// Deleting again is a no-op.
+	if err := Sessions.DeleteSession(sessionID); err != nil {
+		t.Errorf("DeleteSession() of missing session error = %v; want nil", err)
+	}
+}
+
+func TestGetLatestEvent(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	sessionID := uuid.New()
+	if _, _, err := Sessions.CreateSession(sessionID, "/test/workspace", nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	evt, err := Events.GetLatestEvent(sessionID, "usage")
+	if err != nil || evt != nil {
+		t.Fatalf("GetLatestEvent() with no events = %v, %v; want nil, nil", evt, err)
+	}
+
+	Events.SaveEvent(sessionID, "usage", map[string]interface{}{"n": 1})
+	time.Sleep(10 * time.Millisecond)
+	Events.SaveEvent(sessionID, "usage", map[string]interface{}{"n": 2})
+	Events.SaveEvent(sessionID, "other", map[string]interface{}{"n": 3})
+
+	evt, err = Events.GetLatestEvent(sessionID, "usage")
+	if err != nil || evt == nil {
+		t.Fatalf("GetLatestEvent() = %v, %v", evt, err)
+	}
+	if string(evt.EventPayload) != `{"n":2}` {
+		t.Errorf("GetLatestEvent() payload = %s; want {\"n\":2}", evt.EventPayload)
+	}
+}
+
+func TestSearchEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	sessionID := uuid.New()
+	if _, _, err := Sessions.CreateSession(sessionID, "/test/workspace", nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	Events.SaveEvent(sessionID, "a", map[string]interface{}{"text": "deploy to staging"})
+	Events.SaveEvent(sessionID, "b", map[string]interface{}{"text": "run <tests> 100% green"})
+	Events.SaveEvent(sessionID, "c", map[string]interface{}{"text": "file_name.go"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Staging", []string{"a"}},
+		{"<tests>", []string{"b"}},
+		{"100%", []string{"b"}},
+		{"e_n", []string{"c"}},
+		{"%", []string{"b"}},
+		{"missing", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		events, err := Events.SearchEvents(sessionID, tt.query)
+		if err != nil {
+			t.Fatalf("SearchEvents(%q) error = %v", tt.query, err)
+		}
+		var got []string
+		for _, evt := range events {
+			got = append(got, evt.EventType)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("SearchEvents(%q) = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
 func TestDeleteEventsFromLastToUserMessage(t *testing.T) {
 	db := setupTestDB(t)
 	defer teardownTestDB(db)
@@ -598,3 +721,233 @@ func TestEventJSONPayload(t *testing.T) {
 		t.Errorf("Payload string_val = %v; want hello", decodedPayload["string_val"])
 	}
 }
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"water.db", "water.db?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"},
+		{"water.db?_busy_timeout=100", "water.db?_busy_timeout=100&_journal_mode=WAL&_txlock=immediate"},
+		{"sqlite:////srv/water.db", "/srv/water.db?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"},
+	}
+	for _, tt := range tests {
+		if got := sqliteDSN(tt.in); got != tt.want {
+			t.Errorf("sqliteDSN(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWithTransactionRollsBack(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	sessionID := uuid.New()
+	err := WithTransaction(func(tx *gorm.DB) error {
+		if _, _, err := Sessions.CreateSession(sessionID, "/test/workspace", nil, nil, tx); err != nil {
+			return err
+		}
+		if _, err := Events.SaveEvent(sessionID, "test_event", map[string]string{"k": "v"}, tx); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if err == nil || err.Error() != "abort" {
+		t.Fatalf("WithTransaction() error = %v; want abort", err)
+	}
+
+	sess, _ := Sessions.GetSessionByID(sessionID)
+	events, _ := Events.GetSessionEvents(sessionID)
+	if sess != nil || len(events) != 0 {
+		t.Errorf("after rollback session = %v, events = %d; want neither", sess, len(events))
+	}
+}
+
+func TestConcurrentSaveEvent(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "concurrent.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	defer teardownTestDB(DB)
+
+	const writers, perWriter = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			sessionID := uuid.New()
+			// Each writer creates its session and first event together,
+			// then appends the rest individually.
+			err := WithTransaction(func(tx *gorm.DB) error {
+				if _, _, err := Sessions.CreateSession(sessionID, fmt.Sprintf("/ws/%d", w), nil, nil, tx); err != nil {
+					return err
+				}
+				_, err := Events.SaveEvent(sessionID, "test_event", map[string]int{"n": 0}, tx)
+				return err
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			for i := 1; i < perWriter; i++ {
+				if _, err := Events.SaveEvent(sessionID, "test_event", map[string]int{"n": i}); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent write error = %v", err)
+	}
+
+	var count int64
+	DB.Model(&Event{}).Count(&count)
+	if count != writers*perWriter {
+		t.Errorf("events = %d; want %d", count, writers*perWriter)
+	}
+}
+
+func TestArchiveAndRestoreSession(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	deviceID := "device-123"
+	sessionID := uuid.New()
+	Sessions.CreateSession(sessionID, "/test/archived", &deviceID, nil)
+	Events.SaveEvent(sessionID, "kept", map[string]string{"k": "v"})
+	Events.SaveEvent(sessionID, "cleared", map[string]string{"k": "v"})
+	// An event deleted before the archive stays deleted after a restore.
+	db.Where("event_type = ?", "cleared").Delete(&Event{})
+	other := uuid.New()
+	Sessions.CreateSession(other, "/test/active", &deviceID, nil)
+
+	archived, err := Sessions.ArchiveSession(sessionID)
+	if err != nil || !archived {
+		t.Fatalf("ArchiveSession() = %v, %v; want true, nil", archived, err)
+	}
+	if again, _ := Sessions.ArchiveSession(sessionID); again {
+		t.Error("ArchiveSession() of an archived session = true; want false")
+	}
+
+	if sess, _ := Sessions.GetSessionByID(sessionID); sess != nil {
+		t.Error("GetSessionByID() should exclude archived sessions")
+	}
+	listed, _ := Sessions.GetSessionsByDeviceID(deviceID)
+	if len(listed) != 1 || listed[0].ID != other.String() {
+		t.Errorf("GetSessionsByDeviceID() = %v; want only the active session", listed)
+	}
+	if events, _ := Events.GetSessionEvents(sessionID); len(events) != 0 {
+		t.Errorf("GetSessionEvents() = %d events; want 0 while archived", len(events))
+	}
+	archivedList, err := Sessions.GetArchivedSessions()
+	if err != nil || len(archivedList) != 1 || archivedList[0].ID != sessionID.String() {
+		t.Errorf("GetArchivedSessions() = %v, %v; want the archived session", archivedList, err)
+	}
+
+	restored, err := Sessions.RestoreSession(sessionID)
+	if err != nil || !restored {
+		t.Fatalf("RestoreSession() = %v, %v; want true, nil", restored, err)
+	}
+	if sess, _ := Sessions.GetSessionByID(sessionID); sess == nil {
+		t.Error("GetSessionByID() should find a restored session")
+	}
+	events, _ := Events.GetSessionEvents(sessionID)
+	if len(events) != 1 || events[0].EventType != "kept" {
+		t.Errorf("restored events = %v; want only the event archived with the session", events)
+	}
+	if again, _ := Sessions.RestoreSession(sessionID); again {
+		t.Error("RestoreSession() of an active session = true; want false")
+	}
+}
+
+func TestArchivedWorkspaceCanBeReused(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	first := uuid.New()
+	Sessions.CreateSession(first, "/test/workspace", nil, nil)
+	if _, _, err := Sessions.CreateSession(uuid.New(), "/test/workspace", nil, nil); err == nil {
+		t.Fatal("CreateSession() with an active workspace should fail")
+	}
+
+	Sessions.ArchiveSession(first)
+	second := uuid.New()
+	if _, _, err := Sessions.CreateSession(second, "/test/workspace", nil, nil); err != nil {
+		t.Fatalf("CreateSession() after archive error = %v", err)
+	}
+	sess, _ := Sessions.GetSessionByWorkspace("/test/workspace")
+	if sess == nil || sess.ID != second.String() {
+		t.Errorf("GetSessionByWorkspace() = %v; want the active session", sess)
+	}
+
+	// The archived session cannot come back while its workspace is in use.
+	if _, err := Sessions.RestoreSession(first); err == nil {
+		t.Error("RestoreSession() into a used workspace should fail")
+	}
+}
+
+func TestInitDBDropsLegacyWorkspaceIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.Exec("CREATE TABLE sessions (id text PRIMARY KEY, workspace_dir text NOT NULL, created_at datetime, device_id text, name text, sandbox_id text)")
+	legacy.Exec("CREATE UNIQUE INDEX idx_sessions_workspace_dir ON sessions(workspace_dir)")
+	teardownTestDB(legacy)
+
+	if err := InitDB(path); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	defer teardownTestDB(DB)
+	if DB.Migrator().HasIndex(&Session{}, legacyWorkspaceIndex) {
+		t.Error("InitDB() should drop the legacy workspace index")
+	}
+
+	first := uuid.New()
+	Sessions.CreateSession(first, "/test/workspace", nil, nil)
+	Sessions.ArchiveSession(first)
+	if _, _, err := Sessions.CreateSession(uuid.New(), "/test/workspace", nil, nil); err != nil {
+		t.Errorf("CreateSession() after archive error = %v", err)
+	}
+}
+
+func TestGetLastEventTimes(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	withEvents, without := uuid.New(), uuid.New()
+	Sessions.CreateSession(withEvents, "/test/a", nil, nil)
+	Sessions.CreateSession(without, "/test/b", nil, nil)
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		id, _ := Events.SaveEvent(withEvents, "test_event", map[string]int{"n": i})
+		db.Model(&Event{}).Where("id = ?", id.String()).Update("timestamp", base.Add(time.Duration(i)*time.Minute))
+	}
+
+	times, err := Events.GetLastEventTimes([]string{withEvents.String(), without.String()})
+	if err != nil {
+		t.Fatalf("GetLastEventTimes() error = %v", err)
+	}
+	if got := times[withEvents.String()]; !got.Equal(base.Add(2 * time.Minute)) {
+		t.Errorf("last event = %v; want %v", got, base.Add(2*time.Minute))
+	}
+	if _, ok := times[without.String()]; ok {
+		t.Error("a session without events should be absent")
+	}
+}
+
+func TestClose(t *testing.T) {
+	setupTestDB(t)
+	if err := Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if DB != nil {
+		t.Error("DB not cleared after Close")
+	}
+	if err := Close(); err != nil {
+		t.Errorf("Close() on closed database error = %v", err)
+	}
+}
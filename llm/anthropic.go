@@ -3,7 +3,6 @@ package llm
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -58,6 +57,7 @@ type anthRequest struct {
 	MaxTokens     int           `json:"max_tokens"`
 	System        string        `json:"system,omitempty"`
 	Temperature   float64       `json:"temperature"`
+	TopP          *float64      `json:"top_p,omitempty"`
 	Tools         []ToolParam   `json:"tools,omitempty"`
 	ToolChoice    interface{}   `json:"tool_choice,omitempty"`
 	Thinking      interface{}   `json:"thinking,omitempty"` // For extended thinking
@@ -71,6 +71,7 @@ func (c *AnthropicClient) Generate(
 	tools []*ToolParam,
 	toolChoice *ToolChoice,
 	thinkingTokens *int,
+	sampling *Sampling,
 ) (*GenerateResponse, error) {
 
 	// 1. Convert Messages
@@ -141,6 +142,11 @@ func (c *AnthropicClient) Generate(
 		}
 	}
 
+	// Anthropic has no seed parameter
+	if sampling != nil {
+		reqBody.TopP = sampling.TopP
+	}
+
 	// Handle Thinking
 	tt := c.config.ThinkingTokens
 	if thinkingTokens != nil {
@@ -174,13 +180,13 @@ func (c *AnthropicClient) Generate(
 	}
 	
 	if err != nil {
-		return nil, err
+		return nil, networkError("anthropic", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Anthropic Error %d: %s", resp.StatusCode, string(b))
+		return nil, classifyResponse("anthropic", resp.StatusCode, b)
 	}
 
 	// 4. Parse Response
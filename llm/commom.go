@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"water-ai/core/config"
 )
 
 // ==========================================
@@ -64,6 +66,9 @@ type ContentBlock struct {
 	ToolCallID string                 `json:"tool_call_id,omitempty"`
 	ToolName   string                 `json:"tool_name,omitempty"`
 	ToolInput  map[string]interface{} `json:"tool_input,omitempty"`
+	// ToolInputError is set when the model's arguments could not be
+	// parsed; ToolInput is then empty.
+	ToolInputError string `json:"tool_input_error,omitempty"`
 
 	// Tool Result
 	ToolOutput interface{} `json:"tool_output,omitempty"` // string or []ContentBlock
@@ -120,9 +125,18 @@ type Client interface {
 		tools []*ToolParam,
 		toolChoice *ToolChoice,
 		thinkingTokens *int,
+		sampling *Sampling,
 	) (*GenerateResponse, error)
 }
 
+// Sampling overrides sampling for one request. Nil fields keep the
+// provider's default, and providers omit the fields they do not support:
+// Anthropic has no seed.
+type Sampling struct {
+	TopP *float64
+	Seed *int
+}
+
 func GetClient(cfg LLMConfig) (Client, error) {
 	switch cfg.APIType {
 	case APITypeOpenAI:
@@ -136,6 +150,72 @@ func GetClient(cfg LLMConfig) (Client, error) {
 	}
 }
 
+// NewClientForModel returns a client for modelName, inferring the API type
+// from the name (OpenAI unless it names Claude or Gemini). The key comes from
+// LLM_API_KEY, falling back to the provider's own variable.
+func NewClientForModel(modelName string, thinkingTokens int) (Client, error) {
+	if modelName == "" {
+		modelName = "gpt-4-turbo"
+	}
+	return NewClientFromConfig(config.LLMConfig{Model: modelName, MaxRetries: 3}, "", thinkingTokens)
+}
+
+// NewClientFromConfig returns a client for cfg. A non-empty modelName
+// selects another model than cfg.Model, and a non-zero thinkingTokens
+// overrides cfg's. Catalog models use their own provider; others use
+// cfg.APIType. cfg's key, base URL and endpoints apply to its own model and
+// to other models of cfg.APIType. Without a key from cfg it comes from
+// LLM_API_KEY, falling back to the provider's own variable.
+func NewClientFromConfig(cfg config.LLMConfig, modelName string, thinkingTokens int) (Client, error) {
+	if modelName == "" {
+		modelName = cfg.Model
+	}
+	if thinkingTokens != 0 {
+		cfg.ThinkingTokens = thinkingTokens
+	}
+
+	apiType := APIType(cfg.APIType)
+	if info, ok := LookupModel(modelName); ok {
+		apiType = info.Provider
+	} else if apiType == "" {
+		apiType = ProviderForModel(modelName)
+	}
+	if modelName != cfg.Model && apiType != APIType(cfg.APIType) {
+		cfg = config.LLMConfig{MaxRetries: cfg.MaxRetries, ThinkingTokens: cfg.ThinkingTokens}
+	}
+	cfg.Model = modelName
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	apiKey := ""
+	if cfg.APIKey != nil {
+		apiKey = cfg.APIKey.Reveal()
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("LLM_API_KEY")
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv(ProviderKeyEnv[apiType])
+	}
+
+	return GetClient(LLMConfig{
+		APIType:         apiType,
+		Model:           cfg.Model,
+		APIKey:          apiKey,
+		BaseURL:         str(cfg.BaseURL),
+		MaxRetries:      cfg.MaxRetries,
+		AzureEndpoint:   str(cfg.AzureEndpoint),
+		AzureAPIVersion: str(cfg.AzureAPIVersion),
+		VertexProjectID: str(cfg.VertexProjectID),
+		VertexRegion:    str(cfg.VertexRegion),
+		ThinkingTokens:  cfg.ThinkingTokens,
+		CotModel:        cfg.CoTModel,
+	})
+}
+
 // ==========================================
 // MESSAGE HISTORY
 // ==========================================
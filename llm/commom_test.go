@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"water-ai/core/config"
 )
 
 func TestAPITypeConstants(t *testing.T) {
@@ -631,3 +633,84 @@ func TestSaveToFileCreatesDirectories(t *testing.T) {
 		t.Error("SaveToFile() should create nested directories")
 	}
 }
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model  string
+		want   string
+		wantOK bool
+	}{
+		{"gpt-4o", "gpt-4o", true},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini", true},
+		{"claude-3-5-sonnet-20241022", "claude-3-5-sonnet", true},
+		{"gpt-4", "", false},
+		{"local-model", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := LookupModel(tt.model)
+		if ok != tt.wantOK || got.ID != tt.want {
+			t.Errorf("LookupModel(%q) = %q, %v; want %q, %v", tt.model, got.ID, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestProviderForModel(t *testing.T) {
+	for model, want := range map[string]APIType{
+		"claude-sonnet-4":  APITypeAnthropic,
+		"gemini-2.5-flash": APITypeGemini,
+		"o3":               APITypeOpenAI,
+		"":                 APITypeOpenAI,
+	} {
+		if got := ProviderForModel(model); got != want {
+			t.Errorf("ProviderForModel(%q) = %s; want %s", model, got, want)
+		}
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	t.Setenv("LLM_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "env-openai")
+	t.Setenv("ANTHROPIC_API_KEY", "env-anthropic")
+	key := config.SecretString("file-key")
+	baseURL := "https://proxy.example.com/v1"
+	const openAIDefault = "https://api.openai.com/v1"
+	file := config.LLMConfig{Model: "gpt-4o", APIType: config.APITypeOpenAI, APIKey: &key, BaseURL: &baseURL, MaxRetries: 2}
+
+	tests := []struct {
+		name        string
+		cfg         config.LLMConfig
+		model       string
+		wantType    APIType
+		wantModel   string
+		wantKey     string
+		wantBaseURL string
+	}{
+		{"configured model", file, "", APITypeOpenAI, "gpt-4o", "file-key", baseURL},
+		{"same provider", file, "gpt-4o-mini", APITypeOpenAI, "gpt-4o-mini", "file-key", baseURL},
+		{"other provider", file, "claude-sonnet-4", APITypeAnthropic, "claude-sonnet-4", "env-anthropic", ""},
+		{"custom model", config.LLMConfig{Model: "llama-3", APIType: config.APITypeOpenAI, APIKey: &key}, "", APITypeOpenAI, "llama-3", "file-key", openAIDefault},
+		{"catalog beats default type", config.LLMConfig{Model: "gpt-4o", APIType: config.APITypeAnthropic, APIKey: &key}, "", APITypeOpenAI, "gpt-4o", "file-key", openAIDefault},
+		{"no key", config.LLMConfig{Model: "gpt-4o", APIType: config.APITypeOpenAI}, "", APITypeOpenAI, "gpt-4o", "env-openai", openAIDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientFromConfig(tt.cfg, tt.model, 0)
+			if err != nil {
+				t.Fatalf("NewClientFromConfig() error = %v", err)
+			}
+			var got LLMConfig
+			switch c := client.(type) {
+			case *OpenAIClient:
+				got = c.config
+			case *AnthropicClient:
+				got = c.config
+			default:
+				t.Fatalf("client = %T", client)
+			}
+			if got.APIType != tt.wantType || got.Model != tt.wantModel || got.APIKey != tt.wantKey || got.BaseURL != tt.wantBaseURL {
+				t.Errorf("config = %s %s key=%q base=%q; want %s %s key=%q base=%q",
+					got.APIType, got.Model, got.APIKey, got.BaseURL, tt.wantType, tt.wantModel, tt.wantKey, tt.wantBaseURL)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package contextmanager
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 // Defaults based on the original code
@@ -15,17 +19,43 @@ const (
 	DefaultMaxSize         = 100
 	DefaultMaxEventLength  = 10000
 	KeepFirst              = 1
-	ImageTokenCost         = 1000
+	summaryCacheSize       = 32
+	ImageTokenCost         = 1000 // charged for images of unknown size
+
+	// Images are downscaled by the provider until they fit both limits
+	// and then cost one token per imagePixelsPerToken pixels.
+	imageMaxLongEdge    = 1568
+	imageMaxPixels      = 1_150_000
+	imagePixelsPerToken = 750
 )
 
-const summaryPromptTemplate = `
+// SummaryPromptPlaceholder marks where a summary prompt receives the
+// material to summarize: the previous summary and the forgotten events
+// when truncating, or the whole conversation for a complete summary.
+// Every summary prompt must contain it exactly once.
+const SummaryPromptPlaceholder = "{events}"
+
+// DefaultSummaryPrompt is the summary prompt used when Config.SummaryPrompt
+// is empty.
+const DefaultSummaryPrompt = `
 Your task is to create a detailed summary of the conversation so far, paying close attention to the user's explicit requests and your previous actions.
 This summary should be thorough in capturing technical details, code patterns, and architectural decisions that would be essential for continuing development work without losing context.
 
 [... Full prompt redacted for brevity, insert original prompt text here ...]
 
+` + SummaryPromptPlaceholder + `
+
 Now summarize the events using the rules above.`
 
+// ValidateSummaryPrompt reports whether prompt can be used as a summary
+// prompt, i.e. contains SummaryPromptPlaceholder exactly once.
+func ValidateSummaryPrompt(prompt string) error {
+	if n := strings.Count(prompt, SummaryPromptPlaceholder); n != 1 {
+		return fmt.Errorf("summary prompt must contain the %s placeholder exactly once, found %d", SummaryPromptPlaceholder, n)
+	}
+	return nil
+}
+
 // ============================================================================
 // Types & Interfaces
 // ============================================================================
@@ -57,8 +87,11 @@ type ToolFormattedResult struct {
 }
 func (t ToolFormattedResult) Type() string { return "ToolFormattedResult" }
 
+// ImageBlock is an image in the conversation. Width and Height are in
+// pixels; when either is unknown the block is charged ImageTokenCost.
 type ImageBlock struct {
-	// Image data omitted for brevity
+	Width  int
+	Height int
 }
 func (t ImageBlock) Type() string { return "ImageBlock" }
 
@@ -85,8 +118,55 @@ type Config struct {
 	TokenBudget    int
 	MaxSize        int
 	MaxEventLength int
+
+	// Budget optionally splits TokenBudget between prompt components.
+	// When nil, only whole-history truncation is applied.
+	Budget *BudgetAllocation
+
+	// SummaryPrompt overrides DefaultSummaryPrompt for both truncation and
+	// complete-conversation summaries. It must contain
+	// SummaryPromptPlaceholder, which is replaced by the material to
+	// summarize; an invalid prompt falls back to the default.
+	SummaryPrompt string
+}
+
+// BudgetAllocation reserves a percentage of the token budget for each
+// component competing for the context window. When the history is over
+// budget, components are trimmed from most to least expendable: tool
+// results first, then older turns (summarized), never the recent turns.
+type BudgetAllocation struct {
+	SystemPercent      int // system prompt + tool definitions
+	RecentTurnsPercent int // trailing conversational turns kept verbatim
+	ToolResultsPercent int // tool outputs anywhere in the history
+}
+
+// DefaultBudgetAllocation is a reasonable split for agent workloads.
+var DefaultBudgetAllocation = BudgetAllocation{
+	SystemPercent:      20,
+	RecentTurnsPercent: 50,
+	ToolResultsPercent: 30,
+}
+
+// BudgetedConfig returns the default limits with DefaultBudgetAllocation,
+// for callers that report their system prompt via SetSystemTokens.
+func BudgetedConfig() *Config {
+	budget := DefaultBudgetAllocation
+	return &Config{
+		TokenBudget:    DefaultTokenBudget,
+		MaxSize:        DefaultMaxSize,
+		MaxEventLength: DefaultMaxEventLength,
+		Budget:         &budget,
+	}
+}
+
+func (b BudgetAllocation) total() int {
+	return b.SystemPercent + b.RecentTurnsPercent + b.ToolResultsPercent
 }
 
+// TrimmedToolResultMarker ends every tool output shortened to fit the
+// tool-result share of the budget.
+const TrimmedToolResultMarker = "... [tool output trimmed to fit context budget]"
+
 // ============================================================================
 // Context Manager Implementation
 // ============================================================================
@@ -96,6 +176,17 @@ type Manager struct {
 	tokenCounter TokenCounter
 	logger       *slog.Logger
 	config       Config
+
+	// systemTokens is the measured size of the system prompt and tool
+	// definitions, reported by the caller via SetSystemTokens.
+	systemTokens int
+
+	// summaryCache maps a hash of a summary prompt to the summary the LLM
+	// produced for it, so an unchanged window is never summarized twice.
+	// summaryKeys records insertion order for eviction.
+	cacheMu      sync.Mutex
+	summaryCache map[string]string
+	summaryKeys  []string
 }
 
 // New creates a new ContextManager.
@@ -110,12 +201,24 @@ func New(client LLMClient, counter TokenCounter, logger *slog.Logger, cfg *Confi
 	if cfg.MaxSize < 1 {
 		cfg.MaxSize = 1
 	}
+	if cfg.Budget != nil && cfg.Budget.total() > 100 {
+		logger.Warn("Budget allocation exceeds 100%, using defaults", "total", cfg.Budget.total())
+		budget := DefaultBudgetAllocation
+		cfg.Budget = &budget
+	}
+	if cfg.SummaryPrompt == "" {
+		cfg.SummaryPrompt = DefaultSummaryPrompt
+	} else if err := ValidateSummaryPrompt(cfg.SummaryPrompt); err != nil {
+		logger.Warn("Invalid summary prompt, using default", "error", err)
+		cfg.SummaryPrompt = DefaultSummaryPrompt
+	}
 
 	return &Manager{
 		client:       client,
 		tokenCounter: counter,
 		logger:       logger,
 		config:       *cfg,
+		summaryCache: make(map[string]string),
 	}
 }
 
@@ -145,7 +248,7 @@ func (m *Manager) CountTokens(messageLists [][]ContentBlock) int {
 					totalTokens += m.tokenCounter.CountTokens(string(bytes))
 				}
 			case ImageBlock:
-				totalTokens += ImageTokenCost
+				totalTokens += ImageTokens(v.Width, v.Height)
 			case AnthropicRedactedThinkingBlock:
 				// Always 0
 			case AnthropicThinkingBlock:
@@ -160,19 +263,63 @@ func (m *Manager) CountTokens(messageLists [][]ContentBlock) int {
 	return totalTokens
 }
 
+// ImageTokens estimates the tokens an image of the given pixel size costs,
+// following Anthropic's formula: the image is scaled down to fit within
+// imageMaxLongEdge and imageMaxPixels, then charged width*height/750.
+// Unknown dimensions fall back to ImageTokenCost.
+func ImageTokens(width, height int) int {
+	if width <= 0 || height <= 0 {
+		return ImageTokenCost
+	}
+	w, h := float64(width), float64(height)
+	if long := max(w, h); long > imageMaxLongEdge {
+		w, h = w*imageMaxLongEdge/long, h*imageMaxLongEdge/long
+	}
+	if pixels := w * h; pixels > imageMaxPixels {
+		scale := math.Sqrt(imageMaxPixels / pixels)
+		w, h = w*scale, h*scale
+	}
+	return max(1, int(math.Ceil(w*h/imagePixelsPerToken)))
+}
+
+// SetSystemTokens records the token cost of the system prompt and tool
+// definitions so the history budget can account for it.
+func (m *Manager) SetSystemTokens(tokens int) {
+	m.systemTokens = tokens
+}
+
+// historyBudget is the share of TokenBudget left for the message history
+// once the system component has been reserved.
+func (m *Manager) historyBudget() int {
+	if m.config.Budget == nil {
+		return m.config.TokenBudget
+	}
+	reserved := m.config.TokenBudget * m.config.Budget.SystemPercent / 100
+	if m.systemTokens > reserved {
+		m.logger.Warn("System prompt exceeds its budget share", "tokens", m.systemTokens, "reserved", reserved)
+		reserved = m.systemTokens
+	}
+	return max(m.config.TokenBudget-reserved, 0)
+}
+
 // ApplyTruncationIfNeeded checks if truncation is required and applies it.
 func (m *Manager) ApplyTruncationIfNeeded(ctx context.Context, messageLists [][]ContentBlock) ([][]ContentBlock, error) {
+	if m.config.Budget != nil {
+		messageLists = m.trimToolResults(messageLists)
+	}
+
 	currentCount := m.CountTokens(messageLists)
-	
+	budget := m.historyBudget()
+
 	// Check if we exceed budget OR max number of turns
-	if currentCount <= m.config.TokenBudget && len(messageLists) <= m.config.MaxSize {
+	if currentCount <= budget && len(messageLists) <= m.config.MaxSize {
 		return messageLists, nil
 	}
 
 	m.logger.Warn("Token limit or max size exceeded, applying truncation", 
 		"current_tokens", currentCount, 
 		"turns", len(messageLists), 
-		"budget", m.config.TokenBudget)
+		"budget", budget)
 
 	truncatedLists, err := m.applyTruncation(ctx, messageLists)
 	if err != nil {
@@ -185,6 +332,74 @@ func (m *Manager) ApplyTruncationIfNeeded(ctx context.Context, messageLists [][]
 	return truncatedLists, nil
 }
 
+// trimToolResults shrinks tool outputs, oldest first, until their combined
+// size fits the tool-result share of the budget. Tool results are the most
+// expendable component, so this runs before any turns are summarized.
+func (m *Manager) trimToolResults(messageLists [][]ContentBlock) [][]ContentBlock {
+	limit := m.config.TokenBudget * m.config.Budget.ToolResultsPercent / 100
+
+	total := 0
+	for _, list := range messageLists {
+		for _, msg := range list {
+			if tr, ok := msg.(ToolFormattedResult); ok {
+				total += m.tokenCounter.CountTokens(tr.ToolOutput)
+			}
+		}
+	}
+	if total <= limit {
+		return messageLists
+	}
+
+	m.logger.Info("Tool results exceed budget share, trimming", "tokens", total, "limit", limit)
+
+	overflow := total - limit
+	result := make([][]ContentBlock, len(messageLists))
+	for i, list := range messageLists {
+		newList := make([]ContentBlock, len(list))
+		for j, msg := range list {
+			tr, ok := msg.(ToolFormattedResult)
+			if !ok || overflow <= 0 {
+				newList[j] = msg
+				continue
+			}
+			tokens := m.tokenCounter.CountTokens(tr.ToolOutput)
+			if tokens == 0 {
+				newList[j] = msg
+				continue
+			}
+			keepTokens := max(tokens-overflow, 0)
+			keepChars := len(tr.ToolOutput) * keepTokens / tokens
+			// Cut on a rune boundary so the output stays valid UTF-8
+			for keepChars > 0 && !utf8.RuneStart(tr.ToolOutput[keepChars]) {
+				keepChars--
+			}
+			overflow -= tokens - keepTokens
+			newList[j] = ToolFormattedResult{ToolOutput: tr.ToolOutput[:keepChars] + TrimmedToolResultMarker}
+		}
+		result[i] = newList
+	}
+	return result
+}
+
+// recentTailLength returns how many trailing turns fit in the recent-turns
+// share of the budget, or fallback when no allocation is configured.
+func (m *Manager) recentTailLength(messageLists [][]ContentBlock, fallback int) int {
+	if m.config.Budget == nil {
+		return fallback
+	}
+	limit := m.config.TokenBudget * m.config.Budget.RecentTurnsPercent / 100
+	used, n := 0, 0
+	for i := len(messageLists) - 1; i >= KeepFirst; i-- {
+		used += m.CountTokens(messageLists[i : i+1])
+		if used > limit && n > 0 {
+			break
+		}
+		n++
+	}
+	// Always leave at least one turn to summarize.
+	return max(min(n, len(messageLists)-KeepFirst-1), 0)
+}
+
 // applyTruncation routes to the specific truncation strategy.
 func (m *Manager) applyTruncation(ctx context.Context, messageLists [][]ContentBlock) ([][]ContentBlock, error) {
 	if m.hasThinkingBlocks(messageLists) {
@@ -204,7 +419,7 @@ func (m *Manager) truncateWithThinkingBlocks(ctx context.Context, messageLists [
 	targetSize := min(m.config.MaxSize, len(messageLists)) / 2
 	
 	// Ensure we don't cut past the last prompt
-	lastSummaryIdx := min(lastPromptIdx, KeepFirst+targetSize)
+	lastSummaryIdx := pairedCut(messageLists, min(lastPromptIdx, KeepFirst+targetSize), KeepFirst)
 
 	eventsToSummarize := messageLists[KeepFirst:lastSummaryIdx]
 	eventsToKeep := messageLists[lastSummaryIdx:]
@@ -242,6 +457,7 @@ func (m *Manager) truncateStandard(ctx context.Context, messageLists [][]Content
 	if eventsFromTail < 0 {
 		eventsFromTail = 0
 	}
+	eventsFromTail = m.recentTailLength(messageLists, eventsFromTail)
 
 	// Determine where to start summarizing. 
 	// If a summary already exists at Head+1, we might merge into it.
@@ -267,7 +483,7 @@ func (m *Manager) truncateStandard(ctx context.Context, messageLists [][]Content
 
 	endIdx := len(messageLists)
 	if eventsFromTail > 0 {
-		endIdx = len(messageLists) - eventsFromTail
+		endIdx = pairedCut(messageLists, len(messageLists)-eventsFromTail, summaryStartIdx)
 	}
 
 	forgottenEvents := messageLists[summaryStartIdx:endIdx]
@@ -286,9 +502,7 @@ func (m *Manager) truncateStandard(ctx context.Context, messageLists [][]Content
 	result = append(result, head...)
 	result = append(result, []ContentBlock{TextResult{Text: "Conversation Summary: " + summary}})
 	
-	if eventsFromTail > 0 {
-		result = append(result, messageLists[len(messageLists)-eventsFromTail:]...)
-	}
+	result = append(result, messageLists[endIdx:]...)
 
 	m.logger.Info("Standard truncation applied", 
 		"original_len", len(messageLists), 
@@ -297,10 +511,28 @@ func (m *Manager) truncateStandard(ctx context.Context, messageLists [][]Content
 	return result, nil
 }
 
+// pairedCut moves the start idx of the kept tail back, no further than
+// floor, while it begins with tool results: their tool calls would be
+// summarized away, and providers reject results without their call.
+func pairedCut(messageLists [][]ContentBlock, idx, floor int) int {
+	for idx > floor && idx < len(messageLists) && hasToolResult(messageLists[idx]) {
+		idx--
+	}
+	return idx
+}
+
+func hasToolResult(list []ContentBlock) bool {
+	for _, block := range list {
+		if _, ok := block.(ToolFormattedResult); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // generateSummary calls the LLM to summarize specific events.
 func (m *Manager) generateSummary(ctx context.Context, events [][]ContentBlock, prevSummary string) (string, error) {
 	var sb strings.Builder
-	sb.WriteString(summaryPromptTemplate)
 
 	// Clean previous summary tag
 	cleanPrev := strings.Replace(prevSummary, "Conversation Summary: ", "", 1)
@@ -315,9 +547,17 @@ func (m *Manager) generateSummary(ctx context.Context, events [][]ContentBlock,
 		fmt.Fprintf(&sb, "<EVENT id=%d>\n%s\n</EVENT>\n", i, m.truncateContent(eventContent))
 	}
 
-	sb.WriteString("\nNow summarize the events using the rules above.")
+	promptText := m.summaryPrompt(sb.String())
 
-	prompt := []ContentBlock{TextPrompt{Text: sb.String()}}
+	// The prompt embeds the events and previous summary, so its hash
+	// changes whenever either does.
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(promptText)))
+	if summary, ok := m.cachedSummary(key); ok {
+		m.logger.Info("Reusing cached summary", "events", len(events))
+		return summary, nil
+	}
+
+	prompt := []ContentBlock{TextPrompt{Text: promptText}}
 	
 	// Call LLM
 	response, err := m.client.Generate(ctx, [][]ContentBlock{prompt}, DefaultSummaryMaxToken, 0.0)
@@ -333,9 +573,33 @@ func (m *Manager) generateSummary(ctx context.Context, events [][]ContentBlock,
 		}
 	}
 
+	m.cacheSummary(key, summary)
 	return summary, nil
 }
 
+func (m *Manager) cachedSummary(key string) (string, bool) {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	summary, ok := m.summaryCache[key]
+	return summary, ok
+}
+
+// cacheSummary stores a summary, evicting the oldest entry once the cache
+// holds summaryCacheSize entries.
+func (m *Manager) cacheSummary(key, summary string) {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	if _, ok := m.summaryCache[key]; ok {
+		return
+	}
+	if len(m.summaryKeys) >= summaryCacheSize {
+		delete(m.summaryCache, m.summaryKeys[0])
+		m.summaryKeys = m.summaryKeys[1:]
+	}
+	m.summaryCache[key] = summary
+	m.summaryKeys = append(m.summaryKeys, key)
+}
+
 // GenerateCompleteConversationSummary creates a summary of the entire history (for /compact commands).
 func (m *Manager) GenerateCompleteConversationSummary(ctx context.Context, messageLists [][]ContentBlock) (string, error) {
 	if len(messageLists) == 0 {
@@ -343,17 +607,15 @@ func (m *Manager) GenerateCompleteConversationSummary(ctx context.Context, messa
 	}
 
 	var sb strings.Builder
-	sb.WriteString(summaryPromptTemplate)
 	sb.WriteString("<CONVERSATION>\n")
 	
 	for i, list := range messageLists {
 		content := m.messageListToString(list)
 		fmt.Fprintf(&sb, "<TURN id=%d>\n%s\n</TURN>\n\n", i, content)
 	}
-	sb.WriteString("</CONVERSATION>\n\n")
-	sb.WriteString("Now summarize the conversation using the rules above.")
+	sb.WriteString("</CONVERSATION>")
 
-	prompt := []ContentBlock{TextPrompt{Text: sb.String()}}
+	prompt := []ContentBlock{TextPrompt{Text: m.summaryPrompt(sb.String())}}
 
 	response, err := m.client.Generate(ctx, [][]ContentBlock{prompt}, DefaultSummaryMaxToken, 0.0)
 	if err != nil {
@@ -373,6 +635,11 @@ func (m *Manager) GenerateCompleteConversationSummary(ctx context.Context, messa
 // Helpers
 // ============================================================================
 
+// summaryPrompt fills the configured summary prompt with material.
+func (m *Manager) summaryPrompt(material string) string {
+	return strings.Replace(m.config.SummaryPrompt, SummaryPromptPlaceholder, material, 1)
+}
+
 func (m *Manager) truncateContent(content string) string {
 	if len(content) <= m.config.MaxEventLength {
 		return content
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"testing"
 	"strings" // Added for cleaner contains check
+	"unicode/utf8"
 )
 
 // MockTokenCounter implements TokenCounter for testing
@@ -176,6 +177,44 @@ func TestManagerCountTokensWithImages(t *testing.T) {
 	}
 }
 
+func TestManagerCountTokensScalesWithImageSize(t *testing.T) {
+	manager := New(&MockLLMClient{}, &MockTokenCounter{}, slog.Default(), nil)
+
+	small := manager.CountTokens([][]ContentBlock{{ImageBlock{Width: 200, Height: 200}}})
+	large := manager.CountTokens([][]ContentBlock{{ImageBlock{Width: 1000, Height: 1000}}})
+
+	if small != 54 { // ceil(200*200/750)
+		t.Errorf("small image tokens = %d; want 54", small)
+	}
+	if large != 1334 { // ceil(1000*1000/750)
+		t.Errorf("large image tokens = %d; want 1334", large)
+	}
+	if small >= large {
+		t.Errorf("small image (%d) should cost fewer tokens than large (%d)", small, large)
+	}
+}
+
+func TestImageTokens(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want          int
+	}{
+		{"unknown size", 0, 0, ImageTokenCost},
+		{"unknown height", 800, 0, ImageTokenCost},
+		{"tiny", 10, 10, 1},
+		{"long edge capped", 3136, 100, 105},     // scaled to 1568x50
+		{"pixel count capped", 1500, 1500, 1534}, // scaled to ~1.15MP
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ImageTokens(tt.width, tt.height); got != tt.want {
+				t.Errorf("ImageTokens(%d, %d) = %d; want %d", tt.width, tt.height, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestManagerCountTokensWithThinking(t *testing.T) {
 	logger := slog.Default()
 	counter := &MockTokenCounter{}
@@ -474,4 +513,265 @@ func TestTruncateStandard(t *testing.T) {
 	if len(result) >= len(messageLists) {
 		t.Error("Standard truncation should reduce message count")
 	}
-}
\ No newline at end of file
+}
+func TestBudgetAllocationTrimsToolResultBeforeRecentTurns(t *testing.T) {
+	logger := slog.Default()
+	counter := &MockTokenCounter{countFunc: func(text string) int { return len(text) }}
+	summarized := false
+	client := &MockLLMClient{
+		generateFunc: func(ctx context.Context, messages [][]ContentBlock, maxTokens int, temperature float64) ([]ContentBlock, error) {
+			summarized = true
+			return []ContentBlock{TextResult{Text: "summary"}}, nil
+		},
+	}
+
+	cfg := &Config{
+		TokenBudget:    1000,
+		MaxSize:        100,
+		MaxEventLength: DefaultMaxEventLength,
+		Budget: &BudgetAllocation{
+			SystemPercent:      20,
+			RecentTurnsPercent: 50,
+			ToolResultsPercent: 30,
+		},
+	}
+	manager := New(client, counter, logger, cfg)
+
+	recentPrompt := "Now please fix the failing test in parser.go"
+	recentReply := "I will look at parser.go next."
+	messageLists := [][]ContentBlock{
+		{TextPrompt{Text: "Build me a parser"}},
+		{ToolCall{ToolInput: map[string]interface{}{"command": "cat big.log"}}},
+		{ToolFormattedResult{ToolOutput: strings.Repeat("x", 2000)}},
+		{TextPrompt{Text: recentPrompt}},
+		{TextResult{Text: recentReply}},
+	}
+
+	result, err := manager.ApplyTruncationIfNeeded(context.Background(), messageLists)
+	if err != nil {
+		t.Fatalf("ApplyTruncationIfNeeded() error = %v", err)
+	}
+
+	if summarized {
+		t.Error("recent turns were summarized; the tool result should have been trimmed instead")
+	}
+	if len(result) != len(messageLists) {
+		t.Fatalf("len(result) = %d; want %d", len(result), len(messageLists))
+	}
+
+	tr, ok := result[2][0].(ToolFormattedResult)
+	if !ok {
+		t.Fatalf("result[2][0] = %T; want ToolFormattedResult", result[2][0])
+	}
+	if !strings.HasSuffix(tr.ToolOutput, TrimmedToolResultMarker) {
+		t.Error("tool result should carry the trimmed marker")
+	}
+	if got := counter.CountTokens(strings.TrimSuffix(tr.ToolOutput, TrimmedToolResultMarker)); got > 300 {
+		t.Errorf("tool result tokens = %d; want <= 300 (30%% of budget)", got)
+	}
+
+	if tp, _ := result[3][0].(TextPrompt); tp.Text != recentPrompt {
+		t.Errorf("recent prompt = %q; want unchanged", tp.Text)
+	}
+	if tr, _ := result[4][0].(TextResult); tr.Text != recentReply {
+		t.Errorf("recent reply = %q; want unchanged", tr.Text)
+	}
+
+	// The caller's slice must not be modified in place.
+	if orig := messageLists[2][0].(ToolFormattedResult); len(orig.ToolOutput) != 2000 {
+		t.Error("original message list was mutated")
+	}
+}
+
+func TestBudgetAllocationTrimsOnRuneBoundary(t *testing.T) {
+	counter := &MockTokenCounter{countFunc: func(text string) int { return len(text) }}
+	manager := New(&MockLLMClient{}, counter, slog.Default(), &Config{
+		TokenBudget: 1000,
+		MaxSize:     100,
+		Budget:      &BudgetAllocation{SystemPercent: 20, RecentTurnsPercent: 50, ToolResultsPercent: 30},
+	})
+
+	// Three-byte runes: most cut points fall inside one
+	for _, size := range []int{301, 302, 400, 1000} {
+		lists := [][]ContentBlock{{ToolFormattedResult{ToolOutput: strings.Repeat("€", size)}}}
+		out := manager.trimToolResults(lists)[0][0].(ToolFormattedResult).ToolOutput
+		if !utf8.ValidString(out) {
+			t.Errorf("trimmed output of %d runes is not valid UTF-8", size)
+		}
+	}
+}
+
+func TestBudgetAllocationReservesSystemShare(t *testing.T) {
+	logger := slog.Default()
+	counter := &MockTokenCounter{countFunc: func(text string) int { return len(text) }}
+	client := &MockLLMClient{}
+
+	manager := New(client, counter, logger, &Config{
+		TokenBudget: 1000,
+		MaxSize:     100,
+		Budget:      &BudgetAllocation{SystemPercent: 20, RecentTurnsPercent: 50, ToolResultsPercent: 30},
+	})
+
+	if got := manager.historyBudget(); got != 800 {
+		t.Errorf("historyBudget() = %d; want 800", got)
+	}
+
+	// A system prompt larger than its share eats into the history budget.
+	manager.SetSystemTokens(400)
+	if got := manager.historyBudget(); got != 600 {
+		t.Errorf("historyBudget() with 400 system tokens = %d; want 600", got)
+	}
+}
+
+func TestBudgetAllocationOver100FallsBackToDefault(t *testing.T) {
+	manager := New(&MockLLMClient{}, &MockTokenCounter{}, slog.Default(), &Config{
+		TokenBudget: 1000,
+		MaxSize:     10,
+		Budget:      &BudgetAllocation{SystemPercent: 60, RecentTurnsPercent: 60, ToolResultsPercent: 60},
+	})
+
+	if *manager.config.Budget != DefaultBudgetAllocation {
+		t.Errorf("Budget = %+v; want default %+v", *manager.config.Budget, DefaultBudgetAllocation)
+	}
+}
+
+func TestGenerateSummaryCachesIdenticalWindows(t *testing.T) {
+	calls := 0
+	client := &MockLLMClient{
+		generateFunc: func(ctx context.Context, messages [][]ContentBlock, maxTokens int, temperature float64) ([]ContentBlock, error) {
+			calls++
+			return []ContentBlock{TextResult{Text: "summary"}}, nil
+		},
+	}
+	manager := New(client, &MockTokenCounter{}, slog.Default(), nil)
+	events := [][]ContentBlock{
+		{TextPrompt{Text: "build the parser"}},
+		{TextResult{Text: "parser built"}},
+	}
+
+	for i := 0; i < 2; i++ {
+		summary, err := manager.generateSummary(context.Background(), events, "No events summarized")
+		if err != nil || summary != "summary" {
+			t.Fatalf("generateSummary() = %q, %v", summary, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("LLM calls = %d; want 1 for identical windows", calls)
+	}
+
+	manager.generateSummary(context.Background(), events, "Conversation Summary: earlier work")
+	if calls != 2 {
+		t.Errorf("LLM calls = %d; want 2 after the previous summary changed", calls)
+	}
+}
+
+func TestGenerateSummaryDoesNotCacheFailures(t *testing.T) {
+	calls := 0
+	client := &MockLLMClient{
+		generateFunc: func(ctx context.Context, messages [][]ContentBlock, maxTokens int, temperature float64) ([]ContentBlock, error) {
+			calls++
+			return nil, context.DeadlineExceeded
+		},
+	}
+	manager := New(client, &MockTokenCounter{}, slog.Default(), nil)
+	events := [][]ContentBlock{{TextPrompt{Text: "hello"}}}
+
+	manager.generateSummary(context.Background(), events, "No events summarized")
+	manager.generateSummary(context.Background(), events, "No events summarized")
+	if calls != 2 {
+		t.Errorf("LLM calls = %d; want failed summaries to be retried", calls)
+	}
+}
+
+func TestSummaryCacheEvictsOldest(t *testing.T) {
+	manager := New(&MockLLMClient{}, &MockTokenCounter{}, slog.Default(), nil)
+	for i := 0; i <= summaryCacheSize; i++ {
+		manager.cacheSummary(strings.Repeat("k", i+1), "s")
+	}
+	if len(manager.summaryCache) != summaryCacheSize {
+		t.Errorf("cache size = %d; want %d", len(manager.summaryCache), summaryCacheSize)
+	}
+	if _, ok := manager.cachedSummary("k"); ok {
+		t.Error("oldest entry should have been evicted")
+	}
+}
+
+func TestCustomSummaryPromptIsSentToClient(t *testing.T) {
+	var sent []string
+	client := &MockLLMClient{
+		generateFunc: func(ctx context.Context, messages [][]ContentBlock, maxTokens int, temperature float64) ([]ContentBlock, error) {
+			sent = append(sent, messages[0][0].(TextPrompt).Text)
+			return []ContentBlock{TextResult{Text: "summary"}}, nil
+		},
+	}
+	custom := "Preserve every API contract verbatim.\n{events}\nSummarize for a backend team."
+	manager := New(client, &MockTokenCounter{}, slog.Default(), &Config{
+		TokenBudget:    DefaultTokenBudget,
+		MaxSize:        DefaultMaxSize,
+		MaxEventLength: DefaultMaxEventLength,
+		SummaryPrompt:  custom,
+	})
+	events := [][]ContentBlock{{TextPrompt{Text: "add GET /users"}}}
+
+	manager.generateSummary(context.Background(), events, "No events summarized")
+	manager.GenerateCompleteConversationSummary(context.Background(), events)
+
+	if len(sent) != 2 {
+		t.Fatalf("LLM calls = %d; want 2", len(sent))
+	}
+	for i, prompt := range sent {
+		if !strings.HasPrefix(prompt, "Preserve every API contract verbatim.\n") ||
+			!strings.HasSuffix(prompt, "\nSummarize for a backend team.") {
+			t.Errorf("prompt %d does not use the custom template:\n%s", i, prompt)
+		}
+		if !strings.Contains(prompt, "add GET /users") {
+			t.Errorf("prompt %d is missing the events:\n%s", i, prompt)
+		}
+		if strings.Contains(prompt, SummaryPromptPlaceholder) {
+			t.Errorf("prompt %d still contains the placeholder", i)
+		}
+	}
+}
+
+func TestInvalidSummaryPromptFallsBackToDefault(t *testing.T) {
+	for _, prompt := range []string{"no placeholder", "{events} twice {events}"} {
+		if err := ValidateSummaryPrompt(prompt); err == nil {
+			t.Errorf("ValidateSummaryPrompt(%q) = nil; want an error", prompt)
+		}
+		manager := New(&MockLLMClient{}, &MockTokenCounter{}, slog.Default(), &Config{MaxSize: 10, SummaryPrompt: prompt})
+		if manager.config.SummaryPrompt != DefaultSummaryPrompt {
+			t.Errorf("SummaryPrompt for %q = %q; want the default", prompt, manager.config.SummaryPrompt)
+		}
+	}
+	if err := ValidateSummaryPrompt(DefaultSummaryPrompt); err != nil {
+		t.Errorf("default prompt is invalid: %v", err)
+	}
+}
+
+func TestTruncationKeepsToolCallsWithTheirResults(t *testing.T) {
+	cfg := &Config{TokenBudget: 100000, MaxSize: 18, MaxEventLength: 1000}
+	manager := New(&MockLLMClient{}, &MockTokenCounter{}, slog.Default(), cfg)
+
+	messageLists := [][]ContentBlock{{TextPrompt{Text: "Build it"}}}
+	for i := 0; i < 13; i++ {
+		messageLists = append(messageLists,
+			[]ContentBlock{ToolCall{ToolInput: map[string]interface{}{"step": i}}},
+			[]ContentBlock{ToolFormattedResult{ToolOutput: "ok"}},
+		)
+	}
+
+	result, err := manager.ApplyTruncationIfNeeded(context.Background(), messageLists)
+	if err != nil {
+		t.Fatalf("ApplyTruncationIfNeeded() error = %v", err)
+	}
+	if len(result) >= len(messageLists) {
+		t.Fatalf("history of %d turns was not truncated", len(messageLists))
+	}
+	for i := 1; i < len(result); i++ {
+		if hasToolResult(result[i]) {
+			if _, ok := result[i-1][0].(ToolCall); !ok {
+				t.Fatalf("turn %d is a tool result after %T; want it kept with its tool call", i, result[i-1][0])
+			}
+		}
+	}
+}
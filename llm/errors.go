@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ==========================================
+// ERRORS
+// ==========================================
+
+// Error kinds returned by the clients. Match them with errors.Is; the
+// *APIError carrying them has the status code and provider message.
+var (
+	ErrRateLimited   = errors.New("rate limited")
+	ErrAuth          = errors.New("authentication failed")
+	ErrContextLength = errors.New("context length exceeded")
+	ErrServer        = errors.New("provider server error")
+	ErrBadRequest    = errors.New("bad request")
+	// ErrNetwork wraps transport failures that never got a response.
+	ErrNetwork = errors.New("network error")
+)
+
+// APIError is an error response from a provider.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	// Kind is one of the error kinds above.
+	Kind error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error %d (%v): %s", e.Provider, e.StatusCode, e.Kind, e.Message)
+}
+
+func (e *APIError) Unwrap() error { return e.Kind }
+
+// IsRetryable reports whether err is transient, so the same request may
+// succeed later.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer) || errors.Is(err, ErrNetwork)
+}
+
+// contextLengthMarkers appear in the messages providers send when the
+// prompt does not fit the model's context window.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"prompt is too long",
+	"exceeds the maximum number of tokens",
+	"input token count",
+	"too many tokens",
+}
+
+// classifyResponse turns an error response into an *APIError, reading
+// the provider's message from the body when it is JSON.
+func classifyResponse(provider string, status int, body []byte) error {
+	message := errorMessage(body)
+	return &APIError{
+		Provider:   provider,
+		StatusCode: status,
+		Message:    message,
+		Kind:       errorKind(status, message),
+	}
+}
+
+func errorKind(status int, message string) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrContextLength
+	case status >= 500:
+		return ErrServer
+	}
+	lower := strings.ToLower(message)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(lower, marker) {
+			return ErrContextLength
+		}
+	}
+	return ErrBadRequest
+}
+
+// errorMessage extracts error.message (and error.code) from the bodies of
+// OpenAI, Anthropic and Gemini, falling back to the raw body.
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error struct {
+			Message string      `json:"message"`
+			Code    interface{} `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil || parsed.Error.Message == "" {
+		return strings.TrimSpace(string(body))
+	}
+	if code, ok := parsed.Error.Code.(string); ok && code != "" {
+		return fmt.Sprintf("%s (%s)", parsed.Error.Message, code)
+	}
+	return parsed.Error.Message
+}
+
+// networkError marks a failed request as ErrNetwork.
+func networkError(provider string, err error) error {
+	return fmt.Errorf("%s request failed: %w: %w", provider, ErrNetwork, err)
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"unauthorized", 401, `{"error":{"message":"Incorrect API key provided","code":"invalid_api_key"}}`, ErrAuth},
+		{"forbidden", 403, `{"error":{"code":403,"message":"API key not valid","status":"PERMISSION_DENIED"}}`, ErrAuth},
+		{"rate limited", 429, `{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`, ErrRateLimited},
+		{"openai context", 400, `{"error":{"message":"This model's maximum context length is 128000 tokens","code":"context_length_exceeded"}}`, ErrContextLength},
+		{"anthropic context", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, ErrContextLength},
+		{"request too large", 413, `request_too_large`, ErrContextLength},
+		{"bad request", 400, `{"error":{"message":"Invalid value for 'temperature'"}}`, ErrBadRequest},
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrServer},
+		{"server error", 500, `upstream failure`, ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyResponse("openai", tt.status, []byte(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("classifyResponse(%d) = %v; want %v", tt.status, err, tt.want)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("classifyResponse(%d) = %#v; want an *APIError with the status", tt.status, err)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct{ body, want string }{
+		{`{"error":{"message":"bad key","code":"invalid_api_key"}}`, "bad key (invalid_api_key)"},
+		{`{"error":{"code":400,"message":"bad field"}}`, "bad field"},
+		{"  plain text\n", "plain text"},
+	}
+	for _, tt := range tests {
+		if got := errorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("errorMessage(%q) = %q; want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAIClientReturnsTypedErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrAuth},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(`{"error":{"message":"nope"}}`))
+		}))
+		client := NewOpenAIClient(LLMConfig{APIType: APITypeOpenAI, Model: "gpt-4o", BaseURL: srv.URL, MaxRetries: 1})
+		_, err := client.Generate([]*Message{{Role: "user", Content: []*ContentBlock{TextBlock("hi")}}}, 16, "", 0, nil, nil, nil, nil)
+		srv.Close()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: Generate() error = %v; want %v", tt.status, err, tt.want)
+		}
+		if IsRetryable(err) != (tt.want == ErrRateLimited) {
+			t.Errorf("status %d: IsRetryable() = %v", tt.status, IsRetryable(err))
+		}
+	}
+
+	client := NewOpenAIClient(LLMConfig{APIType: APITypeOpenAI, Model: "gpt-4o", BaseURL: "http://127.0.0.1:1", MaxRetries: 1})
+	if _, err := client.Generate([]*Message{{Role: "user", Content: []*ContentBlock{TextBlock("hi")}}}, 16, "", 0, nil, nil, nil, nil); !errors.Is(err, ErrNetwork) || !IsRetryable(err) {
+		t.Errorf("unreachable server: Generate() error = %v; want a retryable ErrNetwork", err)
+	}
+}
@@ -56,8 +56,10 @@ type geminiRequest struct {
 	Tools            []interface{}   `json:"tools,omitempty"`
 	SystemInstr      *geminiContent  `json:"systemInstruction,omitempty"`
 	GenerationConfig struct {
-		Temperature     float64 `json:"temperature"`
-		MaxOutputTokens int     `json:"maxOutputTokens"`
+		Temperature     float64  `json:"temperature"`
+		MaxOutputTokens int      `json:"maxOutputTokens"`
+		TopP            *float64 `json:"topP,omitempty"`
+		Seed            *int     `json:"seed,omitempty"`
 	} `json:"generationConfig"`
 }
 
@@ -69,6 +71,7 @@ func (c *GeminiClient) Generate(
 	tools []*ToolParam,
 	toolChoice *ToolChoice,
 	thinkingTokens *int,
+	sampling *Sampling,
 ) (*GenerateResponse, error) {
 
 	// 1. Convert Messages
@@ -128,6 +131,10 @@ func (c *GeminiClient) Generate(
 	}
 	reqBody.GenerationConfig.Temperature = temperature
 	reqBody.GenerationConfig.MaxOutputTokens = maxTokens
+	if sampling != nil {
+		reqBody.GenerationConfig.TopP = sampling.TopP
+		reqBody.GenerationConfig.Seed = sampling.Seed
+	}
 
 	if systemPrompt != "" {
 		reqBody.SystemInstr = &geminiContent{
@@ -147,13 +154,13 @@ func (c *GeminiClient) Generate(
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, networkError("gemini", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Gemini Error %d: %s", resp.StatusCode, string(b))
+		return nil, classifyResponse("gemini", resp.StatusCode, b)
 	}
 
 	// 5. Parse Response
@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrMockExhausted is returned by MockClient.Generate when no scripted
+// responses remain in the queue.
+var ErrMockExhausted = errors.New("mock client: no scripted responses left")
+
+// MockCall records the arguments of a single Generate invocation.
+type MockCall struct {
+	Messages       []*Message
+	MaxTokens      int
+	SystemPrompt   string
+	Temperature    float64
+	Tools          []*ToolParam
+	ToolChoice     *ToolChoice
+	ThinkingTokens *int
+	Sampling       *Sampling
+}
+
+type mockReply struct {
+	resp *GenerateResponse
+	err  error
+}
+
+// MockClient is a deterministic Client for tests. Responses are scripted up
+// front and returned in FIFO order; every call is recorded for inspection.
+type MockClient struct {
+	mu      sync.Mutex
+	replies []mockReply
+	calls   []MockCall
+}
+
+// NewMockClient creates a MockClient pre-loaded with the given responses.
+func NewMockClient(responses ...*GenerateResponse) *MockClient {
+	m := &MockClient{}
+	for _, r := range responses {
+		m.Enqueue(r)
+	}
+	return m
+}
+
+// Enqueue appends a response to the end of the queue.
+func (m *MockClient) Enqueue(resp *GenerateResponse) *MockClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replies = append(m.replies, mockReply{resp: resp})
+	return m
+}
+
+// EnqueueBlocks appends a response made of the given content blocks.
+func (m *MockClient) EnqueueBlocks(blocks ...*ContentBlock) *MockClient {
+	return m.Enqueue(&GenerateResponse{Content: blocks})
+}
+
+// EnqueueError appends an error to the queue; the matching Generate call
+// returns it instead of a response.
+func (m *MockClient) EnqueueError(err error) *MockClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replies = append(m.replies, mockReply{err: err})
+	return m
+}
+
+// Generate records the call and pops the next scripted reply.
+func (m *MockClient) Generate(
+	messages []*Message,
+	maxTokens int,
+	systemPrompt string,
+	temperature float64,
+	tools []*ToolParam,
+	toolChoice *ToolChoice,
+	thinkingTokens *int,
+	sampling *Sampling,
+) (*GenerateResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Copy the message slice so later history mutations don't rewrite the record.
+	recorded := make([]*Message, len(messages))
+	copy(recorded, messages)
+
+	m.calls = append(m.calls, MockCall{
+		Messages:       recorded,
+		MaxTokens:      maxTokens,
+		SystemPrompt:   systemPrompt,
+		Temperature:    temperature,
+		Tools:          tools,
+		ToolChoice:     toolChoice,
+		ThinkingTokens: thinkingTokens,
+		Sampling:       sampling,
+	})
+
+	if len(m.replies) == 0 {
+		return nil, ErrMockExhausted
+	}
+	next := m.replies[0]
+	m.replies = m.replies[1:]
+	return next.resp, next.err
+}
+
+// Calls returns a copy of all recorded Generate invocations.
+func (m *MockClient) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]MockCall, len(m.calls))
+	copy(out, m.calls)
+	return out
+}
+
+// Remaining returns the number of scripted replies not yet consumed.
+func (m *MockClient) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.replies)
+}
+
+// TextBlock builds a text ContentBlock.
+func TextBlock(text string) *ContentBlock {
+	return &ContentBlock{Type: ContentTypeText, Text: text}
+}
+
+// ToolCallBlock builds a tool call ContentBlock.
+func ToolCallBlock(id, name string, input map[string]interface{}) *ContentBlock {
+	return &ContentBlock{
+		Type:       ContentTypeToolCall,
+		ToolCallID: id,
+		ToolName:   name,
+		ToolInput:  input,
+	}
+}
+
+// ThinkingContentBlock builds a thinking ContentBlock.
+func ThinkingContentBlock(thinking string) *ContentBlock {
+	return &ContentBlock{Type: ContentTypeThinking, Thinking: thinking}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMockClientImplementsClient(t *testing.T) {
+	var _ Client = NewMockClient()
+}
+
+func TestMockClientFIFOOrder(t *testing.T) {
+	mock := NewMockClient().
+		EnqueueBlocks(ToolCallBlock("call_1", "bash", map[string]interface{}{"command": "ls"})).
+		EnqueueBlocks(TextBlock("done"))
+
+	first, err := mock.Generate(nil, 100, "", 0, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("first Generate() error = %v", err)
+	}
+	if len(first.Content) != 1 || first.Content[0].Type != ContentTypeToolCall {
+		t.Fatalf("first response = %+v; want a tool call", first.Content)
+	}
+	if first.Content[0].ToolName != "bash" || first.Content[0].ToolInput["command"] != "ls" {
+		t.Errorf("tool call = %+v; want bash(ls)", first.Content[0])
+	}
+
+	second, err := mock.Generate(nil, 100, "", 0, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("second Generate() error = %v", err)
+	}
+	if second.Content[0].Type != ContentTypeText || second.Content[0].Text != "done" {
+		t.Errorf("second response = %+v; want text 'done'", second.Content[0])
+	}
+
+	if mock.Remaining() != 0 {
+		t.Errorf("Remaining() = %d; want 0", mock.Remaining())
+	}
+
+	if _, err := mock.Generate(nil, 100, "", 0, nil, nil, nil, nil); !errors.Is(err, ErrMockExhausted) {
+		t.Errorf("Generate() on empty queue error = %v; want ErrMockExhausted", err)
+	}
+}
+
+func TestMockClientRecordsCalls(t *testing.T) {
+	mock := NewMockClient(&GenerateResponse{Content: []*ContentBlock{TextBlock("hi")}})
+
+	history := NewMessageHistory()
+	history.AddUserPrompt("hello", nil)
+	tools := []*ToolParam{{Name: "bash", Description: "run a command"}}
+	choice := &ToolChoice{Type: "auto"}
+
+	if _, err := mock.Generate(history.GetMessages(), 512, "system", 0.5, tools, choice, nil, nil); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// Mutating history afterwards must not rewrite the recorded call.
+	history.AddAssistantTurn([]*ContentBlock{TextBlock("hi")})
+
+	calls := mock.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("len(Calls()) = %d; want 1", len(calls))
+	}
+	call := calls[0]
+	if len(call.Messages) != 1 || call.Messages[0].Content[0].Text != "hello" {
+		t.Errorf("recorded messages = %+v; want single 'hello' prompt", call.Messages)
+	}
+	if call.MaxTokens != 512 || call.SystemPrompt != "system" || call.Temperature != 0.5 {
+		t.Errorf("recorded params = %d/%q/%v; want 512/system/0.5", call.MaxTokens, call.SystemPrompt, call.Temperature)
+	}
+	if len(call.Tools) != 1 || call.Tools[0].Name != "bash" {
+		t.Errorf("recorded tools = %+v; want [bash]", call.Tools)
+	}
+	if call.ToolChoice == nil || call.ToolChoice.Type != "auto" {
+		t.Errorf("recorded tool choice = %+v; want auto", call.ToolChoice)
+	}
+}
+
+func TestMockClientEnqueueError(t *testing.T) {
+	boom := errors.New("boom")
+	mock := NewMockClient().EnqueueError(boom).EnqueueBlocks(TextBlock("recovered"))
+
+	if _, err := mock.Generate(nil, 0, "", 0, nil, nil, nil, nil); !errors.Is(err, boom) {
+		t.Errorf("Generate() error = %v; want boom", err)
+	}
+	resp, err := mock.Generate(nil, 0, "", 0, nil, nil, nil, nil)
+	if err != nil || resp.Content[0].Text != "recovered" {
+		t.Errorf("Generate() = %+v, %v; want 'recovered'", resp, err)
+	}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import "strings"
+
+// ==========================================
+// MODEL CATALOG
+// ==========================================
+
+// ModelInfo describes what a model supports.
+type ModelInfo struct {
+	ID               string  `json:"id"`
+	Provider         APIType `json:"provider"`
+	ContextWindow    int     `json:"context_window"`
+	SupportsTools    bool    `json:"supports_tools"`
+	SupportsVision   bool    `json:"supports_vision"`
+	SupportsThinking bool    `json:"supports_thinking"`
+}
+
+// ModelLimits is the curated catalog of models offered per provider, in
+// the order they are presented to users.
+var ModelLimits = []ModelInfo{
+	{ID: "claude-opus-4-1", Provider: APITypeAnthropic, ContextWindow: 200000, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "claude-sonnet-4-5", Provider: APITypeAnthropic, ContextWindow: 200000, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "claude-sonnet-4", Provider: APITypeAnthropic, ContextWindow: 200000, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "claude-3-7-sonnet", Provider: APITypeAnthropic, ContextWindow: 200000, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "claude-3-5-sonnet", Provider: APITypeAnthropic, ContextWindow: 200000, SupportsTools: true, SupportsVision: true},
+	{ID: "claude-3-5-haiku", Provider: APITypeAnthropic, ContextWindow: 200000, SupportsTools: true, SupportsVision: true},
+
+	{ID: "gpt-4.1", Provider: APITypeOpenAI, ContextWindow: 1047576, SupportsTools: true, SupportsVision: true},
+	{ID: "gpt-4o", Provider: APITypeOpenAI, ContextWindow: 128000, SupportsTools: true, SupportsVision: true},
+	{ID: "gpt-4o-mini", Provider: APITypeOpenAI, ContextWindow: 128000, SupportsTools: true, SupportsVision: true},
+	{ID: "gpt-4-turbo", Provider: APITypeOpenAI, ContextWindow: 128000, SupportsTools: true, SupportsVision: true},
+	{ID: "o3", Provider: APITypeOpenAI, ContextWindow: 200000, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "o4-mini", Provider: APITypeOpenAI, ContextWindow: 200000, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+
+	{ID: "gemini-2.5-pro", Provider: APITypeGemini, ContextWindow: 1048576, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "gemini-2.5-flash", Provider: APITypeGemini, ContextWindow: 1048576, SupportsTools: true, SupportsVision: true, SupportsThinking: true},
+	{ID: "gemini-2.0-flash", Provider: APITypeGemini, ContextWindow: 1048576, SupportsTools: true, SupportsVision: true},
+}
+
+// Providers lists the supported providers in presentation order.
+var Providers = []APIType{APITypeAnthropic, APITypeOpenAI, APITypeGemini}
+
+// ProviderKeyEnv names the environment variable holding each provider's
+// API key. LLM_API_KEY, when set, applies to whichever provider is in use.
+var ProviderKeyEnv = map[APIType]string{
+	APITypeOpenAI:    "OPENAI_API_KEY",
+	APITypeAnthropic: "ANTHROPIC_API_KEY",
+	APITypeGemini:    "GEMINI_API_KEY",
+}
+
+// ProviderForModel infers the provider serving a model from its name,
+// defaulting to OpenAI.
+func ProviderForModel(model string) APIType {
+	switch {
+	case strings.Contains(model, "claude") || strings.Contains(model, "anthropic"):
+		return APITypeAnthropic
+	case strings.Contains(model, "gemini"):
+		return APITypeGemini
+	}
+	return APITypeOpenAI
+}
+
+// ModelsForProvider returns the catalog entries for provider.
+func ModelsForProvider(provider APIType) []ModelInfo {
+	var models []ModelInfo
+	for _, m := range ModelLimits {
+		if m.Provider == provider {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// LookupModel returns the catalog entry for model. Dated or suffixed
+// names such as "claude-3-5-sonnet-20241022" match the longest catalog ID
+// they start with.
+func LookupModel(model string) (ModelInfo, bool) {
+	var best ModelInfo
+	found := false
+	for _, m := range ModelLimits {
+		if model == m.ID {
+			return m, true
+		}
+		if strings.HasPrefix(model, m.ID+"-") && len(m.ID) > len(best.ID) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
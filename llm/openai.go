@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -59,6 +59,8 @@ type oaRequest struct {
 	Messages    []oaMessage `json:"messages"`
 	MaxTokens   int         `json:"max_tokens,omitempty"`
 	Temperature float64     `json:"temperature"`
+	TopP        *float64    `json:"top_p,omitempty"`
+	Seed        *int        `json:"seed,omitempty"`
 	Tools       []oaToolDef `json:"tools,omitempty"`
 	ToolChoice  interface{} `json:"tool_choice,omitempty"`
 }
@@ -76,6 +78,7 @@ func (c *OpenAIClient) Generate(
 	tools []*ToolParam,
 	toolChoice *ToolChoice,
 	thinkingTokens *int,
+	sampling *Sampling,
 ) (*GenerateResponse, error) {
 
 	// 1. Prepare Messages
@@ -188,6 +191,10 @@ func (c *OpenAIClient) Generate(
 		Messages:    oaMsgs,
 		Temperature: temperature,
 	}
+	if sampling != nil {
+		reqBody.TopP = sampling.TopP
+		reqBody.Seed = sampling.Seed
+	}
 	
 	if c.config.CotModel {
 		// O1 models don't support temperature/max_tokens in the standard way mostly
@@ -235,13 +242,13 @@ func (c *OpenAIClient) Generate(
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, networkError("openai", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error: %d - %s", resp.StatusCode, string(body))
+		return nil, classifyResponse("openai", resp.StatusCode, body)
 	}
 
 	// 5. Parse Response
@@ -277,18 +284,20 @@ func (c *OpenAIClient) Generate(
 
 	// Tool Calls
 	for _, tc := range oaRespMsg.ToolCalls {
-		var args map[string]interface{}
-		// OpenAI returns stringified JSON for arguments
-		if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
-			log.Printf("Error unmarshaling tool args: %v", err)
-			continue
-		}
-		blocks = append(blocks, &ContentBlock{
+		block := &ContentBlock{
 			Type:       ContentTypeToolCall,
 			ToolCallID: tc.ID,
 			ToolName:   tc.Function.Name,
-			ToolInput:  args,
-		})
+		}
+		// OpenAI returns stringified JSON for arguments. A call whose
+		// arguments do not parse is kept so the agent can answer it.
+		args, err := parseToolArguments(tc.Function.Arguments)
+		if err != nil {
+			block.ToolInputError = err.Error()
+			args = map[string]interface{}{}
+		}
+		block.ToolInput = args
+		blocks = append(blocks, block)
 	}
 
 	return &GenerateResponse{
@@ -299,4 +308,16 @@ func (c *OpenAIClient) Generate(
 			RawResponse:  result,
 		},
 	}, nil
-}
\ No newline at end of file
+}
+// parseToolArguments decodes the stringified JSON arguments of a tool call.
+// Empty arguments are an empty object.
+func parseToolArguments(raw string) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(raw) == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return nil, fmt.Errorf("arguments are not valid JSON (%v): %q", err, raw)
+	}
+	return args, nil
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureOpenAIRequest runs one Generate call against a fake OpenAI server
+// and returns the decoded request body.
+func captureOpenAIRequest(t *testing.T, sampling *Sampling) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(LLMConfig{APIType: APITypeOpenAI, Model: "gpt-4o", BaseURL: srv.URL, MaxRetries: 1})
+	messages := []*Message{{Role: "user", Content: []*ContentBlock{TextBlock("hi")}}}
+	if _, err := client.Generate(messages, 16, "", 0.2, nil, nil, nil, sampling); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return body
+}
+
+func TestOpenAIRequestSampling(t *testing.T) {
+	topP, seed := 0.9, 42
+	body := captureOpenAIRequest(t, &Sampling{TopP: &topP, Seed: &seed})
+	if body["top_p"] != 0.9 {
+		t.Errorf("top_p = %v; want 0.9", body["top_p"])
+	}
+	if body["seed"] != float64(42) {
+		t.Errorf("seed = %v; want 42", body["seed"])
+	}
+
+	body = captureOpenAIRequest(t, nil)
+	for _, key := range []string{"top_p", "seed"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("%s sent without a sampling override", key)
+		}
+	}
+}
+
+func TestOpenAIToolCallArguments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[
+			{"id":"c1","type":"function","function":{"name":"ls","arguments":"{\"dir\":\".\"}"}},
+			{"id":"c2","type":"function","function":{"name":"ls","arguments":""}},
+			{"id":"c3","type":"function","function":{"name":"ls","arguments":"{\"dir\":"}}
+		]}}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(LLMConfig{APIType: APITypeOpenAI, Model: "gpt-4o", BaseURL: srv.URL, MaxRetries: 1})
+	messages := []*Message{{Role: "user", Content: []*ContentBlock{TextBlock("hi")}}}
+	resp, err := client.Generate(messages, 16, "", 0.2, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(resp.Content) != 3 {
+		t.Fatalf("blocks = %d; want all 3 tool calls kept", len(resp.Content))
+	}
+
+	tests := []struct {
+		id        string
+		wantArgs  int
+		wantError bool
+	}{
+		{"c1", 1, false},
+		{"c2", 0, false},
+		{"c3", 0, true},
+	}
+	for i, tt := range tests {
+		block := resp.Content[i]
+		if block.ToolCallID != tt.id || block.Type != ContentTypeToolCall {
+			t.Errorf("block %d = %+v; want tool call %s", i, block, tt.id)
+			continue
+		}
+		if block.ToolInput == nil || len(block.ToolInput) != tt.wantArgs {
+			t.Errorf("%s: ToolInput = %v; want %d arguments", tt.id, block.ToolInput, tt.wantArgs)
+		}
+		if got := block.ToolInputError != ""; got != tt.wantError {
+			t.Errorf("%s: ToolInputError = %q; want error marked %v", tt.id, block.ToolInputError, tt.wantError)
+		}
+	}
+	if !strings.Contains(resp.Content[2].ToolInputError, "not valid JSON") {
+		t.Errorf("ToolInputError = %q; want it to say the JSON is invalid", resp.Content[2].ToolInputError)
+	}
+}
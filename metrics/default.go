@@ -0,0 +1,37 @@
+package metrics
+
+// Default holds the process-wide metrics below.
+var Default = NewRegistry()
+
+var (
+	// HTTPRequests counts gateway HTTP requests by route template, method
+	// and status code.
+	HTTPRequests = NewCounterVec("water_http_requests_total",
+		"HTTP requests handled by the gateway.", "route", "method", "status")
+
+	// AgentTurns counts model turns, by the component that ran them.
+	AgentTurns = NewCounterVec("water_agent_turns_total",
+		"Agent turns executed.", "component")
+
+	// LLMCallDuration times LLM calls, by the component that made them.
+	LLMCallDuration = NewHistogramVec("water_llm_call_duration_seconds",
+		"Duration of LLM calls in seconds.", DefaultBuckets, "component")
+
+	// LLMTokens counts LLM tokens, by component and direction ("input" or
+	// "output").
+	LLMTokens = NewCounterVec("water_llm_tokens_total",
+		"LLM tokens consumed.", "component", "direction")
+)
+
+func init() {
+	Default.Register(HTTPRequests)
+	Default.Register(AgentTurns)
+	Default.Register(LLMCallDuration)
+	Default.Register(LLMTokens)
+}
+
+// RecordTokens adds a call's token usage to LLMTokens.
+func RecordTokens(component string, input, output int) {
+	LLMTokens.Add(float64(input), component, "input")
+	LLMTokens.Add(float64(output), component, "output")
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounterVecText(t *testing.T) {
+	c := NewCounterVec("requests_total", "Requests.", "route", "status")
+	c.Inc("/b", "200")
+	c.Add(2, "/a", "500")
+	c.Inc("/a", "500")
+	c.Add(-1, "/a", "500") // counters never go down
+	c.Inc(`/q"x\`, "200")
+
+	var b strings.Builder
+	if err := c.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a",status="500"} 3
+requests_total{route="/b",status="200"} 1
+requests_total{route="/q\"x\\",status="200"} 1
+`
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+	if got := c.Value("/a", "500"); got != 3 {
+		t.Errorf("Value() = %v; want 3", got)
+	}
+}
+
+func TestHistogramVecText(t *testing.T) {
+	h := NewHistogramVec("latency_seconds", "Latency.", []float64{0.5, 1}, "component")
+	h.Observe(0.2, "chat")
+	h.Observe(0.5, "chat")
+	h.Observe(3, "chat")
+
+	var b strings.Builder
+	h.WriteText(&b)
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{component="chat",le="0.5"} 2
+latency_seconds_bucket{component="chat",le="1"} 2
+latency_seconds_bucket{component="chat",le="+Inf"} 3
+latency_seconds_sum{component="chat"} 3.7
+latency_seconds_count{component="chat"} 3
+`
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := NewCounterVec("turns_total", "Turns.")
+	c.Inc()
+	r.Register(c)
+
+	var b strings.Builder
+	r.WriteText(&b, NewGaugeFunc("sessions", "Sessions.", func() float64 { return 2 }))
+	want := "# HELP turns_total Turns.\n# TYPE turns_total counter\nturns_total 1\n" +
+		"# HELP sessions Sessions.\n# TYPE sessions gauge\nsessions 2\n"
+	if b.String() != want {
+		t.Errorf("WriteText() = %q; want %q", b.String(), want)
+	}
+}
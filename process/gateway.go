@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"water-ai/core"
+	"water-ai/core/config"
 	"water-ai/server"
 )
 
@@ -50,6 +51,10 @@ func (g *Gateway) Start(ctx context.Context) error {
 	serverConfig := server.Config{
 		WorkspaceRoot: os.Getenv("WORKSPACE_ROOT"),
 		Port:          g.config.Port,
+		APIKey:        os.Getenv("WATER_API_KEY"),
+		EnableMetrics: os.Getenv("WATER_METRICS") == "true",
+		MinimizeLogs:  os.Getenv("MINIMIZE_STDOUT_LOGS") == "true",
+		LLM:           config.LLMConfig{MaxRetries: 3},
 	}
 
 	// Create the server
@@ -153,6 +158,9 @@ func (g *Gateway) shutdown() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
+		if err := g.server.WSManager.Shutdown(ctx); err != nil {
+			g.logger.Warn("websocket sessions did not drain in time", "error", err)
+		}
 		if err := g.httpServer.Shutdown(ctx); err != nil {
 			g.logger.Error("error shutting down http server", "error", err)
 		}
@@ -2,13 +2,13 @@ package process
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
 	"sync"
-	"syscall"
 	"time"
 
 	"water-ai/core"
@@ -39,6 +39,30 @@ type Manager struct {
 	doneChan     chan struct{}
 	restartCount int
 	restartMu    sync.Mutex
+
+	// Supervisor outcome, guarded by restartMu
+	totalRestarts     int
+	lastRestartReason string
+	gaveUp            bool
+	failure           error
+
+	// launch starts the gateway; replaced in tests
+	launch func(ctx context.Context) error
+}
+
+// ErrRestartLimitReached is recorded when the gateway failed its health
+// check MaxRestartAttempts times in a row and the manager stopped restarting it.
+var ErrRestartLimitReached = errors.New("gateway restart limit reached")
+
+// ManagerStatus is a snapshot of the supervisor state
+type ManagerStatus struct {
+	Running           bool      `json:"running"`
+	PID               int       `json:"pid"`
+	LastHealthCheck   time.Time `json:"last_health_check"` // last successful check
+	RestartCount      int       `json:"restart_count"`     // consecutive restarts since the last healthy check
+	TotalRestarts     int       `json:"total_restarts"`
+	LastRestartReason string    `json:"last_restart_reason,omitempty"`
+	GaveUp            bool      `json:"gave_up"`
 }
 
 // NewManager creates a new process manager
@@ -56,7 +80,7 @@ func NewManager(cfg ManagerConfig) *Manager {
 		cfg.MaxRestartDelay = 30 * time.Second
 	}
 
-	return &Manager{
+	m := &Manager{
 		config: cfg,
 		logger: core.Logger.With("component", "process_manager"),
 		httpClient: &http.Client{
@@ -65,6 +89,8 @@ func NewManager(cfg ManagerConfig) *Manager {
 		stopChan: make(chan struct{}),
 		doneChan: make(chan struct{}),
 	}
+	m.launch = m.startGateway
+	return m
 }
 
 // Start begins the gateway process and starts monitoring
@@ -74,7 +100,7 @@ func (m *Manager) Start(ctx context.Context) error {
 		"frontend_port", m.config.FrontendPort)
 
 	// Start the gateway process
-	if err := m.startGateway(ctx); err != nil {
+	if err := m.launch(ctx); err != nil {
 		return fmt.Errorf("failed to start gateway: %w", err)
 	}
 
@@ -90,16 +116,17 @@ func (m *Manager) startGateway(ctx context.Context) error {
 	m.cmdMu.Lock()
 	defer m.cmdMu.Unlock()
 
-	// Create the command - run the same binary with gateway mode
-	m.cmd = exec.CommandContext(ctx, os.Args[0], "gateway",
+	// Create the command - run the configured binary (or ourselves) in gateway mode
+	binary := m.config.GatewayPath
+	if binary == "" {
+		binary = os.Args[0]
+	}
+	m.cmd = exec.CommandContext(ctx, binary, "gateway",
 		"--port", m.config.GatewayPort,
 	)
 
 	// Set process group so we can terminate all child processes
-	m.cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-		Pgid:   0,
-	}
+	setProcessGroup(m.cmd)
 
 	// Capture output
 	m.cmd.Stdout = os.Stdout
@@ -134,9 +161,29 @@ func (m *Manager) healthCheckLoop(ctx context.Context) {
 			close(m.doneChan)
 			return
 		default:
-			if !m.checkHealth() {
+			if m.checkHealth() {
+				m.restartMu.Lock()
+				m.restartCount = 0
+				m.restartMu.Unlock()
+			} else {
+				m.restartMu.Lock()
+				exhausted := m.restartCount >= m.config.MaxRestartAttempts
+				if exhausted {
+					m.gaveUp = true
+					m.failure = fmt.Errorf("%w after %d attempts: %s",
+						ErrRestartLimitReached, m.restartCount, m.lastRestartReason)
+				}
+				m.restartMu.Unlock()
+
+				if exhausted {
+					m.logger.Error("gateway kept failing health checks, giving up",
+						"attempts", m.config.MaxRestartAttempts)
+					close(m.doneChan)
+					return
+				}
+
 				m.logger.Warn("gateway health check failed, attempting restart")
-				if err := m.restartGateway(ctx); err != nil {
+				if err := m.restartGateway(ctx, "health check failed"); err != nil {
 					m.logger.Error("failed to restart gateway", "error", err)
 				}
 			}
@@ -160,7 +207,7 @@ func (m *Manager) checkHealth() bool {
 	cmd := m.cmd
 	m.cmdMu.RUnlock()
 
-	if cmd.Process == nil {
+	if cmd == nil || cmd.Process == nil {
 		return false
 	}
 
@@ -178,30 +225,31 @@ func (m *Manager) checkHealth() bool {
 		return false
 	}
 
+	m.statusMu.Lock()
 	m.lastCheck = time.Now()
+	m.statusMu.Unlock()
 	return true
 }
 
-// restartGateway attempts to restart the gateway process with exponential backoff
-func (m *Manager) restartGateway(ctx context.Context) error {
+// restartGateway attempts to restart the gateway process with increasing backoff.
+// The attempt counter is only reset by a successful health check.
+func (m *Manager) restartGateway(ctx context.Context, reason string) error {
 	m.restartMu.Lock()
 	m.restartCount++
-	m.restartMu.Unlock()
+	m.totalRestarts++
+	attempt := m.restartCount
+	m.lastRestartReason = reason
 
-	// Calculate exponential backoff delay
-	m.restartMu.Lock()
-	delay := time.Duration(m.restartCount) * m.config.RestartDelay
+	// Calculate backoff delay
+	delay := time.Duration(attempt) * m.config.RestartDelay
 	if delay > m.config.MaxRestartDelay {
 		delay = m.config.MaxRestartDelay
 	}
-	// Reset restart count after max delay
-	if m.restartCount >= m.config.MaxRestartAttempts {
-		m.restartCount = 0
-	}
 	m.restartMu.Unlock()
 
 	m.logger.Info("attempting to restart gateway",
-		"attempt", m.restartCount,
+		"attempt", attempt,
+		"reason", reason,
 		"delay", delay.String())
 
 	// Stop the current process
@@ -213,7 +261,7 @@ func (m *Manager) restartGateway(ctx context.Context) error {
 	time.Sleep(delay)
 
 	// Start a new process
-	return m.startGateway(ctx)
+	return m.launch(ctx)
 }
 
 // stopGateway gracefully stops the gateway process
@@ -232,9 +280,9 @@ func (m *Manager) stopGateway() error {
 	m.isRunning = false
 	m.statusMu.Unlock()
 
-	// Send SIGTERM
-	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
-		m.logger.Warn("failed to send SIGTERM, sending SIGKILL", "error", err)
+	// Ask the process to exit (SIGTERM on Unix, taskkill on Windows)
+	if err := terminate(cmd); err != nil {
+		m.logger.Warn("graceful terminate failed, killing process", "error", err)
 		// Force kill if SIGTERM fails
 		if err := cmd.Process.Kill(); err != nil {
 			return fmt.Errorf("failed to kill process: %w", err)
@@ -276,6 +324,43 @@ func (m *Manager) Stop() error {
 	return m.stopGateway()
 }
 
+// Done is closed when the health check loop exits, either because the
+// manager was stopped or because it gave up restarting the gateway.
+func (m *Manager) Done() <-chan struct{} {
+	return m.doneChan
+}
+
+// Err returns the terminal failure recorded when the manager gave up, or nil.
+func (m *Manager) Err() error {
+	m.restartMu.Lock()
+	defer m.restartMu.Unlock()
+	return m.failure
+}
+
+// Status returns a snapshot of the supervisor state
+func (m *Manager) Status() ManagerStatus {
+	var status ManagerStatus
+
+	m.cmdMu.RLock()
+	if m.cmd != nil && m.cmd.Process != nil {
+		status.PID = m.cmd.Process.Pid
+	}
+	m.cmdMu.RUnlock()
+
+	m.statusMu.RLock()
+	status.Running = m.isRunning
+	status.LastHealthCheck = m.lastCheck
+	m.statusMu.RUnlock()
+
+	m.restartMu.Lock()
+	defer m.restartMu.Unlock()
+	status.RestartCount = m.restartCount
+	status.TotalRestarts = m.totalRestarts
+	status.LastRestartReason = m.lastRestartReason
+	status.GaveUp = m.gaveUp
+	return status
+}
+
 // IsRunning returns whether the gateway is currently running
 func (m *Manager) IsRunning() bool {
 	m.statusMu.RLock()
@@ -1,6 +1,11 @@
 package process
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -113,3 +118,127 @@ func TestManagerConfigZeroValues(t *testing.T) {
 		t.Errorf("HealthInterval = %v; want 0", cfg.HealthInterval)
 	}
 }
+
+// TestMain lets the test binary double as a long-running child process:
+// with WATER_PROCESS_HELPER=1 it just sleeps instead of running tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("WATER_PROCESS_HELPER") == "1" {
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestStopGatewayTerminatesProcessOnHostOS(t *testing.T) {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "WATER_PROCESS_HELPER=1")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start helper process: %v", err)
+	}
+
+	manager := NewManager(ManagerConfig{GatewayPort: "0"})
+	manager.cmd = cmd
+	manager.isRunning = true
+
+	start := time.Now()
+	if err := manager.stopGateway(); err != nil {
+		t.Fatalf("stopGateway() error = %v", err)
+	}
+
+	if manager.IsRunning() {
+		t.Error("IsRunning() should be false after stopGateway")
+	}
+	if cmd.ProcessState == nil {
+		t.Error("helper process should have been reaped")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("stopGateway took %v; graceful terminate should not hit the kill timeout", elapsed)
+	}
+}
+
+func TestStopGatewayWithoutProcess(t *testing.T) {
+	manager := NewManager(ManagerConfig{GatewayPort: "0"})
+	if err := manager.stopGateway(); err != nil {
+		t.Errorf("stopGateway() with no process error = %v; want nil", err)
+	}
+}
+
+func TestManagerGivesUpAfterMaxRestartAttempts(t *testing.T) {
+	manager := NewManager(ManagerConfig{
+		GatewayPort:        "1", // nothing listens here, so every health check fails
+		HealthInterval:     time.Millisecond,
+		MaxRestartAttempts: 3,
+		RestartDelay:       time.Millisecond,
+		MaxRestartDelay:    time.Millisecond,
+	})
+
+	var launches int32
+	manager.launch = func(ctx context.Context) error {
+		atomic.AddInt32(&launches, 1)
+		return nil
+	}
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	select {
+	case <-manager.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager did not give up after repeated health failures")
+	}
+
+	// One initial launch plus exactly MaxRestartAttempts restarts.
+	if got := atomic.LoadInt32(&launches); got != 4 {
+		t.Errorf("launches = %d; want 4", got)
+	}
+	if !errors.Is(manager.Err(), ErrRestartLimitReached) {
+		t.Errorf("Err() = %v; want ErrRestartLimitReached", manager.Err())
+	}
+
+	status := manager.Status()
+	if !status.GaveUp {
+		t.Error("Status().GaveUp should be true")
+	}
+	if status.RestartCount != 3 {
+		t.Errorf("Status().RestartCount = %d; want 3", status.RestartCount)
+	}
+	if status.TotalRestarts != 3 {
+		t.Errorf("Status().TotalRestarts = %d; want 3", status.TotalRestarts)
+	}
+	if status.LastRestartReason != "health check failed" {
+		t.Errorf("Status().LastRestartReason = %q", status.LastRestartReason)
+	}
+}
+
+func TestManagerStatusInitial(t *testing.T) {
+	status := NewManager(ManagerConfig{}).Status()
+	if status.Running || status.GaveUp || status.RestartCount != 0 {
+		t.Errorf("initial Status() = %+v; want zero state", status)
+	}
+}
+
+func TestStatusAfterStartGateway(t *testing.T) {
+	t.Setenv("WATER_PROCESS_HELPER", "1")
+
+	manager := NewManager(ManagerConfig{GatewayPort: "0", GatewayPath: os.Args[0]})
+	if err := manager.startGateway(context.Background()); err != nil {
+		t.Fatalf("startGateway() error = %v", err)
+	}
+	defer manager.stopGateway()
+
+	status := manager.Status()
+	if !status.Running {
+		t.Error("Status().Running should be true after startGateway")
+	}
+	if status.PID <= 0 {
+		t.Errorf("Status().PID = %d; want a valid PID", status.PID)
+	}
+	if status.TotalRestarts != 0 || status.GaveUp {
+		t.Errorf("Status() = %+v; want no restarts", status)
+	}
+	if !status.LastHealthCheck.IsZero() {
+		t.Error("LastHealthCheck should be zero before any successful check")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup places the child in its own process group so we can
+// terminate it together with any processes it spawns.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+		Pgid:    0,
+	}
+}
+
+// terminate asks the process to shut down gracefully with SIGTERM.
+func terminate(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package process
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the child in a new process group so console
+// control events and taskkill /T reach it and its descendants.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP,
+	}
+}
+
+// terminate asks the process tree to close via taskkill (without /F). The
+// caller falls back to Process.Kill if this fails.
+func terminate(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
@@ -0,0 +1,307 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+
+	"water-ai/utils"
+)
+
+const (
+	// DefaultDockerImage ships code-server, which the workspace runs as
+	// the container's main process.
+	DefaultDockerImage = "codercom/code-server:latest"
+	// DefaultDockerWorkDir is where the session workspace is mounted.
+	DefaultDockerWorkDir = "/workspace"
+)
+
+// DockerWorkspace is a session workspace inside a Docker container. The
+// host workspace directory is bind-mounted into the container, so file
+// tools work on the host copy while commands run in the container.
+type DockerWorkspace struct {
+	Session string
+	Image   string
+	// HostDir is the session workspace on the host, mounted at WorkDir.
+	HostDir string
+	WorkDir string
+	// CodeServerPort is the port code-server listens on in the container;
+	// it is published on a free host port.
+	CodeServerPort int
+	// Command is the container's main process; it defaults to code-server
+	// serving WorkDir.
+	Command []string
+	Memory  int64
+	CPUs    float64
+	Client  *client.Client
+	// Store records the container ID so a resumed session reattaches to
+	// it; nil keeps the ID in memory only.
+	Store SandboxIDStore
+
+	mu          sync.Mutex
+	containerID string
+	hostPort    string
+}
+
+// NewDockerWorkspace returns a workspace for sessionID that mounts hostDir
+// and serves code-server on codeServerPort. The image and resource limits
+// come from settings.
+func NewDockerWorkspace(sessionID, hostDir string, settings *Settings, codeServerPort int, store SandboxIDStore) (*DockerWorkspace, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("connecting to docker: %w", err)
+	}
+	img := settings.SandboxConfig.Image
+	if img == "" {
+		img = DefaultDockerImage
+	}
+	return &DockerWorkspace{
+		Session:        sessionID,
+		Image:          img,
+		HostDir:        hostDir,
+		WorkDir:        DefaultDockerWorkDir,
+		CodeServerPort: codeServerPort,
+		Memory:         settings.SandboxConfig.MemoryLimit,
+		CPUs:           settings.SandboxConfig.CPULimit,
+		Client:         cli,
+		Store:          store,
+	}, nil
+}
+
+func (w *DockerWorkspace) command() []string {
+	if len(w.Command) > 0 {
+		return w.Command
+	}
+	return []string{"code-server", "--bind-addr", fmt.Sprintf("0.0.0.0:%d", w.CodeServerPort), "--auth", "none", w.WorkDir}
+}
+
+// Start attaches the workspace to a running container: the one stored for
+// the session if it still exists, otherwise a new one.
+func (w *DockerWorkspace) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.containerID
+	if id == "" {
+		id = w.storedContainerID()
+	}
+	if id != "" {
+		err := w.attach(ctx, id)
+		if err == nil {
+			return nil
+		}
+		if !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("reattaching to container %s: %w", id, err)
+		}
+	}
+	return w.create(ctx)
+}
+
+func (w *DockerWorkspace) storedContainerID() string {
+	if w.Store == nil {
+		return ""
+	}
+	sid, err := uuid.Parse(w.Session)
+	if err != nil {
+		return ""
+	}
+	id, err := w.Store.GetSandboxIDBySessionID(sid)
+	if err != nil || id == nil {
+		return ""
+	}
+	return *id
+}
+
+// create starts a new container and records its ID. w.mu must be held.
+func (w *DockerWorkspace) create(ctx context.Context) error {
+	port := nat.Port(fmt.Sprintf("%d/tcp", w.CodeServerPort))
+	cfg := &container.Config{
+		Image:        w.Image,
+		Entrypoint:   w.command(),
+		WorkingDir:   w.WorkDir,
+		ExposedPorts: nat.PortSet{port: struct{}{}},
+		Labels:       map[string]string{"water-ai.session": w.Session},
+	}
+	hostCfg := &container.HostConfig{
+		Binds:        []string{w.HostDir + ":" + w.WorkDir},
+		PortBindings: nat.PortMap{port: {{HostIP: "127.0.0.1"}}},
+		Resources: container.Resources{
+			Memory:   w.Memory,
+			NanoCPUs: int64(w.CPUs * 1e9),
+		},
+	}
+
+	resp, err := w.Client.ContainerCreate(ctx, cfg, hostCfg, nil, nil, "")
+	if cerrdefs.IsNotFound(err) {
+		if err = w.pullImage(ctx); err == nil {
+			resp, err = w.Client.ContainerCreate(ctx, cfg, hostCfg, nil, nil, "")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("creating container: %w", err)
+	}
+	if err := w.attach(ctx, resp.ID); err != nil {
+		w.Client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return err
+	}
+	if w.Store != nil {
+		if sid, err := uuid.Parse(w.Session); err == nil {
+			if err := w.Store.UpdateSessionSandboxID(sid, resp.ID); err != nil {
+				return fmt.Errorf("saving container ID: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *DockerWorkspace) pullImage(ctx context.Context) error {
+	out, err := w.Client.ImagePull(ctx, w.Image, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("pulling %s: %w", w.Image, err)
+	}
+	defer out.Close()
+	_, err = io.Copy(io.Discard, out)
+	return err
+}
+
+// attach starts container id if it is stopped and records it along with
+// its published code-server port. w.mu must be held.
+func (w *DockerWorkspace) attach(ctx context.Context, id string) error {
+	info, err := w.Client.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	if info.State == nil || !info.State.Running {
+		if err := w.Client.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+			return fmt.Errorf("starting container: %w", err)
+		}
+		if info, err = w.Client.ContainerInspect(ctx, id); err != nil {
+			return err
+		}
+	}
+	w.containerID = id
+	w.hostPort = ""
+	if info.NetworkSettings != nil {
+		port := nat.Port(fmt.Sprintf("%d/tcp", w.CodeServerPort))
+		if bindings := info.NetworkSettings.Ports[port]; len(bindings) > 0 {
+			w.hostPort = bindings[0].HostPort
+		}
+	}
+	return nil
+}
+
+// ContainerID returns the ID of the current container, or "" before Start.
+func (w *DockerWorkspace) ContainerID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.containerID
+}
+
+// CodeServerURL returns the host URL of the container's code-server, or
+// "" when its port is not published.
+func (w *DockerWorkspace) CodeServerURL() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.hostPort == "" {
+		return ""
+	}
+	return "http://localhost:" + w.hostPort
+}
+
+// Execute runs command in the workspace directory of the container.
+func (w *DockerWorkspace) Execute(ctx context.Context, command string) utils.SessionResult {
+	id := w.ContainerID()
+	if id == "" {
+		return utils.SessionResult{Success: false, Output: "container is not running"}
+	}
+	exec, err := w.Client.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", command},
+		WorkingDir:   w.WorkDir,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return utils.SessionResult{Success: false, Output: err.Error()}
+	}
+	attached, err := w.Client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return utils.SessionResult{Success: false, Output: err.Error()}
+	}
+	defer attached.Close()
+
+	var out bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &out, attached.Reader); err != nil {
+		return utils.SessionResult{Success: false, Output: out.String() + err.Error()}
+	}
+	inspect, err := w.Client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return utils.SessionResult{Success: false, Output: out.String() + err.Error()}
+	}
+	if inspect.ExitCode != 0 {
+		return utils.SessionResult{Success: false, Output: out.String() + "exit status " + strconv.Itoa(inspect.ExitCode)}
+	}
+	return utils.SessionResult{Success: true, Output: out.String()}
+}
+
+// ResolvePath returns the host path for a file tool's path argument, which
+// the bind mount makes visible in the container. Absolute container paths
+// under WorkDir are accepted; anything else outside it is rejected.
+func (w *DockerWorkspace) ResolvePath(p string) (string, error) {
+	if path.IsAbs(p) {
+		rel := strings.TrimPrefix(path.Clean(p), w.WorkDir)
+		if rel == p || (rel != "" && !strings.HasPrefix(rel, "/")) {
+			return "", fmt.Errorf("%w: %s", utils.ErrOutsideWorkspace, p)
+		}
+		p = strings.TrimPrefix(rel, "/")
+	}
+	return utils.ResolveInRoot(w.HostDir, p)
+}
+
+// Stop removes the container.
+func (w *DockerWorkspace) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.containerID == "" {
+		return nil
+	}
+	err := w.Client.ContainerRemove(ctx, w.containerID, container.RemoveOptions{Force: true})
+	if err != nil && !cerrdefs.IsNotFound(err) {
+		return err
+	}
+	w.containerID, w.hostPort = "", ""
+	return nil
+}
+
+// WorkspacePath returns the absolute container path of p.
+func (w *DockerWorkspace) WorkspacePath(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(w.WorkDir, p)
+}
+
+// RelativePath returns p relative to the workspace directory when it lies
+// inside it.
+func (w *DockerWorkspace) RelativePath(p string) string {
+	abs := w.WorkspacePath(p)
+	if rel := strings.TrimPrefix(abs, w.WorkDir+"/"); rel != abs {
+		return rel
+	}
+	return abs
+}
+
+// SessionID returns the session the workspace belongs to.
+func (w *DockerWorkspace) SessionID() string { return w.Session }
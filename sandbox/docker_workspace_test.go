@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// dockerClient returns a client for the local Docker daemon, skipping the
+// test when none is reachable.
+func dockerClient(t *testing.T) *client.Client {
+	t.Helper()
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("docker unavailable: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(ctx); err != nil {
+		t.Skipf("docker unavailable: %v", err)
+	}
+	return cli
+}
+
+func TestDockerWorkspaceStartExecStop(t *testing.T) {
+	cli := dockerClient(t)
+	ctx := context.Background()
+	hostDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(hostDir, "hello.txt"), []byte("from host"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := &DockerWorkspace{
+		Session:        "docker-test",
+		Image:          "alpine:3",
+		HostDir:        hostDir,
+		WorkDir:        DefaultDockerWorkDir,
+		CodeServerPort: 9000,
+		Command:        []string{"sleep", "300"},
+		Client:         cli,
+	}
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer w.Stop(ctx)
+	if w.ContainerID() == "" {
+		t.Fatal("ContainerID() is empty after Start")
+	}
+
+	res := w.Execute(ctx, "pwd && cat hello.txt")
+	if !res.Success || res.Output != "/workspace\nfrom host" {
+		t.Errorf("Execute() = %+v; want the mounted workspace", res)
+	}
+	if res := w.Execute(ctx, "exit 3"); res.Success || !strings.Contains(res.Output, "exit status 3") {
+		t.Errorf("Execute(exit 3) = %+v; want a failure with the exit status", res)
+	}
+
+	id := w.ContainerID()
+	if err := w.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := cli.ContainerInspect(ctx, id); err == nil {
+		t.Error("container still exists after Stop")
+	}
+}
+
+func TestDockerWorkspaceResolvePath(t *testing.T) {
+	w := &DockerWorkspace{HostDir: "/host/ws", WorkDir: DefaultDockerWorkDir}
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"src/app.go", "/host/ws/src/app.go", false},
+		{"/workspace/src/app.go", "/host/ws/src/app.go", false},
+		{"/workspace-other/x", "", true},
+		{"/etc/passwd", "", true},
+		{"../escape", "", true},
+	}
+	for _, tt := range tests {
+		got, err := w.ResolvePath(tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolvePath(%q) = %q, %v; want %q, error %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
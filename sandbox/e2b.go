@@ -0,0 +1,275 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrSandboxNotFound is returned when an E2B sandbox no longer exists,
+// typically because it expired.
+var ErrSandboxNotFound = errors.New("e2b sandbox not found or expired")
+
+const (
+	defaultE2BAPIURL = "https://api.e2b.dev"
+	defaultE2BDomain = "e2b.app"
+	e2bEnvdPort      = 49983
+	e2bUser          = "user"
+)
+
+// E2BExecResult is the outcome of a command run in an E2B sandbox.
+type E2BExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// E2BClient is the subset of the E2B API the workspace uses. Methods
+// return ErrSandboxNotFound (possibly wrapped) for sandboxes that are gone.
+type E2BClient interface {
+	CreateSandbox(ctx context.Context, templateID string) (string, error)
+	ConnectSandbox(ctx context.Context, sandboxID string) error
+	KillSandbox(ctx context.Context, sandboxID string) error
+	Exec(ctx context.Context, sandboxID, command, cwd string) (E2BExecResult, error)
+	ReadFile(ctx context.Context, sandboxID, path string) ([]byte, error)
+	WriteFile(ctx context.Context, sandboxID, path string, data []byte) error
+}
+
+// E2BHTTPClient talks to the E2B control plane for sandbox lifecycle and
+// to each sandbox's envd daemon for files and processes.
+type E2BHTTPClient struct {
+	APIKey string
+	APIURL string // defaults to https://api.e2b.dev
+	Domain string // sandbox domain, defaults to e2b.app
+	HTTP   *http.Client
+}
+
+// NewE2BClient returns an HTTP client authenticated with apiKey.
+func NewE2BClient(apiKey string) *E2BHTTPClient {
+	return &E2BHTTPClient{
+		APIKey: apiKey,
+		APIURL: defaultE2BAPIURL,
+		Domain: defaultE2BDomain,
+		HTTP:   &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+func (c *E2BHTTPClient) apiRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.APIURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", c.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("e2b: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := e2bStatusError(resp); err != nil {
+		return err
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// e2bStatusError maps a non-2xx response to an error.
+func e2bStatusError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadGateway {
+		return fmt.Errorf("%w: %s", ErrSandboxNotFound, strings.TrimSpace(string(body)))
+	}
+	return fmt.Errorf("e2b: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func (c *E2BHTTPClient) CreateSandbox(ctx context.Context, templateID string) (string, error) {
+	var out struct {
+		SandboxID string `json:"sandboxID"`
+	}
+	if err := c.apiRequest(ctx, http.MethodPost, "/sandboxes", map[string]string{"templateID": templateID}, &out); err != nil {
+		return "", err
+	}
+	if out.SandboxID == "" {
+		return "", errors.New("e2b: create response has no sandbox ID")
+	}
+	return out.SandboxID, nil
+}
+
+func (c *E2BHTTPClient) ConnectSandbox(ctx context.Context, sandboxID string) error {
+	return c.apiRequest(ctx, http.MethodGet, "/sandboxes/"+url.PathEscape(sandboxID), nil, nil)
+}
+
+func (c *E2BHTTPClient) KillSandbox(ctx context.Context, sandboxID string) error {
+	return c.apiRequest(ctx, http.MethodDelete, "/sandboxes/"+url.PathEscape(sandboxID), nil, nil)
+}
+
+// envdURL returns the URL of path on the sandbox's envd daemon.
+func (c *E2BHTTPClient) envdURL(sandboxID, path string) string {
+	return fmt.Sprintf("https://%d-%s.%s%s", e2bEnvdPort, sandboxID, c.Domain, path)
+}
+
+func (c *E2BHTTPClient) envdRequest(ctx context.Context, method, target, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(e2bUser, "")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("e2b: %w", err)
+	}
+	if err := e2bStatusError(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *E2BHTTPClient) ReadFile(ctx context.Context, sandboxID, path string) ([]byte, error) {
+	q := url.Values{"path": {path}, "username": {e2bUser}}
+	resp, err := c.envdRequest(ctx, http.MethodGet, c.envdURL(sandboxID, "/files?"+q.Encode()), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (c *E2BHTTPClient) WriteFile(ctx context.Context, sandboxID, path string, data []byte) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", path)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	q := url.Values{"path": {path}, "username": {e2bUser}}
+	resp, err := c.envdRequest(ctx, http.MethodPost, c.envdURL(sandboxID, "/files?"+q.Encode()), mw.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exec starts command through envd's Connect process service and collects
+// its output from the server stream until the process ends.
+func (c *E2BHTTPClient) Exec(ctx context.Context, sandboxID, command, cwd string) (E2BExecResult, error) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"process": map[string]interface{}{
+			"cmd":  "/bin/bash",
+			"args": []string{"-l", "-c", command},
+			"cwd":  cwd,
+		},
+	})
+	if err != nil {
+		return E2BExecResult{}, err
+	}
+	var frame bytes.Buffer
+	frame.WriteByte(0)
+	binary.Write(&frame, binary.BigEndian, uint32(len(msg)))
+	frame.Write(msg)
+
+	resp, err := c.envdRequest(ctx, http.MethodPost, c.envdURL(sandboxID, "/process.Process/Start"), "application/connect+json", &frame)
+	if err != nil {
+		return E2BExecResult{}, err
+	}
+	defer resp.Body.Close()
+	return readE2BProcessStream(resp.Body)
+}
+
+// readE2BProcessStream decodes the enveloped Connect messages of a process
+// stream. Each envelope is a flag byte, a big-endian length and a JSON
+// message; flag 2 marks the end-of-stream trailer.
+func readE2BProcessStream(r io.Reader) (E2BExecResult, error) {
+	var res E2BExecResult
+	var stdout, stderr strings.Builder
+	br := bufio.NewReader(r)
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF {
+				return res, errors.New("e2b: process stream ended before the process exited")
+			}
+			return res, fmt.Errorf("e2b: %w", err)
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return res, fmt.Errorf("e2b: %w", err)
+		}
+		if header[0]&2 != 0 {
+			var trailer struct {
+				Error *struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			json.Unmarshal(payload, &trailer)
+			if trailer.Error != nil {
+				if trailer.Error.Code == "not_found" {
+					return res, fmt.Errorf("%w: %s", ErrSandboxNotFound, trailer.Error.Message)
+				}
+				return res, fmt.Errorf("e2b: %s: %s", trailer.Error.Code, trailer.Error.Message)
+			}
+			return res, errors.New("e2b: process stream ended before the process exited")
+		}
+
+		var event struct {
+			Event struct {
+				Data *struct {
+					Stdout string `json:"stdout"`
+					Stderr string `json:"stderr"`
+				} `json:"data"`
+				End *struct {
+					ExitCode int    `json:"exitCode"`
+					Error    string `json:"error"`
+				} `json:"end"`
+			} `json:"event"`
+		}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return res, fmt.Errorf("e2b: decoding process event: %w", err)
+		}
+		if d := event.Event.Data; d != nil {
+			out, _ := base64.StdEncoding.DecodeString(d.Stdout)
+			stdout.Write(out)
+			errOut, _ := base64.StdEncoding.DecodeString(d.Stderr)
+			stderr.Write(errOut)
+		}
+		if end := event.Event.End; end != nil {
+			res.Stdout, res.Stderr, res.ExitCode = stdout.String(), stderr.String(), end.ExitCode
+			return res, nil
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeE2BClient is an in-memory E2B API. Sandboxes missing from alive are
+// reported as expired.
+type fakeE2BClient struct {
+	alive   map[string]bool
+	created int
+	execs   []string
+}
+
+func newFakeE2BClient(alive ...string) *fakeE2BClient {
+	c := &fakeE2BClient{alive: map[string]bool{}}
+	for _, id := range alive {
+		c.alive[id] = true
+	}
+	return c
+}
+
+func (c *fakeE2BClient) check(id string) error {
+	if !c.alive[id] {
+		return fmt.Errorf("%w: %s", ErrSandboxNotFound, id)
+	}
+	return nil
+}
+
+func (c *fakeE2BClient) CreateSandbox(ctx context.Context, templateID string) (string, error) {
+	c.created++
+	id := fmt.Sprintf("%s-%d", templateID, c.created)
+	c.alive[id] = true
+	return id, nil
+}
+
+func (c *fakeE2BClient) ConnectSandbox(ctx context.Context, id string) error { return c.check(id) }
+
+func (c *fakeE2BClient) KillSandbox(ctx context.Context, id string) error {
+	delete(c.alive, id)
+	return nil
+}
+
+func (c *fakeE2BClient) Exec(ctx context.Context, id, command, cwd string) (E2BExecResult, error) {
+	if err := c.check(id); err != nil {
+		return E2BExecResult{}, err
+	}
+	c.execs = append(c.execs, id+":"+cwd+":"+command)
+	return E2BExecResult{Stdout: "ok\n"}, nil
+}
+
+func (c *fakeE2BClient) ReadFile(ctx context.Context, id, path string) ([]byte, error) {
+	return nil, c.check(id)
+}
+
+func (c *fakeE2BClient) WriteFile(ctx context.Context, id, path string, data []byte) error {
+	return c.check(id)
+}
+
+type fakeSandboxIDStore map[uuid.UUID]string
+
+func (s fakeSandboxIDStore) GetSandboxIDBySessionID(sessionID uuid.UUID) (*string, error) {
+	if id, ok := s[sessionID]; ok {
+		return &id, nil
+	}
+	return nil, nil
+}
+
+func (s fakeSandboxIDStore) UpdateSessionSandboxID(sessionID uuid.UUID, sandboxID string) error {
+	s[sessionID] = sandboxID
+	return nil
+}
+
+func newTestE2BWorkspace(client E2BClient, store SandboxIDStore, session uuid.UUID) *E2BWorkspace {
+	return &E2BWorkspace{Session: session.String(), TemplateID: "tpl", WorkDir: DefaultE2BWorkDir, Client: client, Store: store}
+}
+
+func TestE2BWorkspaceCreatesAndStoresSandbox(t *testing.T) {
+	session := uuid.New()
+	client, store := newFakeE2BClient(), fakeSandboxIDStore{}
+	w := newTestE2BWorkspace(client, store, session)
+
+	res := w.Execute(context.Background(), "ls")
+	if !res.Success || res.Output != "ok\n" {
+		t.Fatalf("Execute() = %+v; want success", res)
+	}
+	if client.created != 1 || store[session] != "tpl-1" {
+		t.Errorf("created %d sandboxes, stored %q; want 1 and tpl-1", client.created, store[session])
+	}
+	if len(client.execs) != 1 || client.execs[0] != "tpl-1:/home/user:ls" {
+		t.Errorf("execs = %v; want ls in /home/user on tpl-1", client.execs)
+	}
+}
+
+func TestE2BWorkspaceReconnectsToStoredSandbox(t *testing.T) {
+	session := uuid.New()
+	client := newFakeE2BClient("existing")
+	store := fakeSandboxIDStore{session: "existing"}
+	w := newTestE2BWorkspace(client, store, session)
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if w.SandboxID() != "existing" || client.created != 0 {
+		t.Errorf("SandboxID() = %q after creating %d; want to reuse existing", w.SandboxID(), client.created)
+	}
+}
+
+func TestE2BWorkspaceReprovisionsExpiredSandbox(t *testing.T) {
+	session := uuid.New()
+	client := newFakeE2BClient()
+	store := fakeSandboxIDStore{session: "expired"}
+	w := newTestE2BWorkspace(client, store, session)
+
+	// The stored sandbox is gone on resume.
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if w.SandboxID() != "tpl-1" || store[session] != "tpl-1" {
+		t.Fatalf("SandboxID() = %q, stored %q; want tpl-1", w.SandboxID(), store[session])
+	}
+
+	// It expires again mid-session; the command is retried on a new one.
+	client.KillSandbox(context.Background(), "tpl-1")
+	if res := w.Execute(context.Background(), "pwd"); !res.Success {
+		t.Fatalf("Execute() = %+v; want success after reprovisioning", res)
+	}
+	if w.SandboxID() != "tpl-2" || store[session] != "tpl-2" || len(client.execs) != 1 {
+		t.Errorf("SandboxID() = %q, stored %q, execs %v; want one exec on tpl-2", w.SandboxID(), store[session], client.execs)
+	}
+}
+
+func TestReadE2BProcessStream(t *testing.T) {
+	var stream bytes.Buffer
+	frame := func(flags byte, msg string) {
+		stream.WriteByte(flags)
+		binary.Write(&stream, binary.BigEndian, uint32(len(msg)))
+		stream.WriteString(msg)
+	}
+	b64 := base64.StdEncoding.EncodeToString
+	frame(0, `{"event":{"start":{"pid":7}}}`)
+	frame(0, fmt.Sprintf(`{"event":{"data":{"stdout":%q}}}`, b64([]byte("hello "))))
+	frame(0, fmt.Sprintf(`{"event":{"data":{"stderr":%q}}}`, b64([]byte("warn"))))
+	frame(0, fmt.Sprintf(`{"event":{"data":{"stdout":%q}}}`, b64([]byte("world"))))
+	frame(0, `{"event":{"end":{"exitCode":3,"exited":true}}}`)
+
+	res, err := readE2BProcessStream(&stream)
+	if err != nil {
+		t.Fatalf("readE2BProcessStream() error = %v", err)
+	}
+	if res.Stdout != "hello world" || res.Stderr != "warn" || res.ExitCode != 3 {
+		t.Errorf("result = %+v; want stdout %q, stderr %q, exit 3", res, "hello world", "warn")
+	}
+}
@@ -0,0 +1,231 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"water-ai/utils"
+)
+
+// DefaultE2BWorkDir is the workspace directory inside an E2B sandbox.
+const DefaultE2BWorkDir = "/home/user"
+
+// SandboxIDStore persists the sandbox backing each session, e.g. db.Sessions.
+type SandboxIDStore interface {
+	GetSandboxIDBySessionID(sessionID uuid.UUID) (*string, error)
+	UpdateSessionSandboxID(sessionID uuid.UUID, sandboxID string) error
+}
+
+// E2BWorkspace is a session workspace inside an E2B sandbox. Commands and
+// file operations run in the remote sandbox; a sandbox that has expired is
+// replaced by a fresh one from the same template.
+type E2BWorkspace struct {
+	Session    string
+	TemplateID string
+	WorkDir    string
+	Client     E2BClient
+	// Store records the sandbox ID so a resumed session reconnects to it;
+	// nil keeps the ID in memory only.
+	Store SandboxIDStore
+
+	mu        sync.Mutex
+	sandboxID string
+}
+
+// NewE2BWorkspace returns a workspace for sessionID using the template and
+// API key in settings.
+func NewE2BWorkspace(sessionID string, settings *Settings, store SandboxIDStore) *E2BWorkspace {
+	return &E2BWorkspace{
+		Session:    sessionID,
+		TemplateID: settings.SandboxConfig.TemplateID,
+		WorkDir:    DefaultE2BWorkDir,
+		Client:     NewE2BClient(settings.SandboxConfig.SandboxAPIKey),
+		Store:      store,
+	}
+}
+
+// Start attaches the workspace to a sandbox: the one stored for the
+// session if it is still alive, otherwise a newly created one.
+func (w *E2BWorkspace) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.sandboxID
+	if id == "" {
+		id = w.storedSandboxID()
+	}
+	if id != "" {
+		err := w.Client.ConnectSandbox(ctx, id)
+		if err == nil {
+			w.sandboxID = id
+			return nil
+		}
+		if !errors.Is(err, ErrSandboxNotFound) {
+			return fmt.Errorf("reconnecting to sandbox %s: %w", id, err)
+		}
+	}
+	return w.provision(ctx)
+}
+
+// SandboxID returns the ID of the current sandbox, or "" before Start.
+func (w *E2BWorkspace) SandboxID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sandboxID
+}
+
+func (w *E2BWorkspace) storedSandboxID() string {
+	if w.Store == nil {
+		return ""
+	}
+	sid, err := uuid.Parse(w.Session)
+	if err != nil {
+		return ""
+	}
+	id, err := w.Store.GetSandboxIDBySessionID(sid)
+	if err != nil || id == nil {
+		return ""
+	}
+	return *id
+}
+
+// provision creates a sandbox and records its ID. w.mu must be held.
+func (w *E2BWorkspace) provision(ctx context.Context) error {
+	id, err := w.Client.CreateSandbox(ctx, w.TemplateID)
+	if err != nil {
+		return fmt.Errorf("creating e2b sandbox: %w", err)
+	}
+	w.sandboxID = id
+	if w.Store != nil {
+		if sid, err := uuid.Parse(w.Session); err == nil {
+			if err := w.Store.UpdateSessionSandboxID(sid, id); err != nil {
+				return fmt.Errorf("saving sandbox ID: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// withSandbox runs op against the current sandbox, starting one if
+// needed. When the sandbox has expired it is reprovisioned and op retried
+// once.
+func (w *E2BWorkspace) withSandbox(ctx context.Context, op func(sandboxID string) error) error {
+	if w.SandboxID() == "" {
+		if err := w.Start(ctx); err != nil {
+			return err
+		}
+	}
+	id := w.SandboxID()
+	err := op(id)
+	if !errors.Is(err, ErrSandboxNotFound) {
+		return err
+	}
+
+	w.mu.Lock()
+	if w.sandboxID == id {
+		err = w.provision(ctx)
+	} else {
+		err = nil // another call already replaced it
+	}
+	id = w.sandboxID
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return op(id)
+}
+
+// Execute runs command in the workspace directory of the sandbox.
+func (w *E2BWorkspace) Execute(ctx context.Context, command string) utils.SessionResult {
+	var res E2BExecResult
+	err := w.withSandbox(ctx, func(id string) error {
+		var err error
+		res, err = w.Client.Exec(ctx, id, command, w.WorkDir)
+		return err
+	})
+	if err != nil {
+		return utils.SessionResult{Success: false, Output: err.Error()}
+	}
+	output := res.Stdout + res.Stderr
+	if res.ExitCode != 0 {
+		return utils.SessionResult{Success: false, Output: fmt.Sprintf("%sexit status %d", output, res.ExitCode)}
+	}
+	return utils.SessionResult{Success: true, Output: output}
+}
+
+// ResolvePath returns the sandbox path for a file tool's path argument.
+// Relative paths are taken from the workspace directory.
+func (w *E2BWorkspace) ResolvePath(p string) (string, error) {
+	return w.WorkspacePath(p), nil
+}
+
+// ReadFile reads a file from the sandbox.
+func (w *E2BWorkspace) ReadFile(ctx context.Context, p string) ([]byte, error) {
+	var data []byte
+	err := w.withSandbox(ctx, func(id string) error {
+		var err error
+		data, err = w.Client.ReadFile(ctx, id, w.WorkspacePath(p))
+		return err
+	})
+	return data, err
+}
+
+// WriteFile writes a file in the sandbox.
+func (w *E2BWorkspace) WriteFile(ctx context.Context, p string, data []byte) error {
+	return w.withSandbox(ctx, func(id string) error {
+		return w.Client.WriteFile(ctx, id, w.WorkspacePath(p), data)
+	})
+}
+
+// Kill shuts down the sandbox.
+func (w *E2BWorkspace) Kill(ctx context.Context) error {
+	id := w.SandboxID()
+	if id == "" {
+		return nil
+	}
+	err := w.Client.KillSandbox(ctx, id)
+	if errors.Is(err, ErrSandboxNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WorkspacePath returns the absolute sandbox path of p.
+func (w *E2BWorkspace) WorkspacePath(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(w.WorkDir, p)
+}
+
+// RelativePath returns p relative to the workspace directory when it lies
+// inside it.
+func (w *E2BWorkspace) RelativePath(p string) string {
+	abs := w.WorkspacePath(p)
+	if rel := strings.TrimPrefix(abs, w.WorkDir+"/"); rel != abs {
+		return rel
+	}
+	return abs
+}
+
+// PortURL returns the public URL of port in the current sandbox.
+func (w *E2BWorkspace) PortURL(port int) string {
+	return fmt.Sprintf("https://%d-%s.%s", port, w.SandboxID(), w.domain())
+}
+
+// domain returns the domain sandbox ports are exposed under.
+func (w *E2BWorkspace) domain() string {
+	if c, ok := w.Client.(*E2BHTTPClient); ok && c.Domain != "" {
+		return c.Domain
+	}
+	return defaultE2BDomain
+}
+
+// SessionID returns the session the workspace belongs to.
+func (w *E2BWorkspace) SessionID() string { return w.Session }
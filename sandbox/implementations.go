@@ -8,6 +8,8 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+
+	"water-ai/db"
 )
 
 // --- Docker Sandbox ---
@@ -62,32 +64,51 @@ func (s *DockerSandbox) Stop(ctx context.Context) error    { return s.client.Con
 func (s *DockerSandbox) Cleanup(ctx context.Context) error { return s.client.ContainerRemove(ctx, s.SandboxID, container.RemoveOptions{Force: true}) }
 func (s *DockerSandbox) ExposePort(port int) string        { return fmt.Sprintf("http://%s-%d.%s", s.SessionID, port, os.Getenv("BASE_URL")) }
 
-// --- E2B Sandbox (Mocked/Simplified for Go) ---
+// --- E2B Sandbox ---
 
+// E2BSandbox manages the lifecycle of an E2BWorkspace's sandbox.
 type E2BSandbox struct {
 	Base
+	Workspace *E2BWorkspace
 }
 
 func init() {
 	Register(ModeE2B, func(sid string, s *Settings) Sandbox {
-		return &E2BSandbox{Base: Base{SessionID: sid, Settings: s}}
+		return &E2BSandbox{Base: Base{SessionID: sid, Settings: s}, Workspace: NewE2BWorkspace(sid, s, sessionStore())}
 	})
 }
 
+// sessionStore returns db.Sessions once the database is open, so sandbox
+// IDs survive restarts; before that they are kept in memory only.
+func sessionStore() SandboxIDStore {
+	if db.DB == nil {
+		return nil
+	}
+	return db.Sessions
+}
+
 func (s *E2BSandbox) Create(ctx context.Context) error {
-	// In a real scenario, you'd use an E2B Go SDK or HTTP client here
-	s.SandboxID = "e2b-proto-" + s.SessionID
+	if err := s.Workspace.Start(ctx); err != nil {
+		return err
+	}
+	s.SandboxID = s.Workspace.SandboxID()
 	s.HostURL = s.ExposePort(s.Settings.SandboxConfig.ServicePort)
 	return nil
 }
 
-func (s *E2BSandbox) Connect(ctx context.Context) error { return nil }
+// Connect reattaches to the sandbox in SandboxID, replacing it if it has
+// expired.
+func (s *E2BSandbox) Connect(ctx context.Context) error {
+	s.Workspace.mu.Lock()
+	s.Workspace.sandboxID = s.SandboxID
+	s.Workspace.mu.Unlock()
+	return s.Create(ctx)
+}
+
 func (s *E2BSandbox) Start(ctx context.Context) error   { return nil }
 func (s *E2BSandbox) Stop(ctx context.Context) error    { return nil }
-func (s *E2BSandbox) Cleanup(ctx context.Context) error { return nil }
-func (s *E2BSandbox) ExposePort(port int) string {
-	return fmt.Sprintf("https://%d-%s.e2b.dev", port, s.SandboxID)
-}
+func (s *E2BSandbox) Cleanup(ctx context.Context) error { return s.Workspace.Kill(ctx) }
+func (s *E2BSandbox) ExposePort(port int) string { return s.Workspace.PortURL(port) }
 
 // --- Local Sandbox ---
 
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/agents"
+	"water-ai/core"
+	"water-ai/core/config"
+	"water-ai/db"
+	"water-ai/llm"
+	contextmanager "water-ai/llm/context_manager"
+	"water-ai/tools"
+	"water-ai/utils"
+)
+
+// agentMaxOutputTokens caps each model reply in a chat turn.
+const agentMaxOutputTokens = 4096
+
+// sessionPrompt is the session's system prompt as an agent prompt builder.
+type sessionPrompt string
+
+func (p sessionPrompt) GetSystemPrompt() string { return string(p) }
+
+// sessionClient records the usage and latency of every call the agent
+// makes through the session's LLM client.
+type sessionClient struct {
+	session *ChatSession
+}
+
+func (c *sessionClient) Generate(messages []*llm.Message, maxTokens int, systemPrompt string, temperature float64, tools []*llm.ToolParam, toolChoice *llm.ToolChoice, thinkingTokens *int, sampling *llm.Sampling) (*llm.GenerateResponse, error) {
+	callStarted := time.Now()
+	resp, err := c.session.LLMClient.Generate(messages, maxTokens, systemPrompt, temperature, tools, toolChoice, thinkingTokens, sampling)
+	observeLLMCall(callStarted)
+	if err != nil {
+		core.Logger.Error("LLM Generate error", "session_id", c.session.SessionUUID.String(), "error", err)
+		return nil, err
+	}
+	c.session.recordUsage(resp.Usage)
+	return resp, nil
+}
+
+// agentTools returns the tools the session's agent may use. The terminal
+// and file editor change the workspace, so each call waits for the user's
+// approval. Commands run in the session's docker sandbox when it has one.
+// Large outputs are saved to the workspace for read_tool_output.
+func (s *ChatSession) agentTools() []agents.LLMTool {
+	terminal := &tools.TerminalTool{WorkDir: s.Workspace}
+	s.mu.Lock()
+	if ex, ok := s.sandbox.(tools.CommandExecutor); ok {
+		// The docker sandbox mounts the workspace, so commands run in
+		// its container see the files the editor writes.
+		terminal.Executor = ex
+	}
+	s.mu.Unlock()
+	return agents.OffloadLargeOutputs([]agents.LLMTool{
+		agents.RequireApproval(agents.WrapTool(&tools.FileEditorTool{BaseDir: s.Workspace})),
+		agents.RequireApproval(agents.WrapTool(terminal)),
+	}, s.Workspace, 0)
+}
+
+// newAgent builds the agent that runs one query against the session
+// history, sending its events to events. Older turns are summarized once
+// the history outgrows the context budget.
+func (s *ChatSession) newAgent(events chan agents.RealtimeEvent) *agents.FunctionCallAgent {
+	client := agents.NewLLMClientAdapter(&sessionClient{session: s}, s.llmConfig().Temperature)
+	toolManager := agents.NewAgentToolManager(s.agentTools())
+	cm := agents.NewLLMContextManager(client, core.Logger, contextmanager.BudgetedConfig())
+	cm.ReserveSystemTokens(s.SystemPrompt, toolManager)
+	agent := agents.NewFunctionCallAgent(
+		sessionPrompt(s.SystemPrompt),
+		client,
+		toolManager,
+		agents.NewLLMHistory(s.History),
+		&agents.DirWorkspace{Root: s.Workspace, ID: s.SessionUUID.String()},
+		events,
+		slog.NewLogLogger(core.Logger.Handler(), slog.LevelWarn),
+		cm,
+		agentMaxOutputTokens,
+		config.MaxTurns,
+		nil,
+	)
+	if s.approver != nil {
+		agent.Approver = s.approver
+	} else if s.Approvals != nil {
+		agent.Approver = s.Approvals
+	}
+	if db.DB != nil {
+		agent.EventSink = db.Events
+	}
+	s.mu.Lock()
+	agent.RequestID = s.correlationID
+	s.mu.Unlock()
+	if s.Manager != nil {
+		agent.MinimizeStdoutLogs = s.Manager.config.MinimizeLogs
+		if cfg := s.Manager.config.Agent; cfg != nil {
+			agent.ApplyConfig(cfg)
+		}
+	}
+	// The token budget covers the whole session, not just this query
+	if s.Tracker != nil {
+		totals := s.Tracker.Report().Totals
+		agent.SetTokensUsed(totals.InputTokens + totals.OutputTokens)
+	}
+	return agent
+}
+
+// runAgent runs the agent on text with the attached files, workspace paths
+// as returned by /api/upload, and returns its final answer. Its events
+// are saved and relayed to the session's connections, except agent
+// responses: the answer is returned instead so the caller sends it once.
+// Response deltas are relayed but not saved, as the answer holds their
+// text.
+func (s *ChatSession) runAgent(ctx context.Context, text string, files []string) (string, error) {
+	input := map[string]interface{}{"instruction": text}
+	var attached []string
+	for _, f := range files {
+		path, err := utils.ResolveInRoot(s.Workspace, strings.TrimPrefix(f, "/"))
+		if err != nil {
+			s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot attach %s: %v", f, err)})
+			continue
+		}
+		attached = append(attached, path)
+	}
+	if len(attached) > 0 {
+		input["files"] = attached
+	}
+
+	events := make(chan agents.RealtimeEvent, 100)
+	relayed := make(chan struct{})
+	go func() {
+		defer close(relayed)
+		for evt := range events {
+			if evt.Type == agents.EventTypeAgentResponse {
+				continue
+			}
+			if evt.Type != agents.EventTypeAgentResponseDelta {
+				s.recordEvent(evt.Type, evt.Content)
+			}
+			s.SendEvent(evt.Type, gin.H(evt.Content))
+		}
+	}()
+
+	agent := s.newAgent(events)
+	out, err := agent.Run(ctx, input, agent.History)
+	close(events)
+	<-relayed
+	return out.ToolOutput, err
+}
+
+// context returns the context of the session's turns, cancelled when the
+// session ends.
+func (s *ChatSession) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+	return s.ctx
+}
+
+// turnContext returns the context of a query, cancelled by a cancel
+// message or when the session ends. Queries running together share it.
+func (s *ChatSession) turnContext() context.Context {
+	base := s.context()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.turnCtx == nil || s.turnCtx.Err() != nil {
+		s.turnCtx, s.turnCancel = context.WithCancel(base)
+	}
+	return s.turnCtx
+}
+
+// cancelTurns cancels the running queries, leaving the session open for
+// the next one.
+func (s *ChatSession) cancelTurns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.turnCancel != nil {
+		s.turnCancel()
+	}
+}
+
+// end cancels the session's running turns, e.g. a tool call waiting for
+// an approval that will never come.
+func (s *ChatSession) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
@@ -0,0 +1,327 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/agents"
+	"water-ai/core"
+	"water-ai/core/config"
+	"water-ai/llm"
+	"water-ai/utils"
+)
+
+// awaitEvent polls conn until an event of eventType matching ok arrives.
+func awaitEvent(t *testing.T, conn *recordingConn, eventType string, ok func(gin.H) bool) gin.H {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		conn.mu.Lock()
+		for _, e := range conn.events {
+			if content, _ := e.Content.(gin.H); e.Type == eventType && ok(content) {
+				conn.mu.Unlock()
+				return content
+			}
+		}
+		conn.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no %s event arrived; got %v", eventType, conn.types())
+	return nil
+}
+
+// answer sends an approve or deny message, retrying until the agent has
+// registered its approval request.
+func answer(t *testing.T, session *ChatSession, conn *recordingConn, msgType, toolCallID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		before := countEvents(conn, EventTypeError)
+		session.HandleMessage([]byte(`{"type":"` + msgType + `","content":{"tool_call_id":"` + toolCallID + `"}}`))
+		if countEvents(conn, EventTypeError) == before {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("approval request for %s never registered", toolCallID)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func countEvents(conn *recordingConn, eventType string) int {
+	n := 0
+	for _, typ := range conn.types() {
+		if typ == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestQueryToolCallAwaitsApproval(t *testing.T) {
+	tests := []struct {
+		msgType    string
+		wantResult string
+		wantFile   bool
+	}{
+		{"approve", "", true},
+		{"deny", agents.ToolRejectedMsg, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msgType, func(t *testing.T) {
+			mock := llm.NewMockClient().
+				EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "touch approved.txt"})).
+				EnqueueBlocks(llm.TextBlock("All done."))
+			conn := &recordingConn{}
+			session := newTestSession(t, conn, mock)
+			os.MkdirAll(session.Workspace, 0755)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				session.HandleMessage([]byte(`{"type":"query","content":{"text":"create a file"}}`))
+			}()
+
+			awaitEvent(t, conn, EventTypeToolCall, func(c gin.H) bool { return c["awaiting_approval"] == true })
+			answer(t, session, conn, tt.msgType, "call-1")
+			<-done
+
+			result := awaitEvent(t, conn, EventTypeToolResult, func(c gin.H) bool { return c["tool_call_id"] == "call-1" && c["partial"] == nil })
+			if tt.wantResult != "" && result["result"] != tt.wantResult {
+				t.Errorf("tool result = %v; want %q", result["result"], tt.wantResult)
+			}
+			_, err := os.Stat(filepath.Join(session.Workspace, "approved.txt"))
+			if got := err == nil; got != tt.wantFile {
+				t.Errorf("command ran = %v; want %v", got, tt.wantFile)
+			}
+			if got := awaitEvent(t, conn, EventTypeAgentResponse, func(gin.H) bool { return true }); got["text"] != "All done." {
+				t.Errorf("agent response = %v; want the model's final answer", got["text"])
+			}
+		})
+	}
+}
+
+func TestQueryToolCallCancelledWhenSessionEnds(t *testing.T) {
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "touch never.txt"}))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.HandleMessage([]byte(`{"type":"query","content":{"text":"create a file"}}`))
+	}()
+	awaitEvent(t, conn, EventTypeToolCall, func(c gin.H) bool { return c["awaiting_approval"] == true })
+	session.Manager.Disconnect(conn)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("query still waiting for approval after the session ended")
+	}
+	if _, err := os.Stat(filepath.Join(session.Workspace, "never.txt")); err == nil {
+		t.Error("tool ran after the session ended")
+	}
+}
+
+func TestQueryLLMErrorLoggedWithMinimizedLogs(t *testing.T) {
+	var buf bytes.Buffer
+	orig := core.Logger
+	core.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	defer func() { core.Logger = orig }()
+
+	mock := llm.NewMockClient().EnqueueError(errors.New("rate limited"))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+	session.HandleMessage([]byte(`{"type":"query","content":{"text":"hello"}}`))
+
+	if out := buf.String(); !strings.Contains(out, "LLM Generate error") || !strings.Contains(out, "rate limited") {
+		t.Errorf("log = %q; want the LLM error at WARN level and above", out)
+	}
+}
+
+// execSandbox is a docker-like sandbox that records the commands it runs.
+type execSandbox struct {
+	fakeSandbox
+	commands chan string
+}
+
+func (e *execSandbox) Execute(ctx context.Context, command string) utils.SessionResult {
+	e.commands <- command
+	return utils.SessionResult{Success: true, Output: "ran in the sandbox"}
+}
+
+func TestQueryRunsCommandsInSandbox(t *testing.T) {
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "touch host.txt"})).
+		EnqueueBlocks(llm.TextBlock("All done."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+	os.MkdirAll(session.Workspace, 0755)
+	sb := &execSandbox{commands: make(chan string, 1)}
+	session.sandbox = sb
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.HandleMessage([]byte(`{"type":"query","content":{"text":"create a file"}}`))
+	}()
+	awaitEvent(t, conn, EventTypeToolCall, func(c gin.H) bool { return c["awaiting_approval"] == true })
+	answer(t, session, conn, "approve", "call-1")
+	<-done
+
+	if got := <-sb.commands; got != "touch host.txt" {
+		t.Errorf("sandbox command = %q; want the tool call's command", got)
+	}
+	if _, err := os.Stat(filepath.Join(session.Workspace, "host.txt")); err == nil {
+		t.Error("command ran on the host despite the sandbox")
+	}
+	result := awaitEvent(t, conn, EventTypeToolResult, func(c gin.H) bool { return c["tool_call_id"] == "call-1" && c["partial"] == nil })
+	if result["result"] != "ran in the sandbox" {
+		t.Errorf("tool result = %v; want the sandbox output", result["result"])
+	}
+}
+
+func TestDockerSettings(t *testing.T) {
+	s := dockerSettings(config.SandboxConfig{Image: "water/sandbox:1", MemoryLimitMB: 512, CPULimit: 2})
+	if s.SandboxConfig.Image != "water/sandbox:1" || s.SandboxConfig.MemoryLimit != 512<<20 || s.SandboxConfig.CPULimit != 2 {
+		t.Errorf("dockerSettings() = %+v; want the image and limits in bytes and CPUs", s.SandboxConfig)
+	}
+}
+
+func TestNewAgentSummarizesLongHistory(t *testing.T) {
+	session := newTestSession(t, &recordingConn{}, llm.NewMockClient())
+	agent := session.newAgent(make(chan agents.RealtimeEvent, 1))
+	if agent.ContextManager == nil {
+		t.Error("agent has no ContextManager; long sessions would overflow the context")
+	}
+}
+
+func TestCancelMessageEndsRunningQuery(t *testing.T) {
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "touch never.txt"})).
+		EnqueueBlocks(llm.TextBlock("All done."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.HandleMessage([]byte(`{"type":"query","content":{"text":"create a file"}}`))
+	}()
+	awaitEvent(t, conn, EventTypeToolCall, func(c gin.H) bool { return c["awaiting_approval"] == true })
+	session.HandleMessage([]byte(`{"type":"cancel"}`))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("query still running after cancel")
+	}
+	if _, err := os.Stat(filepath.Join(session.Workspace, "never.txt")); err == nil {
+		t.Error("tool ran after the query was cancelled")
+	}
+	if n := countEvents(conn, EventTypeAgentResponse); n != 0 {
+		t.Errorf("%d agent_response events after cancel; want none", n)
+	}
+	if session.context().Err() != nil {
+		t.Error("cancel ended the session; want only the query cancelled")
+	}
+}
+
+func TestQueryAttachesUploadedImage(t *testing.T) {
+	mock := llm.NewMockClient().EnqueueBlocks(llm.TextBlock("A red square."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+	os.MkdirAll(filepath.Join(session.Workspace, "uploads"), 0755)
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	os.WriteFile(filepath.Join(session.Workspace, "uploads", "pic.png"), buf.Bytes(), 0644)
+
+	session.HandleMessage([]byte(`{"type":"query","content":{"text":"what is this?","files":["/uploads/pic.png"]}}`))
+
+	calls := mock.Calls()
+	if len(calls) == 0 {
+		t.Fatal("model was not called")
+	}
+	var attached *llm.ContentBlock
+	var text string
+	for _, block := range calls[0].Messages[0].Content {
+		switch block.Type {
+		case llm.ContentTypeImage:
+			attached = block
+		case llm.ContentTypeText:
+			text = block.Text
+		}
+	}
+	if attached == nil || attached.Source == nil || attached.Source.MediaType != "image/png" || attached.Source.Data != base64.StdEncoding.EncodeToString(buf.Bytes()) {
+		t.Errorf("model request image = %+v; want the uploaded PNG", attached)
+	}
+	if !strings.Contains(text, "uploads/pic.png") {
+		t.Errorf("model request text = %q; want the attachment listed", text)
+	}
+}
+
+func TestAgentTokenBudgetCoversTheSession(t *testing.T) {
+	mock := llm.NewMockClient().EnqueueBlocks(llm.TextBlock("More work."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+	session.Manager.config.Agent = &config.WaterAgentConfig{MaxTurns: 5, MaxOutputTokensPerTurn: 1024, TokenBudget: 100}
+	// Earlier queries of the session spent the budget
+	session.Tracker.Record("mock", llm.UsageMetadata{InputTokens: 90, OutputTokens: 20})
+
+	session.HandleMessage([]byte(`{"type":"query","content":{"text":"keep going"}}`))
+
+	if n := len(mock.Calls()); n != 0 {
+		t.Errorf("model called %d times; want none once the session budget is spent", n)
+	}
+	if got := lastMessage(conn, EventTypeSystem); !strings.Contains(got, agents.TokenBudgetExceededMsg) {
+		t.Errorf("system message = %q; want the budget exhausted", got)
+	}
+}
+
+// hangingClient never answers, like a provider that stopped responding.
+type hangingClient struct {
+	release chan struct{}
+}
+
+func (c *hangingClient) Generate(messages []*llm.Message, maxTokens int, systemPrompt string, temperature float64, tools []*llm.ToolParam, toolChoice *llm.ToolChoice, thinkingTokens *int, sampling *llm.Sampling) (*llm.GenerateResponse, error) {
+	<-c.release
+	return nil, errors.New("released")
+}
+
+func TestAgentTurnTimeoutAppliesToSessions(t *testing.T) {
+	client := &hangingClient{release: make(chan struct{})}
+	defer close(client.release)
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, client)
+	session.Manager.config.Agent = &config.WaterAgentConfig{MaxTurns: 5, MaxOutputTokensPerTurn: 1024, TurnTimeoutSeconds: 1}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.HandleMessage([]byte(`{"type":"query","content":{"text":"hello"}}`))
+	}()
+	awaitEvent(t, conn, EventTypeSystem, func(c gin.H) bool {
+		msg, _ := c["message"].(string)
+		return strings.Contains(msg, "timed out")
+	})
+	session.HandleMessage([]byte(`{"type":"cancel"}`))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("query still running after the turn timed out and was cancelled")
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"water-ai/agents"
+	"water-ai/llm"
+)
+
+func TestApproveAndDenyMessages(t *testing.T) {
+	tests := []struct {
+		msgType string
+		want    bool
+	}{
+		{"approve", true},
+		{"deny", false},
+	}
+	for _, tt := range tests {
+		conn := &recordingConn{}
+		session := newTestSession(t, conn, llm.NewMockClient())
+
+		answer := make(chan bool, 1)
+		go func() {
+			approved, _ := session.Approvals.AwaitApproval(context.Background(), agents.ToolCallParameters{ID: "call-1"})
+			answer <- approved
+		}()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			conn.events = nil
+			session.HandleMessage([]byte(`{"type":"` + tt.msgType + `","content":{"tool_call_id":"call-1"}}`))
+			if lastMessage(conn, EventTypeError) == "" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: approval request never registered", tt.msgType)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got := <-answer; got != tt.want {
+			t.Errorf("%s: approved = %v; want %v", tt.msgType, got, tt.want)
+		}
+	}
+}
+
+func TestApproveUnknownToolCall(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient())
+	session.HandleMessage([]byte(`{"type":"approve","content":{"tool_call_id":"nope"}}`))
+	if got := lastMessage(conn, EventTypeError); !strings.Contains(got, "no tool call is awaiting approval") {
+		t.Errorf("error = %q; want a no pending approval error", got)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/core/config"
+	"water-ai/tools"
+	"water-ai/utils"
+)
+
+// audioConfig returns the gateway's audio settings.
+func (s *ChatSession) audioConfig() config.AudioConfig {
+	if s.Manager == nil {
+		return config.AudioConfig{}
+	}
+	return s.Manager.config.Audio
+}
+
+// handleAudio transcribes an uploaded audio file and sends the transcript
+// as a transcription event, then runs it as a query when asked to.
+func (s *ChatSession) handleAudio(content AudioContent) {
+	transcriber := tools.NewTranscriber(s.audioConfig())
+	if transcriber == nil {
+		s.SendEvent(EventTypeError, gin.H{"message": "Audio transcription is not configured: set OPENAI_API_KEY"})
+		return
+	}
+	rel := strings.TrimPrefix(content.Path, "/")
+	if err := tools.CheckAudioFormat(rel); err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot transcribe %s: %v", content.Path, err)})
+		return
+	}
+	path, err := utils.ResolveInRoot(s.Workspace, rel)
+	if err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot transcribe %s: %v", content.Path, err)})
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot read audio file: %v", err)})
+		return
+	}
+
+	text, err := transcriber.Transcribe(s.context(), rel, data)
+	if err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Transcription failed: %v", err)})
+		return
+	}
+	s.SendEvent(EventTypeTranscription, gin.H{"text": text, "path": content.Path})
+	if content.AsQuery && text != "" {
+		s.handleQuery(QueryContent{Text: text})
+	}
+}
+
+// speak sends the agent's response as synthesized speech when
+// text-to-speech is enabled. The audio is saved in the workspace; the
+// speech event carries its path and the URL it can be streamed from.
+// Synthesis stops when ctx is cancelled.
+func (s *ChatSession) speak(ctx context.Context, text string) {
+	synth := tools.NewSynthesizer(s.audioConfig())
+	if synth == nil {
+		return
+	}
+	relPath, err := synth.SaveSpeech(ctx, s.Workspace, text)
+	if err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Text-to-speech failed: %v", err)})
+		return
+	}
+	rel := filepath.ToSlash(relPath)
+	s.SendEvent(EventTypeSpeech, gin.H{
+		"path":   "/" + rel,
+		"url":    fmt.Sprintf("/api/sessions/%s/files/%s", s.SessionUUID, rel),
+		"format": "mp3",
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/core/config"
+	"water-ai/llm"
+)
+
+func TestAudioMessageSendsTranscript(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text": "list the files"}`))
+	}))
+	defer srv.Close()
+
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+	key := config.SecretString("az-key")
+	session.Manager.config.Audio = config.AudioConfig{OpenAIAPIKey: &key, AzureEndpoint: &srv.URL}
+	os.MkdirAll(filepath.Join(session.Workspace, "uploads"), 0755)
+	os.WriteFile(filepath.Join(session.Workspace, "uploads", "voice.webm"), []byte("webm"), 0644)
+
+	session.HandleMessage([]byte(`{"type": "audio", "content": {"path": "/uploads/voice.webm"}}`))
+
+	var text interface{}
+	for _, e := range conn.events {
+		if e.Type == EventTypeTranscription {
+			text = e.Content.(gin.H)["text"]
+		}
+	}
+	if text != "list the files" {
+		t.Errorf("transcription text = %v; want the transcript (error: %q)", text, lastMessage(conn, EventTypeError))
+	}
+
+	session.HandleMessage([]byte(`{"type": "audio", "content": {"path": "/uploads/voice.txt"}}`))
+	if msg := lastMessage(conn, EventTypeError); msg == "" {
+		t.Error("no error for an unsupported audio format")
+	}
+}
+
+func TestQueryResponseIsSpokenWhenTTSEnabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ID3 mp3 data"))
+	}))
+	defer srv.Close()
+
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient().EnqueueBlocks(llm.TextBlock("All tests pass.")))
+	key := config.SecretString("az-key")
+	session.Manager.config.Audio = config.AudioConfig{OpenAIAPIKey: &key, AzureEndpoint: &srv.URL, TTSEnabled: true}
+
+	session.HandleMessage([]byte(`{"type": "query", "content": {"text": "run the tests"}}`))
+
+	var path string
+	for _, e := range conn.events {
+		if e.Type == EventTypeSpeech {
+			path, _ = e.Content.(gin.H)["path"].(string)
+		}
+	}
+	if path == "" {
+		t.Fatalf("no speech event (error: %q)", lastMessage(conn, EventTypeError))
+	}
+	if data, err := os.ReadFile(filepath.Join(session.Workspace, path)); err != nil || string(data) != "ID3 mp3 data" {
+		t.Errorf("speech file %s = %q, %v", path, data, err)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyQueryParam carries the API key on requests that cannot set headers,
+// such as browser WebSocket upgrades.
+const APIKeyQueryParam = "api_key"
+
+// APIKeyAuth rejects requests that do not present key as a bearer token,
+// an X-API-Key header or the api_key query parameter. An empty key disables
+// the check so local use needs no configuration.
+func APIKeyAuth(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key == "" {
+			c.Next()
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(requestAPIKey(c.Request)), []byte(key)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestAPIKey extracts the key presented by r, if any
+func requestAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get(APIKeyQueryParam)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func authRouter(key string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/ping", APIKeyAuth(key), func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return router
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		header map[string]string
+		query  string
+		want   int
+	}{
+		{"disabled without key", "", nil, "", http.StatusOK},
+		{"bearer token", "secret", map[string]string{"Authorization": "Bearer secret"}, "", http.StatusOK},
+		{"x-api-key header", "secret", map[string]string{"X-API-Key": "secret"}, "", http.StatusOK},
+		{"query param", "secret", nil, "?api_key=secret", http.StatusOK},
+		{"missing key", "secret", nil, "", http.StatusUnauthorized},
+		{"wrong key", "secret", map[string]string{"Authorization": "Bearer nope"}, "", http.StatusUnauthorized},
+		{"wrong scheme", "secret", map[string]string{"Authorization": "Basic secret"}, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/ping"+tt.query, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			authRouter(tt.key).ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateServerAppliesAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir(), APIKey: "secret"})
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	for _, path := range []string{"/api/settings", "/ws", "/workspace/"} {
+		if code := get(path); code != http.StatusUnauthorized {
+			t.Errorf("GET %s without key = %d; want 401", path, code)
+		}
+	}
+	if code := get("/api/settings?api_key=secret"); code == http.StatusUnauthorized {
+		t.Error("GET /api/settings with key should be authorized")
+	}
+	if code := get("/health/detailed"); code == http.StatusUnauthorized {
+		t.Error("health endpoint should stay public")
+	}
+}
+
+func TestCreateServerWithoutAPIKeyIsOpen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/settings", nil))
+	if w.Code == http.StatusUnauthorized {
+		t.Errorf("status = %d; want no auth when APIKey is empty", w.Code)
+	}
+}
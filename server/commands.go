@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/core"
+	"water-ai/db"
+	"water-ai/llm"
+)
+
+// slashHelp is the /help text.
+const slashHelp = `Available commands:
+  /help          Show this help
+  /compact       Replace the conversation with a summary of it
+  /clear         Forget the conversation and delete its stored events
+  /save <name>   Save the conversation under <name>
+  /load <name>   Restore a conversation saved with /save`
+
+// SavedHistoryDir holds histories saved with /save, relative to the
+// session workspace.
+const SavedHistoryDir = ".water/saved"
+
+var historyNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// commandError reports a failed slash command and ends the turn.
+func (s *ChatSession) commandError(message string) {
+	s.SendEvent(EventTypeError, gin.H{"message": message})
+	s.SendEvent(EventTypeStreamComplete, gin.H{})
+}
+
+// savedHistoryPath returns the file a history saved as name lives in.
+func (s *ChatSession) savedHistoryPath(name string) string {
+	return filepath.Join(s.Workspace, filepath.FromSlash(SavedHistoryDir), name+".json")
+}
+
+// savedHistoryNames lists the names of histories saved in the workspace.
+func (s *ChatSession) savedHistoryNames() []string {
+	matches, _ := filepath.Glob(filepath.Join(s.Workspace, filepath.FromSlash(SavedHistoryDir), "*.json"))
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(m), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseHistoryName validates the single <name> argument of /save and /load.
+func parseHistoryName(cmd string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: %s <name>", cmd)
+	}
+	if !historyNamePattern.MatchString(args[0]) {
+		return "", fmt.Errorf("invalid name %q: use up to 64 letters, digits, '.', '_' or '-', starting with a letter or digit", args[0])
+	}
+	return args[0], nil
+}
+
+// handleClearCommand forgets the conversation and deletes the session's
+// stored events.
+func (s *ChatSession) handleClearCommand(args []string) {
+	if len(args) > 0 {
+		s.commandError("/clear takes no arguments")
+		return
+	}
+
+	if s.History != nil {
+		s.History.Clear()
+	}
+	if err := os.Remove(s.historyPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		core.Logger.Error("failed to remove persisted history", "session_id", s.SessionUUID.String(), "error", err)
+	}
+	if db.DB != nil {
+		if err := db.Events.DeleteSessionEvents(s.SessionUUID); err != nil {
+			s.commandError(fmt.Sprintf("Conversation cleared but its events could not be deleted: %v", err))
+			return
+		}
+	}
+	s.SendEvent(EventTypeSystem, gin.H{"message": "Conversation cleared."})
+	s.SendEvent(EventTypeStreamComplete, gin.H{})
+}
+
+// handleSaveCommand writes the conversation to SavedHistoryDir.
+func (s *ChatSession) handleSaveCommand(args []string) {
+	name, err := parseHistoryName("/save", args)
+	if err != nil {
+		s.commandError(err.Error())
+		return
+	}
+	if s.History == nil {
+		s.commandError("Agent not initialized. Send init_agent first.")
+		return
+	}
+
+	if err := s.History.SaveToFile(s.savedHistoryPath(name)); err != nil {
+		s.commandError(fmt.Sprintf("Failed to save conversation: %v", err))
+		return
+	}
+	s.HistoryName = name
+	s.SendEvent(EventTypeSystem, gin.H{
+		"message": fmt.Sprintf("Saved %d messages as %q.", len(s.History.GetMessages()), name),
+		"name":    name,
+	})
+	s.SendEvent(EventTypeStreamComplete, gin.H{})
+}
+
+// handleLoadCommand replaces the conversation with one saved by /save and
+// replays its text turns to the client.
+func (s *ChatSession) handleLoadCommand(args []string) {
+	name, err := parseHistoryName("/load", args)
+	if err != nil {
+		s.commandError(err.Error())
+		return
+	}
+
+	history := llm.NewMessageHistory()
+	if err := history.LoadFromFile(s.savedHistoryPath(name)); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.commandError(fmt.Sprintf("Failed to load %q: %v", name, err))
+			return
+		}
+		if saved := s.savedHistoryNames(); len(saved) > 0 {
+			s.commandError(fmt.Sprintf("No saved conversation named %q. Saved conversations: %s", name, strings.Join(saved, ", ")))
+		} else {
+			s.commandError(fmt.Sprintf("No saved conversation named %q. Use /save <name> first.", name))
+		}
+		return
+	}
+
+	s.History = history
+	s.HistoryName = name
+	s.replayHistory()
+	s.SendEvent(EventTypeSystem, gin.H{
+		"message": fmt.Sprintf("Loaded %d messages from %q.", len(history.GetMessages()), name),
+		"name":    name,
+	})
+	s.SendEvent(EventTypeStreamComplete, gin.H{})
+}
+
+// replayHistory sends the text of each turn in the history to the client
+// as user_message and agent_response events.
+func (s *ChatSession) replayHistory() {
+	for _, msg := range s.History.GetMessages() {
+		var text strings.Builder
+		for _, block := range msg.Content {
+			if block.Type == llm.ContentTypeText {
+				text.WriteString(block.Text)
+			}
+		}
+		if text.Len() == 0 {
+			continue
+		}
+		if msg.Role == "user" {
+			s.SendEvent(EventTypeUserMessage, gin.H{"text": text.String()})
+		} else {
+			s.SendEvent(EventTypeAgentResponse, gin.H{"text": text.String()})
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/db"
+	"water-ai/llm"
+)
+
+// lastMessage returns the message of the last event of eventType.
+func lastMessage(conn *recordingConn, eventType string) string {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	var msg string
+	for _, e := range conn.events {
+		if e.Type == eventType {
+			content, _ := e.Content.(gin.H)
+			msg, _ = content["message"].(string)
+		}
+	}
+	return msg
+}
+
+func TestSlashClear(t *testing.T) {
+	setupReplayDB(t)
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient())
+	if _, _, err := db.Sessions.CreateSession(session.SessionUUID, session.Workspace, nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	db.Events.SaveEvent(session.SessionUUID, EventTypeUserMessage, RealtimeEvent{Type: EventTypeUserMessage})
+	session.History.AddUserPrompt("hello", nil)
+
+	session.handleSlashCommand("/clear")
+
+	if n := len(session.History.GetMessages()); n != 0 {
+		t.Errorf("history has %d messages after /clear; want 0", n)
+	}
+	if events, _ := db.Events.GetSessionEvents(session.SessionUUID); len(events) != 0 {
+		t.Errorf("session has %d events after /clear; want 0", len(events))
+	}
+	if got := lastMessage(conn, EventTypeSystem); got != "Conversation cleared." {
+		t.Errorf("system message = %q", got)
+	}
+}
+
+func TestSlashSaveAndLoad(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient())
+	session.History.AddUserPrompt("Build a todo app", nil)
+	session.History.AddAssistantTurn([]*llm.ContentBlock{llm.TextBlock("Done.")})
+
+	session.handleSlashCommand("/save todo-v1")
+	if _, err := os.Stat(session.savedHistoryPath("todo-v1")); err != nil {
+		t.Fatalf("saved history not written: %v", err)
+	}
+	if session.HistoryName != "todo-v1" {
+		t.Errorf("HistoryName = %q; want todo-v1", session.HistoryName)
+	}
+
+	session.History.Clear()
+	session.History.AddUserPrompt("something else", nil)
+	conn.events = nil
+
+	session.handleSlashCommand("/load todo-v1")
+	msgs := session.History.GetMessages()
+	if len(msgs) != 2 || msgs[0].Content[0].Text != "Build a todo app" || msgs[1].Content[0].Text != "Done." {
+		t.Fatalf("history after /load = %+v; want the saved turns", msgs)
+	}
+	want := []string{EventTypeUserMessage, EventTypeAgentResponse, EventTypeSystem, EventTypeStreamComplete}
+	if got := conn.types(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events after /load = %v; want %v", got, want)
+	}
+}
+
+func TestSlashCommandArgumentErrors(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"/save", "usage: /save <name>"},
+		{"/save a b", "usage: /save <name>"},
+		{"/save ../escape", "invalid name"},
+		{"/load", "usage: /load <name>"},
+		{"/load missing", "Use /save <name> first"},
+		{"/clear now", "/clear takes no arguments"},
+		{"/compact please", "/compact takes no arguments"},
+		{"/bogus", "Type /help"},
+	}
+	for _, tt := range tests {
+		conn := &recordingConn{}
+		session := newTestSession(t, conn, llm.NewMockClient())
+		session.handleSlashCommand(tt.cmd)
+		if got := lastMessage(conn, EventTypeError); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: error = %q; want it to contain %q", tt.cmd, got, tt.want)
+		}
+	}
+
+	// A missing name lists what is available.
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient())
+	session.handleSlashCommand("/save alpha")
+	session.handleSlashCommand("/load beta")
+	if got := lastMessage(conn, EventTypeError); !strings.Contains(got, "Saved conversations: alpha") {
+		t.Errorf("/load beta error = %q; want it to list alpha", got)
+	}
+}
+
+func TestSlashHelpListsCommands(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient())
+	session.handleSlashCommand("/help")
+	help := lastMessage(conn, EventTypeSystem)
+	for _, cmd := range []string{"/compact", "/clear", "/save <name>", "/load <name>"} {
+		if !strings.Contains(help, cmd) {
+			t.Errorf("/help does not mention %s", cmd)
+		}
+	}
+}
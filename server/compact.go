@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"water-ai/agents"
+	"water-ai/core"
+	"water-ai/llm"
+)
+
+// HistoryFileName is where a session's message history is persisted,
+// relative to its workspace.
+const HistoryFileName = ".water/history.json"
+
+// CompactSummaryPrefix starts the user turn that replaces a compacted
+// history.
+const CompactSummaryPrefix = "Summary of the conversation so far:\n\n"
+
+// historyPath returns the file the session's history is persisted to.
+func (s *ChatSession) historyPath() string {
+	return filepath.Join(s.Workspace, filepath.FromSlash(HistoryFileName))
+}
+
+// loadHistory returns the history persisted in the session's workspace, or
+// an empty history when there is none.
+func (s *ChatSession) loadHistory() *llm.MessageHistory {
+	history := llm.NewMessageHistory()
+	if err := history.LoadFromFile(s.historyPath()); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			core.Logger.Warn("failed to load persisted history", "session_id", s.SessionUUID.String(), "error", err)
+		}
+		return llm.NewMessageHistory()
+	}
+	return history
+}
+
+// persistHistory saves the session's history so a later init_agent in the
+// same workspace picks the conversation up again.
+func (s *ChatSession) persistHistory() {
+	if s.History == nil {
+		return
+	}
+	if err := s.History.SaveToFile(s.historyPath()); err != nil {
+		core.Logger.Error("failed to persist history", "session_id", s.SessionUUID.String(), "error", err)
+	}
+}
+
+// CompactResult reports the effect of compacting a session's history.
+type CompactResult struct {
+	Summary      string `json:"summary"`
+	TokensBefore int    `json:"tokens_before"`
+	TokensAfter  int    `json:"tokens_after"`
+}
+
+// compactHistory replaces the session history with a single user turn
+// holding an LLM-written summary of it and persists the result to
+// HistoryFileName in the workspace.
+func (s *ChatSession) compactHistory(ctx context.Context) (*CompactResult, error) {
+	if s.LLMClient == nil || s.History == nil {
+		return nil, fmt.Errorf("agent not initialized; send init_agent first")
+	}
+	if len(s.History.GetMessages()) == 0 {
+		return nil, fmt.Errorf("there is no conversation history to compact")
+	}
+
+	manager := agents.NewLLMContextManager(agents.NewLLMClientAdapter(&sessionClient{session: s}, s.llmConfig().Temperature), core.Logger, nil)
+	history := agents.NewLLMHistory(s.History)
+	before := manager.CountTokens(history.GetMessagesForLLM())
+
+	summary, err := manager.Summarize(ctx, history.GetMessagesForLLM())
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize history: %w", err)
+	}
+	if strings.TrimSpace(summary) == "" {
+		return nil, fmt.Errorf("failed to summarize history: the model returned an empty summary")
+	}
+
+	s.History.Clear()
+	s.History.AddUserPrompt(CompactSummaryPrefix+summary, nil)
+	after := manager.CountTokens(history.GetMessagesForLLM())
+
+	if err := s.History.SaveToFile(s.historyPath()); err != nil {
+		return nil, fmt.Errorf("history compacted but could not be saved: %w", err)
+	}
+	return &CompactResult{Summary: summary, TokensBefore: before, TokensAfter: after}, nil
+}
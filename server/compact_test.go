@@ -0,0 +1,124 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/llm"
+)
+
+// newTestSession returns a session attached to conn whose workspace is a
+// temporary directory and whose LLM client is mock.
+func newTestSession(t *testing.T, conn *recordingConn, mock llm.Client) *ChatSession {
+	t.Helper()
+	manager := NewConnectionManager(Config{WorkspaceRoot: t.TempDir()})
+	session := manager.Connect(conn, uuid.New().String())
+	session.LLMClient = mock
+	session.History = llm.NewMessageHistory()
+	return session
+}
+
+func TestSlashCompactSummarizesHistory(t *testing.T) {
+	mock := llm.NewMockClient().EnqueueBlocks(llm.TextBlock("User built a todo app; dark mode pending."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+	session.History.AddUserPrompt("Build a todo app "+strings.Repeat("with details ", 50), nil)
+	session.History.AddAssistantTurn([]*llm.ContentBlock{llm.TextBlock("Done. " + strings.Repeat("Here is the code. ", 50))})
+	session.History.AddUserPrompt("Add dark mode", nil)
+
+	session.handleSlashCommand("/compact")
+
+	msgs := session.History.GetMessages()
+	if len(msgs) != 1 || msgs[0].Role != "user" {
+		t.Fatalf("history after /compact has %d messages; want one user turn", len(msgs))
+	}
+	if got := msgs[0].Content[0].Text; got != CompactSummaryPrefix+"User built a todo app; dark mode pending." {
+		t.Errorf("summary turn = %q", got)
+	}
+
+	// The persisted history reconstructs to the same single turn.
+	reloaded := llm.NewMessageHistory()
+	if err := reloaded.LoadFromFile(session.historyPath()); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if len(reloaded.Messages) != 1 || reloaded.Messages[0].Content[0].Text != msgs[0].Content[0].Text {
+		t.Errorf("reloaded history = %+v; want the summary turn", reloaded.Messages)
+	}
+
+	// The summary prompt covered the whole conversation.
+	calls := mock.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("LLM called %d times; want 1", len(calls))
+	}
+	prompt := calls[0].Messages[0].Content[0].Text
+	if !strings.Contains(prompt, "Build a todo app") || !strings.Contains(prompt, "Add dark mode") {
+		t.Errorf("summary prompt is missing history: %q", prompt)
+	}
+
+	var system gin.H
+	for _, e := range conn.events {
+		if e.Type == EventTypeSystem {
+			system, _ = e.Content.(gin.H)
+		}
+	}
+	if system == nil {
+		t.Fatalf("no system event; got %v", conn.types())
+	}
+	before, _ := system["tokens_before"].(int)
+	after, _ := system["tokens_after"].(int)
+	if before <= after || after == 0 {
+		t.Errorf("tokens before/after = %d/%d; want a reduction", before, after)
+	}
+	if system["summary"] != "User built a todo app; dark mode pending." {
+		t.Errorf("summary = %v", system["summary"])
+	}
+}
+
+func TestSlashCompactErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*ChatSession)
+		want  string
+	}{
+		{"not initialized", func(s *ChatSession) { s.LLMClient = nil }, "not initialized"},
+		{"empty history", func(s *ChatSession) {}, "no conversation history"},
+	}
+	for _, tt := range tests {
+		conn := &recordingConn{}
+		session := newTestSession(t, conn, llm.NewMockClient())
+		tt.setup(session)
+
+		session.handleSlashCommand("/compact")
+
+		var msg string
+		for _, e := range conn.events {
+			if e.Type == EventTypeError {
+				content, _ := e.Content.(gin.H)
+				msg, _ = content["message"].(string)
+			}
+		}
+		if !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error = %q; want it to mention %q", tt.name, msg, tt.want)
+		}
+	}
+}
+
+func TestInitAgentReloadsCompactedHistory(t *testing.T) {
+	t.Setenv("LLM_API_KEY", "test-key")
+	mock := llm.NewMockClient().EnqueueBlocks(llm.TextBlock("User built a todo app."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+	session.History.AddUserPrompt("Build a todo app", nil)
+	session.History.AddAssistantTurn([]*llm.ContentBlock{llm.TextBlock("Done.")})
+	session.handleSlashCommand("/compact")
+
+	session.HandleMessage([]byte(`{"type": "init_agent", "content": {"model_name": "gpt-4o"}}`))
+
+	msgs := session.History.GetMessages()
+	if len(msgs) != 1 || msgs[0].Content[0].Text != CompactSummaryPrefix+"User built a todo app." {
+		t.Errorf("history after init_agent = %+v; want the compacted summary turn", msgs)
+	}
+}
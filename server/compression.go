@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+)
+
+// CompressionThreshold is the encoded size from which events are sent
+// compressed, when the connection negotiated per-message deflate. Smaller
+// events such as pongs and status updates cost more to deflate than they
+// save.
+const CompressionThreshold = 4 << 10
+
+// compressibleConn is implemented by connections that can toggle
+// compression per message, e.g. *websocket.Conn.
+type compressibleConn interface {
+	EnableWriteCompression(enable bool)
+	WriteMessage(messageType int, data []byte) error
+}
+
+// writeEvent writes msg to conn, compressing it only when it is at least
+// CompressionThreshold bytes. Callers hold the session lock, which
+// serializes writes to conn.
+func writeEvent(conn EventConn, msg RealtimeEvent) error {
+	cc, ok := conn.(compressibleConn)
+	if !ok {
+		return conn.WriteJSON(msg)
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	cc.EnableWriteCompression(len(data) >= CompressionThreshold)
+	return cc.WriteMessage(websocket.TextMessage, data)
+}
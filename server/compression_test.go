@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestLargeEventRoundTripsCompressed(t *testing.T) {
+	manager := NewConnectionManager(Config{WorkspaceRoot: t.TempDir()})
+	sessions := make(chan *ChatSession, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		sessions <- manager.Connect(conn, "")
+	}))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Fatalf("Sec-WebSocket-Extensions = %q; want permessage-deflate", ext)
+	}
+
+	screenshot := strings.Repeat("iVBORw0KGgoAAAANSUhEUgAA", 50000)
+	session := <-sessions
+	session.SendEvent(EventTypePong, gin.H{})
+	session.SendEvent("browser_use", gin.H{"screenshot": screenshot})
+
+	for _, want := range []string{EventTypePong, "browser_use"} {
+		var event struct {
+			Type    string            `json:"type"`
+			Content map[string]string `json:"content"`
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			t.Fatalf("event is not JSON: %v", err)
+		}
+		if event.Type != want {
+			t.Fatalf("event type = %q; want %q", event.Type, want)
+		}
+		if want == "browser_use" && event.Content["screenshot"] != screenshot {
+			t.Errorf("screenshot did not round-trip: got %d bytes; want %d", len(event.Content["screenshot"]), len(screenshot))
+		}
+	}
+}
+
+// compressionRecorder records whether each message was sent compressed.
+type compressionRecorder struct {
+	recordingConn
+	enabled    bool
+	compressed []bool
+}
+
+func (c *compressionRecorder) EnableWriteCompression(enable bool) { c.enabled = enable }
+
+func (c *compressionRecorder) WriteMessage(messageType int, data []byte) error {
+	c.compressed = append(c.compressed, c.enabled)
+	return nil
+}
+
+func TestWriteEventCompressesOnlyLargeEvents(t *testing.T) {
+	conn := &compressionRecorder{}
+	writeEvent(conn, RealtimeEvent{Type: EventTypePong, Content: gin.H{}})
+	writeEvent(conn, RealtimeEvent{Type: "browser_use", Content: gin.H{"screenshot": strings.Repeat("a", CompressionThreshold)}})
+	if len(conn.compressed) != 2 || conn.compressed[0] || !conn.compressed[1] {
+		t.Errorf("compressed = %v; want [false true]", conn.compressed)
+	}
+}
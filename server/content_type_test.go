@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newWorkspaceRouter(root string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/workspace/*filepath", workspaceFileHandler(root))
+	return router
+}
+
+func TestWorkspaceServesExtensionlessTextFile(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "README"), []byte("just some plain text\n"), 0644)
+
+	w := httptest.NewRecorder()
+	newWorkspaceRouter(root).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workspace/README", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q; want text/plain; charset=utf-8", ct)
+	}
+}
+
+func TestWorkspaceServesBinaryFile(t *testing.T) {
+	root := t.TempDir()
+	// PNG signature followed by binary noise, saved without an extension.
+	png := append([]byte("\x89PNG\r\n\x1a\n"), 0x00, 0x01, 0x02, 0xff)
+	os.WriteFile(filepath.Join(root, "image_blob"), png, 0644)
+	os.WriteFile(filepath.Join(root, "data.bin"), []byte{0x00, 0x01, 0x02, 0x03, 0xfe}, 0644)
+
+	router := newWorkspaceRouter(root)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workspace/image_blob", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q; want image/png", ct)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workspace/data.bin", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Content-Type = %q; want application/octet-stream", ct)
+	}
+}
+
+func TestWorkspaceKnownExtensionWins(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "app.js"), []byte("console.log('hi')"), 0644)
+
+	w := httptest.NewRecorder()
+	newWorkspaceRouter(root).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workspace/app.js", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/javascript" {
+		t.Errorf("Content-Type = %q; want application/javascript", ct)
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	if ct := sniffContentType("notes", []byte("hello")); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("sniffContentType(text) = %q; want text/plain", ct)
+	}
+	if ct := sniffContentType("page.html", []byte{0x00}); ct != "text/html; charset=utf-8" {
+		t.Errorf("sniffContentType(.html) = %q; want extension mapping", ct)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"water-ai/core"
+	"water-ai/db"
+)
+
+// recordEvent saves an event of the session so it can be resumed,
+// searched and exported. The session's record is created with its first
+// event. Failures are logged so a database problem does not stop the turn.
+func (s *ChatSession) recordEvent(eventType string, content interface{}) {
+	if db.DB == nil {
+		return
+	}
+	if err := s.ensureRecord(); err != nil {
+		core.Logger.Error("failed to create session record", "session_id", s.SessionUUID.String(), "error", err)
+		return
+	}
+	if err := db.Events.Save(s.SessionUUID.String(), eventType, RealtimeEvent{Type: eventType, Content: content}); err != nil {
+		core.Logger.Error("failed to save event", "session_id", s.SessionUUID.String(), "type", eventType, "error", err)
+	}
+}
+
+// ensureRecord creates the session's database record unless it exists.
+func (s *ChatSession) ensureRecord() error {
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	if s.recorded {
+		return nil
+	}
+	sess, err := db.Sessions.GetSessionByID(s.SessionUUID)
+	if err != nil {
+		return err
+	}
+	if sess == nil {
+		if _, _, err := db.Sessions.CreateSession(s.SessionUUID, s.Workspace, nil, nil); err != nil {
+			return err
+		}
+	}
+	s.recorded = true
+	return nil
+}
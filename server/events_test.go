@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/db"
+	"water-ai/llm"
+)
+
+func TestQueryPersistsEventsInOrder(t *testing.T) {
+	setupReplayDB(t)
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "echo hi"})).
+		EnqueueBlocks(llm.TextBlock("All done."))
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, mock)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.HandleMessage([]byte(`{"type":"query","content":{"text":"say hi"}}`))
+	}()
+	awaitEvent(t, conn, EventTypeToolCall, func(c gin.H) bool { return c["awaiting_approval"] == true })
+	answer(t, session, conn, "approve", "call-1")
+	<-done
+
+	sess, err := db.Sessions.GetSessionByID(session.SessionUUID)
+	if err != nil || sess == nil {
+		t.Fatalf("session record = %v, %v; want it created with the first event", sess, err)
+	}
+	events, err := db.Events.GetSessionEvents(session.SessionUUID)
+	if err != nil {
+		t.Fatalf("GetSessionEvents() error = %v", err)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+
+	var types []string
+	for _, evt := range events {
+		types = append(types, evt.EventType)
+	}
+	want := []string{EventTypeUserMessage, EventTypeToolCall, EventTypeToolResult, EventTypeAgentResponse}
+	next := 0
+	for _, typ := range types {
+		if next < len(want) && typ == want[next] {
+			next++
+		}
+	}
+	if next != len(want) || types[0] != EventTypeUserMessage {
+		t.Fatalf("saved events = %v; want %v in order", types, want)
+	}
+
+	if prompts := ExtractUserPrompts(events); len(prompts) != 1 || prompts[0].Text != "say hi" {
+		t.Errorf("saved user prompts = %+v; want the query", prompts)
+	}
+	for _, evt := range events {
+		if evt.EventType != EventTypeAgentResponse {
+			continue
+		}
+		var saved RealtimeEvent
+		json.Unmarshal(evt.EventPayload, &saved)
+		if content, _ := saved.Content.(map[string]interface{}); content["text"] != "All done." {
+			t.Errorf("saved agent_response = %v; want the final answer", saved.Content)
+		}
+	}
+}
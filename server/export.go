@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+// TranscriptVersion is the format version written by ExportSession.
+const TranscriptVersion = 1
+
+var errSessionNotFound = errors.New("session not found")
+
+// SessionTranscript is the portable JSON form of a recorded session.
+type SessionTranscript struct {
+	Version    int               `json:"version"`
+	SessionID  string            `json:"session_id"`
+	Name       string            `json:"name,omitempty"`
+	CreatedAt  string            `json:"created_at"`
+	ExportedAt string            `json:"exported_at"`
+	Events     []TranscriptEvent `json:"events"`
+}
+
+// TranscriptEvent is one recorded event. Payload is the stored event
+// payload with secrets scrubbed.
+type TranscriptEvent struct {
+	ID        string      `json:"id"`
+	Timestamp string      `json:"timestamp"`
+	Type      string      `json:"type"`
+	Payload   interface{} `json:"payload"`
+}
+
+// secretKeyPattern matches map keys whose values are credentials.
+var secretKeyPattern = regexp.MustCompile(`(?i)(api[_-]?key|secret|token|password|passwd|authorization|credential)`)
+
+// secretValuePatterns match credentials embedded in free text.
+var secretValuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}`),                   // OpenAI / Anthropic
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`),                   // Google
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`),               // GitHub
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`),            // Slack
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/\-]{8,}=*`),    // Authorization headers
+	regexp.MustCompile(`(?i)\b(api[_-]?key|token|secret)=[^&\s"']+`), // query strings
+}
+
+// scrubSecrets returns a copy of v with credential-like map values and
+// strings replaced by a redaction marker.
+func scrubSecrets(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if s, ok := item.(string); ok && s != "" && secretKeyPattern.MatchString(k) {
+				out[k] = redactedSecret
+				continue
+			}
+			out[k] = scrubSecrets(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = scrubSecrets(item)
+		}
+		return out
+	case string:
+		return scrubString(val)
+	default:
+		return v
+	}
+}
+
+func scrubString(s string) string {
+	for _, re := range secretValuePatterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if key, _, ok := strings.Cut(match, "="); ok && !strings.HasPrefix(strings.ToLower(match), "bearer") {
+				return key + "=" + redactedSecret
+			}
+			return redactedSecret
+		})
+	}
+	return s
+}
+
+// BuildTranscript collects a session and its events, oldest first, into a
+// SessionTranscript with secrets scrubbed.
+func BuildTranscript(sessionID uuid.UUID) (*SessionTranscript, error) {
+	sess, err := db.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, errSessionNotFound
+	}
+	events, err := db.Events.GetSessionEventsWithDetails(sessionID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := &SessionTranscript{
+		Version:    TranscriptVersion,
+		SessionID:  sess.ID,
+		CreatedAt:  sess.CreatedAt.Format(time.RFC3339),
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Events:     make([]TranscriptEvent, 0, len(events)),
+	}
+	if sess.Name != nil {
+		transcript.Name = *sess.Name
+	}
+	for _, evt := range events {
+		id, _ := evt["id"].(string)
+		ts, _ := evt["timestamp"].(string)
+		typ, _ := evt["event_type"].(string)
+		transcript.Events = append(transcript.Events, TranscriptEvent{
+			ID:        id,
+			Timestamp: ts,
+			Type:      typ,
+			Payload:   scrubSecrets(evt["event_payload"]),
+		})
+	}
+	return transcript, nil
+}
+
+// eventContent unwraps a payload stored as a full RealtimeEvent
+// ({"type": ..., "content": ...}) to its content.
+func eventContent(payload interface{}) interface{} {
+	if m, ok := payload.(map[string]interface{}); ok {
+		if content, ok := m["content"]; ok {
+			if _, typed := m["type"]; typed {
+				return content
+			}
+		}
+	}
+	return payload
+}
+
+// RenderTranscriptMarkdown formats a transcript as a readable log.
+func RenderTranscriptMarkdown(t *SessionTranscript) string {
+	var b strings.Builder
+	title := t.Name
+	if title == "" {
+		title = t.SessionID
+	}
+	fmt.Fprintf(&b, "# Session: %s\n\n", title)
+	fmt.Fprintf(&b, "- Session ID: `%s`\n- Created: %s\n- Exported: %s\n", t.SessionID, t.CreatedAt, t.ExportedAt)
+
+	for _, evt := range t.Events {
+		content := eventContent(evt.Payload)
+		fields, _ := content.(map[string]interface{})
+		str := func(key string) string {
+			s, _ := fields[key].(string)
+			return s
+		}
+
+		switch evt.Type {
+		case EventTypeUserMessage:
+			fmt.Fprintf(&b, "\n## User — %s\n\n%s\n", evt.Timestamp, str("text"))
+			if files, ok := fields["files"].([]interface{}); ok && len(files) > 0 {
+				b.WriteString("\nAttached files:\n")
+				for _, f := range files {
+					fmt.Fprintf(&b, "- `%v`\n", f)
+				}
+			}
+		case EventTypeAgentResponse:
+			fmt.Fprintf(&b, "\n## Agent — %s\n\n%s\n", evt.Timestamp, str("text"))
+		case EventTypeAgentThinking:
+			fmt.Fprintf(&b, "\n### Thinking — %s\n\n> %s\n", evt.Timestamp, strings.ReplaceAll(str("text"), "\n", "\n> "))
+		case EventTypeToolCall:
+			input, _ := json.MarshalIndent(fields["tool_input"], "", "  ")
+			fmt.Fprintf(&b, "\n### Tool call: %s — %s\n\n%s", str("tool_name"), evt.Timestamp, fenced("json", string(input)))
+		case EventTypeToolResult:
+			if partial, _ := fields["partial"].(bool); partial {
+				continue
+			}
+			result, ok := fields["result"].(string)
+			if !ok {
+				raw, _ := json.MarshalIndent(fields["result"], "", "  ")
+				result = string(raw)
+			}
+			fmt.Fprintf(&b, "\n### Tool result: %s — %s\n\n%s", str("tool_name"), evt.Timestamp, fenced("", result))
+		case EventTypeError:
+			fmt.Fprintf(&b, "\n## Error — %s\n\n%s\n", evt.Timestamp, str("message"))
+		default:
+			raw, _ := json.MarshalIndent(content, "", "  ")
+			fmt.Fprintf(&b, "\n### %s — %s\n\n%s", evt.Type, evt.Timestamp, fenced("json", string(raw)))
+		}
+	}
+	return b.String()
+}
+
+// fenced wraps text in a code fence longer than any backtick run inside it.
+func fenced(lang, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + fence + "\n"
+}
+
+// ExportSessionHandler handles GET /api/sessions/:id/export?format=json|markdown.
+func (s *Server) ExportSessionHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" && format != "md" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or markdown"})
+		return
+	}
+
+	transcript, err := BuildTranscript(sessionID)
+	if errors.Is(err, errSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := "session-" + sessionID.String()
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, transcript)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, filename))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(RenderTranscriptMarkdown(transcript)))
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+// seedExportSession records a short session with a tool call that carries
+// an API key, returning its ID.
+func seedExportSession(t *testing.T, root string) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	if _, _, err := db.Sessions.CreateSession(id, filepath.Join(root, id.String()), nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	db.Sessions.UpdateSessionName(id, "Weather lookup")
+
+	events := []RealtimeEvent{
+		{Type: EventTypeUserMessage, Content: map[string]interface{}{"text": "What's the weather in Oslo?"}},
+		{Type: EventTypeToolCall, Content: map[string]interface{}{
+			"tool_call_id": "call_1",
+			"tool_name":    "weather_api",
+			"tool_input":   map[string]interface{}{"city": "Oslo", "api_key": "wk-123456"},
+		}},
+		{Type: EventTypeToolResult, Content: map[string]interface{}{
+			"tool_call_id": "call_1",
+			"tool_name":    "weather_api",
+			"result":       "GET https://api.example.com/w?city=Oslo&api_key=wk-123456\n```\n-3C, snow\n```",
+		}},
+		{Type: EventTypeAgentResponse, Content: map[string]interface{}{"text": "It is -3C and snowing in Oslo."}},
+	}
+	for _, evt := range events {
+		if _, err := db.Events.SaveEvent(id, evt.Type, evt); err != nil {
+			t.Fatalf("SaveEvent() error = %v", err)
+		}
+		time.Sleep(5 * time.Millisecond) // keep timestamps ordered
+	}
+	return id
+}
+
+func getExport(t *testing.T, srv *Server, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestExportSessionJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	w := getExport(t, srv, "/api/sessions/"+id.String()+"/export?format=json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; body %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "wk-123456") {
+		t.Errorf("export leaks the API key: %s", w.Body.String())
+	}
+
+	var transcript SessionTranscript
+	if err := json.Unmarshal(w.Body.Bytes(), &transcript); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if transcript.SessionID != id.String() || transcript.Name != "Weather lookup" || transcript.Version != TranscriptVersion {
+		t.Errorf("header = %+v", transcript)
+	}
+	wantTypes := []string{EventTypeUserMessage, EventTypeToolCall, EventTypeToolResult, EventTypeAgentResponse}
+	if len(transcript.Events) != len(wantTypes) {
+		t.Fatalf("events = %d; want %d", len(transcript.Events), len(wantTypes))
+	}
+	for i, want := range wantTypes {
+		if transcript.Events[i].Type != want {
+			t.Errorf("event %d type = %s; want %s", i, transcript.Events[i].Type, want)
+		}
+	}
+	input := eventContent(transcript.Events[1].Payload).(map[string]interface{})["tool_input"].(map[string]interface{})
+	if input["city"] != "Oslo" || input["api_key"] != redactedSecret {
+		t.Errorf("tool_input = %v; want city kept and api_key redacted", input)
+	}
+}
+
+func TestExportSessionMarkdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	w := getExport(t, srv, "/api/sessions/"+id.String()+"/export?format=markdown")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; body %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("Content-Type = %q; want text/markdown", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# Session: Weather lookup",
+		"## User — ",
+		"What's the weather in Oslo?",
+		"### Tool call: weather_api",
+		"\"city\": \"Oslo\"",
+		"### Tool result: weather_api",
+		"````\nGET https://api.example.com/w?city=Oslo&api_key=" + redactedSecret,
+		"## Agent — ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("markdown missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "wk-123456") {
+		t.Errorf("markdown leaks the API key:\n%s", body)
+	}
+	if strings.Index(body, "## User") > strings.Index(body, "## Agent") {
+		t.Error("events are not in chronological order")
+	}
+}
+
+func TestExportSessionErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/sessions/not-a-uuid/export", http.StatusBadRequest},
+		{"/api/sessions/" + uuid.NewString() + "/export", http.StatusNotFound},
+		{"/api/sessions/" + uuid.NewString() + "/export?format=pdf", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := getExport(t, srv, tt.path); w.Code != tt.want {
+			t.Errorf("GET %s = %d; want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
+
+func TestScrubSecrets(t *testing.T) {
+	in := map[string]interface{}{
+		"headers": map[string]interface{}{"Authorization": "Bearer abcdefghijkl"},
+		"args":    []interface{}{"--key", "sk-ant-REDACTED"},
+		"note":    "use AIza" + strings.Repeat("x", 35) + " here",
+		"count":   3.0,
+	}
+	out := scrubSecrets(in).(map[string]interface{})
+	js, _ := json.Marshal(out)
+	for _, secret := range []string{"abcdefghijkl", "sk-ant", "AIza"} {
+		if strings.Contains(string(js), secret) {
+			t.Errorf("scrubbed output still contains %q: %s", secret, js)
+		}
+	}
+	if out["count"] != 3.0 {
+		t.Errorf("count = %v; want non-secret values untouched", out["count"])
+	}
+}
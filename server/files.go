@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errPathEscapesWorkspace = errors.New("path escapes workspace")
+
+// resolveSessionPath maps a session-relative path to a file under that
+// session's workspace, refusing anything that resolves outside it,
+// including through symlinks.
+func resolveSessionPath(root, sessionID, relPath string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	workspace := filepath.Join(root, sessionID)
+	if sessionID == "" || !strings.HasPrefix(workspace, root+string(filepath.Separator)) {
+		return "", errPathEscapesWorkspace
+	}
+
+	fullPath := filepath.Join(workspace, filepath.FromSlash(relPath))
+	if !strings.HasPrefix(fullPath, workspace+string(filepath.Separator)) {
+		return "", errPathEscapesWorkspace
+	}
+
+	// A symlink inside the workspace must not lead outside it.
+	if resolved, err := filepath.EvalSymlinks(fullPath); err == nil {
+		realWorkspace, err := filepath.EvalSymlinks(workspace)
+		if err != nil || !strings.HasPrefix(resolved, realWorkspace+string(filepath.Separator)) {
+			return "", errPathEscapesWorkspace
+		}
+	}
+	return fullPath, nil
+}
+
+// SessionFileHandler handles GET /api/sessions/:id/files/*path, streaming
+// a file from a session workspace. Traversal attempts get 403 and missing
+// files (or directories) get 404.
+func (s *Server) SessionFileHandler(c *gin.Context) {
+	fullPath, err := resolveSessionPath(s.Config.WorkspaceRoot, c.Param("id"), c.Param("path"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+
+	if ct := detectContentType(fullPath); ct != "" {
+		c.Header("Content-Type", ct)
+	}
+	c.File(fullPath)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSessionFileDownload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	root := t.TempDir()
+	sessionID := "3f1c9a52-7c1e-4c8e-9a57-0a4c1d2e3f40"
+	os.MkdirAll(filepath.Join(root, sessionID, "out"), 0755)
+	os.WriteFile(filepath.Join(root, sessionID, "out", "report.json"), []byte(`{"ok":true}`), 0644)
+	os.WriteFile(filepath.Join(root, "other.txt"), []byte("outside"), 0644)
+
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantType string
+		wantBody string
+	}{
+		{"normal file", "/files/out/report.json", http.StatusOK, "application/json", `{"ok":true}`},
+		{"missing file", "/files/out/missing.txt", http.StatusNotFound, "", ""},
+		{"directory", "/files/out", http.StatusNotFound, "", ""},
+		{"traversal", "/files/../../etc/passwd", http.StatusForbidden, "", ""},
+		{"sibling escape", "/files/../other.txt", http.StatusForbidden, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/sessions/"+sessionID+tt.path, nil)
+			srv.Router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d; want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantType != "" && w.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("Content-Type = %q; want %q", w.Header().Get("Content-Type"), tt.wantType)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q; want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestResolveSessionPathRejectsEscapes(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "s1")
+	os.MkdirAll(workspace, 0755)
+	os.Symlink("/etc", filepath.Join(workspace, "link"))
+
+	for _, tc := range []struct{ session, path string }{
+		{"..", "etc/passwd"},
+		{"", "etc/passwd"},
+		{"s1", "../../etc/passwd"},
+		{"s1", "link/passwd"},
+	} {
+		if _, err := resolveSessionPath(root, tc.session, tc.path); err == nil {
+			t.Errorf("resolveSessionPath(%q, %q) should be rejected", tc.session, tc.path)
+		}
+	}
+
+	got, err := resolveSessionPath(root, "s1", "/a/b.txt")
+	if err != nil || got != filepath.Join(workspace, "a", "b.txt") {
+		t.Errorf("resolveSessionPath() = %q, %v; want %q", got, err, filepath.Join(workspace, "a", "b.txt"))
+	}
+}
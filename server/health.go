@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/db"
+)
+
+// BuildInfo carries the version details injected via ldflags
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// SubsystemStatus reports the health of one dependency
+type SubsystemStatus struct {
+	Status string `json:"status"` // "ok" or "error"
+	Error  string `json:"error,omitempty"`
+}
+
+// DetailedHealth is the response body of GET /health/detailed
+type DetailedHealth struct {
+	Status        string                     `json:"status"` // "ok" or "degraded"
+	UptimeSeconds float64                    `json:"uptime_seconds"`
+	Sessions      int                        `json:"active_sessions"`
+	Subsystems    map[string]SubsystemStatus `json:"subsystems"`
+	Build         BuildInfo                  `json:"build"`
+}
+
+const healthCheckTimeout = 2 * time.Second
+
+// pingDB runs SELECT 1 against the global database
+func pingDB(ctx context.Context) error {
+	if db.DB == nil {
+		return errors.New("database not initialized")
+	}
+	return db.DB.WithContext(ctx).Exec("SELECT 1").Error
+}
+
+// DetailedHealthHandler reports uptime, session count, build info and the
+// status of each subsystem. A failing subsystem marks the overall status
+// as degraded but never fails the request itself.
+func (s *Server) DetailedHealthHandler(c *gin.Context) {
+	resp := DetailedHealth{
+		Status:     "ok",
+		Subsystems: map[string]SubsystemStatus{},
+		Build:      s.Build,
+	}
+	if !s.StartedAt.IsZero() {
+		resp.UptimeSeconds = time.Since(s.StartedAt).Seconds()
+	}
+	if s.WSManager != nil {
+		resp.Sessions = s.WSManager.ActiveSessions()
+	}
+
+	check := s.DBCheck
+	if check == nil {
+		check = pingDB
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+	if err := check(ctx); err != nil {
+		resp.Subsystems["database"] = SubsystemStatus{Status: "error", Error: err.Error()}
+		resp.Status = "degraded"
+	} else {
+		resp.Subsystems["database"] = SubsystemStatus{Status: "ok"}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
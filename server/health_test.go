@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getDetailedHealth(t *testing.T, srv *Server) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/health/detailed", srv.DetailedHealthHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/detailed", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	return w.Code, body
+}
+
+func TestDetailedHealthHandlerOK(t *testing.T) {
+	srv := &Server{
+		WSManager: NewConnectionManager(Config{}),
+		StartedAt: time.Now().Add(-time.Minute),
+		Build:     BuildInfo{Version: "1.2.3", GitCommit: "abc123"},
+		DBCheck:   func(ctx context.Context) error { return nil },
+	}
+
+	code, body := getDetailedHealth(t, srv)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", code)
+	}
+	if body["status"] != "ok" {
+		t.Errorf("status = %v; want ok", body["status"])
+	}
+	if uptime, _ := body["uptime_seconds"].(float64); uptime < 59 {
+		t.Errorf("uptime_seconds = %v; want ~60", body["uptime_seconds"])
+	}
+	if body["active_sessions"] != float64(0) {
+		t.Errorf("active_sessions = %v; want 0", body["active_sessions"])
+	}
+
+	build, _ := body["build"].(map[string]interface{})
+	if build["version"] != "1.2.3" || build["git_commit"] != "abc123" {
+		t.Errorf("build = %v; want version 1.2.3 / abc123", build)
+	}
+
+	subsystems, _ := body["subsystems"].(map[string]interface{})
+	dbStatus, _ := subsystems["database"].(map[string]interface{})
+	if dbStatus["status"] != "ok" {
+		t.Errorf("database status = %v; want ok", dbStatus)
+	}
+}
+
+func TestDetailedHealthHandlerDegradesOnDBFailure(t *testing.T) {
+	srv := &Server{
+		WSManager: NewConnectionManager(Config{}),
+		DBCheck:   func(ctx context.Context) error { return errors.New("database is locked") },
+	}
+
+	code, body := getDetailedHealth(t, srv)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d; want 200 even when a subsystem fails", code)
+	}
+	if body["status"] != "degraded" {
+		t.Errorf("status = %v; want degraded", body["status"])
+	}
+
+	subsystems, _ := body["subsystems"].(map[string]interface{})
+	dbStatus, _ := subsystems["database"].(map[string]interface{})
+	if dbStatus["status"] != "error" || dbStatus["error"] != "database is locked" {
+		t.Errorf("database = %v; want error with message", dbStatus)
+	}
+	if _, ok := body["build"]; !ok {
+		t.Error("build info should still be reported")
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"water-ai/db"
+)
+
+// maxTranscriptBytes bounds the size of an uploaded transcript.
+const maxTranscriptBytes = 50 << 20
+
+// validateTranscript reports the first problem that makes t unusable for
+// import.
+func validateTranscript(t *SessionTranscript) error {
+	if t.Version < 1 || t.Version > TranscriptVersion {
+		return fmt.Errorf("unsupported transcript version %d", t.Version)
+	}
+	if t.Events == nil {
+		return errors.New("events is required")
+	}
+	for i, evt := range t.Events {
+		if evt.Type == "" {
+			return fmt.Errorf("events[%d]: type is required", i)
+		}
+		if evt.Payload == nil {
+			return fmt.Errorf("events[%d]: payload is required", i)
+		}
+	}
+	return nil
+}
+
+// replaceSessionID returns a copy of v with every string equal to oldID
+// replaced by newID, so payloads refer to the imported session.
+func replaceSessionID(v interface{}, oldID, newID string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = replaceSessionID(item, oldID, newID)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = replaceSessionID(item, oldID, newID)
+		}
+		return out
+	case string:
+		if oldID != "" && val == oldID {
+			return newID
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// ImportTranscript creates a new session with its own workspace under
+// workspaceRoot and records the transcript's events in order. It returns
+// the ID of the new session.
+func ImportTranscript(t *SessionTranscript, workspaceRoot string) (uuid.UUID, error) {
+	if err := validateTranscript(t); err != nil {
+		return uuid.Nil, err
+	}
+
+	newID := uuid.New()
+	workspace := filepath.Join(workspaceRoot, newID.String())
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return uuid.Nil, err
+	}
+
+	name := fmt.Sprintf("Imported session %.8s", t.SessionID)
+	if t.Name != "" {
+		name = t.Name + " (imported)"
+	}
+
+	// The session and its events are written together so a failed import
+	// leaves no partial session behind.
+	err := db.WithTransaction(func(tx *gorm.DB) error {
+		if _, _, err := db.Sessions.CreateSession(newID, workspace, nil, nil, tx); err != nil {
+			return err
+		}
+		if err := db.Sessions.UpdateSessionName(newID, name, tx); err != nil {
+			return err
+		}
+		for i, evt := range t.Events {
+			payload := replaceSessionID(evt.Payload, t.SessionID, newID.String())
+			if _, err := db.Events.SaveEvent(newID, evt.Type, payload, tx); err != nil {
+				return fmt.Errorf("failed to save event %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		os.RemoveAll(workspace)
+		return uuid.Nil, err
+	}
+	return newID, nil
+}
+
+// ImportSessionHandler handles POST /api/sessions/import with a JSON
+// transcript produced by the export endpoint.
+func (s *Server) ImportSessionHandler(c *gin.Context) {
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	var transcript SessionTranscript
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTranscriptBytes)
+	if err := json.NewDecoder(c.Request.Body).Decode(&transcript); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transcript: " + err.Error()})
+		return
+	}
+	if err := validateTranscript(&transcript); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transcript: " + err.Error()})
+		return
+	}
+
+	newID, err := ImportTranscript(&transcript, s.Config.GetWorkspaceRoot())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"session_id": newID.String(),
+		"events":     len(transcript.Events),
+	})
+}
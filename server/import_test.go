@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+func postImport(t *testing.T, srv *Server, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/import", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	srv.Router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportSessionRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	sourceID := seedExportSession(t, root)
+	db.Events.SaveEvent(sourceID, EventTypeSessionSummary, RealtimeEvent{
+		Type:    EventTypeSessionSummary,
+		Content: SessionSummaryCard{SessionID: sourceID.String(), Asked: "weather"},
+	})
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	exported := getExport(t, srv, "/api/sessions/"+sourceID.String()+"/export")
+	if exported.Code != http.StatusOK {
+		t.Fatalf("export status = %d", exported.Code)
+	}
+
+	w := postImport(t, srv, exported.Body.Bytes())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("import status = %d; body %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		SessionID string `json:"session_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	newID, err := uuid.Parse(resp.SessionID)
+	if err != nil || newID == sourceID {
+		t.Fatalf("session_id = %q; want a new UUID", resp.SessionID)
+	}
+
+	sess, _ := db.Sessions.GetSessionByID(newID)
+	if sess == nil {
+		t.Fatal("imported session was not created")
+	}
+	if info, err := os.Stat(sess.WorkspaceDir); err != nil || !info.IsDir() {
+		t.Errorf("workspace %s was not created", sess.WorkspaceDir)
+	}
+	if sess.Name == nil || *sess.Name != "Weather lookup (imported)" {
+		t.Errorf("Name = %v", sess.Name)
+	}
+
+	original, _ := BuildTranscript(sourceID)
+	imported, _ := BuildTranscript(newID)
+	if len(imported.Events) != len(original.Events) {
+		t.Fatalf("imported %d events; want %d", len(imported.Events), len(original.Events))
+	}
+	for i := range original.Events {
+		if imported.Events[i].Type != original.Events[i].Type {
+			t.Errorf("event %d type = %s; want %s", i, imported.Events[i].Type, original.Events[i].Type)
+		}
+	}
+	card := eventContent(imported.Events[len(imported.Events)-1].Payload).(map[string]interface{})
+	if card["session_id"] != newID.String() {
+		t.Errorf("summary session_id = %v; want it rewritten to %s", card["session_id"], newID)
+	}
+}
+
+func TestImportSessionRejectsMalformedTranscripts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"not json", `{"version": 1, "events": [`, "invalid transcript"},
+		{"bad version", `{"version": 99, "events": []}`, "unsupported transcript version 99"},
+		{"missing events", `{"version": 1}`, "events is required"},
+		{"event without type", `{"version": 1, "events": [{"payload": {}}]}`, "events[0]: type is required"},
+		{"event without payload", `{"version": 1, "events": [{"type": "user_message"}, {"type": "tool_call"}]}`, "events[0]: payload is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postImport(t, srv, []byte(tt.body))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d; want 400", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantErr) {
+				t.Errorf("body = %s; want it to mention %q", w.Body.String(), tt.wantErr)
+			}
+		})
+	}
+}
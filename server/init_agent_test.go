@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/core/config"
+	"water-ai/prompts"
+	"water-ai/tools"
+)
+
+func TestInitAgentSandbox(t *testing.T) {
+	t.Setenv("LLM_API_KEY", "test-key")
+	stubDockerWorkspace(t, &fakeSandbox{})
+	stubE2BWorkspace(t, &fakeSandbox{})
+
+	tests := []struct {
+		name     string
+		content  string
+		wantMode config.WorkSpaceMode
+		wantErr  bool
+	}{
+		{"default is local", `{"model_name": "gpt-4o"}`, config.WorkSpaceModeLocal, false},
+		{"docker", `{"model_name": "gpt-4o", "sandbox": {"mode": "docker"}}`, config.WorkSpaceModeDocker, false},
+		{"e2b with key", `{"model_name": "gpt-4o", "sandbox": {"mode": "e2b", "sandbox_api_key": "e2b-key", "template_id": "tpl"}}`, config.WorkSpaceModeE2B, false},
+		{"e2b without key", `{"model_name": "gpt-4o", "sandbox": {"mode": "e2b"}}`, "", true},
+		{"unknown mode", `{"model_name": "gpt-4o", "sandbox": {"mode": "vm"}}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &recordingConn{}
+			session := newTestSession(t, conn, nil)
+			session.LLMClient = nil
+
+			session.HandleMessage([]byte(`{"type": "init_agent", "content": ` + tt.content + `}`))
+
+			if tt.wantErr {
+				if session.LLMClient != nil {
+					t.Error("agent initialized despite invalid sandbox settings")
+				}
+				if lastMessage(conn, EventTypeError) == "" {
+					t.Error("no error event for invalid sandbox settings")
+				}
+				return
+			}
+			if session.LLMClient == nil {
+				t.Fatalf("agent not initialized: %q", lastMessage(conn, EventTypeError))
+			}
+			if session.Sandbox.Mode != tt.wantMode {
+				t.Errorf("Sandbox.Mode = %q; want %q", session.Sandbox.Mode, tt.wantMode)
+			}
+			if want := prompts.GetSystemPrompt(promptWorkspaceMode(tt.wantMode), false); session.SystemPrompt != want {
+				t.Errorf("system prompt does not match the %s workspace mode", tt.wantMode)
+			}
+		})
+	}
+}
+
+type fakeSandbox struct {
+	mu      sync.Mutex
+	stopped bool
+}
+
+func (f *fakeSandbox) CodeServerURL() string { return "http://localhost:49152" }
+
+func (f *fakeSandbox) Stop(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	return nil
+}
+
+func (f *fakeSandbox) isStopped() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stopped
+}
+
+func stubDockerWorkspace(t *testing.T, ws sandboxWorkspace) {
+	orig := startDockerWorkspace
+	startDockerWorkspace = func(ctx context.Context, s *ChatSession) (sandboxWorkspace, error) { return ws, nil }
+	t.Cleanup(func() { startDockerWorkspace = orig })
+}
+
+// stubE2BWorkspace makes init_agent start ws for e2b sandboxes and returns
+// the settings it was started with.
+func stubE2BWorkspace(t *testing.T, ws sandboxWorkspace) *config.SandboxConfig {
+	var started config.SandboxConfig
+	orig := startE2BWorkspace
+	startE2BWorkspace = func(ctx context.Context, s *ChatSession, cfg config.SandboxConfig) (sandboxWorkspace, error) {
+		started = cfg
+		return ws, nil
+	}
+	t.Cleanup(func() { startE2BWorkspace = orig })
+	return &started
+}
+
+func TestInitAgentDockerSandboxLifecycle(t *testing.T) {
+	t.Setenv("LLM_API_KEY", "test-key")
+	ws := &fakeSandbox{}
+	stubDockerWorkspace(t, ws)
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+
+	session.HandleMessage([]byte(`{"type": "init_agent", "content": {"model_name": "gpt-4o", "sandbox": {"mode": "docker"}}}`))
+
+	var url interface{}
+	for _, e := range conn.events {
+		if e.Type == EventTypeAgentInitialized {
+			url = e.Content.(gin.H)["vscode_url"]
+		}
+	}
+	if url != "http://localhost:49152" {
+		t.Errorf("vscode_url = %v; want the container's code-server", url)
+	}
+
+	session.Manager.Disconnect(conn)
+	deadline := time.Now().Add(2 * time.Second)
+	for !ws.isStopped() {
+		if time.Now().After(deadline) {
+			t.Fatal("sandbox not stopped after the last connection left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInitAgentStartsE2BSandbox(t *testing.T) {
+	t.Setenv("LLM_API_KEY", "test-key")
+	ws := &fakeSandbox{}
+	got := stubE2BWorkspace(t, ws)
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+
+	session.HandleMessage([]byte(`{"type": "init_agent", "content": {"model_name": "gpt-4o", "sandbox": {"mode": "e2b", "template_id": "water", "sandbox_api_key": "e2b-key"}}}`))
+
+	if got.TemplateID == nil || *got.TemplateID != "water" || got.SandboxAPIKey.Reveal() != "e2b-key" {
+		t.Errorf("E2B sandbox config = %+v; want the template and key from init_agent", got)
+	}
+	if c := awaitEvent(t, conn, EventTypeAgentInitialized, func(gin.H) bool { return true }); c["vscode_url"] != ws.CodeServerURL() {
+		t.Errorf("agent_initialized = %v; want the sandbox's code-server URL", c)
+	}
+	session.stopSandbox()
+	if !ws.isStopped() {
+		t.Error("E2B sandbox not stopped with the session")
+	}
+}
+
+func TestDisconnectDeletesSessionDatabase(t *testing.T) {
+	deleted := make(chan string, 1)
+	neon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted <- r.URL.Path
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer neon.Close()
+	orig := newNeonClient
+	newNeonClient = func(config.ThirdPartyIntegrationConfig) *tools.NeonClient {
+		return &tools.NeonClient{APIKey: "neon-key", BaseURL: neon.URL}
+	}
+	t.Cleanup(func() { newNeonClient = orig })
+
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+	os.MkdirAll(session.Workspace, 0755)
+	ref := filepath.Join(session.Workspace, tools.DatabaseRefFile)
+	os.WriteFile(ref, []byte(`{"provider": "neon", "project_id": "proj-1"}`), 0600)
+
+	session.Manager.Disconnect(conn)
+
+	select {
+	case path := <-deleted:
+		if path != "/projects/proj-1" {
+			t.Errorf("DELETE %s; want the session's project", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("session database not deleted after the last connection left")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(ref); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("database reference not removed from the workspace")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"water-ai/core"
+)
+
+// MaxMessageSize is the largest message a client may send. Files travel
+// through /api/upload, so messages only carry text and paths.
+const MaxMessageSize = 1 << 20
+
+// DefaultIdleTimeout is how long a connection may stay silent before it is
+// closed. The server pings every nine tenths of it, so a live client's
+// pongs keep the connection open.
+const DefaultIdleTimeout = 60 * time.Second
+
+func (s *ChatSession) idleTimeout() time.Duration {
+	if s.Manager == nil {
+		return DefaultIdleTimeout
+	}
+	return s.Manager.config.GetIdleTimeout()
+}
+
+// prepareConn bounds message size and starts the idle deadline, which
+// pongs and incoming messages push back.
+func prepareConn(conn *websocket.Conn, idle time.Duration) {
+	conn.SetReadLimit(MaxMessageSize)
+	extendReadDeadline(conn, idle)
+	conn.SetPongHandler(func(string) error { return extendReadDeadline(conn, idle) })
+}
+
+func extendReadDeadline(conn *websocket.Conn, idle time.Duration) error {
+	return conn.SetReadDeadline(time.Now().Add(idle))
+}
+
+// keepAlive pings conn until stop is closed or a ping fails.
+func keepAlive(conn *websocket.Conn, idle time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(idle * 9 / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(closeFrameTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// closeOnReadError tells the client why its connection is being dropped
+// after a failed read. Oversized messages are already answered with a
+// close frame by the websocket package.
+func closeOnReadError(conn *websocket.Conn, err error) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, websocket.ErrReadLimit):
+		log.Printf("Closing connection: message exceeds %d bytes", MaxMessageSize)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		log.Printf("Closing idle connection")
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "idle timeout"),
+			time.Now().Add(closeFrameTimeout))
+	case websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure):
+		core.Logger.Warn("websocket error", "error", err)
+	}
+}
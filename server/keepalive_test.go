@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialLoop starts a server running StartLoop for each connection and
+// dials it, returning the client side after the handshake event. Cleanup
+// closes the client and waits for the loop to return, since the server
+// does not track hijacked connections and a lingering loop would touch
+// db.DB while the next test replaces it.
+func dialLoop(t *testing.T, idle time.Duration) *websocket.Conn {
+	t.Helper()
+	manager := NewConnectionManager(Config{WorkspaceRoot: t.TempDir(), IdleTimeout: idle})
+	var loops sync.WaitGroup
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		loops.Add(1)
+		defer loops.Done()
+		manager.Connect(conn, "").StartLoop(conn)
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		loops.Wait()
+	})
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("reading connection_established: %v", err)
+	}
+	return conn
+}
+
+func TestOversizedMessageClosesConnection(t *testing.T) {
+	conn := dialLoop(t, 0)
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "query", "content": {"text": "`+strings.Repeat("a", MaxMessageSize)+`"}}`))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("ReadMessage() error = %v; want close %d", err, websocket.CloseMessageTooBig)
+	}
+}
+
+func TestPongsKeepConnectionAlive(t *testing.T) {
+	idle := 200 * time.Millisecond
+	conn := dialLoop(t, idle)
+
+	// Reading lets the client answer the server's pings.
+	events := make(chan string, 10)
+	go func() {
+		defer close(events)
+		for {
+			var event RealtimeEvent
+			if err := conn.ReadJSON(&event); err != nil {
+				return
+			}
+			events <- event.Type
+		}
+	}()
+	time.Sleep(3 * idle)
+	conn.WriteJSON(WebSocketMessage{Type: "ping"})
+	select {
+	case got, ok := <-events:
+		if !ok || got != EventTypePong {
+			t.Errorf("after %v of pings, got event %q (open %v); want %q", 3*idle, got, ok, EventTypePong)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no reply to ping")
+	}
+}
+
+func TestIdleConnectionIsClosed(t *testing.T) {
+	conn := dialLoop(t, 100*time.Millisecond)
+
+	// Without reading, the client never answers pings.
+	time.Sleep(300 * time.Millisecond)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("ReadMessage() error = %v; want an idle close", err)
+	}
+}
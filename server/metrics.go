@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/metrics"
+)
+
+// Components label the metrics recorded by the gateway.
+const metricsComponentChat = "chat"
+
+// requestMetrics counts every request by its route template, so
+// /api/sessions/:id is one series however many sessions there are.
+// Requests matching no route are counted under "unmatched".
+func requestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.Inc(route, c.Request.Method, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// MetricsHandler handles GET /metrics in the Prometheus text format. It
+// adds this server's connected-session count to the process-wide metrics.
+func (s *Server) MetricsHandler(c *gin.Context) {
+	sessions := metrics.NewGaugeFunc("water_ws_active_sessions",
+		"WebSocket sessions with at least one connection.",
+		func() float64 { return float64(s.WSManager.ActiveSessions()) })
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	metrics.Default.WriteText(c.Writer, sessions)
+}
+
+// observeLLMCall records the duration of an LLM call started at start.
+func observeLLMCall(start time.Time) {
+	metrics.LLMCallDuration.Observe(time.Since(start).Seconds(), metricsComponentChat)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/metrics"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir(), EnableMetrics: true})
+	srv.WSManager.Connect(&recordingConn{}, "")
+
+	before := metrics.HTTPRequests.Value("/api/settings", http.MethodGet, "200")
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/settings", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/settings status = %d", w.Code)
+	}
+	if got := metrics.HTTPRequests.Value("/api/settings", http.MethodGet, "200"); got != before+1 {
+		t.Errorf("request counter = %v; want %v", got, before+1)
+	}
+
+	w = httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("Content-Type = %q; want %q", ct, metrics.ContentType)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE water_http_requests_total counter\n",
+		`water_http_requests_total{route="/api/settings",method="GET",status="200"} `,
+		"# TYPE water_llm_call_duration_seconds histogram\n",
+		"water_ws_active_sessions 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsDisabledByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /metrics status = %d; want 404 when metrics are disabled", w.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/llm"
+)
+
+// ProviderModels lists the models offered by one configured provider.
+type ProviderModels struct {
+	Provider llm.APIType     `json:"provider"`
+	Models   []llm.ModelInfo `json:"models"`
+}
+
+// ModelsResponse is the body of GET /api/models.
+type ModelsResponse struct {
+	Providers []ProviderModels `json:"providers"`
+}
+
+// configuredProviders reports which providers have an API key, either in
+// the environment or in a stored LLM config. LLM_API_KEY counts for the
+// provider named by LLM_API_TYPE, or else the one serving LLM_MODEL.
+func configuredProviders(settings Settings) map[llm.APIType]bool {
+	configured := make(map[llm.APIType]bool)
+	for provider, env := range llm.ProviderKeyEnv {
+		if os.Getenv(env) != "" {
+			configured[provider] = true
+		}
+	}
+	if os.Getenv("LLM_API_KEY") != "" {
+		provider := llm.APIType(os.Getenv("LLM_API_TYPE"))
+		if provider == "" {
+			provider = llm.ProviderForModel(os.Getenv("LLM_MODEL"))
+		}
+		configured[provider] = true
+	}
+	for _, cfg := range settings.LLMConfigs {
+		if hasSecret(cfg.APIKey) {
+			configured[llm.ProviderForModel(cfg.Model)] = true
+		}
+	}
+	return configured
+}
+
+// ListModelsHandler returns the models and their capabilities for every
+// provider with an API key configured. None of the providers' listing
+// APIs report capabilities, so the curated llm.ModelLimits catalog is
+// served for each.
+func (s *Server) ListModelsHandler(c *gin.Context) {
+	settings, err := s.settingsStore().Load()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	configured := configuredProviders(settings)
+	resp := ModelsResponse{Providers: []ProviderModels{}}
+	for _, provider := range llm.Providers {
+		if configured[provider] {
+			resp.Providers = append(resp.Providers, ProviderModels{Provider: provider, Models: llm.ModelsForProvider(provider)})
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
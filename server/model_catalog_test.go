@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/llm"
+)
+
+// clearProviderKeys unsets every key the models endpoint looks at.
+func clearProviderKeys(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{"OPENAI_API_KEY", "ANTHROPIC_API_KEY", "GEMINI_API_KEY", "LLM_API_KEY", "LLM_API_TYPE", "LLM_MODEL"} {
+		t.Setenv(env, "")
+	}
+}
+
+func listModels(t *testing.T, srv *Server) map[llm.APIType][]llm.ModelInfo {
+	t.Helper()
+	w := sendSessionRequest(t, srv, http.MethodGet, "/api/models", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/models = %d: %s", w.Code, w.Body.String())
+	}
+	var resp ModelsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid models JSON: %v", err)
+	}
+	providers := make(map[llm.APIType][]llm.ModelInfo)
+	for _, p := range resp.Providers {
+		providers[p.Provider] = p.Models
+	}
+	return providers
+}
+
+func TestListModelsOnlyConfiguredProviders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		settings string
+		want     []llm.APIType
+	}{
+		{"no keys", nil, "", nil},
+		{"provider key", map[string]string{"ANTHROPIC_API_KEY": "sk-ant"}, "", []llm.APIType{llm.APITypeAnthropic}},
+		{"generic key follows model", map[string]string{"LLM_API_KEY": "k", "LLM_MODEL": "gemini-2.5-pro"}, "", []llm.APIType{llm.APITypeGemini}},
+		{"generic key with api type", map[string]string{"LLM_API_KEY": "k", "LLM_API_TYPE": "anthropic"}, "", []llm.APIType{llm.APITypeAnthropic}},
+		{"stored settings", map[string]string{"OPENAI_API_KEY": "sk-oa"}, `{"llm_configs": {"default": {"model": "claude-3-5-sonnet", "api_key": "sk-ant"}, "nokey": {"model": "gemini-2.5-pro"}}}`, []llm.APIType{llm.APITypeAnthropic, llm.APITypeOpenAI}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearProviderKeys(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+			if tt.settings != "" {
+				if w := sendSessionRequest(t, srv, http.MethodPost, "/api/settings", tt.settings); w.Code != http.StatusOK {
+					t.Fatalf("POST /api/settings = %d: %s", w.Code, w.Body.String())
+				}
+			}
+
+			got := listModels(t, srv)
+			if len(got) != len(tt.want) {
+				t.Errorf("providers = %v; want %v", got, tt.want)
+			}
+			for _, provider := range tt.want {
+				models := got[provider]
+				if len(models) == 0 {
+					t.Errorf("provider %s listed no models", provider)
+				}
+				for _, m := range models {
+					if m.Provider != provider || m.ContextWindow == 0 {
+						t.Errorf("model %+v under %s", m, provider)
+					}
+				}
+			}
+		})
+	}
+}
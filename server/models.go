@@ -1,6 +1,10 @@
 package server
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"water-ai/core/config"
+)
 
 // --- WebSocket Messages ---
 
@@ -25,6 +29,16 @@ const (
 	EventTypePong                  = "pong"
 	EventTypeWorkspaceInfo         = "workspace_info"
 	EventTypeAgentInitialized      = "agent_initialized"
+	EventTypeUserMessage           = "user_message"
+	EventTypeSessionSummary        = "session_summary"
+	EventTypeAgentThinking         = "agent_thinking"
+	EventTypeToolCall              = "tool_call"
+	EventTypeToolResult            = "tool_result"
+	EventTypeUsage                 = "usage"
+	EventTypeTranscription         = "transcription"
+	EventTypeSpeech                = "speech"
+	EventTypeSubscribed            = "subscribed"
+	EventTypeBrowserTabs           = "browser_tabs"
 )
 
 // --- Request Content Models ---
@@ -33,6 +47,10 @@ type InitAgentContent struct {
 	ModelName      string                 `json:"model_name"`
 	ToolArgs       map[string]interface{} `json:"tool_args"`
 	ThinkingTokens int                    `json:"thinking_tokens"`
+	// SummarizeSession asks the model for a one-line summary on each session summary card
+	SummarizeSession bool `json:"summarize_session"`
+	// Sandbox selects where the agent works; nil keeps the local workspace
+	Sandbox *config.SandboxConfig `json:"sandbox,omitempty"`
 }
 
 type QueryContent struct {
@@ -41,6 +59,32 @@ type QueryContent struct {
 	Files  []string `json:"files"`
 }
 
+// ToolApprovalContent answers a tool call sent with awaiting_approval, in
+// an approve or deny message.
+type ToolApprovalContent struct {
+	ToolCallID string `json:"tool_call_id"`
+}
+
+// AudioContent asks for an uploaded audio file to be transcribed. Path is
+// the workspace path returned by /api/upload; AsQuery runs the transcript
+// as a query.
+type AudioContent struct {
+	Path    string `json:"path"`
+	AsQuery bool   `json:"as_query"`
+}
+
+// SwitchTabContent asks for the browser tab with PageID to become the
+// current one.
+type SwitchTabContent struct {
+	PageID int `json:"page_id"`
+}
+
+// SubscribeContent limits the events sent to the connection to
+// EventTypes. An empty list restores all events.
+type SubscribeContent struct {
+	EventTypes []string `json:"event_types"`
+}
+
 type EditQueryContent struct {
 	Text   string   `json:"text"`
 	Resume bool     `json:"resume"`
@@ -75,6 +119,7 @@ type SessionInfo struct {
 	CreatedAt    string `json:"created_at"`
 	DeviceID     string `json:"device_id"`
 	Name         string `json:"name"`
+	LastEventAt  string `json:"last_event_at,omitempty"`
 }
 
 type SessionResponse struct {
@@ -98,21 +143,23 @@ type EventResponse struct {
 type Settings struct {
 	LLMConfigs     map[string]LLMConfig `json:"llm_configs"`
 	SearchConfig   *SearchConfig        `json:"search_config,omitempty"`
+	SandboxConfig  *config.SandboxConfig `json:"sandbox_config,omitempty"`
 	// Additional fields omitted for brevity
 }
 
 type LLMConfig struct {
-	APIKey         *string `json:"api_key,omitempty"`
-	Model          string  `json:"model"`
-	ThinkingTokens int     `json:"thinking_tokens,omitempty"`
+	APIKey         *config.SecretString `json:"api_key,omitempty"`
+	Model          string               `json:"model"`
+	ThinkingTokens int                  `json:"thinking_tokens,omitempty"`
 }
 
 type SearchConfig struct {
-	APIKey *string `json:"api_key,omitempty"`
+	APIKey *config.SecretString `json:"api_key,omitempty"`
 }
 
 type GETSettingsModel struct {
 	Settings
 	LLMAPIKeySet    bool `json:"llm_api_key_set"`
 	SearchAPIKeySet bool `json:"search_api_key_set"`
+	SandboxAPIKeySet bool `json:"sandbox_api_key_set"`
 }
\ No newline at end of file
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"water-ai/agents"
+	"water-ai/core/config"
+	"water-ai/db"
+	"water-ai/llm"
+	"water-ai/prompts"
+)
+
+// ReplayOptions configures ReplaySession.
+type ReplayOptions struct {
+	ModelName     string           // model to replay against
+	WorkspaceRoot string           // root under which the new session workspace is created
+	Client        llm.Client       // optional; overrides the client built from ModelName
+	LLM           config.LLMConfig // key, provider and base URL for the built client
+	// Agent sets the limits of the replay's agent; nil keeps the built-in
+	// turn and output limits
+	Agent *config.WaterAgentConfig
+}
+
+// ReplayPrompt is a user turn extracted from a recorded session.
+type ReplayPrompt struct {
+	Text  string   `json:"text"`
+	Files []string `json:"files,omitempty"`
+}
+
+// approveAll approves every tool call of a replay, which has no user to
+// ask and works in its own copy of the workspace.
+type approveAll struct{}
+
+func (approveAll) AwaitApproval(ctx context.Context, call agents.ToolCallParameters) (bool, error) {
+	return true, nil
+}
+
+// ReplaySession re-runs the user turns of a recorded session through the
+// session agent, with its tools, on the requested model. The results are
+// recorded as a new session, event for event as a live one, so outcomes
+// can be compared side by side. Attached files are copied into the new
+// workspace. It returns the ID of the new session.
+func ReplaySession(ctx context.Context, sourceID uuid.UUID, opts ReplayOptions) (uuid.UUID, error) {
+	source, err := db.Sessions.GetSessionByID(sourceID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if source == nil {
+		return uuid.Nil, errSessionNotFound
+	}
+
+	events, err := db.Events.GetSessionEvents(sourceID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	userPrompts := ExtractUserPrompts(events)
+	if len(userPrompts) == 0 {
+		return uuid.Nil, fmt.Errorf("session %s has no user messages to replay", sourceID)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client, err = newLLMClient(opts.LLM, opts.ModelName, 0)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	newID := uuid.New()
+	workspace := filepath.Join(opts.WorkspaceRoot, newID.String())
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return uuid.Nil, err
+	}
+	name := fmt.Sprintf("Replay of %s (%s)", sourceID.String()[:8], opts.ModelName)
+	if source.Name != nil {
+		name = fmt.Sprintf("%s (replay: %s)", *source.Name, opts.ModelName)
+	}
+	err = db.WithTransaction(func(tx *gorm.DB) error {
+		if _, _, err := db.Sessions.CreateSession(newID, workspace, source.DeviceID, nil, tx); err != nil {
+			return err
+		}
+		return db.Sessions.UpdateSessionName(newID, name, tx)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	session := &ChatSession{
+		conns:        make(map[EventConn]eventFilter),
+		SessionUUID:  newID,
+		Workspace:    workspace,
+		Manager:      NewConnectionManager(Config{WorkspaceRoot: opts.WorkspaceRoot, LLM: opts.LLM, Agent: opts.Agent}),
+		LLMClient:    client,
+		History:      llm.NewMessageHistory(),
+		SystemPrompt: prompts.GetSystemPrompt(prompts.WorkspaceModeLocal, false),
+		ModelName:    opts.ModelName,
+		Tracker:      NewUsageTracker(newID),
+		approver:     approveAll{},
+		recorded:     true,
+	}
+	defer session.persistUsage()
+
+	for _, prompt := range userPrompts {
+		for _, f := range prompt.Files {
+			if err := copyWorkspaceFile(source.WorkspaceDir, workspace, f); err != nil {
+				return newID, fmt.Errorf("failed to copy attachment %s: %w", f, err)
+			}
+		}
+
+		session.recordEvent(EventTypeUserMessage, QueryContent{Text: prompt.Text, Files: prompt.Files})
+		responseText, err := session.runAgent(ctx, prompt.Text, prompt.Files)
+		session.persistHistory()
+		if err != nil {
+			session.recordEvent(EventTypeError, map[string]interface{}{"message": err.Error()})
+			return newID, fmt.Errorf("replay failed: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return newID, err
+		}
+		session.recordEvent(EventTypeAgentResponse, map[string]interface{}{"text": responseText})
+	}
+
+	return newID, nil
+}
+
+// ReplaySessionHandler handles POST /api/sessions/:id/replay with
+// {"model_name": ...}. It replays the session on that model and answers
+// with the ID of the new session once the replay has finished.
+func (s *Server) ReplaySessionHandler(c *gin.Context) {
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+	sourceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	var req struct {
+		ModelName string `json:"model_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ModelName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model_name is required"})
+		return
+	}
+
+	newID, err := ReplaySession(c.Request.Context(), sourceID, ReplayOptions{
+		ModelName:     req.ModelName,
+		WorkspaceRoot: s.Config.GetWorkspaceRoot(),
+		LLM:           s.Config.LLM,
+		Agent:         s.Config.Agent,
+	})
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil && newID == uuid.Nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"session_id": newID.String(), "error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"session_id": newID.String()})
+	}
+}
+
+// ExtractUserPrompts returns the user turns of a session in chronological
+// order. Payloads may be a full RealtimeEvent ({"content": {...}}) or the
+// bare content object.
+func ExtractUserPrompts(events []db.Event) []ReplayPrompt {
+	sorted := make([]db.Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var out []ReplayPrompt
+	for _, evt := range sorted {
+		if evt.EventType != EventTypeUserMessage {
+			continue
+		}
+		var wrapped struct {
+			Content *ReplayPrompt `json:"content"`
+		}
+		var prompt ReplayPrompt
+		if err := json.Unmarshal(evt.EventPayload, &wrapped); err == nil && wrapped.Content != nil {
+			prompt = *wrapped.Content
+		} else if err := json.Unmarshal(evt.EventPayload, &prompt); err != nil {
+			continue
+		}
+		if prompt.Text == "" && len(prompt.Files) == 0 {
+			continue
+		}
+		out = append(out, prompt)
+	}
+	return out
+}
+
+// copyWorkspaceFile copies a workspace-relative file from src to dst,
+// refusing paths that escape the source workspace.
+func copyWorkspaceFile(srcRoot, dstRoot, rel string) error {
+	rel = filepath.Clean("/" + filepath.FromSlash(rel))
+	srcPath := filepath.Join(srcRoot, rel)
+	dstPath := filepath.Join(dstRoot, rel)
+	if !strings.HasPrefix(srcPath, filepath.Clean(srcRoot)+string(filepath.Separator)) {
+		return fmt.Errorf("path escapes workspace")
+	}
+
+	in, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
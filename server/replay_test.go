@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/core/config"
+	"water-ai/db"
+	"water-ai/llm"
+)
+
+func setupReplayDB(t *testing.T) {
+	t.Helper()
+	if err := db.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+}
+
+// recordSourceSession runs two queries through a live session, the first
+// with an uploaded attachment, so the database holds the events the
+// server saves. It returns the session.
+func recordSourceSession(t *testing.T) *ChatSession {
+	t.Helper()
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.TextBlock("old model answer 1")).
+		EnqueueBlocks(llm.TextBlock("old model answer 2"))
+	session := newTestSession(t, &recordingConn{}, mock)
+	os.MkdirAll(filepath.Join(session.Workspace, "uploads"), 0755)
+	os.WriteFile(filepath.Join(session.Workspace, "uploads", "spec.txt"), []byte("the spec"), 0644)
+
+	session.HandleMessage([]byte(`{"type":"query","content":{"text":"Build a todo app","files":["/uploads/spec.txt"]}}`))
+	time.Sleep(10 * time.Millisecond)
+	session.HandleMessage([]byte(`{"type":"query","content":{"text":"Add dark mode"}}`))
+	return session
+}
+
+func TestReplaySessionRunsTheAgent(t *testing.T) {
+	setupReplayDB(t)
+	source := recordSourceSession(t)
+	device := "device-1"
+	db.DB.Model(&db.Session{}).Where("id = ?", source.SessionUUID.String()).Update("device_id", device)
+
+	// The replay model runs a command on the first turn: the session's
+	// tools must be available and their calls approved.
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.ToolCallBlock("call-1", "terminal_execute", map[string]interface{}{"command": "cat uploads/spec.txt > out.txt"})).
+		EnqueueBlocks(llm.TextBlock("new answer 1")).
+		EnqueueBlocks(llm.TextBlock("new answer 2"))
+
+	root := t.TempDir()
+	newID, err := ReplaySession(context.Background(), source.SessionUUID, ReplayOptions{
+		ModelName:     "mock-model",
+		WorkspaceRoot: root,
+		Client:        mock,
+	})
+	if err != nil {
+		t.Fatalf("ReplaySession() error = %v", err)
+	}
+	if newID == source.SessionUUID {
+		t.Fatal("replay should create a new session")
+	}
+
+	newSession, err := db.Sessions.GetSessionByID(newID)
+	if err != nil || newSession == nil {
+		t.Fatalf("new session not found: %v", err)
+	}
+	if newSession.DeviceID == nil || *newSession.DeviceID != device {
+		t.Error("new session should keep the source device ID")
+	}
+	if newSession.Name == nil || !strings.Contains(*newSession.Name, "mock-model") {
+		t.Errorf("new session name = %v; want it to mention the model", newSession.Name)
+	}
+
+	out, err := os.ReadFile(filepath.Join(newSession.WorkspaceDir, "out.txt"))
+	if err != nil || string(out) != "the spec" {
+		t.Errorf("out.txt = %q, %v; want the tool call run on the copied attachment", out, err)
+	}
+
+	events := mustSessionEvents(t, newID)
+	prompts := ExtractUserPrompts(events)
+	if len(prompts) != 2 || prompts[0].Text != "Build a todo app" || prompts[1].Text != "Add dark mode" {
+		t.Fatalf("replayed prompts = %+v; want the two source prompts in order", prompts)
+	}
+	if len(prompts[0].Files) != 1 || prompts[0].Files[0] != "/uploads/spec.txt" {
+		t.Errorf("first prompt files = %v; want attachment preserved", prompts[0].Files)
+	}
+
+	var types []string
+	for _, e := range events {
+		types = append(types, e.EventType)
+	}
+	want := []string{EventTypeUserMessage, EventTypeToolCall, EventTypeToolResult, EventTypeAgentResponse, EventTypeUserMessage, EventTypeAgentResponse}
+	next := 0
+	for _, typ := range types {
+		if next < len(want) && typ == want[next] {
+			next++
+		}
+	}
+	if next != len(want) {
+		t.Errorf("replay events = %v; want %v in order", types, want)
+	}
+
+	var responses []string
+	for _, e := range events {
+		if e.EventType == EventTypeAgentResponse {
+			var payload struct {
+				Content map[string]string `json:"content"`
+			}
+			json.Unmarshal(e.EventPayload, &payload)
+			responses = append(responses, payload.Content["text"])
+		}
+	}
+	if len(responses) != 2 || responses[0] != "new answer 1" || responses[1] != "new answer 2" {
+		t.Errorf("agent responses = %v; want the replay model's answers", responses)
+	}
+
+	// Each turn carries the conversation so far and the session's tools.
+	calls := mock.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("model calls = %d; want 3", len(calls))
+	}
+	if len(calls[2].Messages) <= len(calls[0].Messages) {
+		t.Errorf("last call has %d messages; want the earlier turns included", len(calls[2].Messages))
+	}
+	if len(calls[0].Tools) == 0 {
+		t.Error("replay model calls should offer the session's tools")
+	}
+	if !strings.Contains(calls[0].Messages[0].Content[0].Text, "uploads/spec.txt") {
+		t.Error("first prompt should list the attached file")
+	}
+}
+
+func TestReplaySessionRoute(t *testing.T) {
+	setupReplayDB(t)
+	source := recordSourceSession(t)
+
+	mock := llm.NewMockClient().
+		EnqueueBlocks(llm.TextBlock("new answer 1")).
+		EnqueueBlocks(llm.TextBlock("new answer 2"))
+	orig := newLLMClient
+	newLLMClient = func(cfg config.LLMConfig, modelName string, thinkingTokens int) (llm.Client, error) {
+		return mock, nil
+	}
+	t.Cleanup(func() { newLLMClient = orig })
+
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir(), APIKey: "secret"})
+	replay := func(id, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions/"+id+"/replay", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"model_name":"mock-model"}`
+	if w := replay(source.SessionUUID.String(), "", body); w.Code != http.StatusUnauthorized {
+		t.Errorf("replay without key = %d; want 401", w.Code)
+	}
+	if w := replay(source.SessionUUID.String(), "secret", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("replay without model = %d; want 400", w.Code)
+	}
+	if w := replay(uuid.New().String(), "secret", body); w.Code != http.StatusNotFound {
+		t.Errorf("replay of unknown session = %d; want 404", w.Code)
+	}
+
+	w := replay(source.SessionUUID.String(), "secret", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("replay = %d %s; want 201", w.Code, w.Body.String())
+	}
+	var resp struct {
+		SessionID string `json:"session_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	newID, err := uuid.Parse(resp.SessionID)
+	if err != nil {
+		t.Fatalf("session_id = %q; want the new session's ID", resp.SessionID)
+	}
+	if prompts := ExtractUserPrompts(mustSessionEvents(t, newID)); len(prompts) != 2 {
+		t.Errorf("replayed prompts = %+v; want 2", prompts)
+	}
+}
+
+func mustSessionEvents(t *testing.T, id uuid.UUID) []db.Event {
+	t.Helper()
+	events, err := db.Events.GetSessionEvents(id)
+	if err != nil {
+		t.Fatalf("GetSessionEvents() error = %v", err)
+	}
+	return events
+}
+
+func TestReplaySessionUnknownSession(t *testing.T) {
+	setupReplayDB(t)
+	if _, err := ReplaySession(context.Background(), uuid.New(), ReplayOptions{WorkspaceRoot: t.TempDir(), Client: llm.NewMockClient()}); err == nil {
+		t.Error("ReplaySession() should fail for an unknown session")
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/core"
+)
+
+// RequestIDHeader carries the correlation ID of a request. An incoming
+// value is reused so that IDs can be traced across services; otherwise one
+// is generated. Responses always carry it.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request ID in the gin context.
+const requestIDKey = "request_id"
+
+// maxRequestIDLen bounds an incoming request ID.
+const maxRequestIDLen = 128
+
+// sensitiveParams are substrings of query parameter names whose values are
+// redacted from request logs.
+var sensitiveParams = []string{"key", "token", "secret", "password", "auth"}
+
+// RequestID returns the correlation ID assigned to the request by
+// requestLogger, or "" outside of it.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// requestLogger assigns or propagates the request's X-Request-ID and logs
+// each request once it completes.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		start := time.Now()
+		c.Next()
+
+		attrs := []any{
+			"request_id", id,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+		}
+		if query := redactedQuery(c.Request.URL.Query()); query != "" {
+			attrs = append(attrs, "query", query)
+		}
+		core.Logger.Info("http request", attrs...)
+	}
+}
+
+// validRequestID accepts IDs of up to maxRequestIDLen letters, digits and
+// ".-_:", so that a client cannot inject arbitrary text into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// redactedQuery encodes query with the values of sensitive parameters
+// redacted.
+func redactedQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	safe := make(url.Values, len(query))
+	for name, values := range query {
+		if !isSensitiveParam(name) {
+			safe[name] = values
+			continue
+		}
+		for range values {
+			safe.Add(name, redactedSecret)
+		}
+	}
+	return safe.Encode()
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRequestID returns a copy of an error payload carrying the session's
+// correlation ID. Other payloads are returned unchanged.
+func withRequestID(content interface{}, requestID string) interface{} {
+	if requestID == "" {
+		return content
+	}
+	var payload map[string]interface{}
+	switch c := content.(type) {
+	case gin.H:
+		payload = c
+	case map[string]interface{}:
+		payload = c
+	default:
+		return content
+	}
+	out := make(gin.H, len(payload)+1)
+	for k, v := range payload {
+		out[k] = v
+	}
+	out[requestIDKey] = requestID
+	return out
+}
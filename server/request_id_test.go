@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/agents"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated when absent", "", false},
+		{"propagated", "trace-42:abc.def_1", true},
+		{"replaced when invalid", "bad id\nwith newline", false},
+		{"replaced when too long", strings.Repeat("a", maxRequestIDLen+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/settings", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			srv.Router.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tt.keep {
+				if got != tt.incoming {
+					t.Errorf("%s = %q; want %q", RequestIDHeader, got, tt.incoming)
+				}
+				return
+			}
+			if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("%s = %q; want a generated UUID", RequestIDHeader, got)
+			}
+		})
+	}
+
+	// Unmatched routes get an ID too.
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if w.Header().Get(RequestIDHeader) == "" {
+		t.Error("404 response should carry a request ID")
+	}
+}
+
+func TestRedactedQuery(t *testing.T) {
+	query := url.Values{
+		"session_uuid": {"abc"},
+		"api_key":      {"sk-secret"},
+		"Token":        {"t1", "t2"},
+	}
+	got := redactedQuery(query)
+	if strings.Contains(got, "sk-secret") || strings.Contains(got, "t1") {
+		t.Errorf("redactedQuery() = %q leaks a secret", got)
+	}
+	if !strings.Contains(got, "session_uuid=abc") {
+		t.Errorf("redactedQuery() = %q; want non-sensitive params kept", got)
+	}
+	if redactedQuery(nil) != "" {
+		t.Error("redactedQuery(nil) should be empty")
+	}
+}
+
+func TestErrorEventsCarryCorrelationID(t *testing.T) {
+	conn := &recordingConn{}
+	manager := NewConnectionManager(Config{WorkspaceRoot: t.TempDir()})
+	session := manager.Connect(conn, "")
+	session.setCorrelationID("req-7")
+
+	payload := gin.H{"message": "boom"}
+	session.SendEvent(EventTypeError, payload)
+	session.SendEvent(EventTypeProcessing, gin.H{"message": "working"})
+
+	errEvent := lastContent(conn, EventTypeError)
+	if errEvent["request_id"] != "req-7" || errEvent["message"] != "boom" {
+		t.Errorf("error event = %v; want request_id req-7", errEvent)
+	}
+	if _, ok := payload["request_id"]; ok {
+		t.Error("SendEvent() should not modify the caller's payload")
+	}
+	if _, ok := lastContent(conn, EventTypeProcessing)["request_id"]; ok {
+		t.Error("only error events should carry request_id")
+	}
+}
+
+// lastContent returns the content of the last event of eventType sent on
+// conn.
+func lastContent(conn *recordingConn, eventType string) gin.H {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	var content gin.H
+	for _, e := range conn.events {
+		if e.Type == eventType {
+			content, _ = e.Content.(gin.H)
+		}
+	}
+	return content
+}
+
+func TestAgentCarriesCorrelationID(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+	session.setCorrelationID("req-8")
+
+	agent := session.newAgent(make(chan agents.RealtimeEvent, 1))
+
+	if agent.RequestID != "req-8" {
+		t.Errorf("agent RequestID = %q; want req-8", agent.RequestID)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"water-ai/core"
+	"water-ai/core/config"
+	"water-ai/db"
+	"water-ai/sandbox"
+	"water-ai/tools"
+)
+
+// sandboxStopTimeout bounds tearing down a session's sandbox or database.
+const sandboxStopTimeout = 30 * time.Second
+
+// sandboxWorkspace is a running sandbox that a session tears down when it
+// ends.
+type sandboxWorkspace interface {
+	CodeServerURL() string
+	Stop(ctx context.Context) error
+}
+
+// startDockerWorkspace starts the container for a session's workspace,
+// reattaching to the session's stored container if it still exists. Tests
+// replace it.
+var startDockerWorkspace = func(ctx context.Context, s *ChatSession) (sandboxWorkspace, error) {
+	hostDir, err := filepath.Abs(s.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config.SandboxConfig
+	if s.Manager != nil {
+		cfg = s.Manager.config.Sandbox
+	}
+	port := (&config.WaterAgentConfig{}).CodeServerPort()
+	ws, err := sandbox.NewDockerWorkspace(s.SessionUUID.String(), hostDir, dockerSettings(cfg), port, sandboxIDStore())
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.Start(ctx); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// dockerSettings returns the image and resource limits of cfg as sandbox
+// settings.
+func dockerSettings(cfg config.SandboxConfig) *sandbox.Settings {
+	settings := &sandbox.Settings{}
+	settings.SandboxConfig.Image = cfg.Image
+	settings.SandboxConfig.MemoryLimit = int64(cfg.MemoryLimitMB) << 20
+	settings.SandboxConfig.CPULimit = cfg.CPULimit
+	return settings
+}
+
+// startE2BWorkspace starts the E2B sandbox for a session's workspace,
+// reattaching to the session's stored sandbox if it is still alive. Tests
+// replace it.
+var startE2BWorkspace = func(ctx context.Context, s *ChatSession, cfg config.SandboxConfig) (sandboxWorkspace, error) {
+	settings := &sandbox.Settings{}
+	settings.SandboxConfig.ServicePort = cfg.ServicePort
+	if cfg.TemplateID != nil {
+		settings.SandboxConfig.TemplateID = *cfg.TemplateID
+	}
+	if cfg.SandboxAPIKey != nil {
+		settings.SandboxConfig.SandboxAPIKey = cfg.SandboxAPIKey.Reveal()
+	}
+	ws := sandbox.NewE2BWorkspace(s.SessionUUID.String(), settings, sandboxIDStore())
+	if err := ws.Start(ctx); err != nil {
+		return nil, err
+	}
+	return e2bWorkspace{ws: ws, port: (&config.WaterAgentConfig{}).CodeServerPort()}, nil
+}
+
+// e2bWorkspace is an E2B sandbox whose code-server listens on port. File
+// tools work on the host, so commands are not run in it.
+type e2bWorkspace struct {
+	ws   *sandbox.E2BWorkspace
+	port int
+}
+
+func (w e2bWorkspace) CodeServerURL() string { return w.ws.PortURL(w.port) }
+
+func (w e2bWorkspace) Stop(ctx context.Context) error { return w.ws.Kill(ctx) }
+
+// sandboxIDStore records the sandbox of each session once the database is
+// open.
+func sandboxIDStore() sandbox.SandboxIDStore {
+	if db.DB == nil {
+		return nil
+	}
+	return db.Sessions
+}
+
+// newNeonClient returns the client that deletes session databases, or nil
+// without a Neon key. Tests replace it.
+var newNeonClient = tools.NewNeonClient
+
+// teardown releases what an ended session holds outside the process: its
+// sandbox and the database provisioned for its workspace.
+func (s *ChatSession) teardown() {
+	s.stopSandbox()
+	s.cleanupDatabase()
+}
+
+// cleanupDatabase deletes the database provisioned for the session's
+// workspace, if any.
+func (s *ChatSession) cleanupDatabase() {
+	var thirdParty config.ThirdPartyIntegrationConfig
+	if s.Manager != nil {
+		thirdParty = s.Manager.config.ThirdParty
+	}
+	tool := &tools.GetDatabaseConnectionTool{Neon: newNeonClient(thirdParty), Workspace: s.Workspace}
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxStopTimeout)
+	defer cancel()
+	if err := tool.Cleanup(ctx); err != nil {
+		core.Logger.Error("failed to delete session database", "session_id", s.SessionUUID.String(), "error", err)
+	}
+}
+
+// stopSandbox tears down the session's sandbox, if it has one.
+func (s *ChatSession) stopSandbox() {
+	s.mu.Lock()
+	ws := s.sandbox
+	s.sandbox = nil
+	s.mu.Unlock()
+	if ws == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxStopTimeout)
+	defer cancel()
+	if err := ws.Stop(ctx); err != nil {
+		core.Logger.Error("failed to stop sandbox", "session_id", s.SessionUUID.String(), "error", err)
+	}
+}
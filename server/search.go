@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+// searchContextRunes is how much text is kept on each side of a match in a
+// search snippet.
+const searchContextRunes = 40
+
+// Highlight markers placed around the matched text in a snippet.
+const (
+	HighlightStart = "<<"
+	HighlightEnd   = ">>"
+)
+
+// EventSearchResult is one event matching a search query.
+type EventSearchResult struct {
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	EventType string `json:"event_type"`
+	// Snippet is the text around the first match with the match wrapped in
+	// HighlightStart and HighlightEnd.
+	Snippet string `json:"snippet"`
+}
+
+// EventSearchResponse is the body of the event search endpoint.
+type EventSearchResponse struct {
+	Query   string              `json:"query"`
+	Results []EventSearchResult `json:"results"`
+}
+
+// payloadStrings collects the string values in a decoded payload in a
+// stable order, so snippets are drawn from text rather than JSON syntax.
+func payloadStrings(v interface{}, out []string) []string {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = payloadStrings(val[k], out)
+		}
+	case []interface{}:
+		for _, item := range val {
+			out = payloadStrings(item, out)
+		}
+	case string:
+		out = append(out, val)
+	}
+	return out
+}
+
+// highlightSnippet returns the context around the first case-insensitive
+// match of query in text, or false when text does not contain it.
+func highlightSnippet(text, query string) (string, bool) {
+	lowerText, lowerQuery := strings.ToLower(text), strings.ToLower(query)
+	var idx int
+	if len(lowerText) == len(text) && len(lowerQuery) == len(query) {
+		idx = strings.Index(lowerText, lowerQuery)
+	} else {
+		// Lowercasing changed byte lengths, so its offsets would not line
+		// up with text; match exactly instead.
+		idx = strings.Index(text, query)
+	}
+	if idx < 0 {
+		return "", false
+	}
+	end := idx + len(query)
+
+	start := idx
+	for n := 0; n < searchContextRunes && start > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	stop := end
+	for n := 0; n < searchContextRunes && stop < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[stop:])
+		stop += size
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	b.WriteString(text[start:idx])
+	b.WriteString(HighlightStart)
+	b.WriteString(text[idx:end])
+	b.WriteString(HighlightEnd)
+	b.WriteString(text[end:stop])
+	if stop < len(text) {
+		b.WriteString("...")
+	}
+	return b.String(), true
+}
+
+// eventSnippet picks the first payload value containing query. Matches
+// only in keys or JSON syntax fall back to the raw payload text.
+func eventSnippet(raw json.RawMessage, query string) string {
+	var payload interface{}
+	if err := json.Unmarshal(raw, &payload); err == nil {
+		for _, s := range payloadStrings(payload, nil) {
+			if snippet, ok := highlightSnippet(s, query); ok {
+				return snippet
+			}
+		}
+	}
+	snippet, _ := highlightSnippet(string(raw), query)
+	return snippet
+}
+
+// SearchSessionEvents returns the events of a session whose payload
+// contains query, oldest first.
+func SearchSessionEvents(sessionID uuid.UUID, query string) ([]EventSearchResult, error) {
+	events, err := db.Events.SearchEvents(sessionID, query)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]EventSearchResult, 0, len(events))
+	for _, evt := range events {
+		results = append(results, EventSearchResult{
+			ID:        evt.ID,
+			Timestamp: evt.Timestamp.UTC().Format(time.RFC3339Nano),
+			EventType: evt.EventType,
+			Snippet:   eventSnippet(evt.EventPayload, query),
+		})
+	}
+	return results, nil
+}
+
+// SearchEventsHandler handles GET /api/sessions/:id/events/search?q=
+func (s *Server) SearchEventsHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	sess, err := db.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sess == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errSessionNotFound.Error()})
+		return
+	}
+
+	results, err := SearchSessionEvents(sessionID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, EventSearchResponse{Query: query, Results: results})
+}
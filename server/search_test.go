@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+func TestSearchEventsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	other := seedExportSession(t, root)
+	db.Events.SaveEvent(other, EventTypeAgentResponse, RealtimeEvent{
+		Type:    EventTypeAgentResponse,
+		Content: map[string]interface{}{"text": "Snowing in Bergen too"},
+	})
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	tests := []struct {
+		query     string
+		wantTypes []string
+		snippet   string
+	}{
+		{"snow", []string{EventTypeToolResult, EventTypeAgentResponse}, "-3C, <<snow>>"},
+		{"SNOWING", []string{EventTypeAgentResponse}, "It is -3C and <<snowing>> in Oslo."},
+		{"bergen", nil, ""},
+		{"100%", nil, ""},
+	}
+	for _, tt := range tests {
+		w := sendSessionRequest(t, srv, http.MethodGet, "/api/sessions/"+id.String()+"/events/search?q="+strings.ReplaceAll(tt.query, "%", "%25"), "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("search %q status = %d; body %s", tt.query, w.Code, w.Body.String())
+		}
+		var resp EventSearchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("search %q: invalid JSON: %v", tt.query, err)
+		}
+		if len(resp.Results) != len(tt.wantTypes) {
+			t.Errorf("search %q returned %d results; want %d", tt.query, len(resp.Results), len(tt.wantTypes))
+			continue
+		}
+		for i, r := range resp.Results {
+			if r.EventType != tt.wantTypes[i] {
+				t.Errorf("search %q result %d type = %s; want %s", tt.query, i, r.EventType, tt.wantTypes[i])
+			}
+			if r.Timestamp == "" {
+				t.Errorf("search %q result %d has no timestamp", tt.query, i)
+			}
+		}
+		if tt.snippet != "" && !strings.Contains(resp.Results[0].Snippet, tt.snippet) {
+			t.Errorf("search %q snippet = %q; want it to contain %q", tt.query, resp.Results[0].Snippet, tt.snippet)
+		}
+	}
+}
+
+func TestSearchEventsEndpointErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/sessions/" + uuid.New().String() + "/events/search", http.StatusBadRequest},
+		{"/api/sessions/not-a-uuid/events/search?q=x", http.StatusBadRequest},
+		{"/api/sessions/" + uuid.New().String() + "/events/search?q=x", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := sendSessionRequest(t, srv, http.MethodGet, tt.target, "")
+		if w.Code != tt.want {
+			t.Errorf("GET %s status = %d; want %d", tt.target, w.Code, tt.want)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	long := strings.Repeat("a", 60) + "needle" + strings.Repeat("b", 60)
+	tests := []struct {
+		text, query string
+		want        string
+		ok          bool
+	}{
+		{"find the Needle here", "needle", "find the <<Needle>> here", true},
+		{long, "needle", "..." + strings.Repeat("a", 40) + "<<needle>>" + strings.Repeat("b", 40) + "...", true},
+		{"nothing", "needle", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := highlightSnippet(tt.text, tt.query)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("highlightSnippet(%q, %q) = %q, %v; want %q, %v", tt.text, tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -17,8 +19,14 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
+	"water-ai/agents"
+	"water-ai/core"
+	"water-ai/core/config"
 	"water-ai/llm"
+	"water-ai/metrics"
 	"water-ai/prompts"
+	"water-ai/tools"
+	"water-ai/utils"
 )
 
 // --- Configuration & Global State ---
@@ -26,6 +34,30 @@ import (
 type Config struct {
 	WorkspaceRoot string
 	Port          string
+	// APIKey, when set, is required on /api, /ws and /workspace requests
+	APIKey string
+	// EnableMetrics serves Prometheus metrics on /metrics and counts
+	// HTTP requests
+	EnableMetrics bool
+	// Audio configures transcription of audio messages
+	Audio config.AudioConfig
+	// MinimizeLogs runs gin in release mode and raises core.Logger to
+	// WARN, for deployments with MinimizeStdoutLogs set
+	MinimizeLogs bool
+	// IdleTimeout closes WebSocket connections that send nothing and
+	// answer no pings for this long; zero means DefaultIdleTimeout
+	IdleTimeout time.Duration
+	// LLM holds the API key, provider, base URL and temperature of the
+	// clients sessions build; an empty key is read from the environment
+	LLM config.LLMConfig
+	// Sandbox sets the image and resource limits of docker sandboxes
+	Sandbox config.SandboxConfig
+	// ThirdParty holds the Neon key used to delete the databases
+	// provisioned for sessions when they end
+	ThirdParty config.ThirdPartyIntegrationConfig
+	// Agent sets the turn, output, token and time limits of session
+	// agents; nil keeps the built-in turn and output limits
+	Agent *config.WaterAgentConfig
 }
 
 // GetPort returns the configured port or default
@@ -36,6 +68,14 @@ func (c Config) GetPort() string {
 	return c.Port
 }
 
+// GetIdleTimeout returns the configured idle timeout or default
+func (c Config) GetIdleTimeout() time.Duration {
+	if c.IdleTimeout <= 0 {
+		return DefaultIdleTimeout
+	}
+	return c.IdleTimeout
+}
+
 // GetWorkspaceRoot returns the configured workspace or default
 func (c Config) GetWorkspaceRoot() string {
 	if c.WorkspaceRoot == "" {
@@ -51,78 +91,189 @@ type Server struct {
 	WSManager  *ConnectionManager
 	// Stub for DB/FileStore interfaces
 	FileStore  interface{} 
+
+	StartedAt time.Time
+	Build     BuildInfo
+	// DBCheck overrides the database probe used by /health/detailed
+	DBCheck func(ctx context.Context) error
+	// Settings persists /api/settings; defaults to SettingsFileName under the workspace root
+	Settings     *SettingsStore
+	settingsOnce sync.Once
 }
 
 // --- WebSocket Manager ---
 
+// EventConn is the write side of a client connection. *websocket.Conn
+// satisfies it.
+type EventConn interface {
+	WriteJSON(v interface{}) error
+}
+
+// controlConn is implemented by connections that can send control frames
+type controlConn interface {
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+}
+
+// ShutdownCloseReason is sent in the close frame when the server stops
+const ShutdownCloseReason = "server shutting down"
+
+const closeFrameTimeout = time.Second
+
+// shutdownDrainTimeout caps how long Shutdown waits for in-flight turns,
+// leaving the rest of the caller's deadline for the HTTP server.
+const shutdownDrainTimeout = 3 * time.Second
+
+// ConnectionManager tracks chat sessions by UUID. Several connections may
+// share one session, e.g. the same task open on two devices.
 type ConnectionManager struct {
-	sessions map[*websocket.Conn]*ChatSession
+	sessions map[uuid.UUID]*ChatSession
+	conns    map[EventConn]uuid.UUID
 	mu       sync.RWMutex
 	config   Config
 }
 
 func NewConnectionManager(cfg Config) *ConnectionManager {
 	return &ConnectionManager{
-		sessions: make(map[*websocket.Conn]*ChatSession),
+		sessions: make(map[uuid.UUID]*ChatSession),
+		conns:    make(map[EventConn]uuid.UUID),
 		config:   cfg,
 	}
 }
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
+	// Negotiate per-message deflate; writeEvent only compresses large events
+	EnableCompression: true,
 }
 
 // --- Chat Session Logic ---
 
 type ChatSession struct {
-	Conn        *websocket.Conn
+	// conns maps each attached connection to the event types it
+	// subscribed to
+	conns       map[EventConn]eventFilter
 	SessionUUID uuid.UUID
 	Workspace   string
 	Manager     *ConnectionManager
 	LLMClient    llm.Client
 	History      *llm.MessageHistory
 	SystemPrompt string
-	mu           sync.Mutex
+	ModelName    string
+	Usage        UsageTotals
+	// Tracker accumulates usage per model across the session's lifetime
+	Tracker *UsageTracker
+	// SummarizeWithLLM adds a short LLM-written line to session summary cards
+	SummarizeWithLLM bool
+	LastSummary      *SessionSummaryCard
+	// HistoryName is the name last used with /save or /load
+	HistoryName string
+	// Sandbox is where the agent works, as chosen in init_agent
+	Sandbox config.SandboxConfig
+	// Approvals holds the agent's tool calls awaiting the user's approve
+	// or deny message
+	Approvals *agents.ApprovalQueue
+	// Browser is the browser the session's agent drives, if it has one
+	Browser tools.TabSwitcher
+	// sandbox is the running Docker workspace, torn down when the session
+	// ends
+	sandbox sandboxWorkspace
+	mu          sync.Mutex
+	turns       sync.WaitGroup // in-flight HandleMessage calls
+	// correlationID is the X-Request-ID of the latest connection
+	correlationID string
+	// ctx is the context of the session's turns; see context and end
+	ctx    context.Context
+	cancel context.CancelFunc
+	// turnCtx is the context of the running queries, derived from ctx and
+	// cancelled by a cancel message; see turnContext
+	turnCtx    context.Context
+	turnCancel context.CancelFunc
+	// approver, when set, answers the agent's tool calls instead of the
+	// user; replays approve every call
+	approver agents.Approver
+	// recorded is set once the session has a database record; see
+	// recordEvent
+	recordMu sync.Mutex
+	recorded bool
 }
 
+// setCorrelationID makes id the correlation ID carried by the session's
+// error events. The latest connection's request ID wins.
+func (s *ChatSession) setCorrelationID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.correlationID = id
+}
+
+// SendEvent broadcasts an event to every connection attached to the
+// session whose subscription includes eventType. Error events carry the session's correlation ID as request_id.
 func (s *ChatSession) SendEvent(eventType string, content interface{}) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
-	if s.Conn == nil {
-		return
-	}
 
+	if eventType == EventTypeError {
+		content = withRequestID(content, s.correlationID)
+	}
 	msg := RealtimeEvent{
 		Type:    eventType,
 		Content: content,
 	}
-	if err := s.Conn.WriteJSON(msg); err != nil {
-		log.Printf("Error sending event: %v", err)
+	for conn, filter := range s.conns {
+		if !filter.allows(eventType) {
+			continue
+		}
+		if err := writeEvent(conn, msg); err != nil {
+			core.Logger.Error("failed to send event", "type", eventType, "error", err)
+		}
+	}
+}
+
+// sendTo writes an event to a single connection of the session
+func (s *ChatSession) sendTo(conn EventConn, eventType string, content interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := writeEvent(conn, RealtimeEvent{Type: eventType, Content: content}); err != nil {
+		core.Logger.Error("failed to send event", "type", eventType, "error", err)
 	}
 }
 
-func (s *ChatSession) StartLoop() {
+// StartLoop reads messages from conn until it closes, is idle for too
+// long or sends a message over MaxMessageSize. Each connection of a shared
+// session runs its own loop.
+func (s *ChatSession) StartLoop(conn *websocket.Conn) {
+	idle := s.idleTimeout()
+	stop := make(chan struct{})
 	defer func() {
-		s.Manager.Disconnect(s.Conn)
-		s.Conn.Close()
+		close(stop)
+		s.Manager.Disconnect(conn)
+		conn.Close()
 	}()
+	prepareConn(conn, idle)
+	go keepAlive(conn, idle, stop)
 
 	// Handshake
-	s.SendEvent(EventTypeConnectionEstablished, gin.H{
+	s.sendTo(conn, EventTypeConnectionEstablished, gin.H{
 		"message":        "Connected to Water AI Server",
 		"workspace_path": s.Workspace,
+		"session_id":     s.SessionUUID.String(),
 	})
 
 	for {
-		_, messageData, err := s.Conn.ReadMessage()
+		_, messageData, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WS Error: %v", err)
-			}
+			closeOnReadError(conn, err)
 			break
 		}
-		go s.HandleMessage(messageData)
+		extendReadDeadline(conn, idle)
+		if s.handleSubscribe(conn, messageData) {
+			continue
+		}
+		s.turns.Add(1)
+		go func() {
+			defer s.turns.Done()
+			s.HandleMessage(messageData)
+		}()
 	}
 }
 
@@ -147,7 +298,20 @@ func (s *ChatSession) HandleMessage(data []byte) {
 	case "workspace_info":
 		s.SendEvent(EventTypeWorkspaceInfo, gin.H{"path": s.Workspace})
 	case "cancel":
+		s.cancelTurns()
 		s.SendEvent(EventTypeSystem, gin.H{"message": "Query cancelled"})
+	case "approve", "deny":
+		var content ToolApprovalContent
+		_ = json.Unmarshal(msg.Content, &content)
+		s.handleToolApproval(content, msg.Type == "approve")
+	case "audio":
+		var content AudioContent
+		_ = json.Unmarshal(msg.Content, &content)
+		s.handleAudio(content)
+	case "switch_tab":
+		var content SwitchTabContent
+		_ = json.Unmarshal(msg.Content, &content)
+		s.handleSwitchTab(content)
 	// Add other handlers (edit_query, etc.) as needed
 	default:
 		s.SendEvent(EventTypeError, gin.H{"message": "Unknown message type"})
@@ -155,59 +319,122 @@ func (s *ChatSession) HandleMessage(data []byte) {
 }
 
 func (s *ChatSession) handleInitAgent(content InitAgentContent) {
-	// Create workspace if needed
-	os.MkdirAll(s.Workspace, 0755)
-
-	// Determine API type from model name
-	modelName := content.ModelName
-	if modelName == "" {
-		modelName = "gpt-4-turbo"
-	}
-
-	apiType := llm.APITypeOpenAI
-	if strings.Contains(modelName, "claude") || strings.Contains(modelName, "anthropic") {
-		apiType = llm.APITypeAnthropic
-	} else if strings.Contains(modelName, "gemini") {
-		apiType = llm.APITypeGemini
-	}
-
-	// Read API key from environment
-	apiKey := os.Getenv("LLM_API_KEY")
-	if apiKey == "" {
-		switch apiType {
-		case llm.APITypeOpenAI:
-			apiKey = os.Getenv("OPENAI_API_KEY")
-		case llm.APITypeAnthropic:
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
-		case llm.APITypeGemini:
-			apiKey = os.Getenv("GEMINI_API_KEY")
+	sandbox := config.SandboxConfig{Mode: config.WorkSpaceModeLocal}
+	if content.Sandbox != nil {
+		sandbox = *content.Sandbox
+		if sandbox.Mode == "" {
+			sandbox.Mode = config.WorkSpaceModeLocal
+		}
+		if err := sandbox.Validate(); err != nil {
+			s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Invalid sandbox settings: %v", err)})
+			return
 		}
 	}
 
-	cfg := llm.LLMConfig{
-		APIType:        apiType,
-		Model:          modelName,
-		APIKey:         apiKey,
-		MaxRetries:     3,
-		ThinkingTokens: content.ThinkingTokens,
-	}
+	// Create workspace if needed
+	os.MkdirAll(s.Workspace, 0755)
 
-	client, err := llm.GetClient(cfg)
+	client, err := newLLMClient(s.llmConfig(), content.ModelName, content.ThinkingTokens)
 	if err != nil {
 		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Failed to initialize LLM client: %v", err)})
 		return
 	}
 
+	// Re-initializing replaces any sandbox started earlier
+	s.stopSandbox()
+	var vscodeURL string
+	if sandbox.Mode != config.WorkSpaceModeLocal {
+		var ws sandboxWorkspace
+		var err error
+		if sandbox.Mode == config.WorkSpaceModeE2B {
+			ws, err = startE2BWorkspace(context.Background(), s, sandbox)
+		} else {
+			ws, err = startDockerWorkspace(context.Background(), s)
+		}
+		if err != nil {
+			s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Failed to start %s sandbox: %v", sandbox.Mode, err)})
+			return
+		}
+		s.mu.Lock()
+		s.sandbox = ws
+		s.mu.Unlock()
+		vscodeURL = ws.CodeServerURL()
+	}
+
 	s.LLMClient = client
-	s.History = llm.NewMessageHistory()
-	s.SystemPrompt = prompts.GetSystemPrompt(prompts.WorkspaceModeLocal, false)
+	s.ModelName = content.ModelName
+	s.SummarizeWithLLM = content.SummarizeSession
+	s.mu.Lock()
+	s.Usage = UsageTotals{}
+	s.mu.Unlock()
+	s.History = s.loadHistory()
+	s.Sandbox = sandbox
+	s.SystemPrompt = prompts.GetSystemPrompt(promptWorkspaceMode(sandbox.Mode), false)
 
 	s.SendEvent(EventTypeAgentInitialized, gin.H{
-		"message": "Agent initialized",
+		"message":        "Agent initialized",
+		"workspace_mode": sandbox.Mode,
+		"vscode_url":     vscodeURL,
 	})
 }
 
+// handleToolApproval answers the agent's pending approval request for a
+// tool call.
+func (s *ChatSession) handleToolApproval(content ToolApprovalContent, approved bool) {
+	if err := s.Approvals.Resolve(content.ToolCallID, approved); err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot answer tool call %q: %v", content.ToolCallID, err)})
+	}
+}
+
+// handleSwitchTab makes another browser tab the current one and sends the
+// updated tab list.
+func (s *ChatSession) handleSwitchTab(content SwitchTabContent) {
+	if s.Browser == nil {
+		s.SendEvent(EventTypeError, gin.H{"message": "No browser is open"})
+		return
+	}
+	if err := s.Browser.SwitchToTab(content.PageID); err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot switch to tab %d: %v", content.PageID, err)})
+		return
+	}
+	tabs, err := s.Browser.GetTabsInfo()
+	if err != nil {
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Cannot list browser tabs: %v", err)})
+		return
+	}
+	event := gin.H{"kind": "activate", "active": content.PageID, "tabs": tabs}
+	for _, tab := range tabs {
+		if tab.PageID == content.PageID {
+			event["tab"] = tab
+		}
+	}
+	s.SendEvent(EventTypeBrowserTabs, event)
+}
+
+// promptWorkspaceMode maps a workspace mode to the system prompt variant:
+// docker and e2b agents work inside a sandbox.
+func promptWorkspaceMode(mode config.WorkSpaceMode) prompts.WorkspaceMode {
+	if mode == config.WorkSpaceModeLocal {
+		return prompts.WorkspaceModeLocal
+	}
+	return prompts.WorkspaceModeSandbox
+}
+
+// newLLMClient builds an LLM client for modelName from cfg, reading the
+// API key from the environment when cfg has none. Tests replace it.
+var newLLMClient = llm.NewClientFromConfig
+
+// llmConfig returns the LLM configuration of the session's server.
+func (s *ChatSession) llmConfig() config.LLMConfig {
+	if s.Manager == nil {
+		return config.LLMConfig{MaxRetries: 3}
+	}
+	return s.Manager.config.LLM
+}
+
 func (s *ChatSession) handleQuery(content QueryContent) {
+	defer s.persistUsage()
+
 	if strings.HasPrefix(content.Text, "/") {
 		s.handleSlashCommand(content.Text)
 		return
@@ -219,42 +446,33 @@ func (s *ChatSession) handleQuery(content QueryContent) {
 	}
 
 	s.SendEvent(EventTypeProcessing, gin.H{"message": "Processing request..."})
-
-	// Add user message to history
-	s.History.AddUserPrompt(content.Text, nil)
-
-	// Call the real LLM client
-	resp, err := s.LLMClient.Generate(
-		s.History.GetMessages(),
-		4096,
-		s.SystemPrompt,
-		0.0,
-		nil,  // tools
-		nil,  // toolChoice
-		nil,  // thinkingTokens
-	)
-	if err != nil {
-		log.Printf("LLM Generate error: %v", err)
-		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("LLM error: %v", err)})
+	started := time.Now()
+	workspaceBefore := snapshotWorkspace(s.Workspace)
+	metrics.AgentTurns.Inc(metricsComponentChat)
+
+	s.recordEvent(EventTypeUserMessage, content)
+	ctx := s.turnContext()
+	// The agent adds the query and its turns to the session history and
+	// reports its own errors
+	responseText, err := s.runAgent(ctx, content.Text, content.Files)
+	s.persistHistory()
+	// A cancelled query ends without an answer; the agent has already
+	// noted the interruption in the history
+	if err != nil || ctx.Err() != nil {
 		s.SendEvent(EventTypeStreamComplete, gin.H{})
 		return
 	}
 
-	// Add assistant response to history
-	s.History.AddAssistantTurn(resp.Content)
-
-	// Extract text from response blocks and send to client
-	var responseText string
-	for _, block := range resp.Content {
-		if block.Type == llm.ContentTypeText && block.Text != "" {
-			responseText += block.Text
-		}
-	}
-
 	if responseText != "" {
+		s.recordEvent(EventTypeAgentResponse, gin.H{"text": responseText})
 		s.SendEvent(EventTypeAgentResponse, gin.H{"text": responseText})
 	}
+	s.emitSessionSummary(content.Text, responseText, started, workspaceBefore)
 	s.SendEvent(EventTypeStreamComplete, gin.H{})
+	// Speech follows the completed turn so synthesis does not hold it open
+	if responseText != "" {
+		s.speak(ctx, responseText)
+	}
 }
 
 func (s *ChatSession) handleSlashCommand(cmd string) {
@@ -265,19 +483,42 @@ func (s *ChatSession) handleSlashCommand(cmd string) {
 
 	switch parts[0] {
 	case "/help":
-		s.SendEvent(EventTypeSystem, gin.H{"message": "Available commands: /help, /compact"})
+		s.SendEvent(EventTypeSystem, gin.H{"message": slashHelp})
 		s.SendEvent(EventTypeStreamComplete, gin.H{})
 	case "/compact":
+		if len(parts) > 1 {
+			s.commandError("/compact takes no arguments")
+			return
+		}
 		s.SendEvent(EventTypeProcessing, gin.H{"message": "Compacting memory..."})
-		time.Sleep(500 * time.Millisecond)
-		s.SendEvent(EventTypeSystem, gin.H{"message": "Memory compacted."})
+		result, err := s.compactHistory(s.context())
+		if err != nil {
+			s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Compact failed: %v", err)})
+			s.SendEvent(EventTypeStreamComplete, gin.H{})
+			return
+		}
+		s.SendEvent(EventTypeSystem, gin.H{
+			"message":       fmt.Sprintf("Memory compacted: %d -> %d tokens.", result.TokensBefore, result.TokensAfter),
+			"summary":       result.Summary,
+			"tokens_before": result.TokensBefore,
+			"tokens_after":  result.TokensAfter,
+		})
 		s.SendEvent(EventTypeStreamComplete, gin.H{})
+	case "/clear":
+		s.handleClearCommand(parts[1:])
+	case "/save":
+		s.handleSaveCommand(parts[1:])
+	case "/load":
+		s.handleLoadCommand(parts[1:])
 	default:
-		s.SendEvent(EventTypeError, gin.H{"message": "Unknown command"})
+		s.SendEvent(EventTypeError, gin.H{"message": fmt.Sprintf("Unknown command %s. Type /help for available commands.", parts[0])})
 	}
 }
 
-func (m *ConnectionManager) Connect(conn *websocket.Conn, sessionUUIDStr string) *ChatSession {
+// Connect attaches conn to the session identified by sessionUUIDStr,
+// creating the session if it has no other connections. An invalid UUID
+// starts a new session.
+func (m *ConnectionManager) Connect(conn EventConn, sessionUUIDStr string) *ChatSession {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -286,31 +527,136 @@ func (m *ConnectionManager) Connect(conn *websocket.Conn, sessionUUIDStr string)
 		uid = uuid.New()
 	}
 
-	// Resolve workspace path
-	workspacePath := filepath.Join(m.config.WorkspaceRoot, uid.String())
+	session, ok := m.sessions[uid]
+	if !ok {
+		// Resolve workspace path
+		workspacePath := filepath.Join(m.config.WorkspaceRoot, uid.String())
 
-	session := &ChatSession{
-		Conn:        conn,
-		SessionUUID: uid,
-		Workspace:   workspacePath,
-		Manager:     m,
+		session = &ChatSession{
+			conns:       make(map[EventConn]eventFilter),
+			SessionUUID: uid,
+			Workspace:   workspacePath,
+			Manager:     m,
+			Tracker:     LoadUsageTracker(uid),
+			Approvals:   agents.NewApprovalQueue(),
+		}
+		m.sessions[uid] = session
+		log.Printf("New Session: %s", uid.String())
+	} else {
+		log.Printf("Joined Session: %s", uid.String())
 	}
 
-	m.sessions[conn] = session
-	log.Printf("New Session: %s", uid.String())
+	session.mu.Lock()
+	session.conns[conn] = nil
+	session.mu.Unlock()
+	m.conns[conn] = uid
 	return session
 }
 
-func (m *ConnectionManager) Disconnect(conn *websocket.Conn) {
+// Session returns the connected session with the given ID, or nil.
+func (m *ConnectionManager) Session(id uuid.UUID) *ChatSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sessions[id]
+}
+
+// ActiveSessions returns the number of sessions with at least one connection
+func (m *ConnectionManager) ActiveSessions() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sessions)
+}
+
+// Disconnect detaches conn from its session. The session is dropped once
+// its last connection is gone.
+func (m *ConnectionManager) Disconnect(conn EventConn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if _, ok := m.sessions[conn]; ok {
-		delete(m.sessions, conn)
+
+	uid, ok := m.conns[conn]
+	if !ok {
+		return
+	}
+	delete(m.conns, conn)
+
+	session := m.sessions[uid]
+	session.mu.Lock()
+	delete(session.conns, conn)
+	remaining := len(session.conns)
+	session.mu.Unlock()
+
+	if remaining == 0 {
+		delete(m.sessions, uid)
+		session.end()
+		session.persistUsage()
+		go session.teardown()
+	}
+}
+
+// Shutdown drains every session: it sends a close frame to each
+// connection, waits briefly for in-flight turns to finish, then cancels
+// any turns still running, closes the connections and forgets all
+// sessions. It returns a context error if turns were still running when
+// the wait ended.
+func (m *ConnectionManager) Shutdown(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, shutdownDrainTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = make(map[uuid.UUID]*ChatSession)
+	m.conns = make(map[EventConn]uuid.UUID)
+	m.mu.Unlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, ShutdownCloseReason)
+	for _, session := range sessions {
+		session.mu.Lock()
+		for conn := range session.conns {
+			if cc, ok := conn.(controlConn); ok {
+				if err := cc.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(closeFrameTimeout)); err != nil {
+					core.Logger.Warn("failed to send close frame", "error", err)
+				}
+			}
+		}
+		session.mu.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, session := range sessions {
+			session.turns.Wait()
+		}
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		core.Logger.Warn("shutting down with agent turns still running", "error", err)
+	}
+
+	for _, session := range sessions {
+		session.mu.Lock()
+		for conn := range session.conns {
+			if c, ok := conn.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		session.conns = make(map[EventConn]eventFilter)
+		session.mu.Unlock()
+		session.end()
+		session.teardown()
 	}
+	return err
 }
 
 // --- HTTP Handlers ---
 
+// MaxUploadSize is the largest file /api/upload accepts, after decoding
+const MaxUploadSize = 20 << 20
+
 // UploadHandler handles file uploads (base64 or text)
 func (s *Server) UploadHandler(c *gin.Context) {
 	var req UploadRequest
@@ -369,6 +715,10 @@ func (s *Server) UploadHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode content"})
 		return
 	}
+	if len(contentBytes) > MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds the %d MB upload limit", MaxUploadSize>>20)})
+		return
+	}
 
 	if err := os.WriteFile(fullPath, contentBytes, 0644); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -379,120 +729,58 @@ func (s *Server) UploadHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "File uploaded successfully",
 		"file": gin.H{
-			"path":       "/" + relPath,
-			"saved_path": fullPath,
+			"path":         "/" + relPath,
+			"saved_path":   fullPath,
+			"content_type": sniffContentType(fullPath, contentBytes),
 		},
 	})
 }
 
-// GetSessionsHandler (Mock Implementation)
-func (s *Server) GetSessionsHandler(c *gin.Context) {
-	deviceID := c.Param("device_id")
-	// Note: In a real implementation, you would query SQLite/Postgres here.
-	// Returning a mock response for demonstration.
-	c.JSON(http.StatusOK, SessionResponse{
-		Sessions: []SessionInfo{
-			{
-				ID:           uuid.New().String(),
-				WorkspaceDir: s.Config.WorkspaceRoot,
-				CreatedAt:    time.Now().Format(time.RFC3339),
-				DeviceID:     deviceID,
-				Name:         "Demo Session",
-			},
-		},
-	})
-}
-
-// GetEventsHandler (Mock Implementation)
-func (s *Server) GetEventsHandler(c *gin.Context) {
-	sessionID := c.Param("session_id")
-	c.JSON(http.StatusOK, EventResponse{
-		Events: []EventInfo{
-			{
-				ID:        uuid.New().String(),
-				SessionID: sessionID,
-				Timestamp: time.Now().Format(time.RFC3339),
-				EventType: "system",
-				EventPayload: map[string]interface{}{
-					"message": "Session started",
-				},
-			},
-		},
-	})
-}
-
-// SessionsHandler handles both /sessions/:device_id and /sessions/:session_id/events
-func (s *Server) SessionsHandler(c *gin.Context) {
-	path := c.Param("path")
-	// Remove leading slash if present
-	if len(path) > 0 && path[0] == '/' {
-		path = path[1:]
-	}
-
-	if strings.HasPrefix(path, "events") {
-		// Handle /sessions/:session_id/events
-		sessionID := strings.TrimPrefix(path, "events")
-		sessionID = strings.TrimPrefix(sessionID, "/")
-		c.JSON(http.StatusOK, EventResponse{
-			Events: []EventInfo{
-				{
-					ID:        uuid.New().String(),
-					SessionID: sessionID,
-					Timestamp: time.Now().Format(time.RFC3339),
-					EventType: "system",
-					EventPayload: map[string]interface{}{
-						"message": "Session started",
-					},
-				},
-			},
-		})
-	} else {
-		// Handle /sessions/:device_id
-		deviceID := path
-		c.JSON(http.StatusOK, SessionResponse{
-			Sessions: []SessionInfo{
-				{
-					ID:           uuid.New().String(),
-					WorkspaceDir: s.Config.WorkspaceRoot,
-					CreatedAt:    time.Now().Format(time.RFC3339),
-					DeviceID:     deviceID,
-					Name:         "Demo Session",
-				},
-			},
-		})
-	}
-}
-
-// GetSettingsHandler
+// GetSettingsHandler returns the stored settings with secrets redacted
 func (s *Server) GetSettingsHandler(c *gin.Context) {
-	// Mock loading settings
-	settings := GETSettingsModel{
-		Settings: Settings{
-			LLMConfigs: map[string]LLMConfig{
-				"gpt-4": {Model: "gpt-4"},
-			},
-		},
-		LLMAPIKeySet:    true,
-		SearchAPIKeySet: false,
+	settings, err := s.settingsStore().Load()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	c.JSON(http.StatusOK, settings)
+	c.JSON(http.StatusOK, settingsResponse(settings))
 }
 
-// PostSettingsHandler
+// PostSettingsHandler persists the posted settings
 func (s *Server) PostSettingsHandler(c *gin.Context) {
 	var settings Settings
 	if err := c.ShouldBindJSON(&settings); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Logic to save settings to file/DB goes here
+	if _, err := s.settingsStore().Update(settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Settings updated: %v", config.RedactMap(settings))
 	c.JSON(http.StatusOK, gin.H{"message": "Settings stored"})
 }
 
+// settingsStore returns the server's store, defaulting to the settings
+// file at the workspace root
+func (s *Server) settingsStore() *SettingsStore {
+	s.settingsOnce.Do(func() {
+		if s.Settings == nil {
+			s.Settings = NewSettingsStore(filepath.Join(s.Config.WorkspaceRoot, SettingsFileName))
+		}
+	})
+	return s.Settings
+}
+
 // --- Factory ---
 
 func CreateServer(config Config) *Server {
-	router := gin.Default()
+	if config.MinimizeLogs {
+		gin.SetMode(gin.ReleaseMode)
+		core.MinimizeLogs()
+	}
+	router := gin.New()
+	router.Use(requestLogger(), gin.Recovery())
 	
 	// Setup CORS
 	router.Use(cors.New(cors.Config{
@@ -502,39 +790,70 @@ func CreateServer(config Config) *Server {
 		AllowCredentials: true,
 	}))
 
+	if config.EnableMetrics {
+		router.Use(requestMetrics())
+	}
+
 	manager := NewConnectionManager(config)
 
 	srv := &Server{
 		Config:    config,
 		Router:    router,
 		WSManager: manager,
+		StartedAt: time.Now(),
+	}
+
+	router.GET("/health/detailed", srv.DetailedHealthHandler)
+
+	auth := APIKeyAuth(config.APIKey)
+
+	if config.EnableMetrics {
+		router.GET("/metrics", auth, srv.MetricsHandler)
 	}
 
 	// API Routes
-	api := router.Group("/api")
+	api := router.Group("/api", auth)
 	{
 		api.POST("/upload", srv.UploadHandler)
-		api.GET("/sessions/*path", srv.SessionsHandler)
+		// gin allows one wildcard name per path segment, so the device
+		// listing and the per-session routes share :id.
+		api.GET("/sessions/:id", srv.GetSessionsHandler)
+		api.GET("/sessions/:id/events", srv.GetEventsHandler)
+		api.GET("/sessions/:id/events/search", srv.SearchEventsHandler)
+		api.GET("/sessions/:id/usage", srv.UsageHandler)
+		api.GET("/sessions/:id/export", srv.ExportSessionHandler)
+		api.POST("/sessions/:id/replay", srv.ReplaySessionHandler)
+		api.GET("/sessions/:id/files/*path", srv.SessionFileHandler)
+		api.POST("/sessions/import", srv.ImportSessionHandler)
+		api.PATCH("/sessions/:session_id", srv.RenameSessionHandler)
+		api.DELETE("/sessions/:session_id", srv.DeleteSessionHandler)
 		api.GET("/settings", srv.GetSettingsHandler)
 		api.POST("/settings", srv.PostSettingsHandler)
+		api.GET("/models", srv.ListModelsHandler)
 	}
 
 	// Workspace Static Files
 	// Create root if it doesn't exist
 	os.MkdirAll(config.WorkspaceRoot, 0755)
-	router.StaticFS("/workspace", gin.Dir(config.WorkspaceRoot, true))
+	router.GET("/workspace/*filepath", auth, workspaceFileHandler(config.WorkspaceRoot))
 
 	// WebSocket Endpoint
-	router.GET("/ws", func(c *gin.Context) {
+	router.GET("/ws", auth, func(c *gin.Context) {
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			log.Println("Failed to upgrade WS:", err)
+			core.Logger.Error("failed to upgrade websocket", "error", err)
 			return
 		}
 		
 		sessionID := c.Query("session_uuid")
 		session := manager.Connect(conn, sessionID)
-		go session.StartLoop()
+		requestID := RequestID(c)
+		session.setCorrelationID(requestID)
+		core.Logger.Info("websocket connected", "request_id", requestID, "session_id", session.SessionUUID.String())
+		go func() {
+			session.StartLoop(conn)
+			core.Logger.Info("websocket disconnected", "request_id", requestID, "session_id", session.SessionUUID.String())
+		}()
 	})
 
 	// Frontend Static Files (SPA fallback for client-side routing)
@@ -546,6 +865,67 @@ func CreateServer(config Config) *Server {
 	return srv
 }
 
+// workspaceFileHandler serves files (and directory listings) from the
+// workspace root, setting Content-Type from the extension or, for unknown
+// extensions, by sniffing the file content. The settings file, which holds
+// API keys, is never served under any name that reaches it: another case
+// on a case-insensitive filesystem, a symlink or a hard link.
+func workspaceFileHandler(root string) gin.HandlerFunc {
+	fileServer := http.StripPrefix("/workspace", http.FileServer(gin.Dir(root, true)))
+	settingsPath := filepath.Join(root, SettingsFileName)
+	return func(c *gin.Context) {
+		fullPath, err := utils.ResolveInRoot(root, strings.TrimPrefix(filepath.FromSlash(c.Param("filepath")), string(filepath.Separator)))
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		if info, err := os.Stat(fullPath); err == nil {
+			if settings, err := os.Stat(settingsPath); err == nil && os.SameFile(info, settings) {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			if !info.IsDir() {
+				if ct := detectContentType(fullPath); ct != "" {
+					c.Header("Content-Type", ct)
+				}
+			}
+		}
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// detectContentType returns the Content-Type for a file on disk, using the
+// extension map first and falling back to sniffing its first 512 bytes.
+func detectContentType(path string) string {
+	if ct := getContentType(path); ct != "" {
+		return ct
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// sniffContentType is detectContentType for content already in memory.
+func sniffContentType(path string, content []byte) string {
+	if ct := getContentType(path); ct != "" {
+		return ct
+	}
+	if len(content) > 512 {
+		content = content[:512]
+	}
+	return http.DetectContentType(content)
+}
+
 // getContentType returns the appropriate Content-Type for static files
 func getContentType(path string) string {
 	ext := filepath.Ext(path)
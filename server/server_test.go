@@ -1,11 +1,15 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestConfigGetPort(t *testing.T) {
@@ -105,6 +109,77 @@ func TestConnectionManagerDisconnect(t *testing.T) {
 	manager.Disconnect(nil)
 }
 
+// recordingConn captures the events written to it.
+type recordingConn struct {
+	mu     sync.Mutex
+	events []RealtimeEvent
+}
+
+func (c *recordingConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, v.(RealtimeEvent))
+	return nil
+}
+
+func (c *recordingConn) types() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []string
+	for _, e := range c.events {
+		out = append(out, e.Type)
+	}
+	return out
+}
+
+func TestConnectionManagerBroadcastsToSharedSession(t *testing.T) {
+	manager := NewConnectionManager(Config{WorkspaceRoot: "/test"})
+	id := uuid.New().String()
+	first, second, other := &recordingConn{}, &recordingConn{}, &recordingConn{}
+
+	s1 := manager.Connect(first, id)
+	s2 := manager.Connect(second, id)
+	manager.Connect(other, uuid.New().String())
+	if s1 != s2 {
+		t.Fatal("connections with the same UUID should share one session")
+	}
+	if got := manager.ActiveSessions(); got != 2 {
+		t.Errorf("ActiveSessions() = %d; want 2", got)
+	}
+
+	s1.SendEvent(EventTypeAgentResponse, map[string]string{"text": "hello"})
+	for name, conn := range map[string]*recordingConn{"first": first, "second": second} {
+		if got := conn.types(); len(got) != 1 || got[0] != EventTypeAgentResponse {
+			t.Errorf("%s connection received %v; want [%s]", name, got, EventTypeAgentResponse)
+		}
+	}
+	if got := other.types(); len(got) != 0 {
+		t.Errorf("connection of another session received %v; want nothing", got)
+	}
+
+	// Dropping one connection keeps the session alive for the other.
+	manager.Disconnect(first)
+	if got := manager.ActiveSessions(); got != 2 {
+		t.Errorf("ActiveSessions() after one disconnect = %d; want 2", got)
+	}
+	s2.SendEvent(EventTypeSystem, map[string]string{"message": "still here"})
+	if got := first.types(); len(got) != 1 {
+		t.Errorf("disconnected connection received %v; want no new events", got)
+	}
+	if got := second.types(); len(got) != 2 {
+		t.Errorf("remaining connection received %v; want 2 events", got)
+	}
+	if again := manager.Connect(&recordingConn{}, id); again != s1 {
+		t.Error("rejoining a live session should reuse it")
+	}
+
+	manager.Disconnect(second)
+	manager.Disconnect(other)
+	if got := manager.ActiveSessions(); got != 1 {
+		t.Errorf("ActiveSessions() = %d; want 1", got)
+	}
+}
+
 func TestGetContentType(t *testing.T) {
 	tests := []struct {
 		path     string
@@ -220,7 +295,7 @@ func TestChatSessionStruct(t *testing.T) {
 
 func TestConnectionManagerStruct(t *testing.T) {
 	manager := &ConnectionManager{
-		sessions: make(map[*websocket.Conn]*ChatSession),
+		sessions: make(map[uuid.UUID]*ChatSession),
 		config:   Config{},
 	}
 
@@ -508,4 +583,88 @@ func TestEventInfoStruct(t *testing.T) {
 
 func strPtr(s string) *string {
 	return &s
-}
\ No newline at end of file
+}
+// closingConn records close frames and Close calls on top of recordingConn.
+type closingConn struct {
+	recordingConn
+	closeFrames [][]byte
+	closed      bool
+}
+
+func (c *closingConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if messageType == websocket.CloseMessage {
+		c.closeFrames = append(c.closeFrames, data)
+	}
+	return nil
+}
+
+func (c *closingConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func TestConnectionManagerShutdown(t *testing.T) {
+	manager := NewConnectionManager(Config{WorkspaceRoot: "/test"})
+	id := uuid.New().String()
+	a, b, c := &closingConn{}, &closingConn{}, &closingConn{}
+	session := manager.Connect(a, id)
+	manager.Connect(b, id)
+	manager.Connect(c, uuid.New().String())
+
+	// Simulate an agent turn that finishes shortly after shutdown begins.
+	session.turns.Add(1)
+	finished := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+		session.turns.Done()
+	}()
+
+	if err := manager.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("Shutdown() should wait for in-flight turns")
+	}
+
+	want := websocket.FormatCloseMessage(websocket.CloseGoingAway, ShutdownCloseReason)
+	for i, conn := range []*closingConn{a, b, c} {
+		if len(conn.closeFrames) != 1 || !bytes.Equal(conn.closeFrames[0], want) {
+			t.Errorf("conn %d close frames = %q; want one %q", i, conn.closeFrames, want)
+		}
+		if !conn.closed {
+			t.Errorf("conn %d was not closed", i)
+		}
+	}
+	if got := manager.ActiveSessions(); got != 0 {
+		t.Errorf("ActiveSessions() = %d; want 0 after shutdown", got)
+	}
+	// Late disconnects from read loops must be harmless.
+	manager.Disconnect(a)
+}
+
+func TestConnectionManagerShutdownTimesOut(t *testing.T) {
+	manager := NewConnectionManager(Config{})
+	session := manager.Connect(&closingConn{}, "")
+	session.turns.Add(1)
+	defer session.turns.Done()
+	turnCtx := session.context()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := manager.Shutdown(ctx); err == nil {
+		t.Error("Shutdown() should report turns still running at the deadline")
+	}
+	if turnCtx.Err() == nil {
+		t.Error("Shutdown() should cancel the turns still running")
+	}
+	if got := manager.ActiveSessions(); got != 0 {
+		t.Errorf("ActiveSessions() = %d; want 0", got)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+// GetSessionsHandler handles GET /api/sessions/:id, listing the sessions of
+// the device :id, newest first.
+func (s *Server) GetSessionsHandler(c *gin.Context) {
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+	deviceID := c.Param("id")
+
+	sessions, err := db.Sessions.GetSessionsByDeviceID(deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ids := make([]string, len(sessions))
+	for i, sess := range sessions {
+		ids[i] = sess.ID
+	}
+	lastEvents, err := db.Events.GetLastEventTimes(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		info := SessionInfo{
+			ID:           sess.ID,
+			WorkspaceDir: sess.WorkspaceDir,
+			CreatedAt:    sess.CreatedAt.Format(time.RFC3339),
+			DeviceID:     deviceID,
+		}
+		if sess.Name != nil {
+			info.Name = *sess.Name
+		}
+		if t, ok := lastEvents[sess.ID]; ok {
+			info.LastEventAt = t.Format(time.RFC3339)
+		}
+		infos = append(infos, info)
+	}
+	c.JSON(http.StatusOK, SessionResponse{Sessions: infos})
+}
+
+// GetEventsHandler handles GET /api/sessions/:id/events, returning the
+// session's events oldest first.
+func (s *Server) GetEventsHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	sess, err := db.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sess == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	events, err := db.Events.GetSessionEventsWithDetails(sessionID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	infos := make([]EventInfo, 0, len(events))
+	for _, evt := range events {
+		payload, _ := evt["event_payload"].(map[string]interface{})
+		infos = append(infos, EventInfo{
+			ID:           evt["id"].(string),
+			SessionID:    evt["session_id"].(string),
+			Timestamp:    evt["timestamp"].(string),
+			EventType:    evt["event_type"].(string),
+			EventPayload: payload,
+			WorkspaceDir: sess.WorkspaceDir,
+		})
+	}
+	c.JSON(http.StatusOK, EventResponse{Events: infos})
+}
+
+// RenameSessionRequest is the body of PATCH /api/sessions/:session_id.
+type RenameSessionRequest struct {
+	Name string `json:"name"`
+}
+
+// RenameSessionHandler handles PATCH /api/sessions/:session_id
+func (s *Server) RenameSessionHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	var req RenameSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	sess, err := db.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sess == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errSessionNotFound.Error()})
+		return
+	}
+	if err := db.Sessions.UpdateSessionName(sessionID, name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session_id": sessionID.String(), "name": name})
+}
+
+// DeleteSessionHandler handles DELETE /api/sessions/:session_id. Deletion
+// is idempotent: a missing session also gets 204. With
+// ?remove_workspace=true the session's workspace directory is removed too,
+// provided it lies under the workspace root.
+func (s *Server) DeleteSessionHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	sess, err := db.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sess == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err := db.Sessions.DeleteSession(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("remove_workspace") == "true" {
+		if err := s.removeWorkspace(sess.WorkspaceDir); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// removeWorkspace deletes dir if it is strictly inside the workspace root;
+// anything else is left alone.
+func (s *Server) removeWorkspace(dir string) error {
+	root, err := filepath.Abs(s.Config.GetWorkspaceRoot())
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(abs, root+string(filepath.Separator)) {
+		return nil
+	}
+	return os.RemoveAll(abs)
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+)
+
+func sendSessionRequest(t *testing.T, srv *Server, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	srv.Router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRenameSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	w := sendSessionRequest(t, srv, http.MethodPatch, "/api/sessions/"+id.String(), `{"name":"  Oslo forecast "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d; body %s", w.Code, w.Body.String())
+	}
+	sess, _ := db.Sessions.GetSessionByID(id)
+	if sess == nil || sess.Name == nil || *sess.Name != "Oslo forecast" {
+		t.Errorf("session name = %v; want Oslo forecast", sess.Name)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   int
+	}{
+		{"empty name", "/api/sessions/" + id.String(), `{"name":"  "}`, http.StatusBadRequest},
+		{"bad id", "/api/sessions/not-a-uuid", `{"name":"x"}`, http.StatusBadRequest},
+		{"missing session", "/api/sessions/" + uuid.New().String(), `{"name":"x"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := sendSessionRequest(t, srv, http.MethodPatch, tt.target, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	sess, _ := db.Sessions.GetSessionByID(id)
+	os.MkdirAll(sess.WorkspaceDir, 0755)
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	w := sendSessionRequest(t, srv, http.MethodDelete, "/api/sessions/"+id.String()+"?remove_workspace=true", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d; body %s", w.Code, w.Body.String())
+	}
+	if sess, _ := db.Sessions.GetSessionByID(id); sess != nil {
+		t.Error("session still exists after delete")
+	}
+	if events, _ := db.Events.GetSessionEvents(id); len(events) != 0 {
+		t.Errorf("GetSessionEvents() returned %d events after delete; want 0", len(events))
+	}
+	if _, err := os.Stat(sess.WorkspaceDir); !os.IsNotExist(err) {
+		t.Errorf("workspace still exists after delete: %v", err)
+	}
+}
+
+func TestDeleteSessionKeepsWorkspaceByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	sess, _ := db.Sessions.GetSessionByID(id)
+	os.MkdirAll(sess.WorkspaceDir, 0755)
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	w := sendSessionRequest(t, srv, http.MethodDelete, "/api/sessions/"+id.String(), "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d", w.Code)
+	}
+	if _, err := os.Stat(sess.WorkspaceDir); err != nil {
+		t.Errorf("workspace removed without remove_workspace: %v", err)
+	}
+}
+
+func TestDeleteMissingSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	w := sendSessionRequest(t, srv, http.MethodDelete, "/api/sessions/"+uuid.New().String(), "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d; want %d", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestGetSessionsByDevice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	device, otherDevice := "device-1", "device-2"
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var ids []uuid.UUID
+	for i, name := range []string{"oldest", "middle", "newest"} {
+		id := uuid.New()
+		ids = append(ids, id)
+		db.Sessions.CreateSession(id, root+"/"+name, &device, nil)
+		db.Sessions.UpdateSessionName(id, name)
+		db.DB.Model(&db.Session{}).Where("id = ?", id.String()).Update("created_at", base.Add(time.Duration(i)*time.Hour))
+	}
+	db.Sessions.CreateSession(uuid.New(), root+"/elsewhere", &otherDevice, nil)
+	db.Events.SaveEvent(ids[1], EventTypeUserMessage, RealtimeEvent{Type: EventTypeUserMessage})
+	lastEvent := base.Add(48 * time.Hour)
+	db.DB.Model(&db.Event{}).Where("session_id = ?", ids[1].String()).Update("timestamp", lastEvent)
+
+	w := sendSessionRequest(t, srv, http.MethodGet, "/api/sessions/"+device, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; body %s", w.Code, w.Body.String())
+	}
+	var resp SessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	var names []string
+	for _, sess := range resp.Sessions {
+		names = append(names, sess.Name)
+		if sess.DeviceID != device {
+			t.Errorf("DeviceID = %q; want %q", sess.DeviceID, device)
+		}
+	}
+	if strings.Join(names, ",") != "newest,middle,oldest" {
+		t.Fatalf("sessions = %v; want newest first", names)
+	}
+	if got := resp.Sessions[0].CreatedAt; got != base.Add(2*time.Hour).Format(time.RFC3339) {
+		t.Errorf("CreatedAt = %q", got)
+	}
+	if got := resp.Sessions[0].WorkspaceDir; got != root+"/newest" {
+		t.Errorf("WorkspaceDir = %q", got)
+	}
+	if got := resp.Sessions[1].LastEventAt; got != lastEvent.Format(time.RFC3339) {
+		t.Errorf("LastEventAt = %q; want %q", got, lastEvent.Format(time.RFC3339))
+	}
+	if got := resp.Sessions[0].LastEventAt; got != "" {
+		t.Errorf("LastEventAt without events = %q; want empty", got)
+	}
+
+	w = sendSessionRequest(t, srv, http.MethodGet, "/api/sessions/unknown-device", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"sessions":[]`) {
+		t.Errorf("unknown device = %d %s; want an empty list", w.Code, w.Body.String())
+	}
+}
+
+func TestGetSessionEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	id := seedExportSession(t, root)
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	w := sendSessionRequest(t, srv, http.MethodGet, "/api/sessions/"+id.String()+"/events", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; body %s", w.Code, w.Body.String())
+	}
+	var resp EventResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	stored, _ := db.Events.GetSessionEvents(id)
+	if len(resp.Events) == 0 || len(resp.Events) != len(stored) {
+		t.Fatalf("events = %d; want the %d stored events", len(resp.Events), len(stored))
+	}
+	for _, evt := range resp.Events {
+		if evt.SessionID != id.String() || evt.EventPayload == nil {
+			t.Errorf("event = %+v", evt)
+		}
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/sessions/not-a-uuid/events", http.StatusBadRequest},
+		{"/api/sessions/" + uuid.New().String() + "/events", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := sendSessionRequest(t, srv, http.MethodGet, tt.target, ""); w.Code != tt.want {
+			t.Errorf("GET %s status = %d; want %d", tt.target, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"water-ai/core/config"
+)
+
+// SettingsFileName is the settings file kept at the workspace root. It is
+// never served through /workspace.
+const SettingsFileName = ".water_settings.json"
+
+// redactedSecret is what config.SecretString marshals non-empty values to
+var redactedSecret = config.SecretString("x").String()
+
+// SettingsStore persists Settings as JSON on disk. Secrets are written in
+// the clear (the file is 0600) but stay redacted in API responses.
+type SettingsStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewSettingsStore(path string) *SettingsStore {
+	return &SettingsStore{path: path}
+}
+
+// Path returns the location of the settings file
+func (s *SettingsStore) Path() string {
+	return s.path
+}
+
+// diskLLMConfig and diskSettings mirror the API models with secrets
+// revealed, since SecretString always marshals redacted.
+type diskLLMConfig struct {
+	APIKey         *string `json:"api_key,omitempty"`
+	Model          string  `json:"model"`
+	ThinkingTokens int     `json:"thinking_tokens,omitempty"`
+}
+
+type diskSearchConfig struct {
+	APIKey *string `json:"api_key,omitempty"`
+}
+
+type diskSandboxConfig struct {
+	Mode          config.WorkSpaceMode `json:"mode"`
+	TemplateID    *string              `json:"template_id,omitempty"`
+	SandboxAPIKey *string              `json:"sandbox_api_key,omitempty"`
+	ServicePort   int                  `json:"service_port"`
+}
+
+type diskSettings struct {
+	LLMConfigs    map[string]diskLLMConfig `json:"llm_configs"`
+	SearchConfig  *diskSearchConfig        `json:"search_config,omitempty"`
+	SandboxConfig *diskSandboxConfig       `json:"sandbox_config,omitempty"`
+}
+
+func revealSecret(secret *config.SecretString) *string {
+	if secret == nil {
+		return nil
+	}
+	v := secret.Reveal()
+	return &v
+}
+
+// Load reads the settings file. A missing file yields empty settings.
+func (s *SettingsStore) Load() (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *SettingsStore) load() (Settings, error) {
+	settings := Settings{LLMConfigs: map[string]LLMConfig{}}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to read settings: %w", err)
+	}
+	// SecretString has no custom unmarshaler, so real values load as-is.
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if settings.LLMConfigs == nil {
+		settings.LLMConfigs = map[string]LLMConfig{}
+	}
+	return settings, nil
+}
+
+// Save replaces the settings on disk
+func (s *SettingsStore) Save(settings Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(settings)
+}
+
+func (s *SettingsStore) save(settings Settings) error {
+	out := diskSettings{LLMConfigs: make(map[string]diskLLMConfig, len(settings.LLMConfigs))}
+	for name, cfg := range settings.LLMConfigs {
+		out.LLMConfigs[name] = diskLLMConfig{
+			APIKey:         revealSecret(cfg.APIKey),
+			Model:          cfg.Model,
+			ThinkingTokens: cfg.ThinkingTokens,
+		}
+	}
+	if settings.SearchConfig != nil {
+		out.SearchConfig = &diskSearchConfig{APIKey: revealSecret(settings.SearchConfig.APIKey)}
+	}
+	if sb := settings.SandboxConfig; sb != nil {
+		out.SandboxConfig = &diskSandboxConfig{
+			Mode:          sb.Mode,
+			TemplateID:    sb.TemplateID,
+			SandboxAPIKey: revealSecret(sb.SandboxAPIKey),
+			ServicePort:   sb.ServicePort,
+		}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial file.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return nil
+}
+
+// Update stores incoming settings. Secrets that are missing or still
+// redacted (as returned by GET) keep their stored value, so a client can
+// post back what it read without wiping keys.
+func (s *SettingsStore) Update(incoming Settings) (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.load()
+	if err != nil {
+		return current, err
+	}
+
+	merged := Settings{LLMConfigs: make(map[string]LLMConfig, len(incoming.LLMConfigs))}
+	for name, cfg := range incoming.LLMConfigs {
+		if isUnsetSecret(cfg.APIKey) {
+			cfg.APIKey = current.LLMConfigs[name].APIKey
+		}
+		merged.LLMConfigs[name] = cfg
+	}
+	if incoming.SearchConfig != nil {
+		search := *incoming.SearchConfig
+		if isUnsetSecret(search.APIKey) && current.SearchConfig != nil {
+			search.APIKey = current.SearchConfig.APIKey
+		}
+		merged.SearchConfig = &search
+	} else {
+		merged.SearchConfig = current.SearchConfig
+	}
+	if incoming.SandboxConfig != nil {
+		sandbox := *incoming.SandboxConfig
+		if isUnsetSecret(sandbox.SandboxAPIKey) && current.SandboxConfig != nil {
+			sandbox.SandboxAPIKey = current.SandboxConfig.SandboxAPIKey
+		}
+		merged.SandboxConfig = &sandbox
+	} else {
+		merged.SandboxConfig = current.SandboxConfig
+	}
+
+	return merged, s.save(merged)
+}
+
+func isUnsetSecret(secret *config.SecretString) bool {
+	return secret == nil || *secret == config.SecretString(redactedSecret)
+}
+
+func hasSecret(secret *config.SecretString) bool {
+	return secret != nil && secret.Reveal() != ""
+}
+
+// settingsResponse builds the GET model, flagging which keys are present
+func settingsResponse(settings Settings) GETSettingsModel {
+	resp := GETSettingsModel{Settings: settings}
+	for _, cfg := range settings.LLMConfigs {
+		if hasSecret(cfg.APIKey) {
+			resp.LLMAPIKeySet = true
+		}
+	}
+	resp.SearchAPIKeySet = settings.SearchConfig != nil && hasSecret(settings.SearchConfig.APIKey)
+	resp.SandboxAPIKeySet = settings.SandboxConfig != nil && hasSecret(settings.SandboxConfig.SandboxAPIKey)
+	return resp
+}
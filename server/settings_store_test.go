@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/core/config"
+)
+
+func settingsRouter(srv *Server) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/settings", srv.GetSettingsHandler)
+	router.POST("/api/settings", srv.PostSettingsHandler)
+	return router
+}
+
+func doSettingsRequest(t *testing.T, router *gin.Engine, method, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/api/settings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s /api/settings = %d: %s", method, w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestSettingsHandlersRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	router := settingsRouter(&Server{Config: Config{WorkspaceRoot: root}})
+
+	// Nothing stored yet.
+	var empty GETSettingsModel
+	json.Unmarshal(doSettingsRequest(t, router, http.MethodGet, "").Body.Bytes(), &empty)
+	if empty.LLMAPIKeySet || empty.SearchAPIKeySet {
+		t.Errorf("fresh settings report keys set: %+v", empty)
+	}
+
+	doSettingsRequest(t, router, http.MethodPost,
+		`{"llm_configs": {"claude": {"model": "claude-sonnet-4", "api_key": "sk-real-secret"}}}`)
+
+	w := doSettingsRequest(t, router, http.MethodGet, "")
+	if strings.Contains(w.Body.String(), "sk-real-secret") {
+		t.Fatalf("GET response leaks the secret: %s", w.Body.String())
+	}
+	var got struct {
+		LLMConfigs map[string]struct {
+			APIKey string `json:"api_key"`
+			Model  string `json:"model"`
+		} `json:"llm_configs"`
+		LLMAPIKeySet    bool `json:"llm_api_key_set"`
+		SearchAPIKeySet bool `json:"search_api_key_set"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.LLMConfigs["claude"].Model != "claude-sonnet-4" || got.LLMConfigs["claude"].APIKey != "********" {
+		t.Errorf("llm_configs = %+v; want redacted claude config", got.LLMConfigs)
+	}
+	if !got.LLMAPIKeySet || got.SearchAPIKeySet {
+		t.Errorf("key flags = %v/%v; want true/false", got.LLMAPIKeySet, got.SearchAPIKeySet)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, SettingsFileName))
+	if err != nil {
+		t.Fatalf("settings file not written: %v", err)
+	}
+	if !bytes.Contains(data, []byte("sk-real-secret")) {
+		t.Errorf("settings file should hold the real key: %s", data)
+	}
+
+	// Posting back the redacted GET payload must not wipe the stored key.
+	doSettingsRequest(t, router, http.MethodPost, w.Body.String())
+	loaded, err := NewSettingsStore(filepath.Join(root, SettingsFileName)).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if key := loaded.LLMConfigs["claude"].APIKey; key == nil || key.Reveal() != "sk-real-secret" {
+		t.Errorf("stored key = %v; want sk-real-secret preserved", key)
+	}
+
+	// Settings survive a restart (a fresh server on the same root).
+	restarted := settingsRouter(&Server{Config: Config{WorkspaceRoot: root}})
+	if !strings.Contains(doSettingsRequest(t, restarted, http.MethodGet, "").Body.String(), "claude-sonnet-4") {
+		t.Error("settings should be loaded from disk after restart")
+	}
+}
+
+func TestSettingsStoreConcurrentUpdates(t *testing.T) {
+	store := NewSettingsStore(filepath.Join(t.TempDir(), SettingsFileName))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := config.SecretString(fmt.Sprintf("key-%d", i))
+			_, err := store.Update(Settings{
+				LLMConfigs:   map[string]LLMConfig{"m": {Model: "m", APIKey: &key}},
+				SearchConfig: &SearchConfig{APIKey: &key},
+			})
+			if err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() after concurrent writes error = %v", err)
+	}
+	if !strings.HasPrefix(loaded.LLMConfigs["m"].APIKey.Reveal(), "key-") {
+		t.Errorf("unexpected stored key %q", loaded.LLMConfigs["m"].APIKey.Reveal())
+	}
+}
+
+func TestWorkspaceDoesNotServeSettingsFile(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, SettingsFileName), []byte(`{"secret":"x"}`), 0600)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/workspace/*filepath", workspaceFileHandler(root))
+
+	// Other names for the same file, as another case is on a
+	// case-insensitive filesystem
+	os.MkdirAll(filepath.Join(root, "session"), 0755)
+	os.Symlink(filepath.Join(root, SettingsFileName), filepath.Join(root, "session", "link.json"))
+	os.Link(filepath.Join(root, SettingsFileName), filepath.Join(root, "alias.json"))
+
+	for _, path := range []string{SettingsFileName, "session/link.json", "alias.json", "session/../" + SettingsFileName} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workspace/"+path, nil))
+		if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("GET /workspace/%s status = %d; want 404 for the settings file", path, w.Code)
+		}
+	}
+}
+
+func TestSettingsSandboxConfigRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	router := settingsRouter(&Server{Config: Config{WorkspaceRoot: root}})
+
+	doSettingsRequest(t, router, http.MethodPost,
+		`{"llm_configs": {}, "sandbox_config": {"mode": "e2b", "template_id": "water-base", "sandbox_api_key": "e2b-secret"}}`)
+
+	w := doSettingsRequest(t, router, http.MethodGet, "")
+	if strings.Contains(w.Body.String(), "e2b-secret") {
+		t.Fatalf("GET response leaks the sandbox key: %s", w.Body.String())
+	}
+	var got GETSettingsModel
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid settings JSON: %v", err)
+	}
+	sb := got.SandboxConfig
+	if sb == nil || sb.Mode != config.WorkSpaceModeE2B || sb.TemplateID == nil || *sb.TemplateID != "water-base" {
+		t.Errorf("sandbox_config = %+v; want e2b with template water-base", sb)
+	}
+	if !got.SandboxAPIKeySet {
+		t.Error("sandbox_api_key_set = false; want true")
+	}
+
+	// Posting back the redacted payload keeps the stored key.
+	doSettingsRequest(t, router, http.MethodPost, w.Body.String())
+	loaded, err := NewSettingsStore(filepath.Join(root, SettingsFileName)).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if key := loaded.SandboxConfig.SandboxAPIKey; key == nil || key.Reveal() != "e2b-secret" {
+		t.Errorf("stored sandbox key = %v; want e2b-secret preserved", key)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventFilter is the set of event types a connection receives; nil means
+// every type.
+type eventFilter map[string]bool
+
+func (f eventFilter) allows(eventType string) bool {
+	return f == nil || f[eventType]
+}
+
+// handleSubscribe applies a subscribe message from conn and reports whether
+// data was one. Subscriptions are handled inline, before any later message
+// from the same connection, so no filtered-out event slips through.
+func (s *ChatSession) handleSubscribe(conn EventConn, data []byte) bool {
+	var msg WebSocketMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "subscribe" {
+		return false
+	}
+	var content SubscribeContent
+	if err := json.Unmarshal(msg.Content, &content); err != nil {
+		s.sendTo(conn, EventTypeError, gin.H{"message": "Invalid subscribe message"})
+		return true
+	}
+	s.subscribe(conn, content.EventTypes)
+	return true
+}
+
+// subscribe limits the events SendEvent writes to conn to eventTypes, or
+// restores all events when eventTypes is empty. The confirmation is always
+// delivered.
+func (s *ChatSession) subscribe(conn EventConn, eventTypes []string) {
+	var filter eventFilter
+	if len(eventTypes) > 0 {
+		filter = make(eventFilter, len(eventTypes))
+		for _, t := range eventTypes {
+			filter[t] = true
+		}
+	}
+
+	s.mu.Lock()
+	_, attached := s.conns[conn]
+	if attached {
+		s.conns[conn] = filter
+	}
+	s.mu.Unlock()
+	if !attached {
+		return
+	}
+
+	subscribed := []string{}
+	for t := range filter {
+		subscribed = append(subscribed, t)
+	}
+	sort.Strings(subscribed)
+	s.sendTo(conn, EventTypeSubscribed, gin.H{"event_types": subscribed})
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubscribeFiltersEventsPerConnection(t *testing.T) {
+	viewer, full := &recordingConn{}, &recordingConn{}
+	session := newTestSession(t, full, nil)
+	session.Manager.Connect(viewer, session.SessionUUID.String())
+
+	if !session.handleSubscribe(viewer, []byte(`{"type": "subscribe", "content": {"event_types": ["agent_response"]}}`)) {
+		t.Fatal("handleSubscribe() = false; want the subscribe message handled")
+	}
+	session.SendEvent(EventTypeProcessing, gin.H{})
+	session.SendEvent(EventTypeToolCall, gin.H{})
+	session.SendEvent(EventTypeAgentResponse, gin.H{"text": "done"})
+
+	if got, want := viewer.types(), []string{EventTypeSubscribed, EventTypeAgentResponse}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subscribed connection got %v; want %v", got, want)
+	}
+	if got, want := full.types(), []string{EventTypeProcessing, EventTypeToolCall, EventTypeAgentResponse}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unsubscribed connection got %v; want %v", got, want)
+	}
+
+	// An empty list restores every event.
+	session.handleSubscribe(viewer, []byte(`{"type": "subscribe", "content": {"event_types": []}}`))
+	session.SendEvent(EventTypeToolCall, gin.H{})
+	if got := viewer.types(); got[len(got)-1] != EventTypeToolCall {
+		t.Errorf("after resubscribing, last event = %q; want %q", got[len(got)-1], EventTypeToolCall)
+	}
+}
+
+func TestHandleSubscribeIgnoresOtherMessages(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+	for _, data := range []string{`{"type": "ping"}`, `not json`} {
+		if session.handleSubscribe(conn, []byte(data)) {
+			t.Errorf("handleSubscribe(%s) = true; want false", data)
+		}
+	}
+}
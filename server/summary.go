@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"water-ai/core"
+	"water-ai/db"
+	"water-ai/llm"
+	"water-ai/tools"
+)
+
+const maxSummaryFieldLen = 280
+
+// UsageTotals accumulates token usage across LLM calls in a session
+type UsageTotals struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	Calls        int `json:"calls"`
+}
+
+// Add records the usage of one LLM response
+func (u *UsageTotals) Add(usage llm.UsageMetadata) {
+	u.InputTokens += usage.InputTokens
+	u.OutputTokens += usage.OutputTokens
+	u.Calls++
+}
+
+// SessionSummaryCard is a compact preview of a finished task
+type SessionSummaryCard struct {
+	SessionID       string      `json:"session_id"`
+	Model           string      `json:"model,omitempty"`
+	Asked           string      `json:"asked"`
+	Done            string      `json:"done"`
+	Deliverables    []string    `json:"deliverables"`
+	Usage           UsageTotals `json:"usage"`
+	CostUSD         float64     `json:"cost_usd"`
+	DurationSeconds float64     `json:"duration_seconds"`
+	Summary         string      `json:"summary,omitempty"` // optional LLM-written one-liner
+	CompletedAt     string      `json:"completed_at"`
+}
+
+// modelPrices holds USD prices per million input/output tokens for known
+// model families, matched by substring of the model name.
+var modelPrices = []struct {
+	match         string
+	input, output float64
+}{
+	{"claude-3-5-haiku", 0.8, 4},
+	{"claude", 3, 15},
+	{"gpt-4o-mini", 0.15, 0.6},
+	{"gpt-4o", 2.5, 10},
+	{"gpt-4", 10, 30},
+	{"gemini", 1.25, 5},
+}
+
+// estimateCost returns the approximate USD cost of usage on model, or 0
+// when the model's pricing is unknown.
+func estimateCost(model string, usage UsageTotals) float64 {
+	model = strings.ToLower(model)
+	for _, p := range modelPrices {
+		if strings.Contains(model, p.match) {
+			return (float64(usage.InputTokens)*p.input + float64(usage.OutputTokens)*p.output) / 1e6
+		}
+	}
+	return 0
+}
+
+// snapshotWorkspace records the modification time of every workspace
+// file. Uploads and offloaded tool outputs are inputs rather than
+// deliverables, so they are skipped.
+func snapshotWorkspace(workspace string) map[string]time.Time {
+	files := make(map[string]time.Time)
+	filepath.WalkDir(workspace, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, relErr := filepath.Rel(workspace, path)
+		if relErr != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if rel == "uploads" || rel == tools.ToolOutputDir || rel == filepath.Dir(filepath.FromSlash(HistoryFileName)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[filepath.ToSlash(rel)] = info.ModTime()
+		}
+		return nil
+	})
+	return files
+}
+
+// collectDeliverables lists the workspace files created or modified since
+// the before snapshot was taken.
+func collectDeliverables(workspace string, before map[string]time.Time) []string {
+	files := []string{}
+	for path, modTime := range snapshotWorkspace(workspace) {
+		if prev, ok := before[path]; !ok || !modTime.Equal(prev) {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// clip trims s to at most n bytes plus an ellipsis, cutting on a rune
+// boundary so the result stays valid UTF-8.
+func clip(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
+
+// buildSummaryCard aggregates a finished task into a SessionSummaryCard.
+// The LLM one-line summary is only requested when SummarizeWithLLM is set.
+func (s *ChatSession) buildSummaryCard(asked, done string, started time.Time, before map[string]time.Time) SessionSummaryCard {
+	usage := s.usageTotals()
+	card := SessionSummaryCard{
+		SessionID:       s.SessionUUID.String(),
+		Model:           s.ModelName,
+		Asked:           clip(asked, maxSummaryFieldLen),
+		Done:            clip(done, maxSummaryFieldLen),
+		Deliverables:    collectDeliverables(s.Workspace, before),
+		Usage:           usage,
+		CostUSD:         estimateCost(s.ModelName, usage),
+		DurationSeconds: time.Since(started).Seconds(),
+		CompletedAt:     time.Now().Format(time.RFC3339),
+	}
+
+	if s.SummarizeWithLLM && s.LLMClient != nil {
+		prompt := fmt.Sprintf("In one short sentence, summarize what was accomplished.\n\nRequest: %s\n\nResult: %s\n\nFiles produced: %s",
+			card.Asked, card.Done, strings.Join(card.Deliverables, ", "))
+		history := llm.NewMessageHistory()
+		history.AddUserPrompt(prompt, nil)
+		callStarted := time.Now()
+		resp, err := s.LLMClient.Generate(history.GetMessages(), 100, "", 0.0, nil, nil, nil, nil)
+		observeLLMCall(callStarted)
+		if err != nil {
+			core.Logger.Error("session summary generation failed", "error", err)
+		} else {
+			s.recordUsage(resp.Usage)
+			for _, block := range resp.Content {
+				if block.Type == llm.ContentTypeText {
+					card.Summary += block.Text
+				}
+			}
+			card.Summary = clip(card.Summary, maxSummaryFieldLen)
+		}
+	}
+	return card
+}
+
+// emitSessionSummary builds the card for a task that began at started with
+// the workspace in the before state, sends it to the client and stores it
+// as a session event when the database is available.
+func (s *ChatSession) emitSessionSummary(asked, done string, started time.Time, before map[string]time.Time) {
+	card := s.buildSummaryCard(asked, done, started, before)
+	s.LastSummary = &card
+	s.SendEvent(EventTypeSessionSummary, card)
+
+	if db.DB != nil {
+		if _, err := db.Events.SaveEvent(s.SessionUUID, EventTypeSessionSummary, RealtimeEvent{
+			Type:    EventTypeSessionSummary,
+			Content: card,
+		}); err != nil {
+			core.Logger.Error("failed to store session summary", "error", err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"water-ai/db"
+	"water-ai/llm"
+)
+
+// fileWritingClient writes a file into the workspace before replying,
+// standing in for an agent that produced a deliverable.
+type fileWritingClient struct {
+	*llm.MockClient
+	path string
+}
+
+func (c *fileWritingClient) Generate(messages []*llm.Message, maxTokens int, systemPrompt string, temperature float64, tools []*llm.ToolParam, toolChoice *llm.ToolChoice, thinkingTokens *int, sampling *llm.Sampling) (*llm.GenerateResponse, error) {
+	if c.path != "" {
+		os.WriteFile(c.path, []byte("report"), 0644)
+		c.path = ""
+	}
+	return c.MockClient.Generate(messages, maxTokens, systemPrompt, temperature, tools, toolChoice, thinkingTokens, sampling)
+}
+
+func newSummarySession(t *testing.T, client llm.Client, model string) *ChatSession {
+	t.Helper()
+	workspace := t.TempDir()
+	return &ChatSession{
+		SessionUUID: uuid.New(),
+		Workspace:   workspace,
+		LLMClient:   client,
+		History:     llm.NewMessageHistory(),
+		ModelName:   model,
+	}
+}
+
+func TestSessionSummaryCardAfterMockRun(t *testing.T) {
+	setupReplayDB(t)
+
+	mock := llm.NewMockClient(
+		&llm.GenerateResponse{
+			Content: []*llm.ContentBlock{llm.TextBlock("Wrote report.md with the findings.")},
+			Usage:   llm.UsageMetadata{InputTokens: 1000, OutputTokens: 200},
+		},
+		&llm.GenerateResponse{
+			Content: []*llm.ContentBlock{llm.TextBlock("Researched the topic and wrote a report.")},
+		},
+	)
+	client := &fileWritingClient{MockClient: mock}
+	s := newSummarySession(t, client, "claude-sonnet-4")
+	s.SummarizeWithLLM = true
+	client.path = filepath.Join(s.Workspace, "report.md")
+
+	os.MkdirAll(filepath.Join(s.Workspace, "uploads"), 0755)
+	os.WriteFile(filepath.Join(s.Workspace, "uploads", "input.txt"), []byte("attachment"), 0644)
+	if _, _, err := db.Sessions.CreateSession(s.SessionUUID, s.Workspace, nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	s.handleQuery(QueryContent{Text: "Research the topic and write a report"})
+
+	card := s.LastSummary
+	if card == nil {
+		t.Fatal("no session summary was produced")
+	}
+	if card.Asked != "Research the topic and write a report" {
+		t.Errorf("Asked = %q", card.Asked)
+	}
+	if card.Done != "Wrote report.md with the findings." {
+		t.Errorf("Done = %q", card.Done)
+	}
+	if len(card.Deliverables) != 1 || card.Deliverables[0] != "report.md" {
+		t.Errorf("Deliverables = %v; want [report.md]", card.Deliverables)
+	}
+	if card.Usage.InputTokens != 1000 || card.Usage.OutputTokens != 200 || card.Usage.Calls != 1 {
+		t.Errorf("Usage = %+v; want 1000 in / 200 out / 1 call", card.Usage)
+	}
+	if want := (1000*3.0 + 200*15.0) / 1e6; math.Abs(card.CostUSD-want) > 1e-9 {
+		t.Errorf("CostUSD = %v; want %v", card.CostUSD, want)
+	}
+	if card.DurationSeconds < 0 {
+		t.Errorf("DurationSeconds = %v; want >= 0", card.DurationSeconds)
+	}
+	if card.Summary != "Researched the topic and wrote a report." {
+		t.Errorf("Summary = %q", card.Summary)
+	}
+
+	events, err := db.Events.GetSessionEvents(s.SessionUUID)
+	if err != nil {
+		t.Fatalf("GetSessionEvents() error = %v", err)
+	}
+	var stored *SessionSummaryCard
+	for _, evt := range events {
+		if evt.EventType == EventTypeSessionSummary {
+			var wrapped struct {
+				Content SessionSummaryCard `json:"content"`
+			}
+			if err := json.Unmarshal(evt.EventPayload, &wrapped); err != nil {
+				t.Fatalf("stored summary is not valid JSON: %v", err)
+			}
+			stored = &wrapped.Content
+		}
+	}
+	if stored == nil || stored.Asked != card.Asked || len(stored.Deliverables) != 1 {
+		t.Errorf("stored summary = %+v; want %+v", stored, card)
+	}
+}
+
+func TestSessionSummaryWithoutLLM(t *testing.T) {
+	prevDB := db.DB
+	db.DB = nil
+	defer func() { db.DB = prevDB }()
+
+	mock := llm.NewMockClient().EnqueueBlocks(llm.TextBlock("done"))
+	s := newSummarySession(t, mock, "unknown-model")
+
+	s.handleQuery(QueryContent{Text: "hi"})
+
+	if s.LastSummary == nil {
+		t.Fatal("no session summary was produced")
+	}
+	if s.LastSummary.Summary != "" {
+		t.Errorf("Summary = %q; want empty when LLM summaries are disabled", s.LastSummary.Summary)
+	}
+	if len(mock.Calls()) != 1 {
+		t.Errorf("LLM calls = %d; want 1 (no summary call)", len(mock.Calls()))
+	}
+	if s.LastSummary.CostUSD != 0 {
+		t.Errorf("CostUSD = %v; want 0 for unknown model pricing", s.LastSummary.CostUSD)
+	}
+}
+
+func TestClipCutsOnRuneBoundary(t *testing.T) {
+	for n := 0; n < 12; n++ {
+		got := clip("日本語のテキスト", n)
+		if !utf8.ValidString(got) {
+			t.Errorf("clip(_, %d) = %q; want valid UTF-8", n, got)
+		}
+		if len(got) > n+len("...") {
+			t.Errorf("clip(_, %d) = %q; want at most %d bytes before the ellipsis", n, got, n)
+		}
+	}
+	if got := clip("  short  ", 10); got != "short" {
+		t.Errorf("clip() = %q; want the trimmed text unchanged", got)
+	}
+}
+
+func TestCollectDeliverablesSkipsUnchangedAndInternalFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "edited.txt"), []byte("v1"), 0644)
+	before := snapshotWorkspace(dir)
+
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "edited.txt"), later, later)
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".tool_outputs"), 0755)
+	os.WriteFile(filepath.Join(dir, ".tool_outputs", "bash_1.txt"), []byte("log"), 0644)
+
+	got := collectDeliverables(dir, before)
+	want := []string{"edited.txt", "src/main.go"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("collectDeliverables() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"water-ai/browser"
+)
+
+type fakeTabs struct {
+	tabs   []browser.TabInfo
+	active int
+}
+
+func (f *fakeTabs) GetTabsInfo() ([]browser.TabInfo, error) { return f.tabs, nil }
+
+func (f *fakeTabs) SwitchToTab(pageID int) error {
+	if pageID < 0 || pageID >= len(f.tabs) {
+		return fmt.Errorf("no tab found with page_id: %d", pageID)
+	}
+	f.active = pageID
+	return nil
+}
+
+func TestSwitchTabMessage(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, nil)
+	tabs := &fakeTabs{tabs: []browser.TabInfo{
+		{PageID: 0, URL: "https://example.com", Title: "Example"},
+		{PageID: 1, URL: "https://go.dev", Title: "Go"},
+	}}
+	session.Browser = tabs
+
+	session.HandleMessage([]byte(`{"type": "switch_tab", "content": {"page_id": 1}}`))
+
+	if tabs.active != 1 {
+		t.Errorf("active tab = %d; want 1", tabs.active)
+	}
+	event := awaitEvent(t, conn, EventTypeBrowserTabs, func(gin.H) bool { return true })
+	if event["active"] != 1 || event["tab"] != tabs.tabs[1] {
+		t.Errorf("browser_tabs event = %v; want tab 1 active", event)
+	}
+
+	session.HandleMessage([]byte(`{"type": "switch_tab", "content": {"page_id": 5}}`))
+	if msg := lastMessage(conn, EventTypeError); msg == "" {
+		t.Error("no error event for an unknown tab")
+	}
+
+	session.Browser = nil
+	session.HandleMessage([]byte(`{"type": "switch_tab", "content": {"page_id": 0}}`))
+	if msg := lastMessage(conn, EventTypeError); msg != "No browser is open" {
+		t.Errorf("error = %q; want No browser is open", msg)
+	}
+}
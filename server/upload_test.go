@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func uploadBody(sessionID, name string, content []byte) string {
+	body, _ := json.Marshal(UploadRequest{
+		SessionID: sessionID,
+		File:      FileInfo{Path: name, Content: "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(content)},
+	})
+	return string(body)
+}
+
+func TestUploadHandlerSizeLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	w := sendSessionRequest(t, srv, http.MethodPost, "/api/upload", uploadBody("s1", "notes.txt", []byte("hello")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload status = %d; body %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		File struct {
+			Path string `json:"path"`
+		} `json:"file"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.File.Path != "/uploads/notes.txt" {
+		t.Errorf("path = %q; want /uploads/notes.txt", resp.File.Path)
+	}
+
+	w = sendSessionRequest(t, srv, http.MethodPost, "/api/upload", uploadBody("s1", "big.bin", make([]byte, MaxUploadSize+1)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload status = %d; want 413", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "upload limit") {
+		t.Errorf("oversized upload body = %s", w.Body.String())
+	}
+}
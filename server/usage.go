@@ -0,0 +1,208 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/core"
+	"water-ai/db"
+	"water-ai/llm"
+	"water-ai/metrics"
+)
+
+// ModelUsage is the usage and estimated cost of one model in a session.
+type ModelUsage struct {
+	UsageTotals
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// UsageReport summarizes what a session has spent so far.
+type UsageReport struct {
+	SessionID string                `json:"session_id"`
+	Totals    UsageTotals           `json:"totals"`
+	CostUSD   float64               `json:"cost_usd"`
+	Models    map[string]ModelUsage `json:"models"`
+}
+
+// UsageTracker accumulates token usage and estimated cost per model for a
+// session. It is safe for concurrent use.
+type UsageTracker struct {
+	sessionID uuid.UUID
+	mu        sync.Mutex
+	models    map[string]*ModelUsage
+	dirty     bool
+}
+
+// NewUsageTracker creates an empty tracker for sessionID.
+func NewUsageTracker(sessionID uuid.UUID) *UsageTracker {
+	return &UsageTracker{sessionID: sessionID, models: make(map[string]*ModelUsage)}
+}
+
+// LoadUsageTracker creates a tracker for sessionID seeded with the last
+// usage event persisted for it, so totals survive reconnects.
+func LoadUsageTracker(sessionID uuid.UUID) *UsageTracker {
+	t := NewUsageTracker(sessionID)
+	report, err := latestUsageReport(sessionID)
+	if err != nil {
+		core.Logger.Error("failed to load usage", "session_id", sessionID.String(), "error", err)
+	}
+	if report != nil {
+		for model, usage := range report.Models {
+			usage := usage
+			t.models[model] = &usage
+		}
+	}
+	return t
+}
+
+// Record adds the usage of one Generate call made with model.
+func (t *UsageTracker) Record(model string, usage llm.UsageMetadata) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m, ok := t.models[model]
+	if !ok {
+		m = &ModelUsage{}
+		t.models[model] = m
+	}
+	m.Add(usage)
+	m.CostUSD = estimateCost(model, m.UsageTotals)
+	t.dirty = true
+}
+
+// Report returns the totals and per-model breakdown recorded so far.
+func (t *UsageTracker) Report() UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := UsageReport{SessionID: t.sessionID.String(), Models: make(map[string]ModelUsage, len(t.models))}
+	for model, m := range t.models {
+		report.Models[model] = *m
+		report.Totals.InputTokens += m.InputTokens
+		report.Totals.OutputTokens += m.OutputTokens
+		report.Totals.Calls += m.Calls
+		report.CostUSD += m.CostUSD
+	}
+	return report
+}
+
+// Persist stores the current report as a usage event when anything was
+// recorded since the last call. It is a no-op without a database.
+func (t *UsageTracker) Persist() error {
+	if db.DB == nil {
+		return nil
+	}
+	t.mu.Lock()
+	dirty := t.dirty
+	t.dirty = false
+	t.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	report := t.Report()
+	_, err := db.Events.SaveEvent(t.sessionID, EventTypeUsage, RealtimeEvent{Type: EventTypeUsage, Content: report})
+	if err != nil {
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// latestUsageReport decodes the last usage event of a session, or returns
+// nil when there is none or no database.
+func latestUsageReport(sessionID uuid.UUID) (*UsageReport, error) {
+	if db.DB == nil {
+		return nil, nil
+	}
+	evt, err := db.Events.GetLatestEvent(sessionID, EventTypeUsage)
+	if err != nil || evt == nil {
+		return nil, err
+	}
+	var payload struct {
+		Content UsageReport `json:"content"`
+	}
+	if err := json.Unmarshal(evt.EventPayload, &payload); err != nil {
+		return nil, err
+	}
+	return &payload.Content, nil
+}
+
+// recordUsage adds the usage of one Generate call to the session totals
+// and its tracker. Queries run concurrently, so the totals are updated
+// under the session lock.
+func (s *ChatSession) recordUsage(usage llm.UsageMetadata) {
+	metrics.RecordTokens(metricsComponentChat, usage.InputTokens, usage.OutputTokens)
+	s.mu.Lock()
+	s.Usage.Add(usage)
+	s.mu.Unlock()
+	if s.Tracker != nil {
+		s.Tracker.Record(s.ModelName, usage)
+		// Keep clients' running totals current
+		s.SendEvent(EventTypeUsage, s.Tracker.Report())
+	}
+}
+
+// usageTotals returns the session totals so far.
+func (s *ChatSession) usageTotals() UsageTotals {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Usage
+}
+
+// persistUsage stores the session's usage so far, logging failures.
+func (s *ChatSession) persistUsage() {
+	if s.Tracker == nil {
+		return
+	}
+	if err := s.Tracker.Persist(); err != nil {
+		core.Logger.Error("failed to store usage", "error", err)
+	}
+}
+
+// UsageHandler handles GET /api/sessions/:id/usage. A connected session
+// reports its live totals; otherwise the last persisted report is used.
+func (s *Server) UsageHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	if s.WSManager != nil {
+		if session := s.WSManager.Session(sessionID); session != nil && session.Tracker != nil {
+			c.JSON(http.StatusOK, session.Tracker.Report())
+			return
+		}
+	}
+
+	if db.DB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+	sess, err := db.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sess == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errSessionNotFound.Error()})
+		return
+	}
+
+	report, err := latestUsageReport(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if report == nil {
+		empty := NewUsageTracker(sessionID).Report()
+		report = &empty
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"water-ai/db"
+	"water-ai/llm"
+)
+
+func TestUsageTrackerSumsAcrossModels(t *testing.T) {
+	tracker := NewUsageTracker(uuid.New())
+	tracker.Record("claude-3-5-sonnet", llm.UsageMetadata{InputTokens: 1000, OutputTokens: 200})
+	tracker.Record("claude-3-5-sonnet", llm.UsageMetadata{InputTokens: 3000, OutputTokens: 800})
+	tracker.Record("gpt-4o-mini", llm.UsageMetadata{InputTokens: 2000, OutputTokens: 1000})
+	tracker.Record("local-model", llm.UsageMetadata{InputTokens: 10, OutputTokens: 5})
+
+	report := tracker.Report()
+	want := UsageTotals{InputTokens: 6010, OutputTokens: 2005, Calls: 4}
+	if report.Totals != want {
+		t.Errorf("Totals = %+v; want %+v", report.Totals, want)
+	}
+
+	claude := report.Models["claude-3-5-sonnet"]
+	if claude.Calls != 2 || claude.InputTokens != 4000 || claude.OutputTokens != 1000 {
+		t.Errorf("claude usage = %+v", claude)
+	}
+	// 4000*3/1e6 + 1000*15/1e6 and 2000*0.15/1e6 + 1000*0.6/1e6
+	if math.Abs(claude.CostUSD-0.027) > 1e-9 {
+		t.Errorf("claude cost = %v; want 0.027", claude.CostUSD)
+	}
+	if math.Abs(report.CostUSD-0.0279) > 1e-9 {
+		t.Errorf("CostUSD = %v; want 0.0279", report.CostUSD)
+	}
+	if report.Models["local-model"].CostUSD != 0 {
+		t.Errorf("unknown model cost = %v; want 0", report.Models["local-model"].CostUSD)
+	}
+}
+
+func TestRecordUsageFromConcurrentQueries(t *testing.T) {
+	session := newTestSession(t, &recordingConn{}, llm.NewMockClient())
+	session.ModelName = "claude-3-5-sonnet"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			session.recordUsage(llm.UsageMetadata{InputTokens: 10, OutputTokens: 2})
+		}()
+		go func() {
+			defer wg.Done()
+			session.buildSummaryCard("asked", "done", time.Now(), nil)
+		}()
+	}
+	wg.Wait()
+
+	want := UsageTotals{InputTokens: 500, OutputTokens: 100, Calls: 50}
+	if got := session.usageTotals(); got != want {
+		t.Errorf("session totals = %+v; want %+v", got, want)
+	}
+	if got := session.Tracker.Report().Totals; got != want {
+		t.Errorf("tracker totals = %+v; want %+v", got, want)
+	}
+}
+
+func getUsage(t *testing.T, srv *Server, id uuid.UUID) UsageReport {
+	t.Helper()
+	w := sendSessionRequest(t, srv, http.MethodGet, "/api/sessions/"+id.String()+"/usage", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET usage status = %d; body %s", w.Code, w.Body.String())
+	}
+	var report UsageReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid usage JSON: %v", err)
+	}
+	return report
+}
+
+func TestSessionUsageEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	root := t.TempDir()
+	srv := CreateServer(Config{WorkspaceRoot: root})
+
+	id := uuid.New()
+	conn := &recordingConn{}
+	session := srv.WSManager.Connect(conn, id.String())
+	if _, _, err := db.Sessions.CreateSession(id, session.Workspace, nil, nil); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	mock := llm.NewMockClient()
+	for i := 1; i <= 3; i++ {
+		mock.Enqueue(&llm.GenerateResponse{
+			Content: []*llm.ContentBlock{llm.TextBlock("ok")},
+			Usage:   llm.UsageMetadata{InputTokens: 100 * i, OutputTokens: 10 * i},
+		})
+	}
+	session.LLMClient = mock
+	session.ModelName = "gpt-4o"
+	session.History = llm.NewMessageHistory()
+
+	for i := 0; i < 3; i++ {
+		session.handleQuery(QueryContent{Text: "next step"})
+	}
+
+	want := UsageTotals{InputTokens: 600, OutputTokens: 60, Calls: 3}
+	live := getUsage(t, srv, id)
+	if live.Totals != want {
+		t.Errorf("live Totals = %+v; want %+v", live.Totals, want)
+	}
+	if live.Models["gpt-4o"].Calls != 3 {
+		t.Errorf("per-model breakdown = %+v; want 3 gpt-4o calls", live.Models)
+	}
+
+	// Once the session disconnects, the persisted report is served.
+	srv.WSManager.Disconnect(conn)
+	stored := getUsage(t, srv, id)
+	if stored.Totals != want || math.Abs(stored.CostUSD-live.CostUSD) > 1e-12 {
+		t.Errorf("stored report = %+v; want %+v", stored, live)
+	}
+
+	// A reconnect resumes from the stored totals.
+	if got := LoadUsageTracker(id).Report().Totals; got != want {
+		t.Errorf("LoadUsageTracker() Totals = %+v; want %+v", got, want)
+	}
+}
+
+func TestSessionUsageEndpointErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupReplayDB(t)
+	srv := CreateServer(Config{WorkspaceRoot: t.TempDir()})
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/sessions/not-a-uuid/usage", http.StatusBadRequest},
+		{"/api/sessions/" + uuid.New().String() + "/usage", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := sendSessionRequest(t, srv, http.MethodGet, tt.target, "")
+		if w.Code != tt.want {
+			t.Errorf("GET %s status = %d; want %d", tt.target, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRecordUsageSendsRunningTotals(t *testing.T) {
+	conn := &recordingConn{}
+	session := newTestSession(t, conn, llm.NewMockClient())
+	session.ModelName = "claude-3-5-sonnet"
+
+	session.recordUsage(llm.UsageMetadata{InputTokens: 1000, OutputTokens: 200})
+	session.recordUsage(llm.UsageMetadata{InputTokens: 3000, OutputTokens: 800})
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	var last *UsageReport
+	for _, e := range conn.events {
+		if e.Type == EventTypeUsage {
+			report := e.Content.(UsageReport)
+			last = &report
+		}
+	}
+	if last == nil {
+		t.Fatal("no usage event sent")
+	}
+	if last.Totals.InputTokens != 4000 || last.Totals.OutputTokens != 1000 || last.Totals.Calls != 2 {
+		t.Errorf("Totals = %+v; want the running totals", last.Totals)
+	}
+	if math.Abs(last.CostUSD-0.027) > 1e-9 {
+		t.Errorf("CostUSD = %v; want 0.027", last.CostUSD)
+	}
+}
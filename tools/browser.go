@@ -3,17 +3,25 @@ package tools
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/playwright-community/playwright-go"
+
+	"water-ai/browser"
 )
 
+// BrowserManager owns the browser the browser tools drive. Their actions
+// run through a browser.Supervisor, so a browser that crashed mid-task is
+// restarted with its cookies and the action retried once.
 type BrowserManager struct {
-	pw      *playwright.Playwright
-	browser playwright.Browser
-	context playwright.BrowserContext
-	page    playwright.Page
+	pw         *playwright.Playwright
+	headless   bool
+	browser    playwright.Browser
+	context    playwright.BrowserContext
+	page       playwright.Page
+	supervisor *browser.Supervisor
 }
 
 func NewBrowserManager(headless bool) (*BrowserManager, error) {
@@ -21,27 +29,82 @@ func NewBrowserManager(headless bool) (*BrowserManager, error) {
 	if err != nil {
 		return nil, err
 	}
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(headless),
+	return newBrowserManager(pw, headless)
+}
+
+func newBrowserManager(pw *playwright.Playwright, headless bool) (*BrowserManager, error) {
+	m := &BrowserManager{pw: pw, headless: headless}
+	if err := m.launch(nil); err != nil {
+		return nil, err
+	}
+	m.supervisor = browser.NewSupervisor(m)
+	return m, nil
+}
+
+// launch starts a browser with a single page, restoring cookies, as
+// returned by GetStorageState.
+func (b *BrowserManager) launch(cookies interface{}) error {
+	br, err := b.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(b.headless),
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	ctx, err := browser.NewContext()
+	ctx, err := br.NewContext()
 	if err != nil {
-		return nil, err
+		br.Close()
+		return err
+	}
+	var pCookies []playwright.OptionalCookie
+	if data, err := json.Marshal(cookies); err == nil && json.Unmarshal(data, &pCookies) == nil && len(pCookies) > 0 {
+		ctx.AddCookies(pCookies)
 	}
 	page, err := ctx.NewPage()
+	if err != nil {
+		br.Close()
+		return err
+	}
+	b.browser, b.context, b.page = br, ctx, page
+	return nil
+}
+
+// GetStorageState returns the cookies of the browser context.
+func (b *BrowserManager) GetStorageState() (map[string]interface{}, error) {
+	cookies, err := b.context.Cookies()
 	if err != nil {
 		return nil, err
 	}
-	return &BrowserManager{pw: pw, browser: browser, context: ctx, page: page}, nil
+	return map[string]interface{}{"cookies": cookies}, nil
+}
+
+// RestartWithStorageState replaces the browser with a new one holding the
+// cookies of state.
+func (b *BrowserManager) RestartWithStorageState(state map[string]interface{}) error {
+	b.browser.Close()
+	return b.launch(state["cookies"])
+}
+
+// OnDisconnected calls fn when the browser disconnects or its context
+// closes.
+func (b *BrowserManager) OnDisconnected(fn func()) {
+	b.browser.OnDisconnected(func(playwright.Browser) { fn() })
+	b.context.OnClose(func(playwright.BrowserContext) { fn() })
+}
+
+// do runs action on the current page through the supervisor.
+func (b *BrowserManager) do(action func(page playwright.Page) error) error {
+	return b.supervisor.Do(func() error { return action(b.page) })
 }
 
 func (b *BrowserManager) captureState() (string, string, error) {
 	// Returns screenshot (base64) and simple text message
-	screenshot, err := b.page.Screenshot(playwright.PageScreenshotOptions{
-		Type: playwright.ScreenshotTypePng,
+	var screenshot []byte
+	err := b.do(func(page playwright.Page) error {
+		var err error
+		screenshot, err = page.Screenshot(playwright.PageScreenshotOptions{
+			Type: playwright.ScreenshotTypePng,
+		})
+		return err
 	})
 	if err != nil {
 		return "", "", err
@@ -69,7 +132,11 @@ func (t *BrowserNavigateTool) Run(ctx context.Context, input ToolInput) (*ToolOu
 	if err != nil {
 		return ErrorOutput(err), nil
 	}
-	if _, err := t.Manager.page.Goto(url); err != nil {
+	err = t.Manager.do(func(page playwright.Page) error {
+		_, err := page.Goto(url)
+		return err
+	})
+	if err != nil {
 		return ErrorOutput(err), nil
 	}
 	img, _, _ := t.Manager.captureState()
@@ -96,7 +163,7 @@ func (t *BrowserClickTool) Run(ctx context.Context, input ToolInput) (*ToolOutpu
 	y, err := GetArg[float64](input, "y")
 	if err != nil { return ErrorOutput(err), nil }
 
-	if err := t.Manager.page.Mouse().Click(x, y); err != nil {
+	if err := t.Manager.do(func(page playwright.Page) error { return page.Mouse().Click(x, y) }); err != nil {
 		return ErrorOutput(err), nil
 	}
 	time.Sleep(1 * time.Second) // Wait for reaction
@@ -118,7 +185,7 @@ func (t *BrowserScrollTool) Run(ctx context.Context, input ToolInput) (*ToolOutp
 	if t.Direction == "up" {
 		deltaY = -500.0
 	}
-	if err := t.Manager.page.Mouse().Wheel(0, deltaY); err != nil {
+	if err := t.Manager.do(func(page playwright.Page) error { return page.Mouse().Wheel(0, deltaY) }); err != nil {
 		return ErrorOutput(err), nil
 	}
 	time.Sleep(500 * time.Millisecond)
@@ -143,9 +210,17 @@ func (t *BrowserTypeTool) Run(ctx context.Context, input ToolInput) (*ToolOutput
     text, _ := GetArg[string](input, "text")
     pressEnter, _ := GetArg[bool](input, "press_enter")
     
-    t.Manager.page.Keyboard().Type(text)
-    if pressEnter {
-        t.Manager.page.Keyboard().Press("Enter")
+    err := t.Manager.do(func(page playwright.Page) error {
+        if err := page.Keyboard().Type(text); err != nil {
+            return err
+        }
+        if pressEnter {
+            return page.Keyboard().Press("Enter")
+        }
+        return nil
+    })
+    if err != nil {
+        return ErrorOutput(err), nil
     }
     time.Sleep(1 * time.Second)
     img, _, _ := t.Manager.captureState()
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"water-ai/browser"
+)
+
+// TabSwitcher lists and switches browser tabs. *browser.Browser
+// satisfies it.
+type TabSwitcher interface {
+	GetTabsInfo() ([]browser.TabInfo, error)
+	SwitchToTab(pageID int) error
+}
+
+// BrowserSwitchTabTool makes another open tab the current one.
+type BrowserSwitchTabTool struct{ Browser TabSwitcher }
+
+func (t *BrowserSwitchTabTool) Name() string { return "browser_switch_tab" }
+func (t *BrowserSwitchTabTool) Description() string {
+	return "Switch the browser to the open tab with the given page_id."
+}
+func (t *BrowserSwitchTabTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"page_id": map[string]string{"type": "integer", "description": "The tab's page_id, as listed in the browser state"},
+		},
+		"required": []string{"page_id"},
+	}
+}
+
+func (t *BrowserSwitchTabTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	pageID, err := GetArg[int](input, "page_id")
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	if err := t.Browser.SwitchToTab(pageID); err != nil {
+		return ErrorOutput(err), nil
+	}
+	tabs, _ := t.Browser.GetTabsInfo()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Switched to tab %d. Open tabs:\n", pageID)
+	for _, tab := range tabs {
+		marker := " "
+		if tab.PageID == pageID {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "%s [%d] %s (%s)\n", marker, tab.PageID, tab.Title, tab.URL)
+	}
+	return &ToolOutput{Text: b.String()}, nil
+}
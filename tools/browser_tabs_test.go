@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"water-ai/browser"
+)
+
+type fakeTabs struct {
+	tabs    []browser.TabInfo
+	current int
+}
+
+func (f *fakeTabs) GetTabsInfo() ([]browser.TabInfo, error) { return f.tabs, nil }
+
+func (f *fakeTabs) SwitchToTab(pageID int) error {
+	if pageID < 0 || pageID >= len(f.tabs) {
+		return fmt.Errorf("no tab found with page_id: %d", pageID)
+	}
+	f.current = pageID
+	return nil
+}
+
+func TestBrowserSwitchTabTool(t *testing.T) {
+	tabs := &fakeTabs{tabs: []browser.TabInfo{
+		{PageID: 0, URL: "https://example.com/", Title: "Example"},
+		{PageID: 1, URL: "https://example.com/docs", Title: "Docs"},
+	}}
+	tool := &BrowserSwitchTabTool{Browser: tabs}
+
+	// JSON numbers arrive as float64.
+	out, _ := tool.Run(context.Background(), ToolInput{"page_id": float64(1)})
+	if out.Error != "" || tabs.current != 1 || !strings.Contains(out.Text, "* [1] Docs (https://example.com/docs)") {
+		t.Errorf("Run(1) = %q, error %q, current tab %d; want tab 1 marked current", out.Text, out.Error, tabs.current)
+	}
+	if out, _ := tool.Run(context.Background(), ToolInput{"page_id": float64(5)}); out.Error == "" {
+		t.Error("Run(5) succeeded; want an error for a missing tab")
+	}
+}
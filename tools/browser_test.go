@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fakeChromium launches browsers whose pages come from pages in order.
+type fakeChromium struct {
+	playwright.BrowserType
+	pages    []*fakePage
+	launches int
+	restored []playwright.OptionalCookie
+}
+
+func (f *fakeChromium) Launch(options ...playwright.BrowserTypeLaunchOptions) (playwright.Browser, error) {
+	page := f.pages[f.launches]
+	f.launches++
+	return &fakePWBrowser{ctx: &fakePWContext{page: page, chromium: f}}, nil
+}
+
+type fakePWBrowser struct {
+	playwright.Browser
+	ctx *fakePWContext
+}
+
+func (b *fakePWBrowser) NewContext(options ...playwright.BrowserNewContextOptions) (playwright.BrowserContext, error) {
+	return b.ctx, nil
+}
+func (b *fakePWBrowser) Close(options ...playwright.BrowserCloseOptions) error { return nil }
+func (b *fakePWBrowser) OnDisconnected(fn func(playwright.Browser))            {}
+
+type fakePWContext struct {
+	playwright.BrowserContext
+	page     *fakePage
+	chromium *fakeChromium
+}
+
+func (c *fakePWContext) NewPage() (playwright.Page, error)          { return c.page, nil }
+func (c *fakePWContext) OnClose(fn func(playwright.BrowserContext)) {}
+func (c *fakePWContext) Cookies(urls ...string) ([]playwright.Cookie, error) {
+	return []playwright.Cookie{{Name: "sid", Value: "abc", Domain: "example.com", Path: "/"}}, nil
+}
+func (c *fakePWContext) AddCookies(cookies []playwright.OptionalCookie) error {
+	c.chromium.restored = cookies
+	return nil
+}
+
+// fakePage fails every navigation with gotoErr.
+type fakePage struct {
+	playwright.Page
+	gotoErr error
+	url     string
+}
+
+func (p *fakePage) Goto(url string, options ...playwright.PageGotoOptions) (playwright.Response, error) {
+	if p.gotoErr != nil {
+		return nil, p.gotoErr
+	}
+	p.url = url
+	return nil, nil
+}
+func (p *fakePage) Screenshot(options ...playwright.PageScreenshotOptions) ([]byte, error) {
+	return []byte("png"), nil
+}
+func (p *fakePage) URL() string { return p.url }
+
+func TestBrowserNavigateRestartsCrashedBrowser(t *testing.T) {
+	crashed := &fakePage{gotoErr: errors.New("Target page, context or browser has been closed")}
+	fresh := &fakePage{}
+	chromium := &fakeChromium{pages: []*fakePage{crashed, fresh}}
+	mgr, err := newBrowserManager(&playwright.Playwright{Chromium: chromium}, true)
+	if err != nil {
+		t.Fatalf("newBrowserManager() error = %v", err)
+	}
+
+	out, _ := (&BrowserNavigateTool{Manager: mgr}).Run(context.Background(), ToolInput{"url": "https://example.com/"})
+	if out.Error != "" {
+		t.Fatalf("Run() error = %q; want the navigation retried after a restart", out.Error)
+	}
+	if chromium.launches != 2 || fresh.url != "https://example.com/" {
+		t.Errorf("launches = %d, fresh page at %q; want one restart that navigated", chromium.launches, fresh.url)
+	}
+	if len(chromium.restored) != 1 || chromium.restored[0].Name != "sid" {
+		t.Errorf("restored cookies = %+v; want the crashed browser's cookie", chromium.restored)
+	}
+}
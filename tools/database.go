@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"water-ai/core/config"
+)
+
+const (
+	defaultNeonAPIURL = "https://console.neon.tech/api/v2"
+	// DatabaseRefFile records the database provisioned for a workspace so
+	// later calls reuse it and cleanup can delete it.
+	DatabaseRefFile = ".water_database.json"
+	// DatabaseEnvVar is the variable the connection string is written to
+	// in the workspace .env file.
+	DatabaseEnvVar = "DATABASE_URL"
+)
+
+// NeonProject is a provisioned Neon Postgres project.
+type NeonProject struct {
+	ID            string
+	Database      string
+	Role          string
+	ConnectionURI string
+}
+
+// NeonClient manages projects through the Neon API.
+type NeonClient struct {
+	APIKey  string
+	BaseURL string // defaults to https://console.neon.tech/api/v2
+	Client  *http.Client
+}
+
+// NewNeonClient returns a client for the Neon key in thirdParty, or nil
+// when none is set.
+func NewNeonClient(thirdParty config.ThirdPartyIntegrationConfig) *NeonClient {
+	if thirdParty.NeonDBAPIKey == nil || thirdParty.NeonDBAPIKey.Reveal() == "" {
+		return nil
+	}
+	return &NeonClient{APIKey: thirdParty.NeonDBAPIKey.Reveal()}
+}
+
+func (c *NeonClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = defaultNeonAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("neon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("neon returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// CreateProject provisions a project named name with its default database.
+func (c *NeonClient) CreateProject(ctx context.Context, name string) (NeonProject, error) {
+	var out struct {
+		Project struct {
+			ID string `json:"id"`
+		} `json:"project"`
+		ConnectionURIs []struct {
+			ConnectionURI    string `json:"connection_uri"`
+			ConnectionParams struct {
+				Database string `json:"database"`
+				Role     string `json:"role"`
+			} `json:"connection_parameters"`
+		} `json:"connection_uris"`
+	}
+	body := map[string]interface{}{"project": map[string]string{"name": name}}
+	if err := c.do(ctx, http.MethodPost, "/projects", body, &out); err != nil {
+		return NeonProject{}, err
+	}
+	if out.Project.ID == "" || len(out.ConnectionURIs) == 0 {
+		return NeonProject{}, errors.New("neon did not return a project connection string")
+	}
+	uri := out.ConnectionURIs[0]
+	return NeonProject{
+		ID:            out.Project.ID,
+		Database:      uri.ConnectionParams.Database,
+		Role:          uri.ConnectionParams.Role,
+		ConnectionURI: uri.ConnectionURI,
+	}, nil
+}
+
+// ConnectionURI returns the connection string for database as role in an
+// existing project.
+func (c *NeonClient) ConnectionURI(ctx context.Context, projectID, database, role string) (string, error) {
+	q := url.Values{"database_name": {database}, "role_name": {role}}
+	var out struct {
+		URI string `json:"uri"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(projectID)+"/connection_uri?"+q.Encode(), nil, &out); err != nil {
+		return "", err
+	}
+	return out.URI, nil
+}
+
+// DeleteProject deletes a project and its databases.
+func (c *NeonClient) DeleteProject(ctx context.Context, projectID string) error {
+	return c.do(ctx, http.MethodDelete, "/projects/"+url.PathEscape(projectID), nil, nil)
+}
+
+// RedactConnectionString hides the password in a database URL.
+func RedactConnectionString(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "(unparseable connection string)"
+	}
+	return u.Redacted()
+}
+
+// databaseRef is the content of DatabaseRefFile. It holds no credentials.
+type databaseRef struct {
+	Provider  string `json:"provider"`
+	ProjectID string `json:"project_id"`
+	Database  string `json:"database"`
+	Role      string `json:"role"`
+}
+
+// GetDatabaseConnectionTool gives the generated app a Postgres database. The
+// first call provisions a Neon project for the workspace; later calls
+// return the same database. The connection string is written to the
+// workspace .env file, and only its redacted form appears in the output.
+type GetDatabaseConnectionTool struct {
+	Neon      *NeonClient
+	Workspace string
+	// Session names the Neon project.
+	Session string
+}
+
+func (t *GetDatabaseConnectionTool) Name() string { return "get_database_connection" }
+func (t *GetDatabaseConnectionTool) Description() string {
+	return "Get a PostgreSQL database for the app. The connection string is written to " + DatabaseEnvVar + " in the workspace .env file."
+}
+func (t *GetDatabaseConnectionTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *GetDatabaseConnectionTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	if t.Neon == nil {
+		return ErrorOutput(errors.New("database provisioning is not configured: set NEON_DB_API_KEY")), nil
+	}
+
+	ref, err := t.loadRef()
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	var uri string
+	provisioned := ref == nil
+	if ref != nil {
+		if uri, err = t.Neon.ConnectionURI(ctx, ref.ProjectID, ref.Database, ref.Role); err != nil {
+			return ErrorOutput(fmt.Errorf("failed to get the connection string: %w", err)), nil
+		}
+	} else {
+		project, err := t.Neon.CreateProject(ctx, "water-"+t.Session)
+		if err != nil {
+			return ErrorOutput(fmt.Errorf("failed to provision a database: %w", err)), nil
+		}
+		ref = &databaseRef{Provider: "neon", ProjectID: project.ID, Database: project.Database, Role: project.Role}
+		if err := t.saveRef(ref); err != nil {
+			return ErrorOutput(err), nil
+		}
+		uri = project.ConnectionURI
+	}
+
+	if err := setEnvVar(filepath.Join(t.Workspace, ".env"), DatabaseEnvVar, uri); err != nil {
+		return ErrorOutput(fmt.Errorf("failed to write .env: %w", err)), nil
+	}
+	verb := "Using the existing"
+	if provisioned {
+		verb = "Provisioned a new"
+	}
+	redacted := RedactConnectionString(uri)
+	return &ToolOutput{
+		Text: fmt.Sprintf("%s PostgreSQL database. %s is set in .env: %s\nRead it from the environment; do not hard-code it.", verb, DatabaseEnvVar, redacted),
+		Auxiliary: map[string]interface{}{
+			"provider":          ref.Provider,
+			"project_id":        ref.ProjectID,
+			"connection_string": redacted,
+		},
+	}, nil
+}
+
+// Cleanup deletes the database provisioned for the workspace, if any.
+func (t *GetDatabaseConnectionTool) Cleanup(ctx context.Context) error {
+	ref, err := t.loadRef()
+	if err != nil || ref == nil {
+		return err
+	}
+	if t.Neon == nil {
+		return errors.New("database provisioning is not configured")
+	}
+	if err := t.Neon.DeleteProject(ctx, ref.ProjectID); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(t.Workspace, DatabaseRefFile))
+}
+
+func (t *GetDatabaseConnectionTool) loadRef() (*databaseRef, error) {
+	data, err := os.ReadFile(filepath.Join(t.Workspace, DatabaseRefFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ref databaseRef
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", DatabaseRefFile, err)
+	}
+	return &ref, nil
+}
+
+func (t *GetDatabaseConnectionTool) saveRef(ref *databaseRef) error {
+	data, err := json.MarshalIndent(ref, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.Workspace, DatabaseRefFile), data, 0600)
+}
+
+// setEnvVar sets key in the dotenv file at path, replacing an existing
+// assignment and keeping the other lines.
+func setEnvVar(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	line := fmt.Sprintf("%s=%q", key, value)
+	var lines []string
+	replaced := false
+	for _, l := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), key+"=") {
+			l, replaced = line, true
+		}
+		if l != "" || len(lines) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	if !replaced {
+		lines = append(lines, line)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
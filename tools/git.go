@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"water-ai/core/config"
+	"water-ai/utils"
+)
+
+// Default identity for commits when the repository has none configured.
+const (
+	DefaultGitAuthorName  = "Water Agent"
+	DefaultGitAuthorEmail = "agent@water.local"
+)
+
+// GitTool clones, inspects, commits and pushes repositories inside the
+// workspace. Token, when set, authenticates HTTPS requests to github.com.
+type GitTool struct {
+	Workspace string
+	Token     string
+}
+
+// NewGitTool returns a GitTool for workspace using the GitHub token in
+// thirdParty, if any.
+func NewGitTool(thirdParty config.ThirdPartyIntegrationConfig, workspace string) *GitTool {
+	t := &GitTool{Workspace: workspace}
+	if thirdParty.GitHubToken != nil {
+		t.Token = thirdParty.GitHubToken.Reveal()
+	}
+	return t
+}
+
+func (t *GitTool) Name() string { return "git" }
+func (t *GitTool) Description() string {
+	return "Work with git repositories in the workspace: clone, status, diff, commit and push."
+}
+func (t *GitTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action":  map[string]interface{}{"type": "string", "enum": []string{"clone", "status", "diff", "commit", "push"}},
+			"path":    map[string]string{"type": "string", "description": "Repository directory relative to the workspace; for clone, the destination (defaults to the repository name)"},
+			"url":     map[string]string{"type": "string", "description": "Repository URL to clone"},
+			"message": map[string]string{"type": "string", "description": "Commit message"},
+			"files":   map[string]interface{}{"type": "array", "items": map[string]string{"type": "string"}, "description": "Files to commit; defaults to all changes"},
+			"staged":  map[string]string{"type": "boolean", "description": "Show the staged diff instead of unstaged changes"},
+			"remote":  map[string]string{"type": "string", "description": "Remote to push to (default origin)"},
+			"branch":  map[string]string{"type": "string", "description": "Branch to push (default the current branch)"},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GitTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	action, err := GetArg[string](input, "action")
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	relPath, _ := GetArg[string](input, "path")
+
+	if action == "clone" {
+		url, err := GetArg[string](input, "url")
+		if err != nil || url == "" {
+			return ErrorOutput(errors.New("clone requires a url")), nil
+		}
+		if !isRemoteGitURL(url) {
+			return ErrorOutput(fmt.Errorf("cannot clone %q: only http(s) and ssh URLs are allowed", url)), nil
+		}
+		if relPath == "" {
+			relPath = strings.TrimSuffix(path.Base(strings.TrimSuffix(url, "/")), ".git")
+		}
+		dest, err := utils.ResolveInRoot(t.Workspace, relPath)
+		if err != nil {
+			return ErrorOutput(err), nil
+		}
+		if _, err := os.Stat(dest); err == nil {
+			return ErrorOutput(fmt.Errorf("%s already exists in the workspace", relPath)), nil
+		}
+		out, err := t.git(ctx, t.Workspace, "clone", "--", url, dest)
+		if err != nil {
+			return ErrorOutput(err), nil
+		}
+		return &ToolOutput{Text: truncateGitOutput(fmt.Sprintf("Cloned %s into %s\n%s", url, relPath, out))}, nil
+	}
+
+	dir, err := utils.ResolveInRoot(t.Workspace, relPath)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	var out string
+	switch action {
+	case "status":
+		out, err = t.git(ctx, dir, "status", "--short", "--branch")
+	case "diff":
+		args := []string{"diff"}
+		if staged, _ := GetArg[bool](input, "staged"); staged {
+			args = append(args, "--cached")
+		}
+		out, err = t.git(ctx, dir, args...)
+		if err == nil && out == "" {
+			out = "No changes."
+		}
+	case "commit":
+		out, err = t.commit(ctx, dir, input)
+	case "push":
+		remote, _ := GetArg[string](input, "remote")
+		if remote == "" {
+			remote = "origin"
+		}
+		branch, _ := GetArg[string](input, "branch")
+		if branch == "" {
+			branch = "HEAD"
+		}
+		out, err = t.git(ctx, dir, "push", "--set-upstream", remote, branch)
+	default:
+		return ErrorOutput(fmt.Errorf("unknown action %q", action)), nil
+	}
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	return &ToolOutput{Text: truncateGitOutput(out)}, nil
+}
+
+func (t *GitTool) commit(ctx context.Context, dir string, input ToolInput) (string, error) {
+	message, _ := GetArg[string](input, "message")
+	if strings.TrimSpace(message) == "" {
+		return "", errors.New("commit requires a message")
+	}
+	add := []string{"add", "-A"}
+	if files, _ := GetArg[[]interface{}](input, "files"); len(files) > 0 {
+		add = append(add, "--")
+		for _, f := range files {
+			name, ok := f.(string)
+			if !ok {
+				return "", fmt.Errorf("files must be strings, got %T", f)
+			}
+			add = append(add, name)
+		}
+	}
+	if _, err := t.git(ctx, dir, add...); err != nil {
+		return "", err
+	}
+	args := []string{"commit", "-m", message}
+	if name, _ := t.git(ctx, dir, "config", "user.name"); strings.TrimSpace(name) == "" {
+		args = append([]string{"-c", "user.name=" + DefaultGitAuthorName}, args...)
+	}
+	if email, _ := t.git(ctx, dir, "config", "user.email"); strings.TrimSpace(email) == "" {
+		args = append([]string{"-c", "user.email=" + DefaultGitAuthorEmail}, args...)
+	}
+	return t.git(ctx, dir, args...)
+}
+
+// scpLikeGitURL matches the user@host:path form of ssh URLs.
+var scpLikeGitURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/]`)
+
+// isRemoteGitURL reports whether rawURL is an http(s) or ssh repository
+// URL. Local paths and file:// URLs would copy repositories from the host.
+func isRemoteGitURL(rawURL string) bool {
+	if scpLikeGitURL.MatchString(rawURL) {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh":
+		return true
+	}
+	return false
+}
+
+// git runs git in dir and returns its combined output. The token is passed
+// through the environment so it never appears in arguments or output.
+// Repository discovery stops at the workspace, so a directory that is not
+// a repository never resolves to one enclosing the workspace.
+func (t *GitTool) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-c", "protocol.ext.allow=never", "-c", "protocol.file.allow=never"}, args...)...)
+	cmd.Dir = dir
+	root, err := filepath.Abs(t.Workspace)
+	if err != nil {
+		return "", err
+	}
+	cmd.Env = append(gitEnviron(), "GIT_TERMINAL_PROMPT=0", "GIT_CEILING_DIRECTORIES="+filepath.Dir(root))
+	if t.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + t.Token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.https://github.com/.extraheader",
+			"GIT_CONFIG_VALUE_0=AUTHORIZATION: basic "+auth,
+		)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		text := strings.TrimSpace(out.String())
+		if t.Token != "" {
+			text = strings.ReplaceAll(text, t.Token, "xxxxx")
+		}
+		sub := args[0]
+		for i := 0; i+2 < len(args) && args[i] == "-c"; i += 2 {
+			sub = args[i+2]
+		}
+		return "", fmt.Errorf("git %s failed: %v\n%s", sub, err, truncateGitOutput(text))
+	}
+	return out.String(), nil
+}
+
+// gitEnviron returns the process environment without variables that point
+// git at a repository other than the one it discovers.
+func gitEnviron() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		switch strings.SplitN(kv, "=", 2)[0] {
+		case "GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_OBJECT_DIRECTORY", "GIT_COMMON_DIR", "GIT_CEILING_DIRECTORIES":
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+func truncateGitOutput(s string) string {
+	if len(s) > utils.MaxResponseLen {
+		return s[:utils.MaxResponseLen] + "\n" + utils.TruncatedMessage
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBareRepo returns a bare repository with one commit adding README.md.
+func newBareRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "origin.git")
+	seed := filepath.Join(dir, "seed")
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run(dir, "init", "--bare", "-b", "main", bare)
+	run(dir, "clone", bare, seed)
+	os.WriteFile(filepath.Join(seed, "README.md"), []byte("hello\n"), 0644)
+	run(seed, "add", "README.md")
+	run(seed, "commit", "-m", "initial")
+	run(seed, "push", "origin", "HEAD:main")
+	run(bare, "config", "http.receivepack", "true")
+	return bare
+}
+
+// serveGitRepo serves the repositories next to bare over HTTP through git
+// http-backend and returns the URL of bare.
+func serveGitRepo(t *testing.T, bare string) string {
+	t.Helper()
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	srv := httptest.NewServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(bare), "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(srv.Close)
+	return srv.URL + "/" + filepath.Base(bare)
+}
+
+func TestGitToolRoundTrip(t *testing.T) {
+	bare := newBareRepo(t)
+	remote := serveGitRepo(t, bare)
+	// Ignore the host's identity so the default author is used.
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	workspace := t.TempDir()
+	tool := &GitTool{Workspace: workspace}
+	ctx := context.Background()
+
+	steps := []struct {
+		input ToolInput
+		want  string
+	}{
+		{ToolInput{"action": "clone", "url": remote}, "Cloned"},
+		{ToolInput{"action": "diff", "path": "origin"}, "No changes."},
+	}
+	for _, step := range steps {
+		out, _ := tool.Run(ctx, step.input)
+		if out.Error != "" || !strings.Contains(out.Text, step.want) {
+			t.Fatalf("%v: Text = %q, Error = %q; want %q", step.input["action"], out.Text, out.Error, step.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "origin", "README.md")); err != nil {
+		t.Fatalf("clone did not check out README.md: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(workspace, "origin", "README.md"), []byte("hello\nworld\n"), 0644)
+	steps = []struct {
+		input ToolInput
+		want  string
+	}{
+		{ToolInput{"action": "diff", "path": "origin"}, "+world"},
+		{ToolInput{"action": "status", "path": "origin"}, "M README.md"},
+		{ToolInput{"action": "commit", "path": "origin", "message": "Add world", "files": []interface{}{"README.md"}}, "Add world"},
+		{ToolInput{"action": "diff", "path": "origin"}, "No changes."},
+		{ToolInput{"action": "push", "path": "origin"}, ""},
+	}
+	for _, step := range steps {
+		out, _ := tool.Run(ctx, step.input)
+		if out.Error != "" || !strings.Contains(out.Text, step.want) {
+			t.Fatalf("%v: Text = %q, Error = %q; want %q", step.input["action"], out.Text, out.Error, step.want)
+		}
+	}
+
+	log, err := exec.Command("git", "--git-dir", bare, "log", "--format=%s|%an", "-1", "main").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(log)); got != "Add world|"+DefaultGitAuthorName {
+		t.Errorf("origin head = %q; want the pushed commit", got)
+	}
+}
+
+func TestGitToolStaysInWorkspace(t *testing.T) {
+	tool := &GitTool{Workspace: t.TempDir()}
+	tests := []ToolInput{
+		{"action": "status", "path": "../"},
+		{"action": "clone", "url": "https://github.com/example/repo.git", "path": "/tmp/repo"},
+		{"action": "clone", "url": "/etc/repo.git"},
+		{"action": "clone", "url": "file:///etc/repo.git"},
+		{"action": "clone", "url": "ext::sh -c touch% /tmp/pwned"},
+		{"action": "commit", "message": ""},
+		{"action": "rebase"},
+	}
+	for _, input := range tests {
+		if out, _ := tool.Run(context.Background(), input); out.Error == "" {
+			t.Errorf("Run(%v) succeeded; want an error", input)
+		}
+	}
+}
+
+func TestGitToolIgnoresRepositoryAroundWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	host := t.TempDir()
+	if out, err := exec.Command("git", "init", host).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	workspace := filepath.Join(host, "workspace")
+	os.MkdirAll(filepath.Join(workspace, "app"), 0755)
+	tool := &GitTool{Workspace: workspace}
+
+	for _, relPath := range []string{"", "app"} {
+		out, _ := tool.Run(context.Background(), ToolInput{"action": "status", "path": relPath})
+		if !strings.Contains(out.Error, "not a git repository") {
+			t.Errorf("status in %q: Text = %q, Error = %q; want not a git repository", relPath, out.Text, out.Error)
+		}
+	}
+}
+
+func TestIsRemoteGitURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://github.com/example/repo.git", true},
+		{"http://git.example.com/repo", true},
+		{"ssh://git@github.com/example/repo.git", true},
+		{"git@github.com:example/repo.git", true},
+		{"/srv/repo.git", false},
+		{"../repo", false},
+		{"file:///srv/repo.git", false},
+		{"ext::sh -c id", false},
+		{"https:///no-host", false},
+	}
+	for _, tt := range tests {
+		if got := isRemoteGitURL(tt.url); got != tt.want {
+			t.Errorf("isRemoteGitURL(%q) = %v; want %v", tt.url, got, tt.want)
+		}
+	}
+}
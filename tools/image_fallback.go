@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"water-ai/core/config"
+)
+
+// Image sources reported by ImageTool, in the order they are tried. They
+// match the priority in the system prompt.
+const (
+	ImageSourceGenerated = "generate_image_from_text"
+	ImageSourceSearch    = "image_search"
+	ImageSourceSVG       = "svg"
+)
+
+const (
+	defaultSerpAPIURL    = "https://serpapi.com/search.json"
+	imageSearchMaxResult = 5
+	svgPlaceholderWidth  = 1024
+)
+
+// ImageSearchResult is one image found by an ImageSearchBackend.
+type ImageSearchResult struct {
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Source string `json:"source,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// ImageSearchBackend finds existing images matching a query.
+type ImageSearchBackend interface {
+	SearchImages(ctx context.Context, query string, maxResults int) ([]ImageSearchResult, error)
+}
+
+// SerpAPIImageSearch searches Google Images through SerpAPI.
+type SerpAPIImageSearch struct {
+	APIKey  string
+	BaseURL string // defaults to https://serpapi.com/search.json
+	Client  *http.Client
+}
+
+// NewImageSearchBackend returns the image search backend configured in
+// search, or nil when no SerpAPI key is set.
+func NewImageSearchBackend(search config.SearchConfig) ImageSearchBackend {
+	if search.SerpapiAPIKey == nil || search.SerpapiAPIKey.Reveal() == "" {
+		return nil
+	}
+	return &SerpAPIImageSearch{APIKey: search.SerpapiAPIKey.Reveal()}
+}
+
+func (s *SerpAPIImageSearch) SearchImages(ctx context.Context, query string, maxResults int) ([]ImageSearchResult, error) {
+	base := s.BaseURL
+	if base == "" {
+		base = defaultSerpAPIURL
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	q := url.Values{"engine": {"google_images"}, "q": {query}, "api_key": {s.APIKey}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image search returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Images []struct {
+			Original       string `json:"original"`
+			Title          string `json:"title"`
+			Source         string `json:"source"`
+			OriginalWidth  int    `json:"original_width"`
+			OriginalHeight int    `json:"original_height"`
+		} `json:"images_results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding image search results: %w", err)
+	}
+	var results []ImageSearchResult
+	for _, img := range body.Images {
+		if img.Original == "" {
+			continue
+		}
+		results = append(results, ImageSearchResult{URL: img.Original, Title: img.Title, Source: img.Source, Width: img.OriginalWidth, Height: img.OriginalHeight})
+		if len(results) == maxResults {
+			break
+		}
+	}
+	return results, nil
+}
+
+// ImageTool gets an image for a description following the documented
+// priority: it generates one from text, falls back to an image search when
+// generation is unavailable or fails, and writes an SVG placeholder only
+// when neither works. Auxiliary["source"] reports which path was used.
+type ImageTool struct {
+	Generator ImageBackend       // nil skips generation
+	Search    ImageSearchBackend // nil skips the search
+	Workspace string
+}
+
+func (t *ImageTool) Name() string { return "get_image" }
+func (t *ImageTool) Description() string {
+	return "Get an image for a description: generates one, or finds an existing image when generation is unavailable, or writes an SVG placeholder as a last resort."
+}
+func (t *ImageTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]string{"type": "string", "description": "Detailed description of the image"},
+			"aspect_ratio": map[string]interface{}{
+				"type":        "string",
+				"enum":        supportedAspectRatios,
+				"description": "Aspect ratio of the image, defaults to " + defaultAspectRatio,
+			},
+			"search_query": map[string]string{"type": "string", "description": "Query for the image search fallback; defaults to the prompt"},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+func (t *ImageTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	prompt, err := GetArg[string](input, "prompt")
+	if err != nil || strings.TrimSpace(prompt) == "" {
+		return ErrorOutput(errors.New("prompt is required")), nil
+	}
+	aspectRatio, _ := GetArg[string](input, "aspect_ratio")
+	if aspectRatio == "" {
+		aspectRatio = defaultAspectRatio
+	}
+	if !isSupportedAspectRatio(aspectRatio) {
+		return ErrorOutput(fmt.Errorf("unsupported aspect_ratio %q; use one of %s", aspectRatio, strings.Join(supportedAspectRatios, ", "))), nil
+	}
+	query, _ := GetArg[string](input, "search_query")
+	if strings.TrimSpace(query) == "" {
+		query = prompt
+	}
+
+	var fallbacks []string
+	if t.Generator != nil {
+		gen := &GenerateImageTool{Backend: t.Generator, Workspace: t.Workspace}
+		out, err := gen.Run(ctx, ToolInput{"prompt": prompt, "aspect_ratio": aspectRatio})
+		if err == nil && out.Error == "" {
+			return withImageSource(out, ImageSourceGenerated, fallbacks), nil
+		}
+		fallbacks = append(fallbacks, fmt.Sprintf("%s: %s", ImageSourceGenerated, toolFailure(out, err)))
+	} else {
+		fallbacks = append(fallbacks, ImageSourceGenerated+": not configured")
+	}
+
+	if t.Search != nil {
+		results, err := t.Search.SearchImages(ctx, query, imageSearchMaxResult)
+		switch {
+		case err != nil:
+			fallbacks = append(fallbacks, fmt.Sprintf("%s: %v", ImageSourceSearch, err))
+		case len(results) == 0:
+			fallbacks = append(fallbacks, ImageSourceSearch+": no results")
+		default:
+			var b strings.Builder
+			fmt.Fprintf(&b, "Found %d images for %q:\n", len(results), query)
+			for i, r := range results {
+				fmt.Fprintf(&b, "%d. %s", i+1, r.URL)
+				if r.Title != "" {
+					fmt.Fprintf(&b, " (%s)", r.Title)
+				}
+				b.WriteString("\n")
+			}
+			out := &ToolOutput{Text: b.String(), Auxiliary: map[string]interface{}{"images": results}}
+			return withImageSource(out, ImageSourceSearch, fallbacks), nil
+		}
+	} else {
+		fallbacks = append(fallbacks, ImageSourceSearch+": not configured")
+	}
+
+	relPath := fmt.Sprintf("placeholder_image_%s_%s.svg", time.Now().Format("20060102_150405"), uuid.NewString()[:8])
+	if err := os.WriteFile(filepath.Join(t.Workspace, relPath), []byte(svgPlaceholder(prompt, aspectRatio)), 0644); err != nil {
+		return ErrorOutput(fmt.Errorf("failed to save SVG placeholder: %w", err)), nil
+	}
+	out := &ToolOutput{
+		Text:      fmt.Sprintf("No image could be generated or found (%s). Wrote an SVG placeholder to %s; replace it with a real image when one is available.", strings.Join(fallbacks, "; "), relPath),
+		Auxiliary: map[string]interface{}{"path": relPath, "aspect_ratio": aspectRatio},
+	}
+	return withImageSource(out, ImageSourceSVG, fallbacks), nil
+}
+
+// toolFailure describes why a tool call did not succeed.
+func toolFailure(out *ToolOutput, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return out.Error
+}
+
+func withImageSource(out *ToolOutput, source string, fallbacks []string) *ToolOutput {
+	if out.Auxiliary == nil {
+		out.Auxiliary = map[string]interface{}{}
+	}
+	out.Auxiliary["source"] = source
+	if len(fallbacks) > 0 {
+		out.Auxiliary["fallbacks"] = fallbacks
+	}
+	return out
+}
+
+// svgPlaceholder renders a neutral placeholder of the given aspect ratio
+// labelled with the image description.
+func svgPlaceholder(prompt, aspectRatio string) string {
+	var w, h int
+	fmt.Sscanf(aspectRatio, "%d:%d", &w, &h)
+	width, height := svgPlaceholderWidth, svgPlaceholderWidth*h/w
+	label := prompt
+	if r := []rune(label); len(r) > 80 {
+		label = string(r[:77]) + "..."
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">
+<rect width="100%%" height="100%%" fill="#e5e7eb"/>
+<text x="50%%" y="50%%" font-family="sans-serif" font-size="28" fill="#6b7280" text-anchor="middle" dominant-baseline="middle">%s</text>
+</svg>
+`, width, height, width, height, html.EscapeString(label))
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type stubImageSearch struct {
+	query   string
+	results []ImageSearchResult
+	err     error
+}
+
+func (s *stubImageSearch) SearchImages(ctx context.Context, query string, maxResults int) ([]ImageSearchResult, error) {
+	s.query = query
+	return s.results, s.err
+}
+
+func TestImageToolFallbackChain(t *testing.T) {
+	found := []ImageSearchResult{{URL: "https://example.com/lighthouse.jpg", Title: "Lighthouse"}}
+	tests := []struct {
+		name      string
+		generator ImageBackend
+		search    ImageSearchBackend
+		want      string
+	}{
+		{"generation works", &stubImageBackend{}, &stubImageSearch{results: found}, ImageSourceGenerated},
+		{"generation fails", &stubImageBackend{err: errors.New("quota exceeded")}, &stubImageSearch{results: found}, ImageSourceSearch},
+		{"generation unconfigured", nil, &stubImageSearch{results: found}, ImageSourceSearch},
+		{"search finds nothing", nil, &stubImageSearch{}, ImageSourceSVG},
+		{"search fails", &stubImageBackend{err: errors.New("down")}, &stubImageSearch{err: errors.New("timeout")}, ImageSourceSVG},
+		{"nothing configured", nil, nil, ImageSourceSVG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			tool := &ImageTool{Generator: tt.generator, Search: tt.search, Workspace: workspace}
+			out, err := tool.Run(context.Background(), ToolInput{"prompt": "a lighthouse at dusk", "aspect_ratio": "16:9"})
+			if err != nil || out.Error != "" {
+				t.Fatalf("Run() = %v, %v", out, err)
+			}
+			if got := out.Auxiliary["source"]; got != tt.want {
+				t.Fatalf("source = %v; want %s", got, tt.want)
+			}
+			switch tt.want {
+			case ImageSourceSearch:
+				if !strings.Contains(out.Text, "https://example.com/lighthouse.jpg") {
+					t.Errorf("Text = %q; want the found image URL", out.Text)
+				}
+			case ImageSourceSVG:
+				data, err := os.ReadFile(filepath.Join(workspace, out.Auxiliary["path"].(string)))
+				if err != nil || !strings.Contains(string(data), `width="1024" height="576"`) || !strings.Contains(string(data), "a lighthouse at dusk") {
+					t.Errorf("placeholder = %q, %v; want a 16:9 SVG labelled with the prompt", data, err)
+				}
+			}
+			if tt.want != ImageSourceGenerated && len(out.Auxiliary["fallbacks"].([]string)) == 0 {
+				t.Error("fallbacks not reported")
+			}
+		})
+	}
+}
+
+func TestSerpAPIImageSearch(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		w.Write([]byte(`{"images_results": [
+			{"original": "https://a.example/1.png", "title": "One", "original_width": 800, "original_height": 600},
+			{"title": "no url"},
+			{"original": "https://a.example/2.png"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	s := &SerpAPIImageSearch{APIKey: "key", BaseURL: srv.URL}
+	results, err := s.SearchImages(context.Background(), "red fox", 5)
+	if err != nil {
+		t.Fatalf("SearchImages() error = %v", err)
+	}
+	if query != "red fox" || len(results) != 2 || results[0].URL != "https://a.example/1.png" || results[0].Width != 800 {
+		t.Errorf("SearchImages() = %+v (query %q); want the two results with URLs", results, query)
+	}
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+	"google.golang.org/genai"
+
+	"water-ai/core/config"
+)
+
+const (
+	// DefaultImagenModel is the model used by GeminiImageBackend.
+	DefaultImagenModel   = "imagen-3.0-generate-002"
+	defaultAspectRatio   = "1:1"
+	imageThumbnailMaxDim = 256
+)
+
+// supportedAspectRatios are the ratios accepted by Imagen.
+var supportedAspectRatios = []string{"1:1", "3:4", "4:3", "9:16", "16:9"}
+
+// ImageBackend generates an image from a text prompt.
+type ImageBackend interface {
+	GenerateImage(ctx context.Context, prompt, aspectRatio string) ([]byte, error)
+}
+
+// GeminiImageBackend generates images with Imagen through Google AI Studio.
+type GeminiImageBackend struct {
+	APIKey string
+	Model  string // empty uses DefaultImagenModel
+}
+
+// NewImageBackend returns the image backend configured in media, or nil
+// when no Google AI Studio key is set.
+func NewImageBackend(media config.MediaConfig) ImageBackend {
+	if media.GoogleAIStudioAPIKey == nil || media.GoogleAIStudioAPIKey.Reveal() == "" {
+		return nil
+	}
+	return &GeminiImageBackend{APIKey: media.GoogleAIStudioAPIKey.Reveal()}
+}
+
+func (b *GeminiImageBackend) GenerateImage(ctx context.Context, prompt, aspectRatio string) ([]byte, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: b.APIKey, Backend: genai.BackendGeminiAPI})
+	if err != nil {
+		return nil, err
+	}
+	model := b.Model
+	if model == "" {
+		model = DefaultImagenModel
+	}
+	resp, err := client.Models.GenerateImages(ctx, model, prompt, &genai.GenerateImagesConfig{
+		NumberOfImages:   1,
+		AspectRatio:      aspectRatio,
+		OutputMIMEType:   "image/png",
+		IncludeRAIReason: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range resp.GeneratedImages {
+		if img.Image != nil && len(img.Image.ImageBytes) > 0 {
+			return img.Image.ImageBytes, nil
+		}
+		if img.RAIFilteredReason != "" {
+			return nil, fmt.Errorf("image was filtered: %s", img.RAIFilteredReason)
+		}
+	}
+	return nil, errors.New("no image was returned")
+}
+
+// GenerateImageTool generates an image from a prompt and saves it as a PNG
+// in the session workspace.
+type GenerateImageTool struct {
+	Backend   ImageBackend
+	Workspace string
+}
+
+func (t *GenerateImageTool) Name() string { return "generate_image_from_text" }
+func (t *GenerateImageTool) Description() string {
+	return "Generate an image from a text description and save it as a PNG in the workspace."
+}
+func (t *GenerateImageTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]string{"type": "string", "description": "Detailed description of the image"},
+			"aspect_ratio": map[string]interface{}{
+				"type":        "string",
+				"enum":        supportedAspectRatios,
+				"description": "Aspect ratio of the image, defaults to " + defaultAspectRatio,
+			},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+func (t *GenerateImageTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	prompt, err := GetArg[string](input, "prompt")
+	if err != nil || strings.TrimSpace(prompt) == "" {
+		return ErrorOutput(errors.New("prompt is required")), nil
+	}
+	aspectRatio, _ := GetArg[string](input, "aspect_ratio")
+	if aspectRatio == "" {
+		aspectRatio = defaultAspectRatio
+	}
+	if !isSupportedAspectRatio(aspectRatio) {
+		return ErrorOutput(fmt.Errorf("unsupported aspect_ratio %q; use one of %s", aspectRatio, strings.Join(supportedAspectRatios, ", "))), nil
+	}
+	if t.Backend == nil {
+		return ErrorOutput(errors.New("image generation is not configured: set the Google AI Studio API key in the media settings")), nil
+	}
+
+	data, err := t.Backend.GenerateImage(ctx, prompt, aspectRatio)
+	if err != nil {
+		return ErrorOutput(fmt.Errorf("image generation failed: %w", err)), nil
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ErrorOutput(fmt.Errorf("image backend returned invalid image data: %w", err)), nil
+	}
+	if format != "png" {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return ErrorOutput(err), nil
+		}
+		data = buf.Bytes()
+	}
+
+	relPath := fmt.Sprintf("generated_image_%s_%s.png", time.Now().Format("20060102_150405"), uuid.NewString()[:8])
+	if err := os.WriteFile(filepath.Join(t.Workspace, relPath), data, 0644); err != nil {
+		return ErrorOutput(fmt.Errorf("failed to save image: %w", err)), nil
+	}
+
+	thumb, err := pngThumbnail(img, imageThumbnailMaxDim)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	bounds := img.Bounds()
+	return &ToolOutput{
+		Text:   fmt.Sprintf("Generated image saved to %s (%dx%d)", relPath, bounds.Dx(), bounds.Dy()),
+		Images: []string{thumb},
+		Auxiliary: map[string]interface{}{
+			"path":         relPath,
+			"aspect_ratio": aspectRatio,
+		},
+	}, nil
+}
+
+func isSupportedAspectRatio(ratio string) bool {
+	for _, r := range supportedAspectRatios {
+		if r == ratio {
+			return true
+		}
+	}
+	return false
+}
+
+// pngThumbnail scales img to fit within maxDim and returns it as base64 PNG.
+func pngThumbnail(img image.Image, maxDim int) (string, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxDim || h > maxDim {
+		if w >= h {
+			w, h = maxDim, max(1, h*maxDim/w)
+		} else {
+			w, h = max(1, w*maxDim/h), maxDim
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
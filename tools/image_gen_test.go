@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"water-ai/core/config"
+)
+
+type stubImageBackend struct {
+	prompt, aspectRatio string
+	err                 error
+}
+
+func (s *stubImageBackend) GenerateImage(ctx context.Context, prompt, aspectRatio string) ([]byte, error) {
+	s.prompt, s.aspectRatio = prompt, aspectRatio
+	if s.err != nil {
+		return nil, s.err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 1024, 576))
+	for x := 0; x < 1024; x++ {
+		img.Set(x, 10, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes(), nil
+}
+
+func TestGenerateImageToolWritesFile(t *testing.T) {
+	workspace := t.TempDir()
+	backend := &stubImageBackend{}
+	tool := &GenerateImageTool{Backend: backend, Workspace: workspace}
+
+	out, err := tool.Run(context.Background(), ToolInput{"prompt": "a lighthouse at dusk", "aspect_ratio": "16:9"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.Error != "" {
+		t.Fatalf("Run() output error = %s", out.Error)
+	}
+	if backend.prompt != "a lighthouse at dusk" || backend.aspectRatio != "16:9" {
+		t.Errorf("backend got (%q, %q)", backend.prompt, backend.aspectRatio)
+	}
+
+	rel, _ := out.Auxiliary["path"].(string)
+	if rel == "" || filepath.IsAbs(rel) {
+		t.Fatalf("path = %q; want a relative path", rel)
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, rel))
+	if err != nil {
+		t.Fatalf("generated image not written: %v", err)
+	}
+	if cfg, err := png.DecodeConfig(bytes.NewReader(data)); err != nil || cfg.Width != 1024 {
+		t.Errorf("saved image = %+v, %v; want a 1024px wide PNG", cfg, err)
+	}
+
+	if len(out.Images) != 1 {
+		t.Fatalf("Images = %d; want 1 thumbnail", len(out.Images))
+	}
+	raw, _ := base64.StdEncoding.DecodeString(out.Images[0])
+	thumb, err := png.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || thumb.Width != imageThumbnailMaxDim || thumb.Height != 144 {
+		t.Errorf("thumbnail = %+v, %v; want 256x144", thumb, err)
+	}
+}
+
+func TestGenerateImageToolErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		tool  *GenerateImageTool
+		input ToolInput
+	}{
+		{"backend error", &GenerateImageTool{Backend: &stubImageBackend{err: errors.New("quota exceeded")}}, ToolInput{"prompt": "cat"}},
+		{"no backend", &GenerateImageTool{}, ToolInput{"prompt": "cat"}},
+		{"missing prompt", &GenerateImageTool{Backend: &stubImageBackend{}}, ToolInput{}},
+		{"bad aspect ratio", &GenerateImageTool{Backend: &stubImageBackend{}}, ToolInput{"prompt": "cat", "aspect_ratio": "2:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tool.Workspace = t.TempDir()
+			out, err := tt.tool.Run(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("Run() error = %v; want an error output", err)
+			}
+			if out.Error == "" {
+				t.Errorf("Error is empty; want a failure for %s", tt.name)
+			}
+			if entries, _ := os.ReadDir(tt.tool.Workspace); len(entries) != 0 {
+				t.Errorf("workspace has %d files; want none", len(entries))
+			}
+		})
+	}
+}
+
+func TestNewImageBackend(t *testing.T) {
+	if NewImageBackend(config.MediaConfig{}) != nil {
+		t.Error("NewImageBackend() should be nil without an API key")
+	}
+	b, ok := NewImageBackend(config.MediaConfig{GoogleAIStudioAPIKey: config.SecretPtr("key")}).(*GeminiImageBackend)
+	if !ok || b.APIKey != "key" {
+		t.Errorf("NewImageBackend() = %+v; want a GeminiImageBackend with the key", b)
+	}
+}
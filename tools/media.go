@@ -5,32 +5,6 @@ import (
 	"fmt"
 )
 
-// --- Audio Transcription Tool ---
-type AudioTranscribeTool struct {
-	Settings Settings
-}
-
-func (t *AudioTranscribeTool) Name() string        { return "audio_transcribe" }
-func (t *AudioTranscribeTool) Description() string { return "Transcribe audio file using OpenAI." }
-func (t *AudioTranscribeTool) Schema() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"file_path": map[string]string{"type": "string"},
-		},
-		"required": []string{"file_path"},
-	}
-}
-
-func (t *AudioTranscribeTool) Run(ctx context.Context, input ToolInput) (ToolResult, error) {
-	// Implementation would construct multipart/form-data request to OpenAI /v1/audio/transcriptions
-	return ToolResult{
-		Output:        "Audio transcription mock output.",
-		ResultMessage: "Audio transcribed",
-		Success:       true,
-	}, nil
-}
-
 // --- Image Generation Tool ---
 type ImageGenerateTool struct {
 	Settings Settings
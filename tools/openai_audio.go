@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// audioAPI calls an OpenAI audio endpoint, through Azure OpenAI when
+// azureEndpoint is set and the OpenAI API otherwise. Synthesizer and
+// Transcriber share it.
+type audioAPI struct {
+	apiKey          string
+	azureEndpoint   string
+	azureAPIVersion string
+	baseURL         string       // OpenAI API base, defaults to defaultOpenAIBaseURL
+	client          *http.Client // nil uses a client with timeout
+	timeout         time.Duration
+	// action names the call in errors, e.g. "transcription"
+	action string
+}
+
+// endpoint returns the URL of operation ("speech" or "transcriptions")
+// for model, which names the deployment with Azure.
+func (a audioAPI) endpoint(model, operation string) string {
+	if a.azureEndpoint != "" {
+		version := a.azureAPIVersion
+		if version == "" {
+			version = defaultAzureAPIVersion
+		}
+		return fmt.Sprintf("%s/openai/deployments/%s/audio/%s?api-version=%s",
+			strings.TrimSuffix(a.azureEndpoint, "/"), url.PathEscape(model), operation, url.QueryEscape(version))
+	}
+	base := a.baseURL
+	if base == "" {
+		base = defaultOpenAIBaseURL
+	}
+	return strings.TrimSuffix(base, "/") + "/audio/" + operation
+}
+
+// post sends body to operation for model and returns the response body.
+// Statuses other than 200 are returned as errors.
+func (a audioAPI) post(ctx context.Context, model, operation, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint(model, operation), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if a.azureEndpoint != "" {
+		req.Header.Set("api-key", a.apiKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: a.timeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", a.action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s failed with status %d: %s", a.action, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultLargeOutputThreshold is the output size (in bytes) above which a
+// tool result is written to the workspace instead of being inlined.
+const DefaultLargeOutputThreshold = 50000
+
+// ToolOutputDir is the workspace-relative directory holding offloaded outputs.
+const ToolOutputDir = ".tool_outputs"
+
+const (
+	outputPreviewLines  = 20
+	maxReadOutputLines  = 500
+	defaultReadOutLines = 200
+)
+
+// OffloadLargeOutput stores output under the workspace when it exceeds
+// threshold and returns a short reference for the history in its place.
+// Outputs at or below the threshold are returned unchanged.
+func OffloadLargeOutput(workspaceRoot, toolName, output string, threshold int) (string, bool, error) {
+	if threshold <= 0 || len(output) <= threshold {
+		return output, false, nil
+	}
+
+	dir := filepath.Join(workspaceRoot, ToolOutputDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return output, false, err
+	}
+
+	name := fmt.Sprintf("%s_%d.txt", toolName, time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(output), 0644); err != nil {
+		return output, false, err
+	}
+
+	relPath := filepath.ToSlash(filepath.Join(ToolOutputDir, name))
+	lines := strings.Split(output, "\n")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Output of %s was too large to include (%d bytes, %d lines).]\n", toolName, len(output), len(lines))
+	fmt.Fprintf(&sb, "Full output saved to: %s\n\n", relPath)
+	sb.WriteString("Preview:\n")
+	sb.WriteString(previewLines(lines))
+	fmt.Fprintf(&sb, "\n\nUse the read_tool_output tool with {\"path\": %q, \"start_line\": 1, \"end_line\": %d} to read a range (max %d lines per call).",
+		relPath, defaultReadOutLines, maxReadOutputLines)
+
+	return sb.String(), true, nil
+}
+
+// previewLines returns the head and tail of the output.
+func previewLines(lines []string) string {
+	clip := func(s string) string {
+		if len(s) > 200 {
+			return s[:200] + "..."
+		}
+		return s
+	}
+
+	var out []string
+	if len(lines) <= 2*outputPreviewLines {
+		for _, l := range lines {
+			out = append(out, clip(l))
+		}
+		return strings.Join(out, "\n")
+	}
+	for _, l := range lines[:outputPreviewLines] {
+		out = append(out, clip(l))
+	}
+	out = append(out, fmt.Sprintf("... [%d lines omitted] ...", len(lines)-2*outputPreviewLines))
+	for _, l := range lines[len(lines)-outputPreviewLines:] {
+		out = append(out, clip(l))
+	}
+	return strings.Join(out, "\n")
+}
+
+// --- Read Tool Output Tool ---
+
+// ReadToolOutputTool reads line ranges from outputs saved by OffloadLargeOutput.
+type ReadToolOutputTool struct {
+	WorkspaceRoot string
+}
+
+func (t *ReadToolOutputTool) Name() string { return "read_tool_output" }
+func (t *ReadToolOutputTool) Description() string {
+	return "Read a line range from a large tool output that was saved to the workspace."
+}
+func (t *ReadToolOutputTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path":       map[string]string{"type": "string", "description": "Path returned in the tool output reference"},
+			"start_line": map[string]string{"type": "integer", "description": "First line to read (1-based)"},
+			"end_line":   map[string]string{"type": "integer", "description": "Last line to read (inclusive)"},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ReadToolOutputTool) Run(ctx context.Context, input ToolInput) (ToolResult, error) {
+	relPath, err := GetArg[string](input, "path")
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	outputDir := filepath.Join(t.WorkspaceRoot, ToolOutputDir)
+	fullPath := filepath.Join(t.WorkspaceRoot, filepath.FromSlash(relPath))
+	if !strings.HasPrefix(fullPath, outputDir+string(filepath.Separator)) {
+		return ToolResult{Output: "Access denied: path is not a saved tool output", Success: false}, nil
+	}
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return ToolResult{Output: fmt.Sprintf("Error: %v", err), Success: false}, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+	start, _ := GetArg[int](input, "start_line")
+	end, _ := GetArg[int](input, "end_line")
+	if start < 1 {
+		start = 1
+	}
+	if end < start {
+		end = start + defaultReadOutLines - 1
+	}
+	if end-start+1 > maxReadOutputLines {
+		end = start + maxReadOutputLines - 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if start > len(lines) {
+		return ToolResult{Output: fmt.Sprintf("start_line %d is past the end of the output (%d lines)", start, len(lines)), Success: false}, nil
+	}
+
+	return ToolResult{
+		Output:        strings.Join(lines[start-1:end], "\n"),
+		ResultMessage: fmt.Sprintf("Lines %d-%d of %d", start, end, len(lines)),
+		Success:       true,
+	}, nil
+}
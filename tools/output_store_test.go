@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoTool returns its "text" argument as output.
+type echoTool struct{}
+
+func (e *echoTool) Name() string                   { return "echo" }
+func (e *echoTool) Description() string            { return "Echo input" }
+func (e *echoTool) Schema() map[string]interface{} { return nil }
+func (e *echoTool) Run(ctx context.Context, input ToolInput) (ToolResult, error) {
+	text, _ := input["text"].(string)
+	return ToolResult{Output: text, Success: true}, nil
+}
+
+func bigOutput(lines int) string {
+	var sb strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&sb, "line %d of the log\n", i)
+	}
+	return sb.String()
+}
+
+func TestOffloadLargeOutputSmallStaysInline(t *testing.T) {
+	dir := t.TempDir()
+	out, offloaded, err := OffloadLargeOutput(dir, "bash", "hello", 100)
+	if err != nil {
+		t.Fatalf("OffloadLargeOutput() error = %v", err)
+	}
+	if offloaded || out != "hello" {
+		t.Errorf("OffloadLargeOutput() = %q, %v; want inline output", out, offloaded)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ToolOutputDir)); !os.IsNotExist(err) {
+		t.Error("no artifact directory should be created for small outputs")
+	}
+}
+
+func TestOffloadLargeOutputBecomesReference(t *testing.T) {
+	dir := t.TempDir()
+	output := bigOutput(1000)
+
+	ref, offloaded, err := OffloadLargeOutput(dir, "bash", output, 1000)
+	if err != nil {
+		t.Fatalf("OffloadLargeOutput() error = %v", err)
+	}
+	if !offloaded {
+		t.Fatal("large output should be offloaded")
+	}
+	if len(ref) >= len(output) {
+		t.Errorf("reference length %d should be smaller than output %d", len(ref), len(output))
+	}
+	if !strings.Contains(ref, "read_tool_output") || !strings.Contains(ref, ToolOutputDir+"/") {
+		t.Errorf("reference should include path and read instructions: %s", ref)
+	}
+	if !strings.Contains(ref, "line 1 of the log") || !strings.Contains(ref, "line 1000 of the log") {
+		t.Error("reference should preview the head and tail of the output")
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, ToolOutputDir))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one saved artifact, got %v (err %v)", entries, err)
+	}
+	saved, _ := os.ReadFile(filepath.Join(dir, ToolOutputDir, entries[0].Name()))
+	if string(saved) != output {
+		t.Error("saved artifact should contain the full output")
+	}
+}
+
+func TestManagerExecuteToolOffloadsLargeResults(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Settings{WorkspaceRoot: dir, LargeOutputThreshold: 500})
+	m.Register(&echoTool{})
+
+	small, err := m.ExecuteTool(context.Background(), "echo", `{"text": "short"}`)
+	if err != nil || small.Output != "short" {
+		t.Errorf("small result = %q, %v; want inline 'short'", small.Output, err)
+	}
+
+	large := bigOutput(300)
+	raw := fmt.Sprintf(`{"text": %q}`, large)
+	res, err := m.ExecuteTool(context.Background(), "echo", raw)
+	if err != nil {
+		t.Fatalf("ExecuteTool() error = %v", err)
+	}
+	if res.Output == large || !strings.Contains(res.Output, "Full output saved to:") {
+		t.Fatalf("large result should be replaced by a reference, got %d bytes", len(res.Output))
+	}
+	if res.AuxiliaryData["original_size"] != len(large) {
+		t.Errorf("original_size = %v; want %d", res.AuxiliaryData["original_size"], len(large))
+	}
+
+	// The model can page through the saved output.
+	var path string
+	for _, line := range strings.Split(res.Output, "\n") {
+		if strings.HasPrefix(line, "Full output saved to: ") {
+			path = strings.TrimPrefix(line, "Full output saved to: ")
+		}
+	}
+	page, err := m.ExecuteTool(context.Background(), "read_tool_output",
+		fmt.Sprintf(`{"path": %q, "start_line": 10, "end_line": 12}`, path))
+	if err != nil {
+		t.Fatalf("read_tool_output error = %v", err)
+	}
+	want := "line 10 of the log\nline 11 of the log\nline 12 of the log"
+	if page.Output != want {
+		t.Errorf("read_tool_output = %q; want %q", page.Output, want)
+	}
+}
+
+func TestManagerOffloadDisabled(t *testing.T) {
+	m := NewManager(Settings{WorkspaceRoot: t.TempDir(), LargeOutputThreshold: -1})
+	m.Register(&echoTool{})
+
+	large := bigOutput(5000)
+	res, err := m.ExecuteTool(context.Background(), "echo", fmt.Sprintf(`{"text": %q}`, large))
+	if err != nil || res.Output != large {
+		t.Error("offloading should be disabled with a negative threshold")
+	}
+}
+
+func TestReadToolOutputRejectsOtherPaths(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644)
+	tool := &ReadToolOutputTool{WorkspaceRoot: dir}
+
+	for _, p := range []string{"secret.txt", ToolOutputDir + "/../secret.txt"} {
+		res, err := tool.Run(context.Background(), ToolInput{"path": p})
+		if err != nil {
+			t.Fatalf("Run(%q) error = %v", p, err)
+		}
+		if res.Success || strings.Contains(res.Output, "secret") && !strings.Contains(res.Output, "denied") {
+			t.Errorf("Run(%q) = %+v; want access denied", p, res)
+		}
+	}
+}
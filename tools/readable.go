@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// skippedElements hold no readable content.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "iframe": true, "nav": true, "footer": true,
+	"form": true, "button": true,
+}
+
+// blockElements start a new paragraph in the extracted text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "aside": true, "blockquote": true, "table": true, "tr": true,
+	"ul": true, "ol": true, "dl": true, "dt": true, "dd": true, "figure": true,
+	"figcaption": true, "hr": true,
+}
+
+var (
+	inlineSpace = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLines  = regexp.MustCompile(`\n[ \t]*\n(\s*\n)+`)
+)
+
+// htmlToMarkdown extracts the title and readable text of an HTML document
+// as lightweight markdown. Relative links are resolved against base.
+func htmlToMarkdown(r io.Reader, base *url.URL) (title, text string, err error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if pre {
+				b.WriteString(n.Data)
+			} else {
+				b.WriteString(inlineSpace.ReplaceAllString(strings.ReplaceAll(n.Data, "\n", " "), " "))
+			}
+			return
+		case html.ElementNode:
+			if n.Data == "title" {
+				if title == "" {
+					title = strings.TrimSpace(nodeText(n))
+				}
+				return
+			}
+			if skippedElements[n.Data] {
+				return
+			}
+			switch n.Data {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				b.WriteString("\n\n" + strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+			case "li":
+				b.WriteString("\n- ")
+			case "br":
+				b.WriteString("\n")
+			case "pre":
+				b.WriteString("\n\n```\n")
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					walk(c, true)
+				}
+				b.WriteString("\n```\n\n")
+				return
+			case "a":
+				if href := attr(n, "href"); href != "" && !pre {
+					label := strings.TrimSpace(inlineSpace.ReplaceAllString(nodeText(n), " "))
+					if label != "" {
+						b.WriteString("[" + label + "](" + resolveLink(base, href) + ")")
+					}
+					return
+				}
+			default:
+				if blockElements[n.Data] {
+					b.WriteString("\n\n")
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+	}
+	walk(doc, false)
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return title, strings.TrimSpace(text), nil
+}
+
+// nodeText returns the concatenated text content of n.
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func resolveLink(base *url.URL, href string) string {
+	ref, err := url.Parse(href)
+	if err != nil || base == nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
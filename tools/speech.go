@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"water-ai/core/config"
+)
+
+const (
+	// DefaultSpeechModel and DefaultSpeechVoice are used when the
+	// Synthesizer leaves them empty. With Azure the model names the
+	// deployment.
+	DefaultSpeechModel = "tts-1"
+	DefaultSpeechVoice = "alloy"
+	// SpeechDir is the workspace directory synthesized audio is saved in.
+	SpeechDir = "speech"
+	// maxSpeechInput is the longest text the speech endpoint accepts.
+	maxSpeechInput = 4096
+)
+
+// Synthesizer turns text into speech, through Azure OpenAI when
+// AzureEndpoint is set and the OpenAI API otherwise.
+type Synthesizer struct {
+	APIKey          string
+	AzureEndpoint   string
+	AzureAPIVersion string
+	Model           string  // empty uses DefaultSpeechModel
+	Voice           string  // empty uses DefaultSpeechVoice
+	Speed           float64 // 0 uses the provider default
+	BaseURL         string  // OpenAI API base, defaults to https://api.openai.com/v1
+	Client          *http.Client
+}
+
+// NewSynthesizer returns the synthesizer configured in audio, or nil when
+// text-to-speech is disabled or no API key is set.
+func NewSynthesizer(audio config.AudioConfig) *Synthesizer {
+	if !audio.TTSEnabled || audio.OpenAIAPIKey == nil || audio.OpenAIAPIKey.Reveal() == "" {
+		return nil
+	}
+	s := &Synthesizer{APIKey: audio.OpenAIAPIKey.Reveal(), Voice: audio.TTSVoice, Speed: audio.TTSSpeed}
+	if audio.AzureEndpoint != nil {
+		s.AzureEndpoint = *audio.AzureEndpoint
+	}
+	if audio.AzureAPIVersion != nil {
+		s.AzureAPIVersion = *audio.AzureAPIVersion
+	}
+	return s
+}
+
+// Synthesize returns text spoken as MP3. Text beyond the endpoint's input
+// limit is dropped.
+func (s *Synthesizer) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	if r := []rune(text); len(r) > maxSpeechInput {
+		text = string(r[:maxSpeechInput])
+	}
+	model := s.Model
+	if model == "" {
+		model = DefaultSpeechModel
+	}
+	voice := s.Voice
+	if voice == "" {
+		voice = DefaultSpeechVoice
+	}
+	body := map[string]interface{}{"model": model, "input": text, "voice": voice, "response_format": "mp3"}
+	if s.Speed != 0 {
+		body["speed"] = s.Speed
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	api := audioAPI{
+		apiKey:          s.APIKey,
+		azureEndpoint:   s.AzureEndpoint,
+		azureAPIVersion: s.AzureAPIVersion,
+		baseURL:         s.BaseURL,
+		client:          s.Client,
+		timeout:         2 * time.Minute,
+		action:          "speech synthesis",
+	}
+	return api.post(ctx, model, "speech", "application/json", bytes.NewReader(data))
+}
+
+// SaveSpeech synthesizes text into an MP3 under SpeechDir in workspace and
+// returns its workspace-relative path.
+func (s *Synthesizer) SaveSpeech(ctx context.Context, workspace, text string) (string, error) {
+	audio, err := s.Synthesize(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(workspace, SpeechDir), 0755); err != nil {
+		return "", err
+	}
+	relPath := filepath.Join(SpeechDir, fmt.Sprintf("response_%s_%s.mp3", time.Now().Format("20060102_150405"), uuid.NewString()[:8]))
+	if err := os.WriteFile(filepath.Join(workspace, relPath), audio, 0644); err != nil {
+		return "", fmt.Errorf("failed to save speech: %w", err)
+	}
+	return relPath, nil
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"water-ai/core/config"
+)
+
+func TestSynthesizerSaveSpeech(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request = %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("ID3 mp3 data"))
+	}))
+	defer srv.Close()
+
+	workspace := t.TempDir()
+	s := &Synthesizer{APIKey: "sk-test", BaseURL: srv.URL, Voice: "nova", Speed: 1.25}
+	rel, err := s.SaveSpeech(context.Background(), workspace, "The build is green.")
+	if err != nil {
+		t.Fatalf("SaveSpeech() error = %v", err)
+	}
+	if !strings.HasPrefix(rel, SpeechDir+string(filepath.Separator)) || filepath.Ext(rel) != ".mp3" {
+		t.Errorf("path = %q; want an mp3 under %s", rel, SpeechDir)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, rel)); err != nil || string(data) != "ID3 mp3 data" {
+		t.Errorf("saved audio = %q, %v", data, err)
+	}
+	if got["input"] != "The build is green." || got["voice"] != "nova" || got["speed"] != 1.25 || got["model"] != DefaultSpeechModel {
+		t.Errorf("request body = %v", got)
+	}
+}
+
+func TestNewSynthesizerRequiresFlagAndKey(t *testing.T) {
+	key := config.SecretPtr("sk-test")
+	if NewSynthesizer(config.AudioConfig{OpenAIAPIKey: key}) != nil {
+		t.Error("NewSynthesizer() with TTS disabled is not nil")
+	}
+	if NewSynthesizer(config.AudioConfig{TTSEnabled: true}) != nil {
+		t.Error("NewSynthesizer() without a key is not nil")
+	}
+	if s := NewSynthesizer(config.AudioConfig{OpenAIAPIKey: key, TTSEnabled: true, TTSVoice: "echo"}); s == nil || s.Voice != "echo" {
+		t.Errorf("NewSynthesizer() = %+v; want a synthesizer with voice echo", s)
+	}
+}
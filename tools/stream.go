@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+)
+
+// OutputSink receives tool output line by line while a tool is running.
+// stream is "stdout" or "stderr".
+type OutputSink func(stream, line string)
+
+type outputSinkKey struct{}
+
+// WithOutputSink returns a context that streams tool output to sink.
+func WithOutputSink(ctx context.Context, sink OutputSink) context.Context {
+	return context.WithValue(ctx, outputSinkKey{}, sink)
+}
+
+// OutputSinkFrom returns the sink installed with WithOutputSink, or nil.
+func OutputSinkFrom(ctx context.Context) OutputSink {
+	sink, _ := ctx.Value(outputSinkKey{}).(OutputSink)
+	return sink
+}
+
+// combinedOutput collects stdout and stderr in arrival order.
+type combinedOutput struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (c *combinedOutput) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf.WriteString(line)
+	c.buf.WriteByte('\n')
+}
+
+func (c *combinedOutput) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+// lineWriter splits a command's output stream into lines, recording each
+// in the combined output and forwarding it to the sink.
+type lineWriter struct {
+	stream  string
+	out     *combinedOutput
+	sink    OutputSink
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(data[:i]))
+		data = data[i+1:]
+	}
+	w.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// flush emits a trailing line that had no newline.
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *lineWriter) emit(line string) {
+	line = strings.TrimSuffix(line, "\r")
+	w.out.add(line)
+	if w.sink != nil {
+		w.sink(w.stream, line)
+	}
+}
@@ -7,15 +7,32 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"water-ai/core/config"
+	"water-ai/utils"
 )
 
 // --- File System Tools ---
 
 type FileEditorTool struct {
 	BaseDir string
+	// Editor applies edits and keeps their undo history; nil creates one on
+	// first use.
+	Editor *utils.StrReplaceManager
+
+	editorOnce sync.Once
+}
+
+func (t *FileEditorTool) editor() *utils.StrReplaceManager {
+	t.editorOnce.Do(func() {
+		if t.Editor == nil {
+			t.Editor = utils.NewStrReplaceManager(false, false)
+		}
+	})
+	return t.Editor
 }
 
 func (t *FileEditorTool) Name() string { return "file_editor" }
@@ -24,11 +41,18 @@ func (t *FileEditorTool) InputSchema() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"action":   map[string]interface{}{"type": "string", "enum": []string{"read", "write", "str_replace"}},
-			"path":     map[string]interface{}{"type": "string"},
-			"content":  map[string]interface{}{"type": "string"},
-			"old_str":  map[string]interface{}{"type": "string"},
-			"new_str":  map[string]interface{}{"type": "string"},
+			"action":      map[string]interface{}{"type": "string", "enum": []string{"read", "view", "write", "str_replace", "insert"}},
+			"path":        map[string]interface{}{"type": "string"},
+			"content":     map[string]interface{}{"type": "string"},
+			"old_str":     map[string]interface{}{"type": "string"},
+			"new_str":     map[string]interface{}{"type": "string"},
+			"occurrence":  map[string]interface{}{"type": "integer", "description": "Replace only the Nth match of old_str, 1-based (str_replace only)"},
+			"replace_all": map[string]interface{}{"type": "boolean", "description": "Replace every match of old_str (str_replace only)"},
+			"dry_run":     map[string]interface{}{"type": "boolean", "description": "Return the diff without changing the file (str_replace only)"},
+			"start_line":  map[string]interface{}{"type": "integer", "description": "First line to view (1-based, view only)"},
+			"end_line":    map[string]interface{}{"type": "integer", "description": "Last line to view, inclusive; -1 for end of file (view only)"},
+			"line":        map[string]interface{}{"type": "integer", "description": "Insert text after this 1-based line; 0 inserts at the start (insert only)"},
+			"text":        map[string]interface{}{"type": "string", "description": "Text to insert (insert only)"},
 		},
 		"required": []string{"action", "path"},
 	}
@@ -39,9 +63,9 @@ func (t *FileEditorTool) Run(ctx context.Context, input ToolInput) (*ToolOutput,
 	relPath, _ := GetArg[string](input, "path")
 	
 	// Security: Prevent directory traversal
-	fullPath := filepath.Join(t.BaseDir, relPath)
-	if !strings.HasPrefix(fullPath, t.BaseDir) {
-		return ErrorOutput(fmt.Errorf("access denied to path outside workspace")), nil
+	fullPath, err := utils.ResolveInRoot(t.BaseDir, relPath)
+	if err != nil {
+		return ErrorOutput(err), nil
 	}
 
 	switch action {
@@ -52,6 +76,19 @@ func (t *FileEditorTool) Run(ctx context.Context, input ToolInput) (*ToolOutput,
 		}
 		return &ToolOutput{Text: string(data)}, nil
 
+	case "view":
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			return ErrorOutput(err), nil
+		}
+		startLine, _ := GetArg[int](input, "start_line")
+		endLine, _ := GetArg[int](input, "end_line")
+		text, err := numberLines(string(data), startLine, endLine)
+		if err != nil {
+			return ErrorOutput(err), nil
+		}
+		return &ToolOutput{Text: text}, nil
+
 	case "write":
 		content, _ := GetArg[string](input, "content")
 		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
@@ -62,32 +99,109 @@ func (t *FileEditorTool) Run(ctx context.Context, input ToolInput) (*ToolOutput,
 	case "str_replace":
 		oldStr, _ := GetArg[string](input, "old_str")
 		newStr, _ := GetArg[string](input, "new_str")
-		
-		data, err := os.ReadFile(fullPath)
-		if err != nil { return ErrorOutput(err), nil }
-		
-		content := string(data)
-		if strings.Count(content, oldStr) > 1 {
-			return ErrorOutput(fmt.Errorf("multiple occurrences of old_str found, please be more specific")), nil
+		occurrence, _ := GetArg[int](input, "occurrence")
+		replaceAll, _ := GetArg[bool](input, "replace_all")
+		dryRun, _ := GetArg[bool](input, "dry_run")
+
+		resp := t.editor().StrReplaceWith(fullPath, oldStr, newStr, utils.StrReplaceOptions{
+			Occurrence: occurrence,
+			ReplaceAll: replaceAll,
+			DryRun:     dryRun,
+		})
+		if !resp.Success {
+			return ErrorOutput(fmt.Errorf("%s", resp.FileContent)), nil
 		}
-		if !strings.Contains(content, oldStr) {
-			return ErrorOutput(fmt.Errorf("old_str not found in file")), nil
+		if resp.DryRun {
+			return &ToolOutput{
+				Text:      fmt.Sprintf("%s Preview of the edit to %s:\n%s", utils.DryRunNotice, relPath, resp.Diff),
+				Auxiliary: map[string]interface{}{"dry_run": true, "diff": resp.Diff},
+			}, nil
 		}
-		
-		newContent := strings.Replace(content, oldStr, newStr, 1)
-		if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
+		return &ToolOutput{Text: "File patched successfully."}, nil
+
+	case "insert":
+		line, err := GetArg[int](input, "line")
+		if err != nil {
 			return ErrorOutput(err), nil
 		}
-		return &ToolOutput{Text: "File patched successfully."}, nil
+		text, err := GetArg[string](input, "text")
+		if err != nil {
+			return ErrorOutput(err), nil
+		}
+		resp := t.editor().Insert(fullPath, line, text)
+		if !resp.Success {
+			return ErrorOutput(fmt.Errorf("%s", resp.FileContent)), nil
+		}
+		return &ToolOutput{Text: fmt.Sprintf("Inserted text into %s:\n%s", relPath, resp.FileContent)}, nil
 	}
 
 	return ErrorOutput(fmt.Errorf("unknown action")), nil
 }
 
+// numberLines renders lines start..end (1-based, inclusive) of content
+// prefixed with their line numbers. A zero start means the first line and a
+// zero or -1 end means the last. Output longer than utils.MaxResponseLen is
+// cut at a line boundary and marked with utils.TruncatedMessage.
+func numberLines(content string, start, end int) (string, error) {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 || end == -1 {
+		end = len(lines)
+	}
+	if len(lines) == 0 {
+		if start == 1 && end == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("invalid range [%d, %d]: file is empty", start, end)
+	}
+	if start < 1 || start > len(lines) {
+		return "", fmt.Errorf("invalid start_line %d: should be between 1 and %d", start, len(lines))
+	}
+	if end > len(lines) {
+		return "", fmt.Errorf("invalid end_line %d: should not exceed the number of lines in the file (%d)", end, len(lines))
+	}
+	if end < start {
+		return "", fmt.Errorf("invalid range [%d, %d]: end_line should not be less than start_line", start, end)
+	}
+
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		line := fmt.Sprintf("%6d\t%s\n", i, lines[i-1])
+		if b.Len()+len(line) > utils.MaxResponseLen {
+			b.WriteString(utils.TruncatedMessage)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
 // --- Terminal Tools ---
 
+// terminalKillGrace bounds how long Run waits for output pipes to close
+// after the process group has been killed.
+const terminalKillGrace = 2 * time.Second
+
+// CommandExecutor runs a shell command somewhere other than the host, such
+// as *sandbox.DockerWorkspace in its container.
+type CommandExecutor interface {
+	Execute(ctx context.Context, command string) utils.SessionResult
+}
+
+// TerminalTool runs shell commands in WorkDir. Each call is bounded by the
+// "timeout" argument (seconds) or, when absent, Config.DefaultTimeout.
+// Output lines are streamed to the OutputSink in the context, if any.
 type TerminalTool struct {
 	WorkDir string
+	Config  *config.ClientConfig // nil uses config.NewClientConfig()
+	// Executor, when set, runs commands instead of the host shell in
+	// WorkDir; their output is not streamed.
+	Executor CommandExecutor
 }
 
 func (t *TerminalTool) Name() string { return "terminal_execute" }
@@ -97,34 +211,70 @@ func (t *TerminalTool) InputSchema() map[string]interface{} {
 		"type": "object",
 		"properties": map[string]interface{}{
 			"command": map[string]string{"type": "string"},
-			"timeout": map[string]string{"type": "integer"},
+			"timeout": map[string]string{"type": "integer", "description": "Timeout in seconds"},
 		},
 		"required": []string{"command"},
 	}
 }
 
+func (t *TerminalTool) clientConfig() config.ClientConfig {
+	if t.Config != nil {
+		return *t.Config
+	}
+	return config.NewClientConfig()
+}
+
 func (t *TerminalTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
 	cmdStr, _ := GetArg[string](input, "command")
+	cfg := t.clientConfig()
+
 	timeoutSec, _ := GetArg[int](input, "timeout")
-	if timeoutSec == 0 { timeoutSec = 30 }
+	if timeoutSec <= 0 {
+		timeoutSec = cfg.DefaultTimeout
+	}
+	shell := cfg.DefaultShell
+	if shell == "" {
+		shell = "/bin/bash"
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSec)*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", cmdStr)
+	if t.Executor != nil {
+		res := t.Executor.Execute(ctx, cmdStr)
+		resultText := res.Output
+		if ctx.Err() == context.DeadlineExceeded {
+			resultText += fmt.Sprintf("\n[Error: Command timed out after %ds; output above is partial]", timeoutSec)
+		}
+		return &ToolOutput{Text: resultText}, nil
+	}
+
+	var output combinedOutput
+	sink := OutputSinkFrom(ctx)
+	stdout := &lineWriter{stream: "stdout", out: &output, sink: sink}
+	stderr := &lineWriter{stream: "stderr", out: &output, sink: sink}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", cmdStr)
 	cmd.Dir = t.WorkDir
-	
-	output, err := cmd.CombinedOutput()
-	resultText := string(output)
-	
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = terminalKillGrace
+
+	err := cmd.Run()
+	stdout.flush()
+	stderr.flush()
+
+	resultText := output.String()
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			resultText += "\n[Error: Command timed out]"
+			resultText += fmt.Sprintf("\n[Error: Command timed out after %ds; output above is partial]", timeoutSec)
 		} else {
 			resultText += fmt.Sprintf("\n[Error: %v]", err)
 		}
 	}
-	
+
 	return &ToolOutput{Text: resultText}, nil
 }
 
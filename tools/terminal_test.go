@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"water-ai/utils"
+)
+
+func TestTerminalToolQuickCommand(t *testing.T) {
+	tool := &TerminalTool{WorkDir: t.TempDir()}
+	out, err := tool.Run(context.Background(), ToolInput{"command": "echo hello"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.Text != "hello\n" {
+		t.Errorf("Text = %q; want %q", out.Text, "hello\n")
+	}
+}
+
+// recordingExecutor answers every command with output.
+type recordingExecutor struct {
+	commands []string
+	output   string
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, command string) utils.SessionResult {
+	e.commands = append(e.commands, command)
+	return utils.SessionResult{Success: true, Output: e.output}
+}
+
+func TestTerminalToolRunsThroughExecutor(t *testing.T) {
+	ex := &recordingExecutor{output: "in the container\n"}
+	tool := &TerminalTool{WorkDir: t.TempDir(), Executor: ex}
+	out, err := tool.Run(context.Background(), ToolInput{"command": "uname -a"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(ex.commands) != 1 || ex.commands[0] != "uname -a" || out.Text != "in the container\n" {
+		t.Errorf("commands = %q, Text = %q; want the command run by the executor", ex.commands, out.Text)
+	}
+}
+
+func TestTerminalToolTimeout(t *testing.T) {
+	tool := &TerminalTool{WorkDir: t.TempDir()}
+	start := time.Now()
+	out, err := tool.Run(context.Background(), ToolInput{
+		"command": "echo started; sleep 30",
+		"timeout": float64(1),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %v; want the command killed after ~1s", elapsed)
+	}
+	if !strings.HasPrefix(out.Text, "started\n") {
+		t.Errorf("Text = %q; want partial output before the timeout", out.Text)
+	}
+	if !strings.Contains(out.Text, "timed out after 1s") {
+		t.Errorf("Text = %q; want a timeout note", out.Text)
+	}
+}
+
+func TestTerminalToolStreamsInterleavedOutput(t *testing.T) {
+	type line struct{ stream, text string }
+	var (
+		mu    sync.Mutex
+		lines []line
+	)
+	ctx := WithOutputSink(context.Background(), func(stream, text string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line{stream, text})
+	})
+
+	tool := &TerminalTool{WorkDir: t.TempDir()}
+	out, err := tool.Run(ctx, ToolInput{
+		"command": "echo out1; sleep 0.1; echo err1 >&2; sleep 0.1; printf out2",
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []line{{"stdout", "out1"}, {"stderr", "err1"}, {"stdout", "out2"}}
+	if len(lines) != len(want) {
+		t.Fatalf("streamed %v; want %v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %v; want %v", i, lines[i], want[i])
+		}
+	}
+	if out.Text != "out1\nerr1\nout2\n" {
+		t.Errorf("Text = %q; want combined output in order", out.Text)
+	}
+}
+
+func TestOutputSinkFromEmptyContext(t *testing.T) {
+	if OutputSinkFrom(context.Background()) != nil {
+		t.Error("OutputSinkFrom() should be nil without WithOutputSink")
+	}
+}
+
+func writeNumberedFile(t *testing.T, lines int) *FileEditorTool {
+	t.Helper()
+	dir := t.TempDir()
+	var b strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &FileEditorTool{BaseDir: dir}
+}
+
+func TestFileEditorRefusesSymlinksOutOfWorkspace(t *testing.T) {
+	tool := writeNumberedFile(t, 1)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(tool.BaseDir, "out")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	out, _ := tool.Run(context.Background(), ToolInput{"action": "read", "path": "out/secret"})
+	if out.Error == "" || out.Text == "secret" {
+		t.Errorf("read through symlink = %q; want it refused", out.Text)
+	}
+	out, _ = tool.Run(context.Background(), ToolInput{"action": "write", "path": "out/planted", "content": "x"})
+	if out.Error == "" {
+		t.Errorf("write through symlink = %q; want it refused", out.Text)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "planted")); err == nil {
+		t.Error("write through symlink created a file outside the workspace")
+	}
+}
+
+func TestFileEditorViewFullFile(t *testing.T) {
+	tool := writeNumberedFile(t, 3)
+	out, _ := tool.Run(context.Background(), ToolInput{"action": "view", "path": "f.txt"})
+
+	want := "     1\tline 1\n     2\tline 2\n     3\tline 3\n"
+	if out.Text != want {
+		t.Errorf("Text = %q; want %q", out.Text, want)
+	}
+}
+
+func TestFileEditorViewRange(t *testing.T) {
+	tool := writeNumberedFile(t, 10)
+	out, _ := tool.Run(context.Background(), ToolInput{
+		"action": "view", "path": "f.txt", "start_line": float64(4), "end_line": float64(6),
+	})
+
+	want := "     4\tline 4\n     5\tline 5\n     6\tline 6\n"
+	if out.Text != want {
+		t.Errorf("Text = %q; want %q", out.Text, want)
+	}
+
+	out, _ = tool.Run(context.Background(), ToolInput{
+		"action": "view", "path": "f.txt", "start_line": float64(9), "end_line": float64(-1),
+	})
+	if out.Text != "     9\tline 9\n    10\tline 10\n" {
+		t.Errorf("Text = %q; want lines 9-10", out.Text)
+	}
+}
+
+func TestFileEditorViewInvalidRange(t *testing.T) {
+	tool := writeNumberedFile(t, 5)
+	tests := []struct {
+		name       string
+		start, end float64
+		wantErr    string
+	}{
+		{"start after end", 4, 2, "end_line should not be less than start_line"},
+		{"start past EOF", 6, 0, "should be between 1 and 5"},
+		{"end past EOF", 1, 9, "should not exceed the number of lines"},
+		{"negative start", -3, 2, "should be between 1 and 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := tool.Run(context.Background(), ToolInput{
+				"action": "view", "path": "f.txt", "start_line": tt.start, "end_line": tt.end,
+			})
+			if !strings.Contains(out.Error, tt.wantErr) {
+				t.Errorf("Error = %q; want it to contain %q", out.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNumberLinesTruncatesHugeRanges(t *testing.T) {
+	content := strings.Repeat(strings.Repeat("x", 100)+"\n", utils.MaxResponseLen/100+10)
+	got, err := numberLines(content, 0, 0)
+	if err != nil {
+		t.Fatalf("numberLines() error = %v", err)
+	}
+	if !strings.HasSuffix(got, utils.TruncatedMessage) {
+		t.Error("huge view should end with the truncation message")
+	}
+	if len(got) > utils.MaxResponseLen+len(utils.TruncatedMessage) {
+		t.Errorf("len = %d; want at most %d", len(got), utils.MaxResponseLen+len(utils.TruncatedMessage))
+	}
+}
+
+func TestFileEditorInsert(t *testing.T) {
+	tool := writeNumberedFile(t, 3)
+	out, _ := tool.Run(context.Background(), ToolInput{
+		"action": "insert", "path": "f.txt", "line": float64(1), "text": "inserted",
+	})
+	if out.Error != "" {
+		t.Fatalf("insert failed: %s", out.Error)
+	}
+	if !strings.Contains(out.Text, "     2 + inserted") {
+		t.Errorf("Text = %q; want a snippet marking the inserted line", out.Text)
+	}
+	data, _ := os.ReadFile(filepath.Join(tool.BaseDir, "f.txt"))
+	if string(data) != "line 1\ninserted\nline 2\nline 3\n" {
+		t.Errorf("content = %q", data)
+	}
+	if len(tool.Editor.History[filepath.Join(tool.BaseDir, "f.txt")]) != 1 {
+		t.Error("insert should record undo history")
+	}
+}
+
+func TestFileEditorDryRun(t *testing.T) {
+	tool := writeNumberedFile(t, 3)
+	out, _ := tool.Run(context.Background(), ToolInput{
+		"action": "str_replace", "path": "f.txt", "old_str": "line 2", "new_str": "second", "dry_run": true,
+	})
+	if out.Error != "" {
+		t.Fatalf("dry run failed: %s", out.Error)
+	}
+	if !strings.HasPrefix(out.Text, utils.DryRunNotice) || !strings.Contains(out.Text, "-line 2\n+second") {
+		t.Errorf("Text = %q; want a dry-run notice and diff", out.Text)
+	}
+	if out.Auxiliary["dry_run"] != true {
+		t.Errorf("Auxiliary = %v; want dry_run true", out.Auxiliary)
+	}
+	data, _ := os.ReadFile(filepath.Join(tool.BaseDir, "f.txt"))
+	if string(data) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("content = %q; want it unchanged", data)
+	}
+	if len(tool.Editor.History[filepath.Join(tool.BaseDir, "f.txt")]) != 0 {
+		t.Error("dry run should not record undo history")
+	}
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"water-ai/core/config"
+	"water-ai/utils"
+)
+
+const (
+	// DefaultTranscriptionModel is the Whisper model, or Azure deployment,
+	// used when Transcriber.Model is empty.
+	DefaultTranscriptionModel  = "whisper-1"
+	defaultOpenAIBaseURL       = "https://api.openai.com/v1"
+	defaultAzureAPIVersion     = "2024-06-01"
+	maxTranscriptionAudioBytes = 25 << 20
+)
+
+// ErrUnsupportedAudioFormat is returned for files Whisper cannot decode.
+var ErrUnsupportedAudioFormat = errors.New("unsupported audio format")
+
+// SupportedAudioFormats are the file extensions Whisper accepts.
+var SupportedAudioFormats = []string{".flac", ".m4a", ".mp3", ".mp4", ".mpeg", ".mpga", ".oga", ".ogg", ".wav", ".webm"}
+
+// Transcriber turns speech into text with Whisper, through Azure OpenAI
+// when AzureEndpoint is set and the OpenAI API otherwise.
+type Transcriber struct {
+	APIKey          string
+	AzureEndpoint   string
+	AzureAPIVersion string
+	Model           string // empty uses DefaultTranscriptionModel
+	BaseURL         string // OpenAI API base, defaults to https://api.openai.com/v1
+	Client          *http.Client
+}
+
+// NewTranscriber returns the transcriber configured in audio, or nil when
+// no API key is set.
+func NewTranscriber(audio config.AudioConfig) *Transcriber {
+	if audio.OpenAIAPIKey == nil || audio.OpenAIAPIKey.Reveal() == "" {
+		return nil
+	}
+	t := &Transcriber{APIKey: audio.OpenAIAPIKey.Reveal()}
+	if audio.AzureEndpoint != nil {
+		t.AzureEndpoint = *audio.AzureEndpoint
+	}
+	if audio.AzureAPIVersion != nil {
+		t.AzureAPIVersion = *audio.AzureAPIVersion
+	}
+	return t
+}
+
+// CheckAudioFormat returns ErrUnsupportedAudioFormat unless filename has
+// an extension Whisper accepts.
+func CheckAudioFormat(filename string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, f := range SupportedAudioFormats {
+		if ext == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q: use one of %s", ErrUnsupportedAudioFormat, ext, strings.Join(SupportedAudioFormats, ", "))
+}
+
+// Transcribe returns the text spoken in data, an audio file named filename.
+func (t *Transcriber) Transcribe(ctx context.Context, filename string, data []byte) (string, error) {
+	if err := CheckAudioFormat(filename); err != nil {
+		return "", err
+	}
+	if len(data) > maxTranscriptionAudioBytes {
+		return "", fmt.Errorf("audio file is %d MB; the limit is %d MB", len(data)>>20, maxTranscriptionAudioBytes>>20)
+	}
+	model := t.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	mw.WriteField("model", model)
+	mw.WriteField("response_format", "json")
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	api := audioAPI{
+		apiKey:          t.APIKey,
+		azureEndpoint:   t.AzureEndpoint,
+		azureAPIVersion: t.AzureAPIVersion,
+		baseURL:         t.BaseURL,
+		client:          t.Client,
+		timeout:         5 * time.Minute,
+		action:          "transcription",
+	}
+	data, err = api.post(ctx, model, "transcriptions", mw.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("decoding transcription: %w", err)
+	}
+	return strings.TrimSpace(out.Text), nil
+}
+
+// TranscribeTool transcribes an audio file in the workspace.
+type TranscribeTool struct {
+	Transcriber *Transcriber
+	Workspace   string
+}
+
+func (t *TranscribeTool) Name() string { return "audio_transcribe" }
+func (t *TranscribeTool) Description() string {
+	return "Transcribe speech in an audio file in the workspace (" + strings.Join(SupportedAudioFormats, ", ") + ")."
+}
+func (t *TranscribeTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"file_path": map[string]string{"type": "string", "description": "Audio file path relative to the workspace"},
+		},
+		"required": []string{"file_path"},
+	}
+}
+
+func (t *TranscribeTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	relPath, _ := GetArg[string](input, "file_path")
+	if t.Transcriber == nil {
+		return ErrorOutput(errors.New("audio transcription is not configured: set the OpenAI API key in the audio settings")), nil
+	}
+	if err := CheckAudioFormat(relPath); err != nil {
+		return ErrorOutput(err), nil
+	}
+	fullPath, err := utils.ResolveInRoot(t.Workspace, relPath)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	text, err := t.Transcriber.Transcribe(ctx, relPath, data)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	return &ToolOutput{Text: text, Auxiliary: map[string]interface{}{"path": relPath}}, nil
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// whisperStub serves a transcription endpoint that checks the upload and
+// returns a fixed transcript.
+func whisperStub(t *testing.T, wantPath, wantAuth, authHeader string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != wantPath {
+			t.Errorf("path = %s; want %s", r.URL.Path, wantPath)
+		}
+		if got := r.Header.Get(authHeader); got != wantAuth {
+			t.Errorf("%s = %q; want %q", authHeader, got, wantAuth)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("no file in upload: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "note.mp3" || string(data) != "ID3 audio" || r.FormValue("model") != DefaultTranscriptionModel {
+			t.Errorf("upload = %s %q model %q", header.Filename, data, r.FormValue("model"))
+		}
+		w.Write([]byte(`{"text": " Deploy the staging build. "}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTranscriberOpenAIAndAzure(t *testing.T) {
+	openai := whisperStub(t, "/v1/audio/transcriptions", "Bearer sk-test", "Authorization")
+	azure := whisperStub(t, "/openai/deployments/whisper-1/audio/transcriptions", "az-key", "api-key")
+
+	for name, tr := range map[string]*Transcriber{
+		"openai": {APIKey: "sk-test", BaseURL: openai.URL + "/v1"},
+		"azure":  {APIKey: "az-key", AzureEndpoint: azure.URL},
+	} {
+		text, err := tr.Transcribe(context.Background(), "note.mp3", []byte("ID3 audio"))
+		if err != nil || text != "Deploy the staging build." {
+			t.Errorf("%s: Transcribe() = %q, %v; want the transcript", name, text, err)
+		}
+	}
+}
+
+func TestTranscribeTool(t *testing.T) {
+	srv := whisperStub(t, "/audio/transcriptions", "Bearer sk-test", "Authorization")
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "note.mp3"), []byte("ID3 audio"), 0644)
+	tool := &TranscribeTool{Transcriber: &Transcriber{APIKey: "sk-test", BaseURL: srv.URL}, Workspace: workspace}
+
+	out, _ := tool.Run(context.Background(), ToolInput{"file_path": "note.mp3"})
+	if out.Error != "" || out.Text != "Deploy the staging build." {
+		t.Errorf("Run() = %+v; want the transcript", out)
+	}
+
+	out, _ = tool.Run(context.Background(), ToolInput{"file_path": "notes.txt"})
+	if out.Error == "" {
+		t.Error("Run() on a text file succeeded; want an unsupported format error")
+	}
+	if err := CheckAudioFormat("clip.aiff"); !errors.Is(err, ErrUnsupportedAudioFormat) {
+		t.Errorf("CheckAudioFormat(clip.aiff) = %v; want ErrUnsupportedAudioFormat", err)
+	}
+}
@@ -18,6 +18,11 @@ type Settings struct {
 	GCPLocation      string
 	GCSOutputBucket  string
 	SearchAPIKey     string // e.g., Serper or Bing
+
+	// LargeOutputThreshold is the size in bytes above which tool outputs are
+	// saved to the workspace and replaced by a reference. Zero uses
+	// DefaultLargeOutputThreshold; a negative value disables offloading.
+	LargeOutputThreshold int
 }
 
 
@@ -46,10 +51,14 @@ type Manager struct {
 }
 
 func NewManager(settings Settings) *Manager {
-	return &Manager{
+	m := &Manager{
 		tools:    make(map[string]SystemTool),
 		Settings: settings,
 	}
+	if settings.WorkspaceRoot != "" {
+		m.Register(&ReadToolOutputTool{WorkspaceRoot: settings.WorkspaceRoot})
+	}
+	return m
 }
 
 func (m *Manager) Register(tools ...SystemTool) {
@@ -93,5 +102,30 @@ func (m *Manager) ExecuteTool(ctx context.Context, name string, rawInput string)
 			AuxiliaryData: map[string]interface{}{"error": err.Error()},
 		}, nil
 	}
-	return result, nil
+	return m.offloadIfLarge(name, result), nil
+}
+
+// offloadIfLarge replaces an oversized output with a workspace reference.
+func (m *Manager) offloadIfLarge(name string, result ToolResult) ToolResult {
+	threshold := m.Settings.LargeOutputThreshold
+	if threshold == 0 {
+		threshold = DefaultLargeOutputThreshold
+	}
+	if m.Settings.WorkspaceRoot == "" || threshold < 0 || name == "read_tool_output" {
+		return result
+	}
+
+	ref, offloaded, err := OffloadLargeOutput(m.Settings.WorkspaceRoot, name, result.Output, threshold)
+	if err != nil {
+		log.Printf("Failed to offload large output of %s: %v", name, err)
+		return result
+	}
+	if offloaded {
+		if result.AuxiliaryData == nil {
+			result.AuxiliaryData = map[string]interface{}{}
+		}
+		result.AuxiliaryData["original_size"] = len(result.Output)
+		result.Output = ref
+	}
+	return result
 }
\ No newline at end of file
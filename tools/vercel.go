@@ -0,0 +1,328 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"water-ai/core/config"
+	"water-ai/utils"
+)
+
+const (
+	defaultVercelAPIURL       = "https://api.vercel.com"
+	defaultVercelPollInterval = 3 * time.Second
+	defaultVercelDeployWait   = 10 * time.Minute
+	maxVercelUploadBytes      = 50 << 20
+)
+
+// Errors reported by VercelDeployTool; Auxiliary["error_kind"] names them.
+var (
+	ErrVercelAuth    = errors.New("vercel rejected the API key")
+	ErrVercelBuild   = errors.New("vercel build failed")
+	ErrVercelTimeout = errors.New("vercel deployment did not become ready in time")
+)
+
+// vercelSkipDirs are not uploaded; Vercel installs dependencies itself.
+// Dotfiles and dot directories, such as .env and .git, are never uploaded
+// either: the deployment is public and they often hold secrets.
+var vercelSkipDirs = map[string]bool{"node_modules": true}
+
+var vercelNameInvalid = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// VercelDeployTool deploys a project directory in the workspace to Vercel
+// and waits for the deployment to go live.
+type VercelDeployTool struct {
+	APIKey    string
+	Workspace string
+	BaseURL   string // defaults to https://api.vercel.com
+	Client    *http.Client
+	// PollInterval and Timeout control waiting for the build; zero uses
+	// 3s and 10m.
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+// NewVercelDeployTool returns the deploy tool for the Vercel key in
+// thirdParty, or nil when none is set.
+func NewVercelDeployTool(thirdParty config.ThirdPartyIntegrationConfig, workspace string) *VercelDeployTool {
+	if thirdParty.VercelAPIKey == nil || thirdParty.VercelAPIKey.Reveal() == "" {
+		return nil
+	}
+	return &VercelDeployTool{APIKey: thirdParty.VercelAPIKey.Reveal(), Workspace: workspace}
+}
+
+func (t *VercelDeployTool) Name() string { return "vercel_deploy" }
+func (t *VercelDeployTool) Description() string {
+	return "Deploy a project directory in the workspace to Vercel for production and return its public URL."
+}
+func (t *VercelDeployTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"project_dir":  map[string]string{"type": "string", "description": "Project directory relative to the workspace"},
+			"project_name": map[string]string{"type": "string", "description": "Vercel project name; defaults to the directory name"},
+		},
+		"required": []string{"project_dir"},
+	}
+}
+
+// vercelDeployment is the part of a Vercel deployment the tool reads.
+type vercelDeployment struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ReadyState   string `json:"readyState"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func (t *VercelDeployTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	relDir, _ := GetArg[string](input, "project_dir")
+	dir, err := utils.ResolveInRoot(t.Workspace, relDir)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ErrorOutput(fmt.Errorf("project_dir %q is not a directory in the workspace", relDir)), nil
+	}
+	name, _ := GetArg[string](input, "project_name")
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	name = strings.Trim(vercelNameInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		name = "water-app"
+	}
+
+	files, err := vercelFiles(dir)
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	var dep vercelDeployment
+	err = t.api(ctx, http.MethodPost, "/v13/deployments", map[string]interface{}{
+		"name":            name,
+		"files":           files,
+		"target":          "production",
+		"projectSettings": map[string]interface{}{"framework": nil},
+	}, &dep)
+	if err != nil {
+		return vercelFailure(err, dep), nil
+	}
+
+	interval := t.PollInterval
+	if interval == 0 {
+		interval = defaultVercelPollInterval
+	}
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = defaultVercelDeployWait
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		switch dep.ReadyState {
+		case "READY":
+			url := "https://" + dep.URL
+			return &ToolOutput{
+				Text:      fmt.Sprintf("Deployed %s to %s", relDir, url),
+				Auxiliary: map[string]interface{}{"url": url, "deployment_id": dep.ID, "files": len(files)},
+			}, nil
+		case "ERROR", "CANCELED":
+			msg := dep.ErrorMessage
+			if msg == "" {
+				msg = strings.ToLower(dep.ReadyState)
+			}
+			return vercelFailure(fmt.Errorf("%w: %s", ErrVercelBuild, msg), dep), nil
+		}
+		if time.Now().After(deadline) {
+			return vercelFailure(fmt.Errorf("%w (state %s after %v)", ErrVercelTimeout, dep.ReadyState, timeout), dep), nil
+		}
+		select {
+		case <-ctx.Done():
+			return vercelFailure(ctx.Err(), dep), nil
+		case <-time.After(interval):
+		}
+		if err := t.api(ctx, http.MethodGet, "/v13/deployments/"+dep.ID, nil, &dep); err != nil {
+			return vercelFailure(err, dep), nil
+		}
+	}
+}
+
+// vercelFailure is the error output for a failed deploy, with the error
+// kind and deployment in Auxiliary.
+func vercelFailure(err error, dep vercelDeployment) *ToolOutput {
+	out := ErrorOutput(err)
+	kind := "api"
+	switch {
+	case errors.Is(err, ErrVercelAuth):
+		kind = "auth"
+	case errors.Is(err, ErrVercelBuild):
+		kind = "build"
+	case errors.Is(err, ErrVercelTimeout), errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		kind = "timeout"
+	}
+	out.Auxiliary = map[string]interface{}{"error_kind": kind}
+	if dep.ID != "" {
+		out.Auxiliary["deployment_id"] = dep.ID
+	}
+	if dep.ErrorCode != "" {
+		out.Auxiliary["error_code"] = dep.ErrorCode
+	}
+	return out
+}
+
+func (t *VercelDeployTool) api(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	base := t.BaseURL
+	if base == "" {
+		base = defaultVercelAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vercel request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w (status %d); check VERCEL_API_KEY", ErrVercelAuth, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("vercel returned status %d: %s (%s)", resp.StatusCode, apiErr.Error.Message, apiErr.Error.Code)
+		}
+		return fmt.Errorf("vercel returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// vercelFiles reads the files under dir as inline deployment files.
+func vercelFiles(dir string) ([]map[string]string, error) {
+	ignore, err := readVercelIgnore(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []map[string]string
+	total := 0
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		skip := strings.HasPrefix(d.Name(), ".") || ignore.matches(rel, d.IsDir())
+		if d.IsDir() {
+			if skip || vercelSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if skip || !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		total += len(data)
+		if total > maxVercelUploadBytes {
+			return fmt.Errorf("project is larger than %d MB; deploy the build output directory instead", maxVercelUploadBytes>>20)
+		}
+		files = append(files, map[string]string{
+			"file":     rel,
+			"data":     base64.StdEncoding.EncodeToString(data),
+			"encoding": "base64",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("project directory is empty")
+	}
+	return files, nil
+}
+
+// vercelIgnore holds the patterns of a project's .vercelignore. Patterns
+// follow .gitignore: a trailing slash matches only directories, and a
+// pattern with a slash is matched against the path from the project root
+// instead of the name. Negated patterns are not supported and are skipped.
+type vercelIgnore []string
+
+func readVercelIgnore(dir string) (vercelIgnore, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ".vercelignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var patterns vercelIgnore
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, nil
+}
+
+// matches reports whether the slash-separated path rel, relative to the
+// project root, is ignored.
+func (ig vercelIgnore) matches(rel string, isDir bool) bool {
+	for _, pattern := range ig {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		name := rel[strings.LastIndex(rel, "/")+1:]
+		if strings.Contains(pattern, "/") {
+			name = rel
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
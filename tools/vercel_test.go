@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeVercel serves the deployment endpoints, reporting finalState after
+// one poll in BUILDING.
+type fakeVercel struct {
+	finalState string
+	polls      int
+	files      []string
+}
+
+func (f *fakeVercel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer vercel-key" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": "forbidden", "message": "Not authorized"}}`))
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v13/deployments":
+		var body struct {
+			Name  string              `json:"name"`
+			Files []map[string]string `json:"files"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, file := range body.Files {
+			f.files = append(f.files, file["file"])
+		}
+		w.Write([]byte(`{"id": "dpl_1", "url": "` + body.Name + `-abc.vercel.app", "readyState": "QUEUED"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v13/deployments/dpl_1":
+		f.polls++
+		if f.polls < 2 {
+			w.Write([]byte(`{"id": "dpl_1", "url": "site-abc.vercel.app", "readyState": "BUILDING"}`))
+			return
+		}
+		w.Write([]byte(`{"id": "dpl_1", "url": "site-abc.vercel.app", "readyState": "` + f.finalState +
+			`", "errorCode": "BUILD_FAILED", "errorMessage": "Command \"npm run build\" exited with 1"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newVercelTestTool(t *testing.T, fake *fakeVercel, key string) *VercelDeployTool {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "site", "node_modules", "dep"), 0755)
+	os.WriteFile(filepath.Join(workspace, "site", "index.html"), []byte("<h1>hi</h1>"), 0644)
+	os.WriteFile(filepath.Join(workspace, "site", "node_modules", "dep", "index.js"), []byte("x"), 0644)
+	return &VercelDeployTool{APIKey: key, Workspace: workspace, BaseURL: srv.URL, PollInterval: time.Millisecond}
+}
+
+func TestVercelDeployTool(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		finalState string
+		wantKind   string
+	}{
+		{"ready", "vercel-key", "READY", ""},
+		{"build failure", "vercel-key", "ERROR", "build"},
+		{"bad key", "wrong", "READY", "auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeVercel{finalState: tt.finalState}
+			tool := newVercelTestTool(t, fake, tt.key)
+			out, _ := tool.Run(context.Background(), ToolInput{"project_dir": "site"})
+			if tt.wantKind == "" {
+				if out.Error != "" {
+					t.Fatalf("Run() error = %s", out.Error)
+				}
+				if out.Auxiliary["url"] != "https://site-abc.vercel.app" {
+					t.Errorf("url = %v; want https://site-abc.vercel.app", out.Auxiliary["url"])
+				}
+				if len(fake.files) != 1 || fake.files[0] != "index.html" {
+					t.Errorf("uploaded %v; want only index.html", fake.files)
+				}
+				return
+			}
+			if out.Error == "" {
+				t.Fatalf("Run() succeeded; want a %s error", tt.wantKind)
+			}
+			if out.Auxiliary["error_kind"] != tt.wantKind {
+				t.Errorf("error_kind = %v; want %s", out.Auxiliary["error_kind"], tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestVercelDeployToolRejectsPathOutsideWorkspace(t *testing.T) {
+	tool := newVercelTestTool(t, &fakeVercel{}, "vercel-key")
+	out, _ := tool.Run(context.Background(), ToolInput{"project_dir": "../etc"})
+	if out.Error == "" {
+		t.Error("Run() accepted a directory outside the workspace")
+	}
+}
+
+func TestVercelDeployToolSkipsSecretsAndIgnoredFiles(t *testing.T) {
+	fake := &fakeVercel{finalState: "READY"}
+	tool := newVercelTestTool(t, fake, "vercel-key")
+	site := filepath.Join(tool.Workspace, "site")
+	os.MkdirAll(filepath.Join(site, "drafts"), 0755)
+	os.MkdirAll(filepath.Join(site, "src", "logs"), 0755)
+	for name, content := range map[string]string{
+		".env":                 "DATABASE_URL=postgres://user:secret@db/app",
+		".env.local":           "API_KEY=secret",
+		".water_settings.json": "{}",
+		".vercelignore":        "# local only\ndrafts/\n*.log\n/src/logs\n",
+		"drafts/post.md":       "draft",
+		"debug.log":            "log",
+		"src/app.js":           "app",
+		"src/logs/today.txt":   "log",
+	} {
+		os.WriteFile(filepath.Join(site, filepath.FromSlash(name)), []byte(content), 0644)
+	}
+
+	out, _ := tool.Run(context.Background(), ToolInput{"project_dir": "site"})
+	if out.Error != "" {
+		t.Fatalf("Run() error = %s", out.Error)
+	}
+	want := map[string]bool{"index.html": true, "src/app.js": true}
+	if len(fake.files) != len(want) {
+		t.Errorf("uploaded %v; want only index.html and src/app.js", fake.files)
+	}
+	for _, file := range fake.files {
+		if !want[file] {
+			t.Errorf("uploaded %s; want it skipped", file)
+		}
+	}
+}
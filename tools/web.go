@@ -5,7 +5,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os/exec"
+	"strings"
+	"time"
+
+	"water-ai/browser"
+	"water-ai/utils"
 )
 
 // --- Web Search Tool ---
@@ -36,37 +42,150 @@ func (t *WebWebSearchTool) Run(ctx context.Context, input ToolInput) (ToolResult
 }
 
 // --- Visit Webpage Tool ---
-type VisitWebpageTool struct{}
 
-func (t *VisitWebpageTool) Name() string        { return "visit_webpage" }
-func (t *VisitWebpageTool) Description() string { return "Visit a URL and extract text." }
-func (t *VisitWebpageTool) Schema() map[string]interface{} {
+const (
+	// VisitWebpageUserAgent identifies the tool to the sites it fetches.
+	VisitWebpageUserAgent = "Mozilla/5.0 (compatible; WaterAI/1.0; +https://github.com/StellariumFoundation/Water)"
+	defaultVisitTimeout   = 30 * time.Second
+	maxPageBytes          = 10 << 20
+	// minReadableLength is the extracted text length below which a page is
+	// assumed to be rendered by JavaScript and the browser is tried instead.
+	minReadableLength = 200
+)
+
+// PageMarkdownSource renders a page in a real browser. *browser.Browser
+// satisfies it.
+type PageMarkdownSource interface {
+	GetPageMarkdown(url string) (string, error)
+}
+
+// VisitWebpageTool fetches a URL and returns its readable content as
+// markdown. When Browser is set, pages that yield little text over plain
+// HTTP are rendered in the browser instead.
+type VisitWebpageTool struct {
+	Client  *http.Client       // nil uses a client with defaultVisitTimeout
+	Browser PageMarkdownSource // optional fallback for JavaScript-heavy pages
+}
+
+func (t *VisitWebpageTool) Name() string { return "visit_webpage" }
+func (t *VisitWebpageTool) Description() string {
+	return "Visit a URL and extract its readable text as markdown."
+}
+func (t *VisitWebpageTool) InputSchema() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"url": map[string]string{"type": "string"},
+			"url": map[string]string{"type": "string", "description": "The http(s) URL to visit"},
 		},
 		"required": []string{"url"},
 	}
 }
 
-func (t *VisitWebpageTool) Run(ctx context.Context, input ToolInput) (ToolResult, error) {
-	url, _ := input["url"].(string)
-	resp, err := http.Get(url)
+func (t *VisitWebpageTool) httpClient() *http.Client {
+	if t.Client != nil {
+		return t.Client
+	}
+	return &http.Client{Timeout: defaultVisitTimeout}
+}
+
+func (t *VisitWebpageTool) Run(ctx context.Context, input ToolInput) (*ToolOutput, error) {
+	rawURL, err := GetArg[string](input, "url")
+	if err != nil {
+		return ErrorOutput(err), nil
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return ErrorOutput(fmt.Errorf("invalid URL %q: only http and https are supported", rawURL)), nil
+	}
+
+	if browser.IsPDFURL(ctx, rawURL) {
+		text, err := browser.ExtractPDFText(rawURL)
+		return pdfOutput(rawURL, text, err), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
-		return ToolResult{Output: err.Error(), Success: false}, nil
+		return ErrorOutput(err), nil
+	}
+	req.Header.Set("User-Agent", VisitWebpageUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+
+	resp, err := t.httpClient().Do(req)
+	if err != nil {
+		return ErrorOutput(fmt.Errorf("failed to fetch %s: %w", rawURL, err)), nil
 	}
 	defer resp.Body.Close()
-	
-	body, _ := io.ReadAll(resp.Body)
-	// Simplified: In production, strip HTML tags properly
-	return ToolResult{
-		Output:        string(body)[:min(len(body), 5000)], // Truncate
-		ResultMessage: "Page visited",
-		Success:       true,
+
+	finalURL := resp.Request.URL
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return ErrorOutput(fmt.Errorf("failed to fetch %s: %s", finalURL, resp.Status)), nil
+	}
+
+	body := io.LimitReader(resp.Body, maxPageBytes)
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	var title, text string
+	switch {
+	case strings.Contains(contentType, "application/pdf"):
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return ErrorOutput(fmt.Errorf("failed to read %s: %w", finalURL, err)), nil
+		}
+		text, err := browser.PDFText(raw)
+		return pdfOutput(finalURL.String(), text, err), nil
+	case contentType == "" || strings.Contains(contentType, "html"):
+		title, text, err = htmlToMarkdown(body, finalURL)
+		if err != nil {
+			return ErrorOutput(fmt.Errorf("failed to parse %s: %w", finalURL, err)), nil
+		}
+	case strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json") || strings.Contains(contentType, "xml"):
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return ErrorOutput(fmt.Errorf("failed to read %s: %w", finalURL, err)), nil
+		}
+		text = string(raw)
+	default:
+		return ErrorOutput(fmt.Errorf("unsupported content type %q at %s", contentType, finalURL)), nil
+	}
+
+	if len(text) < minReadableLength && t.Browser != nil {
+		if rendered, err := t.Browser.GetPageMarkdown(finalURL.String()); err == nil && len(rendered) > len(text) {
+			title, text = "", rendered
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "URL: %s\n", finalURL)
+	if finalURL.String() != rawURL {
+		fmt.Fprintf(&b, "(redirected from %s)\n", rawURL)
+	}
+	if title != "" {
+		fmt.Fprintf(&b, "Title: %s\n", title)
+	}
+	b.WriteString("\n")
+	b.WriteString(text)
+
+	out := b.String()
+	if len(out) > utils.VisitWebPageMaxOutputLength {
+		out = out[:utils.VisitWebPageMaxOutputLength] + "\n" + utils.TruncatedMessage
+	}
+	return &ToolOutput{
+		Text:      out,
+		Auxiliary: map[string]interface{}{"url": finalURL.String(), "title": title},
 	}, nil
 }
 
+// pdfOutput returns the text extracted from the PDF at pdfURL, or tells the
+// model that the URL is a PDF that could not be read if extraction failed.
+func pdfOutput(pdfURL, text string, err error) *ToolOutput {
+	if err != nil {
+		text = fmt.Sprintf("This URL is a PDF document and its text could not be extracted: %v", err)
+	}
+	return &ToolOutput{
+		Text:      fmt.Sprintf("URL: %s\n\n%s", pdfURL, text),
+		Auxiliary: map[string]interface{}{"url": pdfURL, "content_type": "application/pdf"},
+	}
+}
+
 // --- YouTube Transcript Tool ---
 type YouTubeTranscriptTool struct{}
 
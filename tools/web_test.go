@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"water-ai/utils"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html><head><title>Water Facts</title><style>body{color:red}</style></head>
+<body>
+<nav><a href="/home">Home</a></nav>
+<h1>Water</h1>
+<p>Water is a   chemical
+substance.</p>
+<p>It covers about seventy percent of the surface of the Earth and is vital
+for all known forms of life, even though it provides no calories or organic
+nutrients.</p>
+<ul><li>Boils at 100C</li><li>Freezes at 0C</li></ul>
+<p>See <a href="/more">more facts</a>.</p>
+<script>document.write("hidden")</script>
+</body></html>`
+
+func newWebServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() != VisitWebpageUserAgent {
+			http.Error(w, "bad user agent", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, articleHTML)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article", http.StatusFound)
+	})
+	mux.HandleFunc("/paper", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	})
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><div id="root"></div><script src="app.js"></script></body></html>`)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, strings.Repeat("a", utils.VisitWebPageMaxOutputLength*2))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVisitWebpageExtractsReadableText(t *testing.T) {
+	srv := newWebServer(t)
+	tool := &VisitWebpageTool{}
+
+	out, err := tool.Run(context.Background(), ToolInput{"url": srv.URL + "/article"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out.Error != "" {
+		t.Fatalf("Run() output error = %s", out.Error)
+	}
+	for _, want := range []string{
+		"URL: " + srv.URL + "/article",
+		"Title: Water Facts",
+		"# Water",
+		"Water is a chemical substance.",
+		"- Boils at 100C",
+		"[more facts](" + srv.URL + "/more)",
+	} {
+		if !strings.Contains(out.Text, want) {
+			t.Errorf("Text missing %q:\n%s", want, out.Text)
+		}
+	}
+	for _, unwanted := range []string{"hidden", "color:red", "Home"} {
+		if strings.Contains(out.Text, unwanted) {
+			t.Errorf("Text contains %q:\n%s", unwanted, out.Text)
+		}
+	}
+}
+
+func TestVisitWebpageReportsRedirects(t *testing.T) {
+	srv := newWebServer(t)
+	out, _ := (&VisitWebpageTool{}).Run(context.Background(), ToolInput{"url": srv.URL + "/old"})
+
+	if !strings.HasPrefix(out.Text, "URL: "+srv.URL+"/article\n(redirected from "+srv.URL+"/old)") {
+		t.Errorf("Text = %q; want the resolved URL and redirect note", out.Text)
+	}
+	if out.Auxiliary["url"] != srv.URL+"/article" {
+		t.Errorf("Auxiliary url = %v", out.Auxiliary["url"])
+	}
+}
+
+func TestVisitWebpageDetectsPDF(t *testing.T) {
+	srv := newWebServer(t)
+	out, _ := (&VisitWebpageTool{}).Run(context.Background(), ToolInput{"url": srv.URL + "/paper"})
+
+	if out.Auxiliary["content_type"] != "application/pdf" {
+		t.Errorf("content_type = %v; want application/pdf", out.Auxiliary["content_type"])
+	}
+	if !strings.Contains(out.Text, "PDF document") {
+		t.Errorf("Text = %q; want a PDF note", out.Text)
+	}
+}
+
+func TestVisitWebpageExtractsPDFText(t *testing.T) {
+	sample, err := os.ReadFile("../browser/testdata/sample.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(sample)
+	}))
+	defer srv.Close()
+
+	out, _ := (&VisitWebpageTool{}).Run(context.Background(), ToolInput{"url": srv.URL + "/report"})
+	if !strings.Contains(out.Text, "--- Page 2 ---\nRivers flow to the sea") {
+		t.Errorf("Text = %q; want the page-delimited PDF text", out.Text)
+	}
+}
+
+type stubPageSource struct{ calls []string }
+
+func (s *stubPageSource) GetPageMarkdown(url string) (string, error) {
+	s.calls = append(s.calls, url)
+	return "# Rendered\n\n" + strings.Repeat("content rendered by javascript ", 20), nil
+}
+
+func TestVisitWebpageFallsBackToBrowser(t *testing.T) {
+	srv := newWebServer(t)
+	stub := &stubPageSource{}
+	tool := &VisitWebpageTool{Browser: stub}
+
+	out, _ := tool.Run(context.Background(), ToolInput{"url": srv.URL + "/app"})
+	if len(stub.calls) != 1 || stub.calls[0] != srv.URL+"/app" {
+		t.Errorf("browser calls = %v; want one for /app", stub.calls)
+	}
+	if !strings.Contains(out.Text, "content rendered by javascript") {
+		t.Errorf("Text = %q; want the rendered content", out.Text)
+	}
+
+	stub.calls = nil
+	tool.Run(context.Background(), ToolInput{"url": srv.URL + "/article"})
+	if len(stub.calls) != 0 {
+		t.Errorf("browser called for a readable page: %v", stub.calls)
+	}
+}
+
+func TestVisitWebpageTruncatesAndValidates(t *testing.T) {
+	srv := newWebServer(t)
+	tool := &VisitWebpageTool{}
+
+	out, _ := tool.Run(context.Background(), ToolInput{"url": srv.URL + "/big"})
+	if !strings.HasSuffix(out.Text, utils.TruncatedMessage) {
+		t.Error("oversized page should be truncated")
+	}
+	if len(out.Text) > utils.VisitWebPageMaxOutputLength+len(utils.TruncatedMessage)+1 {
+		t.Errorf("len(Text) = %d; want at most the max output length", len(out.Text))
+	}
+
+	out, _ = tool.Run(context.Background(), ToolInput{"url": "file:///etc/passwd"})
+	if out.Error == "" {
+		t.Error("non-http URL should be rejected")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -21,6 +22,7 @@ type ChatView struct {
 	scroll        *container.Scroll
 	loadingLabel  *widget.Label
 	loadingBox    *fyne.Container
+	stopButton    *widget.Button
 }
 
 // NewChatView creates a new chat view
@@ -54,6 +56,11 @@ func (cv *ChatView) createUI() {
 	)
 	cv.loadingBox.Hide()
 
+	// Stop button, enabled only while a query runs
+	cv.stopButton = widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), cv.handleStop)
+	cv.stopButton.Importance = widget.DangerImportance
+	cv.stopButton.Disable()
+
 	// Create input area
 	cv.inputArea = NewInputArea(cv.state, cv.wsClient)
 	cv.inputArea.OnSubmit = cv.handleSubmit
@@ -72,9 +79,8 @@ func (cv *ChatView) handleSubmit(text string) {
 	// Clear input
 	cv.inputArea.SetText("")
 
-	// Get attached files
-	files := cv.inputArea.GetAttachedFiles()
-	cv.inputArea.ClearAttachedFiles()
+	// Send the pending attachments with this query
+	files := cv.inputArea.TakeAttachedFiles()
 
 	// Show loading indicator
 	cv.state.IsLoading = true
@@ -82,7 +88,8 @@ func (cv *ChatView) handleSubmit(text string) {
 
 	// Initialize agent if not already done
 	if !cv.state.IsAgentInitialized {
-		cv.wsClient.InitAgent(cv.state.SelectedModel, map[string]interface{}{}, 0)
+		sandbox := cv.state.Sandbox
+		cv.wsClient.InitAgent(cv.state.SelectedModel, map[string]interface{}{}, 0, &sandbox)
 	}
 
 	// Send query with files
@@ -95,6 +102,24 @@ func (cv *ChatView) handleSubmit(text string) {
 	cv.scrollToBottom()
 }
 
+// handleStop cancels the running query and resets the loading state
+func (cv *ChatView) handleStop() {
+	if cv.wsClient != nil {
+		cv.wsClient.CancelQuery()
+	}
+	cv.state.IsLoading = false
+	cv.Refresh()
+}
+
+// AddThinking appends a thinking block for the agent's reasoning
+func (cv *ChatView) AddThinking(text string) {
+	if text == "" {
+		return
+	}
+	cv.state.AddMessage(client.NewMessage(client.RoleThinking, text))
+	cv.Refresh()
+}
+
 // SetLoadingText sets the loading indicator text
 func (cv *ChatView) SetLoadingText(text string) {
 	cv.loadingLabel.SetText(text)
@@ -123,8 +148,10 @@ func (cv *ChatView) Refresh() {
 	// Update loading state
 	if cv.state.IsLoading {
 		cv.loadingBox.Show()
+		cv.stopButton.Enable()
 	} else {
 		cv.loadingBox.Hide()
+		cv.stopButton.Disable()
 	}
 
 	cv.BaseWidget.Refresh()
@@ -139,7 +166,7 @@ func (cv *ChatView) CreateRenderer() fyne.WidgetRenderer {
 	content := container.NewBorder(
 		nil,                        // top
 		container.NewVBox(          // bottom
-			cv.loadingBox,
+			container.NewBorder(nil, nil, nil, cv.stopButton, cv.loadingBox),
 			cv.inputArea,
 		),
 		nil,                        // left
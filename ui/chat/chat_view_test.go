@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"testing"
+
+	"water-ai/client"
+
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+func TestStopButtonTracksLoading(t *testing.T) {
+	test.NewTempApp(t)
+	state := client.NewAppState()
+	cv := NewChatView(state, client.NewWebSocketClient("ws://unused", state))
+
+	if !cv.stopButton.Disabled() {
+		t.Error("Stop enabled while idle")
+	}
+
+	state.IsLoading = true
+	cv.Refresh()
+	if cv.stopButton.Disabled() {
+		t.Error("Stop disabled while a query runs")
+	}
+
+	test.Tap(cv.stopButton)
+	if state.IsLoading {
+		t.Error("IsLoading still set after Stop")
+	}
+	if !cv.stopButton.Disabled() {
+		t.Error("Stop still enabled after stopping")
+	}
+}
+
+func TestAttachmentChips(t *testing.T) {
+	test.NewTempApp(t)
+	state := client.NewAppState()
+	ia := NewInputArea(state, client.NewWebSocketClient("ws://unused", state))
+
+	state.AddAttachment(client.Attachment{Name: "a.png", Path: "uploads/a.png"})
+	state.AddAttachment(client.Attachment{Name: "b.txt", Path: "uploads/b.txt"})
+	ia.updateChips()
+	if len(ia.chips.Objects) != 2 || !ia.chips.Visible() {
+		t.Fatalf("chips = %d (visible %v); want 2 visible", len(ia.chips.Objects), ia.chips.Visible())
+	}
+
+	test.Tap(ia.chips.Objects[0].(*widget.Button))
+	if len(state.Attachments) != 1 || state.Attachments[0].Path != "uploads/b.txt" {
+		t.Errorf("Attachments after removing a chip = %+v", state.Attachments)
+	}
+
+	if files := ia.TakeAttachedFiles(); len(files) != 1 || files[0] != "uploads/b.txt" {
+		t.Errorf("TakeAttachedFiles() = %v", files)
+	}
+	if ia.chips.Visible() {
+		t.Error("chips still shown after sending the attachments")
+	}
+}
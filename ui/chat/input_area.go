@@ -2,6 +2,8 @@ package chat
 
 import (
 	"fmt"
+	"path/filepath"
+
 	"water-ai/client"
 
 	"fyne.io/fyne/v2"
@@ -24,10 +26,7 @@ type InputArea struct {
 	sendBtn    *widget.Button
 	cancelBtn  *widget.Button
 	attachBtn  *widget.Button
-	fileLabel  *widget.Label
-
-	// State
-	attachedFiles []string
+	chips      *fyne.Container
 
 	// Callbacks
 	OnSubmit func(text string)
@@ -36,9 +35,8 @@ type InputArea struct {
 // NewInputArea creates a new input area
 func NewInputArea(state *client.AppState, wsClient *client.WebSocketClient) *InputArea {
 	ia := &InputArea{
-		state:         state,
-		wsClient:      wsClient,
-		attachedFiles: []string{},
+		state:    state,
+		wsClient: wsClient,
 	}
 	ia.ExtendBaseWidget(ia)
 	ia.createUI()
@@ -60,9 +58,9 @@ func (ia *InputArea) createUI() {
 		}
 	}
 
-	// Create file label
-	ia.fileLabel = widget.NewLabel("")
-	ia.fileLabel.Importance = widget.LowImportance
+	// Pending attachments, shown as removable chips
+	ia.chips = container.NewHBox()
+	ia.chips.Hide()
 
 	// Create send button
 	ia.sendBtn = widget.NewButtonWithIcon("Send", theme.MailSendIcon(), func() {
@@ -80,11 +78,14 @@ func (ia *InputArea) createUI() {
 	ia.attachBtn = widget.NewButtonWithIcon("", theme.DocumentIcon(), ia.showFilePicker)
 }
 
-// showFilePicker shows a file picker dialog
-func (ia *InputArea) showFilePicker() {
-	// Get the window from the current focus
-	win := fyne.CurrentApp().Driver().AllWindows()[0]
+// window returns the window hosting the input area
+func (ia *InputArea) window() fyne.Window {
+	return fyne.CurrentApp().Driver().AllWindows()[0]
+}
 
+// showFilePicker shows a file picker dialog and uploads the chosen file
+func (ia *InputArea) showFilePicker() {
+	win := ia.window()
 	dialog.ShowFileOpen(func(uc fyne.URIReadCloser, err error) {
 		if err != nil {
 			dialog.ShowError(err, win)
@@ -93,23 +94,45 @@ func (ia *InputArea) showFilePicker() {
 		if uc == nil {
 			return
 		}
-		defer uc.Close()
-
-		// Add file to attached files
-		ia.attachedFiles = append(ia.attachedFiles, uc.URI().Path())
-		ia.updateFileLabel()
+		uc.Close()
+		ia.attach(uc.URI().Path())
 	}, win)
 }
 
-// updateFileLabel updates the file label text
-func (ia *InputArea) updateFileLabel() {
-	if len(ia.attachedFiles) == 0 {
-		ia.fileLabel.SetText("")
-	} else if len(ia.attachedFiles) == 1 {
-		ia.fileLabel.SetText("📎 1 file attached")
+// attach uploads the local file at path to the session workspace in the
+// background and adds it to the pending attachments
+func (ia *InputArea) attach(path string) {
+	go func() {
+		attachment, err := ia.wsClient.UploadFile(path)
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("could not attach %s: %w", filepath.Base(path), err), ia.window())
+				return
+			}
+			ia.state.AddAttachment(attachment)
+			ia.updateChips()
+		})
+	}()
+}
+
+// updateChips rebuilds the attachment chips from the pending attachments
+func (ia *InputArea) updateChips() {
+	ia.chips.RemoveAll()
+	for _, a := range ia.state.Attachments {
+		path := a.Path
+		chip := widget.NewButtonWithIcon(a.Name, theme.CancelIcon(), func() {
+			ia.state.RemoveAttachment(path)
+			ia.updateChips()
+		})
+		chip.Importance = widget.LowImportance
+		ia.chips.Add(chip)
+	}
+	if len(ia.state.Attachments) == 0 {
+		ia.chips.Hide()
 	} else {
-		ia.fileLabel.SetText(fmt.Sprintf("📎 %d files attached", len(ia.attachedFiles)))
+		ia.chips.Show()
 	}
+	ia.chips.Refresh()
 }
 
 // SetText sets the entry text
@@ -122,15 +145,18 @@ func (ia *InputArea) GetText() string {
 	return ia.entry.Text
 }
 
-// GetAttachedFiles returns the list of attached files
-func (ia *InputArea) GetAttachedFiles() []string {
-	return ia.attachedFiles
+// TakeAttachedFiles returns the workspace paths of the pending
+// attachments and clears them
+func (ia *InputArea) TakeAttachedFiles() []string {
+	files := ia.state.TakeAttachments()
+	ia.updateChips()
+	return files
 }
 
 // ClearAttachedFiles clears the attached files
 func (ia *InputArea) ClearAttachedFiles() {
-	ia.attachedFiles = []string{}
-	ia.updateFileLabel()
+	ia.state.Attachments = nil
+	ia.updateChips()
 }
 
 // Refresh updates the input area state
@@ -160,7 +186,6 @@ func (ia *InputArea) CreateRenderer() fyne.WidgetRenderer {
 	// Create button row
 	buttonRow := container.NewHBox(
 		ia.attachBtn,
-		ia.fileLabel,
 		container.NewCenter(widget.NewLabel("Shift+Enter for newline")),
 		layout.NewSpacer(),
 		ia.cancelBtn,
@@ -169,7 +194,7 @@ func (ia *InputArea) CreateRenderer() fyne.WidgetRenderer {
 
 	// Create main layout
 	content := container.NewBorder(
-		nil,        // top
+		ia.chips,   // top
 		buttonRow,  // bottom
 		nil,        // left
 		nil,        // right
@@ -188,10 +213,8 @@ func (ia *InputArea) MinSize() fyne.Size {
 func (ia *InputArea) FileDropHandler() func([]fyne.URI) {
 	return func(uris []fyne.URI) {
 		for _, uri := range uris {
-			ia.attachedFiles = append(ia.attachedFiles, uri.Path())
+			ia.attach(uri.Path())
 		}
-		ia.updateFileLabel()
-		ia.Refresh()
 	}
 }
 
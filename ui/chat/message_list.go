@@ -35,16 +35,38 @@ func (ml *MessageList) Refresh() {
 	ml.box.Objects = nil
 
 	// Add all visible messages
-	for _, msg := range ml.state.Messages {
-		if msg.IsHidden {
-			continue
-		}
+	for _, msg := range visibleMessages(ml.state) {
 		ml.box.Add(NewMessageItem(msg))
 	}
 
 	ml.BaseWidget.Refresh()
 }
 
+// visibleMessages returns the messages to render, dropping hidden ones and
+// thinking blocks when the user has turned them off
+func visibleMessages(state *client.AppState) []client.Message {
+	var out []client.Message
+	for _, msg := range state.Messages {
+		if msg.IsHidden || (msg.Role == client.RoleThinking && !state.ShowThinking) {
+			continue
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// thinkingText strips the ```Thinking: fence the agent wraps reasoning in
+func thinkingText(raw string) string {
+	text := strings.TrimSpace(raw)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(text, "```")
+	}
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "Thinking:")
+	return strings.TrimSpace(text)
+}
+
 // CreateRenderer creates the widget renderer
 func (ml *MessageList) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(ml.box)
@@ -73,6 +95,10 @@ func NewMessageItem(msg client.Message) *MessageItem {
 
 // CreateRenderer creates the widget renderer
 func (mi *MessageItem) CreateRenderer() fyne.WidgetRenderer {
+	if mi.message.Role == client.RoleThinking {
+		return mi.createThinkingRenderer()
+	}
+
 	// Determine style based on role
 	var icon fyne.Resource
 	var roleLabel string
@@ -111,8 +137,22 @@ func (mi *MessageItem) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(card)
 }
 
+// createThinkingRenderer renders reasoning as a dimmed, collapsed section
+// so it stays out of the way of the final answer
+func (mi *MessageItem) createThinkingRenderer() fyne.WidgetRenderer {
+	label := widget.NewLabelWithStyle(thinkingText(mi.message.Content), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	label.Importance = widget.LowImportance
+	label.Wrapping = fyne.TextWrapWord
+
+	accordion := widget.NewAccordion(widget.NewAccordionItem("Thinking", label))
+	return widget.NewSimpleRenderer(accordion)
+}
+
 // MinSize returns the minimum size for the message item
 func (mi *MessageItem) MinSize() fyne.Size {
+	if mi.message.Role == client.RoleThinking {
+		return mi.BaseWidget.MinSize()
+	}
 	return fyne.NewSize(350, 80)
 }
 
@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"testing"
+
+	"water-ai/client"
+)
+
+func TestThinkingText(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"```Thinking:\nCheck the tests\nthen fix\n```", "Check the tests\nthen fix"},
+		{"Plain reasoning", "Plain reasoning"},
+		{"  Thinking: trimmed  ", "trimmed"},
+	}
+	for _, tt := range tests {
+		if got := thinkingText(tt.raw); got != tt.want {
+			t.Errorf("thinkingText(%q) = %q; want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleMessagesHidesThinkingWhenDisabled(t *testing.T) {
+	state := client.NewAppState()
+	state.AddMessage(client.NewMessage("user", "hi"))
+	state.AddMessage(client.NewMessage(client.RoleThinking, "reasoning"))
+	state.AddMessage(client.NewMessage("assistant", "hello"))
+	hidden := client.NewMessage("system", "internal")
+	hidden.IsHidden = true
+	state.AddMessage(hidden)
+
+	if got := len(visibleMessages(state)); got != 3 {
+		t.Errorf("visible messages with thinking shown = %d; want 3", got)
+	}
+
+	state.ShowThinking = false
+	visible := visibleMessages(state)
+	if len(visible) != 2 {
+		t.Fatalf("visible messages with thinking hidden = %d; want 2", len(visible))
+	}
+	for _, msg := range visible {
+		if msg.Role == client.RoleThinking {
+			t.Error("thinking message should be hidden")
+		}
+	}
+}
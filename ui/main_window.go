@@ -1,6 +1,12 @@
 package ui
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"water-ai/client"
 	"water-ai/resources"
 	"water-ai/ui/chat"
@@ -17,7 +23,7 @@ import (
 )
 
 const (
-	serverURL = "ws://localhost:7777/ws"
+	serverURL = "ws://127.0.0.1:7777/ws"
 )
 
 // MainWindow represents the main application window
@@ -41,13 +47,23 @@ type MainWindow struct {
 	connectionStatus *widget.Label
 	connectionIcon   *widget.Icon
 	workspaceLabel   *widget.Label
+	usageLabel       *widget.Label
+	// countdownStop ends the running reconnect countdown; main thread only
+	countdownStop chan struct{}
+
+	// transcriptPath is where the conversation is autosaved; empty disables it
+	transcriptPath string
+
+	// Bytes of output already streamed to the terminal panel, by tool call
+	streamedCalls map[string]int
 }
 
 // NewMainWindow creates a new main window
 func NewMainWindow(app fyne.App) *MainWindow {
 	mw := &MainWindow{
-		app:   app,
-		state: client.NewAppState(),
+		app:           app,
+		state:         client.NewAppState(),
+		streamedCalls: make(map[string]int),
 	}
 
 	// Initialize WebSocket client
@@ -56,6 +72,9 @@ func NewMainWindow(app fyne.App) *MainWindow {
 	mw.wsClient.SetOnEvent(mw.onEvent)
 	mw.wsClient.SetOnConnected(mw.onConnected)
 	mw.wsClient.SetOnDisconnected(mw.onDisconnected)
+	mw.wsClient.SetOnReconnectAttempt(mw.onReconnectAttempt)
+	mw.wsClient.SetOnReconnectFailed(mw.onReconnectFailed)
+	mw.wsClient.SetOnThinking(mw.onThinking)
 
 	// Create the window
 	mw.window = app.NewWindow("Water AI")
@@ -85,11 +104,19 @@ func (mw *MainWindow) createUI() {
 
 	// Create panel views
 	mw.browserPanel = panels.NewBrowserPanel(mw.state)
+	mw.browserPanel.OnSwitchTab = func(pageID int) {
+		go func() {
+			if err := mw.wsClient.SwitchBrowserTab(pageID); err != nil {
+				log.Printf("Failed to switch to browser tab %d: %v", pageID, err)
+			}
+		}()
+	}
 	mw.codePanel = panels.NewCodePanel(mw.state)
 	mw.terminalPanel = panels.NewTerminalPanel(mw.state)
 
 	// Create settings dialog
 	mw.settingsDialog = settings.NewSettingsDialog(mw.window, mw.state, mw.wsClient)
+	mw.settingsDialog.OnSaved = mw.chatView.Refresh
 
 	// Create tabbed panel
 	mw.panelTabs = container.NewAppTabs(
@@ -169,6 +196,10 @@ func (mw *MainWindow) createStatusBar() fyne.CanvasObject {
 	// Store reference for updates
 	mw.workspaceLabel = workspaceLabel
 
+	// Session tokens and estimated cost
+	mw.usageLabel = widget.NewLabel("")
+	mw.updateUsage(mw.state.Usage)
+
 	return container.NewBorder(
 		nil, nil,
 		container.NewHBox(
@@ -177,7 +208,7 @@ func (mw *MainWindow) createStatusBar() fyne.CanvasObject {
 			widget.NewSeparator(),
 			workspaceLabel,
 		),
-		nil,
+		mw.usageLabel,
 	)
 }
 
@@ -274,13 +305,39 @@ func (mw *MainWindow) onEvent(eventType string, content interface{}) {
 		case client.EventTypeProcessing:
 			mw.chatView.SetLoadingText("Processing...")
 			mw.chatView.ShowLoading()
-		case client.EventTypeStreamComplete:
+		case client.EventTypeStreamComplete, client.EventTypeResponseInterrupt:
 			mw.chatView.HideLoading()
 			mw.state.IsLoading = false
+		case client.EventTypeUsage:
+			if u, ok := content.(client.UsageEvent); ok {
+				mw.updateUsage(u)
+			}
 		}
 	})
 }
 
+// updateUsage shows the session's tokens and cost, colored by how close
+// the cost is to the budget
+func (mw *MainWindow) updateUsage(u client.UsageEvent) {
+	switch client.Budget(u.CostUSD, mw.state.CostBudgetUSD) {
+	case client.BudgetExceeded:
+		mw.usageLabel.Importance = widget.DangerImportance
+	case client.BudgetWarning:
+		mw.usageLabel.Importance = widget.WarningImportance
+	default:
+		mw.usageLabel.Importance = widget.LowImportance
+	}
+	mw.usageLabel.SetText(client.FormatUsage(u))
+}
+
+// onThinking adds the agent's reasoning to the chat as a thinking block
+func (mw *MainWindow) onThinking(event client.AgentThinkingEvent) {
+	fyne.Do(func() {
+		mw.chatView.SetLoadingText("Thinking...")
+		mw.chatView.AddThinking(event.Text)
+	})
+}
+
 // handleToolCall handles tool call events
 func (mw *MainWindow) handleToolCall(tc client.ToolCallEvent) {
 	// Switch to appropriate tab based on tool
@@ -291,12 +348,37 @@ func (mw *MainWindow) handleToolCall(tc client.ToolCallEvent) {
 	case "write_file", "read_file", "edit_file":
 		mw.panelTabs.SelectIndex(1) // Code tab
 		mw.chatView.SetLoadingText("Working on code...")
-	case "execute_command":
+		if path, ok := tc.ToolInput["path"].(string); ok && path != "" {
+			mw.codePanel.SetFile(path)
+		}
+	case "execute_command", "terminal_execute":
 		mw.panelTabs.SelectIndex(2) // Terminal tab
 		mw.chatView.SetLoadingText("Running command...")
 	default:
 		mw.chatView.SetLoadingText("Working...")
 	}
+	if tc.AwaitingApproval {
+		mw.confirmToolCall(tc)
+	}
+}
+
+// confirmToolCall asks the user whether the agent may run a gated tool
+// call and sends the answer to the server
+func (mw *MainWindow) confirmToolCall(tc client.ToolCallEvent) {
+	mw.chatView.SetLoadingText("Waiting for approval...")
+	input, _ := json.MarshalIndent(tc.ToolInput, "", "  ")
+	dialog.ShowConfirm(
+		"Allow "+tc.ToolName+"?",
+		fmt.Sprintf("The agent wants to run %s with:\n%s", tc.ToolName, input),
+		func(approved bool) {
+			go func() {
+				if err := mw.wsClient.AnswerToolCall(tc.ToolCallID, approved); err != nil {
+					log.Printf("Failed to answer tool call %s: %v", tc.ToolCallID, err)
+				}
+			}()
+		},
+		mw.window,
+	)
 }
 
 // handleToolResult handles tool result events
@@ -304,23 +386,67 @@ func (mw *MainWindow) handleToolResult(tr client.ToolResultEvent) {
 	// Update panels based on tool result
 	switch tr.ToolName {
 	case "browser_view", "browser_screenshot":
-		if screenshot, ok := tr.Result.(string); ok {
-			mw.browserPanel.SetScreenshot(screenshot)
+		if tr.Screenshot == nil {
+			log.Printf("Unrecognized %s result: %s", tr.ToolName, tr.Text())
+			return
+		}
+		mw.browserPanel.SetScreenshot(tr.Screenshot.ImageB64)
+		if tr.Screenshot.URL != "" {
+			mw.browserPanel.SetURL(tr.Screenshot.URL)
 		}
 	case "write_file", "read_file":
-		if content, ok := tr.Result.(string); ok {
-			mw.codePanel.SetContent(content)
+		if tr.File == nil {
+			mw.codePanel.SetContent(tr.Text())
+			return
+		}
+		if tr.File.Path != "" {
+			mw.codePanel.SetFile(tr.File.Path)
 		}
-	case "execute_command":
-		if output, ok := tr.Result.(string); ok {
-			mw.terminalPanel.AppendOutput(output)
+		mw.codePanel.SetContent(tr.File.Content)
+	case "execute_command", "terminal_execute":
+		if tr.Command == nil {
+			mw.terminalPanel.AppendOutput(tr.Text())
+			return
 		}
+		mw.handleCommandResult(tr.ToolCallID, tr.Partial, *tr.Command)
+	}
+}
+
+// handleCommandResult shows terminal output, skipping what was already
+// streamed line by line
+func (mw *MainWindow) handleCommandResult(toolCallID string, partial bool, cr client.CommandResult) {
+	output := cr.Output()
+	if partial {
+		mw.streamedCalls[toolCallID] += len(output) + 1
+		mw.terminalPanel.AppendOutput(output)
+		return
+	}
+
+	if n := mw.streamedCalls[toolCallID]; n > 0 {
+		delete(mw.streamedCalls, toolCallID)
+		if cr.ExitCode != nil {
+			// Structured results repeat both streams; only the status is new.
+			output = ""
+		} else if n > len(output) {
+			// The final result repeats the streamed lines; only show what
+			// follows them, such as a timeout note.
+			return
+		} else {
+			output = strings.TrimSpace(output[n:])
+		}
+	}
+	if output != "" {
+		mw.terminalPanel.AppendOutput(output)
+	}
+	if cr.ExitCode != nil && *cr.ExitCode != 0 {
+		mw.terminalPanel.AppendOutput(fmt.Sprintf("[exit code %d]", *cr.ExitCode))
 	}
 }
 
 // onConnected handles connection established
 func (mw *MainWindow) onConnected() {
 	fyne.Do(func() {
+		mw.stopCountdown()
 		mw.updateConnectionStatus(true, "Connected")
 	})
 }
@@ -328,10 +454,71 @@ func (mw *MainWindow) onConnected() {
 // onDisconnected handles disconnection
 func (mw *MainWindow) onDisconnected() {
 	fyne.Do(func() {
+		mw.stopCountdown()
 		mw.updateConnectionStatus(false, "Disconnected")
 	})
 }
 
+// onReconnectAttempt shows the upcoming reconnect attempt and counts down
+// to it once a second
+func (mw *MainWindow) onReconnectAttempt(attempt, of int, retryIn time.Duration) {
+	deadline := time.Now().Add(retryIn)
+	fyne.Do(func() {
+		mw.stopCountdown()
+		stop := make(chan struct{})
+		mw.countdownStop = stop
+		mw.updateConnectionStatus(false, reconnectStatus(attempt, of, retryIn))
+
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+				remaining := time.Until(deadline)
+				fyne.Do(func() {
+					select {
+					case <-stop:
+					default:
+						mw.updateConnectionStatus(false, reconnectStatus(attempt, of, remaining))
+					}
+				})
+				if remaining <= 0 {
+					return
+				}
+			}
+		}()
+	})
+}
+
+// onReconnectFailed reports that every reconnect attempt failed
+func (mw *MainWindow) onReconnectFailed() {
+	fyne.Do(func() {
+		mw.stopCountdown()
+		mw.updateConnectionStatus(false, "Disconnected (press F5 to retry)")
+	})
+}
+
+// stopCountdown ends the reconnect countdown, if one is running
+func (mw *MainWindow) stopCountdown() {
+	if mw.countdownStop != nil {
+		close(mw.countdownStop)
+		mw.countdownStop = nil
+	}
+}
+
+// reconnectStatus formats the status bar text for a pending reconnect
+func reconnectStatus(attempt, of int, remaining time.Duration) string {
+	if remaining <= 0 {
+		return fmt.Sprintf("Reconnecting (%d/%d)...", attempt, of)
+	}
+	secs := int((remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("Reconnecting (%d/%d) in %ds...", attempt, of, secs)
+}
+
 // onClose handles window close
 func (mw *MainWindow) onClose() {
 	// Show confirmation dialog
@@ -340,6 +527,7 @@ func (mw *MainWindow) onClose() {
 		"Are you sure you want to quit?",
 		func(confirmed bool) {
 			if confirmed {
+				mw.saveTranscript()
 				mw.wsClient.Disconnect()
 				mw.window.Close()
 			}
@@ -348,15 +536,8 @@ func (mw *MainWindow) onClose() {
 	)
 }
 
-// ShowAndRun shows the window and runs the application
-func (mw *MainWindow) ShowAndRun() {
-	// Show the window
-	mw.window.Show()
-
-	// Set initial connection status
-	mw.updateConnectionStatus(false, "Connecting...")
-
-	// Attempt to connect to the server
+// connect attempts to connect to the server in the background
+func (mw *MainWindow) connect() {
 	go func() {
 		if err := mw.wsClient.Connect(); err != nil {
 			// Show error dialog on main thread
@@ -366,6 +547,73 @@ func (mw *MainWindow) ShowAndRun() {
 			})
 		}
 	}()
+}
+
+// offerTranscriptRestore asks whether to reload the saved conversation,
+// if there is one, and calls next once the user has chosen
+func (mw *MainWindow) offerTranscriptRestore(next func()) {
+	if path, err := client.DefaultTranscriptPath(); err == nil {
+		mw.transcriptPath = path
+	} else {
+		log.Printf("Transcript autosave disabled: %v", err)
+		next()
+		return
+	}
+
+	saved, err := client.LoadTranscript(mw.transcriptPath)
+	if err != nil {
+		log.Printf("Ignoring saved transcript: %v", err)
+	}
+	if saved == nil || len(saved.Messages) == 0 {
+		next()
+		return
+	}
+
+	dialog.ShowConfirm(
+		"Restore last session?",
+		fmt.Sprintf("Reload the conversation from %s (%d messages)?", saved.SavedAt.Format("Jan 2 15:04"), len(saved.Messages)),
+		func(restore bool) {
+			if restore {
+				mw.state.Restore(saved)
+				mw.chatView.Refresh()
+			}
+			next()
+		},
+		mw.window,
+	)
+}
+
+// autosaveTranscript saves the conversation periodically
+func (mw *MainWindow) autosaveTranscript() {
+	ticker := time.NewTicker(client.TranscriptAutosaveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		fyne.Do(mw.saveTranscript)
+	}
+}
+
+// saveTranscript writes the conversation to the transcript file. Call it
+// on the main thread.
+func (mw *MainWindow) saveTranscript() {
+	if mw.transcriptPath == "" || len(mw.state.Messages) == 0 {
+		return
+	}
+	if err := client.SaveTranscript(mw.transcriptPath, mw.state.Transcript()); err != nil {
+		log.Printf("Failed to save transcript: %v", err)
+	}
+}
+
+// ShowAndRun shows the window and runs the application
+func (mw *MainWindow) ShowAndRun() {
+	// Show the window
+	mw.window.Show()
+
+	// Set initial connection status
+	mw.updateConnectionStatus(false, "Connecting...")
+
+	// Offer to restore the last conversation, then connect to the server
+	mw.offerTranscriptRestore(mw.connect)
+	go mw.autosaveTranscript()
 
 	// Run the application
 	mw.app.Run()
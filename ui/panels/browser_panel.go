@@ -24,6 +24,11 @@ type BrowserPanel struct {
 	statusLabel *widget.Label
 	scroll      *container.Scroll
 	emptyLabel  *widget.Label
+	tabBar      *fyne.Container
+
+	// OnSwitchTab is called with a tab's PageID when the user picks it
+	// from the tab strip
+	OnSwitchTab func(pageID int)
 }
 
 // NewBrowserPanel creates a new browser panel
@@ -57,6 +62,9 @@ func (bp *BrowserPanel) createUI() {
 	bp.statusLabel = widget.NewLabel("Ready")
 	bp.statusLabel.Alignment = fyne.TextAlignCenter
 
+	// Tab strip, filled in by SetTabs
+	bp.tabBar = container.NewHBox()
+
 	// Scroll container for image
 	bp.scroll = container.NewScroll(bp.emptyLabel)
 	bp.scroll.SetMinSize(fyne.NewSize(600, 400))
@@ -84,12 +92,40 @@ func (bp *BrowserPanel) SetURL(url string) {
 	bp.urlEntry.SetText(url)
 }
 
+// SetTabs shows the browser's open tabs as buttons, highlighting the
+// active one; tapping another tab calls OnSwitchTab
+func (bp *BrowserPanel) SetTabs(tabs []client.BrowserTab, active int) {
+	bp.tabBar.RemoveAll()
+	for _, tab := range tabs {
+		title := tab.Title
+		if title == "" {
+			title = tab.URL
+		}
+		if r := []rune(title); len(r) > 24 {
+			title = string(r[:21]) + "..."
+		}
+		pageID := tab.PageID
+		button := widget.NewButton(title, func() {
+			if pageID != active && bp.OnSwitchTab != nil {
+				bp.OnSwitchTab(pageID)
+			}
+		})
+		if pageID == active {
+			button.Importance = widget.HighImportance
+		}
+		bp.tabBar.Add(button)
+	}
+	bp.tabBar.Refresh()
+}
+
 // Refresh updates the browser panel
 func (bp *BrowserPanel) Refresh() {
 	if bp.state.BrowserURL != "" {
 		bp.urlEntry.SetText(bp.state.BrowserURL)
 	}
 
+	bp.SetTabs(bp.state.BrowserTabs, bp.state.ActiveBrowserTab)
+
 	if len(bp.state.BrowserScreenshot) > 0 {
 		staticResource := fyne.NewStaticResource("screenshot.png", bp.state.BrowserScreenshot)
 		bp.image.Resource = staticResource
@@ -134,7 +170,7 @@ func (bp *BrowserPanel) CreateRenderer() fyne.WidgetRenderer {
 
 	// Main content
 	content := container.NewBorder(
-		container.NewVBox(urlBar, toolbar),  // top
+		container.NewVBox(container.NewHScroll(bp.tabBar), urlBar, toolbar), // top
 		bp.statusLabel,                       // bottom
 		nil,                                  // left
 		nil,                                  // right
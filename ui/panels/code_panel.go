@@ -23,12 +23,20 @@ type CodePanel struct {
 	scroll      *container.Scroll
 	emptyLabel  *widget.Label
 	lineNumbers *widget.Label
+	langLabel   *widget.Label
+
+	// AutoDetectLanguage controls whether the language label is derived
+	// from the current file name and content.
+	AutoDetectLanguage bool
+	language           Language
 }
 
 // NewCodePanel creates a new code panel
 func NewCodePanel(state *client.AppState) *CodePanel {
 	cp := &CodePanel{
-		state: state,
+		state:              state,
+		AutoDetectLanguage: true,
+		language:           PlainText,
 	}
 	cp.ExtendBaseWidget(cp)
 	cp.createUI()
@@ -51,6 +59,10 @@ func (cp *CodePanel) createUI() {
 	cp.lineNumbers.TextStyle = fyne.TextStyle{Monospace: true}
 	cp.lineNumbers.Importance = widget.LowImportance
 
+	// Language label
+	cp.langLabel = widget.NewLabel("")
+	cp.langLabel.Importance = widget.LowImportance
+
 	// Code entry (read-only)
 	cp.codeEntry = widget.NewMultiLineEntry()
 	cp.codeEntry.SetPlaceHolder("Code will appear here...")
@@ -72,11 +84,37 @@ func (cp *CodePanel) SetContent(content string) {
 	)
 	cp.scroll.Content.(*container.Split).SetOffset(0.05)
 	cp.updateLineNumbers(content)
+	cp.updateLanguage(cp.state.CodeFile, content)
 }
 
 // SetFile sets the current file name
 func (cp *CodePanel) SetFile(filename string) {
+	cp.state.CodeFile = filename
 	cp.fileLabel.SetText(filename)
+	cp.updateLanguage(filename, cp.codeEntry.Text)
+}
+
+// SetAutoDetectLanguage enables or disables automatic language detection.
+// When disabled the language label is cleared.
+func (cp *CodePanel) SetAutoDetectLanguage(enabled bool) {
+	cp.AutoDetectLanguage = enabled
+	cp.updateLanguage(cp.state.CodeFile, cp.codeEntry.Text)
+}
+
+// Language returns the language detected for the current content.
+func (cp *CodePanel) Language() Language {
+	return cp.language
+}
+
+// updateLanguage re-detects the language and updates the toolbar label
+func (cp *CodePanel) updateLanguage(filename, content string) {
+	if !cp.AutoDetectLanguage {
+		cp.language = PlainText
+		cp.langLabel.SetText("")
+		return
+	}
+	cp.language = DetectLanguage(filename, content)
+	cp.langLabel.SetText(cp.language.Name)
 }
 
 // updateLineNumbers updates the line numbers display
@@ -117,6 +155,8 @@ func (cp *CodePanel) Refresh() {
 		cp.fileLabel.SetText(cp.state.CodeFile)
 	}
 
+	cp.updateLanguage(cp.state.CodeFile, cp.codeEntry.Text)
+
 	cp.BaseWidget.Refresh()
 }
 
@@ -130,16 +170,12 @@ func (cp *CodePanel) CreateRenderer() fyne.WidgetRenderer {
 		}
 	})
 
-	// Language label
-	langLabel := widget.NewLabel("")
-	langLabel.Importance = widget.LowImportance
-
 	// Toolbar
 	toolbar := container.NewHBox(
 		widget.NewIcon(theme.FileTextIcon()),
 		cp.fileLabel,
 		layout.NewSpacer(),
-		langLabel,
+		cp.langLabel,
 		copyBtn,
 	)
 
@@ -0,0 +1,115 @@
+package panels
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Language describes a detected source language and the highlighter
+// (lexer name) that should be used to render it.
+type Language struct {
+	Name        string
+	Highlighter string
+}
+
+// PlainText is returned when no language can be detected.
+var PlainText = Language{Name: "Plain Text", Highlighter: "text"}
+
+// languagesByExtension maps lower-case file extensions to languages.
+var languagesByExtension = map[string]Language{
+	".go":   {Name: "Go", Highlighter: "go"},
+	".py":   {Name: "Python", Highlighter: "python"},
+	".js":   {Name: "JavaScript", Highlighter: "javascript"},
+	".mjs":  {Name: "JavaScript", Highlighter: "javascript"},
+	".jsx":  {Name: "JavaScript (JSX)", Highlighter: "jsx"},
+	".ts":   {Name: "TypeScript", Highlighter: "typescript"},
+	".tsx":  {Name: "TypeScript (TSX)", Highlighter: "tsx"},
+	".html": {Name: "HTML", Highlighter: "html"},
+	".htm":  {Name: "HTML", Highlighter: "html"},
+	".css":  {Name: "CSS", Highlighter: "css"},
+	".scss": {Name: "SCSS", Highlighter: "scss"},
+	".json": {Name: "JSON", Highlighter: "json"},
+	".yaml": {Name: "YAML", Highlighter: "yaml"},
+	".yml":  {Name: "YAML", Highlighter: "yaml"},
+	".toml": {Name: "TOML", Highlighter: "toml"},
+	".md":   {Name: "Markdown", Highlighter: "markdown"},
+	".sh":   {Name: "Shell", Highlighter: "bash"},
+	".bash": {Name: "Shell", Highlighter: "bash"},
+	".sql":  {Name: "SQL", Highlighter: "sql"},
+	".rs":   {Name: "Rust", Highlighter: "rust"},
+	".java": {Name: "Java", Highlighter: "java"},
+	".c":    {Name: "C", Highlighter: "c"},
+	".h":    {Name: "C", Highlighter: "c"},
+	".cpp":  {Name: "C++", Highlighter: "cpp"},
+	".hpp":  {Name: "C++", Highlighter: "cpp"},
+	".rb":   {Name: "Ruby", Highlighter: "ruby"},
+	".php":  {Name: "PHP", Highlighter: "php"},
+	".xml":  {Name: "XML", Highlighter: "xml"},
+	".svg":  {Name: "SVG", Highlighter: "xml"},
+	".txt":  PlainText,
+}
+
+// languagesByFilename maps well-known extension-less file names.
+var languagesByFilename = map[string]Language{
+	"makefile":   {Name: "Makefile", Highlighter: "make"},
+	"dockerfile": {Name: "Dockerfile", Highlighter: "docker"},
+}
+
+// languagesByInterpreter maps shebang interpreters to languages.
+var languagesByInterpreter = map[string]Language{
+	"sh":      {Name: "Shell", Highlighter: "bash"},
+	"bash":    {Name: "Shell", Highlighter: "bash"},
+	"zsh":     {Name: "Shell", Highlighter: "bash"},
+	"python":  {Name: "Python", Highlighter: "python"},
+	"python3": {Name: "Python", Highlighter: "python"},
+	"node":    {Name: "JavaScript", Highlighter: "javascript"},
+	"ruby":    {Name: "Ruby", Highlighter: "ruby"},
+}
+
+// RegisterLanguage adds or overrides the language used for an extension.
+// The extension may be given with or without the leading dot.
+func RegisterLanguage(ext string, lang Language) {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	languagesByExtension[ext] = lang
+}
+
+// DetectLanguage determines the language of a file from its name, falling
+// back to a shebang line in the content when the name is inconclusive.
+func DetectLanguage(filename, content string) Language {
+	if filename != "" {
+		base := filepath.Base(filename)
+		if lang, ok := languagesByFilename[strings.ToLower(base)]; ok {
+			return lang
+		}
+		if lang, ok := languagesByExtension[strings.ToLower(filepath.Ext(base))]; ok {
+			return lang
+		}
+	}
+	return detectFromShebang(content)
+}
+
+// detectFromShebang inspects a leading "#!" line, e.g. "#!/usr/bin/env python3".
+func detectFromShebang(content string) Language {
+	if !strings.HasPrefix(content, "#!") {
+		return PlainText
+	}
+	line := content[2:]
+	if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+		line = line[:idx]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return PlainText
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" && len(fields) > 1 {
+		interp = fields[1]
+	}
+	if lang, ok := languagesByInterpreter[interp]; ok {
+		return lang
+	}
+	return PlainText
+}
@@ -0,0 +1,70 @@
+package panels
+
+import "testing"
+
+func TestDetectLanguageByExtension(t *testing.T) {
+	tests := []struct {
+		filename    string
+		highlighter string
+	}{
+		{"main.go", "go"},
+		{"scripts/build.py", "python"},
+		{"app.JS", "javascript"},
+		{"component.tsx", "tsx"},
+		{"index.html", "html"},
+		{"styles.css", "css"},
+		{"config.yml", "yaml"},
+		{"README.md", "markdown"},
+		{"run.sh", "bash"},
+		{"lib.rs", "rust"},
+		{"Makefile", "make"},
+		{"Dockerfile", "docker"},
+		{"notes.txt", "text"},
+		{"unknown.xyz", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			got := DetectLanguage(tt.filename, "")
+			if got.Highlighter != tt.highlighter {
+				t.Errorf("DetectLanguage(%q) highlighter = %q; want %q", tt.filename, got.Highlighter, tt.highlighter)
+			}
+		})
+	}
+}
+
+func TestDetectLanguageFromShebang(t *testing.T) {
+	tests := []struct {
+		content     string
+		highlighter string
+	}{
+		{"#!/usr/bin/env python3\nprint('hi')", "python"},
+		{"#!/bin/bash\necho hi", "bash"},
+		{"#!/usr/bin/env node\n", "javascript"},
+		{"no shebang here", "text"},
+	}
+
+	for _, tt := range tests {
+		got := DetectLanguage("script", tt.content)
+		if got.Highlighter != tt.highlighter {
+			t.Errorf("DetectLanguage(%q) highlighter = %q; want %q", tt.content, got.Highlighter, tt.highlighter)
+		}
+	}
+}
+
+func TestDetectLanguageExtensionWinsOverShebang(t *testing.T) {
+	got := DetectLanguage("tool.go", "#!/bin/bash\n")
+	if got.Highlighter != "go" {
+		t.Errorf("highlighter = %q; want go", got.Highlighter)
+	}
+}
+
+func TestRegisterLanguage(t *testing.T) {
+	RegisterLanguage("tmpl", Language{Name: "Go Template", Highlighter: "go-html-template"})
+	defer delete(languagesByExtension, ".tmpl")
+
+	got := DetectLanguage("page.tmpl", "")
+	if got.Name != "Go Template" || got.Highlighter != "go-html-template" {
+		t.Errorf("DetectLanguage(page.tmpl) = %+v; want Go Template", got)
+	}
+}
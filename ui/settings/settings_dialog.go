@@ -20,9 +20,19 @@ type SettingsDialog struct {
 	wsClient *client.WebSocketClient
 
 	// UI Components
-	dialog      dialog.Dialog
-	modelEntry  *widget.Select
-	apiKeyEntry *widget.Entry
+	dialog        dialog.Dialog
+	modelEntry    *widget.Select
+	apiKeyEntry   *widget.Entry
+	thinkingCheck *widget.Check
+
+	// Workspace mode and sandbox
+	modeSelect      *widget.Select
+	sandboxKeyEntry *widget.Entry
+	templateEntry   *widget.Entry
+	saveBtn         *widget.Button
+
+	// OnSaved is called after settings are applied
+	OnSaved func()
 }
 
 // NewSettingsDialog creates a new settings dialog
@@ -73,6 +83,31 @@ func (sd *SettingsDialog) createUI() {
 
 	apiKeyFormItem := widget.NewFormItem("API Key", sd.apiKeyEntry)
 
+	// Thinking visibility
+	sd.thinkingCheck = widget.NewCheck("Show agent thinking", nil)
+	sd.thinkingCheck.SetChecked(sd.state.ShowThinking)
+
+	thinkingFormItem := widget.NewFormItem("Chat", sd.thinkingCheck)
+
+	// Workspace mode: local, docker or e2b. E2B needs an API key and
+	// optionally a template ID.
+	sd.sandboxKeyEntry = widget.NewPasswordEntry()
+	sd.sandboxKeyEntry.SetPlaceHolder("E2B API key")
+	sd.sandboxKeyEntry.SetText(sd.state.Sandbox.APIKey)
+	sd.sandboxKeyEntry.OnChanged = func(string) { sd.updateSandboxFields() }
+
+	sd.templateEntry = widget.NewEntry()
+	sd.templateEntry.SetPlaceHolder("Template ID (optional)")
+	sd.templateEntry.SetText(sd.state.Sandbox.TemplateID)
+
+	sd.modeSelect = widget.NewSelect(client.WorkspaceModes, func(string) {
+		sd.updateSandboxFields()
+	})
+
+	modeFormItem := widget.NewFormItem("Workspace Mode", sd.modeSelect)
+	sandboxKeyFormItem := widget.NewFormItem("Sandbox Key", sd.sandboxKeyEntry)
+	templateFormItem := widget.NewFormItem("Template", sd.templateEntry)
+
 	// Connection status
 	connectionStatus := widget.NewLabel("Disconnected")
 	if sd.state.IsConnected {
@@ -93,6 +128,10 @@ func (sd *SettingsDialog) createUI() {
 	form := widget.NewForm(
 		modelFormItem,
 		apiKeyFormItem,
+		thinkingFormItem,
+		modeFormItem,
+		sandboxKeyFormItem,
+		templateFormItem,
 		connectionFormItem,
 		workspaceFormItem,
 	)
@@ -106,7 +145,7 @@ func (sd *SettingsDialog) createUI() {
 	})
 
 	// Save button
-	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+	sd.saveBtn = widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
 		sd.saveSettings()
 	})
 
@@ -119,7 +158,7 @@ func (sd *SettingsDialog) createUI() {
 	buttonRow := container.NewHBox(
 		layout.NewSpacer(),
 		cancelBtn,
-		saveBtn,
+		sd.saveBtn,
 	)
 
 	// Main content
@@ -133,6 +172,12 @@ func (sd *SettingsDialog) createUI() {
 		buttonRow,
 	)
 
+	mode := sd.state.Sandbox.Mode
+	if mode == "" {
+		mode = client.WorkspaceModeLocal
+	}
+	sd.modeSelect.SetSelected(mode)
+
 	// Create custom dialog
 	sd.dialog = dialog.NewCustomWithoutButtons(
 		"Settings",
@@ -141,12 +186,57 @@ func (sd *SettingsDialog) createUI() {
 	)
 }
 
+// sandboxSettings returns the sandbox settings entered in the form
+func (sd *SettingsDialog) sandboxSettings() client.SandboxSettings {
+	settings := client.SandboxSettings{Mode: sd.modeSelect.Selected}
+	if settings.Mode == client.WorkspaceModeE2B {
+		settings.APIKey = sd.sandboxKeyEntry.Text
+		settings.TemplateID = sd.templateEntry.Text
+	}
+	return settings
+}
+
+// updateSandboxFields enables the E2B fields only in e2b mode and blocks
+// saving until the selection is valid
+func (sd *SettingsDialog) updateSandboxFields() {
+	if sd.saveBtn == nil {
+		return
+	}
+	if sd.modeSelect.Selected == client.WorkspaceModeE2B {
+		sd.sandboxKeyEntry.Enable()
+		sd.templateEntry.Enable()
+	} else {
+		sd.sandboxKeyEntry.Disable()
+		sd.templateEntry.Disable()
+	}
+	if sd.sandboxSettings().Validate() != nil {
+		sd.saveBtn.Disable()
+	} else {
+		sd.saveBtn.Enable()
+	}
+}
+
 // saveSettings saves the settings
 func (sd *SettingsDialog) saveSettings() {
+	sandbox := sd.sandboxSettings()
+	if err := sandbox.Validate(); err != nil {
+		dialog.ShowError(err, sd.parent)
+		return
+	}
+
 	// TODO: Implement settings persistence
 	// For now, just update the state
 	sd.state.SelectedModel = sd.modelEntry.Selected
+	sd.state.ShowThinking = sd.thinkingCheck.Checked
+	if sandbox != sd.state.Sandbox {
+		// The agent picks up a new workspace mode when it is next initialized
+		sd.state.Sandbox = sandbox
+		sd.state.IsAgentInitialized = false
+	}
 
+	if sd.OnSaved != nil {
+		sd.OnSaved()
+	}
 	sd.dialog.Hide()
 }
 
@@ -40,6 +40,10 @@ type SessionResult struct {
 type StrReplaceResponse struct {
 	Success     bool   `json:"success"`
 	FileContent string `json:"file_content"`
+	// Diff is the unified diff of an edit; DryRun marks an edit that was
+	// not written.
+	Diff   string `json:"diff,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
 }
 
 // --- Configuration ---
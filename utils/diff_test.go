@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change",
+			"a\nb\nc\n", "a\nB\nc\n",
+			"--- a/f.txt\n+++ b/f.txt\n@@ -1,4 +1,4 @@\n a\n-b\n+B\n c\n \n",
+		},
+		{
+			"insert into empty",
+			"", "x",
+			"--- a/f.txt\n+++ b/f.txt\n@@ -1,1 +1,1 @@\n-\n+x\n",
+		},
+		{
+			"context is trimmed",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9", "1\n2\n3\n4\nfive\n6\n7\n8\n9",
+			"--- a/f.txt\n+++ b/f.txt\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("/f.txt", tt.old, tt.new); got != tt.want {
+				t.Errorf("UnifiedDiff() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	var old, new []string
+	for i := 0; i < 30; i++ {
+		old = append(old, "line")
+		new = append(new, "line")
+	}
+	new[2], new[25] = "changed", "changed"
+
+	got := UnifiedDiff("f", strings.Join(old, "\n"), strings.Join(new, "\n"))
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("hunks = %d; want 2 in %q", n, got)
+	}
+}
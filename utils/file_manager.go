@@ -4,6 +4,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -204,10 +205,40 @@ func (m *StrReplaceManager) WriteFile(pathStr, content string) StrReplaceRespons
 	return StrReplaceResponse{Success: true, FileContent: content}
 }
 
+// StrReplaceOptions choose which match to replace when old_str occurs
+// more than once. The zero value requires a unique match.
+type StrReplaceOptions struct {
+	Occurrence int  // replace only the Nth match, 1-based
+	ReplaceAll bool // replace every match
+	// DryRun computes the edit and its diff without writing the file or
+	// recording undo history.
+	DryRun bool
+}
+
+// DryRunNotice starts the response of a dry-run edit.
+const DryRunNotice = "[DRY RUN] No changes were written."
+
 func (m *StrReplaceManager) StrReplace(pathStr, oldStr, newStr string) StrReplaceResponse {
+	return m.StrReplaceWith(pathStr, oldStr, newStr, StrReplaceOptions{})
+}
+
+// StrReplaceWith replaces oldStr with newStr, using opts to pick among
+// multiple matches. Without options, multiple matches fail with the line
+// number of each so the caller can disambiguate.
+func (m *StrReplaceManager) StrReplaceWith(pathStr, oldStr, newStr string, opts StrReplaceOptions) StrReplaceResponse {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if oldStr == "" {
+		return StrReplaceResponse{Success: false, FileContent: "old_str must not be empty."}
+	}
+	if opts.Occurrence < 0 {
+		return StrReplaceResponse{Success: false, FileContent: fmt.Sprintf("Invalid occurrence %d: should be 1 or greater.", opts.Occurrence)}
+	}
+	if opts.ReplaceAll && opts.Occurrence > 0 {
+		return StrReplaceResponse{Success: false, FileContent: "Use either occurrence or replace_all, not both."}
+	}
+
 	contentBytes, err := os.ReadFile(pathStr)
 	if err != nil {
 		return StrReplaceResponse{Success: false, FileContent: fmt.Sprintf("Read error: %v", err)}
@@ -222,22 +253,33 @@ func (m *StrReplaceManager) StrReplace(pathStr, oldStr, newStr string) StrReplac
 
 	// Simple case
 	if !m.IgnoreIndentation {
-		if strings.Count(content, oldStr) == 0 {
+		var offsets, lineNums []int
+		for i := 0; ; {
+			j := strings.Index(content[i:], oldStr)
+			if j < 0 {
+				break
+			}
+			offsets = append(offsets, i+j)
+			lineNums = append(lineNums, strings.Count(content[:i+j], "\n")+1)
+			i += j + len(oldStr)
+		}
+		if len(offsets) == 0 {
 			return StrReplaceResponse{Success: false, FileContent: "old_str not found verbatim."}
 		}
-		if strings.Count(content, oldStr) > 1 {
-			return StrReplaceResponse{Success: false, FileContent: "Multiple occurrences of old_str found."}
+		selected, errMsg := selectMatches(lineNums, opts)
+		if errMsg != "" {
+			return StrReplaceResponse{Success: false, FileContent: errMsg}
 		}
-		
-		newContent := strings.Replace(content, oldStr, newStr, 1)
-		
-		// History
-		m.History[pathStr] = append(m.History[pathStr], content)
-		
-		if err := os.WriteFile(pathStr, []byte(newContent), 0644); err != nil {
-			return StrReplaceResponse{Success: false, FileContent: err.Error()}
+
+		var b strings.Builder
+		prev := 0
+		for _, idx := range selected {
+			b.WriteString(content[prev:offsets[idx]])
+			b.WriteString(newStr)
+			prev = offsets[idx] + len(oldStr)
 		}
-		return StrReplaceResponse{Success: true, FileContent: makeSnippet(newContent, newStr)}
+		b.WriteString(content[prev:])
+		return m.applyReplace(pathStr, content, b.String(), newStr, opts.DryRun)
 	}
 
 	// Complex Indentation Ignoring Logic
@@ -252,7 +294,7 @@ func (m *StrReplaceManager) StrReplace(pathStr, oldStr, newStr string) StrReplac
 		strippedOld[i] = strings.TrimSpace(l)
 	}
 
-	matchIndex := -1
+	var matches, lineNums []int
 	for i := 0; i <= len(lines)-len(oldLines); i++ {
 		match := true
 		for j := 0; j < len(oldLines); j++ {
@@ -267,29 +309,156 @@ func (m *StrReplaceManager) StrReplace(pathStr, oldStr, newStr string) StrReplac
 			}
 		}
 		if match {
-			if matchIndex != -1 {
-				return StrReplaceResponse{Success: false, FileContent: "Multiple fuzzy matches found."}
-			}
-			matchIndex = i
+			matches = append(matches, i)
+			lineNums = append(lineNums, i+1)
+			i += len(oldLines) - 1 // matches must not overlap
 		}
 	}
 
-	if matchIndex == -1 {
+	if len(matches) == 0 {
 		return StrReplaceResponse{Success: false, FileContent: "No match found."}
 	}
+	selected, errMsg := selectMatches(lineNums, opts)
+	if errMsg != "" {
+		return StrReplaceResponse{Success: false, FileContent: errMsg}
+	}
 
-	// Reconstruct
-	// Match indentation of first line
-	indentedNewStr := matchIndentByFirstLine(newStr, lines[matchIndex])
-	
-	newContentLines := append(lines[:matchIndex], strings.Split(indentedNewStr, "\n")...)
-	newContentLines = append(newContentLines, lines[matchIndex+len(oldLines):]...)
+	// Reconstruct, matching the indentation of each match's first line
+	var newContentLines []string
+	var indentedNewStr string
+	prev := 0
+	for _, idx := range selected {
+		matchIndex := matches[idx]
+		indentedNewStr = matchIndentByFirstLine(newStr, lines[matchIndex])
+		newContentLines = append(newContentLines, lines[prev:matchIndex]...)
+		newContentLines = append(newContentLines, strings.Split(indentedNewStr, "\n")...)
+		prev = matchIndex + len(oldLines)
+	}
+	newContentLines = append(newContentLines, lines[prev:]...)
 	finalContent := strings.Join(newContentLines, "\n")
+	return m.applyReplace(pathStr, content, finalContent, indentedNewStr, opts.DryRun)
+}
+
+// applyReplace writes newContent over content, keeping content for undo,
+// and responds with a snippet around inserted. A dry run writes nothing
+// and responds with the diff instead.
+func (m *StrReplaceManager) applyReplace(pathStr, content, newContent, inserted string, dryRun bool) StrReplaceResponse {
+	diff := UnifiedDiff(pathStr, content, newContent)
+	if dryRun {
+		return StrReplaceResponse{Success: true, FileContent: DryRunNotice + "\n" + diff, DryRun: true, Diff: diff}
+	}
+
+	m.History[pathStr] = append(m.History[pathStr], content)
+	if err := os.WriteFile(pathStr, []byte(newContent), 0644); err != nil {
+		return StrReplaceResponse{Success: false, FileContent: err.Error()}
+	}
+	return StrReplaceResponse{Success: true, FileContent: makeSnippet(newContent, inserted), Diff: diff}
+}
+
+// selectMatches returns the indexes of the matches to replace given the
+// line number of each match, or an error message for the caller.
+func selectMatches(lineNums []int, opts StrReplaceOptions) ([]int, string) {
+	switch {
+	case opts.ReplaceAll:
+		all := make([]int, len(lineNums))
+		for i := range all {
+			all[i] = i
+		}
+		return all, ""
+	case opts.Occurrence > len(lineNums):
+		return nil, fmt.Sprintf("Occurrence %d requested but old_str occurs %d time(s), at lines %s.",
+			opts.Occurrence, len(lineNums), joinInts(lineNums))
+	case opts.Occurrence > 0:
+		return []int{opts.Occurrence - 1}, ""
+	case len(lineNums) > 1:
+		return nil, fmt.Sprintf("Multiple occurrences of old_str found, at lines %s. "+
+			"Set occurrence to pick one, set replace_all, or include more context to make old_str unique.",
+			joinInts(lineNums))
+	}
+	return []int{0}, ""
+}
+
+func joinInts(nums []int) string {
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Insert adds text after the given 1-based line of the file, or at the
+// start when line is 0. A line past the end of the file appends the text.
+// The previous content is kept for Undo and the response holds a snippet
+// of the affected region with inserted lines marked "+".
+func (m *StrReplaceManager) Insert(pathStr string, line int, text string) StrReplaceResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if line < 0 {
+		return StrReplaceResponse{Success: false, FileContent: fmt.Sprintf("Invalid line %d: should be 0 or greater.", line)}
+	}
+	contentBytes, err := os.ReadFile(pathStr)
+	if err != nil {
+		return StrReplaceResponse{Success: false, FileContent: fmt.Sprintf("Read error: %v", err)}
+	}
+	content := string(contentBytes)
+	if m.ExpandTabs {
+		text = strings.ReplaceAll(text, "\t", "    ")
+	}
+
+	lines := splitLines(content)
+	newLines := splitLines(text)
+	if len(newLines) == 0 {
+		newLines = []string{""}
+	}
+
+	note := ""
+	if line > len(lines) {
+		note = fmt.Sprintf("Line %d is past the end of the file (%d lines); the text was appended.\n", line, len(lines))
+		line = len(lines)
+	}
+
+	result := make([]string, 0, len(lines)+len(newLines))
+	result = append(result, lines[:line]...)
+	result = append(result, newLines...)
+	result = append(result, lines[line:]...)
+	newContent := strings.Join(result, "\n")
+	if content == "" || strings.HasSuffix(content, "\n") {
+		newContent += "\n"
+	}
 
 	m.History[pathStr] = append(m.History[pathStr], content)
-	os.WriteFile(pathStr, []byte(finalContent), 0644)
+	if err := os.WriteFile(pathStr, []byte(newContent), 0644); err != nil {
+		m.History[pathStr] = m.History[pathStr][:len(m.History[pathStr])-1]
+		return StrReplaceResponse{Success: false, FileContent: err.Error()}
+	}
 
-	return StrReplaceResponse{Success: true, FileContent: makeSnippet(finalContent, indentedNewStr)}
+	return StrReplaceResponse{Success: true, FileContent: note + insertSnippet(result, line, len(newLines))}
+}
+
+// splitLines splits content into lines, ignoring a trailing newline.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// insertSnippet shows count inserted lines starting at index at, with
+// SnippetLines of context on either side.
+func insertSnippet(lines []string, at, count int) string {
+	from := max(0, at-SnippetLines)
+	to := min(len(lines), at+count+SnippetLines)
+
+	var b strings.Builder
+	for i := from; i < to; i++ {
+		marker := " "
+		if i >= at && i < at+count {
+			marker = "+"
+		}
+		fmt.Fprintf(&b, "%6d %s %s\n", i+1, marker, lines[i])
+	}
+	return b.String()
 }
 
 func (m *StrReplaceManager) Undo(pathStr string) StrReplaceResponse {
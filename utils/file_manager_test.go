@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestStrReplaceManagerInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		line     int
+		text     string
+		want     string
+		wantNote bool
+	}{
+		{"start", "a\nb\n", 0, "first", "first\na\nb\n", false},
+		{"middle", "a\nb\nc\n", 2, "x\ny", "a\nb\nx\ny\nc\n", false},
+		{"end", "a\nb\n", 2, "z\n", "a\nb\nz\n", false},
+		{"past EOF", "a\nb\n", 10, "z", "a\nb\nz\n", true},
+		{"no trailing newline", "a\nb", 1, "x", "a\nx\nb", false},
+		{"empty file", "", 0, "x", "x\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, tt.content)
+			m := NewStrReplaceManager(false, false)
+
+			resp := m.Insert(path, tt.line, tt.text)
+			if !resp.Success {
+				t.Fatalf("Insert() failed: %s", resp.FileContent)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("content = %q; want %q", got, tt.want)
+			}
+			if hasNote := strings.Contains(resp.FileContent, "past the end of the file"); hasNote != tt.wantNote {
+				t.Errorf("past-EOF note = %v; want %v (%q)", hasNote, tt.wantNote, resp.FileContent)
+			}
+		})
+	}
+}
+
+func TestStrReplaceManagerInsertSnippetAndUndo(t *testing.T) {
+	path := writeTempFile(t, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	m := NewStrReplaceManager(false, false)
+
+	resp := m.Insert(path, 5, "new")
+	want := "     2   2\n     3   3\n     4   4\n     5   5\n     6 + new\n     7   6\n     8   7\n     9   8\n    10   9\n"
+	if resp.FileContent != want {
+		t.Errorf("snippet = %q; want %q", resp.FileContent, want)
+	}
+
+	if undo := m.Undo(path); !undo.Success {
+		t.Fatalf("Undo() failed: %s", undo.FileContent)
+	}
+	if got := readFile(t, path); got != "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n" {
+		t.Errorf("content after undo = %q", got)
+	}
+}
+
+func TestStrReplaceManagerInsertErrors(t *testing.T) {
+	m := NewStrReplaceManager(false, false)
+	if resp := m.Insert(writeTempFile(t, "a\n"), -1, "x"); resp.Success {
+		t.Error("Insert() with a negative line should fail")
+	}
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	if resp := m.Insert(missing, 0, "x"); resp.Success {
+		t.Error("Insert() into a missing file should fail")
+	}
+	if len(m.History[missing]) != 0 {
+		t.Error("failed Insert() should not record history")
+	}
+}
+
+func TestStrReplaceOccurrence(t *testing.T) {
+	path := writeTempFile(t, "x = 1\ny = 2\nx = 1\nx = 1\n")
+	m := NewStrReplaceManager(false, false)
+
+	resp := m.StrReplaceWith(path, "x = 1", "x = 9", StrReplaceOptions{Occurrence: 2})
+	if !resp.Success {
+		t.Fatalf("StrReplaceWith() failed: %s", resp.FileContent)
+	}
+	if got := readFile(t, path); got != "x = 1\ny = 2\nx = 9\nx = 1\n" {
+		t.Errorf("content = %q; want only the 2nd match replaced", got)
+	}
+	if len(m.History[path]) != 1 {
+		t.Errorf("history entries = %d; want 1", len(m.History[path]))
+	}
+
+	resp = m.StrReplaceWith(path, "x = 1", "x = 0", StrReplaceOptions{Occurrence: 5})
+	if resp.Success || !strings.Contains(resp.FileContent, "occurs 2 time(s), at lines 1, 4") {
+		t.Errorf("out-of-range occurrence = %+v", resp)
+	}
+}
+
+func TestStrReplaceAll(t *testing.T) {
+	path := writeTempFile(t, "a b a\nb a\n")
+	m := NewStrReplaceManager(false, false)
+
+	resp := m.StrReplaceWith(path, "a", "c", StrReplaceOptions{ReplaceAll: true})
+	if !resp.Success {
+		t.Fatalf("StrReplaceWith() failed: %s", resp.FileContent)
+	}
+	if got := readFile(t, path); got != "c b c\nb c\n" {
+		t.Errorf("content = %q", got)
+	}
+	if len(m.History[path]) != 1 {
+		t.Errorf("history entries = %d; want 1 for one operation", len(m.History[path]))
+	}
+	m.Undo(path)
+	if got := readFile(t, path); got != "a b a\nb a\n" {
+		t.Errorf("content after undo = %q", got)
+	}
+}
+
+func TestStrReplaceMultipleMatchesReportsLines(t *testing.T) {
+	path := writeTempFile(t, "foo()\nbar()\nfoo()\n\nfoo()\n")
+	m := NewStrReplaceManager(false, false)
+
+	resp := m.StrReplace(path, "foo()", "baz()")
+	if resp.Success {
+		t.Fatal("StrReplace() with multiple matches should fail")
+	}
+	if !strings.Contains(resp.FileContent, "at lines 1, 3, 5") {
+		t.Errorf("message = %q; want the matching line numbers", resp.FileContent)
+	}
+	if len(m.History[path]) != 0 {
+		t.Error("failed replace should not record history")
+	}
+}
+
+func TestStrReplaceIgnoreIndentationOccurrence(t *testing.T) {
+	path := writeTempFile(t, "func a() {\n\treturn 1\n}\nfunc b() {\n    return 1\n}\n")
+	m := NewStrReplaceManager(true, false)
+
+	resp := m.StrReplace(path, "return 1", "return 2")
+	if resp.Success || !strings.Contains(resp.FileContent, "at lines 2, 5") {
+		t.Errorf("multi-match = %+v; want lines 2, 5", resp)
+	}
+
+	resp = m.StrReplaceWith(path, "return 1", "return 2", StrReplaceOptions{Occurrence: 2})
+	if !resp.Success {
+		t.Fatalf("StrReplaceWith() failed: %s", resp.FileContent)
+	}
+	if got := readFile(t, path); got != "func a() {\n\treturn 1\n}\nfunc b() {\n    return 2\n}\n" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestStrReplaceOptionErrors(t *testing.T) {
+	path := writeTempFile(t, "a\n")
+	m := NewStrReplaceManager(false, false)
+	for _, opts := range []StrReplaceOptions{{Occurrence: -1}, {Occurrence: 1, ReplaceAll: true}} {
+		if resp := m.StrReplaceWith(path, "a", "b", opts); resp.Success {
+			t.Errorf("StrReplaceWith(%+v) should fail", opts)
+		}
+	}
+	if resp := m.StrReplace(path, "", "b"); resp.Success {
+		t.Error("empty old_str should fail")
+	}
+}
+
+func TestStrReplaceDryRun(t *testing.T) {
+	const content = "one\ntwo\nthree\n"
+	path := writeTempFile(t, content)
+	m := NewStrReplaceManager(false, false)
+
+	resp := m.StrReplaceWith(path, "two", "2", StrReplaceOptions{DryRun: true})
+	if !resp.Success || !resp.DryRun {
+		t.Fatalf("dry run = %+v; want a successful dry run", resp)
+	}
+	if got := readFile(t, path); got != content {
+		t.Errorf("content after dry run = %q; want it unchanged", got)
+	}
+	if len(m.History[path]) != 0 {
+		t.Errorf("history entries = %d; want 0 after a dry run", len(m.History[path]))
+	}
+	if !strings.Contains(resp.Diff, "\n-two\n+2\n") {
+		t.Errorf("Diff = %q; want the replaced line", resp.Diff)
+	}
+	if !strings.HasPrefix(resp.FileContent, DryRunNotice) {
+		t.Errorf("FileContent = %q; want it to start with the dry-run notice", resp.FileContent)
+	}
+
+	// A real edit afterwards produces the same diff.
+	resp = m.StrReplaceWith(path, "two", "2", StrReplaceOptions{})
+	if resp.DryRun || !strings.Contains(resp.Diff, "\n-two\n+2\n") {
+		t.Errorf("real edit = %+v", resp)
+	}
+	if got := readFile(t, path); got != "one\n2\nthree\n" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestStrReplaceDryRunNoMatch(t *testing.T) {
+	path := writeTempFile(t, "one\n")
+	m := NewStrReplaceManager(false, false)
+
+	if resp := m.StrReplaceWith(path, "missing", "x", StrReplaceOptions{DryRun: true}); resp.Success {
+		t.Errorf("dry run without a match = %+v; want failure", resp)
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// LocalExecutor runs commands on the host for a local workspace. There is
+// no sandbox in local mode, so it keeps commands in the workspace root and
+// resolves file paths only inside it.
+type LocalExecutor struct {
+	Workspace *WorkspaceManager
+	Shell     string
+}
+
+// NewLocalExecutor returns an executor for wm using settings.SystemShell,
+// or /bin/sh when settings is nil.
+func NewLocalExecutor(wm *WorkspaceManager, settings *SandboxSettings) *LocalExecutor {
+	shell := "/bin/sh"
+	if settings != nil && settings.SystemShell != "" {
+		shell = settings.SystemShell
+	}
+	return &LocalExecutor{Workspace: wm, Shell: shell}
+}
+
+// ResolvePath returns the host path for a file tool's path argument. In
+// local mode paths outside the workspace are rejected as ResolveInRoot
+// does; other modes map paths into the sandbox as WorkspacePath does.
+func (e *LocalExecutor) ResolvePath(p string) (string, error) {
+	if !e.Workspace.IsLocal() {
+		return e.Workspace.WorkspacePath(p), nil
+	}
+	return ResolveInRoot(e.Workspace.RootPath(), p)
+}
+
+// Execute runs command with the shell, its working directory pinned to the
+// workspace root, and returns the combined output.
+func (e *LocalExecutor) Execute(ctx context.Context, command string) SessionResult {
+	cmd := exec.CommandContext(ctx, e.Shell, "-c", command)
+	cmd.Dir = e.Workspace.RootPath()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return SessionResult{Success: false, Output: fmt.Sprintf("%s%v", out, err)}
+	}
+	return SessionResult{Success: true, Output: string(out)}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalExecutorResolvePath(t *testing.T) {
+	parent := t.TempDir()
+	e := NewLocalExecutor(NewWorkspaceManager(parent, "s1", NewSandboxSettings()), nil)
+	root := filepath.Join(parent, "s1")
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"src/main.go", filepath.Join(root, "src/main.go"), false},
+		{"src/../README.md", filepath.Join(root, "README.md"), false},
+		{"../s2/secret", "", true},
+		{"src/../../etc", "", true},
+		{"/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		got, err := e.ResolvePath(tt.path)
+		if tt.wantErr {
+			if !errors.Is(err, ErrOutsideWorkspace) {
+				t.Errorf("ResolvePath(%q) error = %v; want ErrOutsideWorkspace", tt.path, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolvePath(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestLocalExecutorRunsInWorkspaceRoot(t *testing.T) {
+	wm := NewWorkspaceManager(t.TempDir(), "s1", NewSandboxSettings())
+	if err := os.MkdirAll(wm.Root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	res := NewLocalExecutor(wm, nil).Execute(context.Background(), "pwd")
+	if !res.Success {
+		t.Fatalf("Execute() failed: %s", res.Output)
+	}
+	want, _ := filepath.EvalSymlinks(wm.Root)
+	got, _ := filepath.EvalSymlinks(strings.TrimSpace(res.Output))
+	if got != want {
+		t.Errorf("pwd = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideWorkspace is returned for paths that would leave the workspace.
+var ErrOutsideWorkspace = errors.New("path is outside the workspace")
+
+// ResolveInRoot returns the path of rel inside root. Absolute paths, paths
+// that climb out of root with "..", and paths that lead out of it through
+// a symlink are rejected.
+func ResolveInRoot(root, rel string) (string, error) {
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%w: %s is absolute; use a path relative to the workspace", ErrOutsideWorkspace, rel)
+	}
+	clean := filepath.Clean(rel)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkspace, rel)
+	}
+	path := filepath.Join(root, clean)
+	if !staysInRoot(root, path) {
+		return "", fmt.Errorf("%w: %s leads out of it through a symlink", ErrOutsideWorkspace, rel)
+	}
+	return path, nil
+}
+
+// staysInRoot reports whether path, once its symlinks are followed, is
+// still under root. A path that does not exist yet is judged by its
+// deepest existing ancestor, as that is where a write would land; a
+// symlink that cannot be followed is refused.
+func staysInRoot(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		// Nothing exists under a root that does not exist yet
+		return true
+	}
+	existing, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return resolved == realRoot || strings.HasPrefix(resolved, realRoot+string(filepath.Separator))
+		}
+		if _, statErr := os.Lstat(existing); statErr == nil {
+			return false
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return false
+		}
+		existing = parent
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveInRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "s1")
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"src/main.go", filepath.Join(root, "src/main.go"), false},
+		{"src/../README.md", filepath.Join(root, "README.md"), false},
+		{"../s2/secret", "", true},
+		{"src/../../etc", "", true},
+		{"/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveInRoot(root, tt.path)
+		if tt.wantErr {
+			if !errors.Is(err, ErrOutsideWorkspace) {
+				t.Errorf("ResolveInRoot(%q) error = %v; want ErrOutsideWorkspace", tt.path, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveInRoot(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveInRootFollowsSymlinks(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "s1")
+	outside := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644)
+	for link, target := range map[string]string{
+		"out":      outside,
+		"dangling": filepath.Join(outside, "missing", "file"),
+		"src-link": filepath.Join(root, "src"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	for _, path := range []string{"out", "out/secret", "out/new.txt", "out/new/dir/file", "dangling"} {
+		if _, err := ResolveInRoot(root, path); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("ResolveInRoot(%q) error = %v; want ErrOutsideWorkspace", path, err)
+		}
+	}
+	for _, path := range []string{"src-link/main.go", "src/new.txt", "new/dir/file", "."} {
+		if _, err := ResolveInRoot(root, path); err != nil {
+			t.Errorf("ResolveInRoot(%q) error = %v; want the path inside the workspace", path, err)
+		}
+	}
+}